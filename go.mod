@@ -6,6 +6,7 @@ toolchain go1.24.3
 
 require (
 	github.com/andybalholm/brotli v1.2.0
+	github.com/expr-lang/expr v1.17.6
 	github.com/gin-contrib/gzip v1.2.3
 	github.com/gin-contrib/static v1.1.5
 	github.com/gin-gonic/gin v1.10.1
@@ -18,8 +19,10 @@ require (
 	github.com/nicksnyder/go-i18n/v2 v2.6.0
 	github.com/redis/go-redis/v9 v9.5.3
 	github.com/sirupsen/logrus v1.9.3
+	github.com/tetratelabs/wazero v1.10.1
 	go.uber.org/dig v1.19.0
 	golang.org/x/crypto v0.37.0
+	golang.org/x/sync v0.16.0
 	golang.org/x/text v0.28.0
 	gorm.io/datatypes v1.2.1
 	gorm.io/driver/mysql v1.6.0
@@ -62,7 +65,6 @@ require (
 	github.com/ugorji/go/codec v1.2.12 // indirect
 	golang.org/x/arch v0.16.0 // indirect
 	golang.org/x/net v0.38.0 // indirect
-	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/sys v0.32.0 // indirect
 	google.golang.org/protobuf v1.36.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
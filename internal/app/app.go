@@ -18,6 +18,7 @@ import (
 	"gpt-load/internal/store"
 	"gpt-load/internal/types"
 	"gpt-load/internal/version"
+	"gpt-load/internal/wasmplugin"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -31,11 +32,15 @@ type App struct {
 	configManager     types.ConfigManager
 	settingsManager   *config.SystemSettingsManager
 	groupManager      *services.GroupManager
+	providerStatus    *services.ProviderStatusService
 	logCleanupService *services.LogCleanupService
+	dailyReport       *services.DailyReportService
 	requestLogService *services.RequestLogService
+	auditLogService   *services.AuditLogService
 	cronChecker       *keypool.CronChecker
 	keyPoolProvider   *keypool.KeyProvider
 	proxyServer       *proxy.ProxyServer
+	pluginHost        *wasmplugin.Host
 	storage           store.Store
 	db                *gorm.DB
 	httpServer        *http.Server
@@ -48,11 +53,15 @@ type AppParams struct {
 	ConfigManager     types.ConfigManager
 	SettingsManager   *config.SystemSettingsManager
 	GroupManager      *services.GroupManager
+	ProviderStatus    *services.ProviderStatusService
 	LogCleanupService *services.LogCleanupService
+	DailyReport       *services.DailyReportService
 	RequestLogService *services.RequestLogService
+	AuditLogService   *services.AuditLogService
 	CronChecker       *keypool.CronChecker
 	KeyPoolProvider   *keypool.KeyProvider
 	ProxyServer       *proxy.ProxyServer
+	PluginHost        *wasmplugin.Host
 	Storage           store.Store
 	DB                *gorm.DB
 }
@@ -64,11 +73,15 @@ func NewApp(params AppParams) *App {
 		configManager:     params.ConfigManager,
 		settingsManager:   params.SettingsManager,
 		groupManager:      params.GroupManager,
+		providerStatus:    params.ProviderStatus,
 		logCleanupService: params.LogCleanupService,
+		dailyReport:       params.DailyReport,
 		requestLogService: params.RequestLogService,
+		auditLogService:   params.AuditLogService,
 		cronChecker:       params.CronChecker,
 		keyPoolProvider:   params.KeyPoolProvider,
 		proxyServer:       params.ProxyServer,
+		pluginHost:        params.PluginHost,
 		storage:           params.Storage,
 		db:                params.DB,
 	}
@@ -99,6 +112,9 @@ func (a *App) Start() error {
 			&models.APIKey{},
 			&models.RequestLog{},
 			&models.GroupHourlyStat{},
+			&models.UsageHourlyStat{},
+			&models.Budget{},
+			&models.AuditLog{},
 		); err != nil {
 			return fmt.Errorf("database auto-migration failed: %w", err)
 		}
@@ -125,6 +141,7 @@ func (a *App) Start() error {
 		// 仅 Master 节点启动的服务
 		a.requestLogService.Start()
 		a.logCleanupService.Start()
+		a.dailyReport.Start()
 		a.cronChecker.Start()
 	} else {
 		logrus.Info("Starting as Slave Node.")
@@ -134,10 +151,15 @@ func (a *App) Start() error {
 	// 显示配置并启动所有后台服务
 	a.configManager.DisplayServerConfig()
 
-	a.groupManager.Initialize()
-
 	// Create HTTP server
 	serverConfig := a.configManager.GetEffectiveServerConfig()
+
+	// 加载分组缓存，失败时后台重试，健康检查在加载成功前返回 503
+	a.groupManager.Start(serverConfig.StartupWarmup)
+	a.providerStatus.Start()
+	a.auditLogService.Start()
+	a.proxyServer.Start()
+
 	a.httpServer = &http.Server{
 		Addr:           fmt.Sprintf("%s:%d", serverConfig.Host, serverConfig.Port),
 		Handler:        a.engine,
@@ -184,6 +206,8 @@ func (a *App) Stop(ctx context.Context) {
 	// 使用原始的总超时 context 继续关闭其他后台服务
 	stoppableServices := []func(context.Context){
 		a.groupManager.Stop,
+		a.providerStatus.Stop,
+		a.auditLogService.Stop,
 		a.settingsManager.Stop,
 	}
 
@@ -191,6 +215,7 @@ func (a *App) Stop(ctx context.Context) {
 		stoppableServices = append(stoppableServices,
 			a.cronChecker.Stop,
 			a.logCleanupService.Stop,
+			a.dailyReport.Stop,
 			a.requestLogService.Stop,
 		)
 	}
@@ -221,6 +246,7 @@ func (a *App) Stop(ctx context.Context) {
 	if a.storage != nil {
 		a.storage.Close()
 	}
+	a.pluginHost.Close(ctx)
 
 	logrus.Info("Server exited gracefully")
 }
@@ -3,15 +3,19 @@ package channel
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/jsonengine"
 	"gpt-load/internal/models"
 	"gpt-load/internal/utils"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -51,24 +55,13 @@ func (ch *AnthropicChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bo
 		return true
 	}
 
-	type streamPayload struct {
-		Stream bool `json:"stream"`
-	}
-	var p streamPayload
-	if err := json.Unmarshal(bodyBytes, &p); err == nil {
-		return p.Stream
-	}
-
-	return false
+	stream, _ := jsonengine.Get(bodyBytes, "stream")
+	return stream.Bool()
 }
 
 func (ch *AnthropicChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
-	type modelPayload struct {
-		Model string `json:"model"`
-	}
-	var p modelPayload
-	if err := json.Unmarshal(bodyBytes, &p); err == nil {
-		return p.Model
+	if model, ok := jsonengine.Get(bodyBytes, "model"); ok && model.Type == jsonengine.ValueString {
+		return model.String()
 	}
 	return ""
 }
@@ -116,7 +109,8 @@ func (ch *AnthropicChannel) ValidateKey(ctx context.Context, apiKey *models.APIK
 	// Apply custom header rules if available
 	if len(group.HeaderRuleList) > 0 {
 		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
-		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+		scope := models.RuleScope{Method: req.Method, Path: endpointURL.Path}
+		utils.ApplyHeaderRules(req, group.HeaderRulesFor(scope), headerCtx)
 	}
 
 	resp, err := ch.HTTPClient.Do(req)
@@ -141,3 +135,56 @@ func (ch *AnthropicChannel) ValidateKey(ctx context.Context, apiKey *models.APIK
 
 	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
 }
+
+const (
+	anthropicCacheControlMarker = `"cache_control"`
+	// Anthropic caches default to a 5 minute TTL, optionally extended to 1 hour.
+	anthropicDefaultCacheTTL  = 5 * time.Minute
+	anthropicExtendedCacheTTL = time.Hour
+)
+
+// ExtractCacheReference identifies the prompt prefix cached through cache_control
+// breakpoints. Prompt caches are scoped to the key's organization, so requests
+// sharing a cached prefix are pinned to the key that created it.
+// The ID hashes the model, tools, system prompt and messages up to the last breakpoint.
+func (ch *AnthropicChannel) ExtractCacheReference(c *gin.Context, bodyBytes []byte) *CacheReference {
+	if !bytes.Contains(bodyBytes, []byte(anthropicCacheControlMarker)) {
+		return nil
+	}
+
+	var p struct {
+		Model    string            `json:"model"`
+		Tools    json.RawMessage   `json:"tools"`
+		System   json.RawMessage   `json:"system"`
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(bodyBytes, &p); err != nil {
+		return nil
+	}
+
+	prefixEnd := 0
+	for i, msg := range p.Messages {
+		if bytes.Contains(msg, []byte(anthropicCacheControlMarker)) {
+			prefixEnd = i + 1
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(p.Model))
+	h.Write(p.Tools)
+	h.Write(p.System)
+	for _, msg := range p.Messages[:prefixEnd] {
+		h.Write(msg)
+	}
+
+	ttl := anthropicDefaultCacheTTL
+	if bytes.Contains(bodyBytes, []byte(`"ttl":"1h"`)) || bytes.Contains(bodyBytes, []byte(`"ttl": "1h"`)) {
+		ttl = anthropicExtendedCacheTTL
+	}
+
+	return &CacheReference{
+		ID:       "prompt:" + hex.EncodeToString(h.Sum(nil)),
+		Implicit: true,
+		TTL:      ttl,
+	}
+}
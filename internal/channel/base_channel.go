@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"gpt-load/internal/circuit"
 	"gpt-load/internal/models"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
@@ -14,6 +15,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
 )
@@ -44,6 +46,8 @@ type BaseChannel struct {
 }
 
 // getUpstreamURL selects an upstream URL using a smooth weighted round-robin algorithm.
+// Upstreams whose host circuit breaker is open are skipped; when all are open the first
+// upstream is used.
 func (b *BaseChannel) getUpstreamURL() *url.URL {
 	b.upstreamLock.Lock()
 	defer b.upstreamLock.Unlock()
@@ -51,7 +55,10 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 	if len(b.Upstreams) == 0 {
 		return nil
 	}
+	breakers := circuit.SettingsFrom(b.effectiveConfig)
 	if len(b.Upstreams) == 1 {
+		// Nothing to route around, but let the breaker probe so it can close again
+		upstreamBreakers.Allow(b.Upstreams[0].URL.Host, breakers)
 		return b.Upstreams[0].URL
 	}
 
@@ -60,6 +67,9 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 
 	for i := range b.Upstreams {
 		up := &b.Upstreams[i]
+		if !upstreamBreakers.Ready(up.URL.Host, breakers) {
+			continue
+		}
 		totalWeight += up.Weight
 		up.CurrentWeight += up.Weight
 
@@ -73,6 +83,7 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 	}
 
 	best.CurrentWeight -= totalWeight
+	upstreamBreakers.Allow(best.URL.Host, breakers)
 	return best.URL
 }
 
@@ -250,6 +261,21 @@ func (b *BaseChannel) TransformModelList(req *http.Request, bodyBytes []byte, gr
 	return response, nil
 }
 
+// ExtractCacheReference returns nil; channels without cached contexts need no key affinity.
+func (b *BaseChannel) ExtractCacheReference(c *gin.Context, bodyBytes []byte) *CacheReference {
+	return nil
+}
+
+// IsCacheCreateRequest returns false for channels without explicit cache APIs.
+func (b *BaseChannel) IsCacheCreateRequest(c *gin.Context) bool {
+	return false
+}
+
+// ParseCreatedCache returns nil for channels without explicit cache APIs.
+func (b *BaseChannel) ParseCreatedCache(respBody []byte) *CacheReference {
+	return nil
+}
+
 // buildConfiguredModels builds a list of models from redirect rules
 func buildConfiguredModels(redirectMap map[string][]models.ModelRedirectTarget) []any {
 	if len(redirectMap) == 0 {
@@ -5,10 +5,21 @@ import (
 	"gpt-load/internal/models"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// CacheReference identifies a provider-side cached context. Cached contexts belong
+// to the key that created them, so requests referring to one must use that key.
+type CacheReference struct {
+	ID string
+	// Implicit caches are created by the request itself (e.g. Anthropic cache_control
+	// breakpoints), so the key binding is recorded when the request succeeds.
+	Implicit bool
+	TTL      time.Duration
+}
+
 // ChannelProxy defines the interface for different API channel proxies.
 type ChannelProxy interface {
 	// BuildUpstreamURL constructs the target URL for the upstream service.
@@ -40,4 +51,13 @@ type ChannelProxy interface {
 
 	// TransformModelList transforms the model list response based on redirect rules.
 	TransformModelList(req *http.Request, bodyBytes []byte, group *models.Group) (map[string]any, error)
+
+	// ExtractCacheReference returns the cached context the request depends on, or nil.
+	ExtractCacheReference(c *gin.Context, bodyBytes []byte) *CacheReference
+
+	// IsCacheCreateRequest checks if the request explicitly creates a cached context.
+	IsCacheCreateRequest(c *gin.Context) bool
+
+	// ParseCreatedCache extracts the cached context created by a successful create request.
+	ParseCreatedCache(respBody []byte) *CacheReference
 }
@@ -0,0 +1,22 @@
+package channel
+
+import (
+	"net/url"
+
+	"gpt-load/internal/circuit"
+	"gpt-load/internal/types"
+)
+
+// upstreamBreakers tracks the failure rate of each upstream host across all channels.
+var upstreamBreakers = circuit.NewRegistry("upstream")
+
+// RecordUpstreamResult records the outcome of a request sent to upstreamURL for the host
+// circuit breaker. Only failures that point at the host itself (connection errors and 5xx
+// responses) should be recorded as failures.
+func RecordUpstreamResult(upstreamURL string, cfg *types.SystemSettings, success bool) {
+	u, err := url.Parse(upstreamURL)
+	if err != nil || u.Host == "" {
+		return
+	}
+	upstreamBreakers.Record(u.Host, circuit.SettingsFrom(cfg), success)
+}
@@ -0,0 +1,86 @@
+package channel
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// deprecatedModels 各渠道已弃用或已下线的模型及其推荐的替代模型
+// 新的弃用公告只需在此追加条目；部署方也可通过 model_deprecations 配置补充或取消条目
+var deprecatedModels = map[string]map[string]string{
+	"openai": {
+		"gpt-3.5-turbo-0301":        "gpt-3.5-turbo",
+		"gpt-3.5-turbo-0613":        "gpt-3.5-turbo",
+		"gpt-3.5-turbo-16k":         "gpt-3.5-turbo",
+		"gpt-3.5-turbo-16k-0613":    "gpt-3.5-turbo",
+		"gpt-4-0314":                "gpt-4o",
+		"gpt-4-32k":                 "gpt-4o",
+		"gpt-4-32k-0314":            "gpt-4o",
+		"gpt-4-32k-0613":            "gpt-4o",
+		"gpt-4-vision-preview":      "gpt-4o",
+		"gpt-4-1106-vision-preview": "gpt-4o",
+		"gpt-4.5-preview":           "gpt-4.1",
+		"o1-preview":                "o1",
+		"o1-mini":                   "o4-mini",
+		"text-davinci-002":          "gpt-3.5-turbo-instruct",
+		"text-davinci-003":          "gpt-3.5-turbo-instruct",
+		"code-davinci-002":          "gpt-3.5-turbo-instruct",
+	},
+	"anthropic": {
+		"claude-instant-1.2":         "claude-3-5-haiku-20241022",
+		"claude-2.0":                 "claude-sonnet-4-20250514",
+		"claude-2.1":                 "claude-sonnet-4-20250514",
+		"claude-3-sonnet-20240229":   "claude-sonnet-4-20250514",
+		"claude-3-5-sonnet-20240620": "claude-sonnet-4-20250514",
+		"claude-3-5-sonnet-20241022": "claude-sonnet-4-20250514",
+		"claude-3-opus-20240229":     "claude-opus-4-20250514",
+	},
+	"gemini": {
+		"gemini-pro":            "gemini-2.0-flash",
+		"gemini-pro-vision":     "gemini-2.0-flash",
+		"gemini-1.0-pro":        "gemini-2.0-flash",
+		"gemini-1.5-pro":        "gemini-2.5-pro",
+		"gemini-1.5-pro-001":    "gemini-2.5-pro",
+		"gemini-1.5-pro-002":    "gemini-2.5-pro",
+		"gemini-1.5-pro-latest": "gemini-2.5-pro",
+		"gemini-1.5-flash":      "gemini-2.5-flash",
+		"gemini-1.5-flash-001":  "gemini-2.5-flash",
+		"gemini-1.5-flash-002":  "gemini-2.5-flash",
+		"gemini-1.5-flash-8b":   "gemini-2.5-flash-lite",
+	},
+}
+
+// DeprecatedModelSuccessor 返回已弃用模型的替代模型，未弃用时返回 false
+// overrides 为部署方配置的补充条目，格式为逗号分隔的 old=new，优先于内置表；
+// old= 表示取消该模型的内置条目
+func DeprecatedModelSuccessor(channelType, model, overrides string) (string, bool) {
+	if successor, found := parseDeprecationOverrides(overrides)[model]; found {
+		return successor, successor != "" && successor != model
+	}
+	successor, ok := deprecatedModels[channelType][model]
+	return successor, ok
+}
+
+// parseDeprecationOverrides 解析 old=new 形式的补充条目，忽略格式错误的条目
+func parseDeprecationOverrides(overrides string) map[string]string {
+	overrides = strings.TrimSpace(overrides)
+	if overrides == "" {
+		return nil
+	}
+	result := make(map[string]string)
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		old, successor, ok := strings.Cut(entry, "=")
+		old = strings.TrimSpace(old)
+		if !ok || old == "" {
+			logrus.Warnf("Ignoring invalid model deprecation entry %q, expected old=new", entry)
+			continue
+		}
+		result[old] = strings.TrimSpace(successor)
+	}
+	return result
+}
@@ -109,6 +109,11 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		upstreamInfos = append(upstreamInfos, UpstreamInfo{URL: u, Weight: def.Weight})
 	}
 
+	hostOverride, err := httpclient.NewHostOverride(group.EffectiveConfig.UpstreamHostHeader, group.EffectiveConfig.UpstreamTLSServerName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream host override for %s channel: %w", name, err)
+	}
+
 	// Base configuration for regular requests, derived from the group's effective settings.
 	clientConfig := &httpclient.Config{
 		ConnectTimeout:        time.Duration(group.EffectiveConfig.ConnectTimeout) * time.Second,
@@ -124,6 +129,7 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		ForceAttemptHTTP2:     false,  // ⚡ 禁用HTTP/2，尝试解决流控问题
 		TLSHandshakeTimeout:   15 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
+		HostOverride:          hostOverride,
 	}
 
 	// Create a dedicated configuration for streaming requests.
@@ -6,12 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/jsonengine"
 	"gpt-load/internal/models"
 	"gpt-load/internal/utils"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -62,15 +64,8 @@ func (ch *GeminiChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool
 		return true
 	}
 
-	type streamPayload struct {
-		Stream bool `json:"stream"`
-	}
-	var p streamPayload
-	if err := json.Unmarshal(bodyBytes, &p); err == nil {
-		return p.Stream
-	}
-
-	return false
+	stream, _ := jsonengine.Get(bodyBytes, "stream")
+	return stream.Bool()
 }
 
 func (ch *GeminiChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
@@ -85,14 +80,9 @@ func (ch *GeminiChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
 	}
 
 	// openai format
-	type modelPayload struct {
-		Model string `json:"model"`
-	}
-	var p modelPayload
-	if err := json.Unmarshal(bodyBytes, &p); err == nil && p.Model != "" {
-		return p.Model
+	if model, ok := jsonengine.Get(bodyBytes, "model"); ok && model.Type == jsonengine.ValueString {
+		return model.String()
 	}
-
 	return ""
 }
 
@@ -134,7 +124,8 @@ func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 	// Apply custom header rules if available
 	if len(group.HeaderRuleList) > 0 {
 		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
-		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+		scope := models.RuleScope{Method: req.Method, Path: "/v1beta/models/" + ch.TestModel + ":generateContent"}
+		utils.ApplyHeaderRules(req, group.HeaderRulesFor(scope), headerCtx)
 	}
 
 	resp, err := ch.HTTPClient.Do(req)
@@ -373,3 +364,62 @@ func isFirstPage(req *http.Request) bool {
 	pageToken := req.URL.Query().Get("pageToken")
 	return pageToken == ""
 }
+
+const (
+	geminiCachedContentsSegment = "cachedContents"
+	// geminiDefaultCacheTTL is the TTL Gemini applies when a cache is created without one.
+	geminiDefaultCacheTTL = time.Hour
+)
+
+// ExtractCacheReference returns the cachedContents resource addressed by the path
+// (get/update/delete) or referenced by a generateContent or countTokens body.
+func (ch *GeminiChannel) ExtractCacheReference(c *gin.Context, bodyBytes []byte) *CacheReference {
+	parts := strings.Split(c.Request.URL.Path, "/")
+	for i, part := range parts {
+		if part == geminiCachedContentsSegment && i+1 < len(parts) && parts[i+1] != "" {
+			return &CacheReference{ID: geminiCachedContentsSegment + "/" + parts[i+1]}
+		}
+	}
+
+	type cachePayload struct {
+		CachedContent          string `json:"cachedContent"`
+		GenerateContentRequest struct {
+			CachedContent string `json:"cachedContent"`
+		} `json:"generateContentRequest"`
+	}
+	var p cachePayload
+	if err := json.Unmarshal(bodyBytes, &p); err != nil {
+		return nil
+	}
+	if p.CachedContent != "" {
+		return &CacheReference{ID: p.CachedContent}
+	}
+	if p.GenerateContentRequest.CachedContent != "" {
+		return &CacheReference{ID: p.GenerateContentRequest.CachedContent}
+	}
+	return nil
+}
+
+// IsCacheCreateRequest checks for POST .../cachedContents.
+func (ch *GeminiChannel) IsCacheCreateRequest(c *gin.Context) bool {
+	return c.Request.Method == http.MethodPost && strings.HasSuffix(c.Request.URL.Path, "/"+geminiCachedContentsSegment)
+}
+
+// ParseCreatedCache reads the name and expireTime of a created cachedContents resource.
+func (ch *GeminiChannel) ParseCreatedCache(respBody []byte) *CacheReference {
+	var p struct {
+		Name       string `json:"name"`
+		ExpireTime string `json:"expireTime"`
+	}
+	if err := json.Unmarshal(respBody, &p); err != nil || p.Name == "" {
+		return nil
+	}
+
+	ttl := geminiDefaultCacheTTL
+	if expireAt, err := time.Parse(time.RFC3339Nano, p.ExpireTime); err == nil {
+		if remaining := time.Until(expireAt); remaining > 0 {
+			ttl = remaining
+		}
+	}
+	return &CacheReference{ID: p.Name, TTL: ttl}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/jsonengine"
 	"gpt-load/internal/models"
 	"gpt-load/internal/utils"
 	"io"
@@ -50,24 +51,13 @@ func (ch *OpenAIChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool
 		return true
 	}
 
-	type streamPayload struct {
-		Stream bool `json:"stream"`
-	}
-	var p streamPayload
-	if err := json.Unmarshal(bodyBytes, &p); err == nil {
-		return p.Stream
-	}
-
-	return false
+	stream, _ := jsonengine.Get(bodyBytes, "stream")
+	return stream.Bool()
 }
 
 func (ch *OpenAIChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
-	type modelPayload struct {
-		Model string `json:"model"`
-	}
-	var p modelPayload
-	if err := json.Unmarshal(bodyBytes, &p); err == nil {
-		return p.Model
+	if model, ok := jsonengine.Get(bodyBytes, "model"); ok && model.Type == jsonengine.ValueString {
+		return model.String()
 	}
 	return ""
 }
@@ -113,7 +103,8 @@ func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 	// Apply custom header rules if available
 	if len(group.HeaderRuleList) > 0 {
 		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
-		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+		scope := models.RuleScope{Method: req.Method, Path: endpointURL.Path}
+		utils.ApplyHeaderRules(req, group.HeaderRulesFor(scope), headerCtx)
 	}
 
 	resp, err := ch.HTTPClient.Do(req)
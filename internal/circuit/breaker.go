@@ -0,0 +1,225 @@
+// Package circuit implements rolling-window circuit breakers used to route requests
+// away from failing upstream keys and hosts.
+package circuit
+
+import (
+	"sync"
+	"time"
+
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/types"
+)
+
+// State is the state of a circuit breaker.
+type State string
+
+const (
+	StateClosed   State = "closed"    // requests flow normally
+	StateOpen     State = "open"      // requests are routed elsewhere
+	StateHalfOpen State = "half_open" // a single probe request decides whether to close
+)
+
+// windowBuckets is the number of buckets the rolling window is divided into.
+const windowBuckets = 10
+
+var (
+	breakersOpen = metrics.NewGauge(
+		"gpt_load_circuit_breakers_open",
+		"Circuit breakers currently open or half-open, by scope (key, upstream).",
+		"scope",
+	)
+	breakerTransitionsTotal = metrics.NewCounter(
+		"gpt_load_circuit_breaker_transitions_total",
+		"Circuit breaker state changes, by scope and new state.",
+		"scope", "state",
+	)
+)
+
+// Settings configure the breakers of a group. A zero FailurePercent disables them.
+type Settings struct {
+	FailurePercent int           // failure rate that opens the breaker
+	MinRequests    int           // requests in the window before the failure rate is judged
+	Window         time.Duration // length of the rolling window
+	OpenDuration   time.Duration // time an open breaker waits before letting a probe through
+}
+
+// SettingsFrom returns the breaker settings of a group configuration.
+func SettingsFrom(cfg *types.SystemSettings) Settings {
+	return Settings{
+		FailurePercent: cfg.CircuitBreakerFailurePercent,
+		MinRequests:    cfg.CircuitBreakerMinRequests,
+		Window:         time.Duration(cfg.CircuitBreakerWindowSeconds) * time.Second,
+		OpenDuration:   time.Duration(cfg.CircuitBreakerOpenSeconds) * time.Second,
+	}
+}
+
+// Enabled reports whether breakers are enabled.
+func (s Settings) Enabled() bool {
+	return s.FailurePercent > 0 && s.Window > 0
+}
+
+type bucket struct {
+	epoch    int64
+	total    int
+	failures int
+}
+
+type breaker struct {
+	state    State
+	openedAt time.Time
+	probeAt  time.Time // start of the in-flight half-open probe, zero when there is none
+	buckets  [windowBuckets]bucket
+}
+
+// Registry holds the breakers of one scope, keyed by an ID such as a key ID or host.
+type Registry struct {
+	scope    string
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// NewRegistry creates a registry; scope labels its metrics.
+func NewRegistry(scope string) *Registry {
+	return &Registry{scope: scope, breakers: make(map[string]*breaker)}
+}
+
+// Ready reports whether a request could be sent to id now, without claiming the half-open probe.
+func (r *Registry) Ready(id string, s Settings) bool {
+	if !s.Enabled() {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.breakers[id]
+	return b == nil || b.ready(time.Now(), s)
+}
+
+// Allow reports whether a request may be sent to id. An open breaker turns half-open once
+// OpenDuration has passed and then lets one probe through at a time; a probe that never
+// reports back is replaced after another OpenDuration.
+func (r *Registry) Allow(id string, s Settings) bool {
+	if !s.Enabled() {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.breakers[id]
+	if b == nil || b.state == StateClosed {
+		return true
+	}
+
+	now := time.Now()
+	if !b.ready(now, s) {
+		return false
+	}
+	if b.state == StateOpen {
+		r.transition(b, StateHalfOpen)
+	}
+	b.probeAt = now
+	return true
+}
+
+// Record reports the outcome of a request sent to id. The breaker opens when the failure
+// rate in the window reaches FailurePercent; a half-open breaker closes on a successful
+// probe and opens again on a failed one.
+func (r *Registry) Record(id string, s Settings, success bool) {
+	if !s.Enabled() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.breakers[id]
+	if b == nil {
+		b = &breaker{state: StateClosed}
+		r.breakers[id] = b
+	}
+
+	now := time.Now()
+	switch b.state {
+	case StateHalfOpen:
+		b.probeAt = time.Time{}
+		if success {
+			r.transition(b, StateClosed)
+			b.buckets = [windowBuckets]bucket{}
+		} else {
+			r.transition(b, StateOpen)
+			b.openedAt = now
+		}
+		return
+	case StateOpen:
+		// Late results of requests sent before the breaker opened
+		return
+	}
+
+	total, failures := b.add(now, s.Window, success)
+	if total >= max(s.MinRequests, 1) && failures*100 >= s.FailurePercent*total {
+		r.transition(b, StateOpen)
+		b.openedAt = now
+	}
+}
+
+// State returns the state of the breaker for id.
+func (r *Registry) State(id string) State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b := r.breakers[id]; b != nil {
+		return b.state
+	}
+	return StateClosed
+}
+
+// Reset closes the breaker for id, e.g. after a key was restored by hand.
+func (r *Registry) Reset(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b := r.breakers[id]; b != nil {
+		r.transition(b, StateClosed)
+		delete(r.breakers, id)
+	}
+}
+
+// transition must be called with r.mu held.
+func (r *Registry) transition(b *breaker, state State) {
+	if b.state == state {
+		return
+	}
+	if b.state == StateClosed {
+		breakersOpen.Add(1, r.scope)
+	} else if state == StateClosed {
+		breakersOpen.Add(-1, r.scope)
+	}
+	b.state = state
+	breakerTransitionsTotal.Inc(r.scope, string(state))
+}
+
+func (b *breaker) ready(now time.Time, s Settings) bool {
+	switch b.state {
+	case StateOpen:
+		return now.Sub(b.openedAt) >= s.OpenDuration
+	case StateHalfOpen:
+		return b.probeAt.IsZero() || now.Sub(b.probeAt) >= s.OpenDuration
+	}
+	return true
+}
+
+// add counts a request in the current bucket and returns the totals of the window.
+func (b *breaker) add(now time.Time, window time.Duration, success bool) (total, failures int) {
+	width := max(int64(window)/windowBuckets, 1)
+	epoch := now.UnixNano() / width
+	slot := &b.buckets[epoch%windowBuckets]
+	if slot.epoch != epoch {
+		*slot = bucket{epoch: epoch}
+	}
+	slot.total++
+	if !success {
+		slot.failures++
+	}
+
+	for _, bk := range b.buckets {
+		if bk.epoch > epoch-windowBuckets {
+			total += bk.total
+			failures += bk.failures
+		}
+	}
+	return total, failures
+}
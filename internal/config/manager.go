@@ -48,6 +48,8 @@ type Config struct {
 	Performance   types.PerformanceConfig
 	Log           types.LogConfig
 	Database      types.DatabaseConfig
+	SMTP          types.SMTPConfig
+	Plugin        types.PluginConfig
 	RedisDSN      string
 	EncryptionKey string
 }
@@ -74,13 +76,16 @@ func (m *Manager) ReloadConfig() error {
 			IsMaster:                !utils.ParseBoolean(os.Getenv("IS_SLAVE"), false),
 			Port:                    utils.ParseInteger(os.Getenv("PORT"), 3001),
 			Host:                    utils.GetEnvOrDefault("HOST", "0.0.0.0"),
+			Region:                  os.Getenv("REGION"),
 			ReadTimeout:             utils.ParseInteger(os.Getenv("SERVER_READ_TIMEOUT"), 60),
 			WriteTimeout:            utils.ParseInteger(os.Getenv("SERVER_WRITE_TIMEOUT"), 600),
 			IdleTimeout:             utils.ParseInteger(os.Getenv("SERVER_IDLE_TIMEOUT"), 120),
 			GracefulShutdownTimeout: utils.ParseInteger(os.Getenv("SERVER_GRACEFUL_SHUTDOWN_TIMEOUT"), 10),
+			StartupWarmup:           utils.ParseBoolean(os.Getenv("SERVER_STARTUP_WARMUP"), false),
 		},
 		Auth: types.AuthConfig{
-			Key: os.Getenv("AUTH_KEY"),
+			Key:           os.Getenv("AUTH_KEY"),
+			WebhookSecret: os.Getenv("KEY_WEBHOOK_SECRET"),
 		},
 		CORS: types.CORSConfig{
 			Enabled:          utils.ParseBoolean(os.Getenv("ENABLE_CORS"), false),
@@ -101,6 +106,18 @@ func (m *Manager) ReloadConfig() error {
 		Database: types.DatabaseConfig{
 			DSN: utils.GetEnvOrDefault("DATABASE_DSN", "./data/gpt-load.db"),
 		},
+		SMTP: types.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     utils.ParseInteger(os.Getenv("SMTP_PORT"), 587),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		},
+		Plugin: types.PluginConfig{
+			Dir:           os.Getenv("WASM_PLUGIN_DIR"),
+			TimeoutMs:     utils.ParseInteger(os.Getenv("WASM_PLUGIN_TIMEOUT_MS"), 100),
+			MemoryLimitMB: utils.ParseInteger(os.Getenv("WASM_PLUGIN_MEMORY_MB"), 64),
+		},
 		RedisDSN:      os.Getenv("REDIS_DSN"),
 		EncryptionKey: os.Getenv("ENCRYPTION_KEY"),
 	}
@@ -149,6 +166,16 @@ func (m *Manager) GetDatabaseConfig() types.DatabaseConfig {
 	return m.config.Database
 }
 
+// GetSMTPConfig returns the mail server configuration.
+func (m *Manager) GetSMTPConfig() types.SMTPConfig {
+	return m.config.SMTP
+}
+
+// GetPluginConfig returns the WASM plugin configuration.
+func (m *Manager) GetPluginConfig() types.PluginConfig {
+	return m.config.Plugin
+}
+
 // GetEncryptionKey returns the encryption key.
 func (m *Manager) GetEncryptionKey() string {
 	return m.config.EncryptionKey
@@ -172,6 +199,15 @@ func (m *Manager) Validate() error {
 		validationErrors = append(validationErrors, "max concurrent requests cannot be less than 1")
 	}
 
+	if m.config.Plugin.Dir != "" {
+		if m.config.Plugin.TimeoutMs < 1 {
+			validationErrors = append(validationErrors, "WASM_PLUGIN_TIMEOUT_MS must be at least 1")
+		}
+		if m.config.Plugin.MemoryLimitMB < 1 || m.config.Plugin.MemoryLimitMB > 4096 {
+			validationErrors = append(validationErrors, "WASM_PLUGIN_MEMORY_MB must be between 1-4096")
+		}
+	}
+
 	// Validate auth key
 	if m.config.Auth.Key == "" {
 		validationErrors = append(validationErrors, "AUTH_KEY is required and cannot be empty")
@@ -179,6 +215,10 @@ func (m *Manager) Validate() error {
 		utils.ValidatePasswordStrength(m.config.Auth.Key, "AUTH_KEY")
 	}
 
+	if m.config.Auth.WebhookSecret != "" {
+		utils.ValidatePasswordStrength(m.config.Auth.WebhookSecret, "KEY_WEBHOOK_SECRET")
+	}
+
 	// Validate GracefulShutdownTimeout and reset if necessary
 	if m.config.Server.GracefulShutdownTimeout < 10 {
 		logrus.Warnf("SERVER_GRACEFUL_SHUTDOWN_TIMEOUT value %ds is too short, resetting to minimum 10s.", m.config.Server.GracefulShutdownTimeout)
@@ -218,16 +258,25 @@ func (m *Manager) DisplayServerConfig() {
 	logrus.Info("======= Server Configuration =======")
 	logrus.Info("  --- Server ---")
 	logrus.Infof("    Listen Address: %s:%d", serverConfig.Host, serverConfig.Port)
+	if serverConfig.Region != "" {
+		logrus.Infof("    Region: %s", serverConfig.Region)
+	}
 	logrus.Infof("    Graceful Shutdown Timeout: %d seconds", serverConfig.GracefulShutdownTimeout)
 	logrus.Infof("    Read Timeout: %d seconds", serverConfig.ReadTimeout)
 	logrus.Infof("    Write Timeout: %d seconds", serverConfig.WriteTimeout)
 	logrus.Infof("    Idle Timeout: %d seconds", serverConfig.IdleTimeout)
+	logrus.Infof("    Startup Warmup: %t", serverConfig.StartupWarmup)
 
 	logrus.Info("  --- Performance ---")
 	logrus.Infof("    Max Concurrent Requests: %d", perfConfig.MaxConcurrentRequests)
 
 	logrus.Info("  --- Security ---")
 	logrus.Infof("    Authentication: enabled (key loaded)")
+	if m.config.Auth.WebhookSecret != "" {
+		logrus.Info("    Key Webhook: enabled")
+	} else {
+		logrus.Info("    Key Webhook: disabled")
+	}
 	if encryptionKey != "" {
 		logrus.Info("    Encryption: enabled")
 	} else {
@@ -258,6 +307,14 @@ func (m *Manager) DisplayServerConfig() {
 	} else {
 		logrus.Info("    Redis: not configured")
 	}
+	if m.config.SMTP.Host != "" {
+		logrus.Infof("    SMTP: %s:%d", m.config.SMTP.Host, m.config.SMTP.Port)
+	} else {
+		logrus.Info("    SMTP: not configured")
+	}
+	if m.config.Plugin.Dir != "" {
+		logrus.Infof("    WASM Plugins: %s (timeout %dms, memory %dMB)", m.config.Plugin.Dir, m.config.Plugin.TimeoutMs, m.config.Plugin.MemoryLimitMB)
+	}
 	logrus.Info("====================================")
 	logrus.Info("")
 }
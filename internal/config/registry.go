@@ -0,0 +1,237 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SettingDef 系统配置项的定义，由 types.SystemSettings 的结构体标签注册：
+//   - json：配置键；default：默认值；name、desc、category：界面显示的 i18n 键
+//   - validate：required、min=N、max=N、oneof=a b c
+//   - reload:"restart"：修改后需重启服务才生效，未设置时修改即时生效
+//     目前的配置项都在读取时或经 OnSettingChange 回调即时生效，均未设置该标签；
+//     端口、数据库、Redis 等启动配置来自环境变量，不在此注册
+//
+// models.GroupConfig 中有同名键的配置项可由分组覆盖，两处字段类型必须一致。
+type SettingDef struct {
+	Key           string
+	Type          string // int、bool 或 string
+	Default       string
+	Name          string
+	Description   string
+	Category      string
+	Required      bool
+	Min           *int
+	Max           *int
+	Options       []string // oneof 允许的取值
+	HotReload     bool
+	GroupOverride bool
+
+	field int // types.SystemSettings 中的字段下标
+}
+
+// settingRegistry 按结构体字段顺序注册的配置项
+type settingRegistry struct {
+	defs  []SettingDef
+	byKey map[string]int
+}
+
+var registry = sync.OnceValue(buildSettingRegistry)
+
+// buildSettingRegistry 从结构体标签构建配置项定义，标签与字段类型不一致属于代码错误，直接 panic
+func buildSettingRegistry() *settingRegistry {
+	groupFields := make(map[string]reflect.Type)
+	gt := reflect.TypeFor[models.GroupConfig]()
+	for i := range gt.NumField() {
+		field := gt.Field(i)
+		if key := strings.Split(field.Tag.Get("json"), ",")[0]; key != "" && key != "-" {
+			groupFields[key] = field.Type
+		}
+	}
+
+	r := &settingRegistry{byKey: make(map[string]int)}
+	t := reflect.TypeFor[types.SystemSettings]()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+
+		def := SettingDef{
+			Key:         key,
+			Type:        field.Type.Kind().String(),
+			Default:     field.Tag.Get("default"),
+			Name:        field.Tag.Get("name"),
+			Description: field.Tag.Get("desc"),
+			Category:    field.Tag.Get("category"),
+			HotReload:   field.Tag.Get("reload") != "restart",
+			field:       i,
+		}
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Bool, reflect.String:
+		default:
+			panic(fmt.Sprintf("setting %s has unsupported type %s", key, field.Type))
+		}
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			rule = strings.TrimSpace(rule)
+			switch {
+			case rule == "":
+			case rule == "required":
+				def.Required = true
+			case strings.HasPrefix(rule, "min="), strings.HasPrefix(rule, "max="):
+				n, err := strconv.Atoi(rule[4:])
+				if err != nil || def.Type != "int" {
+					panic(fmt.Sprintf("setting %s has invalid rule %q", key, rule))
+				}
+				if rule[:3] == "min" {
+					def.Min = &n
+				} else {
+					def.Max = &n
+				}
+			case strings.HasPrefix(rule, "oneof="):
+				def.Options = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+			default:
+				panic(fmt.Sprintf("setting %s has unknown rule %q", key, rule))
+			}
+		}
+
+		if groupType, ok := groupFields[key]; ok {
+			if groupType.Kind() != reflect.Ptr || groupType.Elem() != field.Type {
+				panic(fmt.Sprintf("group override %s has type %s, setting has %s", key, groupType, field.Type))
+			}
+			def.GroupOverride = true
+		}
+
+		r.byKey[key] = len(r.defs)
+		r.defs = append(r.defs, def)
+	}
+	return r
+}
+
+// SettingDefs 返回所有配置项的定义，顺序与 types.SystemSettings 的字段一致
+func SettingDefs() []SettingDef {
+	return slices.Clone(registry().defs)
+}
+
+// LookupSetting 按配置键查找定义
+func LookupSetting(key string) (SettingDef, bool) {
+	i, ok := registry().byKey[key]
+	if !ok {
+		return SettingDef{}, false
+	}
+	return registry().defs[i], true
+}
+
+// Value 返回 settings 中该配置项的值
+func (d SettingDef) Value(settings types.SystemSettings) any {
+	return reflect.ValueOf(settings).Field(d.field).Interface()
+}
+
+// Validate 检查 API 提交的值（JSON 解码后的类型）是否符合定义，返回按配置项类型转换后的值
+func (d SettingDef) Validate(value any) (any, error) {
+	typed, err := d.convert(value)
+	if err != nil {
+		return nil, err
+	}
+	switch v := typed.(type) {
+	case int:
+		if d.Min != nil && v < *d.Min {
+			return nil, fmt.Errorf("value for %s (%d) is below minimum value (%d)", d.Key, v, *d.Min)
+		}
+		if d.Max != nil && v > *d.Max {
+			return nil, fmt.Errorf("value for %s (%d) is above maximum value (%d)", d.Key, v, *d.Max)
+		}
+	case string:
+		if d.Required && v == "" {
+			return nil, fmt.Errorf("value for %s is required", d.Key)
+		}
+		if len(d.Options) > 0 && !slices.Contains(d.Options, v) {
+			return nil, fmt.Errorf("value for %s must be one of: %s", d.Key, strings.Join(d.Options, ", "))
+		}
+	}
+	return typed, nil
+}
+
+// convert 把 JSON 解码得到的值转换为配置项类型，数字可以是 float64、json.Number 或整数类型
+func (d SettingDef) convert(value any) (any, error) {
+	switch d.Type {
+	case "int":
+		var f float64
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case json.Number:
+			parsed, err := v.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("invalid type for %s: expected a number, got %q", d.Key, v)
+			}
+			f = parsed
+		case int:
+			return v, nil
+		case int64:
+			f = float64(v)
+		default:
+			return nil, fmt.Errorf("invalid type for %s: expected a number, got %T", d.Key, value)
+		}
+		if f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, fmt.Errorf("invalid value for %s: must be an integer", d.Key)
+		}
+		return int(f), nil
+	case "bool":
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid type for %s: expected a boolean, got %T", d.Key, value)
+		}
+		return v, nil
+	default:
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid type for %s: expected a string, got %T", d.Key, value)
+		}
+		return v, nil
+	}
+}
+
+// set 把已转换的值写入 settings
+func (d SettingDef) set(settings *types.SystemSettings, value any) {
+	reflect.ValueOf(settings).Elem().Field(d.field).Set(reflect.ValueOf(value))
+}
+
+// Info 返回配置项在 settings 中的当前值及其定义，用于设置 API
+func (d SettingDef) Info(settings types.SystemSettings) models.SystemSettingInfo {
+	return models.SystemSettingInfo{
+		Key:           d.Key,
+		Name:          d.Name,
+		Value:         d.Value(settings),
+		Type:          d.Type,
+		DefaultValue:  d.Default,
+		Description:   d.Description,
+		Category:      d.Category,
+		MinValue:      d.Min,
+		MaxValue:      d.Max,
+		Options:       d.Options,
+		Required:      d.Required,
+		HotReload:     d.HotReload,
+		GroupOverride: d.GroupOverride,
+	}
+}
+
+// SettingsMetadata 返回所有配置项的定义和在 settings 中的当前值
+func SettingsMetadata(settings types.SystemSettings) []models.SystemSettingInfo {
+	defs := registry().defs
+	infos := make([]models.SystemSettingInfo, len(defs))
+	for i, def := range defs {
+		infos[i] = def.Info(settings)
+	}
+	return infos
+}
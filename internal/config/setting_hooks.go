@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"gpt-load/internal/types"
+	"reflect"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// settingHook 单个配置项的变更回调
+type settingHook struct {
+	key        string
+	fieldIndex int
+	call       func(oldSettings, newSettings types.SystemSettings)
+}
+
+// settingHooks 已注册的回调及上一次加载的配置，用于比较变更
+type settingHooks struct {
+	mu       sync.Mutex
+	hooks    []settingHook
+	previous types.SystemSettings
+	loaded   bool
+}
+
+// OnSettingChange 为 json 键为 key 的配置项注册变更回调，T 必须与字段类型一致
+// 每个实例在重新加载配置后比较新旧值，值变化时以新旧值调用 hook，
+// 因此通过 UpdateSettings 修改的配置会经 syncer 同步到所有实例并即时生效。
+// 首次加载不触发回调；hook 在 syncer 的 goroutine 中同步执行，不应阻塞。
+func OnSettingChange[T any](sm *SystemSettingsManager, key string, hook func(oldValue, newValue T)) error {
+	def, ok := LookupSetting(key)
+	if !ok {
+		return fmt.Errorf("invalid setting key: %s", key)
+	}
+	index := def.field
+	if field, want := reflect.TypeFor[types.SystemSettings]().Field(index), reflect.TypeFor[T](); field.Type != want {
+		return fmt.Errorf("setting %s has type %s, hook expects %s", key, field.Type, want)
+	}
+
+	sm.hooks.mu.Lock()
+	defer sm.hooks.mu.Unlock()
+	sm.hooks.hooks = append(sm.hooks.hooks, settingHook{
+		key:        key,
+		fieldIndex: index,
+		call: func(oldSettings, newSettings types.SystemSettings) {
+			oldValue := reflect.ValueOf(oldSettings).Field(index).Interface().(T)
+			newValue := reflect.ValueOf(newSettings).Field(index).Interface().(T)
+			hook(oldValue, newValue)
+		},
+	})
+	return nil
+}
+
+// dispatch 比较新旧配置并触发值发生变化的配置项回调
+func (h *settingHooks) dispatch(newSettings types.SystemSettings) {
+	h.mu.Lock()
+	oldSettings, loaded := h.previous, h.loaded
+	h.previous, h.loaded = newSettings, true
+	hooks := h.hooks
+	h.mu.Unlock()
+
+	if !loaded {
+		return
+	}
+
+	oldValue := reflect.ValueOf(oldSettings)
+	newValue := reflect.ValueOf(newSettings)
+	for _, hook := range hooks {
+		if reflect.DeepEqual(oldValue.Field(hook.fieldIndex).Interface(), newValue.Field(hook.fieldIndex).Interface()) {
+			continue
+		}
+		logrus.WithField("setting", hook.key).Debug("Applying setting change hook")
+		hook.call(oldSettings, newSettings)
+	}
+}
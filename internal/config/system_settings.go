@@ -2,7 +2,6 @@ package config
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"gpt-load/internal/db"
 	"gpt-load/internal/models"
@@ -12,7 +11,6 @@ import (
 	"gpt-load/internal/utils"
 	"os"
 	"reflect"
-	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -25,6 +23,7 @@ const SettingsUpdateChannel = "system_settings:updated"
 // SystemSettingsManager 管理系统配置
 type SystemSettingsManager struct {
 	syncer *syncer.CacheSyncer[types.SystemSettings]
+	hooks  settingHooks
 }
 
 // NewSystemSettingsManager creates a new, uninitialized SystemSettingsManager.
@@ -74,6 +73,8 @@ func (sm *SystemSettingsManager) Initialize(store store.Store, gm groupManager,
 		}
 
 		settings.ProxyKeysMap = utils.StringToSet(settings.ProxyKeys, ",")
+		settings.BatchProxyKeysMap = utils.StringToSet(settings.BatchProxyKeys, ",")
+		settings.ProxyKeyHeaderList = utils.SplitAndTrim(settings.ProxyKeyHeaders, ",")
 
 		sm.DisplaySystemConfig(settings)
 
@@ -81,6 +82,8 @@ func (sm *SystemSettingsManager) Initialize(store store.Store, gm groupManager,
 	}
 
 	afterLoader := func(newData types.SystemSettings) {
+		sm.hooks.dispatch(newData)
+
 		if !isMaster {
 			return
 		}
@@ -111,14 +114,11 @@ func (sm *SystemSettingsManager) Stop(ctx context.Context) {
 
 // EnsureSettingsInitialized 确保数据库中存在所有系统设置的记录。
 func (sm *SystemSettingsManager) EnsureSettingsInitialized(authConfig types.AuthConfig) error {
-	defaultSettings := utils.DefaultSystemSettings()
-	metadata := utils.GenerateSettingsMetadata(&defaultSettings)
-
-	for _, meta := range metadata {
+	for _, meta := range SettingDefs() {
 		var existing models.SystemSetting
 		err := db.DB.Where("setting_key = ?", meta.Key).First(&existing).Error
 		if err != nil {
-			value := fmt.Sprintf("%v", meta.DefaultValue)
+			value := meta.Default
 			if meta.Key == "app_url" {
 				host := os.Getenv("HOST")
 				if host == "" || host == "0.0.0.0" {
@@ -180,17 +180,20 @@ func (sm *SystemSettingsManager) GetAppUrl() string {
 
 // UpdateSettings 更新系统配置
 func (sm *SystemSettingsManager) UpdateSettings(settingsMap map[string]any) error {
-	// 验证配置项
-	if err := sm.ValidateSettings(settingsMap); err != nil {
-		return err
-	}
-
-	// 更新数据库
+	// 验证配置项，按转换后的类型保存，避免大数字被格式化为科学计数法
 	var settingsToUpdate []models.SystemSetting
 	for key, value := range settingsMap {
+		def, ok := LookupSetting(key)
+		if !ok {
+			return fmt.Errorf("invalid setting key: %s", key)
+		}
+		typed, err := def.Validate(value)
+		if err != nil {
+			return err
+		}
 		settingsToUpdate = append(settingsToUpdate, models.SystemSetting{
 			SettingKey:   key,
-			SettingValue: fmt.Sprintf("%v", value), // Convert any to string
+			SettingValue: fmt.Sprintf("%v", typed),
 		})
 	}
 
@@ -208,38 +211,25 @@ func (sm *SystemSettingsManager) UpdateSettings(settingsMap map[string]any) erro
 }
 
 // GetEffectiveConfig 获取有效配置 (系统配置 + 分组覆盖)
+// 分组覆盖按配置项定义转换类型，无法识别或类型不符的项记录警告后忽略，不影响其他项
 func (sm *SystemSettingsManager) GetEffectiveConfig(groupConfigJSON datatypes.JSONMap) types.SystemSettings {
 	effectiveConfig := sm.GetSettings()
 
-	if groupConfigJSON == nil {
-		return effectiveConfig
-	}
-
-	var groupConfig models.GroupConfig
-	groupConfigBytes, err := groupConfigJSON.MarshalJSON()
-	if err != nil {
-		logrus.Warnf("Failed to marshal group config JSON, using system settings only. Error: %v", err)
-		return effectiveConfig
-	}
-	if err := json.Unmarshal(groupConfigBytes, &groupConfig); err != nil {
-		logrus.Warnf("Failed to unmarshal group config, using system settings only. Error: %v", err)
-		return effectiveConfig
-	}
-
-	gcv := reflect.ValueOf(groupConfig)
-	ecv := reflect.ValueOf(&effectiveConfig).Elem()
-
-	for i := range gcv.NumField() {
-		groupField := gcv.Field(i)
-		if groupField.Kind() == reflect.Ptr && !groupField.IsNil() {
-			groupFieldValue := groupField.Elem()
-			effectiveField := ecv.FieldByName(gcv.Type().Field(i).Name)
-			if effectiveField.IsValid() && effectiveField.CanSet() {
-				if effectiveField.Type() == groupFieldValue.Type() {
-					effectiveField.Set(groupFieldValue)
-				}
-			}
+	for key, value := range groupConfigJSON {
+		if value == nil {
+			continue
 		}
+		def, ok := LookupSetting(key)
+		if !ok || !def.GroupOverride {
+			logrus.Warnf("Ignoring unknown group config override %q", key)
+			continue
+		}
+		typed, err := def.convert(value)
+		if err != nil {
+			logrus.Warnf("Ignoring group config override: %v", err)
+			continue
+		}
+		def.set(&effectiveConfig, typed)
 	}
 
 	return effectiveConfig
@@ -247,146 +237,36 @@ func (sm *SystemSettingsManager) GetEffectiveConfig(groupConfigJSON datatypes.JS
 
 // ValidateSettings 验证系统配置的有效性
 func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) error {
-	tempSettings := utils.DefaultSystemSettings()
-	v := reflect.ValueOf(&tempSettings).Elem()
-	t := v.Type()
-	jsonToField := make(map[string]reflect.StructField)
-	for i := range t.NumField() {
-		field := t.Field(i)
-		jsonTag := strings.Split(field.Tag.Get("json"), ",")[0]
-		if jsonTag != "" {
-			jsonToField[jsonTag] = field
-		}
-	}
-
 	for key, value := range settingsMap {
-		field, ok := jsonToField[key]
+		def, ok := LookupSetting(key)
 		if !ok {
 			return fmt.Errorf("invalid setting key: %s", key)
 		}
-
-		validateTag := field.Tag.Get("validate")
-		rules := strings.Split(validateTag, ",")
-
-		switch field.Type.Kind() {
-		case reflect.Int:
-			floatVal, ok := value.(float64)
-			if !ok {
-				return fmt.Errorf("invalid type for %s: expected a number, got %T", key, value)
-			}
-			intVal := int(floatVal)
-			if floatVal != float64(intVal) {
-				return fmt.Errorf("invalid value for %s: must be an integer", key)
-			}
-
-			// The 'required' check is implicitly handled by the type assertion above.
-			for _, rule := range rules {
-				trimmedRule := strings.TrimSpace(rule)
-				if strings.HasPrefix(trimmedRule, "min=") {
-					minValStr := strings.TrimPrefix(trimmedRule, "min=")
-					minVal, _ := strconv.Atoi(minValStr)
-					if intVal < minVal {
-						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
-					}
-				}
-			}
-		case reflect.Bool:
-			if _, ok := value.(bool); !ok {
-				return fmt.Errorf("invalid type for %s: expected a boolean, got %T", key, value)
-			}
-		case reflect.String:
-			strVal, ok := value.(string)
-			if !ok {
-				return fmt.Errorf("invalid type for %s: expected a string, got %T", key, value)
-			}
-			for _, rule := range rules {
-				trimmedRule := strings.TrimSpace(rule)
-				if trimmedRule == "required" {
-					if strVal == "" {
-						return fmt.Errorf("value for %s is required", key)
-					}
-				}
-			}
-		default:
-			return fmt.Errorf("unsupported type for setting key validation: %s", key)
+		if _, err := def.Validate(value); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
 // ValidateGroupConfigOverrides validates a map of group-level configuration overrides.
+// A nil value clears the override and is always accepted.
 func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[string]any) error {
-	tempSettings := types.SystemSettings{}
-	v := reflect.ValueOf(&tempSettings).Elem()
-	t := v.Type()
-	jsonToField := make(map[string]reflect.StructField)
-	for i := range t.NumField() {
-		field := t.Field(i)
-		jsonTag := strings.Split(field.Tag.Get("json"), ",")[0]
-		if jsonTag != "" {
-			jsonToField[jsonTag] = field
-		}
-	}
-
 	for key, value := range configMap {
 		if value == nil {
 			continue
 		}
-
-		field, ok := jsonToField[key]
+		def, ok := LookupSetting(key)
 		if !ok {
 			return fmt.Errorf("invalid setting key: %s", key)
 		}
-
-		validateTag := field.Tag.Get("validate")
-		rules := strings.Split(validateTag, ",")
-
-		switch field.Type.Kind() {
-		case reflect.Int:
-			floatVal, ok := value.(float64)
-			if !ok {
-				continue
-			}
-			intVal := int(floatVal)
-			if floatVal != float64(intVal) {
-				return fmt.Errorf("invalid value for %s: must be an integer", key)
-			}
-
-			// The 'required' check is implicitly handled by the type assertion above.
-			for _, rule := range rules {
-				trimmedRule := strings.TrimSpace(rule)
-				if strings.HasPrefix(trimmedRule, "min=") {
-					minValStr := strings.TrimPrefix(trimmedRule, "min=")
-					minVal, _ := strconv.Atoi(minValStr)
-					if intVal < minVal {
-						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
-					}
-				}
-			}
-		case reflect.String:
-			strVal, ok := value.(string)
-			if !ok {
-				continue
-			}
-			for _, rule := range rules {
-				trimmedRule := strings.TrimSpace(rule)
-				if trimmedRule == "required" {
-					if strVal == "" {
-						return fmt.Errorf("value for %s is required", key)
-					}
-				}
-			}
-		case reflect.Bool:
-			_, ok := value.(bool)
-			if !ok {
-				return fmt.Errorf("invalid type for %s: expected boolean, got %T", key, value)
-			}
-		default:
-			// Do not validate other types for group overrides
+		if !def.GroupOverride {
+			return fmt.Errorf("setting %s cannot be overridden by a group", key)
+		}
+		if _, err := def.Validate(value); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
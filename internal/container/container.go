@@ -15,6 +15,7 @@ import (
 	"gpt-load/internal/services"
 	"gpt-load/internal/store"
 	"gpt-load/internal/types"
+	"gpt-load/internal/wasmplugin"
 
 	"go.uber.org/dig"
 )
@@ -47,6 +48,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(channel.NewFactory); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(wasmplugin.NewHost); err != nil {
+		return nil, err
+	}
 
 	// Business Services
 	if err := container.Provide(services.NewTaskService); err != nil {
@@ -64,6 +68,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewKeyDeleteService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewKeyWebhookService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewLogService); err != nil {
 		return nil, err
 	}
@@ -73,6 +80,33 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewRequestLogService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewAuditLogService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewProviderStatusService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewDailyReportService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewGroupDebugService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewBudgetService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewUsageService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewRuleMatchTracker); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewResponseCacheService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewRuleLintService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewSubGroupManager); err != nil {
 		return nil, err
 	}
@@ -41,8 +41,22 @@ func HandleLegacyIndexes(db *gorm.DB) {
 		if idxApiKeysGroupKeyCount > 0 {
 			db.Exec("ALTER TABLE api_keys DROP INDEX idx_api_keys_group_id_key_value")
 		}
+		var idxUsageHourDimsCount int64
+		db.Raw(`
+				SELECT COUNT(*)
+				FROM information_schema.STATISTICS
+				WHERE TABLE_SCHEMA = DATABASE()
+				AND TABLE_NAME = 'usage_hourly_stats'
+				AND INDEX_NAME = 'idx_usage_hour_dims'
+			`).Count(&idxUsageHourDimsCount)
+
+		if idxUsageHourDimsCount > 0 {
+			db.Exec("ALTER TABLE usage_hourly_stats DROP INDEX idx_usage_hour_dims")
+		}
 	} else {
 		db.Exec("DROP INDEX IF EXISTS idx_group_key")
 		db.Exec("DROP INDEX IF EXISTS idx_api_keys_group_id_key_value")
+		// 用量汇总增加代理密钥维度后，旧的唯一索引会使不同代理密钥的记录冲突
+		db.Exec("DROP INDEX IF EXISTS idx_usage_hour_dims")
 	}
 }
@@ -38,6 +38,11 @@ var (
 	ErrNoActiveKeys       = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_ACTIVE_KEYS", Message: "No active API keys available for this group"}
 	ErrMaxRetriesExceeded = &APIError{HTTPStatus: http.StatusBadGateway, Code: "MAX_RETRIES_EXCEEDED", Message: "Request failed after maximum retries"}
 	ErrNoKeysAvailable    = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_KEYS_AVAILABLE", Message: "No API keys available to process the request"}
+	ErrServiceNotReady    = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "SERVICE_NOT_READY", Message: "Service is starting, groups are not loaded yet"}
+	ErrServerBusy         = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "SERVER_BUSY", Message: "The proxy is saturated, please retry later"}
+	ErrTooManyStreams     = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "TOO_MANY_STREAMS", Message: "The group has reached its concurrent stream limit, please retry later or send a non-streaming request"}
+	ErrConcurrencyLimit   = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "CONCURRENCY_LIMIT_EXCEEDED", Message: "The group has reached its concurrency limit, please retry later"}
+	ErrBudgetExceeded     = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "BUDGET_EXCEEDED", Message: "The usage budget has been exhausted until it resets"}
 )
 
 // NewAPIError creates a new APIError with a custom message.
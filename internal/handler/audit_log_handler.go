@@ -0,0 +1,26 @@
+package handler
+
+import (
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs returns the audit logs stored in the database, newest first, filtered by
+// group_name, key_value, proxy_key, model, method, status_code, is_success, start_time and
+// end_time. Logs sent to an external sink are not listed.
+func (s *Server) GetAuditLogs(c *gin.Context) {
+	query := s.AuditLogService.GetAuditLogsQuery(c).Order("timestamp desc")
+
+	var logs []models.AuditLog
+	pagination, err := response.Paginate(c, query, &logs)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	pagination.Items = logs
+	response.Success(c, pagination)
+}
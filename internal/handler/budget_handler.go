@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"strconv"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseBudgetParams parses the group and budget IDs of a budget route.
+func parseBudgetParams(c *gin.Context) (uint, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return 0, 0, false
+	}
+
+	budgetID, err := strconv.Atoi(c.Param("budgetId"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_budget_id")
+		return 0, 0, false
+	}
+	return uint(id), uint(budgetID), true
+}
+
+// ListBudgets returns the budgets of a group with their usage in the current period.
+func (s *Server) ListBudgets(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	budgets, err := s.BudgetService.List(uint(id))
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, budgets)
+}
+
+// CreateBudget adds a budget to a group, or to one of its proxy keys.
+func (s *Server) CreateBudget(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	var req services.BudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	budget, err := s.BudgetService.Create(uint(id), &req)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, budget)
+}
+
+// UpdateBudget changes the schedule, limits and action of a budget.
+func (s *Server) UpdateBudget(c *gin.Context) {
+	id, budgetID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	var req services.BudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	budget, err := s.BudgetService.Update(id, budgetID, &req)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, budget)
+}
+
+// DeleteBudget removes a budget.
+func (s *Server) DeleteBudget(c *gin.Context) {
+	id, budgetID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	if s.handleGroupError(c, s.BudgetService.Delete(id, budgetID)) {
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ResetBudget clears the usage of a budget in the current period.
+func (s *Server) ResetBudget(c *gin.Context) {
+	id, budgetID, ok := parseBudgetParams(c)
+	if !ok {
+		return
+	}
+
+	budget, err := s.BudgetService.Reset(id, budgetID)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, budget)
+}
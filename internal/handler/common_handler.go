@@ -1,14 +1,28 @@
 package handler
 
 import (
+	"strconv"
+	"sync"
+	"time"
+
 	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/jsonengine"
 	"gpt-load/internal/response"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	defaultScannerBenchmarkSizeKB = 512
+	maxScannerBenchmarkSizeKB     = 8 * 1024
+	scannerBenchmarkDuration      = 200 * time.Millisecond
+)
+
 // CommonHandler handles common, non-grouped requests.
-type CommonHandler struct{}
+type CommonHandler struct {
+	benchmarkMu sync.Mutex
+}
 
 // NewCommonHandler creates a new CommonHandler.
 func NewCommonHandler() *CommonHandler {
@@ -20,3 +34,30 @@ func (h *CommonHandler) GetChannelTypes(c *gin.Context) {
 	channelTypes := channel.GetChannels()
 	response.Success(c, channelTypes)
 }
+
+// GetEngineCapabilities reports the SIMD features of this machine and the JSON scanners in use.
+func (h *CommonHandler) GetEngineCapabilities(c *gin.Context) {
+	response.Success(c, jsonengine.Capabilities())
+}
+
+// BenchmarkEngineScanners measures the throughput of every JSON scanner available on this machine.
+// The input size is set with size_kb; only one benchmark runs at a time since it keeps a CPU busy.
+func (h *CommonHandler) BenchmarkEngineScanners(c *gin.Context) {
+	sizeKB := defaultScannerBenchmarkSizeKB
+	if raw := c.Query("size_kb"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxScannerBenchmarkSizeKB {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "size_kb must be between 1 and "+strconv.Itoa(maxScannerBenchmarkSizeKB)))
+			return
+		}
+		sizeKB = parsed
+	}
+
+	if !h.benchmarkMu.TryLock() {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, "A scanner benchmark is already running"))
+		return
+	}
+	defer h.benchmarkMu.Unlock()
+
+	response.Success(c, jsonengine.BenchmarkScanners(sizeKB*1024, scannerBenchmarkDuration))
+}
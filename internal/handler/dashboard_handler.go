@@ -88,6 +88,24 @@ func (s *Server) Stats(c *gin.Context) {
 		errorRateTrendIsGrowth = true
 	}
 
+	// 计算费用趋势
+	currentCost, err := s.getCostStats(twentyFourHoursAgo, now)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "database.current_stats_failed")
+		return
+	}
+	previousCost, err := s.getCostStats(fortyEightHoursAgo, twentyFourHoursAgo)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "database.previous_stats_failed")
+		return
+	}
+	costTrend := 0.0
+	if previousCost > 0 {
+		costTrend = (currentCost - previousCost) / previousCost * 100
+	} else if currentCost > 0 {
+		costTrend = 100.0
+	}
+
 	// 获取安全警告信息
 	securityWarnings := s.getSecurityWarnings(c)
 
@@ -108,6 +126,11 @@ func (s *Server) Stats(c *gin.Context) {
 			Trend:         errorRateTrend,
 			TrendIsGrowth: errorRateTrendIsGrowth,
 		},
+		Cost: models.StatCard{
+			Value:         currentCost,
+			Trend:         costTrend,
+			TrendIsGrowth: costTrend >= 0,
+		},
 		SecurityWarnings: securityWarnings,
 	}
 
@@ -197,6 +220,16 @@ func (s *Server) getHourlyStats(startTime, endTime time.Time) (hourlyStatResult,
 	return result, err
 }
 
+// getCostStats 统计时间范围内按模型价格表计算的费用合计（美元）
+func (s *Server) getCostStats(startTime, endTime time.Time) (float64, error) {
+	var cost float64
+	err := s.DB.Model(&models.UsageHourlyStat{}).
+		Where("time >= ? AND time < ?", startTime, endTime).
+		Select("COALESCE(SUM(cost), 0)").
+		Scan(&cost).Error
+	return cost, err
+}
+
 type rpmStatResult struct {
 	CurrentRequests  int64
 	PreviousRequests int64
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"strconv"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupDebugRequest defines the payload for starting a group debug window.
+type GroupDebugRequest struct {
+	Minutes    int      `json:"minutes"`
+	SampleRate *float64 `json:"sample_rate"` // 请求体抽样率，默认全部记录
+}
+
+// StartGroupDebug temporarily raises the log verbosity of a group.
+func (s *Server) StartGroupDebug(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	var req GroupDebugRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	sampleRate := 1.0
+	if req.SampleRate != nil {
+		sampleRate = *req.SampleRate
+	}
+
+	window, err := s.GroupDebugService.Start(uint(id), req.Minutes, sampleRate)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, window)
+}
+
+// GetGroupDebug returns the active debug window of a group, or null when there is none.
+func (s *Server) GetGroupDebug(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	window, err := s.GroupDebugService.Get(uint(id))
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, window)
+}
+
+// StopGroupDebug ends the debug window of a group before it expires.
+func (s *Server) StopGroupDebug(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	if s.handleGroupError(c, s.GroupDebugService.Stop(uint(id))) {
+		return
+	}
+
+	response.Success(c, nil)
+}
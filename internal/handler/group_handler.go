@@ -62,6 +62,8 @@ type GroupCreateRequest struct {
 	HeaderRules         []models.HeaderRule                   `json:"header_rules"`
 	InboundRules        []jsonengine.PathRule                 `json:"inbound_rules"`
 	OutboundRules       []jsonengine.PathRule                 `json:"outbound_rules"`
+	ErrorOutboundRules  []jsonengine.PathRule                 `json:"error_outbound_rules"`
+	PromptTemplates     map[string]models.PromptTemplate      `json:"prompt_templates"`
 	ProxyKeys           string                                `json:"proxy_keys"`
 }
 
@@ -90,6 +92,8 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		HeaderRules:         req.HeaderRules,
 		InboundRules:        req.InboundRules,
 		OutboundRules:       req.OutboundRules,
+		ErrorOutboundRules:  req.ErrorOutboundRules,
+		PromptTemplates:     req.PromptTemplates,
 		ProxyKeys:           req.ProxyKeys,
 	}
 
@@ -135,6 +139,8 @@ type GroupUpdateRequest struct {
 	HeaderRules         []models.HeaderRule                   `json:"header_rules"`
 	InboundRules        []jsonengine.PathRule                 `json:"inbound_rules"`
 	OutboundRules       []jsonengine.PathRule                 `json:"outbound_rules"`
+	ErrorOutboundRules  []jsonengine.PathRule                 `json:"error_outbound_rules"`
+	PromptTemplates     map[string]models.PromptTemplate      `json:"prompt_templates"`
 	ProxyKeys           *string                               `json:"proxy_keys,omitempty"`
 }
 
@@ -164,6 +170,7 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		ModelRedirectRules:  req.ModelRedirectRules,
 		ModelRedirectStrict: req.ModelRedirectStrict,
 		Config:              req.Config,
+		PromptTemplates:     req.PromptTemplates,
 		ProxyKeys:           req.ProxyKeys,
 	}
 
@@ -192,6 +199,11 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		params.OutboundRules = &rules
 	}
 
+	if req.ErrorOutboundRules != nil {
+		rules := req.ErrorOutboundRules
+		params.ErrorOutboundRules = &rules
+	}
+
 	group, err := s.GroupService.UpdateGroup(c.Request.Context(), uint(id), params)
 	if s.handleGroupError(c, err) {
 		return
@@ -220,8 +232,13 @@ type GroupResponse struct {
 	HeaderRules         []models.HeaderRule     `json:"header_rules"`
 	InboundRules        []jsonengine.PathRule   `json:"inbound_rules"`
 	OutboundRules       []jsonengine.PathRule   `json:"outbound_rules"`
+	ErrorOutboundRules  []jsonengine.PathRule   `json:"error_outbound_rules"`
+	PromptTemplates     map[string]models.PromptTemplate `json:"prompt_templates"`
 	ProxyKeys           string                  `json:"proxy_keys"`
 	SubGroupIds         []uint              `json:"sub_group_ids,omitempty"`
+	ProviderStatus      *services.ProviderStatus `json:"provider_status,omitempty"`
+	LoadError           string                   `json:"load_error,omitempty"`
+	RuleIssues          []services.GroupRuleIssue `json:"rule_issues,omitempty"`
 	LastValidatedAt     *time.Time          `json:"last_validated_at"`
 	CreatedAt           time.Time           `json:"created_at"`
 	UpdatedAt           time.Time           `json:"updated_at"`
@@ -266,6 +283,24 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		}
 	}
 
+	// Parse error outbound rules from JSON
+	var errorOutboundRules []jsonengine.PathRule
+	if len(group.ErrorOutboundRules) > 0 {
+		if err := json.Unmarshal(group.ErrorOutboundRules, &errorOutboundRules); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal error outbound rules")
+			errorOutboundRules = make([]jsonengine.PathRule, 0)
+		}
+	}
+
+	// Parse prompt templates from JSON
+	promptTemplates := make(map[string]models.PromptTemplate)
+	if len(group.PromptTemplates) > 0 {
+		if err := json.Unmarshal(group.PromptTemplates, &promptTemplates); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal prompt templates")
+			promptTemplates = make(map[string]models.PromptTemplate)
+		}
+	}
+
 	// Extract sub-group IDs for aggregate groups
 	var subGroupIds []uint
 	if group.GroupType == "aggregate" && len(group.SubGroups) > 0 {
@@ -275,6 +310,12 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		}
 	}
 
+	// Attach the provider incident status when the status poller is enabled
+	var providerStatus *services.ProviderStatus
+	if status, ok := s.ProviderStatusService.GetStatus(group.ChannelType); ok {
+		providerStatus = &status
+	}
+
 	return &GroupResponse{
 		ID:                  group.ID,
 		Name:                group.Name,
@@ -294,8 +335,13 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		HeaderRules:         headerRules,
 		InboundRules:        inboundRules,
 		OutboundRules:       outboundRules,
+		ErrorOutboundRules:  errorOutboundRules,
+		PromptTemplates:     promptTemplates,
 		ProxyKeys:           group.ProxyKeys,
 		SubGroupIds:         subGroupIds,
+		ProviderStatus:      providerStatus,
+		LoadError:           s.GroupManager.GroupLoadError(group.Name),
+		RuleIssues:          s.GroupManager.GroupRuleIssues(group.Name),
 		LastValidatedAt:     group.LastValidatedAt,
 		CreatedAt:           group.CreatedAt,
 		UpdatedAt:           group.UpdatedAt,
@@ -318,10 +364,15 @@ func (s *Server) DeleteGroup(c *gin.Context) {
 
 // ConfigOption represents a single configurable option for a group.
 type ConfigOption struct {
-	Key          string `json:"key"`
-	Name         string `json:"name"`
-	Description  string `json:"description"`
-	DefaultValue any    `json:"default_value"`
+	Key          string   `json:"key"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	DefaultValue any      `json:"default_value"`
+	Type         string   `json:"type"`
+	MinValue     *int     `json:"min_value,omitempty"`
+	MaxValue     *int     `json:"max_value,omitempty"`
+	Options      []string `json:"options,omitempty"`
+	HotReload    bool     `json:"hot_reload"`
 }
 
 // GetGroupConfigOptions returns a list of available configuration options for groups.
@@ -347,12 +398,65 @@ func (s *Server) GetGroupConfigOptions(c *gin.Context) {
 			Name:         name,
 			Description:  description,
 			DefaultValue: option.DefaultValue,
+			Type:         option.Type,
+			MinValue:     option.MinValue,
+			MaxValue:     option.MaxValue,
+			Options:      option.Options,
+			HotReload:    option.HotReload,
 		})
 	}
 
 	response.Success(c, translated)
 }
 
+// ExplainRulesRequest defines the payload for previewing JSON rules against a sample body.
+type ExplainRulesRequest struct {
+	Rules        []jsonengine.PathRule `json:"rules"`
+	ConflictMode string                `json:"conflict_mode"`
+	Input        json.RawMessage       `json:"input"`
+}
+
+// ExplainRulesResponse lists the rule matches and the transformed body.
+type ExplainRulesResponse struct {
+	Matches []jsonengine.MatchReport `json:"matches"`
+	Output  json.RawMessage          `json:"output"`
+}
+
+// ExplainRules dry-runs inbound/outbound rules on a sample JSON body without saving anything.
+func (s *Server) ExplainRules(c *gin.Context) {
+	var req ExplainRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if len(req.Input) == 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "input is required"))
+		return
+	}
+
+	engine, err := jsonengine.NewPathEngine(req.Rules, jsonengine.WithConflictMode(jsonengine.ConflictMode(req.ConflictMode)))
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	matches, err := engine.Explain(req.Input)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	output, err := engine.ProcessBytes(req.Input)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	if matches == nil {
+		matches = []jsonengine.MatchReport{}
+	}
+
+	response.Success(c, ExplainRulesResponse{Matches: matches, Output: output})
+}
+
 // calculateRequestStats is a helper to compute request statistics.
 func (s *Server) GetGroupStats(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -31,6 +31,14 @@ type Server struct {
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	ProviderStatusService      *services.ProviderStatusService
+	DailyReportService         *services.DailyReportService
+	KeyWebhookService          *services.KeyWebhookService
+	GroupDebugService          *services.GroupDebugService
+	BudgetService              *services.BudgetService
+	UsageService               *services.UsageService
+	AuditLogService            *services.AuditLogService
+	RuleLintService            *services.RuleLintService
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
 }
@@ -50,6 +58,14 @@ type NewServerParams struct {
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	ProviderStatusService      *services.ProviderStatusService
+	DailyReportService         *services.DailyReportService
+	KeyWebhookService          *services.KeyWebhookService
+	GroupDebugService          *services.GroupDebugService
+	BudgetService              *services.BudgetService
+	UsageService               *services.UsageService
+	AuditLogService            *services.AuditLogService
+	RuleLintService            *services.RuleLintService
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
 }
@@ -69,6 +85,14 @@ func NewServer(params NewServerParams) *Server {
 		KeyImportService:           params.KeyImportService,
 		KeyDeleteService:           params.KeyDeleteService,
 		LogService:                 params.LogService,
+		ProviderStatusService:      params.ProviderStatusService,
+		DailyReportService:         params.DailyReportService,
+		KeyWebhookService:          params.KeyWebhookService,
+		GroupDebugService:          params.GroupDebugService,
+		BudgetService:              params.BudgetService,
+		UsageService:               params.UsageService,
+		AuditLogService:            params.AuditLogService,
+		RuleLintService:            params.RuleLintService,
 		CommonHandler:              params.CommonHandler,
 		EncryptionSvc:              params.EncryptionSvc,
 	}
@@ -122,6 +146,15 @@ func (s *Server) Health(c *gin.Context) {
 		}
 	}
 
+	if !s.GroupManager.IsReady() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "starting",
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"uptime":    uptime,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "healthy",
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
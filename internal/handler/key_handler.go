@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
 	"log"
@@ -175,6 +177,7 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 			keys[i].KeyValue = decryptedValue
 		}
 	}
+	s.KeyService.FillAvailableAt(keys)
 	paginatedResult.Items = keys
 
 	response.Success(c, paginatedResult)
@@ -487,3 +490,39 @@ func (s *Server) UpdateKeyNotes(c *gin.Context) {
 
 	response.Success(c, nil)
 }
+
+// UpdateKeyWeightRequest defines the payload for updating a key's weight.
+type UpdateKeyWeightRequest struct {
+	Weight *int `json:"weight" binding:"required"`
+}
+
+// UpdateKeyWeight handles updating the weight of a specific API key for the weighted_random strategy.
+func (s *Server) UpdateKeyWeight(c *gin.Context) {
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.Atoi(keyIDStr)
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyWeightRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if *req.Weight < 0 || *req.Weight > keypool.MaxKeyWeight {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("weight must be between 0 and %d", keypool.MaxKeyWeight)))
+		return
+	}
+
+	if err := s.KeyService.KeyProvider.UpdateKeyWeight(uint(keyID), *req.Weight); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	response.Success(c, nil)
+}
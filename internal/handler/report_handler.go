@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDailyReport returns the key pool report for the 24 hours before end (RFC 3339, default now),
+// the same report the scheduled job delivers.
+func (s *Server) GetDailyReport(c *gin.Context) {
+	end := time.Now()
+	if v := c.Query("end"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "end must be an RFC 3339 timestamp"))
+			return
+		}
+		end = t
+	}
+
+	report, err := s.DailyReportService.BuildReport(c.Request.Context(), end)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// SendDailyReport builds the report for the last 24 hours and delivers it to the configured
+// webhook and email recipients immediately, e.g. to check the delivery settings.
+func (s *Server) SendDailyReport(c *gin.Context) {
+	err := s.DailyReportService.SendReport(c.Request.Context(), time.Now())
+	if errors.Is(err, services.ErrDailyReportNoTarget) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+
+	response.Success(c, nil)
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"strconv"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LintAllRules checks the inbound, outbound and error outbound rules of every group in one pass.
+// The report lists invalid rules, legacy syntax and rules that have not matched within
+// unused_days (default 30), most severe first. Matches are tracked per process, so unmatched
+// rules are only reported once this instance has been running for the whole window.
+func (s *Server) LintAllRules(c *gin.Context) {
+	unusedDays := services.DefaultRuleLintUnusedDays
+	if v := c.Query("unused_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "unused_days must be an integer"))
+			return
+		}
+		unusedDays = days
+	}
+
+	report, err := s.RuleLintService.LintAll(unusedDays)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, report)
+}
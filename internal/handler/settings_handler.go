@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"fmt"
+	"gpt-load/internal/config"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/metrics"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
 	"gpt-load/internal/utils"
@@ -16,7 +20,7 @@ import (
 // It retrieves all system settings, groups them by category, and returns them.
 func (s *Server) GetSettings(c *gin.Context) {
 	currentSettings := s.SettingsManager.GetSettings()
-	settingsInfo := utils.GenerateSettingsMetadata(&currentSettings)
+	settingsInfo := config.SettingsMetadata(currentSettings)
 
 	// Translate settings info
 	for i := range settingsInfo {
@@ -70,10 +74,41 @@ func (s *Server) UpdateSettings(c *gin.Context) {
 	}
 
 	// Sanitize proxy_keys input
-	if proxyKeys, ok := settingsMap["proxy_keys"]; ok {
-		if proxyKeysStr, ok := proxyKeys.(string); ok {
-			cleanedKeys := utils.SplitAndTrim(proxyKeysStr, ",")
-			settingsMap["proxy_keys"] = strings.Join(cleanedKeys, ",")
+	for _, field := range []string{"proxy_keys", "batch_proxy_keys"} {
+		if proxyKeys, ok := settingsMap[field]; ok {
+			if proxyKeysStr, ok := proxyKeys.(string); ok {
+				cleanedKeys := utils.SplitAndTrim(proxyKeysStr, ",")
+				settingsMap[field] = strings.Join(cleanedKeys, ",")
+			}
+		}
+	}
+
+	if err := s.SettingsManager.ValidateSettings(settingsMap); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	// Reject label rules that cannot be parsed before they reach the metrics
+	for _, field := range []string{"metrics_model_labels", "metrics_path_labels"} {
+		if spec, ok := settingsMap[field].(string); ok {
+			if _, err := metrics.ParseLabelRules(spec); err != nil {
+				response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("invalid %s: %v", field, err)))
+				return
+			}
+		}
+	}
+
+	if spec, ok := settingsMap["inbound_json_schema"].(string); ok && strings.TrimSpace(spec) != "" {
+		if _, err := jsonengine.CompileSchema([]byte(spec)); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("invalid inbound_json_schema: %v", err)))
+			return
+		}
+	}
+
+	if spec, ok := settingsMap["request_seed"].(string); ok {
+		if _, _, err := utils.ParseRequestSeed(spec); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
 		}
 	}
 
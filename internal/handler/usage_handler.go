@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetUsage returns the token usage and cost between start_time and end_time (RFC 3339,
+// default the last 24 hours), optionally filtered by group_id, key_id, proxy_key and model
+// and broken down by the comma-separated group_by dimensions hour, group, key, proxy_key
+// and model.
+func (s *Server) GetUsage(c *gin.Context) {
+	query, ok := parseUsageQuery(c)
+	if !ok {
+		return
+	}
+
+	report, err := s.UsageService.Query(query)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// ExportUsage exports the usage report for the same parameters as GetUsage as a CSV file,
+// one row per combination of the group_by dimensions, for chargeback per proxy key.
+func (s *Server) ExportUsage(c *gin.Context) {
+	query, ok := parseUsageQuery(c)
+	if !ok {
+		return
+	}
+
+	report, err := s.UsageService.Query(query)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	filename := fmt.Sprintf("usage_export_%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	if err := s.UsageService.WriteCSV(report, c.Writer); err != nil {
+		logrus.WithError(err).Error("Failed to write usage CSV")
+	}
+}
+
+// parseUsageQuery reads the usage report parameters, writing an error response when one is invalid.
+func parseUsageQuery(c *gin.Context) (services.UsageQuery, bool) {
+	query := services.UsageQuery{End: time.Now(), ProxyKey: c.Query("proxy_key"), Model: c.Query("model")}
+	if v := c.Query("end_time"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "end_time must be an RFC 3339 timestamp"))
+			return query, false
+		}
+		query.End = t
+	}
+	query.Start = query.End.Add(-24 * time.Hour)
+	if v := c.Query("start_time"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "start_time must be an RFC 3339 timestamp"))
+			return query, false
+		}
+		query.Start = t
+	}
+
+	if v := c.Query("group_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 0)
+		if err != nil {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+			return query, false
+		}
+		query.GroupID = uint(id)
+	}
+	if v := c.Query("key_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 0)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "key_id must be a positive integer"))
+			return query, false
+		}
+		query.KeyID = uint(id)
+	}
+	for _, dimension := range strings.Split(c.Query("group_by"), ",") {
+		if dimension = strings.TrimSpace(dimension); dimension != "" {
+			query.GroupBy = append(query.GroupBy, dimension)
+		}
+	}
+	return query, true
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxKeyWebhookBodySize limits the size of a key provisioning event payload.
+const maxKeyWebhookBodySize = 4 << 20
+
+// KeyWebhook handles signed key provisioning events from external secret managers.
+func (s *Server) KeyWebhook(c *gin.Context) {
+	if !s.KeyWebhookService.Enabled() {
+		response.Error(c, app_errors.ErrResourceNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxKeyWebhookBodySize+1))
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+		return
+	}
+	if len(body) > maxKeyWebhookBodySize {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "webhook payload is too large"))
+		return
+	}
+
+	if err := s.KeyWebhookService.VerifySignature(body, c.GetHeader("X-Webhook-Timestamp"), c.GetHeader("X-Webhook-Signature")); err != nil {
+		s.handleKeyWebhookError(c, err)
+		return
+	}
+
+	var event services.KeyProvisioningEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	result, err := s.KeyWebhookService.Apply(&event)
+	if err != nil {
+		s.handleKeyWebhookError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+func (s *Server) handleKeyWebhookError(c *gin.Context, err error) {
+	var apiErr *app_errors.APIError
+	switch {
+	case errors.Is(err, services.ErrKeyWebhookDisabled):
+		response.Error(c, app_errors.ErrResourceNotFound)
+	case errors.As(err, &apiErr):
+		response.Error(c, apiErr)
+	default:
+		logrus.WithContext(c.Request.Context()).WithError(err).Error("Failed to apply key provisioning event")
+		response.Error(c, app_errors.ParseDBError(err))
+	}
+}
@@ -0,0 +1,112 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// HostOverride is the Host header and TLS server name sent to an upstream instead of
+// the ones taken from its URL, for upstreams addressed by IP or fronted by a CDN.
+type HostOverride struct {
+	Host       string // Host header, as host or host:port
+	ServerName string // TLS SNI and the name the certificate is verified against
+}
+
+// NewHostOverride validates the configured overrides. When only the Host header is set,
+// the server name defaults to its hostname, so the certificate is verified against the
+// name the request is addressed to rather than the IP or CDN hostname in the URL.
+func NewHostOverride(host, serverName string) (HostOverride, error) {
+	o := HostOverride{Host: strings.TrimSpace(host), ServerName: strings.ToLower(strings.TrimSpace(serverName))}
+
+	var hostname string
+	if o.Host != "" {
+		var err error
+		if hostname, err = parseHostHeader(o.Host); err != nil {
+			return HostOverride{}, err
+		}
+	}
+
+	if o.ServerName != "" {
+		if net.ParseIP(o.ServerName) != nil {
+			return HostOverride{}, fmt.Errorf("TLS server name %q must be a DNS name, SNI cannot carry an IP address", o.ServerName)
+		}
+		if !isDNSName(o.ServerName) {
+			return HostOverride{}, fmt.Errorf("TLS server name %q is not a valid DNS name", o.ServerName)
+		}
+		// A certificate checked for one name while the request asks the server for another
+		// either fails at the CDN (421 Misdirected Request) or reaches the wrong virtual host.
+		if hostname != "" && !strings.EqualFold(hostname, o.ServerName) {
+			return HostOverride{}, fmt.Errorf("TLS server name %q does not match the Host header %q", o.ServerName, o.Host)
+		}
+	} else if hostname != "" && net.ParseIP(hostname) == nil {
+		o.ServerName = strings.ToLower(hostname)
+	}
+	return o, nil
+}
+
+// IsZero reports whether no override is configured.
+func (o HostOverride) IsZero() bool {
+	return o.Host == "" && o.ServerName == ""
+}
+
+// parseHostHeader validates a Host header value and returns its hostname.
+func parseHostHeader(host string) (string, error) {
+	if strings.Contains(host, "://") || strings.ContainsAny(host, "/?#@ ") {
+		return "", fmt.Errorf("host header %q must be a host or host:port, not a URL", host)
+	}
+	hostname := host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("host header %q has an invalid port", host)
+		}
+		hostname = h
+	} else if strings.Count(host, ":") == 1 {
+		return "", fmt.Errorf("host header %q has an invalid port", host)
+	}
+	if net.ParseIP(strings.Trim(hostname, "[]")) == nil && !isDNSName(hostname) {
+		return "", fmt.Errorf("host header %q is not a valid host name", host)
+	}
+	return hostname, nil
+}
+
+// isDNSName checks the hostname syntax: dot-separated labels of letters, digits and hyphens.
+func isDNSName(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hostOverrideTransport sends requests with a fixed Host header.
+type hostOverrideTransport struct {
+	base *http.Transport
+	host string
+}
+
+func (t *hostOverrideTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Redirects may point at other hosts, which must receive their own Host header.
+	if req.Response == nil {
+		req = req.Clone(req.Context())
+		req.Host = t.host
+	}
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the underlying transport.
+func (t *hostOverrideTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+}
@@ -1,6 +1,7 @@
 package httpclient
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -27,6 +28,7 @@ type Config struct {
 	TLSHandshakeTimeout   time.Duration
 	ExpectContinueTimeout time.Duration
 	ProxyURL              string
+	HostOverride          HostOverride
 }
 
 // HTTPClientManager manages the lifecycle of HTTP clients.
@@ -85,6 +87,10 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 		ReadBufferSize:        config.ReadBufferSize,
 	}
 
+	if config.HostOverride.ServerName != "" {
+		transport.TLSClientConfig = &tls.Config{ServerName: config.HostOverride.ServerName}
+	}
+
 	// Set http proxy.
 	if config.ProxyURL != "" {
 		proxyURL, err := url.Parse(config.ProxyURL)
@@ -98,8 +104,13 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 		transport.Proxy = http.ProxyFromEnvironment
 	}
 
+	var roundTripper http.RoundTripper = transport
+	if config.HostOverride.Host != "" {
+		roundTripper = &hostOverrideTransport{base: transport, host: config.HostOverride.Host}
+	}
+
 	newClient := &http.Client{
-		Transport: transport,
+		Transport: roundTripper,
 		Timeout:   config.RequestTimeout,
 	}
 
@@ -110,7 +121,7 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 // getFingerprint generates a unique string representation of the client configuration.
 func (c *Config) getFingerprint() string {
 	return fmt.Sprintf(
-		"ct:%.0fs|rt:%.0fs|it:%.0fs|mic:%d|mich:%d|rht:%.0fs|dc:%t|wbs:%d|rbs:%d|fh2:%t|tlst:%.0fs|ect:%.0fs|proxy:%s",
+		"ct:%.0fs|rt:%.0fs|it:%.0fs|mic:%d|mich:%d|rht:%.0fs|dc:%t|wbs:%d|rbs:%d|fh2:%t|tlst:%.0fs|ect:%.0fs|proxy:%s|host:%s|sni:%s",
 		c.ConnectTimeout.Seconds(),
 		c.RequestTimeout.Seconds(),
 		c.IdleConnTimeout.Seconds(),
@@ -124,5 +135,7 @@ func (c *Config) getFingerprint() string {
 		c.TLSHandshakeTimeout.Seconds(),
 		c.ExpectContinueTimeout.Seconds(),
 		c.ProxyURL,
+		c.HostOverride.Host,
+		c.HostOverride.ServerName,
 	)
 }
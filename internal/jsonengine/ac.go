@@ -5,9 +5,27 @@ type ACNode struct {
 	children map[string]*ACNode // 精确匹配子节点
 	wildcard *ACNode            // * 通配子节点
 	arrayAll *ACNode            // [*] 数组通配子节点
+	slices   []sliceChild       // [start:end] 数组切片子节点
+	patterns []patternChild     // /regex/、prefix:xxx 键模式子节点
 	fail     *ACNode            // 失败指针
 	output   []RuleAction       // 匹配输出
 	depth    int                // 节点深度
+
+	// 白名单（keep）模式标记，只在规则自身的路径上设置，不经失败指针合并
+	keepPrefix   bool // 位于某条 keep 规则路径上（需要保留并继续向下过滤）
+	keepTerminal bool // keep 规则的终点（整个子树原样保留）
+}
+
+// sliceChild 数组切片子节点
+type sliceChild struct {
+	seg  Segment
+	node *ACNode
+}
+
+// patternChild 键模式子节点
+type patternChild struct {
+	seg  Segment
+	node *ACNode
 }
 
 // ⚡ 性能优化：缓存常用的数组索引字符串（避免重复分配）
@@ -22,8 +40,15 @@ func init() {
 
 // PathMatcher 路径匹配器（预编译的 AC 自动机）
 type PathMatcher struct {
-	root  *ACNode
-	rules []PathRule
+	root      *ACNode
+	rules     []PathRule
+	keepRules bool // 是否存在 keep 规则（启用白名单模式）
+
+	// 是否存在需要数组长度的规则（负索引/负切片边界），此时需预先统计数组长度
+	needsArrayLen bool
+
+	conflictMode  ConflictMode // 规则冲突解析方式
+	hasPriorities bool         // 是否存在设置了 priority 的规则
 }
 
 // NewPathMatcher 创建路径匹配器
@@ -50,22 +75,95 @@ func (m *PathMatcher) AddRule(rule PathRule) error {
 	}
 
 	rule.segments = segments
+
+	// 预编译值转换函数
+	transform, err := compileValueTransform(rule)
+	if err != nil {
+		return err
+	}
+
+	expr, err := compileRuleExpr(rule)
+	if err != nil {
+		return err
+	}
+
+	var truncate *truncateSpec
+	var strip *stripSpec
+	switch rule.Action {
+	case ActionTruncate:
+		truncate, _ = compileTruncate(rule) // 已由 compileValueTransform 校验
+	case ActionStripBase64:
+		strip, _ = compileStripBase64(rule)
+	}
+
+	valueBytes := rule.ValueBytes
+	switch rule.Action {
+	case ActionClamp:
+		// clamp 规则的 default 在字段缺失时按 add 处理
+		if rule.Default != nil {
+			valueBytes = marshalValue(rule.Default)
+		}
+	case ActionRename:
+		// 预序列化新字段名（含引号），匹配时直接替换原 key 输出
+		target, err := fieldTarget(rule, segments)
+		if err != nil {
+			return err
+		}
+		valueBytes = marshalString(target)
+	case ActionCapture:
+		if _, ok := rule.Value.(string); rule.Value != nil && !ok {
+			return &PathError{Msg: "capture value must be the result name"}
+		}
+	}
+
 	ruleIdx := len(m.rules)
 	m.rules = append(m.rules, rule)
+	if rule.Priority != 0 {
+		m.hasPriorities = true
+	}
 
 	// 插入到 AC 自动机
-	node := m.root
+	// JSON Pointer 的数字段同时插入数组索引和对象键两条分支，规则的终点可能有多个节点
+	nodes := []*ACNode{m.root}
 	for _, seg := range segments {
-		node = node.getOrCreate(seg)
+		if seg.NeedsArrayLen() {
+			m.needsArrayLen = true
+		}
+		next := make([]*ACNode, 0, len(nodes)*2)
+		for _, node := range nodes {
+			next = append(next, node.getOrCreate(seg))
+			if seg.Key != "" {
+				next = append(next, node.getOrCreate(Segment{Type: SegField, Value: seg.Key}))
+			}
+		}
+		nodes = next
+		if rule.Action == ActionKeep {
+			for _, node := range nodes {
+				node.keepPrefix = true
+			}
+		}
+	}
+	if rule.Action == ActionKeep {
+		m.keepRules = true
 	}
 
 	// 添加输出
-	node.output = append(node.output, RuleAction{
-		Index:      ruleIdx,
-		Action:     rule.Action,
-		Value:      rule.Value,
-		ValueBytes: rule.ValueBytes,
-	})
+	for _, node := range nodes {
+		if rule.Action == ActionKeep {
+			node.keepTerminal = true
+		}
+		node.output = append(node.output, RuleAction{
+			Index:      ruleIdx,
+			Action:     rule.Action,
+			Value:      rule.Value,
+			ValueBytes: valueBytes,
+			Priority:   rule.Priority,
+			Transform:  transform,
+			truncate:   truncate,
+			strip:      strip,
+			expr:       expr,
+		})
+	}
 
 	return nil
 }
@@ -87,6 +185,14 @@ func (m *PathMatcher) Build() {
 		m.root.arrayAll.fail = m.root
 		queue = append(queue, m.root.arrayAll)
 	}
+	for _, sc := range m.root.slices {
+		sc.node.fail = m.root
+		queue = append(queue, sc.node)
+	}
+	for _, pc := range m.root.patterns {
+		pc.node.fail = m.root
+		queue = append(queue, pc.node)
+	}
 
 	// BFS 构建其他节点的失败指针
 	for len(queue) > 0 {
@@ -120,6 +226,24 @@ func (m *PathMatcher) Build() {
 			}
 			queue = append(queue, curr.arrayAll)
 		}
+
+		// 处理数组切片子节点（失败指针与 [*] 相同处理）
+		for _, sc := range curr.slices {
+			sc.node.fail = m.findFailNodeArrayAll(curr.fail)
+			if sc.node.fail != nil && len(sc.node.fail.output) > 0 {
+				sc.node.output = append(sc.node.output, sc.node.fail.output...)
+			}
+			queue = append(queue, sc.node)
+		}
+
+		// 处理键模式子节点（失败指针与 * 相同处理）
+		for _, pc := range curr.patterns {
+			pc.node.fail = m.findFailNodeWildcard(curr.fail)
+			if pc.node.fail != nil && len(pc.node.fail.output) > 0 {
+				pc.node.output = append(pc.node.output, pc.node.fail.output...)
+			}
+			queue = append(queue, pc.node)
+		}
 	}
 }
 
@@ -167,6 +291,12 @@ func (m *PathMatcher) findFailNodeArrayAll(fail *ACNode) *ACNode {
 // Match 从当前状态匹配下一个段
 // 返回新状态和匹配到的动作列表
 func (m *PathMatcher) Match(state *ACNode, key string, isArray bool, arrayIdx int) (*ACNode, []RuleAction) {
+	return m.MatchElement(state, key, isArray, arrayIdx, -1)
+}
+
+// MatchElement 同 Match，arrayLen 为当前数组长度（未知时传 -1），用于负索引和切片匹配
+// 数组元素匹配优先级：[n] > [-n] > [start:end] > [*]
+func (m *PathMatcher) MatchElement(state *ACNode, key string, isArray bool, arrayIdx, arrayLen int) (*ACNode, []RuleAction) {
 	if state == nil {
 		state = m.root
 	}
@@ -182,14 +312,20 @@ func (m *PathMatcher) Match(state *ACNode, key string, isArray bool, arrayIdx in
 			idxKey := "[" + itoa(arrayIdx) + "]"
 			if child := node.children[idxKey]; child != nil {
 				next = child
+			} else if child := node.negativeIndexChild(arrayIdx, arrayLen); child != nil {
+				next = child
+			} else if child := node.sliceChild(arrayIdx, arrayLen); child != nil {
+				next = child
 			} else if node.arrayAll != nil {
 				// 回退到 [*] 通配
 				next = node.arrayAll
 			}
 		} else {
-			// 对象键：尝试精确匹配或 *
+			// 对象键：精确匹配 > 键模式 > *
 			if child := node.children[key]; child != nil {
 				next = child
+			} else if child := node.patternChild(key); child != nil {
+				next = child
 			} else if node.wildcard != nil {
 				next = node.wildcard
 			}
@@ -245,11 +381,49 @@ func (m *PathMatcher) HasRules() bool {
 	return len(m.rules) > 0
 }
 
+// NeedsArrayLen 检查是否存在需要数组长度的规则（负索引/负切片边界）
+func (m *PathMatcher) NeedsArrayLen() bool {
+	return m.needsArrayLen
+}
+
+// HasKeepRules 检查是否启用白名单（keep）模式
+func (m *PathMatcher) HasKeepRules() bool {
+	return m.keepRules
+}
+
 // Rules 返回规则列表
 func (m *PathMatcher) Rules() []PathRule {
 	return m.rules
 }
 
+// negativeIndexChild 按倒数索引 [-n] 查找子节点（数组长度未知时返回 nil）
+func (n *ACNode) negativeIndexChild(arrayIdx, arrayLen int) *ACNode {
+	if arrayLen <= 0 || arrayIdx >= arrayLen {
+		return nil
+	}
+	return n.children["[-"+itoa(arrayLen-arrayIdx)+"]"]
+}
+
+// sliceChild 查找第一个包含该索引的切片子节点
+func (n *ACNode) sliceChild(arrayIdx, arrayLen int) *ACNode {
+	for _, sc := range n.slices {
+		if sc.seg.MatchIndex(arrayIdx, arrayLen) {
+			return sc.node
+		}
+	}
+	return nil
+}
+
+// patternChild 查找第一个匹配该键的模式子节点（按规则添加顺序）
+func (n *ACNode) patternChild(key string) *ACNode {
+	for _, pc := range n.patterns {
+		if pc.seg.MatchKey(key) {
+			return pc.node
+		}
+	}
+	return nil
+}
+
 // getOrCreate 获取或创建子节点
 func (n *ACNode) getOrCreate(seg Segment) *ACNode {
 	switch seg.Type {
@@ -263,8 +437,26 @@ func (n *ACNode) getOrCreate(seg Segment) *ACNode {
 			n.arrayAll = newACNode(n.depth + 1)
 		}
 		return n.arrayAll
+	case SegArraySlice:
+		for _, sc := range n.slices {
+			if sc.seg.Value == seg.Value {
+				return sc.node
+			}
+		}
+		child := newACNode(n.depth + 1)
+		n.slices = append(n.slices, sliceChild{seg: seg, node: child})
+		return child
+	case SegPattern:
+		for _, pc := range n.patterns {
+			if pc.seg.Value == seg.Value {
+				return pc.node
+			}
+		}
+		child := newACNode(n.depth + 1)
+		n.patterns = append(n.patterns, patternChild{seg: seg, node: child})
+		return child
 	case SegArrayIdx:
-		// 数组索引当作精确匹配处理
+		// 数组索引当作精确匹配处理（负索引以 "[-n]" 为 key，匹配时按数组长度换算）
 		key := seg.Value
 		if child := n.children[key]; child != nil {
 			return child
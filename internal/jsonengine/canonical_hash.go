@@ -0,0 +1,369 @@
+package jsonengine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"slices"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 规范化摘要的字符串读取状态
+const (
+	chText    = iota // 字符串外
+	chString         // 字符串内
+	chEscape         // 刚读到反斜杠
+	chUnicode        // \u 之后的十六进制位
+	chNumber         // 数字内
+)
+
+// hashFrame 摘要计算中的容器
+type hashFrame struct {
+	object  bool
+	member  hash.Hash           // 对象：当前成员（key、冒号和值）的摘要
+	members [][sha256.Size]byte // 对象：已完成成员的摘要
+	open    bool                // 对象：有成员正在读取
+}
+
+// CanonicalHasher 流式计算 JSON 规范化后的 SHA-256 摘要
+// 语义相同的文档得到相同摘要：忽略对象 key 的顺序和无意义的空白，
+// 字符串按解码后的内容计算（"\u0061" 与 "a" 相同），带小数或指数的数字按数值计算（1.0、1e0 与 1 相同），
+// 整数按字面量计算以免大整数精度丢失。数组元素顺序有意义，重复 key 均计入摘要。
+// 对象的每个成员单独摘要，结束时排序后合并，内存占用与嵌套深度和成员数成正比，与文档大小无关。
+// 输入按 Write 的分块依次送入，不是合法 JSON 时返回 *SyntaxError。
+type CanonicalHasher struct {
+	checker *syntaxChecker
+	root    hash.Hash
+	frames  []hashFrame
+	depth   int
+
+	state     int
+	hex       rune
+	hexDigits int
+	highSurr  rune   // 等待低位代理项的高位代理项，0 表示无
+	number    []byte // 读取中的数字
+	scratch   []byte
+	err       error
+}
+
+// NewCanonicalHasher 创建规范化摘要计算器
+func NewCanonicalHasher() *CanonicalHasher {
+	return &CanonicalHasher{checker: newSyntaxChecker(), root: sha256.New()}
+}
+
+// CanonicalHash 计算完整 JSON 数据的规范化摘要（见 CanonicalHasher）
+func CanonicalHash(data []byte) ([sha256.Size]byte, error) {
+	h := NewCanonicalHasher()
+	h.Write(data)
+	return h.Sum()
+}
+
+// Reset 清空状态以便复用
+func (h *CanonicalHasher) Reset() {
+	*h.checker = *newSyntaxChecker()
+	h.root.Reset()
+	h.depth = 0
+	h.state = chText
+	h.highSurr = 0
+	h.number = h.number[:0]
+	h.err = nil
+}
+
+// Write 送入下一个数据块，输入不合法时返回错误，之后的写入均返回同一错误
+func (h *CanonicalHasher) Write(p []byte) (int, error) {
+	if h.err != nil {
+		return 0, h.err
+	}
+	// 语法检查先于摘要处理同一数据块，摘要部分只会看到合法的前缀，无需重复校验
+	if err := h.checker.Feed(p); err != nil {
+		h.err = err
+		return 0, err
+	}
+	for i := 0; i < len(p); i++ {
+		if h.state == chString {
+			i += h.writeStringRun(p[i:])
+			if i >= len(p) {
+				break
+			}
+		}
+		h.step(p[i])
+	}
+	return len(p), nil
+}
+
+// Sum 结束输入并返回摘要，输入不完整时返回 *SyntaxError
+func (h *CanonicalHasher) Sum() ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	if h.err != nil {
+		return sum, h.err
+	}
+	if err := h.checker.Finish(); err != nil {
+		h.err = err
+		return sum, err
+	}
+	if h.state == chNumber {
+		h.flushNumber()
+	}
+	h.root.Sum(sum[:0])
+	return sum, nil
+}
+
+// sink 返回当前值写入的摘要：最近一层对象的当前成员，或顶层
+func (h *CanonicalHasher) sink() hash.Hash {
+	for i := h.depth - 1; i >= 0; i-- {
+		if h.frames[i].object {
+			return h.frames[i].member
+		}
+	}
+	return h.root
+}
+
+// push 进入对象或数组，复用同一深度上次分配的成员摘要
+func (h *CanonicalHasher) push(object bool) {
+	if h.depth == len(h.frames) {
+		h.frames = append(h.frames, hashFrame{})
+	}
+	f := &h.frames[h.depth]
+	f.object = object
+	f.members = f.members[:0]
+	f.open = false
+	if object {
+		if f.member == nil {
+			f.member = sha256.New()
+		}
+		f.member.Reset()
+	}
+	h.depth++
+}
+
+// endMember 结束对象的当前成员
+func (h *CanonicalHasher) endMember(f *hashFrame) {
+	if !f.open {
+		return
+	}
+	var sum [sha256.Size]byte
+	f.member.Sum(sum[:0])
+	f.members = append(f.members, sum)
+	f.member.Reset()
+	f.open = false
+}
+
+// popObject 结束对象：成员摘要排序后以 { 数量 摘要... } 写入外层，数量前缀避免摘要字节与结构字符混淆
+func (h *CanonicalHasher) popObject() {
+	f := &h.frames[h.depth-1]
+	h.endMember(f)
+	slices.SortFunc(f.members, func(a, b [sha256.Size]byte) int { return bytes.Compare(a[:], b[:]) })
+
+	h.depth--
+	out := h.sink()
+	h.scratch = append(h.scratch[:0], '{')
+	h.scratch = binary.AppendUvarint(h.scratch, uint64(len(f.members)))
+	out.Write(h.scratch)
+	for i := range f.members {
+		out.Write(f.members[i][:])
+	}
+	out.Write([]byte{'}'})
+}
+
+// step 处理字符串外的一个字节，以及字符串内的转义序列
+func (h *CanonicalHasher) step(c byte) {
+	switch h.state {
+	case chNumber:
+		if c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-' {
+			h.number = append(h.number, c)
+			return
+		}
+		h.flushNumber()
+		h.state = chText
+
+	case chString:
+		// writeStringRun 已处理普通字节，这里只会是引号或反斜杠
+		if c == '\\' {
+			h.state = chEscape
+			return
+		}
+		h.flushHighSurrogate()
+		h.sink().Write([]byte{'"'})
+		h.state = chText
+		return
+
+	case chEscape:
+		if c == 'u' {
+			h.state = chUnicode
+			h.hex, h.hexDigits = 0, 0
+			return
+		}
+		h.flushHighSurrogate()
+		h.writeRune(rune(unescapeByte(c)))
+		h.state = chString
+		return
+
+	case chUnicode:
+		h.hex = h.hex<<4 | rune(hexValue(c))
+		if h.hexDigits++; h.hexDigits < 4 {
+			return
+		}
+		h.state = chString
+		h.writeUnicodeEscape(h.hex)
+		return
+	}
+
+	switch c {
+	case ' ', '\t', '\n', '\r':
+	case '{':
+		h.markMember()
+		h.push(true)
+	case '[':
+		h.markMember()
+		h.sink().Write([]byte{'['})
+		h.push(false)
+	case '}':
+		h.popObject()
+	case ']':
+		h.depth--
+		h.sink().Write([]byte{']'})
+	case ',':
+		if f := &h.frames[h.depth-1]; f.object {
+			h.endMember(f)
+		} else {
+			h.sink().Write([]byte{','})
+		}
+	case ':':
+		h.sink().Write([]byte{':'})
+	case '"':
+		h.markMember()
+		h.sink().Write([]byte{'"'})
+		h.state = chString
+	default:
+		h.markMember()
+		if c == '-' || c >= '0' && c <= '9' {
+			h.number = append(h.number[:0], c)
+			h.state = chNumber
+			return
+		}
+		// true / false / null，语法检查已保证字面量合法
+		h.sink().Write([]byte{c})
+	}
+}
+
+// markMember 对象中遇到 key 时开始新成员
+func (h *CanonicalHasher) markMember() {
+	if h.depth > 0 {
+		if f := &h.frames[h.depth-1]; f.object && !f.open {
+			f.open = true
+		}
+	}
+}
+
+// writeStringRun 写入字符串内不需要特殊处理的连续字节，返回消耗的字节数
+func (h *CanonicalHasher) writeStringRun(p []byte) int {
+	n := 0
+	for n < len(p) && p[n] != '"' && p[n] != '\\' {
+		n++
+	}
+	if n > 0 {
+		h.flushHighSurrogate()
+		h.writeCanonical(p[:n])
+	}
+	return n
+}
+
+// writeUnicodeEscape 处理 \uXXXX，代理对合并为一个字符，孤立的代理项按 U+FFFD 处理（与 encoding/json 一致）
+func (h *CanonicalHasher) writeUnicodeEscape(r rune) {
+	if h.highSurr != 0 {
+		high := h.highSurr
+		h.highSurr = 0
+		if dec := utf16.DecodeRune(high, r); dec != utf8.RuneError {
+			h.writeRune(dec)
+			return
+		}
+		h.writeRune(utf8.RuneError)
+	}
+	switch {
+	case r >= 0xd800 && r < 0xdc00:
+		h.highSurr = r
+	case utf16.IsSurrogate(r):
+		h.writeRune(utf8.RuneError)
+	default:
+		h.writeRune(r)
+	}
+}
+
+// flushHighSurrogate 高位代理项之后不是低位代理项时按 U+FFFD 输出
+func (h *CanonicalHasher) flushHighSurrogate() {
+	if h.highSurr != 0 {
+		h.highSurr = 0
+		h.writeRune(utf8.RuneError)
+	}
+}
+
+// writeRune 以规范形式写入字符串中的一个字符
+func (h *CanonicalHasher) writeRune(r rune) {
+	h.writeCanonical(utf8.AppendRune(h.scratch[:0], r))
+}
+
+// writeCanonical 以规范形式写入已解码的字符串内容：只转义引号、反斜杠和控制字符
+func (h *CanonicalHasher) writeCanonical(s []byte) {
+	out := h.sink()
+	start := 0
+	for i, c := range s {
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+		out.Write(s[start:i])
+		out.Write([]byte{'\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf]})
+		start = i + 1
+	}
+	out.Write(s[start:])
+}
+
+// flushNumber 写入读取完的数字：整数按字面量，带小数或指数的按数值的最短表示
+func (h *CanonicalHasher) flushNumber() {
+	num := h.number
+	if bytes.ContainsAny(num, ".eE") {
+		if f, err := strconv.ParseFloat(string(num), 64); err == nil {
+			if f == float64(int64(f)) && f > -1e18 && f < 1e18 {
+				num = strconv.AppendInt(h.scratch[:0], int64(f), 10)
+			} else {
+				num = strconv.AppendFloat(h.scratch[:0], f, 'g', -1, 64)
+			}
+		}
+	}
+	h.sink().Write(num)
+	h.number = h.number[:0]
+}
+
+const hexDigits = "0123456789abcdef"
+
+// unescapeByte 返回单字符转义序列表示的字节
+func unescapeByte(c byte) byte {
+	switch c {
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	default: // " \ /
+		return c
+	}
+}
+
+// hexValue 返回十六进制位的值
+func hexValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
@@ -0,0 +1,131 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestCanonicalHashEquivalent(t *testing.T) {
+	pairs := [][2]string{
+		{`{"a":1,"b":2}`, `{"b":2,"a":1}`},
+		{`{"a": {"x": [1, 2], "y": null}}`, "{\n\t\"a\" : {\"y\":null,\"x\":[ 1 ,2 ]}\n}"},
+		{`{"s":"a"}`, `{"s":"\u0061"}`},
+		{`{"s":"a/b\"c"}`, `{"s":"a\/b\u0022c"}`},
+		{`{"s":"😀"}`, `{"s":"\ud83d\ude00"}`},
+		{`{"k":1}`, `{"\u006b":1}`},
+		{`{"n":1}`, `{"n":1.0}`},
+		{`{"n":100}`, `{"n":1e2}`},
+		{`{"n":0.5}`, `{"n":5E-1}`},
+		{`[{"a":1,"b":[{"c":1,"d":2}]}]`, `[{"b":[{"d":2,"c":1}],"a":1}]`},
+		{`"x"`, ` "x" `},
+	}
+	for _, p := range pairs {
+		a, err := CanonicalHash([]byte(p[0]))
+		if err != nil {
+			t.Fatalf("CanonicalHash(%s): %v", p[0], err)
+		}
+		b, err := CanonicalHash([]byte(p[1]))
+		if err != nil {
+			t.Fatalf("CanonicalHash(%s): %v", p[1], err)
+		}
+		if a != b {
+			t.Errorf("hashes differ for %s and %s", p[0], p[1])
+		}
+	}
+}
+
+func TestCanonicalHashDistinct(t *testing.T) {
+	docs := []string{
+		`{"a":1}`,
+		`{"a":"1"}`,
+		`{"a":[1,2]}`,
+		`{"a":[2,1]}`,
+		`{"a":{"b":1}}`,
+		`{"a":{},"b":1}`,
+		`{"a":[],"b":1}`,
+		`{"a":{"b":1},"c":2}`,
+		`{"a":{"b":1,"c":2}}`,
+		`{"ab":1}`,
+		`{"a":"b:1"}`,
+		`{"a":null}`,
+		`{"a":true}`,
+		`{"a":"true"}`,
+		`[["a"],"b"]`,
+		`[["a","b"]]`,
+		`{"a":"\u0000"}`,
+		`{"a":""}`,
+		`{"n":12345678901234567890}`,
+		`{"n":12345678901234567891}`,
+		`{}`,
+		`[]`,
+	}
+	seen := make(map[[32]byte]string)
+	for _, doc := range docs {
+		sum, err := CanonicalHash([]byte(doc))
+		if err != nil {
+			t.Fatalf("CanonicalHash(%s): %v", doc, err)
+		}
+		if other, ok := seen[sum]; ok {
+			t.Errorf("%s and %s hash identically", doc, other)
+		}
+		seen[sum] = doc
+	}
+}
+
+func TestCanonicalHashInvalid(t *testing.T) {
+	for _, doc := range []string{``, `{"a":1`, `{"a" 1}`, `[1,]`, `{"a":1}}`, `{"a":tru}`} {
+		_, err := CanonicalHash([]byte(doc))
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("CanonicalHash(%q) error = %v, want *SyntaxError", doc, err)
+		}
+	}
+}
+
+// TestCanonicalHashDifferential 随机文档与 encoding/json 重新编码（key 排序、转义和空白规范化）后的摘要一致，
+// 且按任意分块写入结果不变
+func TestCanonicalHashDifferential(t *testing.T) {
+	h := NewCanonicalHasher()
+	for seed := int64(0); seed < 500; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		doc := randomFuzzDocument(rng)
+		if hasDuplicateKeys(doc) {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(doc))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("seed %d: generated invalid JSON: %v", seed, err)
+		}
+		reencoded, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, err := CanonicalHash(reencoded)
+		if err != nil {
+			t.Fatalf("seed %d: CanonicalHash(%s): %v", seed, reencoded, err)
+		}
+
+		h.Reset()
+		for rest := doc; len(rest) > 0; {
+			n := min(1+rng.Intn(8), len(rest))
+			if _, err := h.Write(rest[:n]); err != nil {
+				t.Fatalf("seed %d: Write: %v\ndoc: %s", seed, err, doc)
+			}
+			rest = rest[n:]
+		}
+		got, err := h.Sum()
+		if err != nil {
+			t.Fatalf("seed %d: Sum: %v", seed, err)
+		}
+		if got != want {
+			t.Fatalf("seed %d: hash differs from re-encoded document\ndoc: %s\nre-encoded: %s", seed, doc, reencoded)
+		}
+	}
+}
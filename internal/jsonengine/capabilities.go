@@ -0,0 +1,109 @@
+package jsonengine
+
+import (
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/cpu"
+)
+
+// CapabilityReport 当前机器的 SIMD 能力和扫描器选择
+type CapabilityReport struct {
+	Arch        string   `json:"arch"`
+	AVX2        bool     `json:"avx2"`
+	AVX512      bool     `json:"avx512"` // AVX512F + AVX512BW
+	NEON        bool     `json:"neon"`
+	Scanner     string   `json:"scanner"`     // ScanStructural 对长输入选用的实现
+	Classifier  string   `json:"classifier"`  // ScanStructuralSkipStrings（处理器实际使用）的块分类实现
+	Scanners    []string `json:"scanners"`    // 可用的扫描实现
+	Classifiers []string `json:"classifiers"` // 可用的块分类实现
+}
+
+// scannerImpl 结构字符扫描实现
+type scannerImpl struct {
+	name string
+	scan func(data []byte, positions []uint32) int
+}
+
+// classifierImpl 64 字节块分类实现
+type classifierImpl struct {
+	name     string
+	classify func(data []byte, masks []uint64)
+}
+
+// Capabilities 返回当前机器的 SIMD 能力以及运行时选用的扫描实现
+// 不依赖构建标签，任何平台都可调用；NEON 目前只报告可用性，arm64 使用通用实现
+func Capabilities() CapabilityReport {
+	r := CapabilityReport{
+		Arch:   runtime.GOARCH,
+		AVX2:   cpu.X86.HasAVX2,
+		AVX512: cpu.X86.HasAVX512F && cpu.X86.HasAVX512BW,
+		NEON:   cpu.ARM64.HasASIMD,
+	}
+	for _, impl := range scannerImpls() {
+		r.Scanners = append(r.Scanners, impl.name)
+	}
+	for _, impl := range classifierImpls() {
+		r.Classifiers = append(r.Classifiers, impl.name)
+	}
+	r.Scanner = r.Scanners[0]
+	r.Classifier = r.Classifiers[0]
+	return r
+}
+
+// ScannerBenchmark 单个扫描实现的吞吐
+type ScannerBenchmark struct {
+	Name       string  `json:"name"`
+	Selected   bool    `json:"selected"` // 运行时实际选用的实现
+	Iterations int     `json:"iterations"`
+	MBPerSec   float64 `json:"mb_per_sec"`
+}
+
+// ScannerBenchmarkReport 扫描实现的微基准结果
+type ScannerBenchmarkReport struct {
+	Capabilities CapabilityReport   `json:"capabilities"`
+	InputBytes   int                `json:"input_bytes"`
+	Results      []ScannerBenchmark `json:"results"`
+}
+
+// 基准输入中重复的响应片段：带标点和转义的长文本，接近 LLM 响应体
+const benchmarkFragment = `{"index":0,"message":{"role":"assistant","content":"Step 1: call {\"tool\": [1, 2]}, then check \"done\".\n"},"finish_reason":"stop"},`
+
+// BenchmarkScanners 在当前机器上依次测量每个可用实现的吞吐
+// 包括 ScanStructural 的各实现（scan/<name>）和处理器使用的字符串感知扫描（skip_strings/<name>）。
+// size 为输入字节数，每个实现至少运行 duration。调用方负责限制参数和并发，避免占满 CPU。
+func BenchmarkScanners(size int, duration time.Duration) ScannerBenchmarkReport {
+	caps := Capabilities()
+	data := []byte(strings.Repeat(benchmarkFragment, size/len(benchmarkFragment)+1)[:size])
+	positions := make([]uint32, len(data))
+	report := ScannerBenchmarkReport{Capabilities: caps, InputBytes: len(data)}
+
+	for _, impl := range scannerImpls() {
+		scan := impl.scan
+		report.Results = append(report.Results, runScannerBenchmark("scan/"+impl.name, impl.name == caps.Scanner, data, duration, func() {
+			scan(data, positions)
+		}))
+	}
+	for _, impl := range classifierImpls() {
+		classify := impl.classify
+		report.Results = append(report.Results, runScannerBenchmark("skip_strings/"+impl.name, impl.name == caps.Classifier, data, duration, func() {
+			var state ScanState
+			scanSkipStrings(data, positions, &state, classify)
+		}))
+	}
+	return report
+}
+
+func runScannerBenchmark(name string, selected bool, data []byte, duration time.Duration, run func()) ScannerBenchmark {
+	result := ScannerBenchmark{Name: name, Selected: selected}
+	start := time.Now()
+	for result.Iterations == 0 || time.Since(start) < duration {
+		run()
+		result.Iterations++
+	}
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		result.MBPerSec = float64(len(data)) * float64(result.Iterations) / elapsed / (1 << 20)
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// captureName 返回 capture 规则在结果中的名称
+func (r *PathRule) captureName() string {
+	if name, ok := r.Value.(string); ok && name != "" {
+		return name
+	}
+	return r.Path
+}
+
+// matchCaptures 记录匹配到的 capture 规则，随后跳过的值会被收集
+func (p *PathProcessor) matchCaptures(actions []RuleAction) {
+	for _, action := range actions {
+		if action.Action != ActionCapture {
+			continue
+		}
+		if !p.capturing {
+			p.capturing = true
+			p.captureNames = p.captureNames[:0]
+			p.transformBuf = p.transformBuf[:0]
+		}
+		p.captureNames = append(p.captureNames, p.matcher.rules[action.Index].captureName())
+	}
+}
+
+// beginCapture 没有其他作用于值的操作时，以不做转换的值转换模式跳过并原样输出原值
+// 输出不变，因此不计入 applied
+func (p *PathProcessor) beginCapture() {
+	p.setValue = nil
+	p.transforming = true
+	p.transform = nil
+}
+
+// finishCapture 保存收集到的原值，同一名称多次命中时保留最后一次
+func (p *PathProcessor) finishCapture() {
+	raw := bytes.TrimSpace(p.transformBuf)
+	if p.captured == nil {
+		p.captured = make(map[string]json.RawMessage, len(p.captureNames))
+	}
+	for _, name := range p.captureNames {
+		p.captured[name] = append(json.RawMessage(nil), raw...)
+	}
+	p.capturing = false
+	p.captureNames = p.captureNames[:0]
+	if !p.transforming {
+		p.transformBuf = p.transformBuf[:0]
+	}
+}
+
+// Captured 返回本次处理中 capture 规则收集到的值，未命中时为 nil
+// 表达式引用的内部收集结果不包含在内
+func (p *PathProcessor) Captured() map[string]json.RawMessage {
+	for name := range p.captured {
+		if strings.HasPrefix(name, exprRefPrefix) {
+			return publicCaptures(p.captured)
+		}
+	}
+	return p.captured
+}
+
+// publicCaptures 去掉表达式引用的内部收集结果
+func publicCaptures(captured map[string]json.RawMessage) map[string]json.RawMessage {
+	var public map[string]json.RawMessage
+	for name, raw := range captured {
+		if strings.HasPrefix(name, exprRefPrefix) {
+			continue
+		}
+		if public == nil {
+			public = make(map[string]json.RawMessage, len(captured))
+		}
+		public[name] = raw
+	}
+	return public
+}
+
+// lookupExprRef 返回表达式引用的字段在本次处理中已收集到的值
+func (p *PathProcessor) lookupExprRef(path string) (json.RawMessage, bool) {
+	raw, ok := p.captured[exprRefPrefix+path]
+	return raw, ok
+}
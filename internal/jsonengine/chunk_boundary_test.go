@@ -0,0 +1,143 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+)
+
+// geminiResponses 真实的 Gemini 响应（已缩短 base64 和签名），覆盖多字节 UTF-8、转义序列、代理对和转义的键名
+var geminiResponses = []string{
+	`{"candidates": [{"content": {"parts": [{"text": "**Thinking about the request**\n\nThe user wants a haiku.","thought": true},{"text": "古池や\n蛙飛び込む\n水の音 🐸 \"quoted\" \\ end","thoughtSignature": "CiQB0e2Kb1Xc4l9Ej7m+Qx/2a3v0Zq9k0sYQ6d8Vw1ZbJx0KUgHR7YpS0Zy3u3dJqz1m3Vv1bKx0="}],"role": "model"},"finishReason": "STOP","index": 0,"safetyRatings": [{"category": "HARM_CATEGORY_HATE_SPEECH","probability": "NEGLIGIBLE"}]}],"usageMetadata": {"promptTokenCount": 12,"candidatesTokenCount": 21,"totalTokenCount": 57,"thoughtsTokenCount": 24},"modelVersion": "gemini-2.5-flash","responseId": "x2xRaJ2fK8Oe1MkP2b7W8Ak"}`,
+	`{"candidates":[{"content":{"parts":[{"text":"Here is the image you asked for:"},{"inlineData":{"mimeType":"image/png","data":"iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR4nGNgYGD4DwABBAEAwS2OUAAAAABJRU5ErkJggg=="}}],"role":"model"},"finishReason":"STOP","index":0}],"usageMetadata":{"promptTokenCount":9,"candidatesTokenCount":1290,"totalTokenCount":1299,"promptTokensDetails":[{"modality":"TEXT","tokenCount":9}]},"modelVersion":"gemini-2.5-flash-image-preview"}`,
+	`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"location":"Zürich","unit":"celsius","days":[1,2,3],"detail":null}},"thoughtSignature":"EpsCCpgCAdHtim9="},{"text":"🌤️ Checking \"weather\"\/forecast"}],"role":"model"},"finishReason":"STOP","avgLogprobs":-0.0513,"index":0}],"usageMetadata":{"promptTokenCount":58,"candidatesTokenCount":17,"totalTokenCount":75},"modelVersion":"gemini-2.5-pro"}`,
+	`{"candidates" : [ { "content" : { "parts" : [ { "text" : "a, b: {c} [d] \"e\" 1e10 true null" , "thoughtSignature" : "x" } ] , "role" : "model" } , "finishReason" : "MAX_TOKENS" } ] , "usageMetadata" : { "promptTokenCount" : 5 , "totalTokenCount" : 1.5e3 } }`,
+	`{"candidates":[{"content":{"parts":[{"text":"smile \ud83d\ude00 \u00e9t\u00e9","\u0074houghtSignature":"sig\/x"}],"role":"model"},"finish\u0052eason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"totalTokenCount":4}}`,
+}
+
+var truncateMax = 7.0
+
+// chunkBoundaryRuleSets 覆盖删除、写入字符串中间、改名、掩码、截断和移除 base64 等跨块需要保留状态的操作
+var chunkBoundaryRuleSets = []struct {
+	name  string
+	rules []PathRule
+	opts  []PathEngineOption
+}{
+	{name: "remove signature", rules: []PathRule{
+		{Path: "candidates[*].content.parts[*].thoughtSignature", Action: ActionRemove},
+	}},
+	{name: "set text", rules: []PathRule{
+		{Path: "candidates[*].content.parts[*].text", Action: ActionSet, Value: "替换 ✓ \"new\""},
+		{Path: "usageMetadata.totalTokenCount", Action: ActionSet, Value: 0},
+	}},
+	{name: "set object and add", rules: []PathRule{
+		{Path: "candidates[*].content.parts[*].functionCall.args", Action: ActionSet, Value: map[string]any{"location": "Bern"}},
+		{Path: "candidates[*].content.parts[*].inlineData", Action: ActionSet, Value: nil},
+		{Path: "candidates[*].grounding", Action: ActionAdd, Value: []any{"a", 1}},
+	}},
+	{name: "rename and keep", rules: []PathRule{
+		{Path: "candidates[*].finishReason", Action: ActionRename, Value: "finish_reason"},
+		{Path: "usageMetadata.promptTokenCount", Action: ActionKeep},
+	}},
+	{name: "mask and truncate", rules: []PathRule{
+		{Path: "candidates[*].content.parts[*].thoughtSignature", Action: ActionMask},
+		{Path: "candidates[*].content.parts[*].text", Action: ActionTruncate, Max: &truncateMax, Value: "…"},
+	}},
+	{name: "strip base64 and capture", rules: []PathRule{
+		{Path: "candidates[*].content.parts[*].inlineData", Action: ActionStripBase64},
+		{Path: "modelVersion", Action: ActionCapture},
+	}},
+	{name: "normalized keys", rules: []PathRule{
+		{Path: "candidates[*].finishReason", Action: ActionSet, Value: "STOP"},
+	}, opts: []PathEngineOption{WithNormalizedKeys()}},
+}
+
+// processSplit 在 offsets 处把 input 拆成多个数据块依次送入同一个处理器
+func processSplit(t *testing.T, engine *PathEngine, input []byte, offsets ...int) []byte {
+	t.Helper()
+	proc := engine.GetProcessor()
+	defer engine.ReleaseProcessor(proc)
+
+	var out bytes.Buffer
+	prev := 0
+	for _, offset := range append(offsets, len(input)) {
+		if err := proc.ProcessChunk(input[prev:offset], &out); err != nil {
+			t.Fatalf("ProcessChunk(%d:%d) error: %v", prev, offset, err)
+		}
+		prev = offset
+	}
+	if err := proc.Finish(&out); err != nil {
+		t.Fatalf("Finish error: %v", err)
+	}
+	return out.Bytes()
+}
+
+// TestPathEngineChunkBoundaries 在每个字节偏移处把真实响应拆成两块（以及逐字节送入），
+// 结果必须与整体处理一致：键、字符串值、转义序列和多字节字符跨块时处理器需保留读取状态
+func TestPathEngineChunkBoundaries(t *testing.T) {
+	for _, rs := range chunkBoundaryRuleSets {
+		engine, err := NewPathEngine(rs.rules, rs.opts...)
+		if err != nil {
+			t.Fatalf("%s: NewPathEngine error: %v", rs.name, err)
+		}
+		for i, doc := range geminiResponses {
+			input := []byte(doc)
+			want := processSplit(t, engine, input)
+			if !json.Valid(want) {
+				t.Fatalf("%s/response %d: invalid output %s", rs.name, i, want)
+			}
+
+			for offset := 1; offset < len(input); offset++ {
+				if got := processSplit(t, engine, input, offset); !bytes.Equal(got, want) {
+					t.Fatalf("%s/response %d split at %d (%q|%q):\ngot  %s\nwant %s",
+						rs.name, i, offset, input[max(offset-10, 0):offset], input[offset:min(offset+10, len(input))], got, want)
+				}
+			}
+
+			offsets := make([]int, 0, len(input))
+			for offset := 1; offset < len(input); offset++ {
+				offsets = append(offsets, offset)
+			}
+			if got := processSplit(t, engine, input, offsets...); !bytes.Equal(got, want) {
+				t.Fatalf("%s/response %d byte by byte:\ngot  %s\nwant %s", rs.name, i, got, want)
+			}
+		}
+	}
+}
+
+// TestPathEngineChunkBoundariesStrict 通过 Process 按读取边界拆分输入，严格模式的语法检查同样需要跨块保留状态
+func TestPathEngineChunkBoundariesStrict(t *testing.T) {
+	engine, err := NewPathEngine(chunkBoundaryRuleSets[1].rules, WithStrictMode(), WithCompact())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, doc := range geminiResponses {
+		input := []byte(doc)
+		want, err := engine.ProcessBytes(input)
+		if err != nil {
+			t.Fatalf("response %d: %v", i, err)
+		}
+		for offset := 1; offset < len(input); offset++ {
+			var out bytes.Buffer
+			reader := io.MultiReader(bytes.NewReader(input[:offset]), bytes.NewReader(input[offset:]))
+			if err := engine.Process(reader, &out); err != nil {
+				t.Fatalf("response %d split at %d: %v", i, offset, err)
+			}
+			if !bytes.Equal(out.Bytes(), want) {
+				t.Fatalf("response %d split at %d:\ngot  %s\nwant %s", i, offset, out.Bytes(), want)
+			}
+		}
+	}
+
+	// 截断的输入在任意位置拆分都报告同一个语法错误
+	truncated := []byte(geminiResponses[0][:len(geminiResponses[0])/2])
+	for offset := 1; offset < len(truncated); offset++ {
+		reader := io.MultiReader(bytes.NewReader(truncated[:offset]), bytes.NewReader(truncated[offset:]))
+		var syntaxErr *SyntaxError
+		if err := engine.Process(reader, io.Discard); !errors.As(err, &syntaxErr) || syntaxErr.Offset != int64(len(truncated)) {
+			t.Fatalf("truncated input split at %d: %v", offset, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package jsonengine
+
+import (
+	"fmt"
+	"slices"
+)
+
+// CompiledRules 编译后的规则集：解析好的路径和构建好的匹配器（AC 自动机）
+// 编译后不再修改，可在多个 goroutine 间共享，并用于创建任意数量的 PathEngine（见 NewPathEngineFromRules）。
+// 同一组规则需要不同的引擎选项（严格模式、格式、限制、回调等）时只需编译一次。
+type CompiledRules struct {
+	rules   []PathRule // 有效的用户规则，不含表达式引用的内部规则
+	matcher *PathMatcher
+}
+
+// CompileRules 解析规则路径并构建匹配器，路径为空的规则被忽略
+// 冲突解析方式影响匹配器的结构，因此在编译时确定，空值表示默认的 ConflictHighestPriority
+func CompileRules(rules []PathRule, mode ConflictMode) (*CompiledRules, error) {
+	// 过滤无效规则
+	validRules := make([]PathRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Path != "" {
+			// 解析路径
+			segments, err := ParsePath(r.Path)
+			if err != nil {
+				return nil, err
+			}
+			r.segments = segments
+			validRules = append(validRules, r)
+		}
+	}
+
+	// 表达式引用的字段以内部 capture 规则加入匹配器，排在用户规则之后，不改变规则下标
+	matcherRules := validRules
+	if refs := exprRefRules(validRules); len(refs) > 0 {
+		if err := checkExprRefs(validRules); err != nil {
+			return nil, err
+		}
+		matcherRules = append(validRules[:len(validRules):len(validRules)], refs...)
+	}
+
+	// 构建匹配器
+	matcher, err := BuildMatcher(matcherRules)
+	if err != nil {
+		return nil, err
+	}
+	if err := matcher.SetConflictMode(mode); err != nil {
+		return nil, err
+	}
+
+	return &CompiledRules{rules: validRules, matcher: matcher}, nil
+}
+
+// Rules 返回有效规则的副本
+func (c *CompiledRules) Rules() []PathRule {
+	return slices.Clone(c.rules)
+}
+
+// HasRules 检查是否有规则
+func (c *CompiledRules) HasRules() bool {
+	return c.matcher.HasRules()
+}
+
+// ConflictMode 返回编译时确定的冲突解析方式
+func (c *CompiledRules) ConflictMode() ConflictMode {
+	return c.matcher.conflictMode
+}
+
+// NewPathEngineFromRules 以已编译的规则创建引擎，不重新解析路径或构建匹配器
+// 引擎只持有规则集的引用，创建开销与选项数量相当，适合按请求创建带不同选项的引擎。
+// 冲突解析方式已在编译时确定，opts 中的 WithConflictMode 与之不同时返回错误。
+func NewPathEngineFromRules(compiled *CompiledRules, opts ...PathEngineOption) (*PathEngine, error) {
+	engine := newPathEngine(opts)
+	if mode := engine.conflictMode; mode != "" {
+		if !mode.valid() {
+			return nil, fmt.Errorf("unsupported rule conflict mode: %q", mode)
+		}
+		if mode != compiled.ConflictMode() {
+			return nil, fmt.Errorf("rule conflict mode %q differs from the compiled rules (%q)", mode, compiled.ConflictMode())
+		}
+	}
+	engine.setCompiled(compiled)
+	return engine, nil
+}
+
+// Compiled 返回引擎使用的已编译规则，可用于创建共享同一规则集的其他引擎
+func (e *PathEngine) Compiled() *CompiledRules {
+	return e.compiled.Load()
+}
+
+// setCompiled 切换引擎使用的规则集，每次处理开始时取用当时的规则集
+func (e *PathEngine) setCompiled(compiled *CompiledRules) {
+	e.compiled.Store(compiled)
+}
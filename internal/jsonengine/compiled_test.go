@@ -0,0 +1,76 @@
+package jsonengine
+
+import (
+	"testing"
+)
+
+func TestCompiledRulesShared(t *testing.T) {
+	compiled, err := CompileRules([]PathRule{
+		{Path: "a", Action: ActionRemove},
+		{Path: ""},
+		{Path: "b", Action: ActionSet, Value: 2},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compiled.Rules()) != 2 || compiled.ConflictMode() != ConflictHighestPriority {
+		t.Fatalf("Rules = %+v, ConflictMode = %q", compiled.Rules(), compiled.ConflictMode())
+	}
+
+	plain, err := NewPathEngineFromRules(compiled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict, err := NewPathEngineFromRules(compiled, WithStrictMode(), WithCompact(), WithConflictMode(ConflictHighestPriority))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Compiled() != compiled || strict.Compiled() != compiled {
+		t.Fatal("engines do not share the compiled rules")
+	}
+
+	input := []byte(`{"a": 1, "b": 1, "c": 3}`)
+	if out, err := plain.ProcessBytes(input); err != nil || string(out) != `{ "b":2, "c": 3}` {
+		t.Errorf("plain = %s, %v", out, err)
+	}
+	if out, err := strict.ProcessBytes(input); err != nil || string(out) != `{"b":2,"c":3}` {
+		t.Errorf("strict compact = %s, %v", out, err)
+	}
+	if _, err := strict.ProcessBytes([]byte(`{"a":`)); err == nil {
+		t.Error("strict engine accepted truncated input")
+	}
+
+	// AddRule 重新编译，不影响共享同一规则集的引擎
+	if err := plain.AddRule(PathRule{Path: "c", Action: ActionRemove}); err != nil {
+		t.Fatal(err)
+	}
+	if plain.Compiled() == compiled || len(compiled.Rules()) != 2 {
+		t.Fatal("AddRule modified the shared compiled rules")
+	}
+	if out, err := plain.ProcessBytes(input); err != nil || string(out) != `{ "b":2}` {
+		t.Errorf("after AddRule = %s, %v", out, err)
+	}
+	if out, err := strict.ProcessBytes(input); err != nil || string(out) != `{"b":2,"c":3}` {
+		t.Errorf("other engine after AddRule = %s, %v", out, err)
+	}
+}
+
+func TestCompiledRulesErrors(t *testing.T) {
+	if _, err := CompileRules([]PathRule{{Path: "a./unterminated", Action: ActionRemove}}, ""); err == nil {
+		t.Error("expected a path error")
+	}
+	if _, err := CompileRules([]PathRule{{Path: "a", Action: ActionRemove}}, "unknown"); err == nil {
+		t.Error("expected a conflict mode error")
+	}
+
+	compiled, err := CompileRules([]PathRule{{Path: "a", Action: ActionRemove}}, ConflictAllApply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPathEngineFromRules(compiled, WithConflictMode(ConflictHighestPriority)); err == nil {
+		t.Error("expected an error for a conflict mode differing from the compiled rules")
+	}
+	if _, err := NewPathEngineFromRules(compiled, WithConflictMode(ConflictAllApply)); err != nil {
+		t.Errorf("matching conflict mode: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package jsonengine
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestPathEngineConcurrentUse 多个 goroutine 同时用同一个引擎处理，另一个 goroutine 同时 AddRule，
+// 配合 go test -race 检查数据竞争；每次处理的结果必须对应某一时刻完整的规则集
+func TestPathEngineConcurrentUse(t *testing.T) {
+	var matches atomic.Int64
+	engine, err := NewPathEngine([]PathRule{
+		{Path: "drop", Action: ActionRemove},
+		{Path: "items[*].secret", Action: ActionMask},
+		{Path: "model", Action: ActionCapture},
+	}, WithStrictMode(), WithOnMatch(func(MatchEvent) { matches.Add(1) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const added = 20
+	input := []byte(`{"drop":1,"model":"m","items":[{"secret":"abcdef"},{"secret":"xyz"}]}`)
+	stream := "data: " + string(input) + "\n\ndata: [DONE]\n\n"
+
+	// 已添加 n 条 add 规则时的输出前缀检查：原有规则始终生效，新增字段按添加顺序出现
+	check := func(out []byte) error {
+		s := string(out)
+		if strings.Contains(s, `"drop"`) || strings.Contains(s, "abcdef") {
+			return fmt.Errorf("base rules not applied: %s", s)
+		}
+		for i := added - 1; i > 0; i-- {
+			if strings.Contains(s, fmt.Sprintf(`"k%d"`, i)) && !strings.Contains(s, fmt.Sprintf(`"k%d"`, i-1)) {
+				return fmt.Errorf("rule k%d applied without k%d: %s", i, i-1, s)
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				var err error
+				var out bytes.Buffer
+				switch i % 4 {
+				case 0:
+					err = engine.Process(bytes.NewReader(input), &out)
+				case 1:
+					var b []byte
+					b, err = engine.ProcessBytes(input)
+					out.Write(b)
+				case 2:
+					_, err = engine.ProcessSSE(strings.NewReader(stream), &out)
+				default:
+					_, err = engine.Explain(input)
+					out.WriteString(`{}`)
+				}
+				if err == nil {
+					err = check(out.Bytes())
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+				_ = engine.Stats()
+			}
+		}()
+	}
+
+	for i := 0; i < added; i++ {
+		if err := engine.AddRule(PathRule{Path: fmt.Sprintf("k%d", i), Action: ActionAdd, Value: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got := len(engine.Rules()); got != 3+added {
+		t.Errorf("rules = %d, want %d", got, 3+added)
+	}
+	out, err := engine.ProcessBytes(input)
+	if err != nil || !strings.Contains(string(out), fmt.Sprintf(`"k%d":%d`, added-1, added-1)) {
+		t.Errorf("after AddRule = %s, %v", out, err)
+	}
+	if matches.Load() == 0 {
+		t.Error("OnMatch was not called")
+	}
+}
+
+// TestCompiledRulesConcurrentEngines 同一 CompiledRules 被多个 goroutine 各自创建的引擎同时使用
+func TestCompiledRulesConcurrentEngines(t *testing.T) {
+	compiled, err := CompileRules([]PathRule{
+		{Path: "a", Action: ActionSet, Value: "x"},
+		{Path: "b[-1]", Action: ActionRemove},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(compact bool) {
+			defer wg.Done()
+			var opts []PathEngineOption
+			want := `{"a":"x", "b":[1, 2]}`
+			if compact {
+				opts = append(opts, WithCompact())
+				want = `{"a":"x","b":[1,2]}`
+			}
+			engine, err := NewPathEngineFromRules(compiled, opts...)
+			if err != nil {
+				errs <- err
+				return
+			}
+			for i := 0; i < 100; i++ {
+				out, err := engine.ProcessBytes([]byte(`{"a":1, "b":[1, 2, 3]}`))
+				if err != nil || string(out) != want {
+					errs <- fmt.Errorf("compact=%v: got %s, %v; want %s", compact, out, err, want)
+					return
+				}
+			}
+		}(g%2 == 0)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,300 @@
+package jsonengine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ConflictMode 多条规则作用于同一字段值时的冲突解析方式
+// 参与冲突的是 remove、set 以及 mask/transform/clamp/copy 等改写值的操作；
+// add、rename、keep 不改写现有值，始终按各自语义生效。
+// 候选规则包括匹配路径上的所有规则，例如对 a.b 而言，a.b、a.*、a./^b/ 上的规则都是候选。
+type ConflictMode string
+
+const (
+	// ConflictHighestPriority 优先级最高的规则生效（默认）
+	// 优先级相同时，路径更具体的规则优先（精确键 > 键模式 > *，[n] > [-n] > [start:end] > [*]），
+	// 仍相同时按 remove > set > 值转换的顺序选择。未设置 priority 时与旧版行为一致。
+	ConflictHighestPriority ConflictMode = "highest_priority"
+	// ConflictFirstMatch 规则列表中排在最前的候选规则生效，忽略 priority
+	ConflictFirstMatch ConflictMode = "first_match"
+	// ConflictAllApply 所有候选规则依次生效：按 priority 从高到低、同优先级按规则顺序执行，
+	// 后执行的 set 覆盖之前的结果，值转换作用于当前结果，remove 删除字段后不再执行其余规则；
+	// copy 总是最后执行，复制的是最终值
+	ConflictAllApply ConflictMode = "all_apply"
+)
+
+// valid 检查冲突解析方式是否受支持
+func (m ConflictMode) valid() bool {
+	switch m {
+	case "", ConflictHighestPriority, ConflictFirstMatch, ConflictAllApply:
+		return true
+	}
+	return false
+}
+
+// WithConflictMode 设置规则冲突解析方式，见 ConflictMode
+func WithConflictMode(mode ConflictMode) PathEngineOption {
+	return func(e *PathEngine) {
+		e.conflictMode = mode
+	}
+}
+
+// SetConflictMode 设置匹配器的冲突解析方式
+func (m *PathMatcher) SetConflictMode(mode ConflictMode) error {
+	if !mode.valid() {
+		return fmt.Errorf("unsupported rule conflict mode: %q", mode)
+	}
+	if mode == "" {
+		mode = ConflictHighestPriority
+	}
+	m.conflictMode = mode
+	return nil
+}
+
+// collectsCandidates 检查冲突解析是否需要同级的所有匹配节点
+// 默认模式且未设置 priority 时只看最具体的节点，保持旧版的匹配路径
+func (m *PathMatcher) collectsCandidates() bool {
+	return m.hasPriorities || (m.conflictMode != "" && m.conflictMode != ConflictHighestPriority)
+}
+
+// matchingChildren 按具体程度从高到低返回所有匹配的子节点
+func (n *ACNode) matchingChildren(dst []*ACNode, key string, isArray bool, arrayIdx, arrayLen int) []*ACNode {
+	if isArray {
+		if child := n.children["["+itoa(arrayIdx)+"]"]; child != nil {
+			dst = append(dst, child)
+		}
+		if child := n.negativeIndexChild(arrayIdx, arrayLen); child != nil {
+			dst = append(dst, child)
+		}
+		for _, sc := range n.slices {
+			if sc.seg.MatchIndex(arrayIdx, arrayLen) {
+				dst = append(dst, sc.node)
+			}
+		}
+		if n.arrayAll != nil {
+			dst = append(dst, n.arrayAll)
+		}
+		return dst
+	}
+
+	if child := n.children[key]; child != nil {
+		dst = append(dst, child)
+	}
+	for _, pc := range n.patterns {
+		if pc.seg.MatchKey(key) {
+			dst = append(dst, pc.node)
+		}
+	}
+	if n.wildcard != nil {
+		dst = append(dst, n.wildcard)
+	}
+	return dst
+}
+
+// matchCandidates 同 MatchElement，但返回所有匹配节点上的操作作为冲突候选
+// 下一状态仍为最具体的节点；p.candidateRanks 记录每个候选所属节点的具体程度（越小越具体）
+func (p *PathProcessor) matchCandidates(state *ACNode, key string, isArray bool, arrayIdx, arrayLen int) (*ACNode, []RuleAction) {
+	m := p.matcher
+	if state == nil {
+		state = m.root
+	}
+
+	p.candidates = p.candidates[:0]
+	p.candidateRanks = p.candidateRanks[:0]
+
+	for node := state; node != nil; node = node.fail {
+		p.childBuf = node.matchingChildren(p.childBuf[:0], key, isArray, arrayIdx, arrayLen)
+		if len(p.childBuf) > 0 {
+			for rank, child := range p.childBuf {
+				for _, action := range child.output {
+					if !p.hasCandidate(action.Index) {
+						p.candidates = append(p.candidates, action)
+						p.candidateRanks = append(p.candidateRanks, rank)
+					}
+				}
+			}
+			return p.childBuf[0], p.candidates
+		}
+		if node == m.root {
+			break
+		}
+	}
+	return m.root, nil
+}
+
+// hasCandidate 检查规则是否已在候选列表中（失败指针合并的输出可能在多个节点上重复）
+func (p *PathProcessor) hasCandidate(index int) bool {
+	for _, c := range p.candidates {
+		if c.Index == index {
+			return true
+		}
+	}
+	return false
+}
+
+// actionPrecedence 优先级相同时操作的先后：remove > set > 值转换，不改写值的操作为 0
+func actionPrecedence(a Action) int {
+	switch {
+	case a == ActionRemove:
+		return 3
+	case a == ActionSet:
+		return 2
+	case a.transformsValue():
+		return 1
+	}
+	return 0
+}
+
+// resolveValueAction 按冲突解析方式从候选操作中得出作用于字段值的操作
+// ranks 为 nil 时所有候选视为同一节点；没有改写值的操作时返回 false
+func (p *PathProcessor) resolveValueAction(actions []RuleAction, ranks []int) (RuleAction, bool) {
+	rank := func(i int) int {
+		if ranks == nil {
+			return 0
+		}
+		return ranks[i]
+	}
+
+	switch p.matcher.conflictMode {
+	case ConflictFirstMatch:
+		best := -1
+		for i, action := range actions {
+			if actionPrecedence(action.Action) > 0 && (best < 0 || action.Index < actions[best].Index) {
+				best = i
+			}
+		}
+		if best < 0 {
+			return RuleAction{}, false
+		}
+		p.recordResolved(actions[best].Index)
+		return actions[best], true
+
+	case ConflictAllApply:
+		return p.applyAll(actions)
+
+	default:
+		best := -1
+		for i, action := range actions {
+			prec := actionPrecedence(action.Action)
+			if prec == 0 {
+				continue
+			}
+			if best < 0 {
+				best = i
+				continue
+			}
+			b := actions[best]
+			switch {
+			case action.Priority != b.Priority:
+				if action.Priority > b.Priority {
+					best = i
+				}
+			case rank(i) != rank(best):
+				if rank(i) < rank(best) {
+					best = i
+				}
+			case prec > actionPrecedence(b.Action):
+				best = i
+			}
+		}
+		if best < 0 {
+			return RuleAction{}, false
+		}
+		p.recordResolved(actions[best].Index)
+		return actions[best], true
+	}
+}
+
+// recordResolved Explain 模式或设置了规则生效回调时记录冲突解析得出的规则
+func (p *PathProcessor) recordResolved(indexes ...int) {
+	if p.recordsResolved() {
+		p.resolved = append(p.resolved[:0], indexes...)
+	}
+}
+
+// recordsResolved 检查是否需要记录冲突解析得出的规则
+func (p *PathProcessor) recordsResolved() bool {
+	return p.explain != nil || p.onMatch != nil
+}
+
+// applyAll 依次执行所有候选操作，合并为一个等效操作
+func (p *PathProcessor) applyAll(actions []RuleAction) (RuleAction, bool) {
+	p.ordered = p.ordered[:0]
+	for _, action := range actions {
+		if actionPrecedence(action.Action) > 0 {
+			p.ordered = append(p.ordered, action)
+		}
+	}
+	switch len(p.ordered) {
+	case 0:
+		return RuleAction{}, false
+	case 1:
+		p.recordResolved(p.ordered[0].Index)
+		return p.ordered[0], true
+	}
+
+	sort.SliceStable(p.ordered, func(i, j int) bool {
+		a, b := p.ordered[i], p.ordered[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.Index < b.Index
+	})
+
+	record := p.recordsResolved()
+	if record {
+		p.resolved = p.resolved[:0]
+	}
+
+	var setValue []byte
+	var transforms, copies []func([]byte) []byte
+	for _, action := range p.ordered {
+		if record {
+			p.resolved = append(p.resolved, action.Index)
+		}
+		switch action.Action {
+		case ActionRemove:
+			return RuleAction{Index: action.Index, Action: ActionRemove}, true
+		case ActionSet:
+			value := action.ValueBytes
+			if action.expr != nil {
+				// 表达式无法计算时该 set 不生效，保留之前的结果
+				var ok bool
+				if value, ok = action.expr.eval(p.lookupExprRef); !ok {
+					continue
+				}
+			} else if len(value) == 0 {
+				value = marshalValue(action.Value)
+			}
+			setValue = value
+			transforms = transforms[:0]
+		case ActionCopy:
+			copies = append(copies, action.Transform)
+		default:
+			transforms = append(transforms, action.Transform)
+		}
+	}
+
+	// copy 在值后追加 ,"target":值，不能再被其他转换处理，因此最后执行，复制的是最终值
+	chain := func(raw []byte) []byte {
+		for _, fn := range transforms {
+			raw = fn(raw)
+		}
+		if len(copies) == 0 {
+			return raw
+		}
+		out := append([]byte(nil), raw...)
+		for _, fn := range copies {
+			out = append(out, fn(raw)[len(raw):]...)
+		}
+		return out
+	}
+	if setValue != nil {
+		return RuleAction{Action: ActionSet, ValueBytes: chain(setValue)}, true
+	}
+	if len(transforms) == 0 && len(copies) == 0 {
+		return RuleAction{}, false
+	}
+	return RuleAction{Action: ActionTransform, Transform: chain}, true
+}
@@ -1,7 +1,10 @@
 package jsonengine
 
 import (
+	"encoding/json"
 	"io"
+	"sync"
+	"sync/atomic"
 )
 
 // Engine JSON 操作引擎
@@ -64,10 +67,31 @@ func (e *Engine) ProcessTo(input io.Reader, output io.Writer) error {
 
 // PathEngine 路径过滤引擎
 // 支持嵌套路径过滤，使用 SIMD 加速和 AC 自动机
+//
+// 并发：创建后可被多个 goroutine 同时使用，所有处理方法（Process、ProcessBytes、ProcessSSE、
+// Explain 等）和 Stats 都是并发安全的。每次处理从池中取独立的处理器，只读共享已编译的规则集；
+// AddRule 以 copy-on-write 替换规则集，可与处理并发调用。选项中的回调（WithOnMatch、WithProgress）
+// 会在处理所在的 goroutine 中被并发调用，需由调用方保证其并发安全。
+// 通过 GetProcessor 取得的处理器只能在单个 goroutine 中使用。
 type PathEngine struct {
-	matcher   *PathMatcher
-	rules     []PathRule
+	compiled  atomic.Pointer[CompiledRules] // AddRule 时整体替换，见 PathEngine 的并发说明
+	addMu     sync.Mutex                    // 串行化 AddRule
 	chunkSize int
+	strict    bool
+	format    *outputFormat
+	limits    limits
+	schema    *Schema
+	relaxed   bool
+
+	removeEmptyParents bool
+
+	normalizeKeys bool
+	conflictMode  ConflictMode
+	progress      func(Progress)
+	onMatch       func(MatchEvent)
+	matchValues   bool
+
+	stats engineStats // 累计处理统计（见 stats.go）
 }
 
 // PathEngineOption 引擎配置选项
@@ -82,39 +106,37 @@ func WithChunkSize(size int) PathEngineOption {
 	}
 }
 
-// NewPathEngine 创建路径过滤引擎
-func NewPathEngine(rules []PathRule, opts ...PathEngineOption) (*PathEngine, error) {
-	// 过滤无效规则
-	validRules := make([]PathRule, 0, len(rules))
-	for _, r := range rules {
-		if r.Path != "" {
-			// 解析路径
-			segments, err := ParsePath(r.Path)
-			if err != nil {
-				return nil, err
-			}
-			r.segments = segments
-			validRules = append(validRules, r)
-		}
+// WithStrictMode 启用严格模式
+// 每个数据块在处理前先做语法检查，输入不是合法 JSON 时返回 *SyntaxError（含字节偏移和路径），
+// 不再输出部分或错乱的结果。出错前已处理的数据块仍会写入 output，需要整体回退时由调用方缓冲输出。
+// 无规则时同样会检查输入。
+func WithStrictMode() PathEngineOption {
+	return func(e *PathEngine) {
+		e.strict = true
 	}
+}
 
-	// 构建匹配器
-	matcher, err := BuildMatcher(validRules)
+// NewPathEngine 创建路径过滤引擎
+// 等价于 CompileRules 后调用 NewPathEngineFromRules；同一组规则需要多个引擎时可先编译再共享
+func NewPathEngine(rules []PathRule, opts ...PathEngineOption) (*PathEngine, error) {
+	engine := newPathEngine(opts)
+	compiled, err := CompileRules(rules, engine.conflictMode)
 	if err != nil {
 		return nil, err
 	}
+	engine.setCompiled(compiled)
+	return engine, nil
+}
 
+// newPathEngine 创建应用了选项、尚未设置规则的引擎
+func newPathEngine(opts []PathEngineOption) *PathEngine {
 	engine := &PathEngine{
-		matcher:   matcher,
-		rules:     validRules,
 		chunkSize: 512 * 1024, // 默认 512KB
 	}
-
 	for _, opt := range opts {
 		opt(engine)
 	}
-
-	return engine, nil
+	return engine
 }
 
 // NewPathEngineFromLegacy 从旧格式规则创建路径引擎（向后兼容）
@@ -123,22 +145,144 @@ func NewPathEngineFromLegacy(rules []Rule, opts ...PathEngineOption) (*PathEngin
 	return NewPathEngine(pathRules, opts...)
 }
 
+// ProcessResult 单次处理的结果
+type ProcessResult struct {
+	// Applied 实际生效的操作次数，为 0 时输出应与输入逐字节一致
+	Applied int
+	// Captured capture 规则收集到的原始 JSON 值（改写之前），键为规则的结果名称
+	Captured map[string]json.RawMessage
+	// Stats 本次处理的统计，同时计入引擎的累计统计（见 PathEngine.Stats）
+	Stats EngineStats
+}
+
 // Process 流式处理 JSON 数据
 func (e *PathEngine) Process(input io.Reader, output io.Writer) error {
-	if !e.matcher.HasRules() {
-		_, err := io.Copy(output, input)
-		return err
+	_, err := e.ProcessWithResult(input, output)
+	return err
+}
+
+// ProcessWithResult 流式处理 JSON 数据并返回处理结果
+func (e *PathEngine) ProcessWithResult(input io.Reader, output io.Writer) (ProcessResult, error) {
+	if e.passthrough() {
+		n, err := io.Copy(output, input)
+		e.recordPassthrough(n)
+		return ProcessResult{Stats: EngineStats{Documents: 1, BytesIn: n, BytesOut: n}}, err
 	}
 
 	// 获取处理器
-	proc := GetPathProcessor(e.matcher)
+	proc := e.GetProcessor()
+	defer PutPathProcessor(proc)
+
+	var buffered appendWriter
+	err := e.process(proc, input, e.wrapOutput(e.schemaOutput(output, &buffered)))
+	if err == nil {
+		err = e.checkSchema(buffered.buf)
+	}
+	stats := proc.Stats()
+	e.stats.add(stats)
+	return ProcessResult{Applied: proc.Applied(), Captured: proc.Captured(), Stats: stats}, err
+}
+
+// ProcessBytes 处理完整的 JSON 数据并返回结果
+// 直接在输入切片上分块处理，省去 bytes.Reader 和 bytes.Buffer 的中转与读缓冲区分配，
+// 输出按输入大小一次性分配。无规则时直接返回 input 本身（不复制）。
+// 严格模式下先整体校验，出错时不产生任何输出。
+func (e *PathEngine) ProcessBytes(input []byte) ([]byte, error) {
+	if e.passthrough() {
+		e.recordPassthrough(int64(len(input)))
+		return input, nil
+	}
+	return e.AppendProcess(make([]byte, 0, len(input)+len(input)/8+64), input)
+}
+
+// ProcessBytesWithResult 同 ProcessBytes，并返回处理结果
+func (e *PathEngine) ProcessBytesWithResult(input []byte) ([]byte, ProcessResult, error) {
+	if e.passthrough() {
+		n := int64(len(input))
+		e.recordPassthrough(n)
+		return input, ProcessResult{Stats: EngineStats{Documents: 1, BytesIn: n, BytesOut: n}}, nil
+	}
+	return e.appendProcess(make([]byte, 0, len(input)+len(input)/8+64), input)
+}
+
+// AppendProcess 处理 input 并将结果追加到 dst，返回追加后的切片
+// 调用方可传入复用的缓冲区（如 dst[:0]）以避免分配
+func (e *PathEngine) AppendProcess(dst, input []byte) ([]byte, error) {
+	if e.passthrough() {
+		e.recordPassthrough(int64(len(input)))
+		return append(dst, input...), nil
+	}
+	out, _, err := e.appendProcess(dst, input)
+	return out, err
+}
+
+func (e *PathEngine) appendProcess(dst, input []byte) ([]byte, ProcessResult, error) {
+	proc := e.GetProcessor()
 	defer PutPathProcessor(proc)
 
+	w := &appendWriter{buf: dst}
+	err := e.processData(proc, input, e.wrapOutput(w))
+	if err == nil {
+		err = e.checkSchema(w.buf[len(dst):])
+	}
+	stats := proc.Stats()
+	e.stats.add(stats)
+	return w.buf, ProcessResult{Applied: proc.Applied(), Captured: proc.Captured(), Stats: stats}, err
+}
+
+// passthrough 无规则、非严格模式、不改写也不校验输出时，输入可原样输出
+func (e *PathEngine) passthrough() bool {
+	return !e.HasRules() && !e.strict && e.format == nil && !e.normalizeKeys && !e.limits.enabled() && e.schema == nil && !e.relaxed
+}
+
+// wrapOutput 按输出格式选项包装 writer
+func (e *PathEngine) wrapOutput(output io.Writer) io.Writer {
+	if e.format == nil {
+		return output
+	}
+	return newFormatWriter(output, e.format)
+}
+
+// appendWriter 将写入追加到字节切片
+type appendWriter struct {
+	buf []byte
+}
+
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// process 使用给定处理器完成整个输入的处理
+func (e *PathEngine) process(proc *PathProcessor, input io.Reader, output io.Writer) error {
+	// 负索引/负切片需要数组长度：整体读入并预先统计（放弃流式）
+	if proc.matcher.NeedsArrayLen() {
+		data, err := io.ReadAll(e.limitReader(input))
+		if err != nil {
+			return err
+		}
+		return e.processData(proc, data, output)
+	}
+
+	if e.relaxed {
+		input = e.relaxReader(input)
+	}
+
+	var checker *syntaxChecker
+	if e.strict {
+		checker = e.newSyntaxChecker()
+	}
+
 	// 分块读取和处理
 	buf := make([]byte, e.chunkSize)
 	for {
 		n, err := input.Read(buf)
 		if n > 0 {
+			if checker != nil {
+				if checkErr := checker.Feed(buf[:n]); checkErr != nil {
+					return checkErr
+				}
+			}
 			if procErr := proc.ProcessChunk(buf[:n], output); procErr != nil {
 				return procErr
 			}
@@ -151,6 +295,44 @@ func (e *PathEngine) Process(input io.Reader, output io.Writer) error {
 		}
 	}
 
+	if checker != nil {
+		if err := checker.Finish(); err != nil {
+			return err
+		}
+	}
+	return proc.Finish(output)
+}
+
+// processData 处理已完整读入内存的数据
+func (e *PathEngine) processData(proc *PathProcessor, data []byte, output io.Writer) error {
+	if e.relaxed {
+		relaxed, err := e.relaxData(data)
+		if err != nil {
+			return err
+		}
+		data = relaxed
+	}
+
+	// 严格模式先整体校验，出错时不产生输出
+	if e.strict {
+		checker := e.newSyntaxChecker()
+		if err := checker.Feed(data); err != nil {
+			return err
+		}
+		if err := checker.Finish(); err != nil {
+			return err
+		}
+	}
+
+	if proc.matcher.NeedsArrayLen() {
+		proc.SetArrayLengths(CountArrayLengths(data))
+	}
+	for start := 0; start < len(data); start += e.chunkSize {
+		end := min(start+e.chunkSize, len(data))
+		if err := proc.ProcessChunk(data[start:end], output); err != nil {
+			return err
+		}
+	}
 	return proc.Finish(output)
 }
 
@@ -161,36 +343,52 @@ func (e *PathEngine) ProcessChunk(proc *PathProcessor, chunk []byte, output io.W
 
 // GetProcessor 获取处理器（用于流式场景）
 func (e *PathEngine) GetProcessor() *PathProcessor {
-	return GetPathProcessor(e.matcher)
+	proc := GetPathProcessor(e.Compiled().matcher)
+	proc.normalizeKeys = e.normalizeKeys
+	proc.progress = e.progress
+	proc.onMatch = e.onMatch
+	proc.matchValues = e.matchValues
+	proc.limits = e.limits
+	proc.removeEmptyParents = e.removeEmptyParents
+	return proc
 }
 
-// ReleaseProcessor 释放处理器
+// ReleaseProcessor 释放处理器，处理过数据时将其统计计入引擎
 func (e *PathEngine) ReleaseProcessor(proc *PathProcessor) {
+	if proc != nil && proc.consumed > 0 {
+		e.stats.add(proc.Stats())
+	}
 	PutPathProcessor(proc)
 }
 
 // HasRules 检查是否有规则
 func (e *PathEngine) HasRules() bool {
-	return e.matcher.HasRules()
+	return e.Compiled().HasRules()
 }
 
-// Rules 返回规则列表
+// Rules 返回规则列表，不应修改
 func (e *PathEngine) Rules() []PathRule {
-	return e.rules
+	return e.Compiled().rules
 }
 
 // AddRule 添加规则（用于测试和动态添加规则）
+// 以现有规则加上 rule 重新编译后整体替换规则集（copy-on-write），不修改原先的规则集，
+// 因此共享同一 CompiledRules 的其他引擎和正在进行的处理不受影响，新规则从之后开始的处理生效。
+// 可与处理方法并发调用，多个 AddRule 之间按调用顺序串行。
 func (e *PathEngine) AddRule(rule PathRule) error {
 	// 解析路径
-	segments, err := ParsePath(rule.Path)
+	if _, err := ParsePath(rule.Path); err != nil {
+		return err
+	}
+
+	e.addMu.Lock()
+	defer e.addMu.Unlock()
+	current := e.Compiled()
+	rules := append(current.rules[:len(current.rules):len(current.rules)], rule)
+	compiled, err := CompileRules(rules, current.ConflictMode())
 	if err != nil {
 		return err
 	}
-	rule.segments = segments
-	
-	// 添加到规则列表
-	e.rules = append(e.rules, rule)
-	
-	// 添加到匹配器
-	return e.matcher.AddRule(rule)
+	e.setCompiled(compiled)
+	return nil
 }
@@ -0,0 +1,117 @@
+package jsonengine
+
+import (
+	"bytes"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// WithNormalizedKeys 输出时将含转义序列的 key 改写为解码后的最简形式
+// 如 "\u0074houghtSignature" 输出为 "thoughtSignature"，仅保留 JSON 必需的转义。
+// 规则匹配始终基于解码后的 key，与此选项无关；不含转义的 key 原样输出。
+func WithNormalizedKeys() PathEngineOption {
+	return func(e *PathEngine) {
+		e.normalizeKeys = true
+	}
+}
+
+// decodeKey 解码 JSON 字符串内容（不含首尾引号）中的转义序列
+// 不含反斜杠时直接返回；非法转义按原样保留，与原始字节比较的旧行为一致
+func decodeKey(raw []byte) string {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return string(raw)
+	}
+
+	out := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c != '\\' || i+1 >= len(raw) {
+			out = append(out, c)
+			continue
+		}
+
+		switch raw[i+1] {
+		case '"', '\\', '/':
+			out = append(out, raw[i+1])
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'u':
+			r, n := decodeUnicodeEscape(raw[i:])
+			if n == 0 {
+				out = append(out, c)
+				continue
+			}
+			out = utf8.AppendRune(out, r)
+			i += n - 1
+			continue
+		default:
+			out = append(out, c)
+			continue
+		}
+		i++
+	}
+	return string(out)
+}
+
+// decodeUnicodeEscape 解码 s 开头的 \uXXXX（含 \ud83d\ude00 形式的代理对），返回字符和消耗的字节数
+// 格式不合法时返回 0；孤立的代理项解码为 U+FFFD，与 encoding/json 一致
+func decodeUnicodeEscape(s []byte) (rune, int) {
+	r, ok := parseHex4(s)
+	if !ok {
+		return 0, 0
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, 6
+	}
+	if r2, ok := parseHex4(s[6:]); ok {
+		if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+			return dec, 12
+		}
+	}
+	return utf8.RuneError, 6
+}
+
+// parseHex4 解析 s 开头的 \uXXXX
+func parseHex4(s []byte) (rune, bool) {
+	if len(s) < 6 || s[0] != '\\' || s[1] != 'u' {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(s[2:6]), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return rune(v), true
+}
+
+// appendQuotedKey 以最简转义形式追加带引号的 key
+func appendQuotedKey(dst []byte, key string) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
@@ -0,0 +1,185 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MatchReport 一次规则命中的说明（见 PathEngine.Explain）
+type MatchReport struct {
+	// Rules 产生该变更的规则在 Rules() 中的下标，all_apply 模式下按执行顺序列出所有参与的规则；
+	// 白名单（keep）模式下因不在任何 keep 路径上而删除的字段为空
+	Rules  []int  `json:"rules"`
+	Action Action `json:"action"`
+	// Path 命中位置的 JSON Pointer，如 /messages/0/content
+	Path string `json:"path"`
+	// Offset 命中位置在输入中的字节偏移：对象字段为 key 的起始引号，数组元素为元素起始，
+	// add 为所在对象的结束 }
+	Offset int `json:"offset"`
+	// Before 原值，add 与 rename 时为空
+	Before json.RawMessage `json:"before,omitempty"`
+	// After 新值，remove 与 rename 时为空；copy 时为复制的值
+	After json.RawMessage `json:"after,omitempty"`
+	// Target rename 的新字段名或 copy 的目标字段名
+	Target string `json:"target,omitempty"`
+}
+
+// explainState Explain 模式下的记录状态
+type explainState struct {
+	input      []byte
+	reports    []MatchReport
+	pending    int // 等待原值结束的报告下标，-1 表示无
+	valueStart int // 待定报告原值的起始偏移
+}
+
+// Explain 试运行规则，返回每处命中的规则、位置以及将产生的变更，不产生输出
+// 用于在保存规则前预览效果；报告按命中位置在输入中的顺序排列，
+// 语义与 Process 完全一致（包括冲突解析方式和白名单模式）。
+// 严格模式下输入不是合法 JSON 时返回 *SyntaxError。
+func (e *PathEngine) Explain(input []byte) ([]MatchReport, error) {
+	if !e.HasRules() {
+		if e.strict {
+			checker := newSyntaxChecker()
+			if err := checker.Feed(input); err != nil {
+				return nil, err
+			}
+			if err := checker.Finish(); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	proc := e.GetProcessor()
+	defer PutPathProcessor(proc)
+
+	proc.explain = &explainState{input: input, pending: -1}
+	if err := e.processData(proc, input, io.Discard); err != nil {
+		return nil, err
+	}
+	proc.finishExplain()
+	return proc.explain.reports, nil
+}
+
+// explainMatch 记录一次命中，offset 为命中位置，value 为 true 时原值从 p.pos 之后开始，待值结束时补全
+func (p *PathProcessor) explainMatch(action Action, rules []int, path string, offset int, value bool) *MatchReport {
+	ex := p.explain
+	ex.reports = append(ex.reports, MatchReport{
+		Rules:  append([]int(nil), rules...),
+		Action: action,
+		Path:   path,
+		Offset: offset,
+	})
+	if value {
+		ex.pending = len(ex.reports) - 1
+		ex.valueStart = skipSpace(ex.input, p.pos+1)
+	}
+	return &ex.reports[len(ex.reports)-1]
+}
+
+// explainValueAction 记录作用于值的操作（remove/set/值转换），原值在跳过结束时补全
+func (p *PathProcessor) explainValueAction(action RuleAction, path string, offset int) {
+	r := p.explainMatch(action.Action, p.resolved, path, offset, true)
+	if action.Action == ActionCopy {
+		r.Target, _ = p.matcher.rules[action.Index].Value.(string)
+	}
+}
+
+// explainValueEnd 原值结束，补全待定报告；after 为输出的新值（remove 时为 nil）
+// 字符串和容器值的结束位置是当前结构字符本身，其余简单值在当前的分隔符之前结束
+func (p *PathProcessor) explainValueEnd(after []byte) {
+	ex := p.explain
+	if ex.pending < 0 {
+		return
+	}
+	r := &ex.reports[ex.pending]
+	ex.pending = -1
+
+	end := min(p.pos, len(ex.input))
+	if ex.valueStart < len(ex.input) {
+		switch ex.input[ex.valueStart] {
+		case '"', '{', '[':
+			end = min(p.pos+1, len(ex.input))
+		}
+	}
+	if end > ex.valueStart {
+		r.Before = append(json.RawMessage(nil), bytes.TrimSpace(ex.input[ex.valueStart:end])...)
+	}
+
+	switch {
+	case after == nil:
+	case r.Action == ActionCopy:
+		r.After = r.Before
+	default:
+		// copy 参与时输出为 值,"target":值，只取第一个值
+		r.After = append(json.RawMessage(nil), firstJSONValue(after)...)
+	}
+}
+
+// finishExplain 输入结束时补全仍未结束的报告（顶层简单值）
+func (p *PathProcessor) finishExplain() {
+	ex := p.explain
+	if ex.pending < 0 {
+		return
+	}
+	p.pos = len(ex.input)
+	p.explainValueEnd(nil)
+}
+
+// fieldPointer 返回当前对象中字段 key 的 JSON Pointer
+func (p *PathProcessor) fieldPointer(key string) string {
+	return p.containerPointer() + "/" + escapePointerToken(key)
+}
+
+// elementPointer 返回当前数组元素的 JSON Pointer
+func (p *PathProcessor) elementPointer() string {
+	top := p.pathStack[len(p.pathStack)-1]
+	return p.containerPointer() + "/" + strconv.Itoa(top.arrayIdx)
+}
+
+// containerPointer 返回当前所在容器的 JSON Pointer（根为空字符串）
+func (p *PathProcessor) containerPointer() string {
+	var sb strings.Builder
+	for i := 1; i < len(p.pathStack); i++ {
+		sb.WriteByte('/')
+		if parent := p.pathStack[i-1]; parent.isArray {
+			sb.WriteString(strconv.Itoa(parent.arrayIdx))
+		} else {
+			sb.WriteString(escapePointerToken(p.pathStack[i].key))
+		}
+	}
+	return sb.String()
+}
+
+// escapePointerToken 按 RFC 6901 转义 JSON Pointer 段
+func escapePointerToken(token string) string {
+	if strings.IndexAny(token, "~/") < 0 {
+		return token
+	}
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// skipSpace 返回 data 中从 i 开始的第一个非空白字符的位置
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// firstJSONValue 返回 data 开头的第一个 JSON 值
+func firstJSONValue(data []byte) []byte {
+	var raw json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&raw); err != nil {
+		return data
+	}
+	return raw
+}
@@ -0,0 +1,479 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// exprRefPrefix 表达式引用字段的内部 capture 名称前缀，不出现在 ProcessResult.Captured 中
+const exprRefPrefix = "\x00expr:"
+
+// maxExprLength 表达式的最大长度
+const maxExprLength = 1024
+
+// 表达式节点类型
+const (
+	exprNumber = iota
+	exprString
+	exprRef
+	exprUnaryMinus
+	exprBinary
+	exprCall
+)
+
+// valueExpr 编译后的值表达式（见 PathRule.Expr）
+// 语法：数字、单/双引号字符串、字段路径（与规则路径相同，如 usage.prompt_tokens、choices[0].index），
+// + - * / % 运算和括号，以及函数 min(a, b, ...)、max(a, b, ...)、coalesce(a, b, ...)。
+// + 的任一操作数为字符串时做字符串拼接；引用的字段为对象、数组、布尔或 null 时只能原样作为结果。
+type valueExpr struct {
+	root *exprNode
+	refs []string // 引用的字段路径（去重）
+}
+
+type exprNode struct {
+	kind int
+	op   byte    // exprBinary 的运算符
+	num  float64 // exprNumber
+	str  string  // exprString 的值、exprRef 的路径、exprCall 的函数名
+	args []*exprNode
+}
+
+// exprValue 表达式求值的中间结果
+type exprValue struct {
+	kind ValueType
+	num  float64
+	str  string
+	raw  []byte // 引用的对象、数组、布尔或 null 值
+}
+
+// compileRuleExpr 编译规则的值表达式，未设置时返回 nil
+func compileRuleExpr(rule PathRule) (*valueExpr, error) {
+	if rule.Expr == "" {
+		return nil, nil
+	}
+	if rule.Action != ActionSet && rule.Action != ActionAdd {
+		return nil, &PathError{Msg: "expr is only supported for set and add rules"}
+	}
+	return compileExpr(rule.Expr)
+}
+
+// exprRefRules 为表达式引用的字段生成内部 capture 规则，处理时随其他规则一起收集引用值
+func exprRefRules(rules []PathRule) []PathRule {
+	var refs []PathRule
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		expr, err := compileRuleExpr(rule)
+		if err != nil || expr == nil {
+			continue
+		}
+		for _, ref := range expr.refs {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, PathRule{Path: ref, Action: ActionCapture, Value: exprRefPrefix + ref})
+			}
+		}
+	}
+	return refs
+}
+
+// exprRefIssues 检查表达式引用字段的内部 capture 规则对其他规则的影响
+// 引用让匹配器进入更具体的分支，或整体捕获了其他规则的父字段时，已有规则会不再生效，
+// 问题报告在引用该字段的规则上，Related 指向受影响的规则
+func exprRefIssues(rules []PathRule) []RuleIssue {
+	refs := exprRefRules(rules)
+	if len(refs) == 0 {
+		return nil
+	}
+	n := len(rules)
+	var issues []RuleIssue
+	for _, issue := range validateRules(append(rules[:n:n], refs...)) {
+		if issue.Rule >= n || issue.Related == nil || *issue.Related < n {
+			continue
+		}
+		if issue.Code != IssueShadowed && issue.Code != IssueUnreachable {
+			continue
+		}
+		ref := refs[*issue.Related-n].Path
+		owner := exprRefOwner(rules, ref)
+		affected := issue.Rule
+		issues = append(issues, RuleIssue{
+			Rule:     owner,
+			Path:     rules[owner].Path,
+			Severity: SeverityError,
+			Code:     IssueInvalidValue,
+			Message:  fmt.Sprintf("expr reference %q keeps rule #%d (%s) from applying: %s", ref, affected, issue.Path, issue.Message),
+			Related:  &affected,
+		})
+	}
+	return issues
+}
+
+// exprRefOwner 返回第一条引用该字段的规则下标
+func exprRefOwner(rules []PathRule, ref string) int {
+	for i, rule := range rules {
+		if expr, err := compileRuleExpr(rule); err == nil && expr != nil && slices.Contains(expr.refs, ref) {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkExprRefs 表达式引用字段会让其他规则不再生效时拒绝这组规则
+func checkExprRefs(rules []PathRule) error {
+	if issues := exprRefIssues(rules); len(issues) > 0 {
+		return &PathError{Msg: issues[0].Message}
+	}
+	return nil
+}
+
+// compileExpr 解析表达式
+func compileExpr(src string) (*valueExpr, error) {
+	if len(src) > maxExprLength {
+		return nil, &PathError{Msg: fmt.Sprintf("expr exceeds %d characters", maxExprLength)}
+	}
+	p := exprParser{src: src}
+	root, err := p.parseSum()
+	if err == nil && p.skipSpace() < len(src) {
+		err = p.errorf("unexpected %q", src[p.pos])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &valueExpr{root: root, refs: p.refs}, nil
+}
+
+// exprParser 递归下降解析器
+type exprParser struct {
+	src  string
+	pos  int
+	refs []string
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return &PathError{Msg: fmt.Sprintf("expr at offset %d: ", p.pos) + fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) skipSpace() int {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n' || p.src[p.pos] == '\r') {
+		p.pos++
+	}
+	return p.pos
+}
+
+// parseSum sum := product (('+'|'-') product)*
+func (p *exprParser) parseSum() (*exprNode, error) {
+	left, err := p.parseProduct()
+	for err == nil && p.skipSpace() < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+		op := p.src[p.pos]
+		p.pos++
+		var right *exprNode
+		if right, err = p.parseProduct(); err == nil {
+			left = &exprNode{kind: exprBinary, op: op, args: []*exprNode{left, right}}
+		}
+	}
+	return left, err
+}
+
+// parseProduct product := unary (('*'|'/'|'%') unary)*
+func (p *exprParser) parseProduct() (*exprNode, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.skipSpace() < len(p.src) && strings.IndexByte("*/%", p.src[p.pos]) >= 0 {
+		op := p.src[p.pos]
+		p.pos++
+		var right *exprNode
+		if right, err = p.parseUnary(); err == nil {
+			left = &exprNode{kind: exprBinary, op: op, args: []*exprNode{left, right}}
+		}
+	}
+	return left, err
+}
+
+// parseUnary unary := '-' unary | primary
+func (p *exprParser) parseUnary() (*exprNode, error) {
+	if p.skipSpace() < len(p.src) && p.src[p.pos] == '-' {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprNode{kind: exprUnaryMinus, args: []*exprNode{operand}}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary primary := number | string | '(' sum ')' | name '(' args ')' | path
+func (p *exprParser) parsePrimary() (*exprNode, error) {
+	if p.skipSpace() >= len(p.src) {
+		return nil, p.errorf("unexpected end of expression")
+	}
+	c := p.src[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		node, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpace() >= len(p.src) || p.src[p.pos] != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return node, nil
+	case c == '\'' || c == '"':
+		return p.parseString(c)
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.src) && (isExprNumberByte(p.src[p.pos]) ||
+			(p.src[p.pos] == '+' || p.src[p.pos] == '-') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')) {
+			p.pos++
+		}
+		text := p.src[start:p.pos]
+		num, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("invalid number %q", text)
+		}
+		return &exprNode{kind: exprNumber, num: num}, nil
+	}
+	return p.parsePath()
+}
+
+// parseString 解析单引号或双引号字符串，支持 \\ 和对应引号的转义
+func (p *exprParser) parseString(quote byte) (*exprNode, error) {
+	var sb strings.Builder
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		if c == quote {
+			p.pos++
+			return &exprNode{kind: exprString, str: sb.String()}, nil
+		}
+		if c == '\\' && p.pos+1 < len(p.src) && (p.src[p.pos+1] == quote || p.src[p.pos+1] == '\\') {
+			p.pos++
+			c = p.src[p.pos]
+		}
+		sb.WriteByte(c)
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+// parsePath 解析字段路径或函数调用
+// 路径读到空白、运算符、逗号或右括号为止，[...] 与 "..." 段内的字符原样保留
+func (p *exprParser) parsePath() (*exprNode, error) {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '[' || c == '"' {
+			closing := byte(']')
+			if c == '"' {
+				closing = '"'
+			}
+			end := strings.IndexByte(p.src[p.pos+1:], closing)
+			if end < 0 {
+				return nil, p.errorf("unterminated %q", c)
+			}
+			p.pos += end + 2
+			continue
+		}
+		if strings.IndexByte(" \t\r\n+-*/%(),", c) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if name == "" {
+		return nil, p.errorf("unexpected %q", p.src[p.pos])
+	}
+
+	if p.skipSpace() < len(p.src) && p.src[p.pos] == '(' {
+		return p.parseCall(name)
+	}
+
+	if _, err := ParsePath(name); err != nil {
+		return nil, p.errorf("invalid field path %q: %v", name, err)
+	}
+	if !slices.Contains(p.refs, name) {
+		p.refs = append(p.refs, name)
+	}
+	return &exprNode{kind: exprRef, str: name}, nil
+}
+
+// parseCall 解析函数调用的参数列表
+func (p *exprParser) parseCall(name string) (*exprNode, error) {
+	switch name {
+	case "min", "max", "coalesce":
+	default:
+		return nil, p.errorf("unknown function %q", name)
+	}
+	p.pos++ // (
+	node := &exprNode{kind: exprCall, str: name}
+	for {
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		node.args = append(node.args, arg)
+		if p.skipSpace() >= len(p.src) {
+			return nil, p.errorf("missing )")
+		}
+		if p.src[p.pos] == ')' {
+			p.pos++
+			return node, nil
+		}
+		if p.src[p.pos] != ',' {
+			return nil, p.errorf("expected , or ) in %s()", name)
+		}
+		p.pos++
+	}
+}
+
+func isExprNumberByte(c byte) bool {
+	return c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E'
+}
+
+// eval 求值并返回结果的 JSON，lookup 返回引用字段的原始 JSON
+// 引用的字段缺失、类型不支持运算、除数为 0 或结果不是有限数值时返回 false
+func (x *valueExpr) eval(lookup func(path string) (json.RawMessage, bool)) ([]byte, bool) {
+	v, ok := x.root.eval(lookup)
+	if !ok {
+		return nil, false
+	}
+	switch v.kind {
+	case ValueNumber:
+		if math.IsNaN(v.num) || math.IsInf(v.num, 0) {
+			return nil, false
+		}
+		return marshalFloat(v.num), true
+	case ValueString:
+		return marshalString(v.str), true
+	default:
+		return v.raw, true
+	}
+}
+
+func (n *exprNode) eval(lookup func(path string) (json.RawMessage, bool)) (exprValue, bool) {
+	switch n.kind {
+	case exprNumber:
+		return exprValue{kind: ValueNumber, num: n.num}, true
+	case exprString:
+		return exprValue{kind: ValueString, str: n.str}, true
+	case exprRef:
+		raw, ok := lookup(n.str)
+		if !ok {
+			return exprValue{}, false
+		}
+		return decodeExprValue(raw)
+	case exprUnaryMinus:
+		v, ok := n.args[0].eval(lookup)
+		if !ok || v.kind != ValueNumber {
+			return exprValue{}, false
+		}
+		return exprValue{kind: ValueNumber, num: -v.num}, true
+	case exprBinary:
+		return n.evalBinary(lookup)
+	default:
+		return n.evalCall(lookup)
+	}
+}
+
+func (n *exprNode) evalBinary(lookup func(path string) (json.RawMessage, bool)) (exprValue, bool) {
+	a, ok := n.args[0].eval(lookup)
+	if !ok {
+		return exprValue{}, false
+	}
+	b, ok := n.args[1].eval(lookup)
+	if !ok {
+		return exprValue{}, false
+	}
+	if n.op == '+' && (a.kind == ValueString || b.kind == ValueString) {
+		as, aok := a.text()
+		bs, bok := b.text()
+		return exprValue{kind: ValueString, str: as + bs}, aok && bok
+	}
+	if a.kind != ValueNumber || b.kind != ValueNumber {
+		return exprValue{}, false
+	}
+	var result float64
+	switch n.op {
+	case '+':
+		result = a.num + b.num
+	case '-':
+		result = a.num - b.num
+	case '*':
+		result = a.num * b.num
+	case '/':
+		if b.num == 0 {
+			return exprValue{}, false
+		}
+		result = a.num / b.num
+	case '%':
+		if b.num == 0 {
+			return exprValue{}, false
+		}
+		result = math.Mod(a.num, b.num)
+	}
+	return exprValue{kind: ValueNumber, num: result}, true
+}
+
+func (n *exprNode) evalCall(lookup func(path string) (json.RawMessage, bool)) (exprValue, bool) {
+	if n.str == "coalesce" {
+		// 返回第一个可求值且不为 null 的参数
+		for _, arg := range n.args {
+			if v, ok := arg.eval(lookup); ok && v.kind != ValueNull {
+				return v, true
+			}
+		}
+		return exprValue{}, false
+	}
+
+	var result exprValue
+	for i, arg := range n.args {
+		v, ok := arg.eval(lookup)
+		if !ok || v.kind != ValueNumber {
+			return exprValue{}, false
+		}
+		if i == 0 || (n.str == "min") == (v.num < result.num) {
+			result = v
+		}
+	}
+	return result, true
+}
+
+// text 返回字符串拼接时的文本，数值按 JSON 格式输出
+func (v exprValue) text() (string, bool) {
+	switch v.kind {
+	case ValueString:
+		return v.str, true
+	case ValueNumber:
+		return string(marshalFloat(v.num)), true
+	}
+	return "", false
+}
+
+// decodeExprValue 将引用字段的原始 JSON 转为求值结果
+func decodeExprValue(raw json.RawMessage) (exprValue, bool) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return exprValue{}, false
+	}
+	kind, ok := valueTypeOf(raw[0])
+	if !ok {
+		return exprValue{}, false
+	}
+	switch kind {
+	case ValueNumber:
+		num, err := strconv.ParseFloat(string(raw), 64)
+		return exprValue{kind: ValueNumber, num: num}, err == nil
+	case ValueString:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return exprValue{}, false
+		}
+		return exprValue{kind: ValueString, str: s}, true
+	}
+	return exprValue{kind: kind, raw: raw}, true
+}
@@ -0,0 +1,211 @@
+package jsonengine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestExprRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []PathRule
+		input string
+		want  string
+	}{
+		{
+			name:  "add sum of sibling fields",
+			rules: []PathRule{{Path: "usage.total_tokens", Action: ActionAdd, Expr: "usage.prompt_tokens + usage.completion_tokens"}},
+			input: `{"usage":{"prompt_tokens":3,"completion_tokens":4}}`,
+			want:  `{"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`,
+		},
+		{
+			name:  "add keeps existing field",
+			rules: []PathRule{{Path: "usage.total_tokens", Action: ActionAdd, Expr: "usage.prompt_tokens + usage.completion_tokens"}},
+			input: `{"usage":{"prompt_tokens":3,"total_tokens":9,"completion_tokens":4}}`,
+			want:  `{"usage":{"prompt_tokens":3,"total_tokens":9,"completion_tokens":4}}`,
+		},
+		{
+			name:  "add skipped when a reference is missing",
+			rules: []PathRule{{Path: "usage.total_tokens", Action: ActionAdd, Expr: "usage.prompt_tokens + usage.completion_tokens"}},
+			input: `{"usage":{"prompt_tokens":3}}`,
+			want:  `{"usage":{"prompt_tokens":3}}`,
+		},
+		{
+			name:  "coalesce falls back",
+			rules: []PathRule{{Path: "usage.total_tokens", Action: ActionAdd, Expr: "coalesce(usage.completion_tokens, 0) + usage.prompt_tokens"}},
+			input: `{"usage":{"prompt_tokens":3}}`,
+			want:  `{"usage":{"prompt_tokens":3,"total_tokens":3}}`,
+		},
+		{
+			name:  "add to empty object with references elsewhere",
+			rules: []PathRule{{Path: "meta.model", Action: ActionAdd, Expr: "model"}},
+			input: `{"model":"gpt-4o","meta":{}}`,
+			want:  `{"model":"gpt-4o","meta":{"model":"gpt-4o"}}`,
+		},
+		{
+			name:  "set from an earlier field",
+			rules: []PathRule{{Path: "b", Action: ActionSet, Expr: "a * 10 - 1"}},
+			input: `{"a":2.5,"b":1}`,
+			want:  `{"a":2.5,"b":24}`,
+		},
+		{
+			name:  "set skipped when the reference comes later",
+			rules: []PathRule{{Path: "b", Action: ActionSet, Expr: "a * 10"}},
+			input: `{"b":1,"a":2}`,
+			want:  `{"b":1,"a":2}`,
+		},
+		{
+			name:  "set array element",
+			rules: []PathRule{{Path: "items[1]", Action: ActionSet, Expr: "max(limit, 5)"}},
+			input: `{"limit":3,"items":[1,2]}`,
+			want:  `{"limit":3,"items":[1,5]}`,
+		},
+		{
+			name:  "string concatenation",
+			rules: []PathRule{{Path: "label", Action: ActionAdd, Expr: `'model: ' + model + " (" + n + ")"`}},
+			input: `{"model":"a\"b","n":2}`,
+			want:  `{"model":"a\"b","n":2,"label":"model: a\"b (2)"}`,
+		},
+		{
+			name:  "object reference copied as is",
+			rules: []PathRule{{Path: "copy", Action: ActionAdd, Expr: "src"}},
+			input: `{"src":{"k":[1, 2]}}`,
+			want:  `{"src":{"k":[1, 2]},"copy":{"k":[1, 2]}}`,
+		},
+		{
+			name:  "division by zero is not applied",
+			rules: []PathRule{{Path: "ratio", Action: ActionAdd, Expr: "a / b"}},
+			input: `{"a":1,"b":0}`,
+			want:  `{"a":1,"b":0}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules)
+			if err != nil {
+				t.Fatalf("NewPathEngine: %v", err)
+			}
+			got, err := engine.ProcessBytes([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ProcessBytes: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ProcessBytes = %s, want %s", got, tt.want)
+			}
+
+			var buf bytes.Buffer
+			if err := engine.Process(iotest.OneByteReader(strings.NewReader(tt.input)), &buf); err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Process one byte at a time = %s, want %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestExprRulesCapturedExcludesReferences(t *testing.T) {
+	engine, err := NewPathEngine([]PathRule{
+		{Path: "usage.total_tokens", Action: ActionAdd, Expr: "usage.prompt_tokens + 1"},
+		{Path: "model", Action: ActionCapture},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, result, err := engine.ProcessBytesWithResult([]byte(`{"model":"m","usage":{"prompt_tokens":1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Captured) != 1 || string(result.Captured["model"]) != `"m"` {
+		t.Errorf("Captured = %v, want only model", result.Captured)
+	}
+	if result.Applied != 1 {
+		t.Errorf("Applied = %d, want 1", result.Applied)
+	}
+	if len(engine.Rules()) != 2 {
+		t.Errorf("Rules() has %d rules, want the 2 configured rules", len(engine.Rules()))
+	}
+}
+
+func TestExprRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule PathRule
+		want string
+	}{
+		{"remove with expr", PathRule{Path: "a", Action: ActionRemove, Expr: "b"}, "only supported for set and add"},
+		{"unknown function", PathRule{Path: "a", Action: ActionSet, Expr: "sum(b)"}, "unknown function"},
+		{"unbalanced", PathRule{Path: "a", Action: ActionSet, Expr: "(b + 1"}, "missing )"},
+		{"trailing operator", PathRule{Path: "a", Action: ActionSet, Expr: "b +"}, "unexpected end"},
+		{"unterminated string", PathRule{Path: "a", Action: ActionSet, Expr: "'x"}, "unterminated string"},
+		{"invalid path", PathRule{Path: "a", Action: ActionSet, Expr: "b[x]"}, "invalid field path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPathEngine([]PathRule{tt.rule})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewPathEngine error = %v, want %q", err, tt.want)
+			}
+			issues := ValidateRules([]PathRule{tt.rule})
+			if len(issues) != 1 || issues[0].Code != IssueInvalidValue {
+				t.Errorf("ValidateRules = %+v, want one invalid_value issue", issues)
+			}
+		})
+	}
+}
+
+func TestExprRulesReferenceShadowsRule(t *testing.T) {
+	rules := []PathRule{
+		{Path: "usage.*", Action: ActionSet, ValueBytes: []byte(`0`)},
+		{Path: "total", Action: ActionAdd, Expr: "usage.prompt_tokens"},
+	}
+	_, err := NewPathEngine(rules)
+	if err == nil || !strings.Contains(err.Error(), `expr reference "usage.prompt_tokens" keeps rule #0`) {
+		t.Errorf("NewPathEngine error = %v, want shadowing error", err)
+	}
+	var found bool
+	for _, issue := range ValidateRules(rules) {
+		if issue.Rule == 1 && issue.Severity == SeverityError && issue.Related != nil && *issue.Related == 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("ValidateRules = %+v, want an error on the expr rule related to rule #0", ValidateRules(rules))
+	}
+
+	rules = []PathRule{
+		{Path: "usage.total_tokens", Action: ActionAdd, Expr: "usage.prompt_tokens"},
+		{Path: "x", Action: ActionAdd, Expr: "usage"},
+	}
+	if _, err := NewPathEngine(rules); err == nil || !strings.Contains(err.Error(), "keeps rule #0") {
+		t.Errorf("NewPathEngine error = %v, want error for a reference capturing the parent of another rule", err)
+	}
+}
+
+func TestExprRulesAllApply(t *testing.T) {
+	rules := []PathRule{
+		{Path: "b", Action: ActionSet, Expr: "a + 1"},
+		{Path: "b", Action: ActionTransform, Value: TransformUpper, Priority: -1},
+		{Path: "c", Action: ActionSet, Expr: "missing"},
+		{Path: "c", Action: ActionSet, ValueBytes: []byte(`"fallback"`), Priority: 1},
+		{Path: "d", Action: ActionSet, Expr: "missing"},
+		{Path: "d", Action: ActionCapture},
+	}
+	engine, err := NewPathEngine(rules, WithConflictMode(ConflictAllApply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, result, err := engine.ProcessBytesWithResult([]byte(`{"a":"x","b":"y","c":1,"d":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":"x","b":"X1","c":"fallback","d":2}`; string(out) != want {
+		t.Errorf("output = %s, want %s", out, want)
+	}
+	if result.Applied != 2 {
+		t.Errorf("Applied = %d, want 2", result.Applied)
+	}
+}
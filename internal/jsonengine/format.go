@@ -0,0 +1,111 @@
+package jsonengine
+
+import (
+	"io"
+	"strings"
+)
+
+// outputFormat 输出 JSON 的空白格式
+type outputFormat struct {
+	indent string // 为空表示紧凑输出
+}
+
+// WithIndent 以 indent 为缩进单位美化输出（每个成员独占一行，冒号后加空格）
+// 格式选项作用于 Process、ProcessWithResult、ProcessBytes 和 AppendProcess，
+// 不影响通过 GetProcessor 手动分块处理的输出
+func WithIndent(indent string) PathEngineOption {
+	return func(e *PathEngine) {
+		e.format = &outputFormat{indent: indent}
+	}
+}
+
+// WithCompact 去除输出中的所有空白
+// 默认情况下输入的空白原样保留，删除字段后可能留下不规则的空白
+func WithCompact() PathEngineOption {
+	return func(e *PathEngine) {
+		e.format = &outputFormat{}
+	}
+}
+
+// formatWriter 流式重排 JSON 空白的 writer
+// 丢弃字符串之外的空白，再按格式重新输出，状态可跨越多次 Write
+type formatWriter struct {
+	w      io.Writer
+	indent string
+	buf    []byte
+
+	depth       int
+	inString    bool
+	escaped     bool
+	pendingOpen bool // 刚输出 { 或 [，等待确认是否为空容器
+}
+
+func newFormatWriter(w io.Writer, format *outputFormat) *formatWriter {
+	return &formatWriter{w: w, indent: format.indent}
+}
+
+func (f *formatWriter) Write(p []byte) (int, error) {
+	f.buf = f.buf[:0]
+	for _, c := range p {
+		if f.inString {
+			f.buf = append(f.buf, c)
+			switch {
+			case f.escaped:
+				f.escaped = false
+			case c == '\\':
+				f.escaped = true
+			case c == '"':
+				f.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '}', ']':
+			f.depth--
+			if f.pendingOpen {
+				f.pendingOpen = false
+			} else {
+				f.newline()
+			}
+			f.buf = append(f.buf, c)
+			continue
+		}
+
+		if f.pendingOpen {
+			f.pendingOpen = false
+			f.newline()
+		}
+		f.buf = append(f.buf, c)
+
+		switch c {
+		case '{', '[':
+			f.depth++
+			f.pendingOpen = true
+		case ',':
+			f.newline()
+		case ':':
+			if f.indent != "" {
+				f.buf = append(f.buf, ' ')
+			}
+		case '"':
+			f.inString = true
+		}
+	}
+
+	if _, err := f.w.Write(f.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// newline 缩进模式下换行并缩进到当前深度
+func (f *formatWriter) newline() {
+	if f.indent == "" {
+		return
+	}
+	f.buf = append(f.buf, '\n')
+	f.buf = append(f.buf, strings.Repeat(f.indent, max(f.depth, 0))...)
+}
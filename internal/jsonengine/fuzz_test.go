@@ -0,0 +1,502 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// 差分模糊测试：对任意 JSON 文档和随机规则集运行 PathEngine，
+// 与基于 encoding/json map 操作的参考实现逐一比对语义结果
+//
+//	go test -run '^$' -fuzz FuzzPathEngineDifferential ./internal/jsonengine
+//
+// 参考实现覆盖 set/add/remove 与字段、*、[n]、[*] 段。生成的规则集中任意两条规则的路径
+// 在第一个不同的段上互斥（不同字段名或不同下标），不会出现同一层级精确字段与通配段同时命中的情况
+
+// fuzzFieldName 可直接写入路径的字段名，其他 key 只能通过 * 命中
+var fuzzFieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// fuzzRuleValues 规则值候选，覆盖标量、空容器和嵌套值
+var fuzzRuleValues = []string{`0`, `-1.5e3`, `true`, `null`, `""`, `"x,\"}]"`, `{}`, `[]`, `{"k":[1,{"m":null}]}`, `[[],{}]`}
+
+// FuzzPathEngineDifferential 任意文档加由 seed 生成的规则集，结果须与参考实现一致
+func FuzzPathEngineDifferential(f *testing.F) {
+	for _, doc := range []string{
+		`{}`,
+		`{"a":1,"b":2,"c":3}`,
+		`{"a":{"b":{"c":[1,2,{"d":"x"}]}},"e":[],"f":{}}`,
+		`{ "a" : [ { "b" : 1 } , { "b" : 2 , "c" : [ ] } ] , "d" : "}\",[" }`,
+		`{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"text","text":"a\\\"b"}]}],"stream":true}`,
+		`{"a\"b":1,"a.b":{"x":null},"":[[],[[]]],"k":-0.5e-3}`,
+		"{\n\t\"a\": [\n\t\t1,\n\t\t2\n\t],\n\t\"b\": {\"c\": {}}\n}",
+		`{"x":[{"y":[{"z":1},{"z":2}]},{"y":[]},{"y":{"z":3}}],"z":[1,[2,[3]]]}`,
+	} {
+		for seed := range int64(4) {
+			f.Add([]byte(doc), seed)
+		}
+	}
+	for seed := range int64(16) {
+		f.Add(randomFuzzDocument(rand.New(rand.NewSource(seed))), seed)
+	}
+
+	f.Fuzz(func(t *testing.T, doc []byte, seed int64) {
+		var root map[string]any
+		if !json.Valid(doc) || json.Unmarshal(doc, &root) != nil || root == nil || hasDuplicateKeys(doc) {
+			return
+		}
+		rules := randomFuzzRules(rand.New(rand.NewSource(seed)), root)
+		checkDifferential(t, doc, rules)
+	})
+}
+
+// TestPathEngineDifferential 以固定种子运行差分比对，不依赖 -fuzz 也能覆盖随机文档
+func TestPathEngineDifferential(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 500 {
+		doc := randomFuzzDocument(rng)
+		var root map[string]any
+		if err := json.Unmarshal(doc, &root); err != nil {
+			t.Fatalf("generated invalid document %s: %v", doc, err)
+		}
+		checkDifferential(t, doc, randomFuzzRules(rng, root))
+	}
+}
+
+// checkDifferential 分别以整块和逐字节输入运行引擎，结果须与参考实现语义相等
+func checkDifferential(t *testing.T, doc []byte, rules []PathRule) {
+	t.Helper()
+	engine, err := NewPathEngine(rules)
+	if err != nil {
+		t.Fatalf("NewPathEngine(%s): %v", describeFuzzRules(rules), err)
+	}
+	want, ok, err := referenceApply(doc, rules)
+	if err != nil {
+		t.Fatalf("reference(%s): %v", describeFuzzRules(rules), err)
+	}
+	if !ok {
+		return
+	}
+
+	whole, err := engine.ProcessBytes(doc)
+	if err != nil {
+		t.Fatalf("ProcessBytes(%s) on %s: %v", describeFuzzRules(rules), doc, err)
+	}
+	var stream bytes.Buffer
+	if err := engine.Process(iotest.OneByteReader(bytes.NewReader(doc)), &stream); err != nil {
+		t.Fatalf("Process(%s) on %s: %v", describeFuzzRules(rules), doc, err)
+	}
+
+	for mode, out := range map[string][]byte{"bytes": whole, "stream": stream.Bytes()} {
+		var got any
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatalf("%s: rules %s on %s produced invalid JSON %s: %v", mode, describeFuzzRules(rules), doc, out, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			wantJSON, _ := json.Marshal(want)
+			t.Fatalf("%s: rules %s on %s\n got %s\nwant %s", mode, describeFuzzRules(rules), doc, out, wantJSON)
+		}
+	}
+}
+
+// referenceApply 参考实现：解码为 map/slice 后按规则修改
+// set/remove 与引擎一致按后缀匹配：规则路径与节点完整路径的末尾若干段匹配即命中（如 "b" 也命中 a.b）；
+// add 从根开始匹配。同一节点命中多条规则时结果取决于冲突模式，返回 ok=false 由调用方跳过
+func referenceApply(doc []byte, rules []PathRule) (result any, ok bool, err error) {
+	var root any
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, false, err
+	}
+	ref := &refEngine{}
+	for _, r := range rules {
+		segs, err := ParsePath(r.Path)
+		if err != nil {
+			return nil, false, err
+		}
+		var value any
+		if r.Action != ActionRemove {
+			if err := json.Unmarshal(r.ValueBytes, &value); err != nil {
+				return nil, false, err
+			}
+		}
+		ref.rules = append(ref.rules, refRule{segs: segs, action: r.Action, value: value})
+	}
+	result = ref.apply(root, nil)
+	return result, !ref.ambiguous, nil
+}
+
+// refRule 参考实现中的规则
+type refRule struct {
+	segs   []Segment
+	action Action
+	value  any
+}
+
+// refStep 节点路径中的一段：对象 key 或数组下标
+type refStep struct {
+	key   string
+	index int
+	array bool
+}
+
+type refEngine struct {
+	rules     []refRule
+	ambiguous bool
+}
+
+// apply 对路径为 path 的节点 v 的子节点应用规则，返回修改后的新值
+func (e *refEngine) apply(v any, path []refStep) any {
+	switch node := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(node))
+		for key, child := range node {
+			if next, keep := e.applyChild(child, append(path, refStep{key: key})); keep {
+				out[key] = next
+			}
+		}
+		for _, r := range e.rules {
+			last := len(r.segs) - 1
+			if r.action != ActionAdd || last != len(path) || r.segs[last].Type != SegField || !refMatch(r.segs[:last], path) {
+				continue
+			}
+			if _, exists := node[r.segs[last].Value]; !exists {
+				out[r.segs[last].Value] = r.value
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, 0, len(node))
+		for i, child := range node {
+			if next, keep := e.applyChild(child, append(path, refStep{index: i, array: true})); keep {
+				out = append(out, next)
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// applyChild 对路径为 path 的节点应用命中的 set/remove，返回 false 表示删除该节点
+func (e *refEngine) applyChild(v any, path []refStep) (any, bool) {
+	var hit *refRule
+	for i, r := range e.rules {
+		if r.action == ActionAdd || len(r.segs) > len(path) || !refMatch(r.segs, path[len(path)-len(r.segs):]) {
+			continue
+		}
+		if hit != nil {
+			e.ambiguous = true
+		}
+		hit = &e.rules[i]
+	}
+	switch {
+	case hit == nil:
+		return e.apply(v, path), true
+	case hit.action == ActionRemove:
+		return nil, false
+	}
+	return hit.value, true
+}
+
+// refMatch 路径段与节点路径逐段匹配
+func refMatch(segs []Segment, path []refStep) bool {
+	if len(segs) != len(path) {
+		return false
+	}
+	for i, seg := range segs {
+		step := path[i]
+		switch seg.Type {
+		case SegField:
+			if step.array || seg.Value != step.key {
+				return false
+			}
+		case SegWildcard:
+			if step.array {
+				return false
+			}
+		case SegArrayIdx:
+			if !step.array || seg.Index != step.index {
+				return false
+			}
+		case SegArrayAll:
+			if !step.array {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// randomFuzzRules 沿文档随机游走生成 1~4 条互不重叠的规则，使大部分规则能命中实际字段
+func randomFuzzRules(rng *rand.Rand, root map[string]any) []PathRule {
+	actions := []Action{ActionSet, ActionAdd, ActionRemove}
+	var rules []PathRule
+	var paths [][]Segment
+	for range 1 + rng.Intn(4) {
+		path := randomFuzzPath(rng, root)
+		segs, err := ParsePath(path)
+		if err != nil {
+			continue
+		}
+		overlaps := false
+		for _, other := range paths {
+			if segmentsConflict(segs, other) {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		paths = append(paths, segs)
+		rule := PathRule{Path: path, Action: actions[rng.Intn(len(actions))]}
+		if rule.Action != ActionRemove {
+			rule.ValueBytes = []byte(fuzzRuleValues[rng.Intn(len(fuzzRuleValues))])
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// randomFuzzPath 从根对象向下随机选择字段或元素，偶尔使用通配段或不存在的字段和下标
+func randomFuzzPath(rng *rand.Rand, root map[string]any) string {
+	var sb strings.Builder
+	var v any = root
+	for depth := 0; ; depth++ {
+		if depth > 0 && rng.Intn(3) == 0 {
+			break
+		}
+		switch node := v.(type) {
+		case map[string]any:
+			key := "missing" + strconv.Itoa(rng.Intn(2))
+			if len(node) > 0 && rng.Intn(5) > 0 {
+				keys := make([]string, 0, len(node))
+				for k := range node {
+					keys = append(keys, k)
+				}
+				// 排序保证同一 seed 生成相同路径
+				slices.Sort(keys)
+				key = keys[rng.Intn(len(keys))]
+			}
+			seg := key
+			if !fuzzFieldName.MatchString(key) || rng.Intn(5) == 0 {
+				seg = "*"
+			}
+			if depth > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(seg)
+			v = node[key]
+		case []any:
+			idx := rng.Intn(len(node) + 2)
+			if rng.Intn(4) == 0 {
+				sb.WriteString("[*]")
+			} else {
+				fmt.Fprintf(&sb, "[%d]", idx)
+			}
+			if idx < len(node) {
+				v = node[idx]
+			} else {
+				v = nil
+			}
+		default:
+			if depth == 0 {
+				return "missing"
+			}
+			return sb.String()
+		}
+	}
+	return sb.String()
+}
+
+// segmentsConflict 两条路径是否可能从根开始命中同一节点、互为祖先，
+// 或在同一层级上一条为精确段、另一条为通配段（引擎只沿优先级最高的分支继续匹配）
+func segmentsConflict(a, b []Segment) bool {
+	for i := range min(len(a), len(b)) {
+		x, y := a[i], b[i]
+		if x.Type == y.Type && x.Value == y.Value {
+			continue
+		}
+		objectSeg := func(s Segment) bool { return s.Type == SegField || s.Type == SegWildcard }
+		// 对象段与数组段互斥，两个不同的字段名或下标互斥，其余（含通配段）视为冲突
+		return objectSeg(x) == objectSeg(y) && (x.Type != y.Type || (x.Type != SegField && x.Type != SegArrayIdx))
+	}
+	return true
+}
+
+// randomFuzzDocument 生成随机对象文档，带随机空白，字符串包含引号、逗号、括号和转义
+func randomFuzzDocument(rng *rand.Rand) []byte {
+	var sb strings.Builder
+	writeFuzzValue(rng, &sb, 0, true)
+	return []byte(sb.String())
+}
+
+func writeFuzzValue(rng *rand.Rand, sb *strings.Builder, depth int, object bool) {
+	space := func() {
+		sb.WriteString([]string{"", "", "", " ", "\n  ", "\t"}[rng.Intn(6)])
+	}
+	kind := rng.Intn(8)
+	if object {
+		kind = 0
+	} else if depth >= 4 {
+		kind = 2 + rng.Intn(6)
+	}
+	switch kind {
+	case 0:
+		keys := []string{"a", "b", "c", "d", "msg", "x_1", "a.b", `q\"k`, `s\\k`, ",", "{", "", "\\u0041"}
+		rng.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+		sb.WriteByte('{')
+		for i := range rng.Intn(5) {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			space()
+			sb.WriteString(`"` + keys[i] + `"`)
+			space()
+			sb.WriteByte(':')
+			space()
+			writeFuzzValue(rng, sb, depth+1, false)
+			space()
+		}
+		sb.WriteByte('}')
+	case 1:
+		sb.WriteByte('[')
+		for i := range rng.Intn(4) {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			space()
+			writeFuzzValue(rng, sb, depth+1, rng.Intn(2) == 0)
+			space()
+		}
+		sb.WriteByte(']')
+	case 2, 3:
+		sb.WriteString([]string{`""`, `"plain"`, `"a,b}"`, `"]["`, `"q\"uote"`, `"back\\slash\\"`, `"é\n"`, `"{\"nested\":1}"`}[rng.Intn(8)])
+	case 4, 5:
+		sb.WriteString([]string{`0`, `-1`, `3.25`, `1e10`, `-0.5E-3`}[rng.Intn(5)])
+	default:
+		sb.WriteString([]string{`true`, `false`, `null`}[rng.Intn(3)])
+	}
+}
+
+// hasDuplicateKeys 检查文档中是否存在同一对象内的重复 key（encoding/json 只保留最后一个，无法作为参考）
+func hasDuplicateKeys(doc []byte) bool {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	var stack []map[string]bool
+	expectKey := func() bool {
+		return len(stack) > 0 && stack[len(stack)-1] != nil
+	}
+	// 对象内 key 与值交替出现，afterKey 记录当前对象是否刚读到 key
+	var afterKey []bool
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return false
+		}
+		if err != nil {
+			return true
+		}
+		if expectKey() && !afterKey[len(afterKey)-1] {
+			if key, ok := tok.(string); ok {
+				if stack[len(stack)-1][key] {
+					return true
+				}
+				stack[len(stack)-1][key] = true
+				afterKey[len(afterKey)-1] = true
+				continue
+			}
+		}
+		if len(afterKey) > 0 {
+			afterKey[len(afterKey)-1] = false
+		}
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, map[string]bool{})
+			afterKey = append(afterKey, false)
+		case json.Delim('['):
+			stack = append(stack, nil)
+			afterKey = append(afterKey, false)
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			afterKey = afterKey[:len(afterKey)-1]
+		}
+	}
+}
+
+func describeFuzzRules(rules []PathRule) string {
+	parts := make([]string, len(rules))
+	for i, r := range rules {
+		parts[i] = fmt.Sprintf("%s %s %s", r.Action, r.Path, r.ValueBytes)
+	}
+	return "[" + strings.Join(parts, "; ") + "]"
+}
+
+// TestPathEngineFuzzRegressions 差分模糊测试发现的问题
+func TestPathEngineFuzzRegressions(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "whitespace between key and colon",
+			rules:  []PathRule{{Path: "b", Action: ActionSet, ValueBytes: []byte(`null`)}},
+			input:  "{\n  \"b\"\t: -1}",
+			expect: "{\n  \"b\"\t:null}",
+		},
+		{
+			name:   "add skips existing key followed by whitespace",
+			rules:  []PathRule{{Path: "msg", Action: ActionAdd, ValueBytes: []byte(`1`)}},
+			input:  `{"msg" : true}`,
+			expect: `{"msg" : true}`,
+		},
+		{
+			name:   "set on empty array",
+			rules:  []PathRule{{Path: "e[0]", Action: ActionSet, ValueBytes: []byte(`1`)}, {Path: "f[*]", Action: ActionSet, ValueBytes: []byte(`1`)}},
+			input:  `{"e":[],"f":[ ]}`,
+			expect: `{"e":[],"f":[ ]}`,
+		},
+		{
+			name:   "remove first element keeps layout",
+			rules:  []PathRule{{Path: "a[0]", Action: ActionRemove}},
+			input:  `{"a":[ 1, 2]}`,
+			expect: `{"a":[ 2]}`,
+		},
+		{
+			name:   "add with array index is not a field",
+			rules:  []PathRule{{Path: "k.*[2]", Action: ActionAdd, ValueBytes: []byte(`{}`)}},
+			input:  `{"k":{"a":{}}}`,
+			expect: `{"k":{"a":{}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules)
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+			for _, input := range []io.Reader{strings.NewReader(tt.input), iotest.OneByteReader(strings.NewReader(tt.input))} {
+				var out bytes.Buffer
+				if err := engine.Process(input, &out); err != nil {
+					t.Fatalf("Process error: %v", err)
+				}
+				if out.String() != tt.expect {
+					t.Errorf("got %q, want %q", out.String(), tt.expect)
+				}
+			}
+		})
+	}
+}
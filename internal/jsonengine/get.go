@@ -0,0 +1,363 @@
+package jsonengine
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ValueType Get 查询结果的 JSON 类型
+type ValueType uint8
+
+const (
+	ValueNull ValueType = iota
+	ValueBool
+	ValueNumber
+	ValueString
+	ValueObject
+	ValueArray
+)
+
+// Value Get 查询到的值
+type Value struct {
+	Type ValueType
+	Raw  []byte // 原始 JSON 字节（字符串含引号），引用输入数据，不复制
+}
+
+// String 返回字符串值（已解码转义），null 返回空串，其他类型返回原始 JSON 文本
+func (v Value) String() string {
+	switch v.Type {
+	case ValueString:
+		return decodeKey(v.Raw[1 : len(v.Raw)-1])
+	case ValueNull:
+		return ""
+	default:
+		return string(v.Raw)
+	}
+}
+
+// Int 返回数值的整数部分，非数值返回 0
+func (v Value) Int() int64 {
+	if v.Type != ValueNumber {
+		return 0
+	}
+	if n, err := strconv.ParseInt(string(v.Raw), 10, 64); err == nil {
+		return n
+	}
+	f, _ := strconv.ParseFloat(string(v.Raw), 64)
+	return int64(f)
+}
+
+// Float 返回数值，非数值返回 0
+func (v Value) Float() float64 {
+	if v.Type != ValueNumber {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(string(v.Raw), 64)
+	return f
+}
+
+// Bool 仅在值为 true 时返回 true
+func (v Value) Bool() bool {
+	return v.Type == ValueBool && v.Raw[0] == 't'
+}
+
+// getQueryCacheSize 缓存的已编译查询路径数上限
+// 调用方的路径通常是代码中的常量，超出上限后新路径每次重新编译，不再缓存
+const getQueryCacheSize = 1024
+
+var (
+	getQueries     sync.Map // path -> *PathMatcher
+	getQueryCached atomic.Int32
+)
+
+// Get 从 JSON 数据中读取单个值，不解析整个文档
+// 路径语法与规则相同（见 ParsePath），但从根开始逐层匹配，不像 set/remove 那样匹配任意深度的后缀。
+// 不在路径上的对象和数组整段跳过；通配符、切片、键模式命中多个值时返回文档顺序中的第一个，
+// 对象含重复 key 时同样取第一个（encoding/json 取最后一个）。
+// 只检查到找到目标为止，不保证整个输入是合法 JSON；路径非法或未找到时返回 false。
+func Get(data []byte, path string) (Value, bool) {
+	matcher := getQueryMatcher(path)
+	if matcher == nil {
+		return Value{}, false
+	}
+	g := getter{data: data, matcher: matcher, depth: len(matcher.Rules()[0].Segments())}
+	_, value, ok := g.find(g.skipSpace(0), matcher.Root())
+	return value, ok
+}
+
+// getQueryMatcher 返回路径编译出的单规则匹配器，路径非法时返回 nil
+func getQueryMatcher(path string) *PathMatcher {
+	if cached, ok := getQueries.Load(path); ok {
+		return cached.(*PathMatcher)
+	}
+	matcher, err := BuildMatcher([]PathRule{{Path: path, Action: ActionCapture}})
+	if err != nil {
+		return nil
+	}
+	if getQueryCached.Load() < getQueryCacheSize {
+		if _, loaded := getQueries.LoadOrStore(path, matcher); !loaded {
+			getQueryCached.Add(1)
+		}
+	}
+	return matcher
+}
+
+// getter Get 的单次查询状态
+type getter struct {
+	data    []byte
+	matcher *PathMatcher
+	depth   int // 目标所在深度，即路径段数
+}
+
+// find 在 i 处的值中查找目标，state 为该值对应的自动机节点
+// 返回值结束后的偏移（-1 表示输入不合法）以及是否找到
+func (g *getter) find(i int, state *ACNode) (int, Value, bool) {
+	if i >= len(g.data) {
+		return -1, Value{}, false
+	}
+
+	// 节点只沿规则自身的路径逐层下降，到达路径深度即为目标
+	if state.depth == g.depth {
+		typ, valid := valueTypeOf(g.data[i])
+		end := g.skipValue(i)
+		if !valid || end < 0 {
+			return -1, Value{}, false
+		}
+		return end, Value{Type: typ, Raw: g.data[i:end]}, true
+	}
+
+	switch g.data[i] {
+	case '{':
+		return g.findInObject(i, state)
+	case '[':
+		return g.findInArray(i, state)
+	default:
+		return g.skipValue(i), Value{}, false
+	}
+}
+
+// findInObject 在对象的字段中查找目标
+func (g *getter) findInObject(i int, state *ACNode) (int, Value, bool) {
+	i = g.skipSpace(i + 1)
+	if i < len(g.data) && g.data[i] == '}' {
+		return i + 1, Value{}, false
+	}
+	for i < len(g.data) && g.data[i] == '"' {
+		keyEnd := skipJSONString(g.data, i)
+		if keyEnd < 0 {
+			return -1, Value{}, false
+		}
+		key := decodeKey(g.data[i+1 : keyEnd-1])
+
+		i = g.skipSpace(keyEnd)
+		if i >= len(g.data) || g.data[i] != ':' {
+			return -1, Value{}, false
+		}
+		i = g.skipSpace(i + 1)
+
+		// 经失败指针回退到的浅层节点不是从根开始的匹配，按未命中处理
+		if next, _ := g.matcher.Match(state, key, false, 0); next.depth == state.depth+1 {
+			end, value, ok := g.find(i, next)
+			if ok {
+				return end, value, true
+			}
+			i = end
+		} else {
+			i = g.skipValue(i)
+		}
+		if i < 0 {
+			return -1, Value{}, false
+		}
+
+		i = g.skipSpace(i)
+		if i >= len(g.data) {
+			break
+		}
+		switch g.data[i] {
+		case ',':
+			i = g.skipSpace(i + 1)
+		case '}':
+			return i + 1, Value{}, false
+		default:
+			return -1, Value{}, false
+		}
+	}
+	return -1, Value{}, false
+}
+
+// findInArray 在数组元素中查找目标
+func (g *getter) findInArray(i int, state *ACNode) (int, Value, bool) {
+	arrayLen := -1
+	if g.matcher.NeedsArrayLen() {
+		if arrayLen = g.countElements(i); arrayLen < 0 {
+			return -1, Value{}, false
+		}
+	}
+
+	i = g.skipSpace(i + 1)
+	if i < len(g.data) && g.data[i] == ']' {
+		return i + 1, Value{}, false
+	}
+	for idx := 0; i >= 0 && i < len(g.data); idx++ {
+		if next, _ := g.matcher.MatchElement(state, "", true, idx, arrayLen); next.depth == state.depth+1 {
+			end, value, ok := g.find(i, next)
+			if ok {
+				return end, value, true
+			}
+			i = end
+		} else {
+			i = g.skipValue(i)
+		}
+		if i < 0 {
+			break
+		}
+
+		i = g.skipSpace(i)
+		if i >= len(g.data) {
+			break
+		}
+		switch g.data[i] {
+		case ',':
+			i = g.skipSpace(i + 1)
+		case ']':
+			return i + 1, Value{}, false
+		default:
+			return -1, Value{}, false
+		}
+	}
+	return -1, Value{}, false
+}
+
+// countElements 统计 i 处数组的元素个数（负索引和切片匹配需要），输入不合法时返回 -1
+func (g *getter) countElements(i int) int {
+	i = g.skipSpace(i + 1)
+	if i < len(g.data) && g.data[i] == ']' {
+		return 0
+	}
+	for n := 1; ; n++ {
+		if i = g.skipValue(i); i < 0 {
+			return -1
+		}
+		if i = g.skipSpace(i); i >= len(g.data) {
+			return -1
+		}
+		switch g.data[i] {
+		case ',':
+			i = g.skipSpace(i + 1)
+		case ']':
+			return n
+		default:
+			return -1
+		}
+	}
+}
+
+// skipValue 跳过 i 处的值，返回值结束后的偏移，输入不合法时返回 -1
+func (g *getter) skipValue(i int) int {
+	if i < 0 || i >= len(g.data) {
+		return -1
+	}
+	switch g.data[i] {
+	case '"':
+		return skipJSONString(g.data, i)
+	case '{', '[':
+		return skipJSONCompound(g.data, i)
+	default:
+		end := i
+		for end < len(g.data) && !isValueDelimiter(g.data[end]) {
+			end++
+		}
+		// 容器内的数字和字面量之后必有分隔符，到达末尾说明输入被截断
+		if end == i || end == len(g.data) {
+			return -1
+		}
+		return end
+	}
+}
+
+// skipSpace 跳过空白
+func (g *getter) skipSpace(i int) int {
+	for i < len(g.data) {
+		switch g.data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// skipJSONString 跳过 i 处（起始引号）的字符串，返回结束引号之后的偏移，未闭合时返回 -1
+func skipJSONString(data []byte, i int) int {
+	for j := i + 1; j < len(data); j++ {
+		k := bytes.IndexByte(data[j:], '"')
+		if k < 0 {
+			return -1
+		}
+		j += k
+		backslashes := 0
+		for b := j - 1; b > i && data[b] == '\\'; b-- {
+			backslashes++
+		}
+		if backslashes%2 == 0 {
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// skipJSONCompound 跳过 i 处的对象或数组，返回结束括号之后的偏移，未闭合时返回 -1
+// 按块用 ScanStructuralSkipStrings 找出字符串外的括号，只统计嵌套深度
+func skipJSONCompound(data []byte, i int) int {
+	var state ScanState
+	var positions [512]uint32
+	depth := 0
+	for off := i; off < len(data); {
+		end := min(off+len(positions), len(data))
+		n := ScanStructuralSkipStrings(data[off:end], positions[:], &state)
+		for _, p := range positions[:n] {
+			switch data[off+int(p)] {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return off + int(p) + 1
+				}
+			}
+		}
+		off = end
+	}
+	return -1
+}
+
+// isValueDelimiter 检查字节是否结束一个数字或字面量
+func isValueDelimiter(c byte) bool {
+	switch c {
+	case ',', '}', ']', ' ', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
+
+// valueTypeOf 由值的首字节判断类型，不是合法的值起始字节时返回 false
+func valueTypeOf(c byte) (ValueType, bool) {
+	switch {
+	case c == '"':
+		return ValueString, true
+	case c == '{':
+		return ValueObject, true
+	case c == '[':
+		return ValueArray, true
+	case c == 't' || c == 'f':
+		return ValueBool, true
+	case c == 'n':
+		return ValueNull, true
+	case c == '-' || c >= '0' && c <= '9':
+		return ValueNumber, true
+	default:
+		return 0, false
+	}
+}
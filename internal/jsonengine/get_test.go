@@ -0,0 +1,177 @@
+package jsonengine
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	doc := []byte(`{
+		"model" : "gpt-4o",
+		"stream": true,
+		"skip": {"model": "nested", "s": "}]\"{["},
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": "hi 你好"}
+		],
+		"usage": {"total_tokens": 42, "cost": 1.5e2},
+		"a.b": null,
+		"tools": [],
+		"dup": 1, "dup": 2
+	}`)
+
+	tests := []struct {
+		path  string
+		typ   ValueType
+		raw   string
+		found bool
+	}{
+		{path: "model", typ: ValueString, raw: `"gpt-4o"`, found: true},
+		{path: "stream", typ: ValueBool, raw: `true`, found: true},
+		{path: "usage.total_tokens", typ: ValueNumber, raw: `42`, found: true},
+		{path: "usage", typ: ValueObject, raw: `{"total_tokens": 42, "cost": 1.5e2}`, found: true},
+		{path: "messages[1].role", typ: ValueString, raw: `"user"`, found: true},
+		{path: "messages[-1].role", typ: ValueString, raw: `"user"`, found: true},
+		{path: "messages[*].content", typ: ValueString, raw: `"be brief"`, found: true},
+		{path: "messages[1:].content", typ: ValueString, raw: `"hi 你好"`, found: true},
+		{path: "*.total_tokens", typ: ValueNumber, raw: `42`, found: true},
+		{path: `"a.b"`, typ: ValueNull, raw: `null`, found: true},
+		{path: "/a.b", typ: ValueNull, raw: `null`, found: true},
+		{path: "tools", typ: ValueArray, raw: `[]`, found: true},
+		{path: "dup", typ: ValueNumber, raw: `1`, found: true},
+		// 从根开始匹配，不匹配任意深度的后缀
+		{path: "total_tokens"},
+		{path: "skip.s.x"},
+		{path: "messages[2].role"},
+		{path: "messages.role"},
+		{path: "missing"},
+		{path: "[invalid"},
+	}
+	for _, tt := range tests {
+		value, found := Get(doc, tt.path)
+		if found != tt.found {
+			t.Errorf("Get(%q) found = %v, want %v", tt.path, found, tt.found)
+			continue
+		}
+		if found && (value.Type != tt.typ || string(value.Raw) != tt.raw) {
+			t.Errorf("Get(%q) = %v %s, want %v %s", tt.path, value.Type, value.Raw, tt.typ, tt.raw)
+		}
+	}
+}
+
+func TestGetValueAccessors(t *testing.T) {
+	doc := []byte(`{"s": "a\"bé", "i": 7, "f": -2.5, "big": 1e3, "t": true, "f2": false, "n": null, "o": {"k": 1}}`)
+	get := func(path string) Value {
+		v, ok := Get(doc, path)
+		if !ok {
+			t.Fatalf("Get(%q) not found", path)
+		}
+		return v
+	}
+
+	if got := get("s").String(); got != "a\"bé" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := get("i").Int(); got != 7 {
+		t.Errorf("Int() = %d", got)
+	}
+	if got := get("f").Float(); got != -2.5 {
+		t.Errorf("Float() = %v", got)
+	}
+	if got := get("big").Int(); got != 1000 {
+		t.Errorf("Int() of 1e3 = %d", got)
+	}
+	if !get("t").Bool() || get("f2").Bool() || get("s").Bool() {
+		t.Error("Bool() mismatch")
+	}
+	if got := get("n").String(); got != "" {
+		t.Errorf("String() of null = %q", got)
+	}
+	if got := get("o").String(); got != `{"k": 1}` {
+		t.Errorf("String() of object = %q", got)
+	}
+	if got := get("s").Int(); got != 0 {
+		t.Errorf("Int() of string = %d", got)
+	}
+}
+
+func TestGetMalformed(t *testing.T) {
+	for _, doc := range []string{
+		``,
+		`{"a": 1`,
+		`{"a": "unterminated}`,
+		`{"x": {"y": [1, 2}`,
+		`{"x" 1, "a": 2}`,
+		`{"a": }`,
+	} {
+		if v, ok := Get([]byte(doc), "a"); ok {
+			t.Errorf("Get(%q) = %s, want not found", doc, v.Raw)
+		}
+	}
+}
+
+// TestGetDifferential 随机文档和路径上与 encoding/json 解码后逐层匹配的结果比对
+// 通配段可能命中多个值，Get 的结果须是其中之一
+func TestGetDifferential(t *testing.T) {
+	for seed := int64(0); seed < 500; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		doc := randomFuzzDocument(rng)
+		if hasDuplicateKeys(doc) {
+			continue
+		}
+		var root map[string]any
+		if err := json.Unmarshal(doc, &root); err != nil {
+			t.Fatalf("seed %d: generated invalid JSON: %v", seed, err)
+		}
+		path := randomFuzzPath(rng, root)
+		segs, err := ParsePath(path)
+		if err != nil {
+			continue
+		}
+
+		want := referenceGet(root, segs)
+		value, found := Get(doc, path)
+		if found != (len(want) > 0) {
+			t.Fatalf("seed %d: Get(%q) found = %v, want %d matches\ndoc: %s", seed, path, found, len(want), doc)
+		}
+		if !found {
+			continue
+		}
+		var got any
+		if err := json.Unmarshal(value.Raw, &got); err != nil {
+			t.Fatalf("seed %d: Get(%q) returned invalid JSON %s: %v", seed, path, value.Raw, err)
+		}
+		matched := false
+		for _, w := range want {
+			matched = matched || reflect.DeepEqual(got, w)
+		}
+		if !matched {
+			t.Fatalf("seed %d: Get(%q) = %s, want one of %v\ndoc: %s", seed, path, value.Raw, want, doc)
+		}
+	}
+}
+
+// referenceGet 在解码后的文档上从根逐层匹配，返回所有命中的值
+func referenceGet(v any, segs []Segment) []any {
+	if len(segs) == 0 {
+		return []any{v}
+	}
+	var out []any
+	switch node := v.(type) {
+	case map[string]any:
+		for key, child := range node {
+			if refMatch(segs[:1], []refStep{{key: key}}) {
+				out = append(out, referenceGet(child, segs[1:])...)
+			}
+		}
+	case []any:
+		for idx, child := range node {
+			if refMatch(segs[:1], []refStep{{array: true, index: idx}}) {
+				out = append(out, referenceGet(child, segs[1:])...)
+			}
+		}
+	}
+	return out
+}
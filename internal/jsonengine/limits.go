@@ -0,0 +1,94 @@
+package jsonengine
+
+import (
+	"fmt"
+	"io"
+)
+
+// 超出的限制类型，见 LimitError.Limit
+const (
+	LimitDepth        = "depth"
+	LimitKeyLength    = "key_length"
+	LimitDocumentSize = "document_size"
+)
+
+// LimitError 输入超出引擎限制（WithMaxDepth/WithMaxKeyLength/WithMaxDocumentSize）时返回的错误
+// 与严格模式一样，出错前已处理的数据块仍会写入 output
+type LimitError struct {
+	Limit  string // LimitDepth、LimitKeyLength 或 LimitDocumentSize
+	Max    int64  // 配置的上限
+	Offset int64  // 超出限制处的字节偏移
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("JSON %s exceeds limit %d at offset %d", e.Limit, e.Max, e.Offset)
+}
+
+// limits 处理限制，0 表示不限制
+type limits struct {
+	maxDepth        int
+	maxKeyLength    int
+	maxDocumentSize int64
+}
+
+func (l limits) enabled() bool {
+	return l.maxDepth > 0 || l.maxKeyLength > 0 || l.maxDocumentSize > 0
+}
+
+// WithMaxDepth 限制对象和数组的最大嵌套深度（顶层对象为 1）
+// 被跳过的值内部的嵌套同样计入；严格模式下语法检查同样受此限制
+func WithMaxDepth(depth int) PathEngineOption {
+	return func(e *PathEngine) {
+		e.limits.maxDepth = max(depth, 0)
+	}
+}
+
+// WithMaxKeyLength 限制 key 的最大字节数（按原始转义形式，不含引号）
+// 只检查需要缓冲以匹配规则的 key，被跳过的值内部的 key 不缓冲也不检查
+func WithMaxKeyLength(length int) PathEngineOption {
+	return func(e *PathEngine) {
+		e.limits.maxKeyLength = max(length, 0)
+	}
+}
+
+// WithMaxDocumentSize 限制单个文档的最大字节数
+// 流式处理在读到超出部分时返回错误，不会先整体读入
+func WithMaxDocumentSize(size int64) PathEngineOption {
+	return func(e *PathEngine) {
+		e.limits.maxDocumentSize = max(size, 0)
+	}
+}
+
+// limitReader 需要整体读入时最多读取 maxDocumentSize+1 字节，超出部分由处理器报告
+func (e *PathEngine) limitReader(input io.Reader) io.Reader {
+	if e.limits.maxDocumentSize <= 0 {
+		return input
+	}
+	return io.LimitReader(input, e.limits.maxDocumentSize+1)
+}
+
+// checkChunkSize 处理下一个数据块前检查文档大小
+func (p *PathProcessor) checkChunkSize(n int) error {
+	if limit := p.limits.maxDocumentSize; limit > 0 && int64(p.consumed+n) > limit {
+		return &LimitError{Limit: LimitDocumentSize, Max: limit, Offset: limit}
+	}
+	return nil
+}
+
+// checkLimits 检查当前嵌套深度和正在读取的 key 长度，offset 为当前字节偏移
+func (p *PathProcessor) checkLimits(offset int) error {
+	if limit := p.limits.maxDepth; limit > 0 {
+		depth := len(p.pathStack)
+		if p.skipping {
+			depth += p.skipState.depth
+		}
+		if depth > limit {
+			return &LimitError{Limit: LimitDepth, Max: int64(limit), Offset: int64(offset)}
+		}
+	}
+	// keyBuffer 包含引号
+	if limit := p.limits.maxKeyLength; limit > 0 && p.inKey && len(p.keyBuffer) > limit+2 {
+		return &LimitError{Limit: LimitKeyLength, Max: int64(limit), Offset: int64(offset)}
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package jsonengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// IssueSeverity 规则问题的严重程度
+type IssueSeverity string
+
+const (
+	// SeverityError 规则无法按预期编译或生效，NewPathEngine 会失败或产生非法输出
+	SeverityError IssueSeverity = "error"
+	// SeverityWarning 规则可以编译，但部分或全部情况下不会生效
+	SeverityWarning IssueSeverity = "warning"
+)
+
+// 规则问题代码
+const (
+	IssueInvalidPath   = "invalid_path"   // 路径为空或无法解析
+	IssueInvalidAction = "invalid_action" // 不支持的操作类型
+	IssueInvalidValue  = "invalid_value"  // 值不合法（ValueBytes 不是 JSON、mask/transform/clamp/rename/copy/truncate/strip_base64 配置错误）
+	IssueConflict      = "conflict"       // 同一路径上有多条互斥的规则，只有一条生效
+	IssueUnreachable   = "unreachable"    // 规则永远不会生效
+	IssueShadowed      = "shadowed"       // 规则在部分分支上被更具体的路径遮蔽
+	IssueInvalidRoute  = "invalid_route"  // 路由选择器（paths/methods/models）不合法
+)
+
+// RuleIssue 规则静态检查发现的问题
+type RuleIssue struct {
+	Rule     int           `json:"rule"`              // 规则在列表中的下标
+	Path     string        `json:"path"`              // 规则路径
+	Severity IssueSeverity `json:"severity"`          // 严重程度
+	Code     string        `json:"code"`              // 问题代码，见 IssueInvalidPath 等
+	Message  string        `json:"message"`           // 问题描述
+	Related  *int          `json:"related,omitempty"` // 引发问题的另一条规则下标
+}
+
+// HasRuleErrors 检查问题列表中是否有 error 级别的问题
+func HasRuleErrors(issues []RuleIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// lintRule 检查中使用的已解析规则
+type lintRule struct {
+	index    int
+	rule     PathRule
+	segments []Segment
+}
+
+// ValidateRules 静态检查规则集，返回无效路径、互斥规则、非法值和无法生效的规则
+// 检查按默认冲突解析方式（ConflictHighestPriority）进行；返回 nil 表示未发现问题。
+// 遮蔽检查基于匹配器的行为：每一层只进入最具体的子节点，
+// 因此 messages[*].role 不会作用于 messages[0]，只要另有规则以 messages[0] 开头。
+func ValidateRules(rules []PathRule) []RuleIssue {
+	return append(validateRules(rules), exprRefIssues(rules)...)
+}
+
+// validateRules 逐条及两两检查规则，不含表达式引用字段带来的问题
+func validateRules(rules []PathRule) []RuleIssue {
+	var issues []RuleIssue
+	report := func(r lintRule, severity IssueSeverity, code, msg string, related *int) {
+		issues = append(issues, RuleIssue{Rule: r.index, Path: r.rule.Path, Severity: severity, Code: code, Message: msg, Related: related})
+	}
+
+	parsed := make([]lintRule, 0, len(rules))
+	for i, rule := range rules {
+		r := lintRule{index: i, rule: rule}
+		if strings.TrimSpace(rule.Path) == "" {
+			report(r, SeverityWarning, IssueInvalidPath, "path is empty, the rule is ignored", nil)
+			continue
+		}
+		segments, err := ParsePath(rule.Path)
+		if err != nil {
+			report(r, SeverityError, IssueInvalidPath, err.Error(), nil)
+			continue
+		}
+		r.segments = segments
+		r.rule.segments = segments
+
+		if !knownAction(rule.Action) {
+			report(r, SeverityError, IssueInvalidAction, fmt.Sprintf("unsupported action %q", rule.Action), nil)
+			continue
+		}
+		if msg := lintValue(r.rule); msg != "" {
+			report(r, SeverityError, IssueInvalidValue, msg, nil)
+			continue
+		}
+		if err := ValidateRoute(rule.Paths, rule.Methods); err != nil {
+			report(r, SeverityError, IssueInvalidRoute, err.Error(), nil)
+			continue
+		}
+		if err := validateModels(rule.Models); err != nil {
+			report(r, SeverityError, IssueInvalidRoute, err.Error(), nil)
+			continue
+		}
+		parsed = append(parsed, r)
+	}
+
+	for i, r := range parsed {
+		if msg, related := lintConflict(parsed[:i], r); msg != "" {
+			report(r, SeverityWarning, IssueConflict, msg, related)
+			continue
+		}
+		if msg, related := lintUnreachable(parsed, r); msg != "" {
+			report(r, SeverityWarning, IssueUnreachable, msg, related)
+			continue
+		}
+		if msg, related := lintShadowed(parsed, r); msg != "" {
+			report(r, SeverityWarning, IssueShadowed, msg, related)
+		}
+	}
+	return issues
+}
+
+// knownAction 检查操作类型是否受 PathEngine 支持
+func knownAction(a Action) bool {
+	switch a {
+	case ActionSet, ActionAdd, ActionRemove, ActionKeep, ActionMask, ActionTransform, ActionClamp, ActionRename, ActionCopy, ActionCapture, ActionTruncate, ActionStripBase64, ActionPlugin:
+		return true
+	}
+	return false
+}
+
+// lintValue 检查规则的值，返回问题描述
+func lintValue(rule PathRule) string {
+	if (rule.Action == ActionSet || rule.Action == ActionAdd) && len(rule.ValueBytes) > 0 && !json.Valid(rule.ValueBytes) {
+		return "valueBytes is not valid JSON"
+	}
+	if _, err := compileValueTransform(rule); err != nil {
+		return err.Error()
+	}
+	if rule.Action == ActionRename {
+		if _, err := fieldTarget(rule, rule.segments); err != nil {
+			return err.Error()
+		}
+	}
+	if _, ok := rule.Value.(string); rule.Action == ActionCapture && rule.Value != nil && !ok {
+		return "capture value must be the result name"
+	}
+	if _, err := compileRuleExpr(rule); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// lintConflict 检查之前的规则中是否有同一路径、同一优先级的互斥规则
+// 改写值的操作（remove/set/mask/transform/clamp/copy/truncate/strip_base64）之间互斥，重复的 add 或 rename 也只有一条生效
+func lintConflict(previous []lintRule, r lintRule) (string, *int) {
+	for _, other := range previous {
+		if other.rule.Priority != r.rule.Priority || !sameSegments(other.segments, r.segments) || !routesOverlap(other.rule, r.rule) {
+			continue
+		}
+		if !exclusiveActions(other.rule.Action, r.rule.Action) {
+			continue
+		}
+		winner := other
+		if actionPrecedence(r.rule.Action) > actionPrecedence(other.rule.Action) {
+			winner = r
+		}
+		return fmt.Sprintf("conflicts with rule #%d (%s %s) on the same path with the same priority; only the %s rule applies, set priority to choose explicitly",
+			other.index, other.rule.Action, other.rule.Path, winner.rule.Action), &other.index
+	}
+	return "", nil
+}
+
+// exclusiveActions 检查同一路径上的两个操作是否只能有一个生效
+func exclusiveActions(a, b Action) bool {
+	if actionPrecedence(a) > 0 && actionPrecedence(b) > 0 {
+		return true
+	}
+	return a == b && (a == ActionAdd || a == ActionRename)
+}
+
+// lintUnreachable 检查规则是否位于被删除或整体捕获的字段之下，或在白名单模式下不在任何保留路径上
+func lintUnreachable(rules []lintRule, r lintRule) (string, *int) {
+	hasKeep := false
+	for _, other := range rules {
+		if other.rule.Action == ActionKeep && routesOverlap(other.rule, r.rule) {
+			hasKeep = true
+		}
+		if len(other.segments) >= len(r.segments) || !sameSegments(other.segments, r.segments[:len(other.segments)]) || !routesOverlap(other.rule, r.rule) {
+			continue
+		}
+		switch other.rule.Action {
+		case ActionRemove:
+			return fmt.Sprintf("the parent field is removed by rule #%d (%s)", other.index, other.rule.Path), &other.index
+		case ActionCapture:
+			return fmt.Sprintf("the parent value is captured as a whole by rule #%d (%s), nested rules do not apply", other.index, other.rule.Path), &other.index
+		}
+	}
+
+	if !hasKeep || r.rule.Action == ActionKeep {
+		return "", nil
+	}
+	for _, other := range rules {
+		if other.rule.Action == ActionKeep && overlaps(other.segments, r.segments) && routesOverlap(other.rule, r.rule) {
+			return "", nil
+		}
+	}
+	return "the path is not kept by any keep rule, so the field is always dropped", nil
+}
+
+// lintShadowed 检查规则的宽泛段（*、模式、[*]、切片、负索引）是否被其他规则更具体的同级段遮蔽
+// 匹配器在每一层只进入最具体的子节点，宽泛规则在该分支下不会生效
+func lintShadowed(rules []lintRule, r lintRule) (string, *int) {
+	if r.rule.Action == ActionKeep {
+		return "", nil
+	}
+	// 设置了 priority 时末段的所有匹配节点都参与冲突解析，只有中间段会被遮蔽
+	last := len(r.segments) - 1
+	if hasPriorities(rules) {
+		last--
+	}
+	for depth, seg := range r.segments[:last+1] {
+		if !broadSegment(seg) {
+			continue
+		}
+		for _, other := range rules {
+			if len(other.segments) <= depth || !sameSegments(other.segments[:depth], r.segments[:depth]) || !routesOverlap(other.rule, r.rule) {
+				continue
+			}
+			if !moreSpecific(other.segments[depth], seg) {
+				continue
+			}
+			branch := segmentsString(other.segments[:depth+1])
+			return fmt.Sprintf("does not apply under %s because rule #%d (%s) takes the more specific branch there",
+				branch, other.index, other.rule.Path), &other.index
+		}
+	}
+	return "", nil
+}
+
+// hasPriorities 检查是否有规则设置了 priority
+func hasPriorities(rules []lintRule) bool {
+	for _, r := range rules {
+		if r.rule.Priority != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// broadSegment 检查段是否可能匹配多个键或索引
+func broadSegment(seg Segment) bool {
+	switch seg.Type {
+	case SegWildcard, SegPattern, SegArrayAll, SegArraySlice:
+		return true
+	case SegArrayIdx:
+		return seg.Index < 0
+	}
+	return false
+}
+
+// moreSpecific 检查 specific 是否是 broad 所匹配范围内更具体的同级段（即匹配时 specific 优先）
+func moreSpecific(specific, broad Segment) bool {
+	switch broad.Type {
+	case SegWildcard:
+		return specific.Type == SegField || specific.Type == SegPattern
+	case SegPattern:
+		return specific.Type == SegField && broad.MatchKey(specific.Value)
+	case SegArrayAll:
+		return specific.Type == SegArrayIdx || specific.Type == SegArraySlice
+	case SegArraySlice:
+		return specific.Type == SegArrayIdx && (specific.Index < 0 || broad.MatchIndex(specific.Index, -1))
+	case SegArrayIdx:
+		// 负索引只被非负索引遮蔽
+		return specific.Type == SegArrayIdx && specific.Index >= 0
+	}
+	return false
+}
+
+// overlaps 检查两条路径是否互为前缀（按段覆盖关系比较），即其中一条位于另一条之下
+func overlaps(a, b []Segment) bool {
+	n := min(len(a), len(b))
+	for i := range n {
+		if !covers(a[i], b[i]) && !covers(b[i], a[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// covers 检查段 a 匹配的范围是否包含段 b
+func covers(a, b Segment) bool {
+	if sameSegment(a, b) {
+		return true
+	}
+	switch a.Type {
+	case SegWildcard:
+		return b.Type == SegField || b.Type == SegPattern
+	case SegPattern:
+		return b.Type == SegField && a.MatchKey(b.Value)
+	case SegArrayAll:
+		return b.Type == SegArrayIdx || b.Type == SegArraySlice
+	}
+	return false
+}
+
+// sameSegments 检查两组段是否完全相同
+func sameSegments(a, b []Segment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameSegment(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameSegment(a, b Segment) bool {
+	return a.Type == b.Type && a.Value == b.Value
+}
+
+// segmentsString 以点号路径语法输出段列表
+func segmentsString(segments []Segment) string {
+	var sb strings.Builder
+	for i, seg := range segments {
+		if i > 0 && seg.Type != SegArrayAll && seg.Type != SegArrayIdx && seg.Type != SegArraySlice {
+			sb.WriteByte('.')
+		}
+		if seg.Type == SegField && strings.ContainsAny(seg.Value, `.[]*"\/`) {
+			sb.WriteString(quoteSegment(seg.Value))
+		} else {
+			sb.WriteString(seg.Value)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,96 @@
+package jsonengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// 脱敏方式（ActionMask 规则的 Value）
+const (
+	MaskPartial = "partial" // 保留前 3 位和后 4 位，如 "sk-****1234"（默认）
+	MaskRedact  = "redact"  // 替换为固定的 "[REDACTED]"
+	MaskHash    = "hash"    // 替换为 "sha256:" + 前 16 位十六进制摘要，便于关联同一值
+)
+
+const (
+	maskPlaceholder    = "****"
+	maskRedacted       = "[REDACTED]"
+	maskKeepPrefix     = 3
+	maskKeepSuffix     = 4
+	maskHashHexLength  = 16
+	maskMinPartialSize = maskKeepPrefix + maskKeepSuffix + 2
+)
+
+// ParseMaskRules 将逗号分隔的 path[=mode] 条目解析为 ActionMask 规则
+// mode 为 partial/redact/hash，省略时使用 defaultMode；= 之后不是脱敏方式时整个条目视为路径
+func ParseMaskRules(spec, defaultMode string) ([]PathRule, error) {
+	var rules []PathRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		path, mode := entry, defaultMode
+		if i := strings.LastIndexByte(entry, '='); i >= 0 {
+			switch suffix := strings.TrimSpace(entry[i+1:]); suffix {
+			case MaskPartial, MaskRedact, MaskHash:
+				path, mode = strings.TrimSpace(entry[:i]), suffix
+			}
+		}
+		if _, err := parseMaskMode(mode); err != nil {
+			return nil, err
+		}
+		segments, err := ParsePath(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(segments) == 0 {
+			return nil, &PathError{Msg: "empty mask path: " + entry}
+		}
+		rules = append(rules, PathRule{Path: path, Action: ActionMask, Value: mode})
+	}
+	return rules, nil
+}
+
+// parseMaskMode 解析脱敏方式（空值使用默认的 partial），返回对应的字符串转换函数
+func parseMaskMode(value any) (func(string) string, error) {
+	if value == nil {
+		return maskPartial, nil
+	}
+	mode, ok := value.(string)
+	if !ok {
+		return nil, &PathError{Msg: "mask mode must be a string"}
+	}
+	switch mode {
+	case "", MaskPartial:
+		return maskPartial, nil
+	case MaskRedact:
+		return maskRedact, nil
+	case MaskHash:
+		return maskHash, nil
+	default:
+		return nil, &PathError{Msg: "invalid mask mode: " + mode}
+	}
+}
+
+// maskPartial 保留首尾少量字符
+func maskPartial(s string) string {
+	runes := []rune(s)
+	// 太短的值保留首尾会泄露大部分内容，整体替换
+	if len(runes) < maskMinPartialSize {
+		return maskPlaceholder
+	}
+	return string(runes[:maskKeepPrefix]) + maskPlaceholder + string(runes[len(runes)-maskKeepSuffix:])
+}
+
+// maskRedact 固定替换
+func maskRedact(string) string {
+	return maskRedacted
+}
+
+// maskHash 替换为截断的 SHA-256 摘要
+func maskHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:])[:maskHashHexLength]
+}
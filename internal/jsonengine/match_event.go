@@ -0,0 +1,73 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MatchEvent 一次规则生效的事件（见 WithOnMatch）
+type MatchEvent struct {
+	// Rule 规则在 Rules() 中的下标，all_apply 模式下每条参与的规则各产生一个事件；
+	// 白名单（keep）模式下因不在任何 keep 路径上而删除的字段为 -1
+	Rule   int
+	Action Action
+	// Path 命中位置的 JSON Pointer，如 /messages/0/content
+	Path string
+	// Value 原值，仅在 WithMatchValues 时提供；add 与 rename 为空
+	Value json.RawMessage
+}
+
+// WithOnMatch 设置规则生效回调，每次规则改变输出时调用
+// 可在不增加第二次解析的情况下统计各字段被删除或改写的次数。capture 不改变输出，不触发回调。
+// 回调在处理 goroutine 中同步执行，不应阻塞；Value 引用的内存在回调返回后可能被复用，需要保留时自行复制。
+func WithOnMatch(fn func(MatchEvent)) PathEngineOption {
+	return func(e *PathEngine) {
+		e.onMatch = fn
+	}
+}
+
+// WithMatchValues 让 WithOnMatch 的事件携带原值
+// 作用于值的操作（remove/set/mask/transform/clamp/copy）在原值跳过结束时才发出事件，并需要缓冲原值。
+func WithMatchValues() PathEngineOption {
+	return func(e *PathEngine) {
+		e.matchValues = true
+	}
+}
+
+// emitMatch 通知一次命中；value 为 true 且需要原值时，事件在原值跳过结束后发出
+func (p *PathProcessor) emitMatch(action Action, rules []int, path string, value bool) {
+	if len(rules) == 0 {
+		p.pushMatch(MatchEvent{Rule: -1, Action: action, Path: path}, value)
+		return
+	}
+	for _, rule := range rules {
+		p.pushMatch(MatchEvent{Rule: rule, Action: action, Path: path}, value)
+	}
+}
+
+func (p *PathProcessor) pushMatch(event MatchEvent, value bool) {
+	if !value || !p.matchValues {
+		p.onMatch(event)
+		return
+	}
+	if !p.matchBuffering {
+		p.matchBuffering = true
+		p.pendingMatches = p.pendingMatches[:0]
+		p.transformBuf = p.transformBuf[:0]
+	}
+	p.pendingMatches = append(p.pendingMatches, event)
+}
+
+// finishMatchValue 原值跳过结束，发出等待原值的事件
+func (p *PathProcessor) finishMatchValue() {
+	raw := json.RawMessage(bytes.TrimSpace(p.transformBuf))
+	for _, event := range p.pendingMatches {
+		event.Value = raw
+		p.onMatch(event)
+	}
+	p.matchBuffering = false
+	p.pendingMatches = p.pendingMatches[:0]
+	if !p.transforming && !p.capturing {
+		p.transformBuf = p.transformBuf[:0]
+	}
+}
@@ -0,0 +1,166 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// jsonPatchOperation RFC 6902 JSON Patch 单条操作
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// FromJSONPatch 将 RFC 6902 JSON Patch 文档编译为 PathRule 列表
+//
+// 映射关系（流式单遍处理，无法回读其他位置的值，因此有以下限制）：
+//   - add：字段存在时替换、不存在时添加（set + add），不支持向数组插入元素
+//   - replace：set
+//   - remove：remove
+//   - move：同一对象内的字段重命名（rename + 删除已存在的目标字段）
+//   - copy：同一对象内的字段复制（copy + 删除已存在的目标字段）
+//
+// 纯数字的指针段按数组索引处理，test 操作不支持。
+func FromJSONPatch(data []byte) ([]PathRule, error) {
+	var ops []jsonPatchOperation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, &PathError{Msg: "invalid JSON Patch document: " + err.Error()}
+	}
+
+	rules := make([]PathRule, 0, len(ops))
+	for i, op := range ops {
+		opRules, err := compilePatchOperation(op)
+		if err != nil {
+			return nil, &PathError{Msg: fmt.Sprintf("patch operation %d (%s %s): %s", i, op.Op, op.Path, patchErrorMsg(err))}
+		}
+		rules = append(rules, opRules...)
+	}
+	return rules, nil
+}
+
+// compilePatchOperation 编译单条 JSON Patch 操作
+func compilePatchOperation(op jsonPatchOperation) ([]PathRule, error) {
+	tokens, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, &PathError{Msg: "operations on the document root are not supported"}
+	}
+	path, err := patchRulePath(op.Path, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace":
+		if len(op.Value) == 0 {
+			return nil, &PathError{Msg: "value is required"}
+		}
+		value, valueBytes, err := decodePatchValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		set := PathRule{Path: path, Action: ActionSet, Value: value, ValueBytes: valueBytes}
+		if op.Op == "replace" {
+			return []PathRule{set}, nil
+		}
+		if isArrayToken(tokens[len(tokens)-1]) {
+			return nil, &PathError{Msg: "inserting into arrays is not supported"}
+		}
+		add := PathRule{Path: path, Action: ActionAdd, Value: value, ValueBytes: valueBytes}
+		return []PathRule{set, add}, nil
+
+	case "remove":
+		return []PathRule{{Path: path, Action: ActionRemove}}, nil
+
+	case "move", "copy":
+		fromTokens, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Equal(fromTokens, tokens) {
+			return nil, nil
+		}
+		if len(fromTokens) != len(tokens) || !slices.Equal(fromTokens[:len(tokens)-1], tokens[:len(tokens)-1]) {
+			return nil, &PathError{Msg: "from and path must be fields of the same object"}
+		}
+		source, target := fromTokens[len(fromTokens)-1], tokens[len(tokens)-1]
+		if isArrayToken(source) || isArrayToken(target) {
+			return nil, &PathError{Msg: "moving or copying array elements is not supported"}
+		}
+		fromPath, err := patchRulePath(op.From, fromTokens)
+		if err != nil {
+			return nil, err
+		}
+		action := ActionRename
+		if op.Op == "copy" {
+			action = ActionCopy
+		}
+		return []PathRule{
+			{Path: path, Action: ActionRemove},
+			{Path: fromPath, Action: action, Value: target},
+		}, nil
+
+	case "test":
+		return nil, &PathError{Msg: "test operation is not supported"}
+
+	default:
+		return nil, &PathError{Msg: "unknown operation"}
+	}
+}
+
+// patchRulePath 返回指针对应的规则路径
+// 规则路径直接支持 JSON Pointer；指针首段形如 /regex/ 时改用点号语法，避免被当作正则段，
+// 无法直接写在点号语法中的字段名使用双引号字面量段
+func patchRulePath(pointer string, tokens []string) (string, error) {
+	if slices.Contains(tokens, "-") {
+		return "", &PathError{Msg: "appending to arrays is not supported"}
+	}
+	if isJSONPointer(pointer) {
+		return pointer, nil
+	}
+
+	var b strings.Builder
+	for _, token := range tokens {
+		if isArrayToken(token) {
+			b.WriteString("[" + token + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		if token == "" || token == "*" || strings.ContainsAny(token, `.[]"\`) ||
+			token[0] == '/' || strings.HasPrefix(token, prefixSegmentMarker) {
+			token = quoteSegment(token)
+		}
+		b.WriteString(token)
+	}
+	return b.String(), nil
+}
+
+// decodePatchValue 解码操作值，同时返回紧凑的 JSON 字节供流式输出
+func decodePatchValue(raw json.RawMessage) (any, []byte, error) {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, nil, &PathError{Msg: "invalid value: " + err.Error()}
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return nil, nil, &PathError{Msg: "invalid value: " + err.Error()}
+	}
+	return value, compact.Bytes(), nil
+}
+
+// patchErrorMsg 去掉内层 PathError 的前缀，避免错误信息重复
+func patchErrorMsg(err error) string {
+	if pathErr, ok := err.(*PathError); ok {
+		return pathErr.Msg
+	}
+	return err.Error()
+}
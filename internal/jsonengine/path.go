@@ -1,6 +1,7 @@
 package jsonengine
 
 import (
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -12,14 +13,31 @@ const (
 	SegField    SegmentType = iota // 具体字段名
 	SegWildcard                    // * 任意键
 	SegArrayAll                    // [*] 数组全部元素
-	SegArrayIdx                    // [n] 数组具体索引
+	SegArrayIdx                    // [n] 数组具体索引（负数表示从末尾倒数，如 [-1]）
+	SegArraySlice                  // [start:end] 数组切片（左闭右开，边界可为负数或省略）
+	SegPattern                     // /regex/ 或 prefix:xxx 匹配一类对象键
 )
 
+// prefixSegmentMarker 前缀匹配段的标记
+const prefixSegmentMarker = "prefix:"
+
 // Segment 路径段
 type Segment struct {
 	Type  SegmentType
 	Value string // 字段名或索引值
 	Index int    // 仅 SegArrayIdx 时有效
+
+	// 仅 JSON Pointer 的数字段有效：同时按该对象键匹配（RFC 6901 中 /a/0 既可指数组元素也可指对象成员 "0"）
+	Key string
+
+	// 仅 SegArraySlice 时有效
+	Start   int  // 起始索引（含），省略时为 0
+	End     int  // 结束索引（不含）
+	OpenEnd bool // 省略结束索引，表示直到数组末尾
+
+	// 仅 SegPattern 时有效（二选一）
+	Prefix string         // 键前缀
+	Regex  *regexp.Regexp // 键正则
 }
 
 // PathRule 路径过滤规则
@@ -28,7 +46,27 @@ type PathRule struct {
 	Action     Action    `json:"action"`
 	Value      any       `json:"value,omitempty"`       // 简单值（string/int/bool）或复杂对象
 	ValueBytes []byte    `json:"valueBytes,omitempty"` // 预验证的JSON字节（流式友好，优先使用）
-	segments   []Segment // 解析缓存
+
+	// 仅 ActionSet/ActionAdd 时有效：由同一文档中其他字段计算值的表达式（语法见 valueExpr），设置后忽略 Value/ValueBytes
+	// 引用的字段按规则路径匹配，须在目标位置之前出现（add 在所在对象结束时写入，同一对象内的字段总能引用）；
+	// 引用缺失或无法计算时规则不生效
+	Expr string `json:"expr,omitempty"`
+
+	// 冲突解析优先级，数值越大越优先，默认 0（见 ConflictMode）
+	Priority int `json:"priority,omitempty"`
+
+	// 仅 ActionClamp 时有效：数值上下限，以及字段缺失时添加的默认值
+	// ActionTruncate 使用 Max 作为保留的最大字符数
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Default any      `json:"default,omitempty"`
+
+	// 路由选择器：仅对匹配的请求路径、方法和模型生效，为空时适用于所有请求（见 RouteMatches、ModelMatches，由调用方筛选）
+	Paths   []string `json:"paths,omitempty"`
+	Methods []string `json:"methods,omitempty"`
+	Models  []string `json:"models,omitempty"`
+
+	segments []Segment // 解析缓存
 }
 
 // RuleAction AC 自动机输出
@@ -37,15 +75,32 @@ type RuleAction struct {
 	Action     Action
 	Value      any
 	ValueBytes []byte // 预验证的JSON字节（优先使用）
+	Priority   int    // 冲突解析优先级
+
+	Transform func(raw []byte) []byte // Mask/Transform/Clamp 的原值转换函数（输入输出均为 JSON）
+	truncate  *truncateSpec           // Truncate 的配置，单独生效时流式截断，不经过 Transform
+	strip     *stripSpec              // StripBase64 的配置，单独生效时流式替换，不经过 Transform
+	expr      *valueExpr              // Set/Add 的值表达式，为 nil 时使用 ValueBytes/Value
+}
+
+// addsWhenMissing 检查输出是否需要在字段缺失时添加值（add，或带 default 的 clamp）
+func (a RuleAction) addsWhenMissing() bool {
+	return a.Action == ActionAdd || (a.Action == ActionClamp && len(a.ValueBytes) > 0)
 }
 
 // ParsePath 解析路径字符串为段列表
 // 语法: segment.segment...
-// segment: fieldName | * | [*] | [n]
+// segment: fieldName | * | [*] | [n] | [-n] | [start:end] | /regex/ | prefix:xxx | "literal"
+// 双引号段或含反斜杠转义（如 a\.b）的段按字面量字段名匹配，可用于包含 . [ ] 或 * 的键
+// 以 / 开头且不是正则段的路径按 RFC 6901 JSON Pointer 解析（如 /messages/0/content），
+// 可用于包含 . 或 [] 的字段名
 func ParsePath(path string) ([]Segment, error) {
 	if path == "" {
 		return nil, nil
 	}
+	if isJSONPointer(path) {
+		return parsePointerPath(path)
+	}
 
 	var segments []Segment
 	parts := splitPath(path)
@@ -61,46 +116,181 @@ func ParsePath(path string) ([]Segment, error) {
 	return segments, nil
 }
 
-// splitPath 按 . 分割路径，但保留 [] 内的内容
+// isJSONPointer 检查路径是否为 JSON Pointer
+// 以 / 开头的路径，若首段是以 . [ 或结尾结束的非空 /regex/，仍按正则段处理
+func isJSONPointer(path string) bool {
+	if path == "" || path[0] != '/' {
+		return false
+	}
+	end := findRegexEnd(path, 1)
+	if end <= 1 {
+		return true
+	}
+	return end+1 < len(path) && path[end+1] != '.' && path[end+1] != '['
+}
+
+// parsePointerPath 将 JSON Pointer 解析为段列表
+// 纯数字的段匹配同一索引的数组元素或同名对象键，其余段按字面量字段名匹配（不支持通配符和模式）
+func parsePointerPath(pointer string) ([]Segment, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	segments := make([]Segment, 0, len(tokens))
+	for _, token := range tokens {
+		if isArrayToken(token) {
+			idx, _ := strconv.Atoi(token)
+			segments = append(segments, Segment{Type: SegArrayIdx, Value: "[" + token + "]", Index: idx, Key: token})
+			continue
+		}
+		segments = append(segments, Segment{Type: SegField, Value: token})
+	}
+	return segments, nil
+}
+
+// parseJSONPointer 解析 RFC 6901 JSON Pointer，返回反转义后的段列表
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, &PathError{Msg: "JSON pointer must start with /: " + pointer}
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// isArrayToken 检查指针段是否为数组索引（RFC 6901：0 或不以 0 开头的十进制数）
+func isArrayToken(token string) bool {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return false
+	}
+	_, err := strconv.ParseUint(token, 10, 31)
+	return err == nil
+}
+
+// splitPath 按 . 分割路径，但保留 []、/regex/ 和 "literal" 内的内容
+// 含反斜杠转义的段会被改写为双引号形式，交由 parseSegment 按字面量处理
 func splitPath(path string) []string {
 	var parts []string
 	var current strings.Builder
 	inBracket := false
+	literal := false // 当前段包含转义字符
+
+	flush := func() {
+		if current.Len() > 0 || literal {
+			part := current.String()
+			if literal {
+				part = quoteSegment(part)
+			}
+			parts = append(parts, part)
+		}
+		current.Reset()
+		literal = false
+	}
 
 	for i := 0; i < len(path); i++ {
 		c := path[i]
+
+		// 段首的 / 开始一个正则段，原样读取到未转义的结束 /
+		// 段首的 " 开始一个字面量段，原样读取到未转义的结束 "
+		if (c == '/' || c == '"') && current.Len() == 0 && !literal && !inBracket {
+			end := findRegexEnd(path, i+1)
+			if c == '"' {
+				end = findQuoteEnd(path, i+1)
+			}
+			if end < 0 {
+				current.WriteString(path[i:])
+				break
+			}
+			parts = append(parts, path[i:end+1])
+			i = end
+			continue
+		}
+
 		switch c {
-		case '[':
-			if current.Len() > 0 {
-				parts = append(parts, current.String())
-				current.Reset()
+		case '\\':
+			if inBracket || i+1 >= len(path) {
+				current.WriteByte(c)
+				continue
 			}
+			i++
+			current.WriteByte(path[i])
+			literal = true
+		case '[':
+			flush()
 			inBracket = true
 			current.WriteByte(c)
 		case ']':
 			current.WriteByte(c)
 			inBracket = false
-			parts = append(parts, current.String())
-			current.Reset()
+			flush()
 		case '.':
 			if inBracket {
 				current.WriteByte(c)
 			} else {
-				if current.Len() > 0 {
-					parts = append(parts, current.String())
-					current.Reset()
-				}
+				flush()
 			}
 		default:
 			current.WriteByte(c)
 		}
 	}
 
-	if current.Len() > 0 {
-		parts = append(parts, current.String())
+	flush()
+	return parts
+}
+
+// findQuoteEnd 返回从 start 开始第一个未转义 " 的位置，不存在时返回 -1
+func findQuoteEnd(path string, start int) int {
+	for i := start; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
 	}
+	return -1
+}
 
-	return parts
+// quoteSegment 将字段名转为双引号字面量段
+func quoteSegment(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
+// unquoteSegment 解析双引号字面量段，\x 表示字符 x
+func unquoteSegment(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != '"' || findQuoteEnd(s, 1) != len(s)-1 {
+		return "", &PathError{Msg: "unterminated quoted segment: " + s}
+	}
+	inner := s[1 : len(s)-1]
+	if !strings.Contains(inner, `\`) {
+		return inner, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) {
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+	return b.String(), nil
+}
+
+// findRegexEnd 返回从 start 开始第一个未转义 / 的位置，不存在时返回 -1
+func findRegexEnd(path string, start int) int {
+	for i := start; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+		case '/':
+			return i
+		}
+	}
+	return -1
 }
 
 // parseSegment 解析单个段
@@ -109,18 +299,50 @@ func parseSegment(s string) (Segment, error) {
 		return Segment{}, &PathError{Msg: "empty segment"}
 	}
 
+	// 字面量段 "name"
+	if s[0] == '"' {
+		name, err := unquoteSegment(s)
+		if err != nil {
+			return Segment{}, err
+		}
+		return Segment{Type: SegField, Value: name}, nil
+	}
+
 	// 通配符
 	if s == "*" {
 		return Segment{Type: SegWildcard, Value: "*"}, nil
 	}
 
-	// 数组索引 [*] 或 [n]
+	// 正则段 /regex/
+	if s[0] == '/' {
+		if len(s) < 3 || s[len(s)-1] != '/' {
+			return Segment{}, &PathError{Msg: "invalid regex segment: " + s}
+		}
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return Segment{}, &PathError{Msg: "invalid regex segment: " + err.Error()}
+		}
+		return Segment{Type: SegPattern, Value: s, Regex: re}, nil
+	}
+
+	// 前缀段 prefix:xxx
+	if prefix, ok := strings.CutPrefix(s, prefixSegmentMarker); ok {
+		if prefix == "" {
+			return Segment{}, &PathError{Msg: "empty prefix segment"}
+		}
+		return Segment{Type: SegPattern, Value: s, Prefix: prefix}, nil
+	}
+
+	// 数组索引 [*]、[n] 或切片 [start:end]
 	if len(s) >= 3 && s[0] == '[' && s[len(s)-1] == ']' {
 		inner := s[1 : len(s)-1]
 		if inner == "*" {
 			return Segment{Type: SegArrayAll, Value: "[*]"}, nil
 		}
-		// 解析数字索引
+		if strings.Contains(inner, ":") {
+			return parseSliceSegment(s, inner)
+		}
+		// 解析数字索引（负数从末尾倒数）
 		idx, err := strconv.Atoi(inner)
 		if err != nil {
 			return Segment{}, &PathError{Msg: "invalid array index: " + inner}
@@ -132,17 +354,108 @@ func parseSegment(s string) (Segment, error) {
 	return Segment{Type: SegField, Value: s}, nil
 }
 
-// Match 检查段是否匹配给定的 key 或 index
+// parseSliceSegment 解析切片段 [start:end]
+func parseSliceSegment(s, inner string) (Segment, error) {
+	startStr, endStr, _ := strings.Cut(inner, ":")
+	seg := Segment{Type: SegArraySlice, Value: s}
+
+	if startStr != "" {
+		start, err := strconv.Atoi(startStr)
+		if err != nil {
+			return Segment{}, &PathError{Msg: "invalid slice start: " + startStr}
+		}
+		seg.Start = start
+	}
+
+	if endStr == "" {
+		seg.OpenEnd = true
+	} else {
+		end, err := strconv.Atoi(endStr)
+		if err != nil {
+			return Segment{}, &PathError{Msg: "invalid slice end: " + endStr}
+		}
+		seg.End = end
+	}
+
+	return seg, nil
+}
+
+// NeedsArrayLen 检查段是否需要知道数组长度才能匹配（负索引或负切片边界）
+func (seg Segment) NeedsArrayLen() bool {
+	switch seg.Type {
+	case SegArrayIdx:
+		return seg.Index < 0
+	case SegArraySlice:
+		return seg.Start < 0 || (!seg.OpenEnd && seg.End < 0)
+	default:
+		return false
+	}
+}
+
+// MatchIndex 检查数组段是否匹配给定索引
+// arrayLen < 0 表示数组长度未知，此时负索引和负边界不匹配
+func (seg Segment) MatchIndex(arrayIdx, arrayLen int) bool {
+	switch seg.Type {
+	case SegArrayAll:
+		return true
+	case SegArrayIdx:
+		if seg.Index >= 0 {
+			return seg.Index == arrayIdx
+		}
+		return arrayLen >= 0 && arrayLen+seg.Index == arrayIdx
+	case SegArraySlice:
+		start := seg.Start
+		if start < 0 {
+			if arrayLen < 0 {
+				return false
+			}
+			start += arrayLen
+		}
+		if arrayIdx < start {
+			return false
+		}
+		if seg.OpenEnd {
+			return true
+		}
+		end := seg.End
+		if end < 0 {
+			if arrayLen < 0 {
+				return false
+			}
+			end += arrayLen
+		}
+		return arrayIdx < end
+	default:
+		return false
+	}
+}
+
+// MatchKey 检查模式段是否匹配对象键
+func (seg Segment) MatchKey(key string) bool {
+	if seg.Regex != nil {
+		return seg.Regex.MatchString(key)
+	}
+	return seg.Prefix != "" && strings.HasPrefix(key, seg.Prefix)
+}
+
+// Match 检查段是否匹配给定的 key 或 index（数组长度未知）
 func (seg Segment) Match(key string, isArray bool, arrayIdx int) bool {
 	switch seg.Type {
 	case SegField:
 		return !isArray && seg.Value == key
+	case SegPattern:
+		return !isArray && seg.MatchKey(key)
 	case SegWildcard:
 		return !isArray // * 匹配任意对象键
 	case SegArrayAll:
 		return isArray // [*] 匹配任意数组索引
 	case SegArrayIdx:
-		return isArray && seg.Index == arrayIdx
+		if !isArray {
+			return seg.Key != "" && seg.Key == key
+		}
+		return seg.MatchIndex(arrayIdx, -1)
+	case SegArraySlice:
+		return isArray && seg.MatchIndex(arrayIdx, -1)
 	default:
 		return false
 	}
@@ -2,8 +2,14 @@ package jsonengine
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestPathParsing(t *testing.T) {
@@ -49,6 +55,91 @@ func TestPathParsing(t *testing.T) {
 				{Type: SegField, Value: "c"},
 			},
 		},
+		{
+			path: "messages.[-1].content",
+			expected: []Segment{
+				{Type: SegField, Value: "messages"},
+				{Type: SegArrayIdx, Value: "[-1]", Index: -1},
+				{Type: SegField, Value: "content"},
+			},
+		},
+		{
+			path: "a.[0:3]",
+			expected: []Segment{
+				{Type: SegField, Value: "a"},
+				{Type: SegArraySlice, Value: "[0:3]", Start: 0, End: 3},
+			},
+		},
+		{
+			path: "meta./^x-.*/",
+			expected: []Segment{
+				{Type: SegField, Value: "meta"},
+				{Type: SegPattern, Value: "/^x-.*/"},
+			},
+		},
+		{
+			path: "prefix:meta_.id",
+			expected: []Segment{
+				{Type: SegPattern, Value: "prefix:meta_"},
+				{Type: SegField, Value: "id"},
+			},
+		},
+		{
+			path: "/messages/0/content",
+			expected: []Segment{
+				{Type: SegField, Value: "messages"},
+				{Type: SegArrayIdx, Value: "[0]", Index: 0, Key: "0"},
+				{Type: SegField, Value: "content"},
+			},
+		},
+		{
+			path: "/meta/a.b[1]/x~1y~0z/*",
+			expected: []Segment{
+				{Type: SegField, Value: "meta"},
+				{Type: SegField, Value: "a.b[1]"},
+				{Type: SegField, Value: "x/y~z"},
+				{Type: SegField, Value: "*"},
+			},
+		},
+		{
+			path: "/v1.0/model",
+			expected: []Segment{
+				{Type: SegField, Value: "v1.0"},
+				{Type: SegField, Value: "model"},
+			},
+		},
+		{
+			path: "/^x-/.id",
+			expected: []Segment{
+				{Type: SegPattern, Value: "/^x-/"},
+				{Type: SegField, Value: "id"},
+			},
+		},
+		{
+			path: `meta."a.b".c`,
+			expected: []Segment{
+				{Type: SegField, Value: "meta"},
+				{Type: SegField, Value: "a.b"},
+				{Type: SegField, Value: "c"},
+			},
+		},
+		{
+			path: `"foo[0]"[1]."*"."q\"x"`,
+			expected: []Segment{
+				{Type: SegField, Value: "foo[0]"},
+				{Type: SegArrayIdx, Value: "[1]", Index: 1},
+				{Type: SegField, Value: "*"},
+				{Type: SegField, Value: `q"x`},
+			},
+		},
+		{
+			path: `a\.b.foo\[0\].\*`,
+			expected: []Segment{
+				{Type: SegField, Value: "a.b"},
+				{Type: SegField, Value: "foo[0]"},
+				{Type: SegField, Value: "*"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -67,6 +158,9 @@ func TestPathParsing(t *testing.T) {
 				if seg.Value != tt.expected[i].Value {
 					t.Errorf("segment[%d].Value = %q, want %q", i, seg.Value, tt.expected[i].Value)
 				}
+				if seg.Index != tt.expected[i].Index || seg.Start != tt.expected[i].Start || seg.End != tt.expected[i].End || seg.OpenEnd != tt.expected[i].OpenEnd {
+					t.Errorf("segment[%d] = %+v, want %+v", i, seg, tt.expected[i])
+				}
 			}
 		})
 	}
@@ -338,6 +432,88 @@ func TestPathEngineArrayIndex(t *testing.T) {
 	}
 }
 
+// TestPathEngineArrayRemove 测试删除数组元素时逗号的修正
+func TestPathEngineArrayRemove(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "remove first element",
+			rules:  []PathRule{{Path: "items.[0]", Action: ActionRemove}},
+			input:  `{"items":["a","b","c"]}`,
+			expect: `{"items":["b","c"]}`,
+		},
+		{
+			name:   "remove middle element",
+			rules:  []PathRule{{Path: "items.[1]", Action: ActionRemove}},
+			input:  `{"items":["a","b","c"]}`,
+			expect: `{"items":["a","c"]}`,
+		},
+		{
+			name:   "remove last element",
+			rules:  []PathRule{{Path: "items.[2]", Action: ActionRemove}},
+			input:  `{"items":["a","b","c"]}`,
+			expect: `{"items":["a","b"]}`,
+		},
+		{
+			name:   "remove middle object element",
+			rules:  []PathRule{{Path: "items.[1]", Action: ActionRemove}},
+			input:  `{"items":[{"id":1},{"id":2},{"id":3}],"n":3}`,
+			expect: `{"items":[{"id":1},{"id":3}],"n":3}`,
+		},
+		{
+			name:   "remove last number element",
+			rules:  []PathRule{{Path: "items.[2]", Action: ActionRemove}},
+			input:  `{"items":[1,2,3]}`,
+			expect: `{"items":[1,2]}`,
+		},
+		{
+			name: "remove first and last element",
+			rules: []PathRule{
+				{Path: "items.[0]", Action: ActionRemove},
+				{Path: "items.[2]", Action: ActionRemove},
+			},
+			input:  `{"items":[1,[2],3]}`,
+			expect: `{"items":[[2]]}`,
+		},
+		{
+			name:   "remove all elements",
+			rules:  []PathRule{{Path: "items.[*]", Action: ActionRemove}},
+			input:  `{"items":[1,{"a":2},"3"]}`,
+			expect: `{"items":[]}`,
+		},
+		{
+			name:   "remove with whitespace",
+			rules:  []PathRule{{Path: "items.[1]", Action: ActionRemove}},
+			input:  `{"items": [1, 2, 3]}`,
+			expect: `{"items": [1, 3]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules)
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			err = engine.Process(strings.NewReader(tt.input), &out)
+			if err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+
+			result := out.String()
+			if result != tt.expect {
+				t.Errorf("got %q, want %q", result, tt.expect)
+			}
+		})
+	}
+}
+
 func TestPathEngineRealWorld(t *testing.T) {
 	// 真实场景：Gemini thoughtSignature 过滤
 	rules := []PathRule{
@@ -388,6 +564,45 @@ func BenchmarkPathEngine(b *testing.B) {
 	}
 }
 
+func BenchmarkPathEngineProcessBytes(b *testing.B) {
+	engine, err := NewPathEngine([]PathRule{
+		{Path: "metadata", Action: ActionRemove},
+		{Path: "stream", Action: ActionAdd, Value: false},
+	})
+	if err != nil {
+		b.Fatalf("NewPathEngine error: %v", err)
+	}
+
+	// 约 1MB 的请求体
+	content := strings.Repeat("x", 1024)
+	var sb strings.Builder
+	sb.WriteString(`{"model":"m","metadata":{"id":1},"messages":[`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`{"role":"user","content":"` + content + `"}`)
+	}
+	sb.WriteString(`]}`)
+	input := []byte(sb.String())
+
+	b.Run("reader", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var out bytes.Buffer
+			engine.Process(bytes.NewReader(input), &out)
+		}
+	})
+	b.Run("bytes", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			engine.ProcessBytes(input)
+		}
+	})
+}
+
 func BenchmarkSIMDScan(b *testing.B) {
 	// 512KB 测试数据
 	data := make([]byte, 512*1024)
@@ -651,3 +866,1844 @@ func TestPathEngineMixed(t *testing.T) {
 		})
 	}
 }
+
+// TestPathEngineKeep 测试白名单（keep）模式
+func TestPathEngineKeep(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		rules    []PathRule
+		expected string
+	}{
+		{
+			name:  "keep_top_level",
+			input: `{"a":1,"b":{"x":1},"c":[1,2]}`,
+			rules: []PathRule{
+				{Path: "b", Action: ActionKeep},
+			},
+			expected: `{"b":{"x":1}}`,
+		},
+		{
+			name:  "keep_nested_path",
+			input: `{"a":1,"b":{"x":1,"y":2},"c":3}`,
+			rules: []PathRule{
+				{Path: "b.y", Action: ActionKeep},
+			},
+			expected: `{"b":{"y":2}}`,
+		},
+		{
+			name:  "keep_gemini_response",
+			input: `{"candidates":[{"content":{"parts":[{"text":"hi"}]},"finishReason":"STOP","safetyRatings":[]}],"usageMetadata":{"promptTokenCount":10},"modelVersion":"x"}`,
+			rules: []PathRule{
+				{Path: "candidates.[*].content", Action: ActionKeep},
+				{Path: "usageMetadata", Action: ActionKeep},
+			},
+			expected: `{"candidates":[{"content":{"parts":[{"text":"hi"}]}}],"usageMetadata":{"promptTokenCount":10}}`,
+		},
+		{
+			name:  "kept_subtree_not_filtered_by_shallow_match",
+			input: `{"meta":{"meta":1,"other":2},"drop":true}`,
+			rules: []PathRule{
+				{Path: "meta", Action: ActionKeep},
+			},
+			expected: `{"meta":{"meta":1,"other":2}}`,
+		},
+		{
+			name:  "nested_key_with_same_name_not_kept",
+			input: `{"a":{"b":1,"id":2},"id":3}`,
+			rules: []PathRule{
+				{Path: "a.b", Action: ActionKeep},
+				{Path: "id", Action: ActionKeep},
+			},
+			expected: `{"a":{"b":1},"id":3}`,
+		},
+		{
+			name:  "keep_with_remove_inside",
+			input: `{"a":{"b":1,"secret":2},"c":3}`,
+			rules: []PathRule{
+				{Path: "a", Action: ActionKeep},
+				{Path: "a.secret", Action: ActionRemove},
+			},
+			expected: `{"a":{"b":1}}`,
+		},
+		{
+			name:  "keep_array_index",
+			input: `{"items":[1,2,3],"x":1}`,
+			rules: []PathRule{
+				{Path: "items.[0]", Action: ActionKeep},
+			},
+			expected: `{"items":[1]}`,
+		},
+		{
+			name:  "keep_field_of_array_index",
+			input: `{"items":[{"a":1,"b":2},{"a":3}]}`,
+			rules: []PathRule{
+				{Path: "items.[0].a", Action: ActionKeep},
+			},
+			expected: `{"items":[{"a":1}]}`,
+		},
+		{
+			name:  "keep_later_array_index",
+			input: `{"items":[ {"a":1}, {"a":2}, {"a":3} ]}`,
+			rules: []PathRule{
+				{Path: "items.[1]", Action: ActionKeep},
+			},
+			expected: `{"items":[ {"a":2} ]}`,
+		},
+		{
+			name:  "keep_field_of_every_element",
+			input: `{"items":[{"a":1,"b":2},{"b":3}]}`,
+			rules: []PathRule{
+				{Path: "items.[*].a", Action: ActionKeep},
+			},
+			expected: `{"items":[{"a":1},{}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules)
+			if err != nil {
+				t.Fatalf("NewPathEngine failed: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("Process failed: %v", err)
+			}
+
+			got := out.String()
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestPathEngineNegativeIndexAndSlice 测试负索引和切片
+func TestPathEngineNegativeIndexAndSlice(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "last element",
+			rules:  []PathRule{{Path: "messages.[-1].content", Action: ActionSet, ValueBytes: []byte(`"x"`)}},
+			input:  `{"messages":[{"role":"system","content":"a"},{"role":"user","content":"b"}]}`,
+			expect: `{"messages":[{"role":"system","content":"a"},{"role":"user","content":"x"}]}`,
+		},
+		{
+			name:   "remove second to last",
+			rules:  []PathRule{{Path: "items.[-2]", Action: ActionRemove}},
+			input:  `{"items":[1,2,3,4]}`,
+			expect: `{"items":[1,2,4]}`,
+		},
+		{
+			name:   "negative index with nested arrays",
+			rules:  []PathRule{{Path: "b.[-1]", Action: ActionRemove}},
+			input:  `{"a":[[1,2],"[x,y]"],"b":[1,[2,3],3]}`,
+			expect: `{"a":[[1,2],"[x,y]"],"b":[1,[2,3]]}`,
+		},
+		{
+			name:   "negative index out of range",
+			rules:  []PathRule{{Path: "items.[-5]", Action: ActionRemove}},
+			input:  `{"items":[1,2]}`,
+			expect: `{"items":[1,2]}`,
+		},
+		{
+			name:   "slice",
+			rules:  []PathRule{{Path: "items.[0:2]", Action: ActionRemove}},
+			input:  `{"items":[1,2,3,4]}`,
+			expect: `{"items":[3,4]}`,
+		},
+		{
+			name:   "open-ended slice",
+			rules:  []PathRule{{Path: "items.[2:]", Action: ActionSet, ValueBytes: []byte(`0`)}},
+			input:  `{"items":[1,2,3,4]}`,
+			expect: `{"items":[1,2,0,0]}`,
+		},
+		{
+			name:   "negative slice",
+			rules:  []PathRule{{Path: "items.[-2:]", Action: ActionRemove}},
+			input:  `{"items":[1,2,3,4]}`,
+			expect: `{"items":[1,2]}`,
+		},
+		{
+			name:   "slice with negative end",
+			rules:  []PathRule{{Path: "items.[1:-1].v", Action: ActionRemove}},
+			input:  `{"items":[{"v":1},{"v":2},{"v":3}]}`,
+			expect: `{"items":[{"v":1},{},{"v":3}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(7))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			err = engine.Process(strings.NewReader(tt.input), &out)
+			if err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+
+			result := out.String()
+			if result != tt.expect {
+				t.Errorf("got %q, want %q", result, tt.expect)
+			}
+		})
+	}
+}
+
+func TestPathEngineKeyPattern(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "regex remove",
+			rules:  []PathRule{{Path: "/^x_.*/", Action: ActionRemove}},
+			input:  `{"model":"m","x_trace":1,"x_debug":{"a":1},"max_x":2}`,
+			expect: `{"model":"m","max_x":2}`,
+		},
+		{
+			name:   "prefix remove nested",
+			rules:  []PathRule{{Path: "metadata.prefix:internal_", Action: ActionRemove}},
+			input:  `{"metadata":{"user":"u","internal_id":1,"internal_tags":["a"]}}`,
+			expect: `{"metadata":{"user":"u"}}`,
+		},
+		{
+			name:   "regex with dot and escaped slash",
+			rules:  []PathRule{{Path: "/^a\\/b\\..*/", Action: ActionSet, ValueBytes: []byte(`0`)}},
+			input:  `{"a/b.c":1,"a/bc":2}`,
+			expect: `{"a/b.c":0,"a/bc":2}`,
+		},
+		{
+			name: "exact takes precedence over pattern",
+			rules: []PathRule{
+				{Path: "prefix:x_", Action: ActionRemove},
+				{Path: "x_keep", Action: ActionSet, ValueBytes: []byte(`true`)},
+			},
+			input:  `{"x_a":1,"x_keep":false}`,
+			expect: `{"x_keep":true}`,
+		},
+		{
+			name: "pattern takes precedence over wildcard",
+			rules: []PathRule{
+				{Path: "*", Action: ActionSet, ValueBytes: []byte(`0`)},
+				{Path: "prefix:x_", Action: ActionRemove},
+			},
+			input:  `{"a":1,"x_b":2}`,
+			expect: `{"a":0}`,
+		},
+		{
+			name:   "pattern under array",
+			rules:  []PathRule{{Path: "messages[*]./^(name|x_.+)$/", Action: ActionRemove}},
+			input:  `{"messages":[{"role":"user","name":"n","x_meta":1}]}`,
+			expect: `{"messages":[{"role":"user"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(7))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			err = engine.Process(strings.NewReader(tt.input), &out)
+			if err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+
+			result := out.String()
+			if result != tt.expect {
+				t.Errorf("got %q, want %q", result, tt.expect)
+			}
+		})
+	}
+
+	if _, err := ParsePath("a./unterminated"); err == nil {
+		t.Error("expected error for unterminated regex segment")
+	}
+	if _, err := ParsePath("a./[/"); err == nil {
+		t.Error("expected error for invalid regex segment")
+	}
+}
+
+func TestPathEngineMask(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "partial by default",
+			rules:  []PathRule{{Path: "api_key", Action: ActionMask}},
+			input:  `{"api_key":"sk-abcdefghij1234","model":"m"}`,
+			expect: `{"api_key":"sk-****1234","model":"m"}`,
+		},
+		{
+			name:   "short value fully masked",
+			rules:  []PathRule{{Path: "pin", Action: ActionMask, Value: MaskPartial}},
+			input:  `{"pin":"1234"}`,
+			expect: `{"pin":"****"}`,
+		},
+		{
+			name:   "redact nested",
+			rules:  []PathRule{{Path: "user.email", Action: ActionMask, Value: MaskRedact}},
+			input:  `{"user":{"email":"a@b.c","name":"n"}}`,
+			expect: `{"user":{"email":"[REDACTED]","name":"n"}}`,
+		},
+		{
+			name:   "hash",
+			rules:  []PathRule{{Path: "token", Action: ActionMask, Value: MaskHash}},
+			input:  `{"token":"secret"}`,
+			expect: `{"token":"sha256:2bb80d537b1da3e3"}`,
+		},
+		{
+			name:   "array elements with escapes",
+			rules:  []PathRule{{Path: "keys[*]", Action: ActionMask, Value: MaskRedact}},
+			input:  `{"keys":["a\"b,c]",1,"x"],"n":1}`,
+			expect: `{"keys":["[REDACTED]",1,"[REDACTED]"],"n":1}`,
+		},
+		{
+			name:   "non-string values untouched",
+			rules:  []PathRule{{Path: "*", Action: ActionMask, Value: MaskRedact}},
+			input:  `{"a":1,"b":{"c":"d"},"e":[1,2],"f":true}`,
+			expect: `{"a":1,"b":{"c":"d"},"e":[1,2],"f":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(5))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			err = engine.Process(strings.NewReader(tt.input), &out)
+			if err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+
+			result := out.String()
+			if result != tt.expect {
+				t.Errorf("got %q, want %q", result, tt.expect)
+			}
+		})
+	}
+
+	if _, err := NewPathEngine([]PathRule{{Path: "a", Action: ActionMask, Value: "rot13"}}); err == nil {
+		t.Error("expected error for invalid mask mode")
+	}
+}
+
+func TestParseMaskRules(t *testing.T) {
+	rules, err := ParseMaskRules(" messages[*].content, api_key=hash,,user.email=partial ", MaskRedact)
+	if err != nil {
+		t.Fatalf("ParseMaskRules error: %v", err)
+	}
+	want := []PathRule{
+		{Path: "messages[*].content", Action: ActionMask, Value: MaskRedact},
+		{Path: "api_key", Action: ActionMask, Value: MaskHash},
+		{Path: "user.email", Action: ActionMask, Value: MaskPartial},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %d rules, want %d", len(rules), len(want))
+	}
+	for i := range want {
+		if rules[i].Path != want[i].Path || rules[i].Action != want[i].Action || rules[i].Value != want[i].Value {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+
+	engine, err := NewPathEngine(rules)
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+	got, err := engine.ProcessBytes([]byte(`{"api_key":"secret","messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("ProcessBytes error: %v", err)
+	}
+	if expect := `{"api_key":"sha256:2bb80d537b1da3e3","messages":[{"role":"user","content":"[REDACTED]"}]}`; string(got) != expect {
+		t.Errorf("got %s, want %s", got, expect)
+	}
+
+	// A suffix that is not a mask mode is part of the path
+	if rules, err := ParseMaskRules(`"a=b"`, MaskRedact); err != nil || len(rules) != 1 || rules[0].Path != `"a=b"` {
+		t.Errorf("quoted path with '=': rules %+v, err %v", rules, err)
+	}
+	for _, spec := range []string{"=redact", "a./[/", "a"} {
+		mode := MaskRedact
+		if spec == "a" {
+			mode = "rot13"
+		}
+		if _, err := ParseMaskRules(spec, mode); err == nil {
+			t.Errorf("expected error for %q with default mode %q", spec, mode)
+		}
+	}
+}
+
+func TestPathEngineTransform(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "strip prefix",
+			rules:  []PathRule{{Path: "model", Action: ActionTransform, Value: map[string]any{"op": "strip_prefix", "prefix": "models/"}}},
+			input:  `{"model":"models/gemini-1.5-pro","n":1}`,
+			expect: `{"model":"gemini-1.5-pro","n":1}`,
+		},
+		{
+			name:   "regex replace with group",
+			rules:  []PathRule{{Path: "model", Action: ActionTransform, Value: `{"op":"replace","pattern":"^(gpt-4o)-\\d{4}-\\d{2}-\\d{2}$","replacement":"$1"}`}},
+			input:  `{"model":"gpt-4o-2024-08-06"}`,
+			expect: `{"model":"gpt-4o"}`,
+		},
+		{
+			name:   "lower in array",
+			rules:  []PathRule{{Path: "messages[*].role", Action: ActionTransform, Value: TransformLower}},
+			input:  `{"messages":[{"role":"USER"},{"role":"Assistant"}]}`,
+			expect: `{"messages":[{"role":"user"},{"role":"assistant"}]}`,
+		},
+		{
+			name:   "trim and upper",
+			rules:  []PathRule{{Path: "a", Action: ActionTransform, Value: TransformTrim}, {Path: "b", Action: ActionTransform, Value: StringTransform{Op: TransformUpper}}},
+			input:  `{"a":"  x \n","b":"y"}`,
+			expect: `{"a":"x","b":"Y"}`,
+		},
+		{
+			name:   "non-string untouched",
+			rules:  []PathRule{{Path: "n", Action: ActionTransform, Value: TransformUpper}},
+			input:  `{"n":12,"m":"x"}`,
+			expect: `{"n":12,"m":"x"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(6))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			err = engine.Process(strings.NewReader(tt.input), &out)
+			if err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+
+			result := out.String()
+			if result != tt.expect {
+				t.Errorf("got %q, want %q", result, tt.expect)
+			}
+		})
+	}
+
+	invalid := []any{"reverse", map[string]any{"op": "replace", "pattern": "("}, map[string]any{"op": "strip_prefix"}, 42}
+	for _, value := range invalid {
+		if _, err := NewPathEngine([]PathRule{{Path: "a", Action: ActionTransform, Value: value}}); err == nil {
+			t.Errorf("expected error for transform config %v", value)
+		}
+	}
+}
+
+func TestPathEngineClamp(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "clamp above max",
+			rules:  []PathRule{{Path: "max_tokens", Action: ActionClamp, Min: f(1), Max: f(4096)}},
+			input:  `{"max_tokens":100000,"model":"m"}`,
+			expect: `{"max_tokens":4096,"model":"m"}`,
+		},
+		{
+			name:   "clamp below min",
+			rules:  []PathRule{{Path: "temperature", Action: ActionClamp, Min: f(0.1), Max: f(1)}},
+			input:  `{"temperature":-1}`,
+			expect: `{"temperature":0.1}`,
+		},
+		{
+			name:   "value in range untouched",
+			rules:  []PathRule{{Path: "max_tokens", Action: ActionClamp, Max: f(4096)}},
+			input:  `{"max_tokens":1024}`,
+			expect: `{"max_tokens":1024}`,
+		},
+		{
+			name:   "default when absent",
+			rules:  []PathRule{{Path: "temperature", Action: ActionClamp, Default: 0.7}},
+			input:  `{"model":"m"}`,
+			expect: `{"model":"m","temperature":0.7}`,
+		},
+		{
+			name:   "default not applied when present",
+			rules:  []PathRule{{Path: "temperature", Action: ActionClamp, Max: f(1), Default: 0.7}},
+			input:  `{"temperature":1.5,"model":"m"}`,
+			expect: `{"temperature":1,"model":"m"}`,
+		},
+		{
+			name:   "nested and non-numeric",
+			rules:  []PathRule{{Path: "generationConfig.maxOutputTokens", Action: ActionClamp, Max: f(8192)}},
+			input:  `{"generationConfig":{"maxOutputTokens":"lots"},"x":{"maxOutputTokens":99999}}`,
+			expect: `{"generationConfig":{"maxOutputTokens":"lots"},"x":{"maxOutputTokens":99999}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(4))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			err = engine.Process(strings.NewReader(tt.input), &out)
+			if err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+
+			result := out.String()
+			if result != tt.expect {
+				t.Errorf("got %q, want %q", result, tt.expect)
+			}
+		})
+	}
+
+	invalid := []PathRule{
+		{Path: "a", Action: ActionClamp},
+		{Path: "a", Action: ActionClamp, Min: f(2), Max: f(1)},
+	}
+	for _, rule := range invalid {
+		if _, err := NewPathEngine([]PathRule{rule}); err == nil {
+			t.Errorf("expected error for clamp rule %+v", rule)
+		}
+	}
+}
+
+func TestFromJSONPatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		patch  string
+		input  string
+		expect string
+	}{
+		{
+			name:   "replace and remove",
+			patch:  `[{"op":"replace","path":"/model","value":"gpt-4o"},{"op":"remove","path":"/user"}]`,
+			input:  `{"model":"gpt-4","user":"u1","stream":true}`,
+			expect: `{"model":"gpt-4o","stream":true}`,
+		},
+		{
+			name:   "add replaces existing",
+			patch:  `[{"op":"add","path":"/stream","value":false}]`,
+			input:  `{"stream":true}`,
+			expect: `{"stream":false}`,
+		},
+		{
+			name:   "add missing nested",
+			patch:  `[{"op":"add","path":"/options/seed","value":{"n": 1}}]`,
+			input:  `{"options":{}}`,
+			expect: `{"options":{"seed":{"n":1}}}`,
+		},
+		{
+			name:   "array index and escaped pointer",
+			patch:  `[{"op":"replace","path":"/messages/0/role","value":"system"},{"op":"remove","path":"/meta/a~1b"}]`,
+			input:  `{"messages":[{"role":"user"},{"role":"user"}],"meta":{"a/b":1,"c":2}}`,
+			expect: `{"messages":[{"role":"system"},{"role":"user"}],"meta":{"c":2}}`,
+		},
+		{
+			name:   "move renames field",
+			patch:  `[{"op":"move","from":"/max_tokens","path":"/max_completion_tokens"}]`,
+			input:  `{"max_completion_tokens":1,"max_tokens":512,"model":"m"}`,
+			expect: `{"max_completion_tokens":512,"model":"m"}`,
+		},
+		{
+			name:   "copy duplicates field",
+			patch:  `[{"op":"copy","from":"/config/a","path":"/config/b"}]`,
+			input:  `{"config":{"a":{"x":[1,2]},"c":true}}`,
+			expect: `{"config":{"a":{"x":[1,2]},"b":{"x":[1,2]},"c":true}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := FromJSONPatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("FromJSONPatch error: %v", err)
+			}
+			engine, err := NewPathEngine(rules, WithChunkSize(5))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("got %q, want %q", out.String(), tt.expect)
+			}
+		})
+	}
+
+	invalid := []string{
+		`{"op":"add"}`,
+		`[{"op":"test","path":"/a","value":1}]`,
+		`[{"op":"add","path":"/items/-","value":1}]`,
+		`[{"op":"add","path":"/items/0","value":1}]`,
+		`[{"op":"move","from":"/a/x","path":"/b/x"}]`,
+		`[{"op":"replace","path":"","value":{}}]`,
+		`[{"op":"remove","path":"a"}]`,
+	}
+	for _, patch := range invalid {
+		if _, err := FromJSONPatch([]byte(patch)); err == nil {
+			t.Errorf("expected error for patch %s", patch)
+		}
+	}
+}
+
+func TestPathEngineJSONPointer(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "dotted key",
+			rules:  []PathRule{{Path: "/headers/x.api.key", Action: ActionRemove}},
+			input:  `{"headers":{"x.api.key":"s","x":{"api":{"key":"k"}}}}`,
+			expect: `{"headers":{"x":{"api":{"key":"k"}}}}`,
+		},
+		{
+			name:   "array index and escaped slash",
+			rules:  []PathRule{{Path: "/items/1/a~1b", Action: ActionSet, Value: 0}},
+			input:  `{"items":[{"a/b":1},{"a/b":2}]}`,
+			expect: `{"items":[{"a/b":1},{"a/b":0}]}`,
+		},
+		{
+			name:   "numeric token matches array element",
+			rules:  []PathRule{{Path: "/a/0", Action: ActionRemove}},
+			input:  `{"a":[1,2]}`,
+			expect: `{"a":[2]}`,
+		},
+		{
+			name:   "numeric token matches object member",
+			rules:  []PathRule{{Path: "/a/0", Action: ActionRemove}},
+			input:  `{"a":{"0":1,"1":2}}`,
+			expect: `{"a":{"1":2}}`,
+		},
+		{
+			name:   "numeric tokens across arrays and objects",
+			rules:  []PathRule{{Path: "/a/1/2", Action: ActionSet, Value: 0}},
+			input:  `{"a":[{"2":1},{"2":2,"1":[0,1,2]}],"b":{"1":{"2":3}}}`,
+			expect: `{"a":[{"2":1},{"2":0,"1":[0,1,2]}],"b":{"1":{"2":3}}}`,
+		},
+		{
+			name:   "numeric token adds missing object member",
+			rules:  []PathRule{{Path: "/a/0", Action: ActionAdd, Value: true}},
+			input:  `{"a":{"1":2}}`,
+			expect: `{"a":{"1":2,"0":true}}`,
+		},
+		{
+			name:   "literal star",
+			rules:  []PathRule{{Path: "/*", Action: ActionRemove}},
+			input:  `{"*":1,"a":2}`,
+			expect: `{"a":2}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules)
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("got %q, want %q", out.String(), tt.expect)
+			}
+		})
+	}
+}
+
+func TestPathEngineProcessWithResult(t *testing.T) {
+	engine, err := NewPathEngine([]PathRule{
+		{Path: "user", Action: ActionRemove},
+		{Path: "items[*].id", Action: ActionSet, Value: 0},
+		{Path: "stream", Action: ActionAdd, Value: false},
+	}, WithChunkSize(3))
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+
+	tests := []struct {
+		input   string
+		applied int
+	}{
+		{input: `{"stream": true, "items": [{"name": "a"}], "x": [1, 2]}`, applied: 0},
+		{input: `{"user":"u","stream":true}`, applied: 1},
+		{input: `{"items":[{"id":1},{"id":2}]}`, applied: 3},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		result, err := engine.ProcessWithResult(strings.NewReader(tt.input), &out)
+		if err != nil {
+			t.Fatalf("Process error: %v", err)
+		}
+		if result.Applied != tt.applied {
+			t.Errorf("%s: applied = %d, want %d", tt.input, result.Applied, tt.applied)
+		}
+		if tt.applied == 0 && out.String() != tt.input {
+			t.Errorf("no-op output %q differs from input", out.String())
+		}
+	}
+}
+
+func TestPathEngineQuotedKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "quoted dotted key",
+			rules:  []PathRule{{Path: `headers."x.api.key"`, Action: ActionRemove}},
+			input:  `{"headers":{"x.api.key":"s","x":{"api":{"key":"k"}}}}`,
+			expect: `{"headers":{"x":{"api":{"key":"k"}}}}`,
+		},
+		{
+			name:   "escaped brackets",
+			rules:  []PathRule{{Path: `foo\[0\]`, Action: ActionSet, Value: 1}},
+			input:  `{"foo[0]":0,"foo":[0]}`,
+			expect: `{"foo[0]":1,"foo":[0]}`,
+		},
+		{
+			name:   "literal star is not a wildcard",
+			rules:  []PathRule{{Path: `"*"`, Action: ActionRemove}},
+			input:  `{"*":1,"a":2}`,
+			expect: `{"a":2}`,
+		},
+		{
+			name:   "add key with escaped quote",
+			rules:  []PathRule{{Path: `a\"b`, Action: ActionAdd, Value: 1}},
+			input:  `{"x":1}`,
+			expect: `{"x":1,"a\"b":1}`,
+		},
+		{
+			name:   "add quoted key with backslash",
+			rules:  []PathRule{{Path: `"a\\b"`, Action: ActionAdd, Value: 1}},
+			input:  `{"x":1}`,
+			expect: `{"x":1,"a\\b":1}`,
+		},
+		{
+			name:   "add pointer key with control character",
+			rules:  []PathRule{{Path: "/meta/a\tb\x01", Action: ActionAdd, Value: true}},
+			input:  `{"meta":{}}`,
+			expect: `{"meta":{"a\tb\u0001":true}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules)
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("got %q, want %q", out.String(), tt.expect)
+			}
+		})
+	}
+
+	if _, err := ParsePath(`a."b`); err == nil {
+		t.Error("expected error for unterminated quoted segment")
+	}
+}
+
+func TestPathEngineTopLevelValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "array elements",
+			rules:  []PathRule{{Path: "[*].a", Action: ActionRemove}},
+			input:  `[{"a":1,"b":1},{"a":2}]`,
+			expect: `[{"b":1},{}]`,
+		},
+		{
+			name:   "array index",
+			rules:  []PathRule{{Path: "[0]", Action: ActionRemove}},
+			input:  `[{"a":1}, {"a":2}]`,
+			expect: `[ {"a":2}]`,
+		},
+		{
+			name:   "gemini stream batch",
+			rules:  []PathRule{{Path: "[*].candidates[*].content.parts[*].text", Action: ActionMask, Value: MaskRedact}},
+			input:  `[{"candidates":[{"content":{"parts":[{"text":"hi"}]}}]},{"candidates":[{"content":{"parts":[{"text":"yo"}]}}]}]`,
+			expect: `[{"candidates":[{"content":{"parts":[{"text":"[REDACTED]"}]}}]},{"candidates":[{"content":{"parts":[{"text":"[REDACTED]"}]}}]}]`,
+		},
+		{
+			name:   "add into array elements",
+			rules:  []PathRule{{Path: "[*].b", Action: ActionAdd, Value: true}},
+			input:  `[{"a":1},{"b":false}]`,
+			expect: `[{"a":1,"b":true},{"b":false}]`,
+		},
+		{
+			name:   "keep in array elements",
+			rules:  []PathRule{{Path: "[*].a", Action: ActionKeep}},
+			input:  `[{"a":1,"b":2},{"c":3}]`,
+			expect: `[{"a":1},{}]`,
+		},
+		{
+			name:   "scalar string",
+			rules:  []PathRule{{Path: "a", Action: ActionRemove}, {Path: "[*]", Action: ActionSet, Value: 1}},
+			input:  `"a,b]}:"`,
+			expect: `"a,b]}:"`,
+		},
+		{
+			name:   "scalar number",
+			rules:  []PathRule{{Path: "a", Action: ActionKeep}},
+			input:  ` 123 `,
+			expect: ` 123 `,
+		},
+		{
+			name:   "empty input",
+			rules:  []PathRule{{Path: "a", Action: ActionAdd, Value: 1}},
+			input:  ``,
+			expect: ``,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(3))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("got %q, want %q", out.String(), tt.expect)
+			}
+		})
+	}
+}
+
+func TestPathEngineStrictMode(t *testing.T) {
+	engine, err := NewPathEngine([]PathRule{
+		{Path: "user", Action: ActionRemove},
+	}, WithChunkSize(4), WithStrictMode())
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+
+	valid := []struct {
+		input  string
+		expect string
+	}{
+		{input: `{"user":"u","n":-1.5e+3,"ok":[true,false,null],"s":"a\"é"}`, expect: `{"n":-1.5e+3,"ok":[true,false,null],"s":"a\"é"}`},
+		{input: ` [ {"user": 1}, 0, {} , [] ] `, expect: ` [ {}, 0, {} , [] ] `},
+		{input: `42`, expect: `42`},
+	}
+	for _, tt := range valid {
+		var out bytes.Buffer
+		if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.input, err)
+		}
+		if out.String() != tt.expect {
+			t.Errorf("%s: got %s, want %s", tt.input, out.String(), tt.expect)
+		}
+	}
+
+	invalid := []struct {
+		input  string
+		offset int64
+		path   string
+	}{
+		{input: `{"a":{"b":[1,2,}}`, offset: 15, path: "a.b[2]"},
+		{input: `{"a":tru}`, offset: 8, path: "a"},
+		{input: `{"a":1}x`, offset: 7, path: ""},
+		{input: `{"a":01}`, offset: 6, path: "a"},
+		{input: `{"a" 1}`, offset: 5, path: "a"},
+		{input: `{"choices":[{"message":{"content":"hi`, offset: 37, path: "choices[0].message.content"},
+		{input: `<html>`, offset: 0, path: ""},
+		{input: ``, offset: 0, path: ""},
+	}
+	for _, tt := range invalid {
+		var out bytes.Buffer
+		err := engine.Process(strings.NewReader(tt.input), &out)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected SyntaxError, got %v", tt.input, err)
+			continue
+		}
+		if syntaxErr.Offset != tt.offset || syntaxErr.Path != tt.path {
+			t.Errorf("%q: got offset %d path %q, want offset %d path %q (%v)", tt.input, syntaxErr.Offset, syntaxErr.Path, tt.offset, tt.path, err)
+		}
+	}
+}
+
+func TestPathEngineProcessBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []PathRule
+		input string
+	}{
+		{
+			name:  "remove and add",
+			rules: []PathRule{{Path: "user", Action: ActionRemove}, {Path: "stream", Action: ActionAdd, Value: false}},
+			input: `{"user":"u","messages":[{"role":"user","content":"hi"}]}`,
+		},
+		{
+			name:  "negative index",
+			rules: []PathRule{{Path: "messages[-1].content", Action: ActionSet, Value: "x"}},
+			input: `{"messages":[{"content":"a"},{"content":"b"}]}`,
+		},
+		{
+			name:  "no rules",
+			input: `{"a":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(5))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var want bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &want); err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+
+			got, err := engine.ProcessBytes([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ProcessBytes error: %v", err)
+			}
+			if string(got) != want.String() {
+				t.Errorf("ProcessBytes = %s, want %s", got, want.String())
+			}
+
+			appended, err := engine.AppendProcess([]byte("prefix:"), []byte(tt.input))
+			if err != nil {
+				t.Fatalf("AppendProcess error: %v", err)
+			}
+			if string(appended) != "prefix:"+want.String() {
+				t.Errorf("AppendProcess = %s, want prefix:%s", appended, want.String())
+			}
+		})
+	}
+
+	// 严格模式下非法输入不产生输出
+	engine, err := NewPathEngine([]PathRule{{Path: "a", Action: ActionRemove}}, WithStrictMode())
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+	out, err := engine.AppendProcess(nil, []byte(`{"a":1,"b":`))
+	if err == nil || len(out) != 0 {
+		t.Errorf("strict AppendProcess = %q, %v; want no output and an error", out, err)
+	}
+}
+
+func TestPathEngineOutputFormat(t *testing.T) {
+	rules := []PathRule{{Path: "b", Action: ActionRemove}}
+	input := `{ "a" : 1 ,  "b": {"x": 2},
+	"c": [ ], "d": {}, "s": "keep  { spaces, \"here\" }", "e": [1, {"f": null}] }`
+
+	tests := []struct {
+		name   string
+		opt    PathEngineOption
+		expect string
+	}{
+		{
+			name:   "compact",
+			opt:    WithCompact(),
+			expect: `{"a":1,"c":[],"d":{},"s":"keep  { spaces, \"here\" }","e":[1,{"f":null}]}`,
+		},
+		{
+			name: "indent",
+			opt:  WithIndent("  "),
+			expect: `{
+  "a": 1,
+  "c": [],
+  "d": {},
+  "s": "keep  { spaces, \"here\" }",
+  "e": [
+    1,
+    {
+      "f": null
+    }
+  ]
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(rules, tt.opt, WithChunkSize(3))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(input), &out); err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("got:\n%s\nwant:\n%s", out.String(), tt.expect)
+			}
+
+			got, err := engine.ProcessBytes([]byte(input))
+			if err != nil {
+				t.Fatalf("ProcessBytes error: %v", err)
+			}
+			if string(got) != tt.expect {
+				t.Errorf("ProcessBytes got:\n%s\nwant:\n%s", got, tt.expect)
+			}
+		})
+	}
+
+	// 无规则时同样生效
+	engine, err := NewPathEngine(nil, WithCompact())
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+	got, err := engine.ProcessBytes([]byte(`{ "a": [1, 2] }`))
+	if err != nil || string(got) != `{"a":[1,2]}` {
+		t.Errorf("no-rule compact = %s, %v", got, err)
+	}
+}
+
+func TestPathEngineEscapedKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		opts   []PathEngineOption
+		input  string
+		expect string
+	}{
+		{
+			name:   "unicode escape",
+			rules:  []PathRule{{Path: "parts[*].thoughtSignature", Action: ActionRemove}},
+			input:  `{"parts":[{"text":"a","\u0074houghtSignature":"x"}]}`,
+			expect: `{"parts":[{"text":"a"}]}`,
+		},
+		{
+			name:   "surrogate pair",
+			rules:  []PathRule{{Path: "emoji\U0001F600", Action: ActionSet, Value: 1}},
+			input:  `{"emoji\ud83d\ude00":0,"emoji":0}`,
+			expect: `{"emoji\ud83d\ude00":1,"emoji":0}`,
+		},
+		{
+			name:   "escaped slash",
+			rules:  []PathRule{{Path: `"a/b"`, Action: ActionRemove}},
+			input:  `{"a\/b":1,"c":2}`,
+			expect: `{"c":2}`,
+		},
+		{
+			name:   "escaped quote",
+			rules:  []PathRule{{Path: `"say \"hi\""`, Action: ActionRemove}},
+			input:  `{"say \"hi\"":1,"c":"\"x\",y"}`,
+			expect: `{"c":"\"x\",y"}`,
+		},
+		{
+			name:   "add skips existing escaped key",
+			rules:  []PathRule{{Path: "model", Action: ActionAdd, Value: "b"}},
+			input:  `{"\u006dodel":"a"}`,
+			expect: `{"\u006dodel":"a"}`,
+		},
+		{
+			name:   "normalized output",
+			rules:  []PathRule{{Path: "c", Action: ActionRemove}},
+			opts:   []PathEngineOption{WithNormalizedKeys()},
+			input:  `{"a":1,"x\ud83d\ude00\/\"\n":2,"c":3,"\u0001":4}`,
+			expect: "{\"a\":1,\"x\U0001F600/\\\"\\n\":2,\"\\u0001\":4}",
+		},
+		{
+			name:   "normalized output without rules",
+			opts:   []PathEngineOption{WithNormalizedKeys()},
+			input:  `{"\u0061":{"b":[1]}}`,
+			expect: `{"a":{"b":[1]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]PathEngineOption{WithChunkSize(3)}, tt.opts...)
+			engine, err := NewPathEngine(tt.rules, opts...)
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("Process error: %v", err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("got %q, want %q", out.String(), tt.expect)
+			}
+		})
+	}
+
+	for raw, want := range map[string]string{
+		`\ud83d`:       "\uFFFD",
+		`\ud83dx`:      "\uFFFDx",
+		`\u00zz`:       `\u00zz`,
+		`\q`:           `\q`,
+		`tab\there`:    "tab\there",
+		`\u00e9\u4e2d`: "\u00e9\u4e2d",
+	} {
+		if got := decodeKey([]byte(raw)); got != want {
+			t.Errorf("decodeKey(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestPathEngineRulePriority(t *testing.T) {
+	const input = `{"a":{"b":"x","c":"y"},"items":[1,2]}`
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		mode   ConflictMode
+		expect string
+	}{
+		{
+			name:   "exact path wins without priority",
+			rules:  []PathRule{{Path: "a.*", Action: ActionRemove}, {Path: "a.b", Action: ActionSet, Value: "z"}},
+			expect: `{"a":{"b":"z"},"items":[1,2]}`,
+		},
+		{
+			name:   "higher priority wildcard wins",
+			rules:  []PathRule{{Path: "a.*", Action: ActionRemove, Priority: 10}, {Path: "a.b", Action: ActionSet, Value: "z"}},
+			expect: `{"a":{},"items":[1,2]}`,
+		},
+		{
+			name:   "priority beats action order on the same path",
+			rules:  []PathRule{{Path: "a.b", Action: ActionRemove}, {Path: "a.b", Action: ActionSet, Value: "z", Priority: 1}},
+			expect: `{"a":{"b":"z","c":"y"},"items":[1,2]}`,
+		},
+		{
+			name:   "higher priority array wildcard wins",
+			rules:  []PathRule{{Path: "items[0]", Action: ActionSet, Value: 0}, {Path: "items[*]", Action: ActionRemove, Priority: 1}},
+			expect: `{"a":{"b":"x","c":"y"},"items":[]}`,
+		},
+		{
+			name:   "first match",
+			rules:  []PathRule{{Path: "a.*", Action: ActionRemove}, {Path: "a.b", Action: ActionSet, Value: "z", Priority: 10}},
+			mode:   ConflictFirstMatch,
+			expect: `{"a":{},"items":[1,2]}`,
+		},
+		{
+			name:   "first match in rule order",
+			rules:  []PathRule{{Path: "a.b", Action: ActionSet, Value: "z"}, {Path: "a.*", Action: ActionRemove}},
+			mode:   ConflictFirstMatch,
+			expect: `{"a":{"b":"z"},"items":[1,2]}`,
+		},
+		{
+			name:   "all apply set then transform",
+			rules:  []PathRule{{Path: "a.*", Action: ActionTransform, Value: TransformUpper}, {Path: "a.b", Action: ActionSet, Value: "z", Priority: 1}},
+			mode:   ConflictAllApply,
+			expect: `{"a":{"b":"Z","c":"Y"},"items":[1,2]}`,
+		},
+		{
+			name:   "all apply later set overrides",
+			rules:  []PathRule{{Path: "a.*", Action: ActionTransform, Value: TransformUpper, Priority: 1}, {Path: "a.b", Action: ActionSet, Value: "z"}},
+			mode:   ConflictAllApply,
+			expect: `{"a":{"b":"z","c":"Y"},"items":[1,2]}`,
+		},
+		{
+			name:   "all apply copies final value",
+			rules:  []PathRule{{Path: "a.b", Action: ActionCopy, Value: "d"}, {Path: "a.*", Action: ActionTransform, Value: TransformUpper}},
+			mode:   ConflictAllApply,
+			expect: `{"a":{"b":"X","d":"X","c":"Y"},"items":[1,2]}`,
+		},
+		{
+			name:   "all apply stops at remove",
+			rules:  []PathRule{{Path: "a.b", Action: ActionSet, Value: "z", Priority: 1}, {Path: "a.*", Action: ActionRemove}},
+			mode:   ConflictAllApply,
+			expect: `{"a":{},"items":[1,2]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(4), WithConflictMode(tt.mode))
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+
+			output, err := engine.ProcessBytes([]byte(input))
+			if err != nil {
+				t.Fatalf("ProcessBytes error: %v", err)
+			}
+			if string(output) != tt.expect {
+				t.Errorf("got %s, want %s", output, tt.expect)
+			}
+		})
+	}
+
+	if _, err := NewPathEngine(nil, WithConflictMode("last_match")); err == nil {
+		t.Error("expected error for unsupported conflict mode")
+	}
+}
+
+func TestPathEngineExplain(t *testing.T) {
+	input := `{"model":"gpt-4","messages":[{"role":"user","content":"hi"}],"temperature": 2 ,"meta":{"a/b":[1]}}`
+	maxTemp := 1.0
+	rules := []PathRule{
+		{Path: "model", Action: ActionSet, Value: "gpt-4o"},
+		{Path: "messages[*].role", Action: ActionTransform, Value: TransformUpper},
+		{Path: "temperature", Action: ActionClamp, Max: &maxTemp},
+		{Path: "stream", Action: ActionAdd, Value: true},
+		{Path: "/meta/a~1b", Action: ActionRemove},
+		{Path: "messages[*].content", Action: ActionRename, Value: "text"},
+	}
+
+	engine, err := NewPathEngine(rules, WithChunkSize(7))
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+	reports, err := engine.Explain([]byte(input))
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+
+	expect := []MatchReport{
+		{Rules: []int{0}, Action: ActionSet, Path: "/model", Offset: strings.Index(input, `"model"`), Before: json.RawMessage(`"gpt-4"`), After: json.RawMessage(`"gpt-4o"`)},
+		{Rules: []int{1}, Action: ActionTransform, Path: "/messages/0/role", Offset: strings.Index(input, `"role"`), Before: json.RawMessage(`"user"`), After: json.RawMessage(`"USER"`)},
+		{Rules: []int{5}, Action: ActionRename, Path: "/messages/0/content", Offset: strings.Index(input, `"content"`), Target: "text"},
+		{Rules: []int{2}, Action: ActionClamp, Path: "/temperature", Offset: strings.Index(input, `"temperature"`), Before: json.RawMessage(`2`), After: json.RawMessage(`1`)},
+		{Rules: []int{4}, Action: ActionRemove, Path: "/meta/a~1b", Offset: strings.Index(input, `"a/b"`), Before: json.RawMessage(`[1]`)},
+		{Rules: []int{3}, Action: ActionAdd, Path: "/stream", Offset: len(input) - 1, After: json.RawMessage(`true`)},
+	}
+	if !reflect.DeepEqual(reports, expect) {
+		got, _ := json.Marshal(reports)
+		want, _ := json.Marshal(expect)
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+
+	t.Run("all apply lists every rule", func(t *testing.T) {
+		engine, err := NewPathEngine([]PathRule{
+			{Path: "a.*", Action: ActionTransform, Value: TransformUpper},
+			{Path: "a.b", Action: ActionSet, Value: "z", Priority: 1},
+			{Path: "a.b", Action: ActionCopy, Value: "c"},
+		}, WithConflictMode(ConflictAllApply))
+		if err != nil {
+			t.Fatalf("NewPathEngine error: %v", err)
+		}
+		reports, err := engine.Explain([]byte(`{"a":{"b":"x"}}`))
+		if err != nil {
+			t.Fatalf("Explain error: %v", err)
+		}
+		if len(reports) != 1 || !reflect.DeepEqual(reports[0].Rules, []int{1, 0, 2}) || string(reports[0].After) != `"Z"` {
+			t.Errorf("unexpected reports: %+v", reports)
+		}
+	})
+
+	t.Run("keep removals", func(t *testing.T) {
+		engine, err := NewPathEngine([]PathRule{{Path: "model", Action: ActionKeep}})
+		if err != nil {
+			t.Fatalf("NewPathEngine error: %v", err)
+		}
+		reports, err := engine.Explain([]byte(`{"model":"m","user":{"id":1}}`))
+		if err != nil {
+			t.Fatalf("Explain error: %v", err)
+		}
+		if len(reports) != 1 || reports[0].Path != "/user" || reports[0].Rules != nil || string(reports[0].Before) != `{"id":1}` {
+			t.Errorf("unexpected reports: %+v", reports)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		reports, err := engine.Explain([]byte(`[1,2]`))
+		if err != nil || len(reports) != 0 {
+			t.Errorf("got %+v, %v", reports, err)
+		}
+	})
+}
+
+func TestPathEngineProgress(t *testing.T) {
+	input := `{"a":{"b":[1,2,{"c":"xxxxxxxx"}]},"d":true}`
+	var snapshots []Progress
+	engine, err := NewPathEngine([]PathRule{{Path: "d", Action: ActionRemove}},
+		WithChunkSize(1), WithProgress(func(p Progress) { snapshots = append(snapshots, p) }))
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := engine.Process(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Process error: %v", err)
+	}
+	if len(snapshots) != len(input) {
+		t.Fatalf("got %d progress callbacks, want %d", len(snapshots), len(input))
+	}
+
+	inner := snapshots[strings.Index(input, "xxx")]
+	if inner.Path != "/a/b/2/c" || inner.Depth != 4 || !inner.InString {
+		t.Errorf("unexpected progress inside nested string: %+v", inner)
+	}
+	skipped := snapshots[strings.Index(input, "true")]
+	if skipped.Path != "/d" || !skipped.Skipping {
+		t.Errorf("unexpected progress inside removed value: %+v", skipped)
+	}
+	last := snapshots[len(snapshots)-1]
+	if last.BytesProcessed != int64(len(input)) || last.Depth != 0 || last.Path != "" || last.Applied != 1 {
+		t.Errorf("unexpected final progress: %+v", last)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	rules := []PathRule{
+		{Path: "messages[x]", Action: ActionSet, Value: 1},              // 0: invalid path
+		{Path: "model", Action: "replace", Value: "x"},                  // 1: invalid action
+		{Path: "extra", Action: ActionAdd, ValueBytes: []byte(`{"a":`)}, // 2: invalid value
+		{Path: "temperature", Action: ActionSet, Value: 0.5},            // 3
+		{Path: "temperature", Action: ActionRemove},                     // 4: conflict with 3
+		{Path: "metadata", Action: ActionRemove},                        // 5
+		{Path: "metadata.user", Action: ActionMask, Value: "redact"},    // 6: unreachable
+		{Path: "messages[*].name", Action: ActionRemove},                // 7: shadowed by 8
+		{Path: "messages[0].role", Action: ActionSet, Value: "system"},  // 8
+		{Path: "stream", Action: ActionSet, Value: true, Priority: 1},   // 9
+		{Path: "stream", Action: ActionRemove},                          // 10: different priority
+	}
+
+	type issueKey struct {
+		rule int
+		code string
+	}
+	want := map[issueKey]IssueSeverity{
+		{0, IssueInvalidPath}:   SeverityError,
+		{1, IssueInvalidAction}: SeverityError,
+		{2, IssueInvalidValue}:  SeverityError,
+		{4, IssueConflict}:      SeverityWarning,
+		{6, IssueUnreachable}:   SeverityWarning,
+		{7, IssueShadowed}:      SeverityWarning,
+	}
+
+	issues := ValidateRules(rules)
+	got := make(map[issueKey]IssueSeverity, len(issues))
+	for _, issue := range issues {
+		got[issueKey{issue.Rule, issue.Code}] = issue.Severity
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateRules issues mismatch:\n got: %v\nwant: %v\nissues: %v", got, want, issues)
+	}
+	if !HasRuleErrors(issues) {
+		t.Error("HasRuleErrors() = false, want true")
+	}
+
+	keepRules := []PathRule{
+		{Path: "model", Action: ActionKeep},
+		{Path: "messages[*]", Action: ActionKeep},
+		{Path: "messages[*].content", Action: ActionTransform, Value: "trim"},
+		{Path: "user", Action: ActionSet, Value: "anonymous"},
+	}
+	issues = ValidateRules(keepRules)
+	if len(issues) != 1 || issues[0].Rule != 3 || issues[0].Code != IssueUnreachable {
+		t.Errorf("keep rules: unexpected issues %v", issues)
+	}
+
+	if issues := ValidateRules([]PathRule{{Path: "model", Action: ActionSet, Value: "x"}}); issues != nil {
+		t.Errorf("valid rules: unexpected issues %v", issues)
+	}
+}
+
+func TestPathEngineCapture(t *testing.T) {
+	input := `{"model":"gemini-pro","usageMetadata":{"promptTokenCount":3, "totalTokenCount": 12},"items":[{"id":"a"},{"id":"b"}],"secret":"x"}`
+	rules := []PathRule{
+		{Path: "model", Action: ActionCapture},
+		{Path: "model", Action: ActionSet, Value: "alias"},
+		{Path: "usageMetadata.totalTokenCount", Action: ActionCapture, Value: "tokens"},
+		{Path: "items[*].id", Action: ActionCapture, Value: "last_id"},
+		{Path: "secret", Action: ActionCapture},
+		{Path: "secret", Action: ActionRemove},
+	}
+	engine, err := NewPathEngine(rules, WithChunkSize(7))
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+
+	var out bytes.Buffer
+	result, err := engine.ProcessWithResult(strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatalf("ProcessWithResult error: %v", err)
+	}
+
+	wantOutput := `{"model":"alias","usageMetadata":{"promptTokenCount":3, "totalTokenCount": 12},"items":[{"id":"a"},{"id":"b"}]}`
+	if out.String() != wantOutput {
+		t.Errorf("output mismatch:\n got: %s\nwant: %s", out.String(), wantOutput)
+	}
+	if result.Applied != 2 {
+		t.Errorf("Applied = %d, want 2", result.Applied)
+	}
+
+	want := map[string]json.RawMessage{
+		"model":   json.RawMessage(`"gemini-pro"`),
+		"tokens":  json.RawMessage(`12`),
+		"last_id": json.RawMessage(`"b"`),
+		"secret":  json.RawMessage(`"x"`),
+	}
+	if !reflect.DeepEqual(result.Captured, want) {
+		t.Errorf("Captured mismatch:\n got: %s\nwant: %s", result.Captured, want)
+	}
+
+	// 只有 capture 规则时输出与输入一致
+	engine, err = NewPathEngine([]PathRule{{Path: "usageMetadata", Action: ActionCapture}})
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+	out.Reset()
+	result, err = engine.ProcessWithResult(strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatalf("ProcessWithResult error: %v", err)
+	}
+	if out.String() != input || result.Applied != 0 {
+		t.Errorf("capture-only changed output (applied=%d): %s", result.Applied, out.String())
+	}
+	if got := string(result.Captured["usageMetadata"]); got != `{"promptTokenCount":3, "totalTokenCount": 12}` {
+		t.Errorf("captured object = %s", got)
+	}
+}
+
+func TestPathEngineOnMatch(t *testing.T) {
+	input := `{"messages":[{"role":"user","name":"bob","content":"hi"},{"role":"assistant","name": {"first":"x"}}],"temperature":2,"user":"u1"}`
+	rules := []PathRule{
+		{Path: "messages[*].name", Action: ActionRemove},
+		{Path: "temperature", Action: ActionSet, Value: 1},
+		{Path: "user", Action: ActionRename, Value: "user_id"},
+		{Path: "metadata", Action: ActionAdd, Value: "gw"},
+	}
+
+	run := func(opts ...PathEngineOption) []MatchEvent {
+		var events []MatchEvent
+		onMatch := WithOnMatch(func(e MatchEvent) {
+			e.Value = append(json.RawMessage(nil), e.Value...)
+			events = append(events, e)
+		})
+		engine, err := NewPathEngine(rules, append(opts, onMatch, WithChunkSize(5))...)
+		if err != nil {
+			t.Fatalf("NewPathEngine error: %v", err)
+		}
+		var out bytes.Buffer
+		if err := engine.Process(strings.NewReader(input), &out); err != nil {
+			t.Fatalf("Process error: %v", err)
+		}
+		want := `{"messages":[{"role":"user","content":"hi"},{"role":"assistant"}],"temperature":1,"user_id":"u1","metadata":"gw"}`
+		if out.String() != want {
+			t.Errorf("output mismatch:\n got: %s\nwant: %s", out.String(), want)
+		}
+		return events
+	}
+
+	want := []MatchEvent{
+		{Rule: 0, Action: ActionRemove, Path: "/messages/0/name", Value: json.RawMessage(`"bob"`)},
+		{Rule: 0, Action: ActionRemove, Path: "/messages/1/name", Value: json.RawMessage(`{"first":"x"}`)},
+		{Rule: 1, Action: ActionSet, Path: "/temperature", Value: json.RawMessage(`2`)},
+		{Rule: 2, Action: ActionRename, Path: "/user"},
+		{Rule: 3, Action: ActionAdd, Path: "/metadata"},
+	}
+	if got := run(WithMatchValues()); !reflect.DeepEqual(got, want) {
+		t.Errorf("events with values mismatch:\n got: %+v\nwant: %+v", got, want)
+	}
+
+	// 不需要原值时事件在命中时立即发出
+	for i := range want {
+		want[i].Value = nil
+	}
+	if got := run(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events mismatch:\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestPathEngineStats(t *testing.T) {
+	input := `{"messages":[{"role":"user","name":"bob","content":"hi"}],"temperature":2,"user":"u1","key":"secret"}`
+	engine, err := NewPathEngine([]PathRule{
+		{Path: "messages[*].name", Action: ActionRemove},
+		{Path: "temperature", Action: ActionSet, Value: 1},
+		{Path: "user", Action: ActionRename, Value: "user_id"},
+		{Path: "key", Action: ActionMask, Value: "redact"},
+		{Path: "metadata", Action: ActionAdd, Value: "gw"},
+	}, WithChunkSize(7))
+	if err != nil {
+		t.Fatalf("NewPathEngine error: %v", err)
+	}
+
+	var out bytes.Buffer
+	result, err := engine.ProcessWithResult(strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatalf("ProcessWithResult error: %v", err)
+	}
+	got := result.Stats
+	if got.Duration <= 0 {
+		t.Errorf("Duration = %v, want > 0", got.Duration)
+	}
+	got.Duration = 0
+	want := EngineStats{
+		Documents:   1,
+		BytesIn:     int64(len(input)),
+		BytesOut:    int64(out.Len()),
+		Removed:     1,
+		Set:         1,
+		Added:       1,
+		Renamed:     1,
+		Transformed: 1,
+	}
+	if got != want {
+		t.Errorf("result stats mismatch:\n got: %+v\nwant: %+v", got, want)
+	}
+
+	// 流式处理器归还时计入累计统计
+	proc := engine.GetProcessor()
+	out.Reset()
+	for i := 0; i < len(input); i += 10 {
+		if err := engine.ProcessChunk(proc, []byte(input[i:min(i+10, len(input))]), &out); err != nil {
+			t.Fatalf("ProcessChunk error: %v", err)
+		}
+	}
+	engine.ReleaseProcessor(proc)
+
+	total := engine.ResetStats()
+	total.Duration = 0
+	want.Documents, want.BytesIn, want.BytesOut = 2, 2*want.BytesIn, 2*want.BytesOut
+	want.Removed, want.Set, want.Added, want.Renamed, want.Transformed = 2, 2, 2, 2, 2
+	if total != want {
+		t.Errorf("cumulative stats mismatch:\n got: %+v\nwant: %+v", total, want)
+	}
+	if stats := engine.Stats(); stats != (EngineStats{}) {
+		t.Errorf("stats after reset = %+v, want zero", stats)
+	}
+
+	// 无规则时原样透传也计入文档数和字节数
+	empty, _ := NewPathEngine(nil)
+	if _, err := empty.ProcessBytes([]byte(input)); err != nil {
+		t.Fatalf("ProcessBytes error: %v", err)
+	}
+	if stats := empty.Stats(); stats.Documents != 1 || stats.BytesIn != int64(len(input)) || stats.BytesOut != stats.BytesIn {
+		t.Errorf("passthrough stats = %+v", stats)
+	}
+}
+
+func TestPathEngineProcessSSE(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []PathRule
+		input string
+		want  string
+	}{
+		{
+			name: "openai chat completion chunks",
+			rules: []PathRule{
+				{Path: "system_fingerprint", Action: ActionRemove},
+				{Path: "usage.prompt_tokens", Action: ActionCapture, Value: "prompt_tokens"},
+			},
+			input: "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"system_fingerprint\":\"fp_1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}]}\n\n" +
+				"data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"system_fingerprint\":\"fp_1\",\"choices\":[],\"usage\":{\"prompt_tokens\":9,\"completion_tokens\":1}}\n\n" +
+				"data: [DONE]\n\n",
+			want: "data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}]}\n\n" +
+				"data: {\"id\":\"chatcmpl-1\",\"object\":\"chat.completion.chunk\",\"choices\":[],\"usage\":{\"prompt_tokens\":9,\"completion_tokens\":1}}\n\n" +
+				"data: [DONE]\n\n",
+		},
+		{
+			name:  "anthropic events keep event lines and comments",
+			rules: []PathRule{{Path: "message.model", Action: ActionSet, Value: "alias"}},
+			input: ": ping\n\n" +
+				"event: message_start\r\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude-x\"}}\r\n\r\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"text\":\"Hi\"}}\n\n",
+			want: ": ping\n\n" +
+				"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"alias\"}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"text\":\"Hi\"}}\n\n",
+		},
+		{
+			name:  "multi-line data is joined before rules apply",
+			rules: []PathRule{{Path: "secret", Action: ActionRemove}},
+			input: "id: 7\ndata: {\"a\":1,\ndata: \"secret\":\"x\",\ndata: \"b\":2}\nretry: 1000\n\n",
+			want:  "id: 7\ndata: {\"a\":1,\ndata: \"b\":2}\nretry: 1000\n\n",
+		},
+		{
+			name:  "stream ending mid-event is passed on",
+			rules: []PathRule{{Path: "x", Action: ActionRemove}},
+			input: "data: {\"x\":1,\"y\":2}\n\ndata: {\"x\":1",
+			want:  "data: {\"y\":2}\n\ndata: {\"x\":1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules)
+			if err != nil {
+				t.Fatalf("NewPathEngine error: %v", err)
+			}
+			// 逐字节读取，模拟事件被拆分在多次 TCP 读取中
+			for _, input := range []io.Reader{strings.NewReader(tt.input), iotest.OneByteReader(strings.NewReader(tt.input))} {
+				var out bytes.Buffer
+				if _, err := engine.ProcessSSE(input, &out); err != nil {
+					t.Fatalf("ProcessSSE error: %v", err)
+				}
+				if out.String() != tt.want {
+					t.Errorf("output mismatch:\n got: %q\nwant: %q", out.String(), tt.want)
+				}
+			}
+		})
+	}
+
+	engine, _ := NewPathEngine([]PathRule{{Path: "usage.prompt_tokens", Action: ActionCapture, Value: "prompt_tokens"}})
+	result, err := engine.ProcessSSE(strings.NewReader(tests[0].input), io.Discard)
+	if err != nil {
+		t.Fatalf("ProcessSSE error: %v", err)
+	}
+	if got := string(result.Captured["prompt_tokens"]); got != "9" || result.Stats.Documents != 2 {
+		t.Errorf("captured prompt_tokens = %q, documents = %d, want 9 and 2", got, result.Stats.Documents)
+	}
+}
+
+// scanSkipStringsReference 逐字节的参考实现，转义只影响引号
+func scanSkipStringsReference(data []byte) []uint32 {
+	var positions []uint32
+	inString, escaped := false, false
+	for i, b := range data {
+		isEscaped := escaped
+		escaped = false
+		switch {
+		case b == '\\' && !isEscaped:
+			escaped = true
+		case b == '"':
+			if !isEscaped {
+				inString = !inString
+				positions = append(positions, uint32(i))
+			}
+		case !inString && isStructural(b):
+			positions = append(positions, uint32(i))
+		}
+	}
+	return positions
+}
+
+// TestScanStructuralSkipStrings 与逐字节参考实现一致，状态跨 chunk 保持（包括在反斜杠和引号处切分）
+func TestScanStructuralSkipStrings(t *testing.T) {
+	alphabet := []byte(`"{}[]:, a\\`)
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range []int{0, 1, 63, 64, 65, 200, 4096 + 17} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		want := scanSkipStringsReference(data)
+		for _, chunkSize := range []int{1, 7, 64, 100, size + 1} {
+			var state ScanState
+			var got []uint32
+			positions := make([]uint32, chunkSize)
+			for start := 0; start < size; start += chunkSize {
+				end := min(start+chunkSize, size)
+				n := ScanStructuralSkipStrings(data[start:end], positions, &state)
+				for _, pos := range positions[:n] {
+					got = append(got, uint32(start)+pos)
+				}
+			}
+			if len(got) != len(want) {
+				t.Errorf("size=%d chunk=%d: found %d positions, want %d", size, chunkSize, len(got), len(want))
+				continue
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("size=%d chunk=%d: position[%d] = %d, want %d", size, chunkSize, i, got[i], want[i])
+					break
+				}
+			}
+		}
+	}
+
+	// 字符串内的结构字符和转义引号不报告
+	positions := make([]uint32, 16)
+	input := `{"a":"x:{y}\"z,"}`
+	n := ScanStructuralSkipStrings([]byte(input), positions, &ScanState{})
+	if want := []uint32{0, 1, 3, 4, 5, 15, 16}; !reflect.DeepEqual(positions[:n], want) {
+		t.Errorf("ScanStructuralSkipStrings(%s) = %v, want %v", input, positions[:n], want)
+	}
+}
+
+// BenchmarkPathEngineTextHeavy 长文本内容的响应体，字符串内含大量标点和转义
+func BenchmarkPathEngineTextHeavy(b *testing.B) {
+	text := strings.Repeat(`Note: {\"step\": [1, 2]}, then \"done\".\n`, 20)
+	var sb strings.Builder
+	sb.WriteString(`{"choices":[`)
+	for i := 0; i < 2000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`{"index":0,"message":{"role":"assistant","content":"` + text + `"}}`)
+	}
+	sb.WriteString(`],"usage":{"total_tokens":1}}`)
+	input := []byte(sb.String())
+
+	engine, err := NewPathEngine([]PathRule{{Path: "usage", Action: ActionRemove}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.ProcessBytes(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestBenchmarkScanners 每个可用实现都有结果，且标记了运行时选用的实现
+func TestBenchmarkScanners(t *testing.T) {
+	caps := Capabilities()
+	if len(caps.Scanners) == 0 || caps.Scanner != caps.Scanners[0] || caps.Classifier != caps.Classifiers[0] {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+
+	report := BenchmarkScanners(4096, 0)
+	if want := len(caps.Scanners) + len(caps.Classifiers); len(report.Results) != want {
+		t.Fatalf("got %d results, want %d", len(report.Results), want)
+	}
+	selected := 0
+	for _, r := range report.Results {
+		if r.Iterations < 1 {
+			t.Errorf("%s ran %d iterations", r.Name, r.Iterations)
+		}
+		if r.Selected {
+			selected++
+		}
+	}
+	if selected != 2 {
+		t.Errorf("got %d selected results, want 2", selected)
+	}
+}
+
+// TestPathEngineLimits 超出深度、key 长度和文档大小限制时返回 *LimitError
+func TestPathEngineLimits(t *testing.T) {
+	deep := strings.Repeat(`{"a":`, 5) + "1" + strings.Repeat("}", 5)
+	tests := []struct {
+		name      string
+		input     string
+		rules     []PathRule
+		opts      []PathEngineOption
+		wantLimit string // 为空表示不应出错
+	}{
+		{"depth within limit", deep, nil, []PathEngineOption{WithMaxDepth(5)}, ""},
+		{"depth exceeded", deep, nil, []PathEngineOption{WithMaxDepth(4)}, LimitDepth},
+		{"depth inside skipped value", `{"x":` + deep + `}`, []PathRule{{Path: "x", Action: ActionRemove}}, []PathEngineOption{WithMaxDepth(5)}, LimitDepth},
+		{"depth in arrays", `[[[[1]]]]`, nil, []PathEngineOption{WithMaxDepth(3)}, LimitDepth},
+		{"depth strict mode", deep, nil, []PathEngineOption{WithMaxDepth(4), WithStrictMode()}, LimitDepth},
+		{"key within limit", `{"abcd":1}`, nil, []PathEngineOption{WithMaxKeyLength(4)}, ""},
+		{"key exceeded", `{"abcde":1}`, nil, []PathEngineOption{WithMaxKeyLength(4)}, LimitKeyLength},
+		{"key braces not structural", `{"a{b}c":1}`, nil, []PathEngineOption{WithMaxKeyLength(4)}, LimitKeyLength},
+		{"size within limit", `{"a":1}`, nil, []PathEngineOption{WithMaxDocumentSize(7)}, ""},
+		{"size exceeded", `{"a":12}`, nil, []PathEngineOption{WithMaxDocumentSize(7)}, LimitDocumentSize},
+		{"size with negative index", `{"a":[1,2,3]}`, []PathRule{{Path: "a[-1]", Action: ActionRemove}}, []PathEngineOption{WithMaxDocumentSize(8)}, LimitDocumentSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPathEngine(tt.rules, append(tt.opts, WithChunkSize(3))...)
+			if err != nil {
+				t.Fatalf("NewPathEngine() error = %v", err)
+			}
+			check := func(mode string, err error) {
+				var limitErr *LimitError
+				if tt.wantLimit == "" {
+					if err != nil {
+						t.Errorf("%s: unexpected error %v", mode, err)
+					}
+					return
+				}
+				if !errors.As(err, &limitErr) || limitErr.Limit != tt.wantLimit {
+					t.Errorf("%s: error = %v, want %s limit error", mode, err, tt.wantLimit)
+				}
+			}
+
+			var out bytes.Buffer
+			check("stream", engine.Process(iotest.OneByteReader(strings.NewReader(tt.input)), &out))
+			_, err = engine.ProcessBytes([]byte(tt.input))
+			check("bytes", err)
+		})
+	}
+}
+
+// TestProcessorPoolConfig 较小的位置缓冲区拆分处理数据块，结果不变；有界池超出上限时丢弃归还的处理器
+func TestProcessorPoolConfig(t *testing.T) {
+	defer ConfigureProcessorPool(ProcessorPoolConfig{})
+
+	input := []byte(`{"messages":[` + strings.Repeat(`{"role":"user","content":"a,b:{c}","x":[1,2,3]},`, 200) + `{}],"drop":{"a":[1,2]},"keep":1}`)
+	rules := []PathRule{
+		{Path: "drop", Action: ActionRemove},
+		{Path: "messages[*].x", Action: ActionRemove},
+		{Path: "keep", Action: ActionSet, Value: 2},
+	}
+	engine, err := NewPathEngine(rules)
+	if err != nil {
+		t.Fatalf("NewPathEngine() error = %v", err)
+	}
+	want, err := engine.ProcessBytes(input)
+	if err != nil {
+		t.Fatalf("ProcessBytes() error = %v", err)
+	}
+
+	ConfigureProcessorPool(ProcessorPoolConfig{PositionsCap: MinPositionsCap, MaxIdle: 1})
+	if stats := ProcessorPoolStatistics(); stats.PositionsCap != MinPositionsCap || stats.MaxIdle != 1 || stats.Idle != 0 {
+		t.Fatalf("stats after configure = %+v", stats)
+	}
+	got, err := engine.ProcessBytes(input)
+	if err != nil {
+		t.Fatalf("ProcessBytes() with small positions error = %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("small positions output differs:\n got %s\nwant %s", got, want)
+	}
+
+	before := ProcessorPoolStatistics()
+	p1, p2 := engine.GetProcessor(), engine.GetProcessor()
+	if len(p1.positions) > MinPositionsCap || len(p2.positions) > MinPositionsCap {
+		t.Errorf("positions exceed cap: %d, %d", len(p1.positions), len(p2.positions))
+	}
+	engine.ReleaseProcessor(p1)
+	engine.ReleaseProcessor(p2)
+	after := ProcessorPoolStatistics()
+	if after.Hits-before.Hits != 1 || after.Misses-before.Misses != 1 {
+		t.Errorf("hits/misses delta = %d/%d, want 1/1", after.Hits-before.Hits, after.Misses-before.Misses)
+	}
+	if after.Drops-before.Drops != 1 || after.Idle != 1 || after.InUse != before.InUse {
+		t.Errorf("stats after release = %+v, before %+v", after, before)
+	}
+}
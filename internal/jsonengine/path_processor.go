@@ -1,9 +1,11 @@
 package jsonengine
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"strconv"
+	"time"
 )
 
 // jsonMarshal 包装 json.Marshal，用于复杂类型的后备序列化
@@ -14,7 +16,12 @@ type pathEntry struct {
 	key      string  // 键名（对象）或索引（数组）
 	isArray  bool    // 是否数组
 	arrayIdx int     // 数组索引
+	emitted  int     // 数组内已输出的元素数（用于决定是否补逗号）
+	arrayLen int     // 数组长度（-1 表示未知，用于负索引/切片匹配）
 	acNode   *ACNode // AC 自动机状态
+	keepAll  bool    // 白名单模式下整个子树原样保留
+	member   string  // 对象内最近读取的字段名（用于进度上报）
+	removed  bool    // 有成员被规则删除（用于 WithRemoveEmptyParents）
 }
 
 // skipState 值跳过状态机
@@ -27,7 +34,9 @@ type skipState struct {
 // addAction 待插入的字段
 type addAction struct {
 	key   string
-	value []byte // 预序列化的JSON值
+	value []byte     // 预序列化的JSON值
+	expr  *valueExpr // 值表达式，在对象结束时求值
+	rule  int        // 规则下标
 }
 
 // PathProcessor 路径过滤处理器
@@ -35,7 +44,12 @@ type PathProcessor struct {
 	matcher *PathMatcher
 
 	// SIMD 扫描结果
-	positions []uint32
+	positions    []uint32
+	positionsCap int       // positions 可增长到的容量，更长的数据块拆分扫描（见 pool.go）
+	scanState    ScanState // 扫描器的跨 chunk 字符串状态
+
+	limits limits // 深度、key 长度和文档大小限制（见 limits.go）
+	err    error  // 超出限制后的错误，之后的数据块不再处理
 
 	// 处理状态（跨 chunk 持久化）
 	pathStack     []pathEntry // 当前路径
@@ -45,18 +59,75 @@ type PathProcessor struct {
 	skipping      bool        // 跳过值模式
 	skipState     skipState   // 跳过状态机
 	pendingComma  bool        // 延迟逗号
+	pendingSpace  []byte      // 延迟逗号之后的空白，随逗号一起输出以保持原有格式
 	keyBuffer     []byte      // key 累积缓冲（包含引号）
 	inKey         bool        // 正在读取 key
 	outputBuf     []byte      // 输出缓冲
 	firstField    bool        // 当前对象的第一个字段
 	lastMatchNode *ACNode     // 最近 key 匹配结果，用于进入子对象
+	lastMatchKeep bool        // 最近匹配是否命中 keep 规则终点（白名单模式）
+	keyOverride   []byte      // rename 操作：替换原 key 输出的新 key（包含引号）
+	awaitElement  bool        // 刚进入数组，首个元素尚未出现（期间的空白暂存在 pendingSpace）
+	normalizeKeys bool        // 输出时将含转义的 key 改写为解码后的形式
+
+	// 删除因规则变空的容器（见 prune.go）
+	removeEmptyParents bool
+	pruner             pruneWriter
 
 	// Set 操作状态（流式友好）
 	setValue []byte // 跳过原值后要输出的新值（nil 表示 remove）
 
+	// Mask/Transform/Clamp 操作状态：跳过原值时收集原始字节，结束后输出转换结果
+	transforming bool
+	transform    func([]byte) []byte
+	transformBuf []byte
+
+	// Truncate/StripBase64 操作状态：跳过原值时边读边输出转换结果，不收集整个值
+	streaming bool
+	streamer  valueStreamer // 指向 truncator 或 stripper
+	truncator stringTruncator
+	stripper  base64Stripper
+	streamBuf []byte // 流式输出的复用缓冲，Explain 模式下保留整个输出
+
+	// 数组长度（按 '[' 在输入中出现的顺序），仅在存在负索引/负切片规则时预先统计
+	arrayLens    []int
+	arrayOrdinal int // 已遇到的 '[' 数量
+
+	// 本次处理中实际生效的操作次数，为 0 时输出应与输入逐字节一致
+	applied int
+	stats   EngineStats    // 本次处理的分类统计（见 stats.go）
+	out     countingWriter // 包装 ProcessChunk 的输出以统计输出字节数
+
 	// Add 操作状态（深度映射）
 	pendingAdds map[int][]addAction // depth -> 待插入字段列表
 	hasAddRules bool                // 是否存在 Add 规则（性能优化，避免每次调用都遍历规则）
+
+	// 冲突解析的复用缓冲（见 matchCandidates）
+	candidates     []RuleAction
+	candidateRanks []int
+	childBuf       []*ACNode
+	ordered        []RuleAction
+
+	// Explain 模式（见 PathEngine.Explain），为 nil 时不记录
+	explain  *explainState
+	resolved []int // 最近一次冲突解析得出的规则下标（仅 Explain 模式或设置了规则生效回调时记录）
+	consumed int    // 之前的 chunk 已处理的字节数
+	pos      int    // 当前结构字符在输入中的偏移
+	keyStart int    // 当前 key 起始引号的偏移
+	lastKey  string // 最近读取的 key，进入容器时记入路径栈
+
+	progress func(Progress) // 进度回调，每个 chunk 结束时调用
+
+	// Capture 操作状态：跳过值时同时收集原始字节（见 capture.go）
+	capturing    bool
+	captureNames []string
+	captured     map[string]json.RawMessage
+
+	// 规则生效回调（见 match_event.go）
+	onMatch        func(MatchEvent)
+	matchValues    bool         // 事件是否携带原值
+	matchBuffering bool         // 正在收集待定事件的原值
+	pendingMatches []MatchEvent // 等待原值结束的事件
 }
 
 // Reset 重置处理器状态
@@ -68,12 +139,38 @@ func (p *PathProcessor) Reset() {
 	p.skipping = false
 	p.skipState = skipState{}
 	p.pendingComma = false
+	p.pendingSpace = p.pendingSpace[:0]
 	p.keyBuffer = p.keyBuffer[:0]
 	p.inKey = false
 	p.outputBuf = p.outputBuf[:0]
 	p.firstField = true
 	p.lastMatchNode = nil
+	p.lastMatchKeep = false
+	p.keyOverride = nil
+	p.awaitElement = false
 	p.setValue = nil
+	p.transforming = false
+	p.transform = nil
+	p.transformBuf = p.transformBuf[:0]
+	p.streaming = false
+	p.streamer = nil
+	p.streamBuf = p.streamBuf[:0]
+	p.arrayLens = nil
+	p.arrayOrdinal = 0
+	p.applied = 0
+	p.stats = EngineStats{}
+	p.out = countingWriter{}
+	p.consumed = 0
+	p.scanState = ScanState{}
+	p.err = nil
+	p.pos = 0
+	p.lastKey = ""
+	p.capturing = false
+	p.captureNames = p.captureNames[:0]
+	p.captured = nil
+	p.matchBuffering = false
+	p.pendingMatches = p.pendingMatches[:0]
+	p.pruner.reset()
 	
 	// 清空 Add 操作状态
 	if p.pendingAdds != nil {
@@ -84,13 +181,48 @@ func (p *PathProcessor) Reset() {
 }
 
 // ProcessChunk 处理单个 chunk
+// 数据块可在任意字节处拆分：key、字符串值（含转义序列和多字节 UTF-8 字符）、数字和字面量跨块时，
+// 读取状态保存在处理器中，set/mask/truncate 等跳过或改写原值的操作也在下一块继续，
+// 输出与整体处理逐字节一致
 func (p *PathProcessor) ProcessChunk(chunk []byte, w io.Writer) error {
 	if len(chunk) == 0 {
 		return nil
 	}
+	if p.err != nil {
+		return p.err
+	}
+	if p.limits.enabled() {
+		if p.err = p.checkChunkSize(len(chunk)); p.err != nil {
+			return p.err
+		}
+	}
+	// 每个字节最多产生一个位置：缓冲区按数据块增长，超过容量上限的数据块拆分处理
+	limit := max(p.positionsCap, len(p.positions), MinPositionsCap)
+	if len(chunk) > limit {
+		for len(chunk) > 0 {
+			n := min(len(chunk), limit)
+			if err := p.ProcessChunk(chunk[:n], w); err != nil {
+				return err
+			}
+			chunk = chunk[n:]
+		}
+		return nil
+	}
+	if len(chunk) > len(p.positions) {
+		p.positions = make([]uint32, min(max(len(chunk), 2*len(p.positions)), limit))
+	}
 
-	// SIMD 扫描结构字符
-	n := ScanStructural(chunk, p.positions)
+	start := time.Now()
+	defer func() { p.stats.Duration += time.Since(start) }()
+	p.out.w = w
+	if p.removeEmptyParents {
+		p.pruner.w = w
+		p.out.w = &p.pruner
+	}
+	w = &p.out
+
+	// SIMD 扫描结构字符，字符串内的结构字符作为普通内容处理
+	n := ScanStructuralSkipStrings(chunk, p.positions, &p.scanState)
 
 	// 处理结构字符之间的内容
 	prev := 0
@@ -104,16 +236,31 @@ func (p *PathProcessor) ProcessChunk(chunk []byte, w io.Writer) error {
 		}
 
 		// 处理结构字符
+		p.pos = p.consumed + pos
 		p.handleStructural(char, w)
 		prev = pos + 1
+
+		if p.limits.enabled() {
+			if p.err = p.checkLimits(p.pos); p.err != nil {
+				p.consumed += pos + 1
+				return p.err
+			}
+		}
+	}
+	p.consumed += len(chunk)
+	if p.progress != nil {
+		p.progress(p.Progress())
 	}
 
 	// 输出剩余内容
 	if prev < len(chunk) {
 		p.handleContent(chunk[prev:], w)
+		if p.limits.enabled() {
+			p.err = p.checkLimits(p.consumed - 1)
+		}
 	}
 
-	return nil
+	return p.err
 }
 
 // handleContent 处理非结构字符内容
@@ -124,6 +271,12 @@ func (p *PathProcessor) handleContent(content []byte, w io.Writer) {
 
 	// 跳过模式：不输出，但跟踪状态
 	if p.skipping {
+		if p.transforming || p.capturing || p.matchBuffering {
+			p.transformBuf = append(p.transformBuf, content...)
+		}
+		if p.streaming {
+			p.writeStreamed(content, w)
+		}
 		for _, b := range content {
 			if p.skipState.escaped {
 				p.skipState.escaped = false
@@ -136,16 +289,65 @@ func (p *PathProcessor) handleContent(content []byte, w io.Writer) {
 		return
 	}
 
+	// 反斜杠不是结构字符，字符串内需在此跟踪转义状态，否则 \" 会被当作字符串结束
+	if p.inString {
+		p.trackEscapes(content)
+	}
+
 	// 在 key 中，累积到缓冲
 	if p.inKey {
 		p.keyBuffer = append(p.keyBuffer, content...)
 		return
 	}
 
+	// 延迟逗号之后的空白：等确定下一个字段保留后再与逗号一起输出
+	if p.pendingComma {
+		p.pendingSpace = append(p.pendingSpace, content...)
+		return
+	}
+
+	// 数组首个元素之前的空白暂存，读到元素（数字、布尔或 null）时再匹配
+	if p.awaitElement {
+		i := skipSpace(content, 0)
+		p.pendingSpace = append(p.pendingSpace, content[:i]...)
+		if i == len(content) {
+			return
+		}
+		p.awaitElement = false
+		p.beginArrayElement(w, p.pos+1)
+		p.handleContent(content[i:], w)
+		return
+	}
+
 	// 正常输出
 	w.Write(content)
 }
 
+// trackEscapes 根据字符串内容更新转义状态，只有末尾未配对的反斜杠会转义下一个结构字符
+func (p *PathProcessor) trackEscapes(content []byte) {
+	if !p.escaped && bytes.IndexByte(content, '\\') < 0 {
+		return
+	}
+	for _, b := range content {
+		if p.escaped {
+			p.escaped = false
+		} else if b == '\\' {
+			p.escaped = true
+		}
+	}
+}
+
+// flushPendingComma 输出延迟的逗号及其后的空白
+func (p *PathProcessor) flushPendingComma(w io.Writer) {
+	if !p.pendingComma {
+		return
+	}
+	w.Write([]byte{','})
+	w.Write(p.pendingSpace)
+	p.pendingComma = false
+	p.pendingSpace = p.pendingSpace[:0]
+}
+
 // handleStructural 处理结构字符
 func (p *PathProcessor) handleStructural(char byte, w io.Writer) {
 	// 跳过模式
@@ -195,6 +397,21 @@ func (p *PathProcessor) handleStructural(char byte, w io.Writer) {
 		return
 	}
 
+	// 数组首个元素：读到元素本身时才匹配，空数组没有元素，[0]、[*] 上的 set 等操作不生效
+	if p.awaitElement {
+		p.awaitElement = false
+		if char == ']' {
+			w.Write(p.pendingSpace)
+			p.pendingSpace = p.pendingSpace[:0]
+		} else {
+			p.beginArrayElement(w, p.pos)
+			if p.skipping {
+				p.handleStructural(char, w)
+				return
+			}
+		}
+	}
+
 	// 非字符串状态
 	switch char {
 	case '"':
@@ -202,6 +419,7 @@ func (p *PathProcessor) handleStructural(char byte, w io.Writer) {
 		p.escaped = false
 		if p.expectKey {
 			// 开始新 key
+			p.keyStart = p.pos
 			p.inKey = true
 			p.keyBuffer = p.keyBuffer[:0]
 			p.keyBuffer = append(p.keyBuffer, char)
@@ -215,11 +433,17 @@ func (p *PathProcessor) handleStructural(char byte, w io.Writer) {
 		if p.inKey {
 			p.inKey = false
 			key := extractKey(p.keyBuffer)
+			p.lastKey = key
+			if len(p.pathStack) > 0 {
+				p.pathStack[len(p.pathStack)-1].member = key
+			}
 
 			action := p.checkKeyMatch(key)
 			
 			// Remove: 跳过整个键值对（不输出key）
 			if action == ActionRemove {
+				p.markRemoved()
+				p.keyOverride = nil
 				p.skipping = true
 				p.skipState = skipState{depth: 0, inString: false, escaped: false}
 				p.expectKey = false
@@ -228,16 +452,22 @@ func (p *PathProcessor) handleStructural(char byte, w io.Writer) {
 			
 			// Set: 输出key，然后跳过原值并替换
 			// 非匹配: 正常输出key和值
-			if p.pendingComma {
-				w.Write([]byte{','})
-				p.pendingComma = false
+			p.flushPendingComma(w)
+			if p.keyOverride != nil {
+				w.Write(p.keyOverride)
+				p.keyOverride = nil
+			} else if p.normalizeKeys && bytes.IndexByte(p.keyBuffer, '\\') >= 0 {
+				p.outputBuf = appendQuotedKey(p.outputBuf[:0], key)
+				w.Write(p.outputBuf)
+				p.applied++
+			} else {
+				w.Write(p.keyBuffer)
 			}
-			w.Write(p.keyBuffer)
 			w.Write([]byte{char})
 			p.firstField = false
 			
-			// Set操作：标记需要跳过原值
-			if action == ActionSet {
+			// Set/Mask/Transform/Clamp/Capture操作：标记需要跳过原值
+			if action == ActionSet || action.transformsValue() || action == ActionCapture {
 				p.skipping = true
 				p.skipState = skipState{depth: 0, inString: false, escaped: false}
 			}
@@ -249,28 +479,21 @@ func (p *PathProcessor) handleStructural(char byte, w io.Writer) {
 		p.expectKey = false
 
 	case '{':
-		if p.pendingComma {
-			w.Write([]byte{','})
-			p.pendingComma = false
-		}
+		p.flushPendingComma(w)
 		w.Write([]byte{char})
 
 		// 进入对象：使用最近匹配的 AC 节点（如果有 key），否则使用当前节点
-		var acNode *ACNode
-		if p.lastMatchNode != nil {
-			acNode = p.lastMatchNode
-			p.lastMatchNode = nil // 清除，避免影响后续
-		} else {
-			acNode = p.currentACNode()
-		}
+		acNode, keepAll := p.nextContainerState()
 
 		// ⚡ 关键修复：在 append 之前调用 registerPendingAdds
 		// 此时 len(pathStack) 才是正确的深度
 		p.registerPendingAdds(acNode)
 
 		entry := pathEntry{
+			key:     p.lastKey,
 			isArray: false,
 			acNode:  acNode,
+			keepAll: keepAll,
 		}
 		p.pathStack = append(p.pathStack, entry)
 		p.expectKey = true
@@ -280,63 +503,58 @@ func (p *PathProcessor) handleStructural(char byte, w io.Writer) {
 		// 退出对象：处理待添加字段
 		p.handleObjectEnd(w)
 		
-		if len(p.pathStack) > 0 {
-			p.pathStack = p.pathStack[:len(p.pathStack)-1]
-		}
+		p.popContainer()
 		w.Write([]byte{char})
 		p.expectKey = false
 		p.pendingComma = false
+		p.pendingSpace = p.pendingSpace[:0]
+		// 容器本身是父对象中已输出的字段值，进入容器时重置的 firstField 需要恢复
+		p.firstField = false
 
 	case '[':
-		if p.pendingComma {
-			w.Write([]byte{','})
-			p.pendingComma = false
-		}
+		p.flushPendingComma(w)
 		w.Write([]byte{char})
 
 		// 进入数组：使用最近匹配的 AC 节点（如果有 key），否则使用当前节点
-		var acNode *ACNode
-		if p.lastMatchNode != nil {
-			acNode = p.lastMatchNode
-			p.lastMatchNode = nil // 清除，避免影响后续
-		} else {
-			acNode = p.currentACNode()
-		}
+		acNode, keepAll := p.nextContainerState()
 		entry := pathEntry{
+			key:      p.lastKey,
 			isArray:  true,
 			arrayIdx: 0,
+			arrayLen: p.nextArrayLen(),
 			acNode:   acNode,
+			keepAll:  keepAll,
 		}
 		p.pathStack = append(p.pathStack, entry)
 		p.expectKey = false
 
-		// 检查数组元素匹配
-		p.checkArrayElementMatch()
+		// 首个元素等读到元素内容时再检查匹配
+		p.awaitElement = true
 
 	case ']':
 		// 退出数组
-		if len(p.pathStack) > 0 {
-			p.pathStack = p.pathStack[:len(p.pathStack)-1]
-		}
+		p.popContainer()
 		w.Write([]byte{char})
 		p.expectKey = false
 		p.pendingComma = false
+		p.pendingSpace = p.pendingSpace[:0]
+		// 容器本身是父对象中已输出的字段值，进入容器时重置的 firstField 需要恢复
+		p.firstField = false
 
 	case ',':
 		// 处理逗号
 		if len(p.pathStack) > 0 {
 			top := &p.pathStack[len(p.pathStack)-1]
 			if top.isArray {
-				// 数组内逗号：增加索引
+				// 数组内逗号：增加索引，逗号是否输出由新元素是否保留决定
 				top.arrayIdx++
-				w.Write([]byte{char})
-				// 检查新数组元素匹配
-				p.checkArrayElementMatch()
+				p.beginArrayElement(w, p.pos+1)
 			} else {
 				// 对象内逗号：只有前面有输出字段时才设置 pendingComma
 				if !p.firstField {
 					p.pendingComma = true
 				}
+				p.pendingSpace = p.pendingSpace[:0]
 				p.expectKey = true
 			}
 		} else {
@@ -348,13 +566,15 @@ func (p *PathProcessor) handleStructural(char byte, w io.Writer) {
 	}
 }
 
-// extractKey 从带引号的 key 缓冲提取实际 key
+// extractKey 从带引号的 key 缓冲提取实际 key，转义序列解码后再参与匹配
 func extractKey(buf []byte) string {
+	// 结束引号与冒号之间的空白也累积在缓冲中
+	buf = bytes.TrimRight(buf, " \t\r\n")
 	if len(buf) < 2 {
 		return ""
 	}
 	// 去掉首尾引号
-	return string(buf[1 : len(buf)-1])
+	return decodeKey(buf[1 : len(buf)-1])
 }
 
 // checkKeyMatch 检查 key 是否匹配规则（remove/set/add）
@@ -372,41 +592,168 @@ func (p *PathProcessor) checkKeyMatch(key string) Action {
 		currentNode = p.matcher.Root()
 	}
 
-	// 匹配
-	nextNode, actions := p.matcher.Match(currentNode, key, false, 0)
+	// 匹配（冲突解析需要时收集同级所有匹配节点上的规则）
+	var nextNode *ACNode
+	var actions []RuleAction
+	var ranks []int
+	if p.matcher.collectsCandidates() {
+		nextNode, actions = p.matchCandidates(currentNode, key, false, 0, -1)
+		ranks = p.candidateRanks
+	} else {
+		nextNode, actions = p.matcher.Match(currentNode, key, false, 0)
+	}
+
+	// 字段已存在：取消对应的待添加字段（add 只在字段缺失时生效）
+	if p.hasAddRules {
+		p.dropPendingAdd(key)
+	}
 
 	// 保存匹配结果，用于进入子对象时（不更新当前对象的 acNode）
 	p.lastMatchNode = nextNode
 
-	// 检查匹配的操作（优先级：Remove > Set）
-	// Add 操作在对象结束时统一处理，不在这里处理
-	for _, action := range actions {
-		switch action.Action {
-		case ActionRemove:
-			p.setValue = nil // remove 操作：跳过后不输出任何内容
+	// 白名单模式：不在任何 keep 路径上的字段直接删除
+	if p.matcher.keepRules {
+		keep, keepAll := p.checkKeep(nextNode)
+		if !keep {
+			p.setValue = nil
+			p.lastMatchKeep = false
+			p.countApplied(ActionRemove)
+			if p.explain != nil {
+				p.explainMatch(ActionRemove, nil, p.fieldPointer(key), p.keyStart, true)
+			}
+			if p.onMatch != nil {
+				p.emitMatch(ActionRemove, nil, p.fieldPointer(key), true)
+			}
 			return ActionRemove
-		case ActionSet:
-			// set 操作：跳过原值后输出新值（优先使用预验证的ValueBytes）
-			if len(action.ValueBytes) > 0 {
-				p.setValue = action.ValueBytes // 零拷贝：直接使用预验证JSON
-			} else {
-				p.setValue = marshalValue(action.Value) // 后备：运行时序列化
+		}
+		p.lastMatchKeep = keepAll
+	}
+
+	// rename 只改变输出的 key，可与其他操作同时生效
+	for _, action := range actions {
+		if action.Action == ActionRename {
+			p.keyOverride = action.ValueBytes
+			p.countApplied(ActionRename)
+			if p.explain != nil {
+				r := p.explainMatch(ActionRename, []int{action.Index}, p.fieldPointer(key), p.keyStart, false)
+				r.Target, _ = p.matcher.rules[action.Index].Value.(string)
 			}
-			return ActionSet
+			if p.onMatch != nil {
+				p.emitMatch(ActionRename, []int{action.Index}, p.fieldPointer(key), false)
+			}
+			break
+		}
+	}
+
+	// capture 只记录原值，与其他操作同时生效
+	p.matchCaptures(actions)
+
+	// 按冲突解析方式选出作用于值的操作（默认：priority 高者优先，其次 Remove > Set > Mask/Transform/Clamp）
+	// Add 操作在对象结束时统一处理，不在这里处理
+	action, ok := p.resolveValueAction(actions, ranks)
+	if ok && action.expr != nil {
+		action.ValueBytes, ok = action.expr.eval(p.lookupExprRef)
+	}
+	if !ok {
+		if p.capturing {
+			p.beginCapture()
+			return ActionCapture
 		}
+		return ""
+	}
+	if p.explain != nil {
+		p.explainValueAction(action, p.fieldPointer(key), p.keyStart)
+	}
+	if p.onMatch != nil {
+		p.emitMatch(action.Action, p.resolved, p.fieldPointer(key), true)
+	}
+	switch action.Action {
+	case ActionRemove:
+		p.setValue = nil // remove 操作：跳过后不输出任何内容
+		p.countApplied(ActionRemove)
+		return ActionRemove
+	case ActionSet:
+		// set 操作：跳过原值后输出新值（优先使用预验证的ValueBytes）
+		if len(action.ValueBytes) > 0 {
+			p.setValue = action.ValueBytes // 零拷贝：直接使用预验证JSON
+		} else {
+			p.setValue = marshalValue(action.Value) // 后备：运行时序列化
+		}
+		p.countApplied(ActionSet)
+		return ActionSet
+	}
+	p.beginTransform(action)
+	return action.Action
+}
+
+// beginTransform 进入值转换模式：跳过原值并收集其原始字节
+// 单独生效的 truncate、strip_base64 不收集原值，改为边读边输出（见 writeStreamed）
+func (p *PathProcessor) beginTransform(action RuleAction) {
+	p.setValue = nil
+	p.countApplied(action.Action)
+	switch {
+	case action.truncate != nil:
+		p.truncator.reset(action.truncate)
+		p.streamer = &p.truncator
+	case action.strip != nil:
+		p.stripper.reset(action.strip)
+		p.streamer = &p.stripper
+	default:
+		p.streamer = nil
+	}
+	if p.streamer != nil {
+		p.streaming = true
+		p.streamBuf = p.streamBuf[:0]
+		return
+	}
+	p.transforming = true
+	p.transform = action.Transform
+	p.transformBuf = p.transformBuf[:0]
+}
+
+// writeStreamed 输出原值下一段转换后的内容
+func (p *PathProcessor) writeStreamed(data []byte, w io.Writer) {
+	n := len(p.streamBuf)
+	p.streamBuf = p.streamer.append(p.streamBuf, data)
+	w.Write(p.streamBuf[n:])
+	if p.explain == nil {
+		p.streamBuf = p.streamBuf[:0]
 	}
-	return ""
+}
+
+// beginArrayElement 开始处理新的数组元素，start 为元素之前（可能含空白）的输入偏移
+// 元素被删除时不输出逗号；保留时仅在前面已有输出元素时补逗号，
+// 从而避免 [a,,c]、[,b] 或 [a,] 这类非法输出
+// 首个元素之前暂存的空白随元素一起保留或删除
+func (p *PathProcessor) beginArrayElement(w io.Writer, start int) {
+	removed := p.checkArrayElementMatch(start)
+	space := p.pendingSpace
+	p.pendingSpace = p.pendingSpace[:0]
+	if removed {
+		p.markRemoved()
+	}
+	if removed || len(p.pathStack) == 0 {
+		return
+	}
+
+	top := &p.pathStack[len(p.pathStack)-1]
+	if top.emitted > 0 {
+		w.Write([]byte{','})
+	}
+	w.Write(space)
+	top.emitted++
 }
 
 // checkArrayElementMatch 检查数组元素匹配
-func (p *PathProcessor) checkArrayElementMatch() {
+// 返回 true 表示该元素被删除
+func (p *PathProcessor) checkArrayElementMatch(start int) bool {
 	if p.matcher == nil || len(p.pathStack) == 0 {
-		return
+		return false
 	}
 
 	top := &p.pathStack[len(p.pathStack)-1]
 	if !top.isArray {
-		return
+		return false
 	}
 
 	// 使用数组 entry 的 acNode 来匹配数组元素
@@ -417,31 +764,82 @@ func (p *PathProcessor) checkArrayElementMatch() {
 	}
 
 	// 匹配数组元素（[*] 或 [n]）
-	nextNode, actions := p.matcher.Match(parentNode, "", true, top.arrayIdx)
+	var nextNode *ACNode
+	var actions []RuleAction
+	var ranks []int
+	if p.matcher.collectsCandidates() {
+		nextNode, actions = p.matchCandidates(parentNode, "", true, top.arrayIdx, top.arrayLen)
+		ranks = p.candidateRanks
+	} else {
+		nextNode, actions = p.matcher.MatchElement(parentNode, "", true, top.arrayIdx, top.arrayLen)
+	}
 
 	// 保存匹配结果，用于数组元素内的对象/数组
 	p.lastMatchNode = nextNode
 
-	// 检查匹配的操作
-	for _, action := range actions {
-		switch action.Action {
-		case ActionRemove:
+	// 白名单模式：不在任何 keep 路径上的元素与字段一样直接删除
+	if p.matcher.keepRules {
+		keep, keepAll := p.checkKeep(nextNode)
+		if !keep {
 			p.skipping = true
-			p.skipState = skipState{depth: 0, inString: false, escaped: false}
+			p.skipState = skipState{}
 			p.setValue = nil
-			return
-		case ActionSet:
-			// 数组元素Set：跳过原值后输出新值
-			if len(action.ValueBytes) > 0 {
-				p.setValue = action.ValueBytes
-			} else {
-				p.setValue = marshalValue(action.Value)
+			p.lastMatchKeep = false
+			p.countApplied(ActionRemove)
+			if p.explain != nil {
+				p.explainMatch(ActionRemove, nil, p.elementPointer(), skipSpace(p.explain.input, start), true)
+			}
+			if p.onMatch != nil {
+				p.emitMatch(ActionRemove, nil, p.elementPointer(), true)
 			}
+			return true
+		}
+		p.lastMatchKeep = keepAll
+	}
+
+	// 检查匹配的操作
+	p.matchCaptures(actions)
+	action, ok := p.resolveValueAction(actions, ranks)
+	if ok && action.expr != nil {
+		action.ValueBytes, ok = action.expr.eval(p.lookupExprRef)
+	}
+	if !ok {
+		if p.capturing {
+			p.beginCapture()
 			p.skipping = true
-			p.skipState = skipState{depth: 0, inString: false, escaped: false}
-			return
+			p.skipState = skipState{}
+		}
+		return false
+	}
+	if p.explain != nil {
+		p.explainValueAction(action, p.elementPointer(), skipSpace(p.explain.input, start))
+	}
+	if p.onMatch != nil {
+		p.emitMatch(action.Action, p.resolved, p.elementPointer(), true)
+	}
+	switch action.Action {
+	case ActionRemove:
+		p.skipping = true
+		p.skipState = skipState{depth: 0, inString: false, escaped: false}
+		p.setValue = nil
+		p.countApplied(ActionRemove)
+		return true
+	case ActionSet:
+		// 数组元素Set：跳过原值后输出新值
+		if len(action.ValueBytes) > 0 {
+			p.setValue = action.ValueBytes
+		} else {
+			p.setValue = marshalValue(action.Value)
 		}
+		p.countApplied(ActionSet)
+		p.skipping = true
+		p.skipState = skipState{depth: 0, inString: false, escaped: false}
+		return false
 	}
+	p.beginTransform(action)
+	p.skipping = true
+	p.skipState = skipState{depth: 0, inString: false, escaped: false}
+	return false
 }
 
 // handleSkipChar 处理跳过模式下的字符
@@ -449,6 +847,17 @@ func (p *PathProcessor) checkArrayElementMatch() {
 func (p *PathProcessor) handleSkipChar(char byte, w io.Writer) bool {
 	sk := &p.skipState
 
+	// 值转换模式收集原值字节（简单值的结束符不属于值本身）
+	if p.transforming || p.capturing || p.matchBuffering || p.streaming {
+		isTerminator := !sk.escaped && !sk.inString && sk.depth == 0 && (char == ',' || char == '}' || char == ']')
+		if !isTerminator && p.streaming {
+			p.writeStreamed([]byte{char}, w)
+		}
+		if !isTerminator && (p.transforming || p.capturing || p.matchBuffering) {
+			p.transformBuf = append(p.transformBuf, char)
+		}
+	}
+
 	if sk.escaped {
 		sk.escaped = false
 		return false
@@ -471,7 +880,10 @@ func (p *PathProcessor) handleSkipChar(char byte, w io.Writer) bool {
 	switch char {
 	case '"':
 		sk.inString = true
-	case '{', '[':
+	case '[':
+		sk.depth++
+		p.arrayOrdinal++ // 被跳过的数组也要计数，保持与预统计的顺序一致
+	case '{':
 		sk.depth++
 	case '}', ']':
 		if sk.depth > 0 {
@@ -488,13 +900,13 @@ func (p *PathProcessor) handleSkipChar(char byte, w io.Writer) bool {
 	case ',':
 		if sk.depth == 0 {
 			// 简单值结束
-			isSet := p.setValue != nil
+			isSet := p.setValue != nil || p.transforming || p.streaming
 			p.finishSkipValue(w)
-			if isSet {
-				// Set操作：逗号需要重新处理（正常输出）
+			if isSet || p.inArray() {
+				// Set操作/数组元素：逗号需要重新处理（正常输出或推进数组索引）
 				return true
 			}
-			// Remove操作：逗号被消费（不输出）
+			// 对象字段Remove操作：逗号被消费（不输出）
 		}
 	}
 	return false
@@ -506,14 +918,57 @@ func (p *PathProcessor) finishSkipValue(w io.Writer) {
 	p.skipping = false
 	p.skipState = skipState{}
 
+	// 规则生效回调：发出等待原值的事件
+	if p.matchBuffering {
+		p.finishMatchValue()
+	}
+
+	// capture 操作：记录原值（在转换之前）
+	if p.capturing {
+		p.finishCapture()
+	}
+
+	// mask/transform/clamp 操作：输出转换后的原值
+	if p.transforming {
+		out := p.transformBuf
+		if p.transform != nil {
+			out = p.transform(p.transformBuf)
+		}
+		w.Write(out)
+		if p.explain != nil {
+			p.explainValueEnd(out)
+		}
+		p.transforming = false
+		p.transform = nil
+		p.transformBuf = p.transformBuf[:0]
+	}
+
+	// truncate/strip_base64 操作：转换结果已随原值输出
+	if p.streaming {
+		if p.explain != nil {
+			p.explainValueEnd(p.streamBuf)
+		}
+		p.streaming = false
+		p.streamer = nil
+		p.streamBuf = p.streamBuf[:0]
+	}
+
 	// set 操作：输出新值
 	if p.setValue != nil {
 		w.Write(p.setValue)
+		if p.explain != nil {
+			p.explainValueEnd(p.setValue)
+		}
 		p.setValue = nil
 		// 注意：不在这里设置pendingComma
 		// 逗号由后续的逗号字符或对象字段输出逻辑处理
 	}
 
+	// remove 操作：补全原值
+	if p.explain != nil {
+		p.explainValueEnd(nil)
+	}
+
 	// 准备下一个字段
 	if len(p.pathStack) > 0 {
 		top := &p.pathStack[len(p.pathStack)-1]
@@ -537,6 +992,116 @@ func (p *PathProcessor) finishSkip() {
 	}
 }
 
+// checkKeep 白名单模式下判断当前字段是否保留
+// 返回 keep 表示字段保留，keepAll 表示其整个子树原样保留
+// 只认可与当前深度对齐的匹配（失败指针回退得到的浅层节点不算）
+func (p *PathProcessor) checkKeep(node *ACNode) (keep bool, keepAll bool) {
+	if p.inKeptSubtree() {
+		return true, true
+	}
+	if node == nil || node.depth != len(p.pathStack) {
+		return false, false
+	}
+	return node.keepPrefix, node.keepTerminal
+}
+
+// SetArrayLengths 设置预先统计的数组长度（见 CountArrayLengths）
+// 设置后负索引 [-n] 和负切片边界才能匹配
+func (p *PathProcessor) SetArrayLengths(lens []int) {
+	p.arrayLens = lens
+	p.arrayOrdinal = 0
+}
+
+// nextArrayLen 进入新数组时取出其预统计长度（未统计时返回 -1）
+func (p *PathProcessor) nextArrayLen() int {
+	ordinal := p.arrayOrdinal
+	p.arrayOrdinal++
+	if ordinal < len(p.arrayLens) {
+		return p.arrayLens[ordinal]
+	}
+	return -1
+}
+
+// CountArrayLengths 统计 JSON 中每个数组的元素个数，按 '[' 出现顺序返回
+func CountArrayLengths(data []byte) []int {
+	var lens []int
+	var stack []int // 数组在 lens 中的下标；对象为 -1
+	expectElem := false
+	inString, escaped := false, false
+
+	for _, b := range data {
+		if inString {
+			if escaped {
+				escaped = false
+			} else if b == '\\' {
+				escaped = true
+			} else if b == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case ',':
+			if len(stack) > 0 && stack[len(stack)-1] >= 0 {
+				expectElem = true
+			}
+			continue
+		case ']', '}':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			expectElem = false
+			continue
+		case ':':
+			continue
+		}
+
+		// 值的开始：若直接位于数组内且正在等待元素，则计数
+		if expectElem && len(stack) > 0 && stack[len(stack)-1] >= 0 {
+			lens[stack[len(stack)-1]]++
+		}
+		expectElem = false
+
+		switch b {
+		case '"':
+			inString = true
+		case '[':
+			stack = append(stack, len(lens))
+			lens = append(lens, 0)
+			expectElem = true
+		case '{':
+			stack = append(stack, -1)
+		}
+	}
+
+	return lens
+}
+
+// inArray 检查当前是否直接位于数组内
+func (p *PathProcessor) inArray() bool {
+	return len(p.pathStack) > 0 && p.pathStack[len(p.pathStack)-1].isArray
+}
+
+// inKeptSubtree 检查当前是否位于 keep 规则终点的子树内
+func (p *PathProcessor) inKeptSubtree() bool {
+	return len(p.pathStack) > 0 && p.pathStack[len(p.pathStack)-1].keepAll
+}
+
+// nextContainerState 计算即将进入的对象/数组的 AC 状态和白名单状态
+// 优先使用最近匹配的 AC 节点（如果有 key），否则沿用当前节点
+func (p *PathProcessor) nextContainerState() (*ACNode, bool) {
+	if p.lastMatchNode != nil {
+		acNode, keepAll := p.lastMatchNode, p.lastMatchKeep
+		p.lastMatchNode = nil // 清除，避免影响后续
+		p.lastMatchKeep = false
+		return acNode, keepAll
+	}
+	return p.currentACNode(), p.inKeptSubtree()
+}
+
 // currentACNode 获取当前 AC 节点
 func (p *PathProcessor) currentACNode() *ACNode {
 	if p.matcher == nil {
@@ -548,14 +1113,40 @@ func (p *PathProcessor) currentACNode() *ACNode {
 	return p.matcher.Root()
 }
 
+// Applied 返回本次处理中实际生效的操作次数（删除、修改、添加、转换、重命名）
+func (p *PathProcessor) Applied() int {
+	return p.applied
+}
+
 // Finish 完成处理（处理跨 chunk 的未完成状态）
 func (p *PathProcessor) Finish(w io.Writer) error {
 	if p.skipping {
 		p.skipping = false
 	}
+	if p.removeEmptyParents && p.pruner.w != nil {
+		return p.pruner.finish()
+	}
 	return nil
 }
 
+// markRemoved 记录当前容器有成员被删除
+func (p *PathProcessor) markRemoved() {
+	if len(p.pathStack) > 0 {
+		p.pathStack[len(p.pathStack)-1].removed = true
+	}
+}
+
+// popContainer 退出当前容器；删除空容器时告知 pruner 即将输出的结束符所属容器是否有成员被删除
+func (p *PathProcessor) popContainer() {
+	if len(p.pathStack) == 0 {
+		return
+	}
+	if p.removeEmptyParents {
+		p.pruner.droppable = p.pathStack[len(p.pathStack)-1].removed
+	}
+	p.pathStack = p.pathStack[:len(p.pathStack)-1]
+}
+
 // marshalValue 将值序列化为 JSON 字节
 // 复用 SIMD 流式架构，避免引入 encoding/json 的反射开销
 func marshalValue(v any) []byte {
@@ -689,11 +1280,16 @@ func (p *PathProcessor) registerPendingAdds(acNode *ACNode) {
 	for key, childNode := range acNode.children {
 		// 检查子节点是否有Add操作
 		for _, action := range childNode.output {
-			if action.Action == ActionAdd {
+			if action.addsWhenMissing() {
 				// 获取规则，检查深度是否匹配
 				rule := p.matcher.rules[action.Index]
 				expectedDepth := len(rule.segments) - 1
 
+				// 数组索引子节点与字段共用 children，只有字段段（或 JSON Pointer 数字段的对象键分支）才能作为 key 添加
+				if last := rule.segments[expectedDepth]; last.Type != SegField && last.Key != key {
+					continue
+				}
+
 				// 只有当前深度匹配规则的目标深度时才添加
 				// 例如：path="key" (len=1) 只在 depth=0 添加
 				//       path="user.email" (len=2) 只在 depth=1 添加
@@ -716,6 +1312,8 @@ func (p *PathProcessor) registerPendingAdds(acNode *ACNode) {
 				p.pendingAdds[depth] = append(p.pendingAdds[depth], addAction{
 					key:   key,
 					value: value,
+					expr:  action.expr,
+					rule:  action.Index,
 				})
 			}
 		}
@@ -723,6 +1321,18 @@ func (p *PathProcessor) registerPendingAdds(acNode *ACNode) {
 
 }
 
+// dropPendingAdd 当前对象中出现了同名字段时，取消该字段的待添加操作
+func (p *PathProcessor) dropPendingAdd(key string) {
+	depth := len(p.pathStack) - 1
+	adds := p.pendingAdds[depth]
+	for i, add := range adds {
+		if add.key == key {
+			p.pendingAdds[depth] = append(adds[:i], adds[i+1:]...)
+			return
+		}
+	}
+}
+
 // handleObjectEnd 退出对象时插入待添加字段
 func (p *PathProcessor) handleObjectEnd(w io.Writer) {
 	// ⚡ 修复：退出对象时，pathStack 还未 pop，所以深度是 len(pathStack)
@@ -741,19 +1351,40 @@ func (p *PathProcessor) handleObjectEnd(w io.Writer) {
 
 	// ⚡ 性能优化：直接添加字段，不做去重检查
 	// 如果 key 重复，让 JSON 解析器处理（后面的值会覆盖前面的）
-	for i, add := range adds {
+	written := 0
+	for _, add := range adds {
+		// 表达式在对象结束时求值，此时同一对象内引用的字段均已收集；无法求值时不添加
+		value := add.value
+		if add.expr != nil {
+			var ok bool
+			if value, ok = add.expr.eval(p.lookupExprRef); !ok {
+				continue
+			}
+		}
+
 		// 输出逗号（对象非空时需要逗号）
-		if !p.firstField || i > 0 {
+		if !p.firstField || written > 0 {
 			w.Write([]byte{','})
 		}
+		written++
 
-		// 输出 "key": value
-		w.Write([]byte{'"'})
-		w.Write([]byte(add.key))
-		w.Write([]byte{'"', ':'})
-		w.Write(add.value)
+		// 输出 "key": value（字面量段的键可含引号、反斜杠或控制字符，须转义）
+		w.Write(marshalString(add.key))
+		w.Write([]byte{':'})
+		w.Write(value)
+
+		if p.explain != nil {
+			rule := p.matcher.rules[add.rule]
+			r := p.explainMatch(rule.Action, []int{add.rule}, p.fieldPointer(add.key), p.pos, false)
+			r.After = append(json.RawMessage(nil), value...)
+		}
+		if p.onMatch != nil {
+			p.emitMatch(p.matcher.rules[add.rule].Action, []int{add.rule}, p.fieldPointer(add.key), false)
+		}
 	}
 
 	// 清理状态
+	p.applied += written
+	p.stats.Added += int64(written)
 	delete(p.pendingAdds, depth)
 }
@@ -0,0 +1,89 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// ValuePlugin 自定义值转换插件（ActionPlugin 规则使用）
+// TransformValue 收到命中字段的原始 JSON 值（已去除首尾空白），返回替换后的 JSON 值。
+// 可能被多个 goroutine 同时调用。返回错误或非法 JSON 时保留原值。
+type ValuePlugin interface {
+	TransformValue(value []byte) ([]byte, error)
+}
+
+// ValuePluginFunc 以函数实现 ValuePlugin
+type ValuePluginFunc func(value []byte) ([]byte, error)
+
+// TransformValue 调用函数本身
+func (f ValuePluginFunc) TransformValue(value []byte) ([]byte, error) {
+	return f(value)
+}
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   = make(map[string]ValuePlugin)
+)
+
+// RegisterValuePlugin 注册插件，规则的 Value 以 name 引用；同名插件被替换
+// 规则在编译时解析插件，替换插件只影响之后编译的规则
+func RegisterValuePlugin(name string, p ValuePlugin) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if p == nil {
+		delete(plugins, name)
+		return
+	}
+	plugins[name] = p
+}
+
+// ValuePlugins 返回已注册的插件名称（已排序）
+func ValuePlugins() []string {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupValuePlugin 按名称查找已注册的插件
+func lookupValuePlugin(name string) (ValuePlugin, bool) {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	p, ok := plugins[name]
+	return p, ok
+}
+
+// compilePlugin 解析 plugin 规则的插件：Value 为已注册的插件名，代码内置的规则也可以直接传入 ValuePlugin
+func compilePlugin(rule PathRule) (func([]byte) []byte, error) {
+	var p ValuePlugin
+	switch v := rule.Value.(type) {
+	case string:
+		if v == "" {
+			return nil, &PathError{Msg: "plugin requires the plugin name as value"}
+		}
+		var ok bool
+		if p, ok = lookupValuePlugin(v); !ok {
+			return nil, &PathError{Msg: "unknown plugin: " + v}
+		}
+	case ValuePlugin:
+		if v == nil {
+			return nil, &PathError{Msg: "plugin is nil"}
+		}
+		p = v
+	default:
+		return nil, &PathError{Msg: "plugin requires the plugin name as value"}
+	}
+	return func(raw []byte) []byte {
+		out, err := p.TransformValue(bytes.TrimSpace(raw))
+		if err != nil || !json.Valid(out) {
+			return raw
+		}
+		return out
+	}, nil
+}
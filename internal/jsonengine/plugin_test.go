@@ -0,0 +1,42 @@
+package jsonengine
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPathEnginePluginAction(t *testing.T) {
+	RegisterValuePlugin("test-upper", ValuePluginFunc(func(value []byte) ([]byte, error) {
+		if len(value) == 0 || value[0] != '"' {
+			return nil, errors.New("not a string")
+		}
+		return bytes.ToUpper(value), nil
+	}))
+	RegisterValuePlugin("test-invalid", ValuePluginFunc(func([]byte) ([]byte, error) {
+		return []byte(`{"unterminated`), nil
+	}))
+	defer RegisterValuePlugin("test-upper", nil)
+	defer RegisterValuePlugin("test-invalid", nil)
+
+	engine, err := NewPathEngine([]PathRule{
+		{Path: "a", Action: ActionPlugin, Value: "test-upper"},
+		{Path: "n", Action: ActionPlugin, Value: "test-upper"},
+		{Path: "b", Action: ActionPlugin, Value: "test-invalid"},
+		{Path: "c", Action: ActionPlugin, Value: ValuePluginFunc(func([]byte) ([]byte, error) { return []byte(`[1]`), nil })},
+	}, WithCompact())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := engine.ProcessBytes([]byte(`{"a": "secret", "n": 1, "b": "keep", "c": {"x": 1}}`))
+	want := `{"a":"SECRET","n":1,"b":"keep","c":[1]}`
+	if err != nil || string(out) != want {
+		t.Errorf("got %s, %v; want %s", out, err, want)
+	}
+
+	for _, value := range []any{"missing", "", 1} {
+		if _, err := NewPathEngine([]PathRule{{Path: "a", Action: ActionPlugin, Value: value}}); err == nil {
+			t.Errorf("value %v: expected an error", value)
+		}
+	}
+}
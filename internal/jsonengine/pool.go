@@ -1,12 +1,21 @@
 package jsonengine
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 const (
 	// DefaultPositionsCap SIMD 扫描位置缓冲区默认容量
 	// 512KB / 4 (平均每 4 字节一个结构字符) = 128K
 	DefaultPositionsCap = 128 * 1024
 
+	// MinPositionsCap 位置缓冲区容量下限
+	MinPositionsCap = 1024
+
+	// initialPositionsCap 新处理器的位置缓冲区初始容量，处理较大的数据块时按需增长到 PositionsCap
+	initialPositionsCap = 4 * 1024
+
 	// DefaultPathStackCap 路径栈默认容量
 	DefaultPathStackCap = 32
 
@@ -14,28 +23,146 @@ const (
 	DefaultKeyBufferCap = 256
 )
 
-// PathProcessorPool 路径处理器对象池
-var PathProcessorPool = sync.Pool{
-	New: func() interface{} {
-		return &PathProcessor{
-			positions:  make([]uint32, DefaultPositionsCap),
-			pathStack:  make([]pathEntry, 0, DefaultPathStackCap),
-			keyBuffer:  make([]byte, 0, DefaultKeyBufferCap),
-			outputBuf:  make([]byte, 0, 4096),
+// ProcessorPoolConfig 处理器池配置，见 ConfigureProcessorPool
+type ProcessorPoolConfig struct {
+	// PositionsCap 每个处理器扫描位置缓冲区的最大容量（条目数，每条 4 字节），0 表示 DefaultPositionsCap
+	// 缓冲区按处理过的最大数据块增长到该容量；更大的数据块拆分处理，结果不变，只是扫描次数增加
+	PositionsCap int
+	// MaxIdle 最多保留的空闲处理器数，超出时归还的处理器直接丢弃
+	// 0 表示不限制，空闲处理器由 sync.Pool 保留到下一次 GC
+	MaxIdle int
+}
+
+// ProcessorPoolStats 处理器池的累计统计和当前状态
+type ProcessorPoolStats struct {
+	Hits         int64 `json:"hits"`          // 复用了空闲处理器的获取次数
+	Misses       int64 `json:"misses"`        // 新建处理器的获取次数
+	Drops        int64 `json:"drops"`         // 因池已满或配置变更而丢弃的归还次数
+	InUse        int64 `json:"in_use"`        // 当前借出的处理器数
+	Idle         int   `json:"idle"`          // 有界池当前的空闲处理器数，无界池为 -1（sync.Pool 无法统计）
+	PositionsCap int   `json:"positions_cap"` // 当前的位置缓冲区容量上限
+	MaxIdle      int   `json:"max_idle"`      // 当前的空闲处理器上限，0 表示不限制
+}
+
+// processorPool 按一份配置创建的处理器池，配置变更时整体替换
+type processorPool struct {
+	positionsCap int
+	maxIdle      int
+	unbounded    sync.Pool           // maxIdle == 0
+	idle         chan *PathProcessor // maxIdle > 0
+}
+
+var (
+	processorPoolMu sync.Mutex
+	activePool      atomic.Pointer[processorPool]
+
+	poolHits   atomic.Int64
+	poolMisses atomic.Int64
+	poolDrops  atomic.Int64
+	poolInUse  atomic.Int64
+)
+
+func init() {
+	activePool.Store(newProcessorPool(ProcessorPoolConfig{}))
+}
+
+func newProcessorPool(cfg ProcessorPoolConfig) *processorPool {
+	pool := &processorPool{positionsCap: cfg.PositionsCap, maxIdle: max(cfg.MaxIdle, 0)}
+	if pool.positionsCap <= 0 {
+		pool.positionsCap = DefaultPositionsCap
+	}
+	pool.positionsCap = max(pool.positionsCap, MinPositionsCap)
+	if pool.maxIdle > 0 {
+		pool.idle = make(chan *PathProcessor, pool.maxIdle)
+	}
+	return pool
+}
+
+// ConfigureProcessorPool 替换处理器池配置，可在运行时调用
+// 旧池中的空闲处理器被丢弃，借出中的处理器归还时按新配置丢弃，不影响正在进行的处理
+func ConfigureProcessorPool(cfg ProcessorPoolConfig) {
+	processorPoolMu.Lock()
+	defer processorPoolMu.Unlock()
+
+	next := newProcessorPool(cfg)
+	current := activePool.Load()
+	if current.positionsCap == next.positionsCap && current.maxIdle == next.maxIdle {
+		return
+	}
+	activePool.Store(next)
+}
+
+// ProcessorPoolStatistics 返回处理器池统计
+func ProcessorPoolStatistics() ProcessorPoolStats {
+	pool := activePool.Load()
+	idle := -1
+	if pool.idle != nil {
+		idle = len(pool.idle)
+	}
+	return ProcessorPoolStats{
+		Hits:         poolHits.Load(),
+		Misses:       poolMisses.Load(),
+		Drops:        poolDrops.Load(),
+		InUse:        poolInUse.Load(),
+		Idle:         idle,
+		PositionsCap: pool.positionsCap,
+		MaxIdle:      pool.maxIdle,
+	}
+}
+
+func (pool *processorPool) get() *PathProcessor {
+	var p *PathProcessor
+	if pool.idle != nil {
+		select {
+		case p = <-pool.idle:
+		default:
 		}
-	},
+	} else if v := pool.unbounded.Get(); v != nil {
+		p = v.(*PathProcessor)
+	}
+	if p == nil {
+		poolMisses.Add(1)
+		p = &PathProcessor{
+			positions: make([]uint32, min(initialPositionsCap, pool.positionsCap)),
+			pathStack: make([]pathEntry, 0, DefaultPathStackCap),
+			keyBuffer: make([]byte, 0, DefaultKeyBufferCap),
+			outputBuf: make([]byte, 0, 4096),
+		}
+	} else {
+		poolHits.Add(1)
+	}
+	p.positionsCap = pool.positionsCap
+	return p
+}
+
+func (pool *processorPool) put(p *PathProcessor) {
+	// 配置变更前借出的处理器可能带着超出新上限的缓冲区
+	if len(p.positions) > pool.positionsCap {
+		poolDrops.Add(1)
+		return
+	}
+	if pool.idle == nil {
+		pool.unbounded.Put(p)
+		return
+	}
+	select {
+	case pool.idle <- p:
+	default:
+		poolDrops.Add(1)
+	}
 }
 
 // GetPathProcessor 从池中获取处理器
 func GetPathProcessor(matcher *PathMatcher) *PathProcessor {
-	p := PathProcessorPool.Get().(*PathProcessor)
+	p := activePool.Load().get()
+	poolInUse.Add(1)
 	p.matcher = matcher
 	
 	// ⚡ 性能优化：检查是否有 Add 规则（只在初始化时检查一次）
 	p.hasAddRules = false
 	if matcher != nil {
 		for _, rule := range matcher.rules {
-			if rule.Action == ActionAdd {
+			if rule.Action == ActionAdd || (rule.Action == ActionClamp && rule.Default != nil) {
 				p.hasAddRules = true
 				break
 			}
@@ -52,11 +179,20 @@ func PutPathProcessor(p *PathProcessor) {
 		return
 	}
 	p.matcher = nil
+	p.normalizeKeys = false
+	p.limits = limits{}
+	p.candidates = p.candidates[:0]
+	p.ordered = p.ordered[:0]
+	p.explain = nil
+	p.progress = nil
+	p.onMatch = nil
+	p.matchValues = false
 	// 清理可能的大缓冲区引用
 	p.pathStack = p.pathStack[:0]
 	p.keyBuffer = p.keyBuffer[:0]
 	p.outputBuf = p.outputBuf[:0]
-	PathProcessorPool.Put(p)
+	poolInUse.Add(-1)
+	activePool.Load().put(p)
 }
 
 // bytesPool 字节切片池（用于临时缓冲）
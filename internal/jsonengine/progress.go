@@ -0,0 +1,53 @@
+package jsonengine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Progress 处理进度快照
+type Progress struct {
+	BytesProcessed int64  // 已处理的输入字节数
+	Depth          int    // 当前嵌套深度（0 表示顶层之外）
+	Path           string // 当前位置的 JSON Pointer，如 /messages/3/content
+	Applied        int    // 目前已生效的操作次数
+	InString       bool   // 是否停在字符串内
+	Skipping       bool   // 是否正在跳过被删除或替换的值
+}
+
+// WithProgress 设置进度回调，每处理完一个数据块调用一次
+// 可用于长时间转换的进度上报，或由看门狗检测长时间无进展的状态机（如 Depth/Skipping 不再变化）。
+// 回调在处理 goroutine 中同步执行，不应阻塞；无规则原样透传时不调用。
+func WithProgress(fn func(Progress)) PathEngineOption {
+	return func(e *PathEngine) {
+		e.progress = fn
+	}
+}
+
+// Progress 返回当前处理进度，流式场景下可在 ProcessChunk 之间调用
+func (p *PathProcessor) Progress() Progress {
+	return Progress{
+		BytesProcessed: int64(p.consumed),
+		Depth:          len(p.pathStack),
+		Path:           p.currentPointer(),
+		Applied:        p.applied,
+		InString:       p.inString || p.skipState.inString,
+		Skipping:       p.skipping,
+	}
+}
+
+// currentPointer 返回当前位置的 JSON Pointer：对象取最近读取的字段，数组取当前元素
+func (p *PathProcessor) currentPointer() string {
+	var sb strings.Builder
+	for _, entry := range p.pathStack {
+		switch {
+		case entry.isArray:
+			sb.WriteByte('/')
+			sb.WriteString(strconv.Itoa(entry.arrayIdx))
+		case entry.member != "":
+			sb.WriteByte('/')
+			sb.WriteString(escapePointerToken(entry.member))
+		}
+	}
+	return sb.String()
+}
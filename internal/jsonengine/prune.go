@@ -0,0 +1,226 @@
+package jsonengine
+
+import "io"
+
+// WithRemoveEmptyParents 规则删除了对象或数组的全部成员时，连同这个变空的容器一起删除
+// 例如用 safetyRatings[*] 删除所有元素后输出中不再保留 "safetyRatings":[]；
+// 删除容器后其父容器也变空时继续向上删除，但顶层文档始终保留。
+// 输入中原本就是空的容器、以及 set 写入的空容器不受影响。
+// 成员是否保留要等到容器结束才能确定，期间其 key 和开括号会被暂存，暂存量与嵌套深度和 key 长度成正比。
+func WithRemoveEmptyParents() PathEngineOption {
+	return func(e *PathEngine) {
+		e.removeEmptyParents = true
+	}
+}
+
+// pruneFrame 输出中一个尚未结束的容器
+type pruneFrame struct {
+	isArray  bool
+	start    int  // 容器成员（含前导逗号和 key）在 buf 中的起始偏移
+	kept     int  // 已保留的成员数
+	nonEmpty bool // 已确定有成员保留，容器不会被删除
+	dropped  bool // 有子容器因变空被删除
+}
+
+// pruneWriter 删除因规则变空的容器的 writer，位于处理器输出之后
+// 按输出的 JSON 结构判断成员边界：成员的前导逗号和 key 先暂存，值是标量时立即放行，
+// 值是容器时继续暂存直到容器出现保留的成员或结束。处理器在输出容器的结束符前
+// 通过 droppable 标明该容器是否有成员被规则删除，状态可跨越多次 Write。
+type pruneWriter struct {
+	w   io.Writer
+	buf []byte // 暂存的输出
+
+	frames    []pruneFrame
+	pending   int  // 未确定是否保留的容器数
+	member    bool // 正在读取成员的前导部分（逗号、key），值尚未开始
+	memberAt  int  // 当前成员在 buf 中的起始偏移
+	expectKey bool
+	inString  bool
+	escaped   bool
+
+	droppable bool // 下一个结束符所属的容器有成员被规则删除
+	err       error
+}
+
+func (pw *pruneWriter) reset() {
+	pw.w = nil
+	pw.buf = pw.buf[:0]
+	pw.frames = pw.frames[:0]
+	pw.pending = 0
+	pw.member = false
+	pw.expectKey = false
+	pw.inString = false
+	pw.escaped = false
+	pw.droppable = false
+	pw.err = nil
+}
+
+// holding 是否有未确定的内容需要暂存
+func (pw *pruneWriter) holding() bool {
+	return pw.member || pw.pending > 0
+}
+
+func (pw *pruneWriter) Write(p []byte) (int, error) {
+	start := 0 // p 中尚未写出或暂存的起始位置
+	for i, c := range p {
+		if pw.inString {
+			switch {
+			case pw.escaped:
+				pw.escaped = false
+			case c == '\\':
+				pw.escaped = true
+			case c == '"':
+				pw.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\r', '\n', ':':
+			if c == ':' {
+				pw.expectKey = false
+			}
+		case ',':
+			top := pw.top()
+			if top == nil {
+				continue
+			}
+			pw.flushTo(p[start:i])
+			start = i + 1
+			if top.kept == 0 {
+				// 之前的成员全部被删除，逗号随之删除
+				pw.expectKey = !top.isArray
+				continue
+			}
+			pw.beginMember()
+			pw.buf = append(pw.buf, ',')
+			pw.expectKey = !top.isArray
+		case '{', '[':
+			pw.flushTo(p[start:i])
+			start = i
+			if !pw.member {
+				pw.beginMember()
+			}
+			pw.member = false
+			pw.frames = append(pw.frames, pruneFrame{isArray: c == '[', start: pw.memberAt})
+			pw.pending++
+			pw.expectKey = c == '{'
+		case '}', ']':
+			pw.flushTo(p[start : i+1])
+			start = i + 1
+			pw.closeFrame()
+		default:
+			if c == '"' {
+				pw.inString = true
+				if pw.expectKey {
+					if !pw.member {
+						pw.flushTo(p[start:i])
+						start = i
+						pw.beginMember()
+					}
+					continue
+				}
+			}
+			// 标量值：所在的成员和所有外层容器都会保留
+			if pw.holding() {
+				pw.flushTo(p[start:i])
+				start = i
+				if top := pw.top(); top != nil && (pw.member || !top.nonEmpty) {
+					top.kept++
+				}
+				pw.member = false
+				pw.release()
+			}
+		}
+	}
+	pw.flushTo(p[start:])
+	if pw.err != nil {
+		return 0, pw.err
+	}
+	return len(p), nil
+}
+
+// flushTo 暂存期间追加到 buf，否则直接写出
+func (pw *pruneWriter) flushTo(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	if pw.holding() {
+		pw.buf = append(pw.buf, data...)
+		return
+	}
+	if pw.err == nil {
+		_, pw.err = pw.w.Write(data)
+	}
+}
+
+func (pw *pruneWriter) top() *pruneFrame {
+	if len(pw.frames) == 0 {
+		return nil
+	}
+	return &pw.frames[len(pw.frames)-1]
+}
+
+// beginMember 开始暂存一个成员
+func (pw *pruneWriter) beginMember() {
+	pw.member = true
+	pw.memberAt = len(pw.buf)
+}
+
+// release 当前成员保留：所有外层容器随之确定保留，写出暂存的内容
+func (pw *pruneWriter) release() {
+	for i := range pw.frames {
+		pw.frames[i].nonEmpty = true
+	}
+	pw.pending = 0
+	pw.member = false
+	if len(pw.buf) > 0 && pw.err == nil {
+		_, pw.err = pw.w.Write(pw.buf)
+	}
+	pw.buf = pw.buf[:0]
+}
+
+// closeFrame 容器结束（结束符已暂存或写出）：没有保留成员且有成员被删除时整体删除
+func (pw *pruneWriter) closeFrame() {
+	droppable := pw.droppable
+	pw.droppable = false
+	if len(pw.frames) == 0 {
+		return
+	}
+	frame := pw.frames[len(pw.frames)-1]
+	pw.frames = pw.frames[:len(pw.frames)-1]
+	parent := pw.top()
+	pw.expectKey = false
+
+	if frame.nonEmpty {
+		if parent != nil {
+			parent.kept++
+		}
+		return
+	}
+	pw.pending--
+	if parent != nil && frame.kept == 0 && (droppable || frame.dropped) {
+		pw.buf = pw.buf[:frame.start]
+		parent.dropped = true
+		if !pw.holding() {
+			pw.release()
+		}
+		return
+	}
+	// 原本为空的容器（或顶层文档）照常保留
+	if parent != nil {
+		parent.kept++
+	}
+	pw.release()
+}
+
+// finish 输入结束时写出暂存的内容（输入不完整时）
+func (pw *pruneWriter) finish() error {
+	pw.member = false
+	pw.pending = 0
+	if len(pw.buf) > 0 && pw.err == nil {
+		_, pw.err = pw.w.Write(pw.buf)
+	}
+	pw.buf = pw.buf[:0]
+	return pw.err
+}
@@ -0,0 +1,118 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPathEngineRemoveEmptyParents(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "all array elements removed",
+			rules:  []PathRule{{Path: "safetyRatings[*]", Action: ActionRemove}},
+			input:  `{"text":"hi","safetyRatings":[{"category":"a"},{"category":"b"}],"finishReason":"STOP"}`,
+			expect: `{"text":"hi","finishReason":"STOP"}`,
+		},
+		{
+			name:   "all object members removed from each element",
+			rules:  []PathRule{{Path: "candidates[*].safetyRatings[*].category", Action: ActionRemove}},
+			input:  `{"candidates":[{"safetyRatings":[{"category":"a"}]}],"usage":1}`,
+			expect: `{"usage":1}`,
+		},
+		{
+			name:   "first member dropped",
+			rules:  []PathRule{{Path: "meta.id", Action: ActionRemove}},
+			input:  `{"meta":{"id":1},"model":"m"}`,
+			expect: `{"model":"m"}`,
+		},
+		{
+			name:   "last member dropped",
+			rules:  []PathRule{{Path: "meta.id", Action: ActionRemove}},
+			input:  `{"model":"m","meta":{"id":1}}`,
+			expect: `{"model":"m"}`,
+		},
+		{
+			name:   "partially emptied container kept",
+			rules:  []PathRule{{Path: "items[0]", Action: ActionRemove}},
+			input:  `{"items":[1,2],"tags":[[1]]}`,
+			expect: `{"items":[2],"tags":[[1]]}`,
+		},
+		{
+			name:   "originally empty containers kept",
+			rules:  []PathRule{{Path: "a.x", Action: ActionRemove}},
+			input:  `{"a":{"x":1},"b":[],"c":{},"d":[{}]}`,
+			expect: `{"b":[],"c":{},"d":[{}]}`,
+		},
+		{
+			name:   "empty value written by set kept",
+			rules:  []PathRule{{Path: "a", Action: ActionSet, Value: map[string]any{}}},
+			input:  `{"a":{"x":1}}`,
+			expect: `{"a":{}}`,
+		},
+		{
+			name:   "root document kept",
+			rules:  []PathRule{{Path: "a", Action: ActionRemove}},
+			input:  `{"a":{"x":1}}`,
+			expect: `{}`,
+		},
+		{
+			name:   "brackets inside strings",
+			rules:  []PathRule{{Path: "a.x", Action: ActionRemove}},
+			input:  `{"s":"{[,\"]}","a":{"x":"}"},"t":"]"}`,
+			expect: `{"s":"{[,\"]}","t":"]"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, chunk := range []int{1, 2, 3, 4096} {
+			engine, err := NewPathEngine(tt.rules, WithRemoveEmptyParents(), WithCompact(), WithChunkSize(chunk))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("%s (chunk %d): Process error: %v", tt.name, chunk, err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("%s (chunk %d): got %s, want %s", tt.name, chunk, out.String(), tt.expect)
+			}
+			if !json.Valid(out.Bytes()) {
+				t.Errorf("%s (chunk %d): invalid output %s", tt.name, chunk, out.String())
+			}
+		}
+
+		// 未开启选项时保留空容器
+		engine, err := NewPathEngine(tt.rules, WithCompact())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := engine.ProcessBytes([]byte(tt.input))
+		if err != nil || !json.Valid(got) {
+			t.Errorf("%s: default = %s, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestPathEngineRemoveEmptyParentsSSE(t *testing.T) {
+	engine, err := NewPathEngine([]PathRule{{Path: "choices[*].delta.refusal", Action: ActionRemove}}, WithRemoveEmptyParents())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := "data: {\"choices\":[{\"delta\":{\"refusal\":null}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"hi\",\"refusal\":null}}]}\n\ndata: [DONE]\n\n"
+	var out bytes.Buffer
+	if _, err := engine.ProcessSSE(strings.NewReader(stream), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "data: {}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
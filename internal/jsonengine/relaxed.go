@@ -0,0 +1,190 @@
+package jsonengine
+
+import "io"
+
+// WithRelaxedInput 容忍 JSON5 风格的宽松输入：跳过 // 行注释和 /* */ 块注释，去掉 } 和 ] 前的多余逗号
+// 输入先被规整为严格 JSON 再进入规则处理，因此输出始终是严格 JSON；注释所在的位置不保留空白。
+// 严格模式的语法检查、SyntaxError 的偏移和 WithMaxDocumentSize 均作用于规整后的数据，
+// 未闭合的块注释在严格模式下返回 *SyntaxError。单引号字符串、未加引号的键等其他 JSON5 语法不在支持范围内。
+// 与格式选项一样，不影响通过 GetProcessor 手动分块处理的输入。
+func WithRelaxedInput() PathEngineOption {
+	return func(e *PathEngine) {
+		e.relaxed = true
+	}
+}
+
+// relaxed 过滤器的注释状态
+const (
+	relaxedNormal       = iota
+	relaxedSlash        // 读到字符串外的 /，等待确认是否为注释
+	relaxedLineComment  // // 注释，直到换行
+	relaxedBlockComment // /* 注释
+	relaxedBlockStar    // 块注释中读到 *，等待 /
+)
+
+// relaxedFilter 把宽松 JSON 流式转换为严格 JSON，状态可跨越多次 append
+// 逗号暂不输出，连同其后的空白一起挂起，直到确认下一个有效字符不是 } 或 ]
+type relaxedFilter struct {
+	state    int
+	inString bool
+	escaped  bool
+
+	pendingComma bool
+	pending      []byte // 挂起逗号之后的空白
+
+	offset       int64 // 已读入的原始字节数
+	commentStart int64 // 当前块注释的起始偏移
+}
+
+// append 过滤 p 并将结果追加到 dst
+func (f *relaxedFilter) append(dst, p []byte) []byte {
+	for i, c := range p {
+		if f.inString {
+			dst = append(dst, c)
+			switch {
+			case f.escaped:
+				f.escaped = false
+			case c == '\\':
+				f.escaped = true
+			case c == '"':
+				f.inString = false
+			}
+			continue
+		}
+
+		switch f.state {
+		case relaxedSlash:
+			switch c {
+			case '/':
+				f.state = relaxedLineComment
+				continue
+			case '*':
+				f.state = relaxedBlockComment
+				f.commentStart = f.offset + int64(i) - 1
+				continue
+			}
+			// 不是注释：原样输出 /，交给语法检查报告
+			f.state = relaxedNormal
+			dst = f.token(dst, '/')
+		case relaxedLineComment:
+			if c != '\n' && c != '\r' {
+				continue
+			}
+			f.state = relaxedNormal
+		case relaxedBlockComment:
+			if c == '*' {
+				f.state = relaxedBlockStar
+			}
+			continue
+		case relaxedBlockStar:
+			switch c {
+			case '/':
+				f.state = relaxedNormal
+			case '*':
+			default:
+				f.state = relaxedBlockComment
+			}
+			continue
+		}
+
+		switch c {
+		case '/':
+			f.state = relaxedSlash
+		case ' ', '\t', '\r', '\n':
+			if f.pendingComma {
+				f.pending = append(f.pending, c)
+			} else {
+				dst = append(dst, c)
+			}
+		case '}', ']':
+			if f.pendingComma {
+				// 多余的逗号：丢弃逗号，保留其后的空白
+				dst = append(dst, f.pending...)
+				f.pendingComma = false
+				f.pending = f.pending[:0]
+			}
+			dst = append(dst, c)
+		case ',':
+			dst = f.flush(dst)
+			f.pendingComma = true
+		default:
+			dst = f.token(dst, c)
+			f.inString = c == '"'
+		}
+	}
+	f.offset += int64(len(p))
+	return dst
+}
+
+// token 输出挂起的逗号和普通字符 c
+func (f *relaxedFilter) token(dst []byte, c byte) []byte {
+	return append(f.flush(dst), c)
+}
+
+// flush 输出挂起的逗号及其后的空白
+func (f *relaxedFilter) flush(dst []byte) []byte {
+	if !f.pendingComma {
+		return dst
+	}
+	dst = append(dst, ',')
+	dst = append(dst, f.pending...)
+	f.pendingComma = false
+	f.pending = f.pending[:0]
+	return dst
+}
+
+// finish 输入结束时输出剩余的挂起内容，strict 时未闭合的块注释返回 *SyntaxError
+func (f *relaxedFilter) finish(dst []byte, strict bool) ([]byte, error) {
+	switch f.state {
+	case relaxedSlash:
+		dst = f.token(dst, '/')
+	case relaxedBlockComment, relaxedBlockStar:
+		if strict {
+			return dst, &SyntaxError{Offset: f.commentStart, Msg: "unterminated comment"}
+		}
+	}
+	f.state = relaxedNormal
+	return f.flush(dst), nil
+}
+
+// relaxData 把完整读入的宽松输入转换为严格 JSON
+func (e *PathEngine) relaxData(data []byte) ([]byte, error) {
+	var f relaxedFilter
+	return f.finish(f.append(make([]byte, 0, len(data)), data), e.strict)
+}
+
+// relaxedReader 从 r 读取宽松输入，读出规整后的严格 JSON
+type relaxedReader struct {
+	r      io.Reader
+	strict bool
+	filter relaxedFilter
+	raw    []byte
+	out    []byte // 过滤后的数据，out[pos:] 尚未读出
+	pos    int
+	err    error // 底层读取或 finish 的错误，out 读完后返回
+}
+
+func (e *PathEngine) relaxReader(input io.Reader) io.Reader {
+	return &relaxedReader{r: input, strict: e.strict, raw: make([]byte, e.chunkSize)}
+}
+
+func (r *relaxedReader) Read(p []byte) (int, error) {
+	// 注释和挂起的逗号可能使一次读取没有输出，循环直到有数据或出错
+	for r.pos == len(r.out) && r.err == nil {
+		n, err := r.r.Read(r.raw)
+		r.out, r.pos = r.filter.append(r.out[:0], r.raw[:n]), 0
+		if err == io.EOF {
+			r.out, err = r.filter.finish(r.out, r.strict)
+			if err == nil {
+				err = io.EOF
+			}
+		}
+		r.err = err
+	}
+	if r.pos < len(r.out) {
+		n := copy(p, r.out[r.pos:])
+		r.pos += n
+		return n, nil
+	}
+	return 0, r.err
+}
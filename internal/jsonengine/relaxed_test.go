@@ -0,0 +1,114 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPathEngineRelaxedInput(t *testing.T) {
+	rules := []PathRule{{Path: "secret", Action: ActionRemove}}
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{
+			name:   "trailing commas",
+			input:  `{"a":[1,2,],"b":{"c":1,},}`,
+			expect: `{"a":[1,2],"b":{"c":1}}`,
+		},
+		{
+			name:   "line and block comments",
+			input:  "{\n  // model name\n  \"model\": \"m\", /* inline */ \"n\": 1 // done\n}",
+			expect: "{\n  \n  \"model\": \"m\",  \"n\": 1 \n}",
+		},
+		{
+			name:   "comment before closing bracket",
+			input:  "[1, 2, /* last */\n]",
+			expect: "[1, 2 \n]",
+		},
+		{
+			name:   "comment markers inside strings",
+			input:  `{"url":"http://x/*y*/","s":"a,]\"//"}`,
+			expect: `{"url":"http://x/*y*/","s":"a,]\"//"}`,
+		},
+		{
+			name:   "starred block comment",
+			input:  `{/** doc **/"a":1/***/}`,
+			expect: `{"a":1}`,
+		},
+		{
+			name:   "removed field with trailing comma",
+			input:  "{\"secret\": \"x\", \"keep\": [true,],\n}",
+			expect: "{ \"keep\": [true]\n}",
+		},
+		{
+			name:   "strict input unchanged",
+			input:  `{"a": [1, {"b": null}], "c": "d"}`,
+			expect: `{"a": [1, {"b": null}], "c": "d"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, chunk := range []int{1, 2, 3, 4096} {
+			engine, err := NewPathEngine(rules, WithRelaxedInput(), WithStrictMode(), WithChunkSize(chunk))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("%s (chunk %d): Process error: %v", tt.name, chunk, err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("%s (chunk %d): got %q, want %q", tt.name, chunk, out.String(), tt.expect)
+			}
+			if !json.Valid(out.Bytes()) {
+				t.Errorf("%s (chunk %d): invalid output %s", tt.name, chunk, out.String())
+			}
+
+			got, err := engine.ProcessBytes([]byte(tt.input))
+			if err != nil || string(got) != tt.expect {
+				t.Errorf("%s (chunk %d): ProcessBytes = %q, %v", tt.name, chunk, got, err)
+			}
+		}
+	}
+}
+
+func TestPathEngineRelaxedInputErrors(t *testing.T) {
+	engine, err := NewPathEngine(nil, WithRelaxedInput(), WithStrictMode(), WithChunkSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var syntaxErr *SyntaxError
+	for _, input := range []string{
+		`{"a":1} /* open`,
+		`[1,,2]`,
+		`[1,2],`,
+		`{"a":1/2}`,
+		`{"a":1}/`,
+	} {
+		if err := engine.Process(strings.NewReader(input), &bytes.Buffer{}); !errors.As(err, &syntaxErr) {
+			t.Errorf("Process(%s) = %v, want *SyntaxError", input, err)
+		}
+		if _, err := engine.ProcessBytes([]byte(input)); !errors.As(err, &syntaxErr) {
+			t.Errorf("ProcessBytes(%s) = %v, want *SyntaxError", input, err)
+		}
+	}
+	if _, err := engine.ProcessBytes([]byte(`{"a":1} /* open`)); !errors.As(err, &syntaxErr) || syntaxErr.Offset != 8 {
+		t.Errorf("unterminated comment = %v, want offset 8", err)
+	}
+
+	// 非严格模式下忽略未闭合的注释
+	lenient, err := NewPathEngine(nil, WithRelaxedInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := lenient.ProcessBytes([]byte(`{"a":[1,]} // end`))
+	if err != nil || string(out) != `{"a":[1]} ` {
+		t.Errorf("lenient ProcessBytes = %q, %v", out, err)
+	}
+}
@@ -10,8 +10,37 @@ const (
 	ActionAdd Action = "add"
 	// ActionRemove 删除存在的字段（字段不存在时不操作）
 	ActionRemove Action = "remove"
+	// ActionKeep 白名单模式：仅保留匹配路径上的字段，其余字段全部删除（仅 PathEngine 支持）
+	ActionKeep Action = "keep"
+	// ActionMask 脱敏字符串值，Value 为脱敏方式 partial/redact/hash（仅 PathEngine 支持）
+	ActionMask Action = "mask"
+	// ActionTransform 转换字符串值，Value 为转换配置（见 ParseStringTransform，仅 PathEngine 支持）
+	ActionTransform Action = "transform"
+	// ActionClamp 将数值限制在 Min/Max 范围内，字段缺失时添加 Default（仅 PathEngine 支持）
+	ActionClamp Action = "clamp"
+	// ActionRename 重命名对象字段，Value 为同一对象内的新字段名（仅 PathEngine 支持）
+	ActionRename Action = "rename"
+	// ActionCopy 将字段值复制到同一对象内的另一字段，Value 为目标字段名（仅 PathEngine 支持）
+	ActionCopy Action = "copy"
+	// ActionTruncate 将字符串值截断为最多 Max 个字符，发生截断时追加 Value 指定的后缀（可选），
+	// 流式处理，不缓冲整个字符串（仅 PathEngine 支持）
+	ActionTruncate Action = "truncate"
+	// ActionStripBase64 将 base64 数据替换为 {"stripped":true,"mimeType":...,"bytes":N} 占位对象：
+	// 作用于字符串时整体替换（data URL 的 MIME 类型取自前缀），作用于对象时替换其中 Value 指定的字段（默认 data），
+	// MIME 类型取自同一对象的 mimeType/mime_type/media_type 字段。流式处理，不缓冲数据（仅 PathEngine 支持）
+	ActionStripBase64 Action = "strip_base64"
+	// ActionPlugin 用注册的插件改写值，Value 为插件名（见 RegisterValuePlugin，仅 PathEngine 支持）
+	ActionPlugin Action = "plugin"
+	// ActionCapture 不改写值，在同一次处理中记录字段的原始 JSON 值（见 ProcessResult.Captured），
+	// Value 为结果中的名称，省略时使用规则路径（仅 PathEngine 支持）
+	ActionCapture Action = "capture"
 )
 
+// transformsValue 检查操作是否基于原值输出新值
+func (a Action) transformsValue() bool {
+	return a == ActionMask || a == ActionTransform || a == ActionClamp || a == ActionCopy || a == ActionTruncate || a == ActionStripBase64 || a == ActionPlugin
+}
+
 // Rule 定义单条操作规则
 type Rule struct {
 	Key    string `json:"key"`             // 目标字段名（顶层 key）
@@ -0,0 +1,126 @@
+package jsonengine
+
+import "math/bits"
+
+// scanBatchBlocks 每次调用块分类函数处理的 64 字节块数，掩码缓冲区放在栈上
+const scanBatchBlocks = 64
+
+// ScanState 跨 chunk 的字符串扫描状态，零值表示位于字符串外
+type ScanState struct {
+	inString bool // 上一个 chunk 结束时位于字符串内
+	escaped  bool // 上一个 chunk 以未配对的反斜杠结束，下一个字节被转义
+}
+
+// ScanStructuralSkipStrings 扫描 JSON 结构字符，跳过字符串内的结构字符
+// 返回字符串外的 { } [ ] : , 以及未转义的引号（字符串的起止位置），
+// 字符串内的结构字符和被转义的引号不返回，由调用方作为普通内容处理。
+// 按 simdjson 的做法逐 64 字节块计算引号、反斜杠和结构字符的位掩码，
+// 由反斜杠得出被转义的字节，再对未转义引号做前缀异或得到字符串内的区间，整块一次过滤。
+// state 记录跨 chunk 的字符串和转义状态，同一输入的各 chunk 必须按顺序使用同一个 state。
+// 转义只影响引号：字符串外的反斜杠（非法 JSON）同样使其后的引号不被视为字符串边界。
+// positions 容量不足时多出的位置被丢弃，但 state 仍按整个 data 更新。
+func ScanStructuralSkipStrings(data []byte, positions []uint32, state *ScanState) int {
+	return scanSkipStrings(data, positions, state, classifyBlocks)
+}
+
+// scanSkipStrings ScanStructuralSkipStrings 的实现，classify 为块分类函数
+func scanSkipStrings(data []byte, positions []uint32, state *ScanState, classify func([]byte, []uint64)) int {
+	var masks [3 * scanBatchBlocks]uint64
+	count := 0
+	full := len(data) &^ 63
+	for off := 0; off < full; {
+		n := min((full-off)/64, scanBatchBlocks)
+		classify(data[off:off+n*64], masks[:3*n])
+		for i := 0; i < n; i++ {
+			mask := state.next(masks[3*i], masks[3*i+1], masks[3*i+2], 64)
+			count = appendPositions(positions, count, mask, off)
+			off += 64
+		}
+	}
+	if full < len(data) {
+		structural, quote, backslash := classifyBlock(data[full:])
+		mask := state.next(structural, quote, backslash, len(data)-full)
+		count = appendPositions(positions, count, mask, full)
+	}
+	return count
+}
+
+// next 处理一个块（n 字节）的掩码，返回需要报告的位置掩码并更新状态
+func (s *ScanState) next(structural, quote, backslash uint64, n int) uint64 {
+	// 被转义的引号既不是字符串边界也不报告
+	structural &^= quote
+	quote &^= s.escapedMask(backslash, n)
+	// 前缀异或：起始引号及字符串内容为 1，结束引号为 0；块尾之后没有引号，最高位即块结束时的状态
+	inside := prefixXor(quote)
+	if s.inString {
+		inside = ^inside
+	}
+	s.inString = inside>>63 != 0
+	return structural&^inside | quote
+}
+
+// escapedMask 返回块内被反斜杠转义的字节
+// 反斜杠在 LLM 文本中也不密集（主要是 \n 和 \"），逐个处理开始转义的反斜杠即可
+func (s *ScanState) escapedMask(backslash uint64, n int) uint64 {
+	var escaped uint64
+	if s.escaped {
+		escaped = 1
+	}
+	s.escaped = false
+	for bs := backslash &^ escaped; bs != 0; {
+		i := bits.TrailingZeros64(bs)
+		if i+1 == n {
+			s.escaped = true
+			break
+		}
+		escaped |= 1 << (i + 1)
+		// 被转义的字节即使是反斜杠也不再开始转义
+		bs &^= 3 << i
+	}
+	return escaped
+}
+
+// prefixXor 第 i 位为输入第 0..i 位的异或
+func prefixXor(x uint64) uint64 {
+	x ^= x << 1
+	x ^= x << 2
+	x ^= x << 4
+	x ^= x << 8
+	x ^= x << 16
+	x ^= x << 32
+	return x
+}
+
+// appendPositions 将掩码中的位置（加上块偏移）写入 positions
+func appendPositions(positions []uint32, count int, mask uint64, offset int) int {
+	for ; mask != 0 && count < len(positions); mask &= mask - 1 {
+		positions[count] = uint32(offset + bits.TrailingZeros64(mask))
+		count++
+	}
+	return count
+}
+
+// classifyBlock 计算不超过 64 字节的块的结构字符（含引号）、引号和反斜杠掩码
+func classifyBlock(block []byte) (structural, quote, backslash uint64) {
+	for i, b := range block {
+		bit := uint64(1) << i
+		switch b {
+		case '"':
+			quote |= bit
+			structural |= bit
+		case '\\':
+			backslash |= bit
+		case '{', '}', '[', ']', ':', ',':
+			structural |= bit
+		}
+	}
+	return structural, quote, backslash
+}
+
+// classifyBlocksGeneric 通用实现：data 长度为 64 的整数倍，每块依次写入 3 个掩码
+func classifyBlocksGeneric(data []byte, masks []uint64) {
+	for i := 0; i+64 <= len(data); i += 64 {
+		j := i / 64 * 3
+		masks[j], masks[j+1], masks[j+2] = classifyBlock(data[i : i+64])
+	}
+}
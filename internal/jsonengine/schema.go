@@ -0,0 +1,904 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaMaxViolations SchemaError 最多保留的违规项数，超出的只计数
+const schemaMaxViolations = 10
+
+// schemaMaxDepth 校验时 $ref 递归的最大深度，防止自引用 schema 无限递归
+const schemaMaxDepth = 256
+
+// schemaUnsupportedKeywords 影响校验结果但不支持的关键字，编译时报错，避免误以为已生效
+var schemaUnsupportedKeywords = []string{"$dynamicRef", "$dynamicAnchor", "$recursiveRef", "unevaluatedProperties", "unevaluatedItems"}
+
+// schemaTypes type 关键字允许的类型名
+var schemaTypes = []string{"null", "boolean", "object", "array", "number", "string", "integer"}
+
+// Schema 编译后的 JSON Schema，支持 draft 2020-12 的校验关键字：
+// type、enum、const，数值的 minimum/maximum/exclusiveMinimum/exclusiveMaximum/multipleOf，
+// 字符串的 minLength/maxLength/pattern，数组的 prefixItems/items/contains/minContains/maxContains/minItems/maxItems/uniqueItems，
+// 对象的 properties/patternProperties/additionalProperties/propertyNames/required/dependentRequired/dependentSchemas/minProperties/maxProperties，
+// 组合的 allOf/anyOf/oneOf/not/if/then/else，以及指向同一文档内的 $ref（如 #/$defs/message）。
+// format 等说明性关键字和未知关键字被忽略；pattern 使用 Go 的 RE2 语法，不支持反向引用和环视。
+// 编译后只读，可在多个引擎和 goroutine 间共享。
+type Schema struct {
+	root *schemaNode
+}
+
+// SchemaViolation 文档不符合 schema 的一处位置
+type SchemaViolation struct {
+	Path    string // 不符合的值所在路径，如 messages[0].role，顶层为空
+	Keyword string // 未通过的关键字，如 required、type；文档不是合法 JSON 时为空
+	Msg     string
+}
+
+// SchemaError 文档不符合 schema 时返回的错误
+// 最多保留 schemaMaxViolations 项违规，Total 为全部违规数
+type SchemaError struct {
+	Violations []SchemaViolation
+	Total      int
+}
+
+func (e *SchemaError) Error() string {
+	if len(e.Violations) == 0 {
+		return "document does not match schema"
+	}
+	v := e.Violations[0]
+	path := v.Path
+	if path == "" {
+		path = "(root)"
+	}
+	msg := fmt.Sprintf("document does not match schema at %s: %s", path, v.Msg)
+	if e.Total > 1 {
+		msg += fmt.Sprintf(" (and %d more)", e.Total-1)
+	}
+	return msg
+}
+
+// WithSchema 处理完成后按 schema 校验整个输出文档，不符合时返回 *SchemaError
+// 作用于 Process、ProcessWithResult、ProcessBytes 和 AppendProcess（需要缓冲完整输出），
+// 不影响通过 GetProcessor 手动分块处理的输出。与严格模式一样，流式处理时输出已写入 output，
+// 需要整体回退时由调用方缓冲输出。无规则时同样会校验输入。
+func WithSchema(schema *Schema) PathEngineOption {
+	return func(e *PathEngine) {
+		e.schema = schema
+	}
+}
+
+// checkSchema 按引擎的 schema 校验处理后的完整输出
+func (e *PathEngine) checkSchema(output []byte) error {
+	if e.schema == nil {
+		return nil
+	}
+	return e.schema.Validate(output)
+}
+
+// schemaOutput 配置了 schema 时同时把输出缓冲到 buf，处理完成后校验
+func (e *PathEngine) schemaOutput(output io.Writer, buf *appendWriter) io.Writer {
+	if e.schema == nil {
+		return output
+	}
+	return io.MultiWriter(output, buf)
+}
+
+// CompileSchema 编译 JSON Schema 文档，schema 本身不合法或使用了不支持的关键字时返回错误
+func CompileSchema(data []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("invalid schema JSON: unexpected data after top-level value")
+	}
+	c := &schemaCompiler{doc: doc, nodes: make(map[string]*schemaNode)}
+	root, err := c.compile(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate 校验完整的 JSON 文档，不符合时返回 *SchemaError
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	err := dec.Decode(&doc)
+	if err == nil && dec.More() {
+		err = fmt.Errorf("unexpected data after top-level value")
+	}
+	if err != nil {
+		return &SchemaError{Violations: []SchemaViolation{{Msg: "invalid JSON: " + err.Error()}}, Total: 1}
+	}
+
+	r := &schemaReport{max: schemaMaxViolations}
+	s.root.validate(doc, nil, r, 0)
+	if r.total == 0 {
+		return nil
+	}
+	return &SchemaError{Violations: r.violations, Total: r.total}
+}
+
+// schemaNode 编译后的 schema 节点，数值限制为 nil 或 -1 表示未设置
+type schemaNode struct {
+	always *bool // 布尔 schema：true 接受任意值，false 拒绝任意值
+
+	ref *schemaNode
+
+	types    []string
+	enum     []any
+	hasConst bool
+	constant any
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+
+	prefixItems []*schemaNode
+	items       *schemaNode
+	contains    *schemaNode
+	minContains int
+	maxContains int
+	minItems    int
+	maxItems    int
+	uniqueItems bool
+
+	properties        map[string]*schemaNode
+	patternProperties []patternSchema
+	additional        *schemaNode
+	propertyNames     *schemaNode
+	required          []string
+	dependentRequired map[string][]string
+	dependentSchemas  map[string]*schemaNode
+	minProperties     int
+	maxProperties     int
+
+	allOf  []*schemaNode
+	anyOf  []*schemaNode
+	oneOf  []*schemaNode
+	not    *schemaNode
+	ifNode *schemaNode
+	then   *schemaNode
+	orElse *schemaNode
+}
+
+type patternSchema struct {
+	re     *regexp.Regexp
+	schema *schemaNode
+}
+
+// schemaCompiler 编译 schema 文档，按 JSON Pointer 记录已编译的节点供 $ref 引用（可递归）
+type schemaCompiler struct {
+	doc   any
+	nodes map[string]*schemaNode
+}
+
+func (c *schemaCompiler) errorf(pointer, format string, args ...any) error {
+	if pointer == "" {
+		pointer = "(root)"
+	}
+	return fmt.Errorf("invalid schema at %s: %s", pointer, fmt.Sprintf(format, args...))
+}
+
+// compile 编译 pointer 处的 schema，节点在编译子 schema 之前登记，使自引用的 $ref 可以解析
+func (c *schemaCompiler) compile(raw any, pointer string) (*schemaNode, error) {
+	if node, ok := c.nodes[pointer]; ok {
+		return node, nil
+	}
+	node := &schemaNode{minLength: -1, maxLength: -1, minItems: -1, maxItems: -1, minContains: -1, maxContains: -1, minProperties: -1, maxProperties: -1}
+	c.nodes[pointer] = node
+
+	if b, ok := raw.(bool); ok {
+		node.always = &b
+		return node, nil
+	}
+	obj, ok := raw.(map[string]any)
+	if !ok {
+		return nil, c.errorf(pointer, "schema must be an object or a boolean")
+	}
+	for _, keyword := range schemaUnsupportedKeywords {
+		if _, ok := obj[keyword]; ok {
+			return nil, c.errorf(pointer, "keyword %s is not supported", keyword)
+		}
+	}
+
+	sub := func(keyword string) (*schemaNode, error) {
+		v, ok := obj[keyword]
+		if !ok {
+			return nil, nil
+		}
+		return c.compile(v, pointer+"/"+escapePointer(keyword))
+	}
+	subList := func(keyword string) ([]*schemaNode, error) {
+		v, ok := obj[keyword]
+		if !ok {
+			return nil, nil
+		}
+		list, ok := v.([]any)
+		if !ok || len(list) == 0 {
+			return nil, c.errorf(pointer, "%s must be a non-empty array of schemas", keyword)
+		}
+		nodes := make([]*schemaNode, len(list))
+		for i, item := range list {
+			n, err := c.compile(item, pointer+"/"+keyword+"/"+strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			nodes[i] = n
+		}
+		return nodes, nil
+	}
+	subMap := func(keyword string) (map[string]*schemaNode, error) {
+		v, ok := obj[keyword]
+		if !ok {
+			return nil, nil
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, c.errorf(pointer, "%s must be an object", keyword)
+		}
+		nodes := make(map[string]*schemaNode, len(m))
+		for name, item := range m {
+			n, err := c.compile(item, pointer+"/"+keyword+"/"+escapePointer(name))
+			if err != nil {
+				return nil, err
+			}
+			nodes[name] = n
+		}
+		return nodes, nil
+	}
+	number := func(keyword string) (*float64, error) {
+		v, ok := obj[keyword]
+		if !ok {
+			return nil, nil
+		}
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, c.errorf(pointer, "%s must be a number", keyword)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return nil, c.errorf(pointer, "%s: %v", keyword, err)
+		}
+		return &f, nil
+	}
+	count := func(keyword string) (int, error) {
+		v, ok := obj[keyword]
+		if !ok {
+			return -1, nil
+		}
+		n, ok := v.(json.Number)
+		if !ok {
+			return 0, c.errorf(pointer, "%s must be a non-negative integer", keyword)
+		}
+		f, err := n.Float64()
+		if err != nil || f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+			return 0, c.errorf(pointer, "%s must be a non-negative integer", keyword)
+		}
+		return int(f), nil
+	}
+	pattern := func(keyword, expr string) (*regexp.Regexp, error) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, c.errorf(pointer, "%s %q: %v", keyword, expr, err)
+		}
+		return re, nil
+	}
+
+	var err error
+	if v, ok := obj["$ref"]; ok {
+		ref, ok := v.(string)
+		if !ok {
+			return nil, c.errorf(pointer, "$ref must be a string")
+		}
+		if node.ref, err = c.resolve(ref, pointer); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := obj["type"]; ok {
+		switch t := v.(type) {
+		case string:
+			node.types = []string{t}
+		case []any:
+			for _, item := range t {
+				name, ok := item.(string)
+				if !ok {
+					return nil, c.errorf(pointer, "type must be a string or an array of strings")
+				}
+				node.types = append(node.types, name)
+			}
+		default:
+			return nil, c.errorf(pointer, "type must be a string or an array of strings")
+		}
+		for _, name := range node.types {
+			if !slices.Contains(schemaTypes, name) {
+				return nil, c.errorf(pointer, "unknown type %q", name)
+			}
+		}
+	}
+	if v, ok := obj["enum"]; ok {
+		list, ok := v.([]any)
+		if !ok {
+			return nil, c.errorf(pointer, "enum must be an array")
+		}
+		node.enum = list
+	}
+	if v, ok := obj["const"]; ok {
+		node.hasConst, node.constant = true, v
+	}
+
+	if node.minimum, err = number("minimum"); err != nil {
+		return nil, err
+	}
+	if node.maximum, err = number("maximum"); err != nil {
+		return nil, err
+	}
+	if node.exclusiveMinimum, err = number("exclusiveMinimum"); err != nil {
+		return nil, err
+	}
+	if node.exclusiveMaximum, err = number("exclusiveMaximum"); err != nil {
+		return nil, err
+	}
+	if node.multipleOf, err = number("multipleOf"); err != nil {
+		return nil, err
+	}
+	if node.multipleOf != nil && *node.multipleOf <= 0 {
+		return nil, c.errorf(pointer, "multipleOf must be greater than 0")
+	}
+
+	if node.minLength, err = count("minLength"); err != nil {
+		return nil, err
+	}
+	if node.maxLength, err = count("maxLength"); err != nil {
+		return nil, err
+	}
+	if v, ok := obj["pattern"]; ok {
+		expr, ok := v.(string)
+		if !ok {
+			return nil, c.errorf(pointer, "pattern must be a string")
+		}
+		if node.pattern, err = pattern("pattern", expr); err != nil {
+			return nil, err
+		}
+	}
+
+	if node.prefixItems, err = subList("prefixItems"); err != nil {
+		return nil, err
+	}
+	if node.items, err = sub("items"); err != nil {
+		return nil, err
+	}
+	if node.contains, err = sub("contains"); err != nil {
+		return nil, err
+	}
+	if node.minContains, err = count("minContains"); err != nil {
+		return nil, err
+	}
+	if node.maxContains, err = count("maxContains"); err != nil {
+		return nil, err
+	}
+	if node.minItems, err = count("minItems"); err != nil {
+		return nil, err
+	}
+	if node.maxItems, err = count("maxItems"); err != nil {
+		return nil, err
+	}
+	if v, ok := obj["uniqueItems"]; ok {
+		if node.uniqueItems, ok = v.(bool); !ok {
+			return nil, c.errorf(pointer, "uniqueItems must be a boolean")
+		}
+	}
+
+	if node.properties, err = subMap("properties"); err != nil {
+		return nil, err
+	}
+	if v, ok := obj["patternProperties"]; ok {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, c.errorf(pointer, "patternProperties must be an object")
+		}
+		exprs := make([]string, 0, len(m))
+		for expr := range m {
+			exprs = append(exprs, expr)
+		}
+		slices.Sort(exprs)
+		for _, expr := range exprs {
+			re, err := pattern("patternProperties", expr)
+			if err != nil {
+				return nil, err
+			}
+			n, err := c.compile(m[expr], pointer+"/patternProperties/"+escapePointer(expr))
+			if err != nil {
+				return nil, err
+			}
+			node.patternProperties = append(node.patternProperties, patternSchema{re: re, schema: n})
+		}
+	}
+	if node.additional, err = sub("additionalProperties"); err != nil {
+		return nil, err
+	}
+	if node.propertyNames, err = sub("propertyNames"); err != nil {
+		return nil, err
+	}
+	if v, ok := obj["required"]; ok {
+		if node.required, err = c.stringList(v, pointer, "required"); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := obj["dependentRequired"]; ok {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, c.errorf(pointer, "dependentRequired must be an object")
+		}
+		node.dependentRequired = make(map[string][]string, len(m))
+		for name, item := range m {
+			if node.dependentRequired[name], err = c.stringList(item, pointer, "dependentRequired"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if node.dependentSchemas, err = subMap("dependentSchemas"); err != nil {
+		return nil, err
+	}
+	if node.minProperties, err = count("minProperties"); err != nil {
+		return nil, err
+	}
+	if node.maxProperties, err = count("maxProperties"); err != nil {
+		return nil, err
+	}
+
+	if node.allOf, err = subList("allOf"); err != nil {
+		return nil, err
+	}
+	if node.anyOf, err = subList("anyOf"); err != nil {
+		return nil, err
+	}
+	if node.oneOf, err = subList("oneOf"); err != nil {
+		return nil, err
+	}
+	if node.not, err = sub("not"); err != nil {
+		return nil, err
+	}
+	if node.ifNode, err = sub("if"); err != nil {
+		return nil, err
+	}
+	if node.then, err = sub("then"); err != nil {
+		return nil, err
+	}
+	if node.orElse, err = sub("else"); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// stringList 解析字符串数组形式的关键字值
+func (c *schemaCompiler) stringList(v any, pointer, keyword string) ([]string, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, c.errorf(pointer, "%s must be an array of strings", keyword)
+	}
+	out := make([]string, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, c.errorf(pointer, "%s must be an array of strings", keyword)
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+// resolve 解析 $ref，只支持指向当前文档的 JSON Pointer 片段（# 或 #/...）
+func (c *schemaCompiler) resolve(ref, pointer string) (*schemaNode, error) {
+	fragment, ok := strings.CutPrefix(ref, "#")
+	if !ok || (fragment != "" && fragment[0] != '/') {
+		return nil, c.errorf(pointer, "$ref %q is not supported, only local references such as #/$defs/name are", ref)
+	}
+	fragment, err := url.PathUnescape(fragment)
+	if err != nil {
+		return nil, c.errorf(pointer, "$ref %q: %v", ref, err)
+	}
+
+	target := c.doc
+	if fragment != "" {
+		for _, token := range strings.Split(fragment[1:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			switch t := target.(type) {
+			case map[string]any:
+				target, ok = t[token]
+			case []any:
+				i, err := strconv.Atoi(token)
+				ok = err == nil && i >= 0 && i < len(t)
+				if ok {
+					target = t[i]
+				}
+			default:
+				ok = false
+			}
+			if !ok {
+				return nil, c.errorf(pointer, "$ref %q does not resolve", ref)
+			}
+		}
+	}
+	// 以规范化的 pointer 登记，同一目标只编译一次
+	var normalized strings.Builder
+	if fragment != "" {
+		for _, token := range strings.Split(fragment[1:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			normalized.WriteString("/" + escapePointer(token))
+		}
+	}
+	return c.compile(target, normalized.String())
+}
+
+// escapePointer 转义 JSON Pointer 中的一段
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// schemaReport 收集违规项；max 为 0 时只计数（用于 anyOf 等只需知道是否匹配的子校验）
+type schemaReport struct {
+	violations []SchemaViolation
+	total      int
+	max        int
+}
+
+func (r *schemaReport) add(path []any, keyword, format string, args ...any) {
+	r.total++
+	if len(r.violations) < r.max {
+		r.violations = append(r.violations, SchemaViolation{Path: formatSchemaPath(path), Keyword: keyword, Msg: fmt.Sprintf(format, args...)})
+	}
+}
+
+// matches 检查值是否符合 schema，不记录违规项
+func (n *schemaNode) matches(v any, path []any, depth int) bool {
+	r := &schemaReport{}
+	n.validate(v, path, r, depth)
+	return r.total == 0
+}
+
+// validate 校验值，违规项记录到 r
+func (n *schemaNode) validate(v any, path []any, r *schemaReport, depth int) {
+	if n.always != nil {
+		if !*n.always {
+			r.add(path, "false", "no value is allowed here")
+		}
+		return
+	}
+	// 只需判断是否匹配时，出现违规即可停止
+	if r.max == 0 && r.total > 0 {
+		return
+	}
+	if n.ref != nil {
+		if depth >= schemaMaxDepth {
+			r.add(path, "$ref", "schema references nest deeper than %d levels", schemaMaxDepth)
+			return
+		}
+		n.ref.validate(v, path, r, depth+1)
+	}
+
+	if len(n.types) > 0 && !slices.ContainsFunc(n.types, func(t string) bool { return schemaTypeMatches(t, v) }) {
+		r.add(path, "type", "expected %s, got %s", strings.Join(n.types, " or "), schemaTypeName(v))
+		// 类型不符时其余针对该类型的关键字没有意义
+		return
+	}
+	if n.enum != nil && !slices.ContainsFunc(n.enum, func(e any) bool { return schemaEqual(e, v) }) {
+		r.add(path, "enum", "value is not one of the allowed values")
+	}
+	if n.hasConst && !schemaEqual(n.constant, v) {
+		r.add(path, "const", "value does not equal the required constant")
+	}
+
+	switch val := v.(type) {
+	case json.Number:
+		n.validateNumber(val, path, r)
+	case string:
+		n.validateString(val, path, r)
+	case []any:
+		n.validateArray(val, path, r, depth)
+	case map[string]any:
+		n.validateObject(val, path, r, depth)
+	}
+
+	for _, s := range n.allOf {
+		s.validate(v, path, r, depth)
+	}
+	if n.anyOf != nil && !slices.ContainsFunc(n.anyOf, func(s *schemaNode) bool { return s.matches(v, path, depth) }) {
+		r.add(path, "anyOf", "value does not match any of the anyOf schemas")
+	}
+	if n.oneOf != nil {
+		matched := 0
+		for _, s := range n.oneOf {
+			if s.matches(v, path, depth) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			r.add(path, "oneOf", "value matches %d of the oneOf schemas, expected exactly 1", matched)
+		}
+	}
+	if n.not != nil && n.not.matches(v, path, depth) {
+		r.add(path, "not", "value must not match the not schema")
+	}
+	if n.ifNode != nil {
+		if n.ifNode.matches(v, path, depth) {
+			if n.then != nil {
+				n.then.validate(v, path, r, depth)
+			}
+		} else if n.orElse != nil {
+			n.orElse.validate(v, path, r, depth)
+		}
+	}
+}
+
+func (n *schemaNode) validateNumber(num json.Number, path []any, r *schemaReport) {
+	f, _ := strconv.ParseFloat(num.String(), 64) // 超出范围时为 ±Inf，比较结果仍然正确
+	if n.minimum != nil && f < *n.minimum {
+		r.add(path, "minimum", "%s is less than the minimum %v", num, *n.minimum)
+	}
+	if n.maximum != nil && f > *n.maximum {
+		r.add(path, "maximum", "%s is greater than the maximum %v", num, *n.maximum)
+	}
+	if n.exclusiveMinimum != nil && f <= *n.exclusiveMinimum {
+		r.add(path, "exclusiveMinimum", "%s must be greater than %v", num, *n.exclusiveMinimum)
+	}
+	if n.exclusiveMaximum != nil && f >= *n.exclusiveMaximum {
+		r.add(path, "exclusiveMaximum", "%s must be less than %v", num, *n.exclusiveMaximum)
+	}
+	if n.multipleOf != nil && !isMultipleOf(num, f, *n.multipleOf) {
+		r.add(path, "multipleOf", "%s is not a multiple of %v", num, *n.multipleOf)
+	}
+}
+
+func (n *schemaNode) validateString(s string, path []any, r *schemaReport) {
+	if n.minLength >= 0 || n.maxLength >= 0 {
+		length := utf8.RuneCountInString(s)
+		if n.minLength >= 0 && length < n.minLength {
+			r.add(path, "minLength", "string is shorter than %d characters", n.minLength)
+		}
+		if n.maxLength >= 0 && length > n.maxLength {
+			r.add(path, "maxLength", "string is longer than %d characters", n.maxLength)
+		}
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		r.add(path, "pattern", "string does not match pattern %q", n.pattern.String())
+	}
+}
+
+func (n *schemaNode) validateArray(arr []any, path []any, r *schemaReport, depth int) {
+	if n.minItems >= 0 && len(arr) < n.minItems {
+		r.add(path, "minItems", "array has fewer than %d items", n.minItems)
+	}
+	if n.maxItems >= 0 && len(arr) > n.maxItems {
+		r.add(path, "maxItems", "array has more than %d items", n.maxItems)
+	}
+	if n.uniqueItems {
+		for i := 1; i < len(arr); i++ {
+			if slices.ContainsFunc(arr[:i], func(prev any) bool { return schemaEqual(prev, arr[i]) }) {
+				r.add(append(path, i), "uniqueItems", "array item duplicates an earlier item")
+				break
+			}
+		}
+	}
+	for i, item := range arr {
+		switch {
+		case i < len(n.prefixItems):
+			n.prefixItems[i].validate(item, append(path, i), r, depth)
+		case n.items != nil:
+			n.items.validate(item, append(path, i), r, depth)
+		}
+	}
+	if n.contains != nil {
+		matched := 0
+		for i, item := range arr {
+			if n.contains.matches(item, append(path, i), depth) {
+				matched++
+			}
+		}
+		minContains := n.minContains
+		if minContains < 0 {
+			minContains = 1
+		}
+		if matched < minContains {
+			r.add(path, "contains", "array has %d items matching contains, expected at least %d", matched, minContains)
+		}
+		if n.maxContains >= 0 && matched > n.maxContains {
+			r.add(path, "maxContains", "array has %d items matching contains, expected at most %d", matched, n.maxContains)
+		}
+	}
+}
+
+func (n *schemaNode) validateObject(obj map[string]any, path []any, r *schemaReport, depth int) {
+	if n.minProperties >= 0 && len(obj) < n.minProperties {
+		r.add(path, "minProperties", "object has fewer than %d properties", n.minProperties)
+	}
+	if n.maxProperties >= 0 && len(obj) > n.maxProperties {
+		r.add(path, "maxProperties", "object has more than %d properties", n.maxProperties)
+	}
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			r.add(path, "required", "missing required property %q", name)
+		}
+	}
+
+	// 按 key 排序，违规项的顺序稳定
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		value := obj[key]
+		childPath := append(path, key)
+		if deps, ok := n.dependentRequired[key]; ok {
+			for _, dep := range deps {
+				if _, ok := obj[dep]; !ok {
+					r.add(path, "dependentRequired", "property %q requires property %q", key, dep)
+				}
+			}
+		}
+		if s, ok := n.dependentSchemas[key]; ok {
+			s.validate(obj, path, r, depth)
+		}
+		if n.propertyNames != nil && !n.propertyNames.matches(key, childPath, depth) {
+			r.add(childPath, "propertyNames", "property name %q is not allowed", key)
+		}
+
+		evaluated := false
+		if s, ok := n.properties[key]; ok {
+			evaluated = true
+			s.validate(value, childPath, r, depth)
+		}
+		for _, p := range n.patternProperties {
+			if p.re.MatchString(key) {
+				evaluated = true
+				p.schema.validate(value, childPath, r, depth)
+			}
+		}
+		if !evaluated && n.additional != nil {
+			if n.additional.always != nil && !*n.additional.always {
+				r.add(childPath, "additionalProperties", "property %q is not allowed", key)
+				continue
+			}
+			n.additional.validate(value, childPath, r, depth)
+		}
+	}
+}
+
+// schemaTypeMatches 检查值是否属于 type 关键字中的类型，integer 包括小数部分为 0 的数（如 1.0）
+func schemaTypeMatches(t string, v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		if t != "integer" {
+			return false
+		}
+		if !strings.ContainsAny(val.String(), ".eE") {
+			return true
+		}
+		f, err := val.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return false
+}
+
+// schemaTypeName 返回值的 JSON 类型名
+func schemaTypeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "number"
+}
+
+// isMultipleOf 检查 num 是否为 m 的整数倍：两者均为 int64 范围内的整数时精确计算，否则按浮点数计算并容许舍入误差
+func isMultipleOf(num json.Number, f, m float64) bool {
+	if i, err := strconv.ParseInt(num.String(), 10, 64); err == nil && m == math.Trunc(m) && m <= math.MaxInt64 {
+		return i%int64(m) == 0
+	}
+	if math.IsInf(f, 0) {
+		return false
+	}
+	q := f / m
+	return math.Abs(q-math.Round(q)) <= 1e-9*math.Max(1, math.Abs(q))
+}
+
+// schemaEqual 比较两个 JSON 值，数字按数值比较
+func schemaEqual(a, b any) bool {
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	case []any:
+		y, ok := b.([]any)
+		return ok && slices.EqualFunc(x, y, schemaEqual)
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, xv := range x {
+			yv, ok := y[key]
+			if !ok || !schemaEqual(xv, yv) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// formatSchemaPath 以点号语法描述值的位置，与 SyntaxError 的路径一致
+func formatSchemaPath(path []any) string {
+	var b strings.Builder
+	for _, elem := range path {
+		switch e := elem.(type) {
+		case int:
+			b.WriteString("[" + strconv.Itoa(e) + "]")
+		case string:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(e)
+		}
+	}
+	return b.String()
+}
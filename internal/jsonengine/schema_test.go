@@ -0,0 +1,213 @@
+package jsonengine
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["model", "messages"],
+		"properties": {
+			"model": {"type": "string", "minLength": 1},
+			"messages": {"type": "array", "minItems": 1, "items": {"$ref": "#/$defs/message"}},
+			"temperature": {"type": "number", "minimum": 0, "maximum": 2},
+			"n": {"type": "integer", "exclusiveMinimum": 0, "multipleOf": 1},
+			"stop": {"anyOf": [{"type": "string"}, {"type": "array", "items": {"type": "string"}, "maxItems": 4}]},
+			"tags": {"type": "array", "uniqueItems": true},
+			"tuple": {"prefixItems": [{"type": "string"}, {"type": "integer"}], "items": false},
+			"meta": {"type": "object", "propertyNames": {"pattern": "^[a-z_]+$"}, "maxProperties": 2},
+			"tool_choice": {"oneOf": [{"const": "auto"}, {"const": "none"}, {"type": "object", "required": ["name"]}]},
+			"stream": {"type": "boolean"}
+		},
+		"patternProperties": {"^x-": {"type": "string"}},
+		"additionalProperties": false,
+		"dependentRequired": {"stream_options": ["stream"]},
+		"if": {"properties": {"stream": {"const": true}}, "required": ["stream"]},
+		"then": {"properties": {"n": {"maximum": 1}}},
+		"$defs": {
+			"message": {
+				"type": "object",
+				"required": ["role", "content"],
+				"properties": {
+					"role": {"enum": ["system", "user", "assistant", "tool"]},
+					"content": {"type": ["string", "array", "null"]},
+					"parts": {"type": "array", "contains": {"type": "string"}, "maxContains": 1}
+				}
+			}
+		}
+	}`
+	s, err := CompileSchema([]byte(schema))
+	if err != nil {
+		t.Fatalf("CompileSchema error: %v", err)
+	}
+
+	valid := []string{
+		`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`,
+		`{"model":"m","messages":[{"role":"user","content":null,"parts":[1,"a"]}],"temperature":1.5,"n":2.0,"stop":["a","b"],"x-trace":"1"}`,
+		`{"model":"m","messages":[{"role":"tool","content":[]}],"tags":[1,"1",{"a":1},{"a":2}],"tuple":["a",1],"meta":{"a_b":1}}`,
+		`{"model":"m","messages":[{"role":"user","content":""}],"tool_choice":{"name":"f"},"stream":true,"n":1}`,
+		`{"model":"m","messages":[{"role":"user","content":""}],"tool_choice":"none","stream":false,"n":3}`,
+	}
+	for _, doc := range valid {
+		if err := s.Validate([]byte(doc)); err != nil {
+			t.Errorf("Validate(%s) = %v, want nil", doc, err)
+		}
+	}
+
+	invalid := []struct {
+		doc     string
+		path    string
+		keyword string
+	}{
+		{`[]`, "", "type"},
+		{`{"messages":[{"role":"user","content":"hi"}]}`, "", "required"},
+		{`{"model":"","messages":[{"role":"user","content":"hi"}]}`, "model", "minLength"},
+		{`{"model":"m","messages":[]}`, "messages", "minItems"},
+		{`{"model":"m","messages":[{"role":"user","content":"a"},{"role":"bot","content":"b"}]}`, "messages[1].role", "enum"},
+		{`{"model":"m","messages":[{"role":"user","content":1}]}`, "messages[0].content", "type"},
+		{`{"model":"m","messages":[{"role":"user","content":"","parts":["a","b"]}]}`, "messages[0].parts", "maxContains"},
+		{`{"model":"m","messages":[{"role":"user","content":"","parts":[1]}]}`, "messages[0].parts", "contains"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"temperature":2.5}`, "temperature", "maximum"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"n":1.5}`, "n", "type"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"n":0}`, "n", "exclusiveMinimum"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"stop":["a","b","c","d","e"]}`, "stop", "anyOf"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"tags":[1,1.0]}`, "tags[1]", "uniqueItems"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"tuple":["a",1,2]}`, "tuple[2]", "false"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"meta":{"Bad":1}}`, "meta.Bad", "propertyNames"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"meta":{"a":1,"b":2,"c":3}}`, "meta", "maxProperties"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"tool_choice":"required"}`, "tool_choice", "oneOf"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"x-trace":1}`, "x-trace", "type"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"extra":1}`, "extra", "additionalProperties"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"stream_options":{}}`, "", "dependentRequired"},
+		{`{"model":"m","messages":[{"role":"user","content":""}],"stream":true,"n":2}`, "n", "maximum"},
+		{`{"model":"m"`, "", ""},
+	}
+	for _, tt := range invalid {
+		err := s.Validate([]byte(tt.doc))
+		var schemaErr *SchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Errorf("Validate(%s) = %v, want *SchemaError", tt.doc, err)
+			continue
+		}
+		v := schemaErr.Violations[0]
+		if v.Path != tt.path || v.Keyword != tt.keyword {
+			t.Errorf("Validate(%s) first violation = %+v, want path %q keyword %q", tt.doc, v, tt.path, tt.keyword)
+		}
+	}
+
+	err = s.Validate([]byte(`{"model":1,"messages":[{"role":"x"}],"a":1,"b":2}`))
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.Total != 5 || len(schemaErr.Violations) != 5 {
+		t.Fatalf("Validate with several violations = %+v", err)
+	}
+	want := "document does not match schema at a: property \"a\" is not allowed (and 4 more)"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestSchemaRecursiveRef(t *testing.T) {
+	s, err := CompileSchema([]byte(`{
+		"$defs": {"node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}, "name": {"type": "string"}}}},
+		"$ref": "#/$defs/node"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate([]byte(`{"name":"a","children":[{"name":"b","children":[{"name":"c"}]}]}`)); err != nil {
+		t.Errorf("valid tree: %v", err)
+	}
+	err = s.Validate([]byte(`{"name":"a","children":[{"name":"b","children":[{"name":3}]}]}`))
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.Violations[0].Path != "children[0].children[0].name" {
+		t.Errorf("invalid tree: %v", err)
+	}
+
+	// 直接自引用不会无限递归
+	s, err = CompileSchema([]byte(`{"$ref": "#"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate([]byte(`{}`)); err == nil {
+		t.Error("expected an error for a schema referencing itself")
+	}
+}
+
+func TestCompileSchemaErrors(t *testing.T) {
+	for _, schema := range []string{
+		`{`,
+		`1`,
+		`{"type": "int"}`,
+		`{"type": 1}`,
+		`{"minLength": -1}`,
+		`{"maxItems": 1.5}`,
+		`{"multipleOf": 0}`,
+		`{"pattern": "("}`,
+		`{"required": "a"}`,
+		`{"allOf": []}`,
+		`{"properties": {"a": 1}}`,
+		`{"$ref": "other.json#/a"}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"unevaluatedProperties": false}`,
+	} {
+		if _, err := CompileSchema([]byte(schema)); err == nil {
+			t.Errorf("CompileSchema(%s) expected error", schema)
+		}
+	}
+}
+
+func TestPathEngineWithSchema(t *testing.T) {
+	s, err := CompileSchema([]byte(`{"type":"object","required":["model"],"properties":{"max_tokens":false}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []PathRule{
+		{Path: "max_tokens", Action: ActionRemove},
+		{Path: "model", Action: ActionAdd, Value: "default"},
+	}
+	engine, err := NewPathEngine(rules, WithSchema(s), WithChunkSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 校验的是改写后的文档
+	out, err := engine.ProcessBytes([]byte(`{"max_tokens":10}`))
+	if err != nil || string(out) != `{"model":"default"}` {
+		t.Errorf("ProcessBytes = %s, %v", out, err)
+	}
+	var buf bytes.Buffer
+	if err := engine.Process(strings.NewReader(`{"max_tokens":10,"model":"m"}`), &buf); err != nil {
+		t.Errorf("Process error: %v", err)
+	}
+
+	strict, err := NewPathEngine(nil, WithSchema(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schemaErr *SchemaError
+	if _, err := strict.ProcessBytes([]byte(`{"max_tokens":10}`)); !errors.As(err, &schemaErr) || schemaErr.Total != 2 {
+		t.Errorf("ProcessBytes without rules = %v, want two violations", err)
+	}
+	buf.Reset()
+	if err := strict.Process(strings.NewReader(`{"model":"m","max_tokens":1}`), &buf); !errors.As(err, &schemaErr) {
+		t.Errorf("Process without rules = %v, want *SchemaError", err)
+	}
+	if buf.String() != `{"model":"m","max_tokens":1}` {
+		t.Errorf("streamed output = %s", buf.String())
+	}
+
+	// 按格式化后的输出校验，AppendProcess 只校验追加的部分
+	compact, err := NewPathEngine(rules, WithSchema(s), WithCompact())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = compact.AppendProcess([]byte("prefix"), []byte(`{ "model" : "m" }`))
+	if err != nil || string(out) != `prefix{"model":"m"}` {
+		t.Errorf("AppendProcess = %s, %v", out, err)
+	}
+}
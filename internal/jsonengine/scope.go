@@ -0,0 +1,194 @@
+package jsonengine
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// 规则的路由选择器（PathRule.Paths/Methods/Models）限定规则适用的请求，由调用方按请求筛选规则后再编译引擎，
+// PathEngine 本身不读取选择器。paths 为以 / 开头的请求路径，以 * 结尾时按前缀匹配；
+// methods 为 HTTP 方法，models 为模型名或含 * 的通配模式，均不区分大小写。空选择器匹配所有请求
+
+// Scoped 检查规则是否限定了请求路径、方法或模型
+func (r PathRule) Scoped() bool {
+	return len(r.Paths) > 0 || len(r.Methods) > 0 || len(r.Models) > 0
+}
+
+// AppliesTo 检查规则是否适用于指定方法、路径和模型的请求
+func (r PathRule) AppliesTo(method, requestPath, model string) bool {
+	return RouteMatches(r.Paths, r.Methods, method, requestPath) && ModelMatches(r.Models, model)
+}
+
+// ModelMatches 检查模型是否满足模型选择器，* 匹配任意字符序列（包括 /）
+// 设置了选择器但请求中没有模型时不匹配
+func ModelMatches(patterns []string, model string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	if model == "" {
+		return false
+	}
+	model = strings.ToLower(model)
+	for _, pattern := range patterns {
+		if globMatch(strings.ToLower(pattern), model) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch 匹配只含 * 通配符的模式
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
+
+// RouteMatches 检查请求是否满足路由选择器
+// 请求路径先规范化，避免 /v1/chat/../embeddings 之类的路径绕过选择器
+func RouteMatches(paths, methods []string, method, requestPath string) bool {
+	if len(methods) > 0 && !containsFold(methods, method) {
+		return false
+	}
+	if len(paths) == 0 {
+		return true
+	}
+	cleanPath := path.Clean("/" + requestPath)
+	for _, pattern := range paths {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(cleanPath, prefix) {
+				return true
+			}
+		} else if cleanPath == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRoute 检查路由选择器：路径须以 / 开头且 * 只能出现在末尾，方法须为非空的单个 token
+func ValidateRoute(paths, methods []string) error {
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("route path %q must start with /", p)
+		}
+		if i := strings.IndexByte(p, '*'); i >= 0 && i != len(p)-1 {
+			return fmt.Errorf("route path %q may only contain * at the end", p)
+		}
+	}
+	for _, m := range methods {
+		if m == "" || strings.ContainsAny(m, " \t/*") {
+			return fmt.Errorf("invalid route method %q", m)
+		}
+	}
+	return nil
+}
+
+// validateModels 检查模型选择器
+func validateModels(patterns []string) error {
+	for _, p := range patterns {
+		if strings.TrimSpace(p) == "" {
+			return errors.New("model selector must not be empty")
+		}
+	}
+	return nil
+}
+
+// routesOverlap 检查两条规则的选择器是否可能匹配同一请求，不可能同时生效的规则之间不存在冲突或遮蔽
+func routesOverlap(a, b PathRule) bool {
+	if len(a.Methods) > 0 && len(b.Methods) > 0 {
+		shared := false
+		for _, m := range a.Methods {
+			if containsFold(b.Methods, m) {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			return false
+		}
+	}
+	if !modelsOverlap(a.Models, b.Models) {
+		return false
+	}
+	if len(a.Paths) == 0 || len(b.Paths) == 0 {
+		return true
+	}
+	for _, p := range a.Paths {
+		for _, q := range b.Paths {
+			if pathPatternsOverlap(p, q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// modelsOverlap 检查两个模型选择器是否可能匹配同一模型；两个通配模式保守地视为重叠
+func modelsOverlap(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, p := range a {
+		for _, q := range b {
+			pWild, qWild := strings.Contains(p, "*"), strings.Contains(q, "*")
+			switch {
+			case pWild && qWild:
+				return true
+			case pWild:
+				if ModelMatches([]string{p}, q) {
+					return true
+				}
+			case qWild:
+				if ModelMatches([]string{q}, p) {
+					return true
+				}
+			default:
+				if strings.EqualFold(p, q) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// pathPatternsOverlap 检查两个路径模式是否能匹配同一路径
+func pathPatternsOverlap(p, q string) bool {
+	pPrefix, pWild := strings.CutSuffix(p, "*")
+	qPrefix, qWild := strings.CutSuffix(q, "*")
+	switch {
+	case pWild && qWild:
+		return strings.HasPrefix(pPrefix, qPrefix) || strings.HasPrefix(qPrefix, pPrefix)
+	case pWild:
+		return strings.HasPrefix(q, pPrefix)
+	case qWild:
+		return strings.HasPrefix(p, qPrefix)
+	}
+	return p == q
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
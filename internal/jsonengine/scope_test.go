@@ -0,0 +1,122 @@
+package jsonengine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRouteMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		methods []string
+		method  string
+		path    string
+		want    bool
+	}{
+		{"no selectors", nil, nil, "POST", "/v1/embeddings", true},
+		{"exact path", []string{"/v1/chat/completions"}, nil, "POST", "/v1/chat/completions", true},
+		{"other path", []string{"/v1/chat/completions"}, nil, "POST", "/v1/embeddings", false},
+		{"prefix path", []string{"/v1beta/models/*"}, nil, "POST", "/v1beta/models/gemini-pro:generateContent", true},
+		{"cleaned path", []string{"/v1/chat/completions"}, nil, "POST", "/v1/embeddings/../chat/completions", true},
+		{"traversal out of prefix", []string{"/v1/chat/*"}, nil, "POST", "/v1/chat/../files", false},
+		{"method case", nil, []string{"post"}, "POST", "/v1/files", true},
+		{"other method", nil, []string{"POST"}, "GET", "/v1/models", false},
+		{"both selectors", []string{"/v1/files"}, []string{"POST"}, "GET", "/v1/files", false},
+	}
+	for _, tt := range tests {
+		if got := RouteMatches(tt.paths, tt.methods, tt.method, tt.path); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestModelMatches(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		model    string
+		want     bool
+	}{
+		{nil, "", true},
+		{[]string{"gpt-4o"}, "gpt-4o", true},
+		{[]string{"gpt-4o"}, "gpt-4o-mini", false},
+		{[]string{"gpt-4o*"}, "gpt-4o-mini", true},
+		{[]string{"gpt-4o*"}, "GPT-4O", true},
+		{[]string{"gpt-4o*"}, "gpt-4", false},
+		{[]string{"*/llama-*-instruct"}, "meta/llama-3.1-70b-instruct", true},
+		{[]string{"*/llama-*-instruct"}, "meta/llama-3.1-70b", false},
+		{[]string{"claude-*", "gemini-*"}, "gemini-2.0-flash", true},
+		{[]string{"a*a"}, "a", false},
+		{[]string{"*"}, "", false},
+	}
+	for _, tt := range tests {
+		if got := ModelMatches(tt.patterns, tt.model); got != tt.want {
+			t.Errorf("ModelMatches(%q, %q) = %v, want %v", tt.patterns, tt.model, got, tt.want)
+		}
+	}
+
+	rule := PathRule{Path: "max_tokens", Action: ActionClamp, Max: new(float64), Models: []string{"gpt-4o*"}, Methods: []string{"POST"}}
+	if !rule.Scoped() || !rule.AppliesTo("POST", "/v1/chat/completions", "gpt-4o-mini") || rule.AppliesTo("GET", "/v1/chat/completions", "gpt-4o") {
+		t.Errorf("unexpected model selector result for %+v", rule)
+	}
+}
+
+func TestPathRuleRouteJSON(t *testing.T) {
+	var rules []PathRule
+	data := `[{"path":"max_tokens","action":"set","value":1024,"paths":["/v1/chat/completions"],"methods":["POST"]}]`
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		t.Fatal(err)
+	}
+	if !rules[0].Scoped() || !rules[0].AppliesTo("POST", "/v1/chat/completions", "") || rules[0].AppliesTo("POST", "/v1/embeddings", "") {
+		t.Errorf("unexpected selectors: %+v", rules[0])
+	}
+	out, err := json.Marshal(rules)
+	if err != nil || string(out) != data {
+		t.Errorf("round trip: got %s, %v", out, err)
+	}
+}
+
+func TestValidateRulesRoutes(t *testing.T) {
+	chat := []string{"/v1/chat/completions"}
+	embeddings := []string{"/v1/embeddings"}
+
+	issues := ValidateRules([]PathRule{{Path: "a", Action: ActionRemove, Paths: []string{"v1/chat"}}})
+	if len(issues) != 1 || issues[0].Code != IssueInvalidRoute {
+		t.Errorf("relative path: got %+v", issues)
+	}
+	issues = ValidateRules([]PathRule{{Path: "a", Action: ActionRemove, Paths: []string{"/v1/*/completions"}}})
+	if len(issues) != 1 || issues[0].Code != IssueInvalidRoute {
+		t.Errorf("inner wildcard: got %+v", issues)
+	}
+	issues = ValidateRules([]PathRule{{Path: "a", Action: ActionRemove, Models: []string{" "}}})
+	if len(issues) != 1 || issues[0].Code != IssueInvalidRoute {
+		t.Errorf("empty model: got %+v", issues)
+	}
+
+	// 选择器互不相交的规则不会同时生效
+	disjoint := [][]PathRule{
+		{{Path: "a", Action: ActionSet, Value: 1, Paths: chat}, {Path: "a", Action: ActionSet, Value: 2, Paths: embeddings}},
+		{{Path: "a", Action: ActionSet, Value: 1, Methods: []string{"POST"}}, {Path: "a", Action: ActionRemove, Methods: []string{"PUT"}}},
+		{{Path: "a", Action: ActionRemove, Paths: []string{"/v1/files*"}}, {Path: "a.b", Action: ActionSet, Value: 1, Paths: chat}},
+		{{Path: "a", Action: ActionKeep, Paths: embeddings}, {Path: "b", Action: ActionSet, Value: 1, Paths: chat}},
+		{{Path: "a", Action: ActionSet, Value: 1, Models: []string{"gpt-4o*"}}, {Path: "a", Action: ActionSet, Value: 2, Models: []string{"claude-3-opus"}}},
+	}
+	for i, rules := range disjoint {
+		if issues := ValidateRules(rules); len(issues) != 0 {
+			t.Errorf("disjoint case %d: got %+v", i, issues)
+		}
+	}
+
+	overlapping := [][]PathRule{
+		{{Path: "a", Action: ActionSet, Value: 1, Paths: chat}, {Path: "a", Action: ActionSet, Value: 2}},
+		{{Path: "a", Action: ActionSet, Value: 1, Paths: []string{"/v1/*"}}, {Path: "a", Action: ActionRemove, Paths: chat}},
+		{{Path: "a", Action: ActionRemove, Methods: []string{"post"}}, {Path: "a.b", Action: ActionSet, Value: 1, Methods: []string{"POST"}}},
+		{{Path: "a", Action: ActionSet, Value: 1, Models: []string{"gpt-4o*"}}, {Path: "a", Action: ActionSet, Value: 2, Models: []string{"gpt-4o-mini"}}},
+		{{Path: "a", Action: ActionSet, Value: 1, Models: []string{"gpt-*"}}, {Path: "a", Action: ActionSet, Value: 2, Models: []string{"*-mini"}}},
+	}
+	for i, rules := range overlapping {
+		if issues := ValidateRules(rules); len(issues) != 1 {
+			t.Errorf("overlapping case %d: got %+v", i, issues)
+		}
+	}
+}
@@ -7,6 +7,9 @@ import "golang.org/x/sys/cpu"
 // hasAVX2 检查 CPU 是否支持 AVX2
 var hasAVX2 = cpu.X86.HasAVX2
 
+// hasAVX512 检查 CPU 和操作系统是否支持 AVX-512 字节比较（AVX512F + AVX512BW）
+var hasAVX512 = cpu.X86.HasAVX512F && cpu.X86.HasAVX512BW
+
 // ScanStructural 扫描 JSON 结构字符，返回位置列表
 // 结构字符: " { } [ ] : ,
 // positions 必须足够大以容纳所有结果（建议 len(data)/4）
@@ -15,6 +18,9 @@ func ScanStructural(data []byte, positions []uint32) int {
 	if len(data) == 0 {
 		return 0
 	}
+	if hasAVX512 && len(data) >= 64 {
+		return scanAVX512(data, positions)
+	}
 	if hasAVX2 && len(data) >= 32 {
 		return scanAVX2(data, positions)
 	}
@@ -27,6 +33,49 @@ func ScanStructural(data []byte, positions []uint32) int {
 //go:noescape
 func scanAVX2(data []byte, positions []uint32) int
 
+// scanAVX512 使用 AVX-512 指令扫描结构字符，每次处理 64 字节
+// 在汇编中实现
+//
+//go:noescape
+func scanAVX512(data []byte, positions []uint32) int
+
+// classifyBlocks 计算每个 64 字节块的结构字符、引号和反斜杠掩码，见 ScanStructuralSkipStrings
+// data 长度必须是 64 的整数倍，masks 长度为块数的 3 倍
+func classifyBlocks(data []byte, masks []uint64) {
+	if hasAVX2 {
+		classifyAVX2(data, masks)
+		return
+	}
+	classifyBlocksGeneric(data, masks)
+}
+
+// classifyAVX2 使用 AVX2 指令计算块掩码，每块分两次处理 32 字节
+// 在汇编中实现
+//
+//go:noescape
+func classifyAVX2(data []byte, masks []uint64)
+
+// scannerImpls 当前 CPU 可用的扫描实现，第一个为 ScanStructural 对长输入选用的实现
+func scannerImpls() []scannerImpl {
+	var impls []scannerImpl
+	if hasAVX512 {
+		impls = append(impls, scannerImpl{name: "avx512", scan: scanAVX512})
+	}
+	if hasAVX2 {
+		impls = append(impls, scannerImpl{name: "avx2", scan: scanAVX2})
+	}
+	return append(impls, scannerImpl{name: "generic", scan: scanGeneric})
+}
+
+// classifierImpls 当前 CPU 可用的块分类实现，第一个为 ScanStructuralSkipStrings 选用的实现
+func classifierImpls() []classifierImpl {
+	var impls []classifierImpl
+	if hasAVX2 {
+		impls = append(impls, classifierImpl{name: "avx2", classify: classifyAVX2})
+	}
+	return append(impls, classifierImpl{name: "generic", classify: classifyBlocksGeneric})
+}
+
 // scanGeneric 通用实现（无 SIMD）
 func scanGeneric(data []byte, positions []uint32) int {
 	count := 0
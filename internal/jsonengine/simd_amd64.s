@@ -139,3 +139,220 @@ done:
     MOVQ    R8, ret+48(FP)
     VZEROUPPER
     RET
+
+// func scanAVX512(data []byte, positions []uint32) int
+// 使用 AVX-512 (BW) 每次比较 64 字节，比较结果直接写入掩码寄存器
+// 返回找到的结构字符数量
+TEXT ·scanAVX512(SB), NOSPLIT, $0-56
+    MOVQ    data_base+0(FP), SI      // SI = data ptr
+    MOVQ    data_len+8(FP), CX       // CX = data len
+    MOVQ    positions_base+24(FP), DI // DI = positions ptr
+    MOVQ    positions_len+32(FP), R11 // R11 = positions capacity
+    XORQ    R8, R8                   // R8 = count (结果数量)
+    XORQ    R9, R9                   // R9 = offset (当前偏移)
+
+    // 广播结构字符到 ZMM 寄存器
+    MOVL    $0x22, AX                // "
+    VPBROADCASTB AX, Z1
+    MOVL    $0x7b, AX                // {
+    VPBROADCASTB AX, Z2
+    MOVL    $0x7d, AX                // }
+    VPBROADCASTB AX, Z3
+    MOVL    $0x5b, AX                // [
+    VPBROADCASTB AX, Z4
+    MOVL    $0x5d, AX                // ]
+    VPBROADCASTB AX, Z5
+    MOVL    $0x3a, AX                // :
+    VPBROADCASTB AX, Z6
+    MOVL    $0x2c, AX                // ,
+    VPBROADCASTB AX, Z7
+
+loop512:
+    // 检查是否还有 64 字节可处理
+    LEAQ    64(R9), R10
+    CMPQ    R10, CX
+    JA      tail512
+
+    // 加载 64 字节数据
+    VMOVDQU8 (SI)(R9*1), Z0
+
+    // 7 种结构字符分别比较，结果按位或到 K1
+    VPCMPEQB Z1, Z0, K1              // "
+    VPCMPEQB Z2, Z0, K2              // {
+    KORQ     K2, K1, K1
+    VPCMPEQB Z3, Z0, K2              // }
+    KORQ     K2, K1, K1
+    VPCMPEQB Z4, Z0, K2              // [
+    KORQ     K2, K1, K1
+    VPCMPEQB Z5, Z0, K2              // ]
+    KORQ     K2, K1, K1
+    VPCMPEQB Z6, Z0, K2              // :
+    KORQ     K2, K1, K1
+    VPCMPEQB Z7, Z0, K2              // ,
+    KORQ     K2, K1, K1
+
+    // 提取 64 位掩码
+    KMOVQ   K1, AX
+    TESTQ   AX, AX
+    JZ      next512
+
+extract512:
+    // 检查是否还有空间
+    CMPQ    R8, R11
+    JAE     done512
+
+    // 提取最低位 1 的位置
+    BSFQ    AX, BX
+    LEAQ    (R9)(BX*1), R10          // R10 = offset + bit position
+    MOVL    R10, (DI)(R8*4)          // 存储位置
+    INCQ    R8                       // count++
+
+    // 清除最低位 1
+    BLSRQ   AX, AX
+    JNZ     extract512
+
+next512:
+    ADDQ    $64, R9
+    JMP     loop512
+
+tail512:
+    // 处理剩余不足 64 字节的数据
+    CMPQ    R9, CX
+    JAE     done512
+
+    // 检查是否还有空间
+    CMPQ    R8, R11
+    JAE     done512
+
+    MOVBLZX (SI)(R9*1), AX
+
+    // 逐个比较结构字符
+    CMPB    AL, $0x22                // "
+    JE      found512
+    CMPB    AL, $0x7b                // {
+    JE      found512
+    CMPB    AL, $0x7d                // }
+    JE      found512
+    CMPB    AL, $0x5b                // [
+    JE      found512
+    CMPB    AL, $0x5d                // ]
+    JE      found512
+    CMPB    AL, $0x3a                // :
+    JE      found512
+    CMPB    AL, $0x2c                // ,
+    JE      found512
+    JMP     skip512
+
+found512:
+    MOVL    R9, (DI)(R8*4)
+    INCQ    R8
+
+skip512:
+    INCQ    R9
+    JMP     tail512
+
+done512:
+    MOVQ    R8, ret+48(FP)
+    VZEROUPPER
+    RET
+
+// func classifyAVX2(data []byte, masks []uint64)
+// 每 64 字节块依次写入 3 个掩码：结构字符（含引号）、引号、反斜杠
+// data 长度必须是 64 的整数倍
+TEXT ·classifyAVX2(SB), NOSPLIT, $0-48
+    MOVQ    data_base+0(FP), SI      // SI = data ptr
+    MOVQ    data_len+8(FP), CX       // CX = data len
+    MOVQ    masks_base+24(FP), DI    // DI = masks ptr
+    SHRQ    $6, CX                   // CX = 块数
+
+    // 广播结构字符和反斜杠到 YMM 寄存器
+    MOVQ    $0x22, AX                // "
+    MOVQ    AX, X1
+    VPBROADCASTB X1, Y1
+    MOVQ    $0x7b, AX                // {
+    MOVQ    AX, X2
+    VPBROADCASTB X2, Y2
+    MOVQ    $0x7d, AX                // }
+    MOVQ    AX, X3
+    VPBROADCASTB X3, Y3
+    MOVQ    $0x5b, AX                // [
+    MOVQ    AX, X4
+    VPBROADCASTB X4, Y4
+    MOVQ    $0x5d, AX                // ]
+    MOVQ    AX, X5
+    VPBROADCASTB X5, Y5
+    MOVQ    $0x3a, AX                // :
+    MOVQ    AX, X6
+    VPBROADCASTB X6, Y6
+    MOVQ    $0x2c, AX                // ,
+    MOVQ    AX, X7
+    VPBROADCASTB X7, Y7
+    MOVQ    $0x5c, AX                // \ (反斜杠)
+    MOVQ    AX, X11
+    VPBROADCASTB X11, Y11
+
+classify_loop:
+    TESTQ   CX, CX
+    JZ      classify_done
+
+    // 加载 64 字节：Y0 低 32 字节，Y10 高 32 字节
+    VMOVDQU (SI), Y0
+    VMOVDQU 32(SI), Y10
+
+    // 引号掩码
+    VPCMPEQB Y0, Y1, Y8
+    VPCMPEQB Y10, Y1, Y9
+    VPMOVMSKB Y8, AX
+    VPMOVMSKB Y9, DX
+    SHLQ    $32, DX
+    ORQ     DX, AX
+    MOVQ    AX, 8(DI)
+
+    // 结构字符掩码（在引号结果上继续合并）
+    VPCMPEQB Y0, Y2, Y12
+    VPOR     Y12, Y8, Y8
+    VPCMPEQB Y10, Y2, Y13
+    VPOR     Y13, Y9, Y9
+    VPCMPEQB Y0, Y3, Y12
+    VPOR     Y12, Y8, Y8
+    VPCMPEQB Y10, Y3, Y13
+    VPOR     Y13, Y9, Y9
+    VPCMPEQB Y0, Y4, Y12
+    VPOR     Y12, Y8, Y8
+    VPCMPEQB Y10, Y4, Y13
+    VPOR     Y13, Y9, Y9
+    VPCMPEQB Y0, Y5, Y12
+    VPOR     Y12, Y8, Y8
+    VPCMPEQB Y10, Y5, Y13
+    VPOR     Y13, Y9, Y9
+    VPCMPEQB Y0, Y6, Y12
+    VPOR     Y12, Y8, Y8
+    VPCMPEQB Y10, Y6, Y13
+    VPOR     Y13, Y9, Y9
+    VPCMPEQB Y0, Y7, Y12
+    VPOR     Y12, Y8, Y8
+    VPCMPEQB Y10, Y7, Y13
+    VPOR     Y13, Y9, Y9
+    VPMOVMSKB Y8, AX
+    VPMOVMSKB Y9, DX
+    SHLQ    $32, DX
+    ORQ     DX, AX
+    MOVQ    AX, (DI)
+
+    // 反斜杠掩码
+    VPCMPEQB Y0, Y11, Y8
+    VPCMPEQB Y10, Y11, Y9
+    VPMOVMSKB Y8, AX
+    VPMOVMSKB Y9, DX
+    SHLQ    $32, DX
+    ORQ     DX, AX
+    MOVQ    AX, 16(DI)
+
+    ADDQ    $64, SI
+    ADDQ    $24, DI
+    DECQ    CX
+    JMP     classify_loop
+
+classify_done:
+    VZEROUPPER
+    RET
@@ -12,6 +12,21 @@ func ScanStructural(data []byte, positions []uint32) int {
 	return scanGeneric(data, positions)
 }
 
+// classifyBlocks 计算每个 64 字节块的结构字符、引号和反斜杠掩码，见 ScanStructuralSkipStrings
+func classifyBlocks(data []byte, masks []uint64) {
+	classifyBlocksGeneric(data, masks)
+}
+
+// scannerImpls 当前平台可用的扫描实现
+func scannerImpls() []scannerImpl {
+	return []scannerImpl{{name: "generic", scan: scanGeneric}}
+}
+
+// classifierImpls 当前平台可用的块分类实现
+func classifierImpls() []classifierImpl {
+	return []classifierImpl{{name: "generic", classify: classifyBlocksGeneric}}
+}
+
 // scanGeneric 通用实现（无 SIMD）
 func scanGeneric(data []byte, positions []uint32) int {
 	// 使用查找表优化
@@ -0,0 +1,195 @@
+package jsonengine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ProcessSSE 按事件改写 Server-Sent Events 流中的 JSON 数据
+// 每个事件的 data 可能分多行发送，也可能被拆分在多次读取中：按空行重组出完整事件后，
+// 将多行 data 按规范以换行拼接为一个 JSON 文档处理，再按输出中的换行重新拆分为 data 行。
+// event/id/retry 字段和注释行原样保留；data 不是 JSON 对象或数组（如 [DONE]）的事件原样输出。
+// 每个事件以一次 Write 写出，便于调用方逐事件刷新。
+// 流在事件中途结束时，未以空行结束的事件原样输出。
+// 单个事件处理失败（如严格模式下的非法 JSON）时该事件原样输出并继续，结束后返回第一个错误。
+// 返回结果汇总所有事件：Applied 与 Stats 累加，Captured 中后出现的值覆盖先出现的值。
+func (e *PathEngine) ProcessSSE(input io.Reader, output io.Writer) (ProcessResult, error) {
+	s := &sseProcessor{engine: e, output: output}
+	reader := bufio.NewReaderSize(input, 64*1024)
+	for {
+		line, err := s.readLine(reader)
+		if len(line) > 0 || err == nil {
+			if writeErr := s.handleLine(line, err == nil); writeErr != nil {
+				return s.result, writeErr
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				return s.result, err
+			}
+			break
+		}
+	}
+	// 流在事件中途结束：已收到的部分原样输出，不丢弃数据
+	if err := s.dispatch(false); err != nil {
+		return s.result, err
+	}
+	return s.result, s.err
+}
+
+// sseProcessor SSE 事件重组状态
+type sseProcessor struct {
+	engine *PathEngine
+	output io.Writer
+	result ProcessResult
+	err    error // 第一个事件处理错误
+
+	lineBuf   []byte   // 跨越读缓冲区的长行
+	lines     [][]byte // 当前事件的原始行（不含换行）
+	dataLine  int      // 第一个 data 行在 lines 中的下标，-1 表示没有 data
+	data      []byte   // 拼接后的 data
+	hasData   bool
+	partial   bool // 最后一行没有换行（流在行中途结束）
+	outputBuf []byte
+}
+
+// readLine 读取一行（不含行尾的 \n 或 \r\n），返回的切片在下次读取前有效
+// 最后一行没有换行时与 io.EOF 一起返回
+func (s *sseProcessor) readLine(r *bufio.Reader) ([]byte, error) {
+	s.lineBuf = s.lineBuf[:0]
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			s.lineBuf = append(s.lineBuf, chunk...)
+			continue
+		}
+		line := chunk
+		if len(s.lineBuf) > 0 {
+			s.lineBuf = append(s.lineBuf, chunk...)
+			line = s.lineBuf
+		}
+		if err == nil {
+			line = bytes.TrimSuffix(line[:len(line)-1], []byte{'\r'})
+		}
+		return line, err
+	}
+}
+
+// handleLine 处理一行；terminated 表示该行以换行结束
+func (s *sseProcessor) handleLine(line []byte, terminated bool) error {
+	if len(line) == 0 && terminated {
+		return s.dispatch(true)
+	}
+	if len(s.lines) == 0 {
+		s.dataLine = -1
+	}
+	s.partial = !terminated
+	s.lines = append(s.lines, append([]byte(nil), line...))
+
+	field, value, _ := bytes.Cut(line, []byte{':'})
+	if string(field) != "data" {
+		return nil
+	}
+	value = bytes.TrimPrefix(value, []byte{' '})
+	if s.dataLine < 0 {
+		s.dataLine = len(s.lines) - 1
+		s.data = append(s.data[:0], value...)
+		s.hasData = true
+	} else {
+		s.data = append(append(s.data, '\n'), value...)
+	}
+	return nil
+}
+
+// dispatch 输出当前事件；terminated 表示事件以空行结束
+func (s *sseProcessor) dispatch(terminated bool) error {
+	if len(s.lines) == 0 {
+		if terminated {
+			_, err := s.output.Write([]byte{'\n'})
+			return err
+		}
+		return nil
+	}
+
+	// 不完整的事件无法安全改写，原样输出
+	var rewritten []byte
+	ok := false
+	if terminated {
+		rewritten, ok = s.processData()
+	}
+	buf := s.outputBuf[:0]
+	for i, line := range s.lines {
+		if !ok || !s.hasData || i < s.dataLine {
+			buf = append(append(buf, line...), '\n')
+			continue
+		}
+		if i == s.dataLine {
+			for _, dataLine := range bytes.Split(rewritten, []byte{'\n'}) {
+				buf = append(append(append(buf, "data: "...), bytes.TrimSuffix(dataLine, []byte{'\r'})...), '\n')
+			}
+			continue
+		}
+		// 其余 data 行已合并输出
+		if field, _, _ := bytes.Cut(line, []byte{':'}); string(field) != "data" {
+			buf = append(append(buf, line...), '\n')
+		}
+	}
+	if terminated {
+		buf = append(buf, '\n')
+	} else if s.partial {
+		buf = bytes.TrimSuffix(buf, []byte{'\n'})
+	}
+	s.outputBuf = buf
+
+	s.lines = s.lines[:0]
+	s.hasData = false
+	s.data = s.data[:0]
+	_, err := s.output.Write(buf)
+	return err
+}
+
+// processData 改写当前事件的 data，不是 JSON 或处理失败时返回 false
+func (s *sseProcessor) processData() ([]byte, bool) {
+	if !s.hasData {
+		return nil, false
+	}
+	trimmed := bytes.TrimSpace(s.data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return nil, false
+	}
+	// [DONE] 这类结束标记以 [ 开头但不是 JSON
+	if trimmed[0] == '[' && !json.Valid(trimmed) {
+		return nil, false
+	}
+
+	out, result, err := s.engine.ProcessBytesWithResult(s.data)
+	if err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		return nil, false
+	}
+	s.merge(result)
+	// 未改写时原样输出原始行，保持字节一致
+	if bytes.Equal(out, s.data) {
+		return nil, false
+	}
+	return out, true
+}
+
+func (s *sseProcessor) merge(result ProcessResult) {
+	r := &s.result
+	r.Applied += result.Applied
+	if len(result.Captured) > 0 {
+		if r.Captured == nil {
+			r.Captured = make(map[string]json.RawMessage, len(result.Captured))
+		}
+		for name, value := range result.Captured {
+			r.Captured[name] = value
+		}
+	}
+	r.Stats.add(result.Stats)
+}
@@ -0,0 +1,135 @@
+package jsonengine
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// EngineStats 处理统计
+type EngineStats struct {
+	Documents   int64         // 处理的文档数（含无规则原样透传）
+	BytesIn     int64         // 输入字节数
+	BytesOut    int64         // 输出字节数（WithIndent/WithCompact 格式化之前）
+	Removed     int64         // 删除的字段和数组元素（含白名单模式下丢弃的字段）
+	Set         int64         // 被 set 替换的值
+	Added       int64         // add 添加的字段
+	Renamed     int64         // rename 改名的字段
+	Transformed int64         // 被 mask/transform/clamp/copy 改写的值
+	Duration    time.Duration // 处理耗时，不含等待输入的时间
+}
+
+// add 累加另一份统计
+func (s *EngineStats) add(o EngineStats) {
+	s.Documents += o.Documents
+	s.BytesIn += o.BytesIn
+	s.BytesOut += o.BytesOut
+	s.Removed += o.Removed
+	s.Set += o.Set
+	s.Added += o.Added
+	s.Renamed += o.Renamed
+	s.Transformed += o.Transformed
+	s.Duration += o.Duration
+}
+
+// engineStats PathEngine 的累计统计，可被多个 goroutine 同时更新
+type engineStats struct {
+	documents   atomic.Int64
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+	removed     atomic.Int64
+	set         atomic.Int64
+	added       atomic.Int64
+	renamed     atomic.Int64
+	transformed atomic.Int64
+	duration    atomic.Int64
+}
+
+func (s *engineStats) add(d EngineStats) {
+	s.documents.Add(d.Documents)
+	s.bytesIn.Add(d.BytesIn)
+	s.bytesOut.Add(d.BytesOut)
+	s.removed.Add(d.Removed)
+	s.set.Add(d.Set)
+	s.added.Add(d.Added)
+	s.renamed.Add(d.Renamed)
+	s.transformed.Add(d.Transformed)
+	s.duration.Add(int64(d.Duration))
+}
+
+// snapshot 读取统计，reset 为 true 时同时清零
+func (s *engineStats) snapshot(reset bool) EngineStats {
+	load := func(v *atomic.Int64) int64 {
+		if reset {
+			return v.Swap(0)
+		}
+		return v.Load()
+	}
+	return EngineStats{
+		Documents:   load(&s.documents),
+		BytesIn:     load(&s.bytesIn),
+		BytesOut:    load(&s.bytesOut),
+		Removed:     load(&s.removed),
+		Set:         load(&s.set),
+		Added:       load(&s.added),
+		Renamed:     load(&s.renamed),
+		Transformed: load(&s.transformed),
+		Duration:    time.Duration(load(&s.duration)),
+	}
+}
+
+// Stats 返回引擎创建（或上次 ResetStats）以来的累计统计
+// 包括 Process/ProcessWithResult/ProcessBytes/AppendProcess 以及通过 ReleaseProcessor 归还的流式处理器；
+// Explain 试运行不计入。单次调用的统计见 ProcessResult.Stats。
+func (e *PathEngine) Stats() EngineStats {
+	return e.stats.snapshot(false)
+}
+
+// ResetStats 返回累计统计并清零，适合按周期导出增量
+// 与并发处理同时调用时各字段分别清零，同一次处理的统计可能分属前后两个周期
+func (e *PathEngine) ResetStats() EngineStats {
+	return e.stats.snapshot(true)
+}
+
+// recordPassthrough 记录一次原样透传
+func (e *PathEngine) recordPassthrough(n int64) {
+	e.stats.add(EngineStats{Documents: 1, BytesIn: n, BytesOut: n})
+}
+
+// countApplied 记录一次生效的操作
+func (p *PathProcessor) countApplied(action Action) {
+	p.applied++
+	switch action {
+	case ActionRemove:
+		p.stats.Removed++
+	case ActionSet:
+		p.stats.Set++
+	case ActionAdd:
+		p.stats.Added++
+	case ActionRename:
+		p.stats.Renamed++
+	default:
+		p.stats.Transformed++
+	}
+}
+
+// Stats 返回处理器本次处理的统计
+func (p *PathProcessor) Stats() EngineStats {
+	stats := p.stats
+	stats.Documents = 1
+	stats.BytesIn = int64(p.consumed)
+	stats.BytesOut = p.out.n
+	return stats
+}
+
+// countingWriter 统计处理器写出的字节数，作为处理器字段复用以避免每个 chunk 分配
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,371 @@
+package jsonengine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError 严格模式下输入不是合法 JSON 时返回的错误
+type SyntaxError struct {
+	Offset int64  // 出错字节在输入中的偏移
+	Path   string // 出错位置所在的路径，如 choices[0].message
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Sprintf("invalid JSON at offset %d near %s: %s", e.Offset, path, e.Msg)
+}
+
+// 语法检查器的结构状态
+const (
+	csValue      = iota // 期望值
+	csValueOrEnd        // 数组刚开始：值或 ]
+	csKeyOrEnd          // 对象刚开始：键或 }
+	csKey               // 逗号之后：键
+	csColon             // 键之后：冒号
+	csCommaOrEnd        // 值之后：逗号或容器结束
+	csDone              // 顶层值已结束
+)
+
+// 当前正在读取的标量
+const (
+	ctNone = iota
+	ctString
+	ctNumber
+	ctLiteral
+)
+
+// 数字读取状态
+const (
+	nsMinus = iota
+	nsZero
+	nsInt
+	nsDot
+	nsFrac
+	nsE
+	nsESign
+	nsExp
+)
+
+// maxContextKeyLen 错误路径中单个键保留的最大长度
+const maxContextKeyLen = 64
+
+type checkFrame struct {
+	object bool
+	key    string
+	index  int
+}
+
+// syntaxChecker 增量 JSON 语法检查器
+// 与 PathProcessor 接收相同的数据块，在处理前发现非法输入，记录偏移和所在路径
+type syntaxChecker struct {
+	offset   int64
+	state    int
+	stack    []checkFrame
+	maxDepth int // 0 表示不限制
+
+	token    int
+	strIsKey bool
+	escape   int // 0 无转义，-1 刚读到反斜杠，>0 剩余的 \u 十六进制位数
+	key      []byte
+	numState int
+	literal  string
+	litPos   int
+}
+
+func newSyntaxChecker() *syntaxChecker {
+	return &syntaxChecker{state: csValue}
+}
+
+// newSyntaxChecker 创建带引擎深度限制的语法检查器
+func (e *PathEngine) newSyntaxChecker() *syntaxChecker {
+	sc := newSyntaxChecker()
+	sc.maxDepth = e.limits.maxDepth
+	return sc
+}
+
+// push 进入对象或数组
+func (sc *syntaxChecker) push(frame checkFrame) error {
+	if sc.maxDepth > 0 && len(sc.stack) >= sc.maxDepth {
+		return &LimitError{Limit: LimitDepth, Max: int64(sc.maxDepth), Offset: sc.offset}
+	}
+	sc.stack = append(sc.stack, frame)
+	return nil
+}
+
+// Feed 检查下一个数据块
+func (sc *syntaxChecker) Feed(chunk []byte) error {
+	for _, c := range chunk {
+		if err := sc.step(c); err != nil {
+			return err
+		}
+		sc.offset++
+	}
+	return nil
+}
+
+// Finish 检查输入是否在一个完整的顶层值之后结束
+func (sc *syntaxChecker) Finish() error {
+	if sc.token == ctNumber {
+		switch sc.numState {
+		case nsZero, nsInt, nsFrac, nsExp:
+			sc.token = ctNone
+			sc.valueDone()
+		}
+	}
+	if sc.token != ctNone || sc.state != csDone {
+		if sc.offset == 0 {
+			return sc.errorf("empty input")
+		}
+		return sc.errorf("unexpected end of input")
+	}
+	return nil
+}
+
+func (sc *syntaxChecker) step(c byte) error {
+	switch sc.token {
+	case ctString:
+		return sc.stepString(c)
+	case ctLiteral:
+		if c != sc.literal[sc.litPos] {
+			return sc.errorf("invalid literal, expected %q", sc.literal)
+		}
+		sc.litPos++
+		if sc.litPos == len(sc.literal) {
+			sc.token = ctNone
+			sc.valueDone()
+		}
+		return nil
+	case ctNumber:
+		done, err := sc.stepNumber(c)
+		if err != nil || !done {
+			return err
+		}
+		// 数字在分隔符处结束，分隔符按结构字符继续处理
+		sc.token = ctNone
+		sc.valueDone()
+	}
+
+	switch c {
+	case ' ', '\t', '\r', '\n':
+		return nil
+	}
+
+	switch sc.state {
+	case csValue, csValueOrEnd:
+		if c == ']' && sc.state == csValueOrEnd {
+			sc.pop()
+			return nil
+		}
+		return sc.beginValue(c)
+
+	case csKeyOrEnd, csKey:
+		if c == '}' && sc.state == csKeyOrEnd {
+			sc.pop()
+			return nil
+		}
+		if c != '"' {
+			if sc.state == csKeyOrEnd {
+				return sc.errorf("expected string key or '}', got %q", c)
+			}
+			return sc.errorf("expected string key, got %q", c)
+		}
+		sc.stack[len(sc.stack)-1].key = ""
+		sc.token = ctString
+		sc.strIsKey = true
+		sc.key = sc.key[:0]
+		return nil
+
+	case csColon:
+		if c != ':' {
+			return sc.errorf("expected ':' after object key, got %q", c)
+		}
+		sc.state = csValue
+		return nil
+
+	case csCommaOrEnd:
+		top := &sc.stack[len(sc.stack)-1]
+		switch {
+		case c == ',' && top.object:
+			sc.state = csKey
+		case c == ',':
+			top.index++
+			sc.state = csValue
+		case c == '}' && top.object, c == ']' && !top.object:
+			sc.pop()
+		case top.object:
+			return sc.errorf("expected ',' or '}', got %q", c)
+		default:
+			return sc.errorf("expected ',' or ']', got %q", c)
+		}
+		return nil
+
+	default: // csDone
+		return sc.errorf("unexpected data after top-level value")
+	}
+}
+
+func (sc *syntaxChecker) beginValue(c byte) error {
+	switch {
+	case c == '{':
+		if err := sc.push(checkFrame{object: true}); err != nil {
+			return err
+		}
+		sc.state = csKeyOrEnd
+	case c == '[':
+		if err := sc.push(checkFrame{}); err != nil {
+			return err
+		}
+		sc.state = csValueOrEnd
+	case c == '"':
+		sc.token = ctString
+		sc.strIsKey = false
+	case c == '-':
+		sc.token, sc.numState = ctNumber, nsMinus
+	case c == '0':
+		sc.token, sc.numState = ctNumber, nsZero
+	case c >= '1' && c <= '9':
+		sc.token, sc.numState = ctNumber, nsInt
+	case c == 't':
+		sc.token, sc.literal, sc.litPos = ctLiteral, "true", 1
+	case c == 'f':
+		sc.token, sc.literal, sc.litPos = ctLiteral, "false", 1
+	case c == 'n':
+		sc.token, sc.literal, sc.litPos = ctLiteral, "null", 1
+	default:
+		return sc.errorf("unexpected character %q, expected a value", c)
+	}
+	return nil
+}
+
+func (sc *syntaxChecker) stepString(c byte) error {
+	closing := sc.escape == 0 && c == '"'
+	if sc.strIsKey && !closing && len(sc.key) < maxContextKeyLen {
+		sc.key = append(sc.key, c)
+	}
+
+	switch {
+	case sc.escape == -1:
+		switch c {
+		case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			sc.escape = 0
+		case 'u':
+			sc.escape = 4
+		default:
+			return sc.errorf("invalid escape character %q in string", c)
+		}
+	case sc.escape > 0:
+		if !isHexDigit(c) {
+			return sc.errorf("invalid character %q in \\u escape", c)
+		}
+		sc.escape--
+	case c == '\\':
+		sc.escape = -1
+	case closing:
+		sc.token = ctNone
+		if sc.strIsKey {
+			sc.stack[len(sc.stack)-1].key = string(sc.key)
+			sc.state = csColon
+		} else {
+			sc.valueDone()
+		}
+	case c < 0x20:
+		return sc.errorf("control character %q in string", c)
+	}
+	return nil
+}
+
+// stepNumber 推进数字状态，返回 true 表示数字已在 c 之前结束
+func (sc *syntaxChecker) stepNumber(c byte) (bool, error) {
+	digit := c >= '0' && c <= '9'
+	switch sc.numState {
+	case nsMinus:
+		switch {
+		case c == '0':
+			sc.numState = nsZero
+		case digit:
+			sc.numState = nsInt
+		default:
+			return false, sc.errorf("invalid number: expected digit after '-'")
+		}
+	case nsZero, nsInt, nsFrac:
+		switch {
+		case digit && sc.numState != nsZero:
+		case c == '.' && sc.numState != nsFrac:
+			sc.numState = nsDot
+		case c == 'e' || c == 'E':
+			sc.numState = nsE
+		default:
+			return true, nil
+		}
+	case nsDot:
+		if !digit {
+			return false, sc.errorf("invalid number: expected digit after '.'")
+		}
+		sc.numState = nsFrac
+	case nsE:
+		switch {
+		case c == '+' || c == '-':
+			sc.numState = nsESign
+		case digit:
+			sc.numState = nsExp
+		default:
+			return false, sc.errorf("invalid number: expected digit in exponent")
+		}
+	case nsESign:
+		if !digit {
+			return false, sc.errorf("invalid number: expected digit in exponent")
+		}
+		sc.numState = nsExp
+	case nsExp:
+		if !digit {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (sc *syntaxChecker) pop() {
+	sc.stack = sc.stack[:len(sc.stack)-1]
+	sc.valueDone()
+}
+
+func (sc *syntaxChecker) valueDone() {
+	if len(sc.stack) == 0 {
+		sc.state = csDone
+	} else {
+		sc.state = csCommaOrEnd
+	}
+}
+
+func (sc *syntaxChecker) errorf(format string, args ...any) error {
+	return &SyntaxError{Offset: sc.offset, Path: sc.path(), Msg: fmt.Sprintf(format, args...)}
+}
+
+// path 以点号语法描述当前位置
+func (sc *syntaxChecker) path() string {
+	var b strings.Builder
+	for _, frame := range sc.stack {
+		if !frame.object {
+			b.WriteString("[" + strconv.Itoa(frame.index) + "]")
+			continue
+		}
+		if frame.key == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(frame.key)
+	}
+	return b.String()
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
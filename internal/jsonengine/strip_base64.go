@@ -0,0 +1,351 @@
+package jsonengine
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// stripDataURLPrefixMax data URL 前缀（data:<mime>;base64,）的最大长度，超过时按普通 base64 处理
+const stripDataURLPrefixMax = 256
+
+// stripMimeKeys 对象值中记录 MIME 类型的字段（Gemini inlineData、OpenAI、Anthropic source）
+var stripMimeKeys = []string{"mimeType", "mime_type", "media_type"}
+
+// stripSpec ActionStripBase64 规则的配置
+type stripSpec struct {
+	field string // 对象值中 base64 数据所在的字段名
+}
+
+// stripHole 对象值中被移除的 base64 数据
+type stripHole struct {
+	pos   int   // 在 obj 中的位置
+	bytes int64 // 解码后的字节数
+}
+
+// compileStripBase64 校验 strip_base64 规则：Value 为对象值中数据字段的名称（可选，默认 data）
+func compileStripBase64(rule PathRule) (*stripSpec, error) {
+	spec := &stripSpec{field: "data"}
+	if rule.Value != nil {
+		field, ok := rule.Value.(string)
+		if !ok || field == "" {
+			return nil, &PathError{Msg: "strip_base64 value must be the data field name"}
+		}
+		spec.field = field
+	}
+	return spec, nil
+}
+
+// transform 返回对完整原值处理的转换函数（all_apply 等需要组合多个转换时使用）
+func (s *stripSpec) transform() func([]byte) []byte {
+	return func(raw []byte) []byte {
+		var t base64Stripper
+		t.reset(s)
+		return t.append(nil, raw)
+	}
+}
+
+// appendStripPlaceholder 输出替代 base64 数据的占位对象
+func appendStripPlaceholder(dst, mime []byte, n int64) []byte {
+	dst = append(dst, `{"stripped":true`...)
+	if len(mime) > 0 {
+		dst = append(dst, `,"mimeType":"`...)
+		dst = append(dst, mime...)
+		dst = append(dst, '"')
+	}
+	dst = append(dst, `,"bytes":`...)
+	dst = strconv.AppendInt(dst, n, 10)
+	return append(dst, '}')
+}
+
+// base64Stripper 流式地把 base64 数据替换为 {"stripped":true,"mimeType":...,"bytes":N} 占位对象
+// 字符串值整体替换，data URL（data:image/png;base64,...）的 MIME 类型取自前缀；
+// 对象值（如 Gemini 的 inlineData）只替换 spec.field 字段，MIME 类型取自同一对象的 mimeType/mime_type/media_type 字段，
+// 对象的其余内容原样保留，在对象结束时输出。base64 数据只计数不缓冲，原值可在任意字节处分块送入。
+// 其他类型的值原样输出。
+type base64Stripper struct {
+	spec    *stripSpec
+	mode    int
+	escaped bool // 字符串内刚读到反斜杠
+	counter base64Counter
+
+	// 字符串值
+	head     []byte // 尚未确定是否为 data URL 的开头部分（原始字节）
+	headDone bool
+	mime     []byte // data URL 的 MIME 类型（JSON 字符串内容）
+
+	// 对象值
+	obj        []byte      // 除 base64 数据外的对象内容
+	holes      []stripHole // 被移除的数据
+	doc        []byte      // 查找 MIME 类型时补全的对象
+	key        []byte      // 最近读取的字段名（原始字节）
+	depth      int
+	inString   bool
+	inKey      bool
+	expectKey  bool
+	awaitValue bool // 读到冒号，等待字段值开始
+	inPayload  bool
+}
+
+// base64Stripper 的处理阶段
+const (
+	stripStart  = iota // 值的首个非空白字节之前
+	stripString        // 字符串值
+	stripObject        // 对象值
+	stripPass          // 其他类型的值，或值已处理完
+)
+
+// reset 开始处理新的值
+func (t *base64Stripper) reset(spec *stripSpec) {
+	*t = base64Stripper{
+		spec:  spec,
+		head:  t.head[:0],
+		mime:  t.mime[:0],
+		obj:   t.obj[:0],
+		holes: t.holes[:0],
+		doc:   t.doc[:0],
+		key:   t.key[:0],
+	}
+}
+
+// append 送入原值的下一段字节，将应输出的部分追加到 dst
+func (t *base64Stripper) append(dst, data []byte) []byte {
+	for i := 0; i < len(data); i++ {
+		switch t.mode {
+		case stripStart:
+			switch data[i] {
+			case ' ', '\t', '\n', '\r':
+				dst = append(dst, data[i])
+			case '"':
+				t.mode = stripString
+			case '{':
+				t.mode = stripObject
+				t.obj = append(t.obj, '{')
+				t.depth = 1
+				t.expectKey = true
+			default:
+				t.mode = stripPass
+				return append(dst, data[i:]...)
+			}
+
+		case stripString:
+			end := t.scanString(data[i:])
+			if end < 0 {
+				t.stringContent(data[i:])
+				return dst
+			}
+			t.stringContent(data[i : i+end])
+			if !t.headDone {
+				t.finishHead()
+			}
+			dst = appendStripPlaceholder(dst, t.mime, t.counter.bytes())
+			t.mode = stripPass
+			return append(dst, data[i+end+1:]...)
+
+		case stripObject:
+			if t.inPayload {
+				end := t.scanString(data[i:])
+				if end < 0 {
+					t.counter.write(data[i:])
+					return dst
+				}
+				t.counter.write(data[i : i+end])
+				t.holes[len(t.holes)-1].bytes = t.counter.bytes()
+				t.inPayload = false
+				i += end
+				continue
+			}
+			if t.objectByte(data[i]) {
+				dst = t.appendObject(dst)
+				t.mode = stripPass
+				return append(dst, data[i+1:]...)
+			}
+
+		default:
+			return append(dst, data[i:]...)
+		}
+	}
+	return dst
+}
+
+// scanString 返回字符串结束引号的位置，未结束时返回 -1
+func (t *base64Stripper) scanString(data []byte) int {
+	for i := 0; i < len(data); i++ {
+		if t.escaped {
+			t.escaped = false
+			continue
+		}
+		j := bytes.IndexAny(data[i:], `"\`)
+		if j < 0 {
+			return -1
+		}
+		i += j
+		if data[i] == '"' {
+			return i
+		}
+		t.escaped = true
+	}
+	return -1
+}
+
+// stringContent 处理字符串值的内容：先收集开头判断是否为 data URL，之后只计数
+func (t *base64Stripper) stringContent(raw []byte) {
+	if t.headDone {
+		t.counter.write(raw)
+		return
+	}
+	n := min(len(raw), stripDataURLPrefixMax-len(t.head))
+	t.head = append(t.head, raw[:n]...)
+	if bytes.IndexByte(t.head, ',') >= 0 || len(t.head) == stripDataURLPrefixMax ||
+		!bytes.HasPrefix(t.head, []byte("data:")[:min(len(t.head), 5)]) {
+		t.finishHead()
+		t.counter.write(raw[n:])
+	}
+}
+
+// finishHead 确定字符串是否为 data URL：是则记录 MIME 类型并从逗号之后开始计数，否则整体计数
+func (t *base64Stripper) finishHead() {
+	t.headDone = true
+	rest := t.head
+	if meta, ok := bytes.CutPrefix(t.head, []byte("data:")); ok {
+		if comma := bytes.IndexByte(meta, ','); comma >= 0 && bytes.HasSuffix(meta[:comma], []byte(";base64")) {
+			mime, _, _ := bytes.Cut(meta[:comma], []byte(";"))
+			t.mime = append(t.mime[:0], mime...)
+			rest = meta[comma+1:]
+		}
+	}
+	t.counter.write(rest)
+}
+
+// objectByte 处理对象值中 base64 数据之外的一个字节，返回对象是否结束
+func (t *base64Stripper) objectByte(c byte) bool {
+	if t.inString {
+		switch {
+		case t.escaped:
+			t.escaped = false
+		case c == '\\':
+			t.escaped = true
+		case c == '"':
+			t.inString = false
+			t.inKey = false
+			t.obj = append(t.obj, c)
+			return false
+		}
+		t.obj = append(t.obj, c)
+		if t.inKey {
+			t.key = append(t.key, c)
+		}
+		return false
+	}
+
+	if t.awaitValue && c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+		t.awaitValue = false
+		if c == '"' && string(t.key) == t.spec.field {
+			t.inPayload = true
+			t.holes = append(t.holes, stripHole{pos: len(t.obj)})
+			t.counter = base64Counter{}
+			return false
+		}
+	}
+
+	t.obj = append(t.obj, c)
+	switch c {
+	case '"':
+		t.inString = true
+		if t.depth == 1 && t.expectKey {
+			t.inKey = true
+			t.expectKey = false
+			t.key = t.key[:0]
+		}
+	case ':':
+		t.awaitValue = t.depth == 1
+	case ',':
+		t.expectKey = t.depth == 1
+	case '{', '[':
+		t.depth++
+	case '}', ']':
+		t.depth--
+		return t.depth == 0
+	}
+	return false
+}
+
+// appendObject 输出处理完的对象，被移除的数据替换为占位对象
+func (t *base64Stripper) appendObject(dst []byte) []byte {
+	if len(t.holes) == 0 {
+		return append(dst, t.obj...)
+	}
+	mime := t.objectMime()
+	prev := 0
+	for _, h := range t.holes {
+		dst = append(dst, t.obj[prev:h.pos]...)
+		dst = appendStripPlaceholder(dst, mime, h.bytes)
+		prev = h.pos
+	}
+	return append(dst, t.obj[prev:]...)
+}
+
+// objectMime 在同一对象中查找 MIME 类型字段，返回其 JSON 字符串内容
+func (t *base64Stripper) objectMime() []byte {
+	// 被移除的数据处补 null，得到可解析的对象
+	t.doc = t.doc[:0]
+	prev := 0
+	for _, h := range t.holes {
+		t.doc = append(t.doc, t.obj[prev:h.pos]...)
+		t.doc = append(t.doc, "null"...)
+		prev = h.pos
+	}
+	t.doc = append(t.doc, t.obj[prev:]...)
+
+	for _, key := range stripMimeKeys {
+		if v, ok := Get(t.doc, key); ok && v.Type == ValueString {
+			return v.Raw[1 : len(v.Raw)-1]
+		}
+	}
+	return nil
+}
+
+// base64Counter 统计 JSON 字符串内容中的 base64 字符数，转义序列按解码后的字符计
+// 忽略填充符 = 和换行等其他字符，兼容标准和 URL 安全字母表
+type base64Counter struct {
+	n    int64
+	esc  int  // 0：不在转义中；1：刚读到反斜杠；2-5：\u 之后已读的十六进制位数加 2
+	code rune // \u 转义的码点
+}
+
+// write 送入字符串内容的下一段原始字节
+func (c *base64Counter) write(data []byte) {
+	for _, b := range data {
+		switch {
+		case c.esc == 1:
+			c.esc = 0
+			if b == 'u' {
+				c.esc, c.code = 2, 0
+			} else if b == '/' {
+				c.n++
+			}
+		case c.esc > 1:
+			c.code = c.code<<4 | rune(hexValue(b))
+			if c.esc++; c.esc == 6 {
+				c.esc = 0
+				if c.code < 0x80 && isBase64Char(byte(c.code)) {
+					c.n++
+				}
+			}
+		case b == '\\':
+			c.esc = 1
+		case isBase64Char(b):
+			c.n++
+		}
+	}
+}
+
+// bytes 返回解码后的字节数
+func (c *base64Counter) bytes() int64 {
+	return c.n * 3 / 4
+}
+
+// isBase64Char 检查是否为 base64 字母表中的字符（含 URL 安全字母表的 - 和 _）
+func isBase64Char(b byte) bool {
+	return b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' ||
+		b == '+' || b == '/' || b == '-' || b == '_'
+}
@@ -0,0 +1,208 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestPathEngineStripBase64(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "gemini inline data",
+			rules:  []PathRule{{Path: "candidates[*].content.parts[*].inlineData", Action: ActionStripBase64}},
+			input:  `{"candidates":[{"content":{"parts":[{"text":"hi"},{"inlineData":{"mimeType":"image/png","data":"iVBORw0KGgo="}}]}}],"n":1}`,
+			expect: `{"candidates":[{"content":{"parts":[{"text":"hi"},{"inlineData":{"mimeType":"image/png","data":{"stripped":true,"mimeType":"image/png","bytes":8}}}]}}],"n":1}`,
+		},
+		{
+			name:   "mime type after data",
+			rules:  []PathRule{{Path: "inlineData", Action: ActionStripBase64}},
+			input:  `{"inlineData": { "data" : "AAAA", "mimeType": "image/jpeg" }}`,
+			expect: `{"inlineData": { "data" : {"stripped":true,"mimeType":"image/jpeg","bytes":3}, "mimeType": "image/jpeg" }}`,
+		},
+		{
+			name:   "anthropic source",
+			rules:  []PathRule{{Path: "content[*].source", Action: ActionStripBase64}},
+			input:  `{"content":[{"type":"image","source":{"type":"base64","media_type":"image/gif","data":"R0lGODlh"}}]}`,
+			expect: `{"content":[{"type":"image","source":{"type":"base64","media_type":"image/gif","data":{"stripped":true,"mimeType":"image/gif","bytes":6}}}]}`,
+		},
+		{
+			name:   "custom data field",
+			rules:  []PathRule{{Path: "file", Action: ActionStripBase64, Value: "content"}},
+			input:  `{"file":{"data":"keep","content":"QUJD","mime_type":"text/plain"}}`,
+			expect: `{"file":{"data":"keep","content":{"stripped":true,"mimeType":"text/plain","bytes":3},"mime_type":"text/plain"}}`,
+		},
+		{
+			name:   "data url string",
+			rules:  []PathRule{{Path: "messages[*].content[*].image_url.url", Action: ActionStripBase64}},
+			input:  `{"messages":[{"content":[{"image_url":{"url":"data:image/png;base64,AAAABBBB"}}]}]}`,
+			expect: `{"messages":[{"content":[{"image_url":{"url":{"stripped":true,"mimeType":"image/png","bytes":6}}}]}]}`,
+		},
+		{
+			name:   "plain string with escapes and padding",
+			rules:  []PathRule{{Path: "data", Action: ActionStripBase64}},
+			input:  `{"data":"AAA\/\n\u0041AA=","x":"data:"}`,
+			expect: `{"data":{"stripped":true,"bytes":5},"x":"data:"}`,
+		},
+		{
+			name:   "nested and quoted members untouched",
+			rules:  []PathRule{{Path: "o", Action: ActionStripBase64}},
+			input:  `{"o":{"meta":{"data":"AAAA"},"note":"a\"data\":\"x","data":"QUJD"}}`,
+			expect: `{"o":{"meta":{"data":"AAAA"},"note":"a\"data\":\"x","data":{"stripped":true,"bytes":3}}}`,
+		},
+		{
+			name:   "array elements",
+			rules:  []PathRule{{Path: "images[*]", Action: ActionStripBase64}},
+			input:  `{"images":[ "QUJD" ,null,{"data":"QQ=="}]}`,
+			expect: `{"images":[ {"stripped":true,"bytes":3} ,null,{"data":{"stripped":true,"bytes":1}}]}`,
+		},
+		{
+			name:   "other values untouched",
+			rules:  []PathRule{{Path: "*", Action: ActionStripBase64}},
+			input:  `{"a":12345,"b":[1,"QUJD"],"c":{"x":"QUJD"},"d":true}`,
+			expect: `{"a":12345,"b":[1,"QUJD"],"c":{"x":"QUJD"},"d":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, chunk := range []int{1, 2, 3, 7, 4096} {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(chunk))
+			if err != nil {
+				t.Fatalf("%s: NewPathEngine error: %v", tt.name, err)
+			}
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("%s: Process error: %v", tt.name, err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("%s (chunk %d): got %s, want %s", tt.name, chunk, out.String(), tt.expect)
+			}
+		}
+	}
+
+	for _, rule := range []PathRule{
+		{Path: "a", Action: ActionStripBase64, Value: 1},
+		{Path: "a", Action: ActionStripBase64, Value: ""},
+	} {
+		if _, err := NewPathEngine([]PathRule{rule}); err == nil {
+			t.Errorf("expected error for strip_base64 rule %+v", rule)
+		}
+		if issues := ValidateRules([]PathRule{rule}); len(issues) != 1 || issues[0].Code != IssueInvalidValue {
+			t.Errorf("ValidateRules(%+v) = %+v, want one invalid_value issue", rule, issues)
+		}
+	}
+}
+
+func TestPathEngineStripBase64Combined(t *testing.T) {
+	rules := []PathRule{
+		{Path: "inlineData", Action: ActionStripBase64},
+		{Path: "inlineData", Action: ActionCapture},
+	}
+	input := []byte(`{"inlineData":{"mimeType":"image/webp","data":"UklGRg=="}}`)
+	engine, err := NewPathEngine(rules, WithConflictMode(ConflictAllApply), WithChunkSize(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, result, err := engine.ProcessBytesWithResult(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"inlineData":{"mimeType":"image/webp","data":{"stripped":true,"mimeType":"image/webp","bytes":4}}}`
+	if string(out) != want {
+		t.Errorf("all_apply output = %s", out)
+	}
+	if string(result.Captured["inlineData"]) != `{"mimeType":"image/webp","data":"UklGRg=="}` {
+		t.Errorf("captured = %s, want the original value", result.Captured["inlineData"])
+	}
+
+	engine, err = NewPathEngine(rules[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := engine.Explain(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || string(reports[0].After) != `{"mimeType":"image/webp","data":{"stripped":true,"mimeType":"image/webp","bytes":4}}` {
+		t.Errorf("Explain = %+v", reports)
+	}
+}
+
+// TestStripBase64Differential 随机数据的 base64 编码（混合转义的 / 和换行）按随机分块处理，
+// 占位对象中的字节数与原始数据长度比对
+func TestStripBase64Differential(t *testing.T) {
+	for seed := int64(0); seed < 500; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		payload := make([]byte, rng.Intn(300))
+		rng.Read(payload)
+
+		encoding := base64.StdEncoding
+		if rng.Intn(2) == 0 {
+			encoding = base64.RawURLEncoding
+		}
+		var sb strings.Builder
+		for i, c := range encoding.EncodeToString(payload) {
+			if i > 0 && i%76 == 0 && rng.Intn(2) == 0 {
+				sb.WriteString(`\n`)
+			}
+			if c == '/' && rng.Intn(2) == 0 {
+				sb.WriteString(`\/`)
+			} else {
+				sb.WriteRune(c)
+			}
+		}
+		data := sb.String()
+
+		var raw string
+		var mime string
+		switch rng.Intn(3) {
+		case 0:
+			raw = `"` + data + `"`
+		case 1:
+			mime = "image/png"
+			raw = `"data:image/png;base64,` + data + `"`
+		default:
+			mime = "audio/wav"
+			raw = `{"data":"` + data + `","mimeType":"audio/wav"}`
+		}
+
+		var st base64Stripper
+		st.reset(&stripSpec{field: "data"})
+		var out []byte
+		for in := []byte(raw); len(in) > 0; {
+			n := min(rng.Intn(8)+1, len(in))
+			out = st.append(out, in[:n])
+			in = in[n:]
+		}
+
+		var placeholder struct {
+			Stripped bool   `json:"stripped"`
+			MimeType string `json:"mimeType"`
+			Bytes    int    `json:"bytes"`
+		}
+		target := out
+		if raw[0] == '{' {
+			var obj struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal(out, &obj); err != nil {
+				t.Fatalf("seed %d: invalid output %s: %v", seed, out, err)
+			}
+			target = obj.Data
+		}
+		if err := json.Unmarshal(target, &placeholder); err != nil {
+			t.Fatalf("seed %d: invalid placeholder %s: %v", seed, out, err)
+		}
+		if !placeholder.Stripped || placeholder.MimeType != mime || placeholder.Bytes != len(payload) {
+			t.Fatalf("seed %d: %s => %s, want mime %q and %d bytes", seed, raw, out, mime, len(payload))
+		}
+	}
+}
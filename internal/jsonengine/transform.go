@@ -0,0 +1,216 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 字符串转换操作（ActionTransform 规则的 op）
+const (
+	TransformReplace     = "replace"      // 正则替换：pattern、replacement（支持 $1 引用）
+	TransformStripPrefix = "strip_prefix" // 去除前缀：prefix
+	TransformStripSuffix = "strip_suffix" // 去除后缀：suffix
+	TransformTrim        = "trim"         // 去除首尾空白
+	TransformLower       = "lower"        // 转小写
+	TransformUpper       = "upper"        // 转大写
+)
+
+// StringTransform 字符串转换配置
+type StringTransform struct {
+	Op          string `json:"op"`
+	Pattern     string `json:"pattern,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+	Prefix      string `json:"prefix,omitempty"`
+	Suffix      string `json:"suffix,omitempty"`
+}
+
+// ParseStringTransform 解析转换配置并返回转换函数
+// value 可以是操作名字符串（如 "lower"）、StringTransform 对象或其 JSON 字符串，
+// 代码内置的规则也可以直接传入转换函数
+func ParseStringTransform(value any) (func(string) string, error) {
+	var t StringTransform
+	switch v := value.(type) {
+	case func(string) string:
+		if v == nil {
+			return nil, &PathError{Msg: "transform func is nil"}
+		}
+		return v, nil
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(v), "{") {
+			if err := json.Unmarshal([]byte(v), &t); err != nil {
+				return nil, &PathError{Msg: "invalid transform config: " + err.Error()}
+			}
+		} else {
+			t.Op = v
+		}
+	case StringTransform:
+		t = v
+	case *StringTransform:
+		if v == nil {
+			return nil, &PathError{Msg: "transform config is required"}
+		}
+		t = *v
+	case map[string]any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, &PathError{Msg: "invalid transform config: " + err.Error()}
+		}
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, &PathError{Msg: "invalid transform config: " + err.Error()}
+		}
+	default:
+		return nil, &PathError{Msg: "transform config must be an operation name or an object"}
+	}
+	return t.compile()
+}
+
+// compile 校验配置并生成转换函数
+func (t StringTransform) compile() (func(string) string, error) {
+	switch t.Op {
+	case TransformReplace:
+		re, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return nil, &PathError{Msg: "invalid transform pattern: " + err.Error()}
+		}
+		replacement := t.Replacement
+		return func(s string) string { return re.ReplaceAllString(s, replacement) }, nil
+	case TransformStripPrefix:
+		if t.Prefix == "" {
+			return nil, &PathError{Msg: "strip_prefix requires a prefix"}
+		}
+		prefix := t.Prefix
+		return func(s string) string { return strings.TrimPrefix(s, prefix) }, nil
+	case TransformStripSuffix:
+		if t.Suffix == "" {
+			return nil, &PathError{Msg: "strip_suffix requires a suffix"}
+		}
+		suffix := t.Suffix
+		return func(s string) string { return strings.TrimSuffix(s, suffix) }, nil
+	case TransformTrim:
+		return strings.TrimSpace, nil
+	case TransformLower:
+		return strings.ToLower, nil
+	case TransformUpper:
+		return strings.ToUpper, nil
+	default:
+		return nil, &PathError{Msg: "invalid transform op: " + t.Op}
+	}
+}
+
+// valueStreamer 边读边输出转换结果的值操作（truncate、strip_base64），原值可在任意字节处分块送入
+type valueStreamer interface {
+	append(dst, data []byte) []byte
+}
+
+// compileValueTransform 为 Mask/Transform/Clamp/Copy/Truncate/StripBase64/Plugin 规则生成原值转换函数，其他操作返回 nil
+func compileValueTransform(rule PathRule) (func([]byte) []byte, error) {
+	var stringTransform func(string) string
+	var err error
+	switch rule.Action {
+	case ActionMask:
+		stringTransform, err = parseMaskMode(rule.Value)
+	case ActionTransform:
+		stringTransform, err = ParseStringTransform(rule.Value)
+	case ActionClamp:
+		return compileClamp(rule)
+	case ActionCopy:
+		return compileCopy(rule)
+	case ActionTruncate:
+		spec, err := compileTruncate(rule)
+		if err != nil {
+			return nil, err
+		}
+		return spec.transform(), nil
+	case ActionStripBase64:
+		spec, err := compileStripBase64(rule)
+		if err != nil {
+			return nil, err
+		}
+		return spec.transform(), nil
+	case ActionPlugin:
+		return compilePlugin(rule)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func(raw []byte) []byte { return transformStringValue(raw, stringTransform) }, nil
+}
+
+// compileClamp 校验 clamp 规则并生成数值限制函数
+func compileClamp(rule PathRule) (func([]byte) []byte, error) {
+	if rule.Min == nil && rule.Max == nil && rule.Default == nil {
+		return nil, &PathError{Msg: "clamp requires min, max or default"}
+	}
+	if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+		return nil, &PathError{Msg: "clamp min is greater than max"}
+	}
+	minVal, maxVal := rule.Min, rule.Max
+	return func(raw []byte) []byte { return clampNumber(raw, minVal, maxVal) }, nil
+}
+
+// fieldTarget 返回 rename/copy 规则的目标字段名
+// 源路径的最后一段必须是具体字段名，目标字段与源字段位于同一对象内
+func fieldTarget(rule PathRule, segments []Segment) (string, error) {
+	if len(segments) == 0 || segments[len(segments)-1].Type != SegField {
+		return "", &PathError{Msg: string(rule.Action) + " requires a field name as the last path segment"}
+	}
+	target, ok := rule.Value.(string)
+	if !ok || target == "" {
+		return "", &PathError{Msg: string(rule.Action) + " requires the target field name as value"}
+	}
+	return target, nil
+}
+
+// compileCopy 生成复制函数：原值之后追加 ,"target":原值
+func compileCopy(rule PathRule) (func([]byte) []byte, error) {
+	target, err := fieldTarget(rule, rule.segments)
+	if err != nil {
+		return nil, err
+	}
+	key := marshalString(target)
+	return func(raw []byte) []byte {
+		out := make([]byte, 0, 2*len(raw)+len(key)+2)
+		out = append(out, raw...)
+		out = append(out, ',')
+		out = append(out, key...)
+		out = append(out, ':')
+		return append(out, raw...)
+	}, nil
+}
+
+// clampNumber 将原始 JSON 数值限制在 [min, max] 内，非数值原样返回
+func clampNumber(raw []byte, minVal, maxVal *float64) []byte {
+	trimmed := bytes.TrimSpace(raw)
+	value, err := strconv.ParseFloat(string(trimmed), 64)
+	if err != nil {
+		return raw
+	}
+	if minVal != nil && value < *minVal {
+		return marshalFloat(*minVal)
+	}
+	if maxVal != nil && value > *maxVal {
+		return marshalFloat(*maxVal)
+	}
+	return raw
+}
+
+// transformStringValue 对原始 JSON 值应用字符串转换
+// 只处理字符串，其他类型原样返回
+func transformStringValue(raw []byte, transform func(string) string) []byte {
+	trimmed := bytes.TrimSpace(raw)
+	if transform == nil || len(trimmed) == 0 || trimmed[0] != '"' {
+		return raw
+	}
+
+	var s string
+	if err := json.Unmarshal(trimmed, &s); err != nil {
+		return raw
+	}
+
+	return marshalString(transform(s))
+}
@@ -0,0 +1,166 @@
+package jsonengine
+
+import (
+	"bytes"
+	"math"
+)
+
+// truncateSpec ActionTruncate 规则的配置
+type truncateSpec struct {
+	max    int    // 保留的最大字符数
+	suffix []byte // 发生截断时追加的后缀（已转义，不含引号）
+}
+
+// compileTruncate 校验 truncate 规则：Max 为保留的最大字符数（正整数），Value 为可选的后缀字符串
+func compileTruncate(rule PathRule) (*truncateSpec, error) {
+	if rule.Max == nil || *rule.Max < 1 || *rule.Max != math.Trunc(*rule.Max) || *rule.Max > math.MaxInt32 {
+		return nil, &PathError{Msg: "truncate requires max as a positive integer"}
+	}
+	spec := &truncateSpec{max: int(*rule.Max)}
+	if rule.Value != nil {
+		suffix, ok := rule.Value.(string)
+		if !ok {
+			return nil, &PathError{Msg: "truncate suffix must be a string"}
+		}
+		quoted := marshalString(suffix)
+		spec.suffix = quoted[1 : len(quoted)-1]
+	}
+	return spec, nil
+}
+
+// transform 返回对完整原值截断的转换函数（all_apply 等需要组合多个转换时使用）
+func (s *truncateSpec) transform() func([]byte) []byte {
+	return func(raw []byte) []byte {
+		var t stringTruncator
+		t.reset(s)
+		return t.append(nil, raw)
+	}
+}
+
+// stringTruncator 流式截断字符串值
+// 按解码后的字符计数：转义序列计为一个字符，\uD83D\uDE00 这样的代理对整体计为一个字符，
+// 只在字符边界截断，不会拆开多字节 UTF-8 序列或转义序列；原值可在任意字节处分块送入，
+// 读到超出长度的字符后只跟踪转义和结束引号，不再缓冲内容。非字符串值原样输出。
+type stringTruncator struct {
+	spec     *truncateSpec
+	started  bool   // 已读到值的首个非空白字节
+	isString bool   // 值是字符串
+	closed   bool   // 已读到结束引号
+	count    int    // 已输出的字符数
+	emit     bool   // 当前字符是否输出（UTF-8 后续字节和低位代理项跟随所属字符）
+	cut      bool   // 有字符被丢弃
+	high     bool   // 上一个字符是 \u 转义的高位代理项
+	esc      []byte // 读取中的转义序列（含反斜杠），可能跨数据块
+}
+
+// reset 开始截断新的值
+func (t *stringTruncator) reset(spec *truncateSpec) {
+	*t = stringTruncator{spec: spec, esc: t.esc[:0]}
+}
+
+// append 送入原值的下一段字节，将应输出的部分追加到 dst
+func (t *stringTruncator) append(dst, data []byte) []byte {
+	start := 0 // 待输出片段的起点
+	drop := func(i int) {
+		dst = append(dst, data[start:i]...)
+		start = i + 1
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case !t.started:
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				t.started = true
+				t.isString = c == '"'
+			}
+			continue
+		case !t.isString || t.closed:
+			continue
+		case len(t.esc) > 0:
+			drop(i)
+			t.esc = append(t.esc, c)
+			if !t.escapeComplete() {
+				continue
+			}
+			high, low := t.surrogate()
+			if !(low && t.high) {
+				t.nextChar()
+			}
+			if t.emit {
+				dst = append(dst, t.esc...)
+			}
+			t.high = high
+			t.esc = t.esc[:0]
+			continue
+		}
+
+		// 已截断：直接跳到下一个反斜杠或引号
+		if t.cut {
+			j := bytes.IndexAny(data[i:], `"\`)
+			if j < 0 {
+				start = len(data)
+				break
+			}
+			i += j
+			start = i
+			c = data[i]
+		}
+
+		switch {
+		case c == '"':
+			t.closed = true
+			if t.cut {
+				dst = append(dst, data[start:i]...)
+				dst = append(dst, t.spec.suffix...)
+				start = i
+			}
+		case c == '\\':
+			drop(i)
+			t.esc = append(t.esc, c)
+		case c&0xc0 == 0x80:
+			// UTF-8 后续字节
+			if !t.emit {
+				drop(i)
+			}
+		default:
+			t.high = false
+			if !t.nextChar() {
+				drop(i)
+			}
+		}
+	}
+	return append(dst, data[start:]...)
+}
+
+// nextChar 开始一个新字符，返回是否输出
+func (t *stringTruncator) nextChar() bool {
+	if t.count < t.spec.max {
+		t.count++
+		t.emit = true
+	} else {
+		t.emit = false
+		t.cut = true
+	}
+	return t.emit
+}
+
+// escapeComplete 检查转义序列是否读取完整（\uXXXX 共 6 字节，其余 2 字节）
+func (t *stringTruncator) escapeComplete() bool {
+	if len(t.esc) < 2 {
+		return false
+	}
+	return t.esc[1] != 'u' || len(t.esc) == 6
+}
+
+// surrogate 返回完整的转义序列是否为高位或低位代理项
+func (t *stringTruncator) surrogate() (high, low bool) {
+	if t.esc[1] != 'u' {
+		return false, false
+	}
+	var r rune
+	for _, c := range t.esc[2:] {
+		r = r<<4 | rune(hexValue(c))
+	}
+	return r >= 0xd800 && r < 0xdc00, r >= 0xdc00 && r < 0xe000
+}
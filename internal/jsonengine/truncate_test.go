@@ -0,0 +1,183 @@
+package jsonengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPathEngineTruncate(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		rules  []PathRule
+		input  string
+		expect string
+	}{
+		{
+			name:   "ascii with suffix",
+			rules:  []PathRule{{Path: "text", Action: ActionTruncate, Max: f(5), Value: "..."}},
+			input:  `{"text":"hello world","n":1}`,
+			expect: `{"text":"hello...","n":1}`,
+		},
+		{
+			name:   "exact length untouched",
+			rules:  []PathRule{{Path: "text", Action: ActionTruncate, Max: f(5), Value: "..."}},
+			input:  `{"text":"hello","n":1}`,
+			expect: `{"text":"hello","n":1}`,
+		},
+		{
+			name:   "multi-byte characters",
+			rules:  []PathRule{{Path: "text", Action: ActionTruncate, Max: f(2), Value: "…"}},
+			input:  `{"text":"你好世界😀"}`,
+			expect: `{"text":"你好…"}`,
+		},
+		{
+			name:   "escapes count as one character",
+			rules:  []PathRule{{Path: "text", Action: ActionTruncate, Max: f(3)}},
+			input:  `{"text":"a\nb\"c\\d"}`,
+			expect: `{"text":"a\nb"}`,
+		},
+		{
+			name:   "surrogate pair kept whole",
+			rules:  []PathRule{{Path: "text", Action: ActionTruncate, Max: f(2)}},
+			input:  `{"text":"x\ud83d\ude00\u00e9y"}`,
+			expect: `{"text":"x\ud83d\ude00"}`,
+		},
+		{
+			name:   "suffix is escaped",
+			rules:  []PathRule{{Path: "text", Action: ActionTruncate, Max: f(1), Value: "\" [cut]"}},
+			input:  `{"text":"abc"}`,
+			expect: `{"text":"a\" [cut]"}`,
+		},
+		{
+			name:   "nested array paths",
+			rules:  []PathRule{{Path: "candidates[*].content.parts[*].text", Action: ActionTruncate, Max: f(4)}},
+			input:  `{"candidates":[{"content":{"parts":[{"text":"first part"},{"text":"ok"}]}},{"content":{"parts":[{"text":"second, with ] and }"}]}}]}`,
+			expect: `{"candidates":[{"content":{"parts":[{"text":"firs"},{"text":"ok"}]}},{"content":{"parts":[{"text":"seco"}]}}]}`,
+		},
+		{
+			name:   "array elements",
+			rules:  []PathRule{{Path: "items[*]", Action: ActionTruncate, Max: f(2)}},
+			input:  `{"items":[ "abc" ,1,"de",null]}`,
+			expect: `{"items":[ "ab" ,1,"de",null]}`,
+		},
+		{
+			name:   "non-string values untouched",
+			rules:  []PathRule{{Path: "*", Action: ActionTruncate, Max: f(1)}},
+			input:  `{"a":12345,"b":{"c":"long"},"d":[1,2],"e":true, "f" : "xy" }`,
+			expect: `{"a":12345,"b":{"c":"long"},"d":[1,2],"e":true, "f" : "x" }`,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, chunk := range []int{1, 2, 3, 7, 4096} {
+			engine, err := NewPathEngine(tt.rules, WithChunkSize(chunk))
+			if err != nil {
+				t.Fatalf("%s: NewPathEngine error: %v", tt.name, err)
+			}
+			var out bytes.Buffer
+			if err := engine.Process(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("%s: Process error: %v", tt.name, err)
+			}
+			if out.String() != tt.expect {
+				t.Errorf("%s (chunk %d): got %s, want %s", tt.name, chunk, out.String(), tt.expect)
+			}
+		}
+	}
+
+	invalid := []PathRule{
+		{Path: "a", Action: ActionTruncate},
+		{Path: "a", Action: ActionTruncate, Max: f(0)},
+		{Path: "a", Action: ActionTruncate, Max: f(1.5)},
+		{Path: "a", Action: ActionTruncate, Max: f(3), Value: 1},
+	}
+	for _, rule := range invalid {
+		if _, err := NewPathEngine([]PathRule{rule}); err == nil {
+			t.Errorf("expected error for truncate rule %+v", rule)
+		}
+		if issues := ValidateRules([]PathRule{rule}); len(issues) != 1 || issues[0].Code != IssueInvalidValue {
+			t.Errorf("ValidateRules(%+v) = %+v, want one invalid_value issue", rule, issues)
+		}
+	}
+}
+
+func TestPathEngineTruncateCombined(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	rules := []PathRule{
+		{Path: "text", Action: ActionTruncate, Max: f(3), Value: "~", Priority: 1},
+		{Path: "text", Action: ActionTransform, Value: TransformUpper},
+		{Path: "text", Action: ActionCapture},
+	}
+	engine, err := NewPathEngine(rules, WithConflictMode(ConflictAllApply), WithChunkSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, result, err := engine.ProcessBytesWithResult([]byte(`{"text":"hello"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"text":"HEL~"}` {
+		t.Errorf("all_apply output = %s", out)
+	}
+	if string(result.Captured["text"]) != `"hello"` {
+		t.Errorf("captured = %s, want the original value", result.Captured["text"])
+	}
+
+	engine, err = NewPathEngine(rules[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := engine.Explain([]byte(`{"text":"hello"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || string(reports[0].Before) != `"hello"` || string(reports[0].After) != `"hel~"` {
+		t.Errorf("Explain = %+v", reports)
+	}
+}
+
+// TestTruncateDifferential 随机字符串（混合原始 UTF-8、转义和代理对）按随机分块截断，
+// 与解码后按字符截断的结果比对
+func TestTruncateDifferential(t *testing.T) {
+	pieces := []string{"a", "Z", " ", "é", "你", "😀", `\n`, `\"`, `\\`, `\/`, `\u00e9`, `\u4f60`, `\ud83d\ude00`, `\ud83d`, `\ude00`, `\u0000`}
+	for seed := int64(0); seed < 500; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		var sb strings.Builder
+		for n := rng.Intn(20); n > 0; n-- {
+			sb.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		raw := `"` + sb.String() + `"`
+		var decoded string
+		if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+			t.Fatalf("seed %d: invalid test string %s: %v", seed, raw, err)
+		}
+
+		limit := rng.Intn(12) + 1
+		spec := &truncateSpec{max: limit, suffix: []byte("+")}
+		var tr stringTruncator
+		tr.reset(spec)
+		var out []byte
+		for data := []byte(raw); len(data) > 0; {
+			n := min(rng.Intn(4)+1, len(data))
+			out = tr.append(out, data[:n])
+			data = data[n:]
+		}
+
+		var got string
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatalf("seed %d: truncating %s to %d produced invalid JSON %s: %v", seed, raw, limit, out, err)
+		}
+		want := decoded
+		if utf8.RuneCountInString(decoded) > limit {
+			want = string([]rune(decoded)[:limit]) + "+"
+		}
+		if got != want {
+			t.Fatalf("seed %d: truncating %s to %d = %s (%q), want %q", seed, raw, limit, out, got, want)
+		}
+	}
+}
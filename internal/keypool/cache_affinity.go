@@ -0,0 +1,69 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+	"gpt-load/internal/circuit"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cacheBindingKey 缓存上下文 -> 创建它的密钥 ID
+func cacheBindingKey(groupID uint, cacheID string) string {
+	return fmt.Sprintf("group:%d:cache:%s", groupID, cacheID)
+}
+
+// BindCache 记录缓存上下文由哪个密钥创建，在缓存过期前同一缓存的请求都使用该密钥。
+func (p *KeyProvider) BindCache(groupID uint, cacheID string, keyID uint, ttl time.Duration) {
+	if cacheID == "" || ttl <= 0 {
+		return
+	}
+	value := []byte(strconv.FormatUint(uint64(keyID), 10))
+	if err := p.store.Set(cacheBindingKey(groupID, cacheID), value, ttl); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "keyID": keyID, "error": err}).Error("Failed to bind cached context to key")
+	}
+}
+
+// UnbindCache 删除缓存上下文与密钥的绑定。
+func (p *KeyProvider) UnbindCache(groupID uint, cacheID string) {
+	if err := p.store.Delete(cacheBindingKey(groupID, cacheID)); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to unbind cached context")
+	}
+}
+
+// SelectCachedKey 返回缓存上下文绑定的密钥。
+// 没有绑定、密钥已不可用、处于限流冷却或熔断器打开时返回 nil，由调用方回退到常规选择。
+func (p *KeyProvider) SelectCachedKey(group *models.Group, cacheID string) (*models.APIKey, error) {
+	raw, err := p.store.Get(cacheBindingKey(group.ID, cacheID))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get cache binding: %w", err)
+	}
+
+	keyID, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	keyDetails, err := p.store.HGetAll(fmt.Sprintf("key:%d", keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+	}
+	if keyDetails["status"] != models.KeyStatusActive || keyDetails["group_id"] != strconv.FormatUint(uint64(group.ID), 10) {
+		return nil, nil
+	}
+	if group.EffectiveConfig.EnableAdaptiveCooldown && !keyCooldownUntil(keyDetails, time.Now()).IsZero() {
+		return nil, nil
+	}
+	if !p.keyBreakers.Allow(strconv.FormatUint(keyID, 10), circuit.SettingsFrom(&group.EffectiveConfig)) {
+		return nil, nil
+	}
+
+	return p.buildAPIKey(uint(keyID), group.ID, keyDetails), nil
+}
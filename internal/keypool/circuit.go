@@ -0,0 +1,17 @@
+package keypool
+
+import (
+	"errors"
+	"strconv"
+
+	"gpt-load/internal/circuit"
+	"gpt-load/internal/models"
+)
+
+// ErrKeyCircuitOpen 本次选择尝试的密钥熔断器均处于打开状态。
+var ErrKeyCircuitOpen = errors.New("circuit breakers of the selected keys are open")
+
+// RecordKeyResult 记录一次请求在指定密钥上的结果，用于密钥熔断器的失败率统计。
+func (p *KeyProvider) RecordKeyResult(group *models.Group, keyID uint, success bool) {
+	p.keyBreakers.Record(strconv.FormatUint(uint64(keyID), 10), circuit.SettingsFrom(&group.EffectiveConfig), success)
+}
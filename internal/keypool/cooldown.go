@@ -0,0 +1,84 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+// keyCooldownField 密钥冷却结束的时间（Unix 毫秒），保存在密钥 HASH 中，多实例共享
+const keyCooldownField = "cooldown_until"
+
+// ErrKeyCoolingDown 本次选择尝试的密钥均处于限流冷却中。
+var ErrKeyCoolingDown = errors.New("selected keys are cooling down after upstream rate limits")
+
+// CooldownUntil 返回 429 响应中上游要求等待到的时间：优先使用 Retry-After，
+// 其次是已耗尽维度的重置时间，都没有时取报告的最晚重置时间。未携带相关头时返回零值。
+func (s *RateLimitState) CooldownUntil() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	if !s.RetryAfter.IsZero() {
+		return s.RetryAfter
+	}
+
+	var exhausted, latest time.Time
+	for _, w := range []*RateLimitWindow{s.Requests, s.Tokens} {
+		if w == nil || w.Reset.IsZero() {
+			continue
+		}
+		if w.Remaining <= 0 && w.Reset.After(exhausted) {
+			exhausted = w.Reset
+		}
+		if w.Reset.After(latest) {
+			latest = w.Reset
+		}
+	}
+	if !exhausted.IsZero() {
+		return exhausted
+	}
+	return latest
+}
+
+// CoolDownKey 使密钥冷却到 until，期间选择密钥时跳过该密钥。
+// 冷却时长不超过分组的 max_key_cooldown_seconds；已过去的时间不会产生冷却。
+func (p *KeyProvider) CoolDownKey(group *models.Group, keyID uint, until time.Time) (time.Time, error) {
+	now := time.Now()
+	if !until.After(now) {
+		return time.Time{}, nil
+	}
+	maxCooldown := time.Duration(group.EffectiveConfig.MaxKeyCooldownSeconds) * time.Second
+	if latest := now.Add(maxCooldown); until.After(latest) {
+		until = latest
+	}
+
+	if err := p.store.HSet(fmt.Sprintf("key:%d", keyID), map[string]any{keyCooldownField: until.UnixMilli()}); err != nil {
+		return time.Time{}, fmt.Errorf("failed to set key cooldown: %w", err)
+	}
+	return until, nil
+}
+
+// KeyAvailableAt 返回密钥冷却结束的时间，未在冷却中时返回零值。
+func (p *KeyProvider) KeyAvailableAt(keyID uint) (time.Time, error) {
+	keyDetails, err := p.store.HGetAll(fmt.Sprintf("key:%d", keyID))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return keyCooldownUntil(keyDetails, time.Now()), nil
+}
+
+// keyCooldownUntil 解析密钥 HASH 中的冷却结束时间，已结束或未设置时返回零值。
+func keyCooldownUntil(keyDetails map[string]string, now time.Time) time.Time {
+	ms, err := strconv.ParseInt(keyDetails[keyCooldownField], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	until := time.UnixMilli(ms)
+	if !until.After(now) {
+		return time.Time{}
+	}
+	return until
+}
@@ -0,0 +1,102 @@
+package keypool
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyEWMAAlpha 延迟 EWMA 的平滑系数
+	latencyEWMAAlpha = 0.2
+	// latencyStaleAfter 超过该时长没有新样本的延迟统计视为过期
+	latencyStaleAfter = time.Minute
+	// p95ZScore 正态分布下 p95 对应的标准差倍数
+	p95ZScore = 1.645
+)
+
+// keyLoad 单个密钥在本实例上的负载与延迟统计
+type keyLoad struct {
+	inFlight   int
+	mean       float64 // 延迟 EWMA（毫秒）
+	variance   float64 // 延迟方差的 EWMA
+	sampledAt  time.Time
+	hasSamples bool
+}
+
+// loadTracker 记录各密钥进行中的请求数和响应延迟，仅在本实例内统计。
+type loadTracker struct {
+	mu   sync.Mutex
+	keys map[string]*keyLoad
+}
+
+func newLoadTracker() *loadTracker {
+	return &loadTracker{keys: make(map[string]*keyLoad)}
+}
+
+func (t *loadTracker) get(keyID string) *keyLoad {
+	load, ok := t.keys[keyID]
+	if !ok {
+		load = &keyLoad{}
+		t.keys[keyID] = load
+	}
+	return load
+}
+
+func (t *loadTracker) inFlight(keyID string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if load, ok := t.keys[keyID]; ok {
+		return load.inFlight
+	}
+	return 0
+}
+
+// p95 返回按 EWMA 均值和方差估算的 p95 延迟（毫秒），没有近期样本时返回 0。
+func (t *loadTracker) p95(keyID string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	load, ok := t.keys[keyID]
+	if !ok || !load.hasSamples || time.Since(load.sampledAt) > latencyStaleAfter {
+		return 0
+	}
+	return load.mean + p95ZScore*math.Sqrt(load.variance)
+}
+
+// BeginRequest 记录密钥开始一次上游请求，返回的函数在请求结束时调用，可重复调用。
+func (p *KeyProvider) BeginRequest(keyID uint) (done func()) {
+	id := strconv.FormatUint(uint64(keyID), 10)
+	p.load.mu.Lock()
+	p.load.get(id).inFlight++
+	p.load.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.load.mu.Lock()
+			defer p.load.mu.Unlock()
+			if load := p.load.get(id); load.inFlight > 0 {
+				load.inFlight--
+			}
+		})
+	}
+}
+
+// RecordLatency 记录密钥一次上游请求从发出到收到响应头的延迟。
+func (p *KeyProvider) RecordLatency(keyID uint, latency time.Duration) {
+	ms := float64(latency) / float64(time.Millisecond)
+	p.load.mu.Lock()
+	defer p.load.mu.Unlock()
+
+	load := p.load.get(strconv.FormatUint(uint64(keyID), 10))
+	if !load.hasSamples || time.Since(load.sampledAt) > latencyStaleAfter {
+		load.mean, load.variance, load.hasSamples = ms, 0, true
+	} else {
+		diff := ms - load.mean
+		incr := latencyEWMAAlpha * diff
+		load.mean += incr
+		load.variance = (1 - latencyEWMAAlpha) * (load.variance + diff*incr)
+	}
+	load.sampledAt = time.Now()
+}
@@ -3,6 +3,7 @@ package keypool
 import (
 	"errors"
 	"fmt"
+	"gpt-load/internal/circuit"
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
@@ -11,6 +12,7 @@ import (
 	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -22,44 +24,99 @@ type KeyProvider struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	encryptionSvc   encryption.Service
+
+	usageSweepMu sync.Mutex
+	usageSweptAt map[uint]int64 // groupID -> 已清理到的小时桶
+
+	keyBreakers *circuit.Registry
+	load        *loadTracker
+	strategies  map[string]Strategy
 }
 
 // NewProvider 创建一个新的 KeyProvider 实例。
 func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service) *KeyProvider {
-	return &KeyProvider{
+	p := &KeyProvider{
 		db:              db,
 		store:           store,
 		settingsManager: settingsManager,
 		encryptionSvc:   encryptionSvc,
+		usageSweptAt:    make(map[uint]int64),
+		keyBreakers:     circuit.NewRegistry("key"),
+		load:            newLoadTracker(),
 	}
+	p.strategies = p.defaultStrategies()
+	return p
 }
 
-// SelectKey 为指定的分组原子性地选择并轮换一个可用的 APIKey。
-func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
-	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
-
-	// 1. Atomically rotate the key ID from the list
-	keyIDStr, err := p.store.Rotate(activeKeysListKey)
-	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return nil, app_errors.ErrNoActiveKeys
+// SelectKey 为指定的分组选择一个可用的 APIKey，由分组的 key_selection_strategy 决定选择方式。
+// 处于预热期的密钥按其流量权重概率性跳过，限流冷却中或熔断器打开的密钥直接跳过，
+// 本实例上并发已满的密钥在有其他候选时跳过。最多尝试全部活跃密钥后才返回冷却或熔断错误。
+func (p *KeyProvider) SelectKey(group *models.Group) (*models.APIKey, error) {
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
+
+	warmup := time.Duration(group.EffectiveConfig.KeyWarmupMinutes) * time.Minute
+	breakers := circuit.SettingsFrom(&group.EffectiveConfig)
+	cooldown := group.EffectiveConfig.EnableAdaptiveCooldown
+	keyLimit := group.EffectiveConfig.KeyMaxConcurrentRequests
+	attempts := 1
+	if warmup > 0 || cooldown || keyLimit > 0 || breakers.Enabled() {
+		if n, err := p.store.LLen(activeKeysListKey); err == nil && n > 1 {
+			attempts = int(n)
 		}
-		return nil, fmt.Errorf("failed to rotate key from store: %w", err)
 	}
 
-	keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
-	}
+	var keyID uint64
+	var keyDetails map[string]string
+	var skipped map[string]struct{}
+	for i := range attempts {
+		keyIDStr, err := p.nextKeyID(group, activeKeysListKey, skipped)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, app_errors.ErrNoActiveKeys
+			}
+			return nil, fmt.Errorf("failed to select key from store: %w", err)
+		}
 
-	// 2. Get key details from HASH
-	keyHashKey := fmt.Sprintf("key:%d", keyID)
-	keyDetails, err := p.store.HGetAll(keyHashKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+		keyID, err = strconv.ParseUint(keyIDStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
+		}
+
+		keyDetails, err = p.store.HGetAll(fmt.Sprintf("key:%d", keyID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+		}
+
+		// 最后一次尝试时即使仍在预热也直接使用
+		warming := i < attempts-1 && skipWarmingKey(keyDetails, warmup)
+		saturated := i < attempts-1 && keyLimit > 0 && p.load.inFlight(keyIDStr) >= keyLimit
+		coolingDown := cooldown && !keyCooldownUntil(keyDetails, time.Now()).IsZero()
+		if !warming && !saturated && !coolingDown && p.keyBreakers.Allow(keyIDStr, breakers) {
+			break
+		}
+		if i == attempts-1 {
+			if coolingDown {
+				return nil, ErrKeyCoolingDown
+			}
+			return nil, ErrKeyCircuitOpen
+		}
+		if skipped == nil {
+			skipped = make(map[string]struct{}, attempts)
+		}
+		skipped[keyIDStr] = struct{}{}
 	}
 
-	// 3. Manually unmarshal the map into an APIKey struct
+	return p.buildAPIKey(uint(keyID), group.ID, keyDetails), nil
+}
+
+// nextKeyID 按分组的密钥选择策略取出下一个密钥 ID。
+func (p *KeyProvider) nextKeyID(group *models.Group, activeKeysListKey string, skipped map[string]struct{}) (string, error) {
+	return p.Strategy(group.EffectiveConfig.KeySelectionStrategy).Next(group, activeKeysListKey, skipped)
+}
+
+// buildAPIKey 将 HASH 中的密钥详情转换为 APIKey 并解密。
+func (p *KeyProvider) buildAPIKey(keyID uint, groupID uint, keyDetails map[string]string) *models.APIKey {
+	// Manually unmarshal the map into an APIKey struct
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
 	createdAt, _ := strconv.ParseInt(keyDetails["created_at"], 10, 64)
 
@@ -75,16 +132,14 @@ func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
 		decryptedKeyValue = encryptedKeyValue
 	}
 
-	apiKey := &models.APIKey{
-		ID:           uint(keyID),
+	return &models.APIKey{
+		ID:           keyID,
 		KeyValue:     decryptedKeyValue,
 		Status:       keyDetails["status"],
 		FailureCount: failureCount,
 		GroupID:      groupID,
 		CreatedAt:    time.Unix(createdAt, 0),
 	}
-
-	return apiKey, nil
 }
 
 // UpdateStatus 异步地提交一个 Key 状态更新任务。
@@ -167,6 +222,10 @@ func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey st
 			return fmt.Errorf("failed to update key in DB: %w", err)
 		}
 
+		if !isActive {
+			// 恢复的密钥重新进入预热期
+			updates[keyActivatedAtField] = time.Now().Unix()
+		}
 		if err := p.store.HSet(keyHashKey, updates); err != nil {
 			return fmt.Errorf("failed to update key details in store: %w", err)
 		}
@@ -282,6 +341,10 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 		return fmt.Errorf("failed during batch processing of keys: %w", err)
 	}
 
+	if err := p.loadKeyWeights(); err != nil {
+		logrus.WithError(err).Error("Failed to load key weights")
+	}
+
 	// 2. 更新所有分组的 active_keys 列表
 	logrus.Info("Updating active key lists for all groups...")
 	for groupID, activeIDs := range allActiveKeyIDs {
@@ -560,6 +623,10 @@ func (p *KeyProvider) addKeyToStore(key *models.APIKey) error {
 	// 1. Store key details in HASH
 	keyHashKey := fmt.Sprintf("key:%d", key.ID)
 	keyDetails := p.apiKeyToMap(key)
+	if key.Status == models.KeyStatusActive {
+		// 新增或恢复的密钥从此刻开始预热
+		keyDetails[keyActivatedAtField] = time.Now().Unix()
+	}
 	if err := p.store.HSet(keyHashKey, keyDetails); err != nil {
 		return fmt.Errorf("failed to HSet key details for key %d: %w", key.ID, err)
 	}
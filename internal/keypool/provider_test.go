@@ -0,0 +1,56 @@
+package keypool
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+)
+
+func TestSelectKeyScansPastCoolingKeys(t *testing.T) {
+	encryptionSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	memStore := store.NewMemoryStore()
+	p := NewProvider(nil, memStore, nil, encryptionSvc)
+
+	group := &models.Group{ID: 1, EffectiveConfig: types.SystemSettings{EnableAdaptiveCooldown: true}}
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
+	coolingUntil := time.Now().Add(time.Minute).UnixMilli()
+
+	// Round robin rotates from the tail, so the twelve cooling keys are tried first
+	const cooling = 12
+	for id := 1; id <= cooling+1; id++ {
+		details := map[string]any{"key_string": "sk-" + strconv.Itoa(id), "status": models.KeyStatusActive}
+		if id <= cooling {
+			details[keyCooldownField] = coolingUntil
+		}
+		if err := memStore.HSet(fmt.Sprintf("key:%d", id), details); err != nil {
+			t.Fatal(err)
+		}
+		if err := memStore.LPush(activeKeysListKey, strconv.Itoa(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	apiKey, err := p.SelectKey(group)
+	if err != nil {
+		t.Fatalf("SelectKey() error = %v, want the healthy key", err)
+	}
+	if apiKey.ID != cooling+1 {
+		t.Errorf("SelectKey() = key %d, want key %d", apiKey.ID, cooling+1)
+	}
+
+	if err := memStore.HSet(fmt.Sprintf("key:%d", cooling+1), map[string]any{keyCooldownField: coolingUntil}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.SelectKey(group); err != ErrKeyCoolingDown {
+		t.Errorf("SelectKey() error = %v, want %v when every key is cooling down", err, ErrKeyCoolingDown)
+	}
+}
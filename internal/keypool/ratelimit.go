@@ -0,0 +1,167 @@
+package keypool
+
+import (
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimitWindow 单个限流维度（请求数或 token 数）的状态
+type RateLimitWindow struct {
+	Limit     int64     `json:"limit"`     // 窗口内的限额，0 表示上游未报告
+	Remaining int64     `json:"remaining"` // 窗口内的剩余额度
+	Reset     time.Time `json:"reset"`     // 额度完全恢复的时间，零值表示未知
+}
+
+// RateLimitState 上游在一次响应中报告的密钥限流状态
+type RateLimitState struct {
+	Requests   *RateLimitWindow `json:"requests,omitempty"`
+	Tokens     *RateLimitWindow `json:"tokens,omitempty"`
+	RetryAfter time.Time        `json:"retry_after"` // 上游要求等待到的时间
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+// Empty 检查响应是否未携带任何限流信息
+func (s *RateLimitState) Empty() bool {
+	return s.Requests == nil && s.Tokens == nil && s.RetryAfter.IsZero()
+}
+
+// PoolRateLimit 分组内所有可用密钥汇总后的限流状态
+type PoolRateLimit struct {
+	ActiveKeys    int
+	ReportingKeys int
+	Requests      *RateLimitWindow
+	Tokens        *RateLimitWindow
+	// RetryAfter 所有可用密钥都已耗尽时，最早有密钥恢复的时间
+	RetryAfter time.Time
+}
+
+// 限流状态保存在 group:{id}:key_ratelimit HASH 中，keyID -> RateLimitState JSON
+func keyRateLimitKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:key_ratelimit", groupID)
+}
+
+// RecordRateLimit 保存上游为密钥报告的最新限流状态，多实例共享
+func (p *KeyProvider) RecordRateLimit(group *models.Group, keyID uint, state *RateLimitState) {
+	if state == nil || state.Empty() {
+		return
+	}
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	field := strconv.FormatUint(uint64(keyID), 10)
+	if err := p.store.HSet(keyRateLimitKey(group.ID), map[string]any{field: string(payload)}); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to record key rate limit")
+	}
+}
+
+// PoolRateLimit 汇总分组内所有可用密钥的限流状态。
+// 已过重置时间的窗口按限额完全恢复计算；尚未报告过状态的密钥按已报告密钥的平均限额计入，
+// 因此在密钥首次使用前汇总值是估算值。
+func (p *KeyProvider) PoolRateLimit(group *models.Group) (*PoolRateLimit, error) {
+	keyIDs, err := p.store.LRange(fmt.Sprintf("group:%d:active_keys", group.ID), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	pool := &PoolRateLimit{ActiveKeys: len(keyIDs)}
+	if len(keyIDs) == 0 {
+		return pool, nil
+	}
+
+	raw, err := p.store.HGetAll(keyRateLimitKey(group.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var requests, tokens windowSum
+	throttled := 0
+	var earliestRetry time.Time
+	for _, keyID := range keyIDs {
+		value, ok := raw[keyID]
+		if !ok {
+			continue
+		}
+		var state RateLimitState
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			continue
+		}
+		pool.ReportingKeys++
+		requests.add(state.Requests, now)
+		tokens.add(state.Tokens, now)
+
+		if retry := state.availableAt(now); !retry.IsZero() {
+			throttled++
+			if earliestRetry.IsZero() || retry.Before(earliestRetry) {
+				earliestRetry = retry
+			}
+		}
+	}
+
+	pool.Requests = requests.window(pool.ActiveKeys)
+	pool.Tokens = tokens.window(pool.ActiveKeys)
+	if throttled == pool.ActiveKeys {
+		pool.RetryAfter = earliestRetry
+	}
+	return pool, nil
+}
+
+// availableAt 返回密钥被限流时恢复可用的时间，未被限流时返回零值
+func (s *RateLimitState) availableAt(now time.Time) time.Time {
+	var at time.Time
+	if s.RetryAfter.After(now) {
+		at = s.RetryAfter
+	}
+	if w := s.Requests; w != nil && w.Remaining <= 0 && w.Reset.After(now) && w.Reset.After(at) {
+		at = w.Reset
+	}
+	return at
+}
+
+// windowSum 累加多个密钥同一维度的限流窗口
+type windowSum struct {
+	keys        int
+	limitKeys   int
+	limit       int64
+	remaining   int64
+	latestReset time.Time
+}
+
+func (s *windowSum) add(w *RateLimitWindow, now time.Time) {
+	if w == nil {
+		return
+	}
+	s.keys++
+	if w.Limit > 0 {
+		s.limitKeys++
+		s.limit += w.Limit
+	}
+	if !w.Reset.IsZero() && !w.Reset.After(now) && w.Limit > 0 {
+		// 窗口已重置
+		s.remaining += w.Limit
+		return
+	}
+	s.remaining += max(w.Remaining, 0)
+	if w.Reset.After(s.latestReset) {
+		s.latestReset = w.Reset
+	}
+}
+
+// window 生成汇总窗口，未报告该维度的密钥按平均限额计入限额和剩余额度
+func (s *windowSum) window(activeKeys int) *RateLimitWindow {
+	if s.keys == 0 {
+		return nil
+	}
+	w := &RateLimitWindow{Limit: s.limit, Remaining: s.remaining, Reset: s.latestReset}
+	if unreported := activeKeys - s.keys; s.limitKeys > 0 && unreported > 0 {
+		estimated := s.limit / int64(s.limitKeys) * int64(unreported)
+		w.Limit += estimated
+		w.Remaining += estimated
+	}
+	return w
+}
@@ -0,0 +1,156 @@
+package keypool
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"math/rand"
+	"strconv"
+)
+
+const (
+	// KeySelectionRoundRobin 按请求轮询密钥（默认）
+	KeySelectionRoundRobin = "round_robin"
+	// KeySelectionFairUsage 选择滚动窗口内 token 消耗最少的密钥
+	KeySelectionFairUsage = "fair_usage"
+	// KeySelectionLeastLoaded 选择本实例上进行中请求最少的密钥
+	KeySelectionLeastLoaded = "least_loaded"
+	// KeySelectionLatency 选择近期 p95 延迟（EWMA 估算）最低的密钥
+	KeySelectionLatency = "latency"
+	// KeySelectionWeightedRandom 按密钥权重随机选择
+	KeySelectionWeightedRandom = "weighted_random"
+)
+
+// Strategy 密钥选择策略，从分组的活跃密钥中选出下一个密钥 ID。
+// skipped 中是本次选择已跳过的密钥（预热、冷却或熔断），应尽量避开。
+type Strategy interface {
+	Next(group *models.Group, activeKeysListKey string, skipped map[string]struct{}) (string, error)
+}
+
+// StrategyFunc 将函数适配为 Strategy。
+type StrategyFunc func(group *models.Group, activeKeysListKey string, skipped map[string]struct{}) (string, error)
+
+// Next 调用 f。
+func (f StrategyFunc) Next(group *models.Group, activeKeysListKey string, skipped map[string]struct{}) (string, error) {
+	return f(group, activeKeysListKey, skipped)
+}
+
+// defaultStrategies 返回内置的密钥选择策略。
+func (p *KeyProvider) defaultStrategies() map[string]Strategy {
+	return map[string]Strategy{
+		KeySelectionRoundRobin: StrategyFunc(func(_ *models.Group, activeKeysListKey string, _ map[string]struct{}) (string, error) {
+			// Atomically rotate the key ID from the list
+			return p.store.Rotate(activeKeysListKey)
+		}),
+		KeySelectionFairUsage:      StrategyFunc(p.selectLeastUsedKeyID),
+		KeySelectionLeastLoaded:    StrategyFunc(p.selectLeastLoadedKeyID),
+		KeySelectionLatency:        StrategyFunc(p.selectFastestKeyID),
+		KeySelectionWeightedRandom: StrategyFunc(p.selectWeightedKeyID),
+	}
+}
+
+// Strategy 返回分组配置的密钥选择策略，未知策略按 round_robin 处理。
+func (p *KeyProvider) Strategy(name string) Strategy {
+	if strategy, ok := p.strategies[name]; ok {
+		return strategy
+	}
+	return p.strategies[KeySelectionRoundRobin]
+}
+
+// activeCandidates 返回分组中未被跳过的活跃密钥；全部被跳过时返回全部活跃密钥。
+func (p *KeyProvider) activeCandidates(activeKeysListKey string, skipped map[string]struct{}) ([]string, error) {
+	keyIDs, err := p.store.LRange(activeKeysListKey, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyIDs) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	candidates := make([]string, 0, len(keyIDs))
+	for _, keyID := range keyIDs {
+		if _, ok := skipped[keyID]; !ok {
+			candidates = append(candidates, keyID)
+		}
+	}
+	if len(candidates) == 0 {
+		return keyIDs, nil
+	}
+	return candidates, nil
+}
+
+// pickLowest 选择 score 最低的密钥，分数相同时随机选择。
+func pickLowest(keyIDs []string, score func(keyID string) float64) string {
+	var best []string
+	var bestScore float64
+	for _, keyID := range keyIDs {
+		s := score(keyID)
+		switch {
+		case len(best) == 0 || s < bestScore:
+			bestScore = s
+			best = append(best[:0], keyID)
+		case s == bestScore:
+			best = append(best, keyID)
+		}
+	}
+	return best[rand.Intn(len(best))]
+}
+
+// selectLeastLoadedKeyID 选择本实例上进行中请求最少的密钥。
+func (p *KeyProvider) selectLeastLoadedKeyID(_ *models.Group, activeKeysListKey string, skipped map[string]struct{}) (string, error) {
+	candidates, err := p.activeCandidates(activeKeysListKey, skipped)
+	if err != nil {
+		return "", err
+	}
+	return pickLowest(candidates, func(keyID string) float64 {
+		return float64(p.load.inFlight(keyID))
+	}), nil
+}
+
+// selectFastestKeyID 选择近期 p95 延迟最低的密钥。
+// 没有近期样本的密钥按零延迟计算，以便新密钥和长时间未被选中的密钥重新被探测。
+func (p *KeyProvider) selectFastestKeyID(_ *models.Group, activeKeysListKey string, skipped map[string]struct{}) (string, error) {
+	candidates, err := p.activeCandidates(activeKeysListKey, skipped)
+	if err != nil {
+		return "", err
+	}
+	return pickLowest(candidates, p.load.p95), nil
+}
+
+// selectWeightedKeyID 按密钥权重随机选择，未设置权重的密钥权重为 1。
+func (p *KeyProvider) selectWeightedKeyID(group *models.Group, activeKeysListKey string, skipped map[string]struct{}) (string, error) {
+	candidates, err := p.activeCandidates(activeKeysListKey, skipped)
+	if err != nil {
+		return "", err
+	}
+	weights, err := p.store.HGetAll(keyWeightsKey(group.ID))
+	if err != nil {
+		return "", fmt.Errorf("failed to get key weights: %w", err)
+	}
+
+	total := 0
+	cumulative := make([]int, len(candidates))
+	for i, keyID := range candidates {
+		total += keyWeight(weights[keyID])
+		cumulative[i] = total
+	}
+	if total == 0 {
+		return candidates[rand.Intn(len(candidates))], nil
+	}
+	target := rand.Intn(total)
+	for i, c := range cumulative {
+		if target < c {
+			return candidates[i], nil
+		}
+	}
+	return candidates[len(candidates)-1], nil
+}
+
+// keyWeight 解析保存的密钥权重，未设置或无效时为 1。
+func keyWeight(value string) int {
+	weight, err := strconv.Atoi(value)
+	if err != nil || weight < 0 {
+		return 1
+	}
+	return weight
+}
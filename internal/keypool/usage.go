@@ -0,0 +1,175 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 用量统计以小时为桶：
+//   - group:{id}:key_usage          HASH keyID -> 窗口内累计 token
+//   - group:{id}:key_usage:{bucket} HASH keyID -> 该小时 token，过期后从累计值中扣除
+//   - group:{id}:key_usage_swept    已清理到的小时桶
+func keyUsageTotalsKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:key_usage", groupID)
+}
+
+func keyUsageBucketKey(groupID uint, bucket int64) string {
+	return fmt.Sprintf("group:%d:key_usage:%d", groupID, bucket)
+}
+
+func keyUsageSweptKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:key_usage_swept", groupID)
+}
+
+func currentUsageBucket() int64 {
+	return time.Now().Unix() / 3600
+}
+
+// selectLeastUsedKeyID 从活跃密钥中选择窗口内 token 消耗最少的一个，用量相同时随机选择。
+// skipped 中的密钥不参与选择，除非没有其他候选。
+func (p *KeyProvider) selectLeastUsedKeyID(group *models.Group, activeKeysListKey string, skipped map[string]struct{}) (string, error) {
+	keyIDs, err := p.store.LRange(activeKeysListKey, 0, -1)
+	if err != nil {
+		return "", err
+	}
+	if len(keyIDs) == 0 {
+		return "", store.ErrNotFound
+	}
+
+	p.sweepExpiredUsage(group)
+
+	totals, err := p.store.HGetAll(keyUsageTotalsKey(group.ID))
+	if err != nil {
+		return "", fmt.Errorf("failed to get key usage: %w", err)
+	}
+
+	var candidates []string
+	var minUsage int64
+	for _, keyID := range keyIDs {
+		if _, ok := skipped[keyID]; ok {
+			continue
+		}
+		usage, _ := strconv.ParseInt(totals[keyID], 10, 64)
+		switch {
+		case len(candidates) == 0 || usage < minUsage:
+			minUsage = usage
+			candidates = append(candidates[:0], keyID)
+		case usage == minUsage:
+			candidates = append(candidates, keyID)
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = keyIDs
+	}
+
+	return candidates[rand.Intn(len(candidates))], nil
+}
+
+// RecordUsage 记录一次请求消耗的 token，仅在 fair_usage 策略下生效。
+func (p *KeyProvider) RecordUsage(group *models.Group, keyID uint, tokens int64) {
+	if tokens <= 0 || group.EffectiveConfig.KeySelectionStrategy != KeySelectionFairUsage {
+		return
+	}
+
+	field := strconv.FormatUint(uint64(keyID), 10)
+	bucket := currentUsageBucket()
+
+	if _, err := p.store.HIncrBy(keyUsageBucketKey(group.ID, bucket), field, tokens); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to record key usage bucket")
+		return
+	}
+	if _, err := p.store.HIncrBy(keyUsageTotalsKey(group.ID), field, tokens); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to record key usage total")
+	}
+}
+
+// sweepExpiredUsage 将滑出窗口的小时桶从累计用量中扣除。
+// 多实例部署时通过 SetNX 保证每个桶只被扣除一次。
+func (p *KeyProvider) sweepExpiredUsage(group *models.Group) {
+	windowHours := int64(group.EffectiveConfig.FairUsageWindowHours)
+	if windowHours <= 0 {
+		return
+	}
+	// 小于 lastExpired 的桶全部过期
+	lastExpired := currentUsageBucket() - windowHours
+
+	p.usageSweepMu.Lock()
+	defer p.usageSweepMu.Unlock()
+
+	if swept, ok := p.usageSweptAt[group.ID]; ok && swept >= lastExpired {
+		return
+	}
+
+	swept, err := p.loadSweptBucket(group.ID, lastExpired)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": group.ID, "error": err}).Error("Failed to load key usage sweep cursor")
+		return
+	}
+
+	for bucket := swept + 1; bucket <= lastExpired; bucket++ {
+		if err := p.sweepUsageBucket(group.ID, bucket); err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": group.ID, "bucket": bucket, "error": err}).Error("Failed to sweep key usage bucket")
+			break
+		}
+		swept = bucket
+	}
+
+	if err := p.store.Set(keyUsageSweptKey(group.ID), []byte(strconv.FormatInt(swept, 10)), 0); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": group.ID, "error": err}).Error("Failed to save key usage sweep cursor")
+		return
+	}
+	p.usageSweptAt[group.ID] = swept
+}
+
+// loadSweptBucket 读取已清理到的桶；首次使用时没有更早的用量，直接从当前过期边界开始。
+func (p *KeyProvider) loadSweptBucket(groupID uint, lastExpired int64) (int64, error) {
+	raw, err := p.store.Get(keyUsageSweptKey(groupID))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return lastExpired, nil
+		}
+		return 0, err
+	}
+	swept, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return lastExpired, nil
+	}
+	return swept, nil
+}
+
+// sweepUsageBucket 从累计用量中扣除单个过期桶并删除该桶。
+func (p *KeyProvider) sweepUsageBucket(groupID uint, bucket int64) error {
+	lockKey := fmt.Sprintf("%s:lock", keyUsageBucketKey(groupID, bucket))
+	acquired, err := p.store.SetNX(lockKey, []byte("1"), time.Hour)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		// 其他实例正在或已经处理该桶
+		return nil
+	}
+
+	bucketKey := keyUsageBucketKey(groupID, bucket)
+	usage, err := p.store.HGetAll(bucketKey)
+	if err != nil {
+		return err
+	}
+	for keyID, value := range usage {
+		tokens, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || tokens == 0 {
+			continue
+		}
+		if _, err := p.store.HIncrBy(keyUsageTotalsKey(groupID), keyID, -tokens); err != nil {
+			return err
+		}
+	}
+	return p.store.Delete(bucketKey)
+}
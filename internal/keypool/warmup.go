@@ -0,0 +1,35 @@
+package keypool
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+const (
+	// keyActivatedAtField 密钥最近一次被添加或恢复为活跃状态的时间（Unix 秒）
+	keyActivatedAtField = "activated_at"
+	// keyWarmupMinShare 预热刚开始时密钥的最低流量权重
+	keyWarmupMinShare = 0.05
+)
+
+// keyWarmupShare 返回密钥在预热期内的流量权重，随时间从 keyWarmupMinShare 线性增长到 1。
+// 没有激活时间的密钥（如启动时从数据库加载的存量密钥）视为已完成预热。
+func keyWarmupShare(keyDetails map[string]string, warmup time.Duration) float64 {
+	activatedAt, err := strconv.ParseInt(keyDetails[keyActivatedAtField], 10, 64)
+	if err != nil || activatedAt <= 0 || warmup <= 0 {
+		return 1
+	}
+
+	elapsed := time.Since(time.Unix(activatedAt, 0))
+	if elapsed >= warmup {
+		return 1
+	}
+	return max(float64(elapsed)/float64(warmup), keyWarmupMinShare)
+}
+
+// skipWarmingKey 按预热权重决定本次是否跳过该密钥。
+func skipWarmingKey(keyDetails map[string]string, warmup time.Duration) bool {
+	share := keyWarmupShare(keyDetails, warmup)
+	return share < 1 && rand.Float64() >= share
+}
@@ -0,0 +1,54 @@
+package keypool
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+	"strconv"
+)
+
+// MaxKeyWeight 密钥权重上限
+const MaxKeyWeight = 1000
+
+// 密钥权重保存在 group:{id}:key_weights HASH 中，keyID -> 权重，未设置的密钥权重为 1
+func keyWeightsKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:key_weights", groupID)
+}
+
+// UpdateKeyWeight 更新密钥在 weighted_random 策略下的权重，权重为 0 的密钥不再被选中（除非没有其他密钥）。
+func (p *KeyProvider) UpdateKeyWeight(keyID uint, weight int) error {
+	if weight < 0 || weight > MaxKeyWeight {
+		return fmt.Errorf("weight must be between 0 and %d", MaxKeyWeight)
+	}
+
+	var key models.APIKey
+	if err := p.db.First(&key, keyID).Error; err != nil {
+		return err
+	}
+	if err := p.db.Model(&key).Update("weight", weight).Error; err != nil {
+		return err
+	}
+	return p.storeKeyWeight(key.GroupID, keyID, weight)
+}
+
+func (p *KeyProvider) storeKeyWeight(groupID, keyID uint, weight int) error {
+	field := strconv.FormatUint(uint64(keyID), 10)
+	if err := p.store.HSet(keyWeightsKey(groupID), map[string]any{field: weight}); err != nil {
+		return fmt.Errorf("failed to store key weight: %w", err)
+	}
+	return nil
+}
+
+// loadKeyWeights 将数据库中非默认的密钥权重写入存储。
+func (p *KeyProvider) loadKeyWeights() error {
+	var keys []models.APIKey
+	err := p.db.Model(&models.APIKey{}).Select("id", "group_id", "weight").Where("weight <> ?", 1).Find(&keys).Error
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := p.storeKeyWeight(key.GroupID, key.ID, key.Weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// OtherLabelValue replaces label values that match none of the configured rules.
+const OtherLabelValue = "other"
+
+// labelCacheSize bounds the number of distinct raw values whose mapping is cached per label.
+const labelCacheSize = 4096
+
+// labelRule maps matching label values to themselves or to a group name.
+type labelRule struct {
+	exact string
+	re    *regexp.Regexp // nil for exact values
+	group string         // replacement, may reference regex groups as $1; empty keeps the value
+}
+
+// LabelMapper collapses the values of one label to bound the number of series.
+type LabelMapper struct {
+	rules  []labelRule
+	cache  sync.Map // raw value -> mapped value
+	cached atomic.Int32
+}
+
+// ParseLabelRules parses comma-separated label rules. Each rule is an exact value, a
+// pattern where * matches any characters, or a /regex/ matching the whole value,
+// optionally followed by =group to report matching values as group. A regex group may
+// use $1 references, e.g. /(gpt-4o)-.*/=$1. The first matching rule wins and values
+// matching no rule are reported as "other". An empty spec returns nil, which keeps
+// values unchanged.
+func ParseLabelRules(spec string) (*LabelMapper, error) {
+	var rules []labelRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, group := entry, ""
+		if i := strings.LastIndex(entry, "="); i >= 0 && !strings.HasSuffix(entry, "/") {
+			pattern, group = strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+			if group == "" {
+				return nil, fmt.Errorf("label rule %q has an empty group", entry)
+			}
+		}
+
+		rule := labelRule{group: group}
+		switch {
+		case len(pattern) >= 2 && pattern[0] == '/' && pattern[len(pattern)-1] == '/':
+			re, err := regexp.Compile("^(?:" + pattern[1:len(pattern)-1] + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("label rule %q: %w", entry, err)
+			}
+			rule.re = re
+		case strings.Contains(pattern, "*"):
+			parts := strings.Split(pattern, "*")
+			for i, part := range parts {
+				parts[i] = regexp.QuoteMeta(part)
+			}
+			rule.re = regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+		case pattern == "":
+			return nil, fmt.Errorf("label rule %q has an empty pattern", entry)
+		default:
+			rule.exact = pattern
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return &LabelMapper{rules: rules}, nil
+}
+
+// Map returns the value reported for raw.
+func (m *LabelMapper) Map(raw string) string {
+	if cached, ok := m.cache.Load(raw); ok {
+		return cached.(string)
+	}
+	mapped := m.mapValue(raw)
+	if m.cached.Load() < labelCacheSize {
+		if _, loaded := m.cache.LoadOrStore(raw, mapped); !loaded {
+			m.cached.Add(1)
+		}
+	}
+	return mapped
+}
+
+func (m *LabelMapper) mapValue(raw string) string {
+	for _, rule := range m.rules {
+		if rule.re == nil {
+			if raw != rule.exact {
+				continue
+			}
+			if rule.group != "" {
+				return rule.group
+			}
+			return raw
+		}
+		match := rule.re.FindStringSubmatchIndex(raw)
+		if match == nil {
+			continue
+		}
+		if rule.group == "" {
+			return raw
+		}
+		return string(rule.re.ExpandString(nil, rule.group, raw, match))
+	}
+	return OtherLabelValue
+}
+
+var labelMappers atomic.Pointer[map[string]*LabelMapper]
+
+// SetLabelMapper applies mapper to every metric with the given label name, or removes
+// the mapping when mapper is nil. Series are recorded with their raw values and merged
+// under the mapped values when scraped, so changing the rules takes effect immediately
+// for all existing series.
+func SetLabelMapper(label string, mapper *LabelMapper) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	mappers := make(map[string]*LabelMapper)
+	if current := labelMappers.Load(); current != nil {
+		for name, m := range *current {
+			mappers[name] = m
+		}
+	}
+	if mapper == nil {
+		delete(mappers, label)
+	} else {
+		mappers[label] = mapper
+	}
+	labelMappers.Store(&mappers)
+}
+
+// labelMappersFor returns the mapper of each label of v, or nil when none is configured.
+func (v *Vec) labelMappersFor() []*LabelMapper {
+	mappers := labelMappers.Load()
+	if mappers == nil || len(*mappers) == 0 {
+		return nil
+	}
+	var out []*LabelMapper
+	for i, name := range v.labels {
+		if mapper := (*mappers)[name]; mapper != nil {
+			if out == nil {
+				out = make([]*LabelMapper, len(v.labels))
+			}
+			out[i] = mapper
+		}
+	}
+	return out
+}
+
+// mapSeries merges series whose label values map to the same reported values, summing them.
+func mapSeries(values map[string]float64, mappers []*LabelMapper) map[string]float64 {
+	merged := make(map[string]float64, len(values))
+	for key, value := range values {
+		labelValues := strings.Split(key, "\xff")
+		for i, mapper := range mappers {
+			if mapper != nil {
+				labelValues[i] = mapper.Map(labelValues[i])
+			}
+		}
+		merged[strings.Join(labelValues, "\xff")] += value
+	}
+	return merged
+}
@@ -0,0 +1,197 @@
+// Package metrics provides lightweight in-process counters and gauges exported
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	kindCounter = "counter"
+	kindGauge   = "gauge"
+)
+
+// Vec is a metric family with a fixed set of label names.
+type Vec struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64 // encoded label values -> value
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*Vec)
+	collectors []func()
+)
+
+// NewCounter registers a monotonically increasing metric.
+// Registering the same name twice returns the existing metric.
+func NewCounter(name, help string, labels ...string) *Vec {
+	return register(name, help, kindCounter, labels)
+}
+
+// NewGauge registers a metric that can go up and down.
+func NewGauge(name, help string, labels ...string) *Vec {
+	return register(name, help, kindGauge, labels)
+}
+
+func register(name, help, kind string, labels []string) *Vec {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if existing, ok := registry[name]; ok {
+		return existing
+	}
+	v := &Vec{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+	registry[name] = v
+	return v
+}
+
+// Inc adds one to the series identified by labelValues.
+func (v *Vec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+// Add adds delta to the series identified by labelValues.
+func (v *Vec) Add(delta float64, labelValues ...string) {
+	key := v.seriesKey(labelValues)
+	v.mu.Lock()
+	v.values[key] += delta
+	v.mu.Unlock()
+}
+
+// Set replaces the value of the series identified by labelValues.
+func (v *Vec) Set(value float64, labelValues ...string) {
+	key := v.seriesKey(labelValues)
+	v.mu.Lock()
+	v.values[key] = value
+	v.mu.Unlock()
+}
+
+// Value returns the current value of the series identified by labelValues.
+func (v *Vec) Value(labelValues ...string) float64 {
+	key := v.seriesKey(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[key]
+}
+
+// seriesKey encodes label values; missing values are treated as empty strings.
+func (v *Vec) seriesKey(labelValues []string) string {
+	if len(labelValues) > len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d labels, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	values := make([]string, len(v.labels))
+	copy(values, labelValues)
+	return strings.Join(values, "\xff")
+}
+
+// OnCollect registers a function that runs before each scrape, for metrics that mirror
+// state kept elsewhere and are set rather than updated as events happen.
+func OnCollect(collect func()) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	collectors = append(collectors, collect)
+}
+
+// WriteText writes all registered metrics in the Prometheus text format.
+func WriteText(w io.Writer) error {
+	registryMu.RLock()
+	collect := collectors
+	registryMu.RUnlock()
+	for _, fn := range collect {
+		fn()
+	}
+
+	registryMu.RLock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	vecs := make([]*Vec, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		vecs = append(vecs, registry[name])
+	}
+	registryMu.RUnlock()
+
+	for _, v := range vecs {
+		if err := v.writeText(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Vec) writeText(w io.Writer) error {
+	v.mu.Lock()
+	series := v.values
+	if mappers := v.labelMappersFor(); mappers != nil {
+		series = mapSeries(v.values, mappers)
+	}
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]float64, len(keys))
+	for i, key := range keys {
+		values[i] = series[key]
+	}
+	v.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+	for i, key := range keys {
+		b.WriteString(v.name)
+		if len(v.labels) > 0 {
+			b.WriteByte('{')
+			for j, value := range strings.Split(key, "\xff") {
+				if j > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(v.labels[j])
+				b.WriteString(`="`)
+				b.WriteString(escapeLabelValue(value))
+				b.WriteByte('"')
+			}
+			b.WriteByte('}')
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(values[i], 'g', -1, 64))
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// Handler serves the registered metrics for Prometheus scraping.
+func Handler(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := WriteText(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
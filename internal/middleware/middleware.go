@@ -3,11 +3,13 @@ package middleware
 
 import (
 	"crypto/subtle"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
 	"gpt-load/internal/types"
@@ -142,17 +144,27 @@ func Auth(authConfig types.AuthConfig) gin.HandlerFunc {
 // ProxyAuth
 func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Check key
-		key := extractAuthKey(c)
-		if key == "" {
-			response.Error(c, app_errors.ErrUnauthorized)
+		// The group decides where the key may be sent, so it is resolved first
+		group, err := gm.GetGroupByName(c.Param("group_name"))
+		if errors.Is(err, services.ErrGroupsNotLoaded) {
+			response.Error(c, app_errors.ErrServiceNotReady)
 			c.Abort()
 			return
 		}
-
-		group, err := gm.GetGroupByName(c.Param("group_name"))
 		if err != nil {
-			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Failed to retrieve proxy group"))
+			if extractAuthKey(c) == "" {
+				response.Error(c, app_errors.ErrUnauthorized)
+			} else {
+				response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Failed to retrieve proxy group"))
+			}
+			c.Abort()
+			return
+		}
+
+		// Check key
+		key := extractProxyKey(c, group.EffectiveConfig)
+		if key == "" {
+			response.Error(c, app_errors.ErrUnauthorized)
 			c.Abort()
 			return
 		}
@@ -162,6 +174,12 @@ func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 		_, existsInGroup := group.ProxyKeysMap[key]
 
 		if existsInEffective || existsInGroup {
+			tier := models.ProxyKeyTierInteractive
+			if _, isBatch := group.EffectiveConfig.BatchProxyKeysMap[key]; isBatch {
+				tier = models.ProxyKeyTierBatch
+			}
+			c.Set("proxyKey", key)
+			c.Set("proxyKeyTier", tier)
 			c.Next()
 			return
 		}
@@ -275,6 +293,47 @@ func extractAuthKey(c *gin.Context) string {
 	return ""
 }
 
+// extractProxyKey extracts a proxy key from the locations enabled for the group:
+// the configured query parameter, the standard auth headers, the group's custom
+// headers and, when enabled, the password (or the username if there is no
+// password) of HTTP basic auth. The query parameter is removed from the URL so
+// it is not forwarded upstream.
+func extractProxyKey(c *gin.Context, cfg types.SystemSettings) string {
+	// Query key
+	if param := strings.TrimSpace(cfg.ProxyKeyQueryParam); param != "" {
+		if key := c.Query(param); key != "" {
+			query := c.Request.URL.Query()
+			query.Del(param)
+			c.Request.URL.RawQuery = query.Encode()
+			return key
+		}
+	}
+
+	// Bearer token
+	if key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return key
+	}
+
+	// X-Api-Key and X-Goog-Api-Key, then the group's custom headers
+	for _, name := range append([]string{"X-Api-Key", "X-Goog-Api-Key"}, cfg.ProxyKeyHeaderList...) {
+		if value := strings.TrimSpace(c.GetHeader(name)); value != "" {
+			return strings.TrimPrefix(value, "Bearer ")
+		}
+	}
+
+	// Basic auth
+	if cfg.ProxyKeyBasicAuth {
+		if username, password, ok := c.Request.BasicAuth(); ok {
+			if password != "" {
+				return password
+			}
+			return username
+		}
+	}
+
+	return ""
+}
+
 // StaticCache creates a middleware for caching static resources
 func StaticCache() gin.HandlerFunc {
 	return func(c *gin.Context) {
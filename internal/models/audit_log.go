@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// AuditLog 对应 audit_logs 表，记录经过代理的每个请求，供合规审查
+// 抽中记录请求体时，RequestBody 和 ResponseBody 为按分组脱敏路径处理后的内容
+type AuditLog struct {
+	ID               string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Timestamp        time.Time `gorm:"not null;index" json:"timestamp"`
+	Method           string    `gorm:"type:varchar(16)" json:"method"`
+	Path             string    `gorm:"type:varchar(500)" json:"path"`
+	GroupID          uint      `gorm:"not null;index" json:"group_id"`
+	GroupName        string    `gorm:"type:varchar(255);index" json:"group_name"`
+	KeyHash          string    `gorm:"type:varchar(128);index" json:"key_hash"`
+	ProxyKeyHash     string    `gorm:"type:varchar(128);index" json:"proxy_key_hash"`
+	Model            string    `gorm:"type:varchar(255);index" json:"model"`
+	StatusCode       int       `gorm:"not null" json:"status_code"`
+	IsSuccess        bool      `gorm:"not null" json:"is_success"`
+	Duration         int64     `gorm:"not null" json:"duration_ms"`
+	PromptTokens     int64     `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int64     `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens      int64     `gorm:"not null;default:0" json:"total_tokens"`
+	SourceIP         string    `gorm:"type:varchar(64)" json:"source_ip"`
+	ErrorMessage     string    `gorm:"type:text" json:"error_message,omitempty"`
+	BodySampled      bool      `gorm:"not null;default:false" json:"body_sampled"`
+	RequestBody      string    `gorm:"type:text" json:"request_body,omitempty"`
+	ResponseBody     string    `gorm:"type:text" json:"response_body,omitempty"`
+}
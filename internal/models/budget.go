@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// 预算用尽后的处理方式
+const (
+	BudgetActionReject    = "reject"    // 拒绝请求，返回 429
+	BudgetActionDowngrade = "downgrade" // 改用 DowngradeModel 继续转发
+)
+
+// Budget 对应 budgets 表，限制分组或分组下某个代理密钥在一个周期内的用量
+// 周期由 Schedule 的 cron 表达式定义，每次触发时用量归零；各上限为 0 表示不限制
+type Budget struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID        uint      `gorm:"not null;index" json:"group_id"`
+	ProxyKeyHash   string    `gorm:"type:varchar(128);index" json:"-"`            // 为空表示作用于整个分组
+	ProxyKeyMask   string    `gorm:"type:varchar(64)" json:"proxy_key,omitempty"` // 脱敏后的代理密钥，仅用于展示
+	Name           string    `gorm:"type:varchar(255)" json:"name"`
+	Schedule       string    `gorm:"type:varchar(64);not null" json:"schedule"` // cron 表达式或 @daily、@monthly 等
+	MaxRequests    int64     `gorm:"not null;default:0" json:"max_requests"`
+	MaxTokens      int64     `gorm:"not null;default:0" json:"max_tokens"`
+	MaxCost        float64   `gorm:"not null;default:0" json:"max_cost"`    // 美元
+	TokenPrice     float64   `gorm:"not null;default:0" json:"token_price"` // 每百万 token 的估算价格（美元），模型不在价格表中时用于计算费用
+	Action         string    `gorm:"type:varchar(20);not null;default:'reject'" json:"action"`
+	DowngradeModel string    `gorm:"type:varchar(255)" json:"downgrade_model"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
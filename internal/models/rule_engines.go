@@ -0,0 +1,199 @@
+package models
+
+import (
+	"sync"
+
+	"gpt-load/internal/jsonengine"
+)
+
+// 规则方向
+const (
+	RuleDirectionInbound       = "inbound"
+	RuleDirectionOutbound      = "outbound"
+	RuleDirectionErrorOutbound = "error_outbound" // 非 2xx 响应
+)
+
+// RuleEngineBuilder 编译分组某个方向的规则引擎，无需改写时返回 nil
+type RuleEngineBuilder func(g *Group, direction string) (*jsonengine.PathEngine, error)
+
+// ruleEngineCache 分组规则引擎缓存，每个方向首次使用时编译一次
+// 分组重新加载时生成新的 Group 对象和新的缓存，规则变化后不会继续使用旧引擎
+type ruleEngineCache struct {
+	build         RuleEngineBuilder
+	inbound       cachedEngine
+	outbound      cachedEngine
+	errorOutbound cachedEngine
+	scoped        sync.Map // 方向和适用规则子集 -> *cachedEngine，见 ScopedEngine
+}
+
+type cachedEngine struct {
+	once   sync.Once
+	engine *jsonengine.PathEngine
+	err    error
+}
+
+func (c *cachedEngine) get(build func() (*jsonengine.PathEngine, error)) (*jsonengine.PathEngine, error) {
+	c.once.Do(func() {
+		c.engine, c.err = build()
+	})
+	return c.engine, c.err
+}
+
+// SetRuleEngineBuilder 设置规则引擎的编译方式并清空已编译的引擎
+// 由 GroupManager 在加载分组时调用，之后分组对象只读，不应再调用
+func (g *Group) SetRuleEngineBuilder(build RuleEngineBuilder) {
+	g.ruleEngines = &ruleEngineCache{build: build}
+}
+
+// InboundEngine 返回入站规则引擎，无入站规则时返回 nil
+func (g *Group) InboundEngine() (*jsonengine.PathEngine, error) {
+	return g.ruleEngine(RuleDirectionInbound)
+}
+
+// OutboundEngine 返回出站规则引擎，无出站规则时返回 nil
+func (g *Group) OutboundEngine() (*jsonengine.PathEngine, error) {
+	return g.ruleEngine(RuleDirectionOutbound)
+}
+
+// ErrorOutboundEngine 返回非 2xx 响应使用的出站规则引擎，无错误响应规则时返回 nil
+func (g *Group) ErrorOutboundEngine() (*jsonengine.PathEngine, error) {
+	return g.ruleEngine(RuleDirectionErrorOutbound)
+}
+
+func (g *Group) ruleEngine(direction string) (*jsonengine.PathEngine, error) {
+	cache := g.ruleEngines
+	if cache == nil {
+		// 未经 GroupManager 加载的分组（如测试或临时构造的对象）不缓存
+		return DefaultRuleEngineBuilder(g, direction)
+	}
+	build := func() (*jsonengine.PathEngine, error) {
+		return cache.builder()(g, direction)
+	}
+	switch direction {
+	case RuleDirectionInbound:
+		return cache.inbound.get(build)
+	case RuleDirectionErrorOutbound:
+		return cache.errorOutbound.get(build)
+	}
+	return cache.outbound.get(build)
+}
+
+func (c *ruleEngineCache) builder() RuleEngineBuilder {
+	if c == nil || c.build == nil {
+		return DefaultRuleEngineBuilder
+	}
+	return c.build
+}
+
+// RuleScope 请求的方法、路径和模型，用于筛选设置了路由选择器（paths/methods/models）的规则
+type RuleScope struct {
+	Method string
+	Path   string
+	Model  string // 客户端请求的模型（模型重定向之前）
+}
+
+// ScopedEngine 返回适用于请求的规则引擎
+// 没有规则设置路由选择器时与 InboundEngine 等相同；否则按适用的规则子集编译并缓存引擎，
+// 适用规则相同的请求（通常即同一端点）共用一个引擎
+func (g *Group) ScopedEngine(direction string, scope RuleScope) (*jsonengine.PathEngine, error) {
+	rules := g.RuleList(direction)
+	key, scoped := scopeKey(direction, rules, scope)
+	if !scoped {
+		return g.ruleEngine(direction)
+	}
+	cache := g.ruleEngines
+	build := func() (*jsonengine.PathEngine, error) {
+		// 在分组副本上替换规则列表，编译方式看到的仍是完整的分组配置
+		sub := *g
+		sub.setRuleList(direction, filterRules(rules, scope))
+		return cache.builder()(&sub, direction)
+	}
+	if cache == nil {
+		return build()
+	}
+	entry, _ := cache.scoped.LoadOrStore(key, &cachedEngine{})
+	return entry.(*cachedEngine).get(build)
+}
+
+// ScopedRuleList 返回分组某个方向适用于请求的规则
+func (g *Group) ScopedRuleList(direction string, scope RuleScope) []jsonengine.PathRule {
+	rules := g.RuleList(direction)
+	if _, scoped := scopeKey(direction, rules, scope); !scoped {
+		return rules
+	}
+	return filterRules(rules, scope)
+}
+
+// HeaderRulesFor 返回适用于请求的请求头规则
+func (g *Group) HeaderRulesFor(scope RuleScope) []HeaderRule {
+	rules := make([]HeaderRule, 0, len(g.HeaderRuleList))
+	for _, rule := range g.HeaderRuleList {
+		if rule.AppliesTo(scope.Method, scope.Path) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// scopeKey 返回缓存键：方向加上每条规则是否适用的标记；没有规则设置路由选择器时 scoped 为 false
+func scopeKey(direction string, rules []jsonengine.PathRule, scope RuleScope) (key string, scoped bool) {
+	mask := make([]byte, 0, len(direction)+1+len(rules))
+	mask = append(mask, direction...)
+	mask = append(mask, ':')
+	for _, rule := range rules {
+		if !rule.Scoped() {
+			mask = append(mask, '1')
+			continue
+		}
+		scoped = true
+		if rule.AppliesTo(scope.Method, scope.Path, scope.Model) {
+			mask = append(mask, '1')
+		} else {
+			mask = append(mask, '0')
+		}
+	}
+	return string(mask), scoped
+}
+
+// filterRules 返回适用于请求的规则
+func filterRules(rules []jsonengine.PathRule, scope RuleScope) []jsonengine.PathRule {
+	filtered := make([]jsonengine.PathRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.AppliesTo(scope.Method, scope.Path, scope.Model) {
+			filtered = append(filtered, rule)
+		}
+	}
+	return filtered
+}
+
+// DefaultRuleEngineBuilder 按分组的规则冲突解析方式编译某个方向的规则
+func DefaultRuleEngineBuilder(g *Group, direction string) (*jsonengine.PathEngine, error) {
+	rules := g.RuleList(direction)
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return jsonengine.NewPathEngine(rules, jsonengine.WithConflictMode(jsonengine.ConflictMode(g.EffectiveConfig.RuleConflictMode)))
+}
+
+// RuleList 返回分组某个方向的规则
+func (g *Group) RuleList(direction string) []jsonengine.PathRule {
+	switch direction {
+	case RuleDirectionInbound:
+		return g.InboundRuleList
+	case RuleDirectionErrorOutbound:
+		return g.ErrorOutboundRuleList
+	}
+	return g.OutboundRuleList
+}
+
+// setRuleList 替换分组某个方向的规则
+func (g *Group) setRuleList(direction string, rules []jsonengine.PathRule) {
+	switch direction {
+	case RuleDirectionInbound:
+		g.InboundRuleList = rules
+	case RuleDirectionErrorOutbound:
+		g.ErrorOutboundRuleList = rules
+	default:
+		g.OutboundRuleList = rules
+	}
+}
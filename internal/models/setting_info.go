@@ -2,15 +2,19 @@ package models
 
 // SystemSettingInfo 表示系统配置的详细信息（用于API返回）
 type SystemSettingInfo struct {
-	Key          string   `json:"key"`
-	Name         string   `json:"name"`
-	Value        any      `json:"value"`
-	Type         string   `json:"type"` // "int", "bool", "string"
-	DefaultValue any      `json:"default_value"`
-	Description  string   `json:"description"`
-	Category     string   `json:"category"`
-	MinValue     *int     `json:"min_value,omitempty"`
-	Required     bool     `json:"required"`
+	Key           string   `json:"key"`
+	Name          string   `json:"name"`
+	Value         any      `json:"value"`
+	Type          string   `json:"type"` // "int", "bool", "string"
+	DefaultValue  any      `json:"default_value"`
+	Description   string   `json:"description"`
+	Category      string   `json:"category"`
+	MinValue      *int     `json:"min_value,omitempty"`
+	MaxValue      *int     `json:"max_value,omitempty"`
+	Options       []string `json:"options,omitempty"` // 可选值（oneof）
+	Required      bool     `json:"required"`
+	HotReload     bool     `json:"hot_reload"`     // 修改后无需重启即生效
+	GroupOverride bool     `json:"group_override"` // 分组可覆盖
 }
 
 // CategorizedSettings a list of settings grouped by category
@@ -2,7 +2,9 @@ package models
 
 import (
 	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/script"
 	"gpt-load/internal/types"
+	"regexp"
 	"time"
 
 	"gorm.io/datatypes"
@@ -33,11 +35,75 @@ type GroupConfig struct {
 	MaxIdleConnsPerHost          *int    `json:"max_idle_conns_per_host,omitempty"`
 	ResponseHeaderTimeout        *int    `json:"response_header_timeout,omitempty"`
 	ProxyURL                     *string `json:"proxy_url,omitempty"`
+	UpstreamHostHeader           *string `json:"upstream_host_header,omitempty"`
+	UpstreamTLSServerName        *string `json:"upstream_tls_server_name,omitempty"`
+	AllowedPaths                 *string `json:"allowed_paths,omitempty"`
+	EnableRequestValidation      *bool   `json:"enable_request_validation,omitempty"`
+	RequestSeed                  *string `json:"request_seed,omitempty"`
+	ResponseWatermarkField       *string `json:"response_watermark_field,omitempty"`
+	ResponsePostProcessors       *string `json:"response_post_processors,omitempty"`
+	PreferredRegions             *string `json:"preferred_regions,omitempty"`
+	StrictOutboundJSON           *bool   `json:"strict_outbound_json,omitempty"`
+	RemoveEmptyParents           *bool   `json:"remove_empty_parents,omitempty"`
+	CompressRewrittenResponses   *bool   `json:"compress_rewritten_responses,omitempty"`
+	RuleConflictMode             *string `json:"rule_conflict_mode,omitempty"`
+	SignedRequestPassthrough     *bool   `json:"signed_request_passthrough,omitempty"`
+	RateLimitHeaders             *string `json:"rate_limit_headers,omitempty"`
+	BatchProxyKeys               *string `json:"batch_proxy_keys,omitempty"`
+	ProxyKeyHeaders              *string `json:"proxy_key_headers,omitempty"`
+	ProxyKeyQueryParam           *string `json:"proxy_key_query_param,omitempty"`
+	ProxyKeyBasicAuth            *bool   `json:"proxy_key_basic_auth,omitempty"`
+	ModelDeprecationRemap        *bool   `json:"model_deprecation_remap,omitempty"`
+	ModelDeprecations            *string `json:"model_deprecations,omitempty"`
+	ModelPrices                  *string `json:"model_prices,omitempty"`
+	AuditLogEnabled              *bool   `json:"audit_log_enabled,omitempty"`
+	AuditBodySamplePercent       *int    `json:"audit_body_sample_percent,omitempty"`
+	AuditRedactPaths             *string `json:"audit_redact_paths,omitempty"`
+	MaxConcurrentStreams         *int    `json:"max_concurrent_streams,omitempty"`
+	StreamOverflowAction         *string `json:"stream_overflow_action,omitempty"`
+	GroupMaxConcurrentRequests   *int    `json:"group_max_concurrent_requests,omitempty"`
+	KeyMaxConcurrentRequests     *int    `json:"key_max_concurrent_requests,omitempty"`
+	ConcurrencyQueueSize         *int    `json:"concurrency_queue_size,omitempty"`
+	ConcurrencyQueueTimeoutMs    *int    `json:"concurrency_queue_timeout_ms,omitempty"`
+	EmbeddingsBatchWindowMs      *int    `json:"embeddings_batch_window_ms,omitempty"`
+	EmbeddingsBatchMaxInputs     *int    `json:"embeddings_batch_max_inputs,omitempty"`
+	ResponseCacheTTLSeconds      *int    `json:"response_cache_ttl_seconds,omitempty"`
+	ResponseCacheMaxEntryKB      *int    `json:"response_cache_max_entry_kb,omitempty"`
+	EnableSemanticCache          *bool   `json:"enable_semantic_cache,omitempty"`
+	SemanticCacheGroup           *string `json:"semantic_cache_group,omitempty"`
+	SemanticCacheModel           *string `json:"semantic_cache_model,omitempty"`
+	SemanticCacheSimilarity      *int    `json:"semantic_cache_similarity,omitempty"`
+	SemanticCacheMaxEntries      *int    `json:"semantic_cache_max_entries,omitempty"`
+	InboundJSONMaxDepth          *int    `json:"inbound_json_max_depth,omitempty"`
+	InboundJSONMaxKeyLength      *int    `json:"inbound_json_max_key_length,omitempty"`
+	InboundJSONMaxSizeMB         *int    `json:"inbound_json_max_size_mb,omitempty"`
+	InboundJSONSchema            *string `json:"inbound_json_schema,omitempty"`
+	InboundScript                *string `json:"inbound_script,omitempty"`
+	OutboundScript               *string `json:"outbound_script,omitempty"`
+	NormalizeFinishReason        *bool   `json:"normalize_finish_reason,omitempty"`
+	FinishReasonMap              *string `json:"finish_reason_map,omitempty"`
 	MaxRetries                   *int    `json:"max_retries,omitempty"`
+	IdempotentMaxRetries         *int    `json:"idempotent_max_retries,omitempty"`
+	IdempotentRetryBackoffMs     *int    `json:"idempotent_retry_backoff_ms,omitempty"`
+	RetryBackoffMs               *int    `json:"retry_backoff_ms,omitempty"`
+	RetryMaxBackoffMs            *int    `json:"retry_max_backoff_ms,omitempty"`
+	RetryStatusCodes             *string `json:"retry_status_codes,omitempty"`
+	HonorRetryAfter              *bool   `json:"honor_retry_after,omitempty"`
+	RetryBudgetPercent           *int    `json:"retry_budget_percent,omitempty"`
+	CircuitBreakerFailurePercent *int    `json:"circuit_breaker_failure_percent,omitempty"`
+	CircuitBreakerMinRequests    *int    `json:"circuit_breaker_min_requests,omitempty"`
+	CircuitBreakerWindowSeconds  *int    `json:"circuit_breaker_window_seconds,omitempty"`
+	CircuitBreakerOpenSeconds    *int    `json:"circuit_breaker_open_seconds,omitempty"`
+	EnableAdaptiveCooldown       *bool   `json:"enable_adaptive_cooldown,omitempty"`
+	MaxKeyCooldownSeconds        *int    `json:"max_key_cooldown_seconds,omitempty"`
 	BlacklistThreshold           *int    `json:"blacklist_threshold,omitempty"`
+	OutageBlacklistThreshold     *int    `json:"outage_blacklist_threshold,omitempty"`
 	KeyValidationIntervalMinutes *int    `json:"key_validation_interval_minutes,omitempty"`
 	KeyValidationConcurrency     *int    `json:"key_validation_concurrency,omitempty"`
 	KeyValidationTimeoutSeconds  *int    `json:"key_validation_timeout_seconds,omitempty"`
+	KeySelectionStrategy         *string `json:"key_selection_strategy,omitempty"`
+	FairUsageWindowHours         *int    `json:"fair_usage_window_hours,omitempty"`
+	KeyWarmupMinutes             *int    `json:"key_warmup_minutes,omitempty"`
 	EnableRequestBodyLogging     *bool   `json:"enable_request_body_logging,omitempty"`
 }
 
@@ -46,6 +112,14 @@ type HeaderRule struct {
 	Key    string `json:"key"`
 	Value  string `json:"value"`
 	Action string `json:"action"` // "set" or "remove"
+	// Optional route selectors; the rule applies to every request when both are empty
+	Paths   []string `json:"paths,omitempty"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// AppliesTo reports whether the rule applies to a request with the given method and path.
+func (r HeaderRule) AppliesTo(method, requestPath string) bool {
+	return jsonengine.RouteMatches(r.Paths, r.Methods, method, requestPath)
 }
 
 // ModelRedirectTarget defines a single redirect target with weight.
@@ -54,6 +128,21 @@ type ModelRedirectTarget struct {
 	Weight int    `json:"weight"`
 }
 
+// PromptMessage 提示词模板中的单条消息
+type PromptMessage struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// PromptPlaceholder 匹配模板内容中的 {{var}} 占位符，变量名两侧允许空白
+var PromptPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// PromptTemplate 命名提示词模板，内容中的 {{var}} 占位符在请求时由变量替换
+type PromptTemplate struct {
+	System   string          `json:"system,omitempty"`
+	Messages []PromptMessage `json:"messages,omitempty"`
+}
+
 // GroupSubGroup 聚合分组和子分组的关联表
 type GroupSubGroup struct {
 	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -106,6 +195,8 @@ type Group struct {
 	ModelRedirectStrict  bool                 `gorm:"default:false" json:"model_redirect_strict"`
 	InboundRules         datatypes.JSON       `gorm:"type:json" json:"inbound_rules"`  // 入站规则（请求体）
 	OutboundRules        datatypes.JSON       `gorm:"type:json" json:"outbound_rules"` // 出站规则（响应体）
+	ErrorOutboundRules   datatypes.JSON       `gorm:"type:json" json:"error_outbound_rules"` // 错误响应出站规则（非 2xx 响应体）
+	PromptTemplates      datatypes.JSON       `gorm:"type:json" json:"prompt_templates"` // 命名提示词模板
 	APIKeys              []APIKey             `gorm:"foreignKey:GroupID" json:"api_keys"`
 	SubGroups            []GroupSubGroup      `gorm:"-" json:"sub_groups,omitempty"`
 	LastValidatedAt      *time.Time           `json:"last_validated_at"`
@@ -118,6 +209,11 @@ type Group struct {
 	ModelRedirectMap  map[string][]ModelRedirectTarget `gorm:"-" json:"-"`
 	InboundRuleList   []jsonengine.PathRule    `gorm:"-" json:"-"` // 解析后的入站规则（支持嵌套路径）
 	OutboundRuleList  []jsonengine.PathRule    `gorm:"-" json:"-"` // 解析后的出站规则（支持嵌套路径）
+	ErrorOutboundRuleList []jsonengine.PathRule `gorm:"-" json:"-"` // 解析后的错误响应出站规则
+	PromptTemplateMap map[string]PromptTemplate `gorm:"-" json:"-"` // 解析后的提示词模板
+	InboundScriptProgram  *script.Program `gorm:"-" json:"-"` // 编译后的请求体脚本
+	OutboundScriptProgram *script.Program `gorm:"-" json:"-"` // 编译后的响应体脚本
+	ruleEngines       *ruleEngineCache           // 编译后的规则引擎（见 rule_engines.go）
 }
 
 // APIKey 对应 api_keys 表
@@ -130,7 +226,9 @@ type APIKey struct {
 	Notes        string     `gorm:"type:varchar(255);default:''" json:"notes"`
 	RequestCount int64      `gorm:"not null;default:0" json:"request_count"`
 	FailureCount int64      `gorm:"not null;default:0" json:"failure_count"`
+	Weight       int        `gorm:"not null;default:1" json:"weight"`
 	LastUsedAt   *time.Time `json:"last_used_at"`
+	AvailableAt  *time.Time `gorm:"-" json:"available_at,omitempty"` // 限流冷却结束的时间，仅在冷却中时返回
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
@@ -141,28 +239,41 @@ const (
 	RequestTypeFinal = "final"
 )
 
+// ProxyKeyTier 代理密钥等级常量
+const (
+	ProxyKeyTierInteractive = "interactive"
+	ProxyKeyTierBatch       = "batch"
+)
+
 // RequestLog 对应 request_logs 表
 type RequestLog struct {
-	ID              string    `gorm:"type:varchar(36);primaryKey" json:"id"`
-	Timestamp       time.Time `gorm:"not null;index" json:"timestamp"`
-	GroupID         uint      `gorm:"not null;index" json:"group_id"`
-	GroupName       string    `gorm:"type:varchar(255);index" json:"group_name"`
-	ParentGroupID   uint      `gorm:"index" json:"parent_group_id"`
-	ParentGroupName string    `gorm:"type:varchar(255);index" json:"parent_group_name"`
-	KeyValue        string    `gorm:"type:text" json:"key_value"`
-	KeyHash         string    `gorm:"type:varchar(128);index" json:"key_hash"`
-	Model           string    `gorm:"type:varchar(255);index" json:"model"`
-	IsSuccess       bool      `gorm:"not null" json:"is_success"`
-	SourceIP        string    `gorm:"type:varchar(64)" json:"source_ip"`
-	StatusCode      int       `gorm:"not null" json:"status_code"`
-	RequestPath     string    `gorm:"type:varchar(500)" json:"request_path"`
-	Duration        int64     `gorm:"not null" json:"duration_ms"`
-	ErrorMessage    string    `gorm:"type:text" json:"error_message"`
-	UserAgent       string    `gorm:"type:varchar(512)" json:"user_agent"`
-	RequestType     string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
-	UpstreamAddr    string    `gorm:"type:varchar(500)" json:"upstream_addr"`
-	IsStream        bool      `gorm:"not null" json:"is_stream"`
-	RequestBody     string    `gorm:"type:text" json:"request_body"`
+	ID               string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Timestamp        time.Time `gorm:"not null;index" json:"timestamp"`
+	GroupID          uint      `gorm:"not null;index" json:"group_id"`
+	GroupName        string    `gorm:"type:varchar(255);index" json:"group_name"`
+	ParentGroupID    uint      `gorm:"index" json:"parent_group_id"`
+	ParentGroupName  string    `gorm:"type:varchar(255);index" json:"parent_group_name"`
+	KeyValue         string    `gorm:"type:text" json:"key_value"`
+	KeyHash          string    `gorm:"type:varchar(128);index" json:"key_hash"`
+	ProxyKeyHash     string    `gorm:"type:varchar(128);index" json:"-"` // 请求使用的代理密钥，用于按团队分摊费用
+	Model            string    `gorm:"type:varchar(255);index" json:"model"`
+	IsSuccess        bool      `gorm:"not null" json:"is_success"`
+	SourceIP         string    `gorm:"type:varchar(64)" json:"source_ip"`
+	StatusCode       int       `gorm:"not null" json:"status_code"`
+	RequestPath      string    `gorm:"type:varchar(500)" json:"request_path"`
+	Duration         int64     `gorm:"not null" json:"duration_ms"`
+	PromptTokens     int64     `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int64     `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens      int64     `gorm:"not null;default:0" json:"total_tokens"`
+	UsageEstimated   bool      `gorm:"not null;default:false" json:"usage_estimated"` // 上游未返回用量，按分词估算
+	Cost             float64   `gorm:"not null;default:0" json:"cost"`                // 按模型价格表计算的费用（美元）
+	ErrorMessage     string    `gorm:"type:text" json:"error_message"`
+	UserAgent        string    `gorm:"type:varchar(512)" json:"user_agent"`
+	RequestType      string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
+	UpstreamAddr     string    `gorm:"type:varchar(500)" json:"upstream_addr"`
+	IsStream         bool      `gorm:"not null" json:"is_stream"`
+	Region           string    `gorm:"type:varchar(64);index" json:"region"`
+	RequestBody      string    `gorm:"type:text" json:"request_body"`
 }
 
 // StatCard 用于仪表盘的单个统计卡片数据
@@ -188,6 +299,7 @@ type DashboardStatsResponse struct {
 	RPM              StatCard          `json:"rpm"`
 	RequestCount     StatCard          `json:"request_count"`
 	ErrorRate        StatCard          `json:"error_rate"`
+	Cost             StatCard          `json:"cost"` // 美元
 	SecurityWarnings []SecurityWarning `json:"security_warnings"`
 }
 
@@ -214,3 +326,21 @@ type GroupHourlyStat struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
+
+// UsageHourlyStat 对应 usage_hourly_stats 表，按小时汇总每个分组、密钥、代理密钥和模型的 token 用量及费用
+type UsageHourlyStat struct {
+	ID                uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Time              time.Time `gorm:"not null;uniqueIndex:idx_usage_hour_key_dims" json:"time"` // 整点时间
+	GroupID           uint      `gorm:"not null;uniqueIndex:idx_usage_hour_key_dims" json:"group_id"`
+	KeyHash           string    `gorm:"type:varchar(128);not null;uniqueIndex:idx_usage_hour_key_dims" json:"key_hash"`
+	Model             string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_usage_hour_key_dims" json:"model"`
+	ProxyKeyHash      string    `gorm:"type:varchar(128);not null;default:'';uniqueIndex:idx_usage_hour_key_dims" json:"-"` // 代理密钥，未使用代理密钥时为空
+	Requests          int64     `gorm:"not null;default:0" json:"requests"`
+	PromptTokens      int64     `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens  int64     `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens       int64     `gorm:"not null;default:0" json:"total_tokens"`
+	EstimatedRequests int64     `gorm:"not null;default:0" json:"estimated_requests"` // 用量为估算值的请求数
+	Cost              float64   `gorm:"not null;default:0" json:"cost"`               // 美元
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
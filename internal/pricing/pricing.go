@@ -0,0 +1,197 @@
+// Package pricing attributes a cost to each request from a price table of upstream models.
+package pricing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gpt-load/internal/usage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Price is the list price of a model in US dollars.
+type Price struct {
+	Input  float64 `json:"input"`  // per 1K prompt tokens
+	Output float64 `json:"output"` // per 1K completion tokens
+	Image  float64 `json:"image"`  // per generated image
+}
+
+// Cost returns the cost of a request with the given usage.
+func (p Price) Cost(u usage.Usage) float64 {
+	return (float64(u.PromptTokens)*p.Input+float64(u.CompletionTokens)*p.Output)/1000 + float64(u.Images)*p.Image
+}
+
+// defaultPrices are the list prices of common models. Dated and suffixed variants match the
+// longest listed prefix, so "gpt-4o-2024-08-06" is priced as "gpt-4o". Deployments add,
+// replace or remove entries with the model_prices setting.
+var defaultPrices = map[string]Price{
+	// OpenAI
+	"gpt-4o":                 {Input: 0.0025, Output: 0.01},
+	"gpt-4o-mini":            {Input: 0.00015, Output: 0.0006},
+	"gpt-4.1":                {Input: 0.002, Output: 0.008},
+	"gpt-4.1-mini":           {Input: 0.0004, Output: 0.0016},
+	"gpt-4.1-nano":           {Input: 0.0001, Output: 0.0004},
+	"gpt-4-turbo":            {Input: 0.01, Output: 0.03},
+	"gpt-4":                  {Input: 0.03, Output: 0.06},
+	"gpt-3.5-turbo":          {Input: 0.0005, Output: 0.0015},
+	"gpt-5":                  {Input: 0.00125, Output: 0.01},
+	"gpt-5-mini":             {Input: 0.00025, Output: 0.002},
+	"gpt-5-nano":             {Input: 0.00005, Output: 0.0004},
+	"o1":                     {Input: 0.015, Output: 0.06},
+	"o3":                     {Input: 0.002, Output: 0.008},
+	"o3-mini":                {Input: 0.0011, Output: 0.0044},
+	"o4-mini":                {Input: 0.0011, Output: 0.0044},
+	"text-embedding-3-small": {Input: 0.00002},
+	"text-embedding-3-large": {Input: 0.00013},
+	"text-embedding-ada-002": {Input: 0.0001},
+	"dall-e-2":               {Image: 0.02},
+	"dall-e-3":               {Image: 0.04},
+	"gpt-image-1":            {Input: 0.005, Output: 0.04},
+
+	// Anthropic
+	"claude-opus-4":     {Input: 0.015, Output: 0.075},
+	"claude-sonnet-4":   {Input: 0.003, Output: 0.015},
+	"claude-3-7-sonnet": {Input: 0.003, Output: 0.015},
+	"claude-3-5-sonnet": {Input: 0.003, Output: 0.015},
+	"claude-3-5-haiku":  {Input: 0.0008, Output: 0.004},
+	"claude-3-opus":     {Input: 0.015, Output: 0.075},
+	"claude-3-haiku":    {Input: 0.00025, Output: 0.00125},
+
+	// Gemini
+	"gemini-2.5-pro":        {Input: 0.00125, Output: 0.01},
+	"gemini-2.5-flash":      {Input: 0.0003, Output: 0.0025},
+	"gemini-2.5-flash-lite": {Input: 0.0001, Output: 0.0004},
+	"gemini-2.0-flash":      {Input: 0.0001, Output: 0.0004},
+	"gemini-2.0-flash-lite": {Input: 0.000075, Output: 0.0003},
+	"gemini-1.5-pro":        {Input: 0.00125, Output: 0.005},
+	"gemini-1.5-flash":      {Input: 0.000075, Output: 0.0003},
+	"text-embedding-004":    {Input: 0.00001},
+	"imagen-3.0":            {Image: 0.03},
+}
+
+// tables caches the parsed price table of each model_prices setting value.
+var tables sync.Map // map[string]map[string]Price
+
+// Lookup returns the price of a model from the built-in table combined with the overrides
+// of the model_prices setting. Exact names take precedence over the longest matching prefix.
+// It reports false for models without a price.
+func Lookup(model, overrides string) (Price, bool) {
+	model = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(model), "models/"))
+	if model == "" {
+		return Price{}, false
+	}
+	table := table(overrides)
+	if price, ok := table[model]; ok {
+		return price, true
+	}
+
+	best, found := "", false
+	for name := range table {
+		if len(name) > len(best) && strings.HasPrefix(model, name) {
+			best, found = name, true
+		}
+	}
+	return table[best], found
+}
+
+// Cost returns the cost of a request to model with the given usage, or 0 when the model has
+// no price.
+func Cost(model, overrides string, u usage.Usage) float64 {
+	price, ok := Lookup(model, overrides)
+	if !ok {
+		return 0
+	}
+	return price.Cost(u)
+}
+
+// table returns the built-in prices combined with the overrides. Entries are removed from
+// the table with an empty price ("model=").
+func table(overrides string) map[string]Price {
+	overrides = strings.TrimSpace(overrides)
+	if overrides == "" {
+		return defaultPrices
+	}
+	if cached, ok := tables.Load(overrides); ok {
+		return cached.(map[string]Price)
+	}
+
+	entries, err := parseOverrides(overrides, true)
+	if err != nil {
+		logrus.WithError(err).Warn("Ignoring invalid model_prices entries")
+	}
+	combined := make(map[string]Price, len(defaultPrices)+len(entries))
+	for name, price := range defaultPrices {
+		combined[name] = price
+	}
+	for name, price := range entries {
+		if price == nil {
+			delete(combined, name)
+		} else {
+			combined[name] = *price
+		}
+	}
+	tables.Store(overrides, combined)
+	return combined
+}
+
+// ValidateOverrides checks the format of a model_prices setting value: comma-separated
+// model=input/output[/image] entries in US dollars per 1K tokens and per image, or model= to
+// remove a built-in entry.
+func ValidateOverrides(overrides string) error {
+	_, err := parseOverrides(overrides, false)
+	return err
+}
+
+// parseOverrides parses the model_prices entries. A nil price removes the model. Invalid
+// entries fail the parse, or are skipped when lenient is true.
+func parseOverrides(overrides string, lenient bool) (map[string]*Price, error) {
+	result := make(map[string]*Price)
+	var firstErr error
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		price, model, err := parseEntry(entry)
+		if err != nil {
+			if !lenient {
+				return nil, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		result[model] = price
+	}
+	return result, firstErr
+}
+
+func parseEntry(entry string) (*Price, string, error) {
+	model, value, ok := strings.Cut(entry, "=")
+	model = strings.ToLower(strings.TrimSpace(model))
+	if !ok || model == "" {
+		return nil, "", fmt.Errorf("invalid entry %q, expected model=input/output[/image]", entry)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, model, nil
+	}
+
+	fields := strings.Split(value, "/")
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, "", fmt.Errorf("invalid price %q for model %s, expected input/output[/image]", value, model)
+	}
+	amounts := make([]float64, 3)
+	for i, field := range fields {
+		amount, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || amount < 0 {
+			return nil, "", fmt.Errorf("invalid price %q for model %s, prices must be non-negative numbers", value, model)
+		}
+		amounts[i] = amount
+	}
+	return &Price{Input: amounts[0], Output: amounts[1], Image: amounts[2]}, model, nil
+}
@@ -0,0 +1,183 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Groups with audit_log_enabled record an audit log entry for every request answered by the
+// upstream or failed after its retries. A sample of the entries, audit_body_sample_percent,
+// also keeps the request and response bodies, masked with the group's audit_redact_paths.
+
+const (
+	auditSampledContextKey = "auditSampled"
+	auditWriterContextKey  = "auditWriter"
+	// maxAuditCaptureSize is the largest response body kept to be redacted and recorded.
+	maxAuditCaptureSize = 1 << 20
+	// maxAuditBodyLength is the longest body stored in an audit log entry.
+	maxAuditBodyLength = 65000
+	// auditBodyOmitted replaces a body that has redaction paths but could not be redacted,
+	// such as a body that is not JSON or a response cut off at maxAuditCaptureSize.
+	auditBodyOmitted = "[omitted: the body could not be redacted]"
+)
+
+// auditRedactEngines caches the masking engine of each audit_redact_paths value.
+var auditRedactEngines sync.Map // map[string]*jsonengine.PathEngine
+
+// auditBodySampled reports whether the bodies of the request are recorded in its audit log
+// entry. The sample is drawn once per request, so retries on other groups keep it.
+func auditBodySampled(c *gin.Context, group *models.Group) bool {
+	if value, exists := c.Get(auditSampledContextKey); exists {
+		sampled, _ := value.(bool)
+		return sampled
+	}
+	cfg := group.EffectiveConfig
+	sampled := cfg.AuditLogEnabled && cfg.AuditBodySamplePercent > 0 && rand.Intn(100) < cfg.AuditBodySamplePercent
+	c.Set(auditSampledContextKey, sampled)
+	return sampled
+}
+
+// auditBodyWriter keeps the start of the response body for the audit log entry.
+type auditBodyWriter struct {
+	gin.ResponseWriter
+	body      []byte
+	truncated bool
+}
+
+// installAuditWriter captures the response body when the request's bodies are sampled. The
+// returned function restores the previous writer.
+func installAuditWriter(c *gin.Context, group *models.Group) func() {
+	if !auditBodySampled(c, group) {
+		return func() {}
+	}
+	w := &auditBodyWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Set(auditWriterContextKey, w)
+	return func() { c.Writer = w.ResponseWriter }
+}
+
+func (w *auditBodyWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditBodyWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditBodyWriter) capture(data []byte) {
+	if room := maxAuditCaptureSize - len(w.body); len(data) > room {
+		data = data[:room]
+		w.truncated = true
+	}
+	w.body = append(w.body, data...)
+}
+
+// recordAudit queues the audit log entry of a request from its request log entry.
+func (ps *ProxyServer) recordAudit(c *gin.Context, group *models.Group, logEntry *models.RequestLog, bodyBytes []byte) {
+	if ps.auditLogService == nil {
+		return
+	}
+
+	entry := &models.AuditLog{
+		Timestamp:        time.Now(),
+		Method:           c.Request.Method,
+		Path:             utils.TruncateString(c.Request.URL.Path, 500), // the query may carry the proxy key
+		GroupID:          logEntry.GroupID,
+		GroupName:        logEntry.GroupName,
+		KeyHash:          logEntry.KeyHash,
+		ProxyKeyHash:     logEntry.ProxyKeyHash,
+		Model:            logEntry.Model,
+		StatusCode:       logEntry.StatusCode,
+		IsSuccess:        logEntry.IsSuccess,
+		Duration:         logEntry.Duration,
+		PromptTokens:     logEntry.PromptTokens,
+		CompletionTokens: logEntry.CompletionTokens,
+		TotalTokens:      logEntry.TotalTokens,
+		SourceIP:         logEntry.SourceIP,
+		ErrorMessage:     logEntry.ErrorMessage,
+	}
+	if auditBodySampled(c, group) {
+		entry.BodySampled = true
+		entry.RequestBody = redactAuditBody(group, requestBodyForLog(c, bodyBytes), false, false)
+		if value, exists := c.Get(auditWriterContextKey); exists {
+			if w, ok := value.(*auditBodyWriter); ok {
+				stream := strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+				entry.ResponseBody = redactAuditBody(group, w.body, stream, w.truncated)
+			}
+		}
+	}
+	ps.auditLogService.Record(entry)
+}
+
+// redactAuditBody masks the group's audit_redact_paths in a JSON body or event stream and
+// truncates the result. Bodies that cannot be redacted are omitted. Without redaction paths
+// the body is recorded as is.
+func redactAuditBody(group *models.Group, body []byte, stream, truncated bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+	paths := strings.TrimSpace(group.EffectiveConfig.AuditRedactPaths)
+	if paths == "" {
+		return utils.TruncateString(string(body), maxAuditBodyLength)
+	}
+	engine, err := auditRedactEngine(paths)
+	if err != nil {
+		logrus.WithError(err).WithField("group_name", group.Name).Warn("Invalid audit redaction paths")
+		return auditBodyOmitted
+	}
+
+	if stream {
+		// Event stream: keep the complete events only, the engine passes partial events through
+		if truncated {
+			end := bytes.LastIndex(body, []byte("\n\n"))
+			if end < 0 {
+				return auditBodyOmitted
+			}
+			body = body[:end+2]
+		}
+		var out bytes.Buffer
+		if _, err := engine.ProcessSSE(bytes.NewReader(body), &out); err != nil {
+			return auditBodyOmitted
+		}
+		return utils.TruncateString(out.String(), maxAuditBodyLength)
+	}
+
+	if truncated || !json.Valid(body) {
+		return auditBodyOmitted
+	}
+	out, err := engine.ProcessBytes(body)
+	if err != nil {
+		return auditBodyOmitted
+	}
+	return utils.TruncateString(string(out), maxAuditBodyLength)
+}
+
+// auditRedactEngine returns the cached masking engine for an audit_redact_paths value.
+func auditRedactEngine(paths string) (*jsonengine.PathEngine, error) {
+	if cached, ok := auditRedactEngines.Load(paths); ok {
+		return cached.(*jsonengine.PathEngine), nil
+	}
+	rules, err := jsonengine.ParseMaskRules(paths, jsonengine.MaskRedact)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := jsonengine.NewPathEngine(rules)
+	if err != nil {
+		return nil, err
+	}
+	auditRedactEngines.Store(paths, engine)
+	return engine, nil
+}
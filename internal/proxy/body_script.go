@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/script"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// scriptRequestInfo describes the client request to a body script.
+func scriptRequestInfo(c *gin.Context, group *models.Group) script.RequestInfo {
+	headers := make(map[string]string, len(c.Request.Header))
+	for name, values := range c.Request.Header {
+		if len(values) > 0 {
+			headers[strings.ToLower(name)] = values[0]
+		}
+	}
+	return script.RequestInfo{
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.Path,
+		Group:   group.Name,
+		Headers: headers,
+	}
+}
+
+// applyInboundScript runs the group's inbound script on a request body. A failing script
+// is logged and the body forwarded unchanged, as when inbound rules fail.
+func applyInboundScript(c *gin.Context, body []byte, group *models.Group) []byte {
+	program := group.InboundScriptProgram
+	if program == nil || len(body) == 0 {
+		return body
+	}
+	out, err := program.Rewrite(body, script.Env{Request: scriptRequestInfo(c, group)})
+	if err != nil {
+		logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to apply inbound script")
+		return body
+	}
+	return out
+}
+
+// applyOutboundScript runs the group's outbound script on a buffered response body.
+// A failing script is logged and the body returned unchanged.
+func applyOutboundScript(c *gin.Context, body []byte, group *models.Group, status int) []byte {
+	program := group.OutboundScriptProgram
+	if program == nil {
+		return body
+	}
+	out, err := program.Rewrite(body, script.Env{
+		Request:  scriptRequestInfo(c, group),
+		Response: script.ResponseInfo{Status: status},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to apply outbound script")
+		return body
+	}
+	return out
+}
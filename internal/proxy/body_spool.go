@@ -0,0 +1,332 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// bodySpoolFilePattern names spool files, so stale ones can be found after a crash.
+	bodySpoolFilePattern = "gpt-load-body-*"
+	// bodySpoolStaleAge is how old a spool file must be before it is removed as left over.
+	bodySpoolStaleAge   = 24 * time.Hour
+	bodySpoolContextKey = "bodySpool"
+)
+
+var (
+	requestBodiesSpooledTotal = metrics.NewCounter(
+		"gpt_load_request_bodies_spooled_total",
+		"Request bodies spilled to temporary files because they exceeded request_body_spool_threshold_mb, by group.",
+		"group",
+	)
+	requestBodySpoolBytes = metrics.NewGauge(
+		"gpt_load_request_body_spool_bytes",
+		"Bytes of request bodies currently held in temporary spool files.",
+	)
+)
+
+// spoolSummaryRules capture the fields the channels read from a request body, so a spilled
+// body is never loaded into memory to detect streaming or log the model.
+var spoolSummaryRules = []jsonengine.PathRule{
+	{Path: "model", Action: jsonengine.ActionCapture},
+	{Path: "stream", Action: jsonengine.ActionCapture},
+}
+
+// bodySpool holds a request body in memory up to a threshold and in a temporary file beyond it.
+// The body can be read any number of times, so retries replay it without keeping it in memory.
+type bodySpool struct {
+	mem  []byte
+	file *os.File // nil while the body fits in memory
+	size int64
+}
+
+// spoolBody reads r, keeping up to threshold bytes in memory and spilling larger bodies to
+// a temporary file in dir (the system temp directory when empty).
+func spoolBody(r io.Reader, threshold int64, dir string) (*bodySpool, error) {
+	mem, err := io.ReadAll(io.LimitReader(r, threshold+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(mem)) <= threshold {
+		return &bodySpool{mem: mem, size: int64(len(mem))}, nil
+	}
+	return writeSpool(dir, func(w io.Writer) error {
+		if _, err := w.Write(mem); err != nil {
+			return err
+		}
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// writeSpool creates a spool file in dir and fills it with write.
+func writeSpool(dir string, write func(w io.Writer) error) (*bodySpool, error) {
+	file, err := os.CreateTemp(dir, bodySpoolFilePattern)
+	if err != nil {
+		return nil, fmt.Errorf("create spool file: %w", err)
+	}
+	s := &bodySpool{file: file}
+
+	counter := &countingWriter{w: file}
+	w := bufio.NewWriterSize(counter, 64*1024)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	s.size = counter.n
+	requestBodySpoolBytes.Add(float64(s.size))
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// spilled reports whether the body is held in a temporary file.
+func (s *bodySpool) spilled() bool {
+	return s.file != nil
+}
+
+// reader returns a new reader over the whole body.
+func (s *bodySpool) reader() io.Reader {
+	if s.file == nil {
+		return bytes.NewReader(s.mem)
+	}
+	return io.NewSectionReader(s.file, 0, s.size)
+}
+
+// placeholder stands in for a spilled body in request logs and debug captures.
+func (s *bodySpool) placeholder() string {
+	return fmt.Sprintf("[request body spooled to disk, %d bytes]", s.size)
+}
+
+// Close removes the spool file. It is safe to call more than once.
+func (s *bodySpool) Close() {
+	if s.file == nil {
+		return
+	}
+	name := s.file.Name()
+	s.file.Close()
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logrus.WithError(err).WithField("file", name).Warn("Failed to remove request body spool file")
+	}
+	requestBodySpoolBytes.Add(-float64(s.size))
+	s.file = nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// canSpoolBody reports whether every body step configured for the request can stream.
+// Prompt templates, validation, parameter overrides, seed injection, deprecation and model
+// redirects and stream downgrades parse the whole body, so those groups keep it in memory.
+func canSpoolBody(originalGroup, group *models.Group) bool {
+	cfg := group.EffectiveConfig
+	if cfg.EnableRequestValidation {
+		return false
+	}
+	if cfg.SignedRequestPassthrough {
+		return true
+	}
+	return len(originalGroup.PromptTemplateMap) == 0 && len(group.PromptTemplateMap) == 0 &&
+		len(group.ParamOverrides) == 0 && len(group.ModelRedirectMap) == 0 &&
+		strings.TrimSpace(cfg.RequestSeed) == "" && !cfg.ModelDeprecationRemap &&
+		!(cfg.MaxConcurrentStreams > 0 && cfg.StreamOverflowAction == streamOverflowDowngrade)
+}
+
+// readRequestBody reads the client body. Bodies over request_body_spool_threshold_mb are
+// returned as a spilled spool instead of bytes when the group allows it.
+func (ps *ProxyServer) readRequestBody(c *gin.Context, originalGroup, group *models.Group) ([]byte, *bodySpool, error) {
+	defer c.Request.Body.Close()
+
+	settings := ps.settingsManager.GetSettings()
+	if settings.RequestBodySpoolThresholdMB <= 0 || !canSpoolBody(originalGroup, group) {
+		body, err := io.ReadAll(c.Request.Body)
+		return body, nil, err
+	}
+
+	spool, err := spoolBody(c.Request.Body, int64(settings.RequestBodySpoolThresholdMB)<<20, settings.RequestBodySpoolDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !spool.spilled() {
+		return spool.mem, nil, nil
+	}
+	requestBodiesSpooledTotal.Inc(group.Name)
+	return nil, spool, nil
+}
+
+// transformSpooledBody is the transform stage for a spilled body: the inbound rules stream
+// from one spool file to another, and the pipeline carries a summary holding only the model
+// and stream fields. The upstream request reads the body from the spool on every attempt.
+func (ps *ProxyServer) transformSpooledBody(req *ProxyRequest, spool *bodySpool, next Handler) {
+	c, group := req.Context, req.Group
+	defer spool.Close()
+
+	passthrough := group.EffectiveConfig.SignedRequestPassthrough
+	final := spool
+	if !passthrough && hasInboundProcessing(group) {
+		setRuleModel(c, req.Channel.ExtractModel(c, spoolSummary(spool)))
+		out, err := ps.applySpooledInboundRules(spool, group, ruleScope(c))
+		var limitErr *jsonengine.LimitError
+		var schemaErr *jsonengine.SchemaError
+		if errors.As(err, &limitErr) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("Request body exceeds limits: %v", err)))
+			return
+		}
+		if errors.As(err, &schemaErr) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("Request body does not match the inbound schema: %v", err)))
+			return
+		}
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply inbound rules: %v", err)))
+			return
+		}
+		if out != nil {
+			defer out.Close()
+			final = out
+		}
+	}
+
+	if group.InboundScriptProgram != nil && !passthrough {
+		logrus.WithField("group_name", group.Name).Debug("Skipping inbound script for spooled request body")
+	}
+
+	summary := spoolSummary(final)
+	if final == spool {
+		setRuleModel(c, req.Channel.ExtractModel(c, summary))
+	}
+	c.Set(bodySpoolContextKey, final)
+
+	req.Body = summary
+	req.FinalBody = summary
+	req.Passthrough = passthrough
+	req.IsStream = req.Channel.IsStreamRequest(c, summary)
+	next(req)
+}
+
+// applySpooledInboundRules writes the rewritten body to a new spool. It returns nil without
+// an error when the rules cannot be applied, so the original body is forwarded as in
+// applyInboundRules.
+func (ps *ProxyServer) applySpooledInboundRules(spool *bodySpool, group *models.Group, scope models.RuleScope) (*bodySpool, error) {
+	engine, err := group.ScopedEngine(ruleDirectionInbound, scope)
+	if err != nil {
+		logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to create path engine for inbound rules")
+		return nil, nil
+	}
+	if engine == nil {
+		return nil, nil
+	}
+
+	var result jsonengine.ProcessResult
+	out, err := writeSpool(ps.settingsManager.GetSettings().RequestBodySpoolDir, func(w io.Writer) error {
+		var err error
+		result, err = engine.ProcessWithResult(spool.reader(), w)
+		return err
+	})
+	if err != nil {
+		var limitErr *jsonengine.LimitError
+		var schemaErr *jsonengine.SchemaError
+		if errors.As(err, &limitErr) || errors.As(err, &schemaErr) {
+			return nil, err
+		}
+		logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to apply inbound rules to spooled body")
+		return nil, nil
+	}
+
+	recordRuleEngineStats(group, ruleDirectionInbound, result.Stats)
+	logrus.WithFields(logrus.Fields{
+		"group":        group.Name,
+		"input_bytes":  result.Stats.BytesIn,
+		"output_bytes": result.Stats.BytesOut,
+	}).Debugf("Inbound PathEngine processing of spooled body: process=%v", result.Stats.Duration)
+	return out, nil
+}
+
+// spoolSummary returns a small JSON object with the model and stream fields of a spilled body.
+func spoolSummary(spool *bodySpool) []byte {
+	summary := []byte("{}")
+	engine, err := jsonengine.NewPathEngine(spoolSummaryRules)
+	if err != nil {
+		return summary
+	}
+	result, err := engine.ProcessWithResult(spool.reader(), io.Discard)
+	if err != nil || len(result.Captured) == 0 {
+		return summary
+	}
+	if encoded, err := json.Marshal(result.Captured); err == nil {
+		summary = encoded
+	}
+	return summary
+}
+
+// requestBodySpool returns the spilled body of the request, or nil.
+func requestBodySpool(c *gin.Context) *bodySpool {
+	if v, ok := c.Get(bodySpoolContextKey); ok {
+		return v.(*bodySpool)
+	}
+	return nil
+}
+
+// upstreamBody returns the body to send upstream and its length: the spilled body when
+// there is one, bodyBytes otherwise.
+func upstreamBody(c *gin.Context, bodyBytes []byte) (io.Reader, int64) {
+	if spool := requestBodySpool(c); spool != nil {
+		return spool.reader(), spool.size
+	}
+	return bytes.NewReader(bodyBytes), int64(len(bodyBytes))
+}
+
+// requestBodyForLog returns the body to record in request logs and debug captures.
+func requestBodyForLog(c *gin.Context, bodyBytes []byte) []byte {
+	if spool := requestBodySpool(c); spool != nil {
+		return []byte(spool.placeholder())
+	}
+	return bodyBytes
+}
+
+// removeStaleBodySpools deletes spool files left behind by a crashed process.
+func removeStaleBodySpools(dir string) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, bodySpoolFilePattern))
+	if err != nil {
+		return
+	}
+	for _, name := range matches {
+		info, err := os.Stat(name)
+		if err != nil || info.IsDir() || time.Since(info.ModTime()) < bodySpoolStaleAge {
+			continue
+		}
+		if err := os.Remove(name); err == nil {
+			logrus.WithField("file", name).Info("Removed stale request body spool file")
+		}
+	}
+}
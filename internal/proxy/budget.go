@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Budgets cap the requests, tokens and cost of a group, or of one of its proxy keys, per
+// period of a cron schedule. Costs come from the model_prices table, or from the budget's
+// token price for models without a price. Once a budget is exhausted its requests are
+// rejected with a 429 until the next reset, or sent on with the budget's downgrade model.
+
+var budgetEnforcedTotal = metrics.NewCounter(
+	"gpt_load_budget_enforced_total",
+	"Requests over an exhausted budget, by group and action (reject, downgrade).",
+	"group", "action",
+)
+
+// budgetStage enforces the budgets of the group named in the URL and counts successful
+// requests and their token usage against them.
+func (ps *ProxyServer) budgetStage(req *ProxyRequest, next Handler) {
+	c, group := req.Context, req.OriginalGroup
+	budgets := ps.budgetService.Applicable(group.ID, ps.proxyKeyHash(c))
+	if len(budgets) == 0 {
+		next(req)
+		return
+	}
+
+	if exhausted := ps.budgetService.Exhausted(budgets); len(exhausted) > 0 {
+		if !ps.applyBudgetDowngrade(req, exhausted) {
+			writeBudgetError(c, group, exhausted)
+			return
+		}
+	}
+
+	next(req)
+
+	// Responses served without an upstream call, e.g. from the response cache, use no tokens
+	if c.Writer.Status() < http.StatusBadRequest {
+		requestUsage, _ := getRequestUsage(c)
+		cost, priced := getRequestCost(c)
+		ps.budgetService.Record(budgets, requestUsage.TotalTokens, cost, priced)
+	}
+}
+
+// proxyKeyHash returns the hash of the proxy key the request authenticated with, or an empty
+// string for requests without one.
+func (ps *ProxyServer) proxyKeyHash(c *gin.Context) string {
+	proxyKey := c.GetString("proxyKey")
+	if proxyKey == "" {
+		return ""
+	}
+	return ps.encryptionSvc.Hash(proxyKey)
+}
+
+// applyBudgetDowngrade switches the request to the downgrade model of the exhausted budgets.
+// It reports false when one of them rejects requests or the model cannot be rewritten.
+func (ps *ProxyServer) applyBudgetDowngrade(req *ProxyRequest, exhausted []services.BudgetStatus) bool {
+	downgradeModel := ""
+	for _, status := range exhausted {
+		if status.Action != models.BudgetActionDowngrade {
+			return false
+		}
+		if downgradeModel == "" {
+			downgradeModel = status.DowngradeModel
+		}
+	}
+	if req.Passthrough || requestBodySpool(req.Context) != nil {
+		return false
+	}
+
+	body, model, ok := rewriteRequestModel(req.Context, req.FinalBody, downgradeModel)
+	if !ok {
+		return false
+	}
+	req.FinalBody = body
+	setRuleModel(req.Context, downgradeModel)
+
+	c := req.Context
+	c.Header("Warning", fmt.Sprintf(`299 gpt-load "Budget exhausted, model '%s' downgraded to '%s'"`, model, downgradeModel))
+	budgetEnforcedTotal.Inc(req.OriginalGroup.Name, models.BudgetActionDowngrade)
+	requestLogger(c).WithFields(logrus.Fields{
+		"group_name":      req.OriginalGroup.Name,
+		"model":           model,
+		"downgrade_model": downgradeModel,
+	}).Debug("Budget exhausted, downgraded model")
+	return true
+}
+
+// rewriteRequestModel replaces the requested model in the body or, for Gemini native
+// requests, in the path. It returns the rewritten body and the previous model.
+func rewriteRequestModel(c *gin.Context, body []byte, model string) ([]byte, string, bool) {
+	var requestData map[string]any
+	if len(body) > 0 && json.Unmarshal(body, &requestData) == nil {
+		if previous, _ := requestData["model"].(string); previous != "" {
+			requestData["model"] = model
+			rewritten, err := json.Marshal(requestData)
+			if err != nil {
+				return nil, "", false
+			}
+			return rewritten, previous, true
+		}
+	}
+
+	parts := strings.Split(c.Request.URL.Path, "/")
+	for i, part := range parts {
+		if part == "models" && i+1 < len(parts) {
+			previous := strings.Split(parts[i+1], ":")[0]
+			parts[i+1] = model + strings.TrimPrefix(parts[i+1], previous)
+			c.Request.URL.Path = strings.Join(parts, "/")
+			c.Request.URL.RawPath = ""
+			return body, previous, true
+		}
+	}
+	return nil, "", false
+}
+
+// writeBudgetError rejects a request over an exhausted budget, with Retry-After set to the
+// earliest time one of the exhausted budgets resets.
+func writeBudgetError(c *gin.Context, group *models.Group, exhausted []services.BudgetStatus) {
+	var resetsAt *time.Time
+	for _, status := range exhausted {
+		if status.ResetsAt != nil && (resetsAt == nil || status.ResetsAt.Before(*resetsAt)) {
+			resetsAt = status.ResetsAt
+		}
+	}
+
+	message := fmt.Sprintf("The usage budget of group '%s' is exhausted", group.Name)
+	if resetsAt != nil {
+		retryAfter := max(int(time.Until(*resetsAt).Seconds())+1, 1)
+		c.Header(headerRetryAfter, strconv.Itoa(retryAfter))
+		message += fmt.Sprintf(" until %s", resetsAt.Format(time.RFC3339))
+	}
+	budgetEnforcedTotal.Inc(group.Name, models.BudgetActionReject)
+	response.Error(c, app_errors.NewAPIError(app_errors.ErrBudgetExceeded, message))
+}
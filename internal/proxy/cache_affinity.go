@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// selectKey picks the key bound to the referenced cached context, since provider
+// caches are only visible to the key that created them. Without a usable binding,
+// and on retries after the first attempt failed, it falls back to the group's
+// regular key selection.
+func (ps *ProxyServer) selectKey(group *models.Group, cacheRef *channel.CacheReference, retryCount int) (*models.APIKey, error) {
+	if cacheRef != nil && retryCount == 0 {
+		apiKey, err := ps.keyProvider.SelectCachedKey(group, cacheRef.ID)
+		if err != nil {
+			logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to look up cached context key, falling back to regular selection")
+		} else if apiKey != nil {
+			return apiKey, nil
+		}
+	}
+	return ps.keyProvider.SelectKey(group)
+}
+
+// updateCacheBinding keeps the cached context binding in sync after a successful request:
+// deleting the context removes the binding, implicit caches are (re)bound to the key used.
+func (ps *ProxyServer) updateCacheBinding(c *gin.Context, group *models.Group, apiKey *models.APIKey, cacheRef *channel.CacheReference, statusCode int) {
+	if cacheRef == nil || statusCode >= http.StatusMultipleChoices {
+		return
+	}
+	switch {
+	case c.Request.Method == http.MethodDelete:
+		ps.keyProvider.UnbindCache(group.ID, cacheRef.ID)
+	case cacheRef.Implicit:
+		ps.keyProvider.BindCache(group.ID, cacheRef.ID, apiKey.ID, cacheRef.TTL)
+	}
+}
+
+// handleCacheCreateResponse relays the response of a cache create request and binds
+// the created context to the key that created it.
+func (ps *ProxyServer) handleCacheCreateResponse(c *gin.Context, resp *http.Response, group *models.Group, apiKey *models.APIKey, channelHandler channel.ChannelProxy) {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logUpstreamError("reading cache create response body", err)
+		return
+	}
+
+	if resp.StatusCode < http.StatusMultipleChoices {
+		decompressed, err := utils.DecompressResponse(resp.Header.Get("Content-Encoding"), bodyBytes)
+		if err != nil {
+			decompressed = bodyBytes
+		}
+		if created := channelHandler.ParseCreatedCache(decompressed); created != nil {
+			ps.keyProvider.BindCache(group.ID, created.ID, apiKey.ID, created.TTL)
+		} else {
+			logrus.WithField("group_name", group.Name).Warn("Cache create response did not contain a cached context name")
+		}
+	}
+
+	if _, err := c.Writer.Write(bodyBytes); err != nil {
+		logUpstreamError("writing cache create response", err)
+	}
+}
@@ -0,0 +1,30 @@
+package proxy
+
+import (
+	"net/http"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+)
+
+// recordCircuitResult feeds the outcome of an upstream attempt to the circuit breakers.
+// Connection errors and 5xx responses count against both the key and the upstream host;
+// rate limits and authentication errors only against the key. Other responses, including
+// client errors, show that both are working.
+func (ps *ProxyServer) recordCircuitResult(group *models.Group, apiKey *models.APIKey, upstreamURL string, resp *http.Response, err error) {
+	cfg := &group.EffectiveConfig
+	if cfg.CircuitBreakerFailurePercent <= 0 {
+		return
+	}
+
+	hostFailed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+	keyFailed := hostFailed
+	if resp != nil {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusUnauthorized, http.StatusForbidden:
+			keyFailed = true
+		}
+	}
+	ps.keyProvider.RecordKeyResult(group, apiKey.ID, !keyFailed)
+	channel.RecordUpstreamResult(upstreamURL, cfg, !hostFailed)
+}
@@ -0,0 +1,283 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Concurrency limits cap the in-flight upstream requests of each group
+// (group_max_concurrent_requests) and of each of its keys (key_max_concurrent_requests).
+// Requests over a limit wait in a queue of up to concurrency_queue_size requests for at most
+// concurrency_queue_timeout_ms; requests that find the queue full or time out get a 429 with
+// Retry-After. The queue is ordered by proxy key tier, then by arrival: interactive requests
+// are admitted before queued batch requests, and take the place of the newest queued batch
+// request when the queue is full. Limits are enforced per instance.
+
+const (
+	concurrencyScopeGroup = "group"
+	concurrencyScopeKey   = "key"
+)
+
+// Queue priorities, highest first.
+const (
+	priorityInteractive = iota
+	priorityBatch
+	priorityLevels
+)
+
+// priorityTiers maps queue priorities to proxy key tiers for metrics.
+var priorityTiers = [priorityLevels]string{models.ProxyKeyTierInteractive, models.ProxyKeyTierBatch}
+
+var (
+	errConcurrencyQueueFull    = errors.New("concurrency queue is full")
+	errConcurrencyQueueTimeout = errors.New("timed out waiting in the concurrency queue")
+	errConcurrencyPreempted    = errors.New("queued request was preempted by a higher priority request")
+)
+
+var (
+	concurrencyQueuedRequests = metrics.NewGauge(
+		"gpt_load_concurrency_queued_requests",
+		"Requests waiting for a concurrency slot, by group, scope (group, key) and proxy key tier. Updated on scrape.",
+		"group", "scope", "tier",
+	)
+	concurrencyRejectedTotal = metrics.NewCounter(
+		"gpt_load_concurrency_rejected_total",
+		"Requests rejected by a concurrency limit, by group, scope (group, key), proxy key tier and reason (queue_full, timeout, preempted).",
+		"group", "scope", "tier", "reason",
+	)
+	concurrencyQueueWaitSeconds = metrics.NewCounter(
+		"gpt_load_concurrency_queue_wait_seconds_total",
+		"Time requests spent waiting for a concurrency slot, by group, scope (group, key) and proxy key tier.",
+		"group", "scope", "tier",
+	)
+)
+
+// tierPriority returns the queue priority of a proxy key tier.
+func tierPriority(tier string) int {
+	if tier == models.ProxyKeyTierBatch {
+		return priorityBatch
+	}
+	return priorityInteractive
+}
+
+// concurrencyWaiter is a request waiting in a limiter queue.
+type concurrencyWaiter struct {
+	ready     chan struct{}
+	granted   bool
+	preempted bool
+}
+
+// concurrencyLimiter is a semaphore whose waiters are admitted by priority, then in arrival order.
+type concurrencyLimiter struct {
+	group    string // name of the group whose settings apply, for metrics
+	scope    string
+	mu       sync.Mutex
+	limit    int
+	inFlight int
+	queues   [priorityLevels]list.List
+}
+
+func (l *concurrencyLimiter) queuedLocked() int {
+	n := 0
+	for i := range l.queues {
+		n += l.queues[i].Len()
+	}
+	return n
+}
+
+// acquire takes a slot, waiting in the queue when all limit slots are taken. When maxQueue
+// requests are already waiting it preempts the newest waiter of a lower priority, or fails.
+func (l *concurrencyLimiter) acquire(ctx context.Context, group string, priority, limit, maxQueue int, timeout time.Duration) error {
+	l.mu.Lock()
+	l.group, l.limit = group, limit
+	queued := l.queuedLocked()
+	if l.inFlight < limit && queued == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	if timeout <= 0 || (queued >= maxQueue && !l.preemptLocked(priority)) {
+		l.mu.Unlock()
+		return errConcurrencyQueueFull
+	}
+	w := &concurrencyWaiter{ready: make(chan struct{})}
+	queue := &l.queues[priority]
+	elem := queue.PushBack(w)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-w.ready:
+		if w.preempted {
+			return errConcurrencyPreempted
+		}
+		return nil
+	case <-timer.C:
+		err = errConcurrencyQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case w.granted:
+		// The slot was handed over while giving up; pass it on
+		l.releaseLocked()
+	case !w.preempted:
+		queue.Remove(elem)
+	}
+	return err
+}
+
+// preemptLocked rejects the newest waiter with a lower priority than priority to make room
+// in the queue. It reports false when there is none.
+func (l *concurrencyLimiter) preemptLocked(priority int) bool {
+	for p := priorityLevels - 1; p > priority; p-- {
+		if back := l.queues[p].Back(); back != nil {
+			w := l.queues[p].Remove(back).(*concurrencyWaiter)
+			w.preempted = true
+			close(w.ready)
+			return true
+		}
+	}
+	return false
+}
+
+// release frees a slot, handing it to the oldest waiter of the highest priority.
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked()
+}
+
+func (l *concurrencyLimiter) releaseLocked() {
+	l.inFlight--
+	for p := range l.queues {
+		queue := &l.queues[p]
+		for l.inFlight < l.limit && queue.Len() > 0 {
+			w := queue.Remove(queue.Front()).(*concurrencyWaiter)
+			w.granted = true
+			l.inFlight++
+			close(w.ready)
+		}
+	}
+}
+
+// concurrencyLimits holds the limiters of all groups and keys.
+type concurrencyLimits struct {
+	mu       sync.Mutex
+	limiters map[string]*concurrencyLimiter
+}
+
+func newConcurrencyLimits() *concurrencyLimits {
+	cl := &concurrencyLimits{limiters: make(map[string]*concurrencyLimiter)}
+	metrics.OnCollect(cl.collectQueueDepth)
+	return cl
+}
+
+// collectQueueDepth copies the queue depth of each group, scope and tier into the metrics before a scrape.
+func (cl *concurrencyLimits) collectQueueDepth() {
+	cl.mu.Lock()
+	limiters := make([]*concurrencyLimiter, 0, len(cl.limiters))
+	for _, l := range cl.limiters {
+		limiters = append(limiters, l)
+	}
+	cl.mu.Unlock()
+
+	depth := make(map[[3]string]int)
+	for _, l := range limiters {
+		l.mu.Lock()
+		if l.group != "" {
+			for p := range l.queues {
+				depth[[3]string{l.group, l.scope, priorityTiers[p]}] += l.queues[p].Len()
+			}
+		}
+		l.mu.Unlock()
+	}
+	for labels, n := range depth {
+		concurrencyQueuedRequests.Set(float64(n), labels[0], labels[1], labels[2])
+	}
+}
+
+func (cl *concurrencyLimits) limiter(scope string, id uint) *concurrencyLimiter {
+	name := scope + ":" + strconv.FormatUint(uint64(id), 10)
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	l, ok := cl.limiters[name]
+	if !ok {
+		l = &concurrencyLimiter{scope: scope}
+		cl.limiters[name] = l
+	}
+	return l
+}
+
+// acquire takes a group or key slot under the group's limits, queueing by the proxy key tier
+// of the request. A zero limit admits every request; the returned release must be called
+// once the request is done.
+func (cl *concurrencyLimits) acquire(c *gin.Context, group *models.Group, scope string, id uint, limit int) (func(), error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	cfg := group.EffectiveConfig
+	timeout := time.Duration(cfg.ConcurrencyQueueTimeoutMs) * time.Millisecond
+	tier := proxyKeyTier(c)
+
+	l := cl.limiter(scope, id)
+	start := time.Now()
+	err := l.acquire(c.Request.Context(), group.Name, tierPriority(tier), limit, cfg.ConcurrencyQueueSize, timeout)
+	if waited := time.Since(start); waited > time.Millisecond {
+		concurrencyQueueWaitSeconds.Add(waited.Seconds(), group.Name, scope, tier)
+	}
+
+	switch {
+	case err == nil:
+		var once sync.Once
+		return func() { once.Do(l.release) }, nil
+	case errors.Is(err, errConcurrencyQueueFull):
+		concurrencyRejectedTotal.Inc(group.Name, scope, tier, "queue_full")
+	case errors.Is(err, errConcurrencyQueueTimeout):
+		concurrencyRejectedTotal.Inc(group.Name, scope, tier, "timeout")
+	case errors.Is(err, errConcurrencyPreempted):
+		concurrencyRejectedTotal.Inc(group.Name, scope, tier, "preempted")
+	}
+	return nil, err
+}
+
+// concurrencyStage holds a slot of the group's concurrency limit for the upstream request.
+func (ps *ProxyServer) concurrencyStage(req *ProxyRequest, next Handler) {
+	group := req.Group
+	release, err := ps.concurrency.acquire(req.Context, group, concurrencyScopeGroup, group.ID, group.EffectiveConfig.GroupMaxConcurrentRequests)
+	if err != nil {
+		writeConcurrencyError(req.Context, group, concurrencyScopeGroup, err)
+		return
+	}
+	defer release()
+	next(req)
+}
+
+// writeConcurrencyError writes the response to a request that did not get a concurrency slot.
+// Clients that went away get no response.
+func writeConcurrencyError(c *gin.Context, group *models.Group, scope string, err error) {
+	if c.Request.Context().Err() != nil {
+		return
+	}
+	retryAfter := max(int(math.Ceil(float64(group.EffectiveConfig.ConcurrencyQueueTimeoutMs)/1000)), 1)
+	c.Header(headerRetryAfter, strconv.Itoa(retryAfter))
+	response.Error(c, app_errors.NewAPIError(app_errors.ErrConcurrencyLimit, fmt.Sprintf("The %s concurrency limit of group '%s' was reached: %v", scope, group.Name, err)))
+}
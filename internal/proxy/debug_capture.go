@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"math/rand"
+	"sync"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	debugCaptureContextKey = "groupDebugCapture"
+	// maxDebugCaptureBodyLength 调试日志中请求体的最大长度
+	maxDebugCaptureBodyLength = 8192
+)
+
+var (
+	debugLogger     *logrus.Logger
+	debugLoggerOnce sync.Once
+)
+
+// groupDebugLogger 返回固定为 debug 级别的 logger，输出目标和格式与全局 logger 一致
+// 首次使用时才创建，此时全局 logger 已完成配置
+func groupDebugLogger() *logrus.Logger {
+	debugLoggerOnce.Do(func() {
+		std := logrus.StandardLogger()
+		debugLogger = &logrus.Logger{
+			Out:       std.Out,
+			Formatter: std.Formatter,
+			Hooks:     std.Hooks,
+			Level:     logrus.DebugLevel,
+		}
+	})
+	return debugLogger
+}
+
+// debugCapture 调试窗口在单个请求中的状态
+type debugCapture struct {
+	logger      *logrus.Entry
+	captureBody bool
+}
+
+// initDebugCapture 检查分组（或其所属聚合分组）是否处于调试窗口中
+// 开启时该请求的日志提升为 debug 级别，并按抽样率决定是否记录请求体
+func (ps *ProxyServer) initDebugCapture(c *gin.Context, originalGroup, group *models.Group) {
+	if ps.groupDebugService == nil {
+		return
+	}
+
+	window := ps.groupDebugService.Active(group.ID)
+	if window == nil && originalGroup.ID != group.ID {
+		window = ps.groupDebugService.Active(originalGroup.ID)
+	}
+	if window == nil {
+		return
+	}
+
+	capture := &debugCapture{
+		logger: groupDebugLogger().WithFields(logrus.Fields{
+			"group_name":    group.Name,
+			"debug_capture": true,
+		}),
+		captureBody: window.SampleRate > 0 && rand.Float64() < window.SampleRate,
+	}
+	c.Set(debugCaptureContextKey, capture)
+}
+
+// getDebugCapture 返回当前请求的调试状态，未开启时返回 nil
+func getDebugCapture(c *gin.Context) *debugCapture {
+	value, exists := c.Get(debugCaptureContextKey)
+	if !exists {
+		return nil
+	}
+	capture, _ := value.(*debugCapture)
+	return capture
+}
+
+// requestLogger 返回当前请求使用的 logger，调试窗口内为 debug 级别
+func requestLogger(c *gin.Context) *logrus.Entry {
+	if capture := getDebugCapture(c); capture != nil {
+		return capture.logger
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// shouldCaptureBody 判断当前请求是否被调试窗口抽中记录请求体
+func shouldCaptureBody(c *gin.Context) bool {
+	capture := getDebugCapture(c)
+	return capture != nil && capture.captureBody
+}
+
+// logCapturedBody 在请求被抽中时输出请求体
+func logCapturedBody(c *gin.Context, stage string, body []byte) {
+	if !shouldCaptureBody(c) {
+		return
+	}
+	requestLogger(c).WithField("stage", stage).Debugf("Captured request body: %s", utils.TruncateString(string(body), maxDebugCaptureBodyLength))
+}
@@ -0,0 +1,593 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+	"gpt-load/internal/pricing"
+	"gpt-load/internal/response"
+	"gpt-load/internal/usage"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Small /embeddings requests to the same group, path and parameters that arrive within the
+// group's embeddings_batch_window_ms are merged into one upstream call. The first request of a
+// batch (the leader) waits for the window or until the batch is full, sends the combined inputs
+// through the rest of the pipeline and splits the response back to every caller by input index.
+// Token usage is apportioned by input size, and each caller's request is logged and charged
+// for its own share of the upstream call.
+
+const embeddingsBatchContextKey = "embeddingsBatch"
+
+var (
+	embeddingsBatchesTotal = metrics.NewCounter(
+		"gpt_load_embeddings_batches_total",
+		"Upstream embeddings calls that merged several client requests, by group.",
+		"group",
+	)
+	embeddingsBatchedRequestsTotal = metrics.NewCounter(
+		"gpt_load_embeddings_batched_requests_total",
+		"Client embeddings requests served by a merged upstream call, by group.",
+		"group",
+	)
+)
+
+// embeddingsBatcher holds the open batches, keyed by group, endpoint and request parameters.
+type embeddingsBatcher struct {
+	mu      sync.Mutex
+	pending map[string]*embeddingsBatch
+}
+
+func newEmbeddingsBatcher() *embeddingsBatcher {
+	return &embeddingsBatcher{pending: make(map[string]*embeddingsBatch)}
+}
+
+// embeddingsBatch collects the inputs of the requests merged into one upstream call.
+type embeddingsBatch struct {
+	key     string
+	inputs  []json.RawMessage
+	members []*batchMember
+	sealed  bool
+	full    chan struct{} // closed when the batch is sealed
+}
+
+// batchMember is one client request in a batch; its inputs are inputs[offset:offset+count].
+type batchMember struct {
+	offset int
+	count  int
+	weight int // input size in bytes, used to apportion token usage
+	result chan batchResult
+}
+
+// batchResult is the response written to one member of a batch. share is set when the
+// upstream answered the call.
+type batchResult struct {
+	status int
+	header http.Header
+	body   []byte
+	share  *batchShare
+}
+
+// batchShare is a member's part of an answered upstream call, for its request log and budgets.
+type batchShare struct {
+	usage        usage.Usage
+	price        pricing.Price
+	priced       bool
+	apiKey       *models.APIKey
+	upstreamAddr string
+}
+
+// embeddingsBatchCall is the upstream call of a batch, stored on the leader's context so the
+// forward stage can split the response before the leader's request is logged.
+type embeddingsBatchCall struct {
+	capture *batchCaptureWriter
+	members []*batchMember
+	total   int
+	results []batchResult
+}
+
+// join adds the inputs of a request to the open batch for key, starting a new batch when
+// there is none or the open one cannot take them. leader reports whether the caller started
+// the batch and must send it.
+func (b *embeddingsBatcher) join(key string, inputs []json.RawMessage, weight, maxInputs int) (batch *embeddingsBatch, member *batchMember, leader bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch = b.pending[key]
+	if batch != nil && len(batch.inputs)+len(inputs) > maxInputs {
+		b.seal(batch)
+		batch = nil
+	}
+	if batch == nil {
+		batch = &embeddingsBatch{key: key, full: make(chan struct{})}
+		b.pending[key] = batch
+		leader = true
+	}
+
+	member = &batchMember{offset: len(batch.inputs), count: len(inputs), weight: weight, result: make(chan batchResult, 1)}
+	batch.inputs = append(batch.inputs, inputs...)
+	batch.members = append(batch.members, member)
+	if len(batch.inputs) >= maxInputs {
+		b.seal(batch)
+	}
+	return batch, member, leader
+}
+
+// close seals the batch so later requests start a new one, and returns its final contents.
+func (b *embeddingsBatcher) close(batch *embeddingsBatch) ([]json.RawMessage, []*batchMember) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seal(batch)
+	return batch.inputs, batch.members
+}
+
+// seal must be called with b.mu held.
+func (b *embeddingsBatcher) seal(batch *embeddingsBatch) {
+	if batch.sealed {
+		return
+	}
+	batch.sealed = true
+	close(batch.full)
+	if b.pending[batch.key] == batch {
+		delete(b.pending, batch.key)
+	}
+}
+
+// embeddingsBatchStage merges concurrent embeddings requests of a group into shared upstream calls.
+// Streaming, signed, spooled and unparsable requests, and requests with more inputs than a batch
+// may hold, are forwarded on their own.
+func (ps *ProxyServer) embeddingsBatchStage(req *ProxyRequest, next Handler) {
+	c := req.Context
+	cfg := req.Group.EffectiveConfig
+	if cfg.EmbeddingsBatchWindowMs <= 0 || req.IsStream || req.Passthrough || c.Request.Method != http.MethodPost || requestBodySpool(c) != nil {
+		next(req)
+		return
+	}
+	if endpoint := findEndpointSchema(c.Request.URL.Path); endpoint == nil || endpoint.class != "embeddings" {
+		next(req)
+		return
+	}
+
+	params, kind, inputs, ok := parseEmbeddingsRequest(req.FinalBody)
+	if !ok || len(inputs) > cfg.EmbeddingsBatchMaxInputs {
+		next(req)
+		return
+	}
+	key, err := embeddingsBatchKey(req, kind, params)
+	if err != nil {
+		next(req)
+		return
+	}
+
+	weight := 0
+	for _, input := range inputs {
+		weight += len(input)
+	}
+	batch, member, leader := ps.embeddings.join(key, inputs, weight, cfg.EmbeddingsBatchMaxInputs)
+	if leader {
+		window := time.Duration(cfg.EmbeddingsBatchWindowMs) * time.Millisecond
+		if done := ps.sendEmbeddingsBatch(req, next, batch, params, window); done {
+			return
+		}
+	}
+
+	select {
+	case result := <-member.result:
+		writeBatchResult(c, req, result)
+		if !leader {
+			ps.logBatchMember(req, result)
+		}
+	case <-c.Request.Context().Done():
+	}
+}
+
+// logBatchMember records the usage, cost and request log of a member whose inputs were sent in
+// another request's upstream call. The leader's share is recorded by the forward stage.
+func (ps *ProxyServer) logBatchMember(req *ProxyRequest, result batchResult) {
+	share := result.share
+	if share == nil {
+		return
+	}
+	c := req.Context
+	setRequestUsage(c, share.usage)
+	if share.priced {
+		setRequestCost(c, share.price.Cost(share.usage))
+	}
+	ps.logRequest(c, req.OriginalGroup, req.Group, share.apiKey, req.StartTime, result.status, nil, false, share.upstreamAddr, req.Channel, req.FinalBody, models.RequestTypeFinal)
+}
+
+// sendEmbeddingsBatch waits for the batch window, then sends the batch as the leader. When no
+// other request joined, the original request is forwarded unchanged and done is true; otherwise
+// every member, the leader included, receives its share of the response on its result channel.
+func (ps *ProxyServer) sendEmbeddingsBatch(req *ProxyRequest, next Handler, batch *embeddingsBatch, params map[string]json.RawMessage, window time.Duration) (done bool) {
+	c := req.Context
+	timer := time.NewTimer(window)
+	select {
+	case <-batch.full:
+	case <-timer.C:
+	case <-c.Request.Context().Done():
+	}
+	timer.Stop()
+
+	inputs, members := ps.embeddings.close(batch)
+	if len(members) == 1 {
+		next(req)
+		return true
+	}
+
+	delivered := false
+	defer func() {
+		if !delivered {
+			deliverBatchResults(members, batchErrorResults(len(members), "embeddings batch was not completed"))
+		}
+	}()
+
+	input, err := json.Marshal(inputs)
+	if err == nil {
+		params["input"] = input
+		req.FinalBody, err = json.Marshal(params)
+	}
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to build batched embeddings request")
+		deliverBatchResults(members, batchErrorResults(len(members), "failed to build batched embeddings request"))
+		delivered = true
+		return false
+	}
+
+	embeddingsBatchesTotal.Inc(req.Group.Name)
+	embeddingsBatchedRequestsTotal.Add(float64(len(members)), req.Group.Name)
+
+	// The other members wait for this call, so it must outlive the leader's client connection
+	capture := newBatchCaptureWriter(c.Writer)
+	c.Writer = capture
+	c.Request = c.Request.WithContext(context.WithoutCancel(c.Request.Context()))
+	defer func() { c.Writer = capture.ResponseWriter }()
+	call := &embeddingsBatchCall{capture: capture, members: members, total: len(inputs)}
+	c.Set(embeddingsBatchContextKey, call)
+
+	next(req)
+
+	// The forward stage splits an answered call; other failures are shared as they are
+	results := call.results
+	if results == nil {
+		results = splitEmbeddingsResponse(capture.Status(), capture.header, capture.body.Bytes(), members, len(inputs))
+	}
+	deliverBatchResults(members, results)
+	delivered = true
+	return false
+}
+
+// shareEmbeddingsBatch splits the response of a batched upstream call and returns the leader's
+// share of its usage. Every member's result carries its own share with the key and address that
+// served the call. Requests that were not batched, and responses that could not be split, keep
+// the usage of the whole call.
+func shareEmbeddingsBatch(c *gin.Context, total usage.Usage, price pricing.Price, priced bool, apiKey *models.APIKey, upstreamAddr string) usage.Usage {
+	value, _ := c.Get(embeddingsBatchContextKey)
+	call, ok := value.(*embeddingsBatchCall)
+	if !ok || call.results != nil {
+		return total
+	}
+
+	status := call.capture.Status()
+	call.results = splitEmbeddingsResponse(status, call.capture.header, call.capture.body.Bytes(), call.members, call.total)
+	if call.results[0].status != status {
+		return total
+	}
+	for i, u := range apportionRequestUsage(total, call.members) {
+		call.results[i].share = &batchShare{usage: u, price: price, priced: priced, apiKey: apiKey, upstreamAddr: upstreamAddr}
+	}
+	return call.results[0].share.usage
+}
+
+// apportionRequestUsage splits the usage of a batched call across its members with the same
+// shares apportionUsage writes into their response bodies.
+func apportionRequestUsage(total usage.Usage, members []*batchMember) []usage.Usage {
+	var fields map[string]json.RawMessage
+	encoded, _ := json.Marshal(total)
+	_ = json.Unmarshal(encoded, &fields)
+
+	shares := make([]usage.Usage, len(members))
+	for i, share := range apportionUsage(fields, members) {
+		encoded, _ = json.Marshal(share)
+		_ = json.Unmarshal(encoded, &shares[i])
+	}
+	return shares
+}
+
+// parseEmbeddingsRequest splits an OpenAI embeddings request into its parameters and inputs.
+// kind is "text" or "tokens": a batch never mixes text inputs with token arrays.
+func parseEmbeddingsRequest(body []byte) (params map[string]json.RawMessage, kind string, inputs []json.RawMessage, ok bool) {
+	if err := json.Unmarshal(body, &params); err != nil || params == nil {
+		return nil, "", nil, false
+	}
+	raw := bytes.TrimSpace(params["input"])
+	if len(raw) == 0 {
+		return nil, "", nil, false
+	}
+
+	switch raw[0] {
+	case '"':
+		return params, "text", []json.RawMessage{raw}, true
+	case '[':
+	default:
+		return nil, "", nil, false
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil || len(items) == 0 {
+		return nil, "", nil, false
+	}
+	// A flat array of numbers is a single token input
+	if first := bytes.TrimSpace(items[0]); len(first) > 0 && first[0] != '"' && first[0] != '[' {
+		return params, "tokens", []json.RawMessage{raw}, true
+	}
+	for _, item := range items {
+		item = bytes.TrimSpace(item)
+		itemKind := "text"
+		if len(item) > 0 && item[0] == '[' {
+			itemKind = "tokens"
+		} else if len(item) == 0 || item[0] != '"' {
+			return nil, "", nil, false
+		}
+		if kind != "" && itemKind != kind {
+			return nil, "", nil, false
+		}
+		kind = itemKind
+	}
+	return params, kind, items, true
+}
+
+// embeddingsBatchKey identifies the requests that can share an upstream call: same group,
+// endpoint, input kind and parameters other than input.
+func embeddingsBatchKey(req *ProxyRequest, kind string, params map[string]json.RawMessage) (string, error) {
+	rest := make(map[string]json.RawMessage, len(params))
+	for k, v := range params {
+		if k != "input" {
+			rest[k] = v
+		}
+	}
+	canonical, err := json.Marshal(rest) // map keys are sorted, values compacted
+	if err != nil {
+		return "", err
+	}
+	u := req.Context.Request.URL
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s", req.Group.Name, u.Path, u.RawQuery, kind, canonical), nil
+}
+
+// splitEmbeddingsResponse turns the response of a batched call into one response per member.
+// Error responses are shared by every member; a successful response whose data does not line up
+// with the inputs is reported to every member as a gateway error.
+func splitEmbeddingsResponse(status int, header http.Header, body []byte, members []*batchMember, total int) []batchResult {
+	header = header.Clone()
+	if encoding := header.Get("Content-Encoding"); encoding != "" {
+		decoded, err := utils.DecompressResponse(encoding, body)
+		if err != nil {
+			logrus.WithError(err).WithField("content_encoding", encoding).Warn("Failed to decode batched embeddings response")
+			return batchErrorResults(len(members), "failed to decode batched embeddings response")
+		}
+		body = decoded
+		header.Del("Content-Encoding")
+	}
+	header.Del("Content-Length")
+
+	results := make([]batchResult, len(members))
+	if status < 200 || status >= 300 {
+		for i := range results {
+			results[i] = batchResult{status: status, header: header, body: body}
+		}
+		return results
+	}
+
+	parts, err := splitEmbeddingsBody(body, members, total)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to split batched embeddings response")
+		return batchErrorResults(len(members), "failed to split batched embeddings response")
+	}
+	for i := range results {
+		results[i] = batchResult{status: status, header: header, body: parts[i]}
+	}
+	return results
+}
+
+// splitEmbeddingsBody assigns each data item to the member that sent its input, renumbering
+// indexes from 0 per member, and apportions the numeric usage counters by member weight.
+func splitEmbeddingsBody(body []byte, members []*batchMember, total int) ([][]byte, error) {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	var data []map[string]json.RawMessage
+	if err := json.Unmarshal(resp["data"], &data); err != nil {
+		return nil, err
+	}
+	if len(data) != total {
+		return nil, fmt.Errorf("got %d embeddings for %d inputs", len(data), total)
+	}
+
+	byIndex := make([]map[string]json.RawMessage, total)
+	for _, item := range data {
+		var index int
+		if err := json.Unmarshal(item["index"], &index); err != nil {
+			return nil, fmt.Errorf("invalid embedding index: %w", err)
+		}
+		if index < 0 || index >= total || byIndex[index] != nil {
+			return nil, fmt.Errorf("unexpected embedding index %d", index)
+		}
+		byIndex[index] = item
+	}
+
+	var usage map[string]json.RawMessage
+	if raw, ok := resp["usage"]; ok {
+		if err := json.Unmarshal(raw, &usage); err != nil {
+			return nil, err
+		}
+	}
+	usageShares := apportionUsage(usage, members)
+
+	parts := make([][]byte, len(members))
+	for i, m := range members {
+		items := make([]map[string]json.RawMessage, m.count)
+		for j := range items {
+			item := make(map[string]json.RawMessage, len(byIndex[m.offset+j]))
+			for k, v := range byIndex[m.offset+j] {
+				item[k] = v
+			}
+			item["index"] = json.RawMessage(strconv.Itoa(j))
+			items[j] = item
+		}
+
+		part := make(map[string]json.RawMessage, len(resp))
+		for k, v := range resp {
+			part[k] = v
+		}
+		encoded, err := json.Marshal(items)
+		if err != nil {
+			return nil, err
+		}
+		part["data"] = encoded
+		if usageShares != nil {
+			if part["usage"], err = json.Marshal(usageShares[i]); err != nil {
+				return nil, err
+			}
+		}
+		if parts[i], err = json.Marshal(part); err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// apportionUsage splits each integer usage counter across members in proportion to their
+// weight; the last member receives the rounding remainder so the shares add up to the total.
+// Non-integer fields are copied to every member.
+func apportionUsage(usage map[string]json.RawMessage, members []*batchMember) []map[string]json.RawMessage {
+	if usage == nil {
+		return nil
+	}
+	totalWeight := 0
+	for _, m := range members {
+		totalWeight += m.weight
+	}
+
+	shares := make([]map[string]json.RawMessage, len(members))
+	for i := range shares {
+		shares[i] = make(map[string]json.RawMessage, len(usage))
+	}
+	keys := make([]string, 0, len(usage))
+	for k := range usage {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		value, err := strconv.ParseInt(string(usage[k]), 10, 64)
+		if err != nil || totalWeight == 0 {
+			for i := range shares {
+				shares[i][k] = usage[k]
+			}
+			continue
+		}
+		remaining := value
+		for i, m := range members {
+			share := remaining
+			if i < len(members)-1 {
+				share = value * int64(m.weight) / int64(totalWeight)
+				remaining -= share
+			}
+			shares[i][k] = json.RawMessage(strconv.FormatInt(share, 10))
+		}
+	}
+	return shares
+}
+
+// batchErrorResults builds a gateway error response for each member of a failed batch.
+func batchErrorResults(n int, message string) []batchResult {
+	body, _ := json.Marshal(response.ErrorResponse{Code: app_errors.ErrBadGateway.Code, Message: message})
+	header := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+	results := make([]batchResult, n)
+	for i := range results {
+		results[i] = batchResult{status: app_errors.ErrBadGateway.HTTPStatus, header: header, body: body}
+	}
+	return results
+}
+
+func deliverBatchResults(members []*batchMember, results []batchResult) {
+	for i, m := range members {
+		m.result <- results[i]
+	}
+}
+
+// writeBatchResult writes a member's share of a batched response to its client.
+func writeBatchResult(c *gin.Context, req *ProxyRequest, result batchResult) {
+	header := c.Writer.Header()
+	for k, v := range result.header {
+		header[k] = append([]string(nil), v...)
+	}
+	c.Status(result.status)
+	writeRewrittenBody(c, req.Group, result.body)
+}
+
+// batchCaptureWriter buffers the response of a batched upstream call so it can be split
+// before anything is sent to the clients.
+type batchCaptureWriter struct {
+	gin.ResponseWriter
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+	written bool
+}
+
+func newBatchCaptureWriter(w gin.ResponseWriter) *batchCaptureWriter {
+	return &batchCaptureWriter{ResponseWriter: w, header: make(http.Header)}
+}
+
+func (w *batchCaptureWriter) Header() http.Header { return w.header }
+
+func (w *batchCaptureWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *batchCaptureWriter) WriteHeaderNow() { w.written = true }
+
+func (w *batchCaptureWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *batchCaptureWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *batchCaptureWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *batchCaptureWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *batchCaptureWriter) Written() bool { return w.written }
+
+func (w *batchCaptureWriter) Flush() {}
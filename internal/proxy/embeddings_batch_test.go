@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/pricing"
+	"gpt-load/internal/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestShareEmbeddingsBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	members := []*batchMember{{offset: 0, count: 1, weight: 10}, {offset: 1, count: 2, weight: 30}}
+	capture := newBatchCaptureWriter(c.Writer)
+	capture.header.Set("Content-Type", "application/json")
+	capture.WriteHeader(http.StatusOK)
+	capture.WriteString(`{"object":"list","data":[` +
+		`{"object":"embedding","index":0,"embedding":[0.1]},` +
+		`{"object":"embedding","index":1,"embedding":[0.2]},` +
+		`{"object":"embedding","index":2,"embedding":[0.3]}],` +
+		`"usage":{"prompt_tokens":41,"total_tokens":41}}`)
+	call := &embeddingsBatchCall{capture: capture, members: members, total: 3}
+	c.Set(embeddingsBatchContextKey, call)
+
+	apiKey := &models.APIKey{ID: 7}
+	total := usage.Usage{PromptTokens: 41, TotalTokens: 41}
+	leader := shareEmbeddingsBatch(c, total, pricing.Price{}, false, apiKey, "https://upstream")
+
+	if leader != (usage.Usage{PromptTokens: 10, TotalTokens: 10}) {
+		t.Fatalf("leader usage = %+v, want its own share", leader)
+	}
+	var sum int64
+	for i, result := range call.results {
+		if result.share == nil {
+			t.Fatalf("member %d has no share", i)
+		}
+		if result.share.apiKey != apiKey || result.share.upstreamAddr != "https://upstream" {
+			t.Errorf("member %d share = %+v, want the key and address of the call", i, result.share)
+		}
+		if body, ok := usage.FromBody(result.body); !ok || body.TotalTokens != result.share.usage.TotalTokens {
+			t.Errorf("member %d body usage = %+v, want %+v", i, body, result.share.usage)
+		}
+		sum += result.share.usage.TotalTokens
+	}
+	if sum != total.TotalTokens {
+		t.Errorf("shares add up to %d tokens, want %d", sum, total.TotalTokens)
+	}
+
+	// The call is split once; later attempts keep their usage
+	if again := shareEmbeddingsBatch(c, total, pricing.Price{}, false, apiKey, ""); again != total {
+		t.Errorf("second split returned %+v, want %+v", again, total)
+	}
+}
+
+func TestShareEmbeddingsBatchUnbatched(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	total := usage.Usage{PromptTokens: 5, TotalTokens: 5}
+	if got := shareEmbeddingsBatch(c, total, pricing.Price{}, false, nil, ""); got != total {
+		t.Errorf("unbatched usage = %+v, want %+v", got, total)
+	}
+}
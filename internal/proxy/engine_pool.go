@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"gpt-load/internal/config"
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ruleProcessorPoolGetsTotal = metrics.NewCounter(
+		"gpt_load_rule_processor_pool_gets_total",
+		"JSON rule processors taken from the pool, by result (hit reuses an idle processor, miss allocates one).",
+		"result",
+	)
+	ruleProcessorPoolDropsTotal = metrics.NewCounter(
+		"gpt_load_rule_processor_pool_drops_total",
+		"JSON rule processors discarded on release because the pool was full or its settings changed.",
+	)
+	ruleProcessorPoolProcessors = metrics.NewGauge(
+		"gpt_load_rule_processor_pool_processors",
+		"JSON rule processors by state (in_use, idle). Idle is only reported for a bounded pool.",
+		"state",
+	)
+	ruleProcessorPositionsCap = metrics.NewGauge(
+		"gpt_load_rule_processor_positions_cap",
+		"Maximum scan positions buffer capacity of a JSON rule processor, in entries of 4 bytes.",
+	)
+)
+
+func init() {
+	metrics.OnCollect(collectRuleProcessorPoolMetrics)
+}
+
+// collectRuleProcessorPoolMetrics copies the processor pool statistics into the metrics before a scrape.
+func collectRuleProcessorPoolMetrics() {
+	stats := jsonengine.ProcessorPoolStatistics()
+	ruleProcessorPoolGetsTotal.Set(float64(stats.Hits), "hit")
+	ruleProcessorPoolGetsTotal.Set(float64(stats.Misses), "miss")
+	ruleProcessorPoolDropsTotal.Set(float64(stats.Drops))
+	ruleProcessorPoolProcessors.Set(float64(stats.InUse), "in_use")
+	if stats.Idle >= 0 {
+		ruleProcessorPoolProcessors.Set(float64(stats.Idle), "idle")
+	}
+	ruleProcessorPositionsCap.Set(float64(stats.PositionsCap))
+}
+
+// Start applies the rule processor pool and metric label settings and follows later changes,
+// and removes request body spool files left by a previous run. Call it once system settings are loaded.
+func (ps *ProxyServer) Start() {
+	removeStaleBodySpools(ps.settingsManager.GetSettings().RequestBodySpoolDir)
+	ps.configureMetricLabels()
+	ps.configureProcessorPool()
+	for _, key := range []string{"rule_engine_positions_cap", "rule_engine_pool_max_idle"} {
+		if err := config.OnSettingChange(ps.settingsManager, key, func(_, _ int) { ps.configureProcessorPool() }); err != nil {
+			logrus.WithError(err).Warn("Failed to register rule processor pool hook")
+		}
+	}
+}
+
+func (ps *ProxyServer) configureProcessorPool() {
+	settings := ps.settingsManager.GetSettings()
+	jsonengine.ConfigureProcessorPool(jsonengine.ProcessorPoolConfig{
+		PositionsCap: settings.RuleEnginePositionsCap,
+		MaxIdle:      settings.RuleEnginePoolMaxIdle,
+	})
+}
@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// abortedFinishReason is the pseudo upstream reason mapped to the reason of a synthesized terminal chunk.
+const abortedFinishReason = "aborted"
+
+// defaultFinishReasons maps OpenAI, Anthropic and Gemini finish reasons to the OpenAI vocabulary.
+var defaultFinishReasons = map[string]string{
+	"stop":           "stop",
+	"length":         "length",
+	"tool_calls":     "tool_calls",
+	"content_filter": "content_filter",
+	"function_call":  "tool_calls",
+
+	"end_turn":      "stop",
+	"stop_sequence": "stop",
+	"pause_turn":    "stop",
+	"max_tokens":    "length",
+	"tool_use":      "tool_calls",
+	"refusal":       "content_filter",
+
+	"STOP":                      "stop",
+	"MAX_TOKENS":                "length",
+	"SAFETY":                    "content_filter",
+	"RECITATION":                "content_filter",
+	"BLOCKLIST":                 "content_filter",
+	"PROHIBITED_CONTENT":        "content_filter",
+	"SPII":                      "content_filter",
+	"OTHER":                     "stop",
+	"FINISH_REASON_UNSPECIFIED": "stop",
+
+	abortedFinishReason: "stop",
+}
+
+// finishReasonPaths locate the finish reason in OpenAI, Gemini and Anthropic bodies and stream events.
+var finishReasonPaths = []string{
+	"choices[*].finish_reason",
+	"candidates[*].finishReason",
+	"stop_reason",
+	"delta.stop_reason",
+}
+
+var streamTerminalsSynthesizedTotal = metrics.NewCounter(
+	"gpt_load_stream_terminals_synthesized_total",
+	"Streams that ended without a terminal chunk and received a synthesized one, by group.",
+	"group",
+)
+
+// finishReasonTable returns the built-in table extended by the group's finish_reason_map entries.
+// Entries are comma-separated upstream=unified pairs; upstream= removes a built-in entry.
+func finishReasonTable(group *models.Group) map[string]string {
+	table := make(map[string]string, len(defaultFinishReasons))
+	for upstream, unified := range defaultFinishReasons {
+		table[upstream] = unified
+	}
+	for _, entry := range strings.Split(group.EffectiveConfig.FinishReasonMap, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		upstream, unified, ok := strings.Cut(entry, "=")
+		upstream = strings.TrimSpace(upstream)
+		if !ok || upstream == "" {
+			logrus.WithField("group_name", group.Name).Warnf("Ignoring invalid finish reason entry %q, expected upstream=unified", entry)
+			continue
+		}
+		if unified = strings.TrimSpace(unified); unified == "" {
+			delete(table, upstream)
+		} else {
+			table[upstream] = unified
+		}
+	}
+	return table
+}
+
+// buildFinishReasonRules returns outbound transform rules rewriting finish reasons into the unified
+// vocabulary when the group enables normalize_finish_reason. Unknown reasons are left unchanged.
+func buildFinishReasonRules(group *models.Group) []jsonengine.PathRule {
+	if !group.EffectiveConfig.NormalizeFinishReason {
+		return nil
+	}
+	table := finishReasonTable(group)
+	transform := func(reason string) string {
+		if unified, ok := table[reason]; ok {
+			return unified
+		}
+		return reason
+	}
+
+	rules := make([]jsonengine.PathRule, 0, len(finishReasonPaths))
+	for _, path := range finishReasonPaths {
+		rules = append(rules, jsonengine.PathRule{Path: path, Action: jsonengine.ActionTransform, Value: transform})
+	}
+	return rules
+}
+
+var (
+	finishReasonPattern = regexp.MustCompile(`"(?:finish_reason|finishReason|stop_reason)"\s*:\s*"`)
+	messageStopPattern  = regexp.MustCompile(`"type"\s*:\s*"message_stop"`)
+)
+
+// finishTracker watches the events of a rewritten event stream for the terminal chunk, so one can be
+// synthesized when the upstream ends without it. ProcessSSE writes one event per Write call.
+type finishTracker struct {
+	w           io.Writer
+	terminal    bool // an event carried a finish reason
+	done        bool // OpenAI [DONE] marker
+	messageStop bool // Anthropic message_stop event
+}
+
+func newFinishTracker(w io.Writer) *finishTracker {
+	return &finishTracker{w: w}
+}
+
+func (t *finishTracker) Write(event []byte) (int, error) {
+	if !t.terminal && bytes.Contains(event, []byte("reason")) && finishReasonPattern.Match(event) {
+		t.terminal = true
+	}
+	if !t.done && bytes.Contains(event, []byte("data: [DONE]")) {
+		t.done = true
+	}
+	if !t.messageStop && bytes.Contains(event, []byte("message_stop")) && messageStopPattern.Match(event) {
+		t.messageStop = true
+	}
+	return t.w.Write(event)
+}
+
+// finish writes the terminal events the stream is missing, in the client's protocol.
+// Nothing is written once the client has gone away.
+func (t *finishTracker) finish(c *gin.Context, group *models.Group, upstreamModel string) {
+	if c.Request.Context().Err() != nil {
+		return
+	}
+	reason := finishReasonTable(group)[abortedFinishReason]
+	if reason == "" {
+		reason = "stop"
+	}
+
+	var events []string
+	switch finishReasonProtocol(c, group) {
+	case "anthropic":
+		if t.messageStop {
+			break
+		}
+		if !t.terminal {
+			events = append(events, sseEvent("message_delta", map[string]any{
+				"type":  "message_delta",
+				"delta": map[string]any{"stop_reason": reason, "stop_sequence": nil},
+				"usage": map[string]any{"output_tokens": 0},
+			}))
+		}
+		events = append(events, sseEvent("message_stop", map[string]any{"type": "message_stop"}))
+	case "gemini":
+		if !t.terminal {
+			events = append(events, sseEvent("", map[string]any{
+				"candidates": []any{map[string]any{
+					"content":      map[string]any{"role": "model", "parts": []any{}},
+					"finishReason": reason,
+					"index":        0,
+				}},
+			}))
+		}
+	default:
+		if !t.terminal {
+			events = append(events, sseEvent("", map[string]any{
+				"id":      "",
+				"object":  "chat.completion.chunk",
+				"created": time.Now().Unix(),
+				"model":   upstreamModel,
+				"choices": []any{map[string]any{"index": 0, "delta": map[string]any{}, "finish_reason": reason}},
+			}))
+		}
+		if !t.done {
+			events = append(events, "data: [DONE]\n\n")
+		}
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	if !t.terminal {
+		streamTerminalsSynthesizedTotal.Inc(group.Name)
+		requestLogger(c).WithField("group_name", group.Name).Warn("Upstream stream ended without a terminal chunk, synthesized one")
+	}
+	for _, event := range events {
+		if _, err := io.WriteString(t.w, event); err != nil {
+			logUpstreamError("writing synthesized terminal chunk", err)
+			return
+		}
+	}
+	if flusher, ok := t.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finishReasonProtocol returns the stream format the client expects.
+func finishReasonProtocol(c *gin.Context, group *models.Group) string {
+	switch group.ChannelType {
+	case "anthropic":
+		return "anthropic"
+	case "gemini":
+		if !strings.Contains(c.Request.URL.Path, "v1beta/openai") {
+			return "gemini"
+		}
+	}
+	return "openai"
+}
+
+// sseEvent formats one server-sent event with an optional event name.
+func sseEvent(name string, data any) string {
+	payload, _ := json.Marshal(data)
+	if name == "" {
+		return "data: " + string(payload) + "\n\n"
+	}
+	return "event: " + name + "\ndata: " + string(payload) + "\n\n"
+}
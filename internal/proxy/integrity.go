@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"hash"
+	"hash/fnv"
+	"io"
+	"math/rand"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	integrityChecksTotal = metrics.NewCounter(
+		"gpt_load_integrity_checks_total",
+		"Sampled outbound transformations, by result (modified, noop, diverged).",
+		"result",
+	)
+	integrityBytesTotal = metrics.NewCounter(
+		"gpt_load_integrity_bytes_total",
+		"Bytes hashed by the integrity sampler, by direction (upstream, transformed).",
+		"direction",
+	)
+)
+
+// integrityCheck hashes the upstream and transformed bytes of a sampled response.
+// When no rule fired the engine must reproduce its input byte for byte, so any
+// difference points at a jsonengine regression rather than a configured change.
+type integrityCheck struct {
+	upstream    hash.Hash64
+	transformed hash.Hash64
+	inBytes     int64
+	outBytes    int64
+}
+
+// newIntegrityCheck returns a check for a sampled request, or nil when the request is not sampled.
+func newIntegrityCheck(group *models.Group) *integrityCheck {
+	percent := group.EffectiveConfig.IntegritySamplePercent
+	if percent <= 0 || (percent < 100 && rand.Intn(100) >= percent) {
+		return nil
+	}
+	return &integrityCheck{upstream: fnv.New64a(), transformed: fnv.New64a()}
+}
+
+// wrap tees the engine input and output through the rolling hashes.
+func (ic *integrityCheck) wrap(input io.Reader, output io.Writer) (io.Reader, io.Writer) {
+	return io.TeeReader(input, countingHash{ic.upstream, &ic.inBytes}),
+		io.MultiWriter(output, countingHash{ic.transformed, &ic.outBytes})
+}
+
+// finish records the outcome of the sampled transformation.
+func (ic *integrityCheck) finish(group *models.Group, result jsonengine.ProcessResult) {
+	integrityBytesTotal.Add(float64(ic.inBytes), "upstream")
+	integrityBytesTotal.Add(float64(ic.outBytes), "transformed")
+
+	if result.Applied > 0 {
+		integrityChecksTotal.Inc("modified")
+		return
+	}
+	if ic.inBytes == ic.outBytes && ic.upstream.Sum64() == ic.transformed.Sum64() {
+		integrityChecksTotal.Inc("noop")
+		return
+	}
+
+	integrityChecksTotal.Inc("diverged")
+	logrus.WithFields(logrus.Fields{
+		"group":             group.Name,
+		"upstream_bytes":    ic.inBytes,
+		"transformed_bytes": ic.outBytes,
+		"upstream_hash":     ic.upstream.Sum64(),
+		"transformed_hash":  ic.transformed.Sum64(),
+	}).Error("jsonengine output diverged from upstream although no rule was applied")
+}
+
+// countingHash feeds writes into a hash and counts the bytes.
+type countingHash struct {
+	hash.Hash64
+	n *int64
+}
+
+func (h countingHash) Write(p []byte) (int, error) {
+	*h.n += int64(len(p))
+	return h.Hash64.Write(p)
+}
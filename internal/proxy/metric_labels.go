@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"gpt-load/internal/config"
+	"gpt-load/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+// metricLabelSettings maps the settings that bound metric label cardinality to the labels they apply to.
+var metricLabelSettings = map[string]string{
+	"metrics_model_labels": "model",
+	"metrics_path_labels":  "path",
+}
+
+// configureMetricLabels applies the label collapsing rules and follows later changes.
+func (ps *ProxyServer) configureMetricLabels() {
+	settings := ps.settingsManager.GetSettings()
+	current := map[string]string{
+		"metrics_model_labels": settings.MetricsModelLabels,
+		"metrics_path_labels":  settings.MetricsPathLabels,
+	}
+	for key, label := range metricLabelSettings {
+		applyMetricLabelRules(label, current[key])
+		if err := config.OnSettingChange(ps.settingsManager, key, func(_, spec string) { applyMetricLabelRules(label, spec) }); err != nil {
+			logrus.WithError(err).Warn("Failed to register metric label hook")
+		}
+	}
+}
+
+// applyMetricLabelRules installs the rules for one label. Invalid rules are rejected when the
+// settings are saved, so a failure here only comes from values stored by an older version.
+func applyMetricLabelRules(label, spec string) {
+	mapper, err := metrics.ParseLabelRules(spec)
+	if err != nil {
+		logrus.WithError(err).WithField("label", label).Warn("Invalid metric label rules, keeping label values unchanged")
+		mapper = nil
+	}
+	metrics.SetLabelMapper(label, mapper)
+}
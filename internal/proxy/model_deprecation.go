@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+var modelDeprecationRedirectsTotal = metrics.NewCounter(
+	"gpt_load_model_deprecation_redirects_total",
+	"Requests for deprecated models redirected to their successors, by group, model and successor.",
+	"group", "model", "successor",
+)
+
+// applyModelDeprecation redirects a deprecated model to its successor when the group enables
+// model_deprecation_remap, and tells the client through a Warning header.
+// Models covered by the group's own redirect rules are left to those rules. Gemini native
+// requests carry the model in the path, so the request URL is rewritten in place.
+func (ps *ProxyServer) applyModelDeprecation(c *gin.Context, bodyBytes []byte, group *models.Group) []byte {
+	cfg := group.EffectiveConfig
+	if !cfg.ModelDeprecationRemap {
+		return bodyBytes
+	}
+
+	var requestData map[string]any
+	model := ""
+	if len(bodyBytes) > 0 && json.Unmarshal(bodyBytes, &requestData) == nil {
+		model, _ = requestData["model"].(string)
+	}
+
+	pathIndex := -1
+	var parts []string
+	if model == "" {
+		parts = strings.Split(c.Request.URL.Path, "/")
+		for i, part := range parts {
+			if part == "models" && i+1 < len(parts) {
+				pathIndex = i + 1
+				model = strings.Split(parts[pathIndex], ":")[0]
+				break
+			}
+		}
+	}
+	if model == "" {
+		return bodyBytes
+	}
+	if _, redirected := group.ModelRedirectMap[model]; redirected {
+		return bodyBytes
+	}
+
+	successor, deprecated := channel.DeprecatedModelSuccessor(group.ChannelType, model, cfg.ModelDeprecations)
+	if !deprecated {
+		return bodyBytes
+	}
+
+	if pathIndex >= 0 {
+		parts[pathIndex] = successor + strings.TrimPrefix(parts[pathIndex], model)
+		c.Request.URL.Path = strings.Join(parts, "/")
+		c.Request.URL.RawPath = ""
+	} else {
+		requestData["model"] = successor
+		rewritten, err := json.Marshal(requestData)
+		if err != nil {
+			logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to rewrite deprecated model, passing through")
+			return bodyBytes
+		}
+		bodyBytes = rewritten
+	}
+
+	c.Header("Warning", fmt.Sprintf(`299 gpt-load "Model '%s' is deprecated, redirected to '%s'"`, model, successor))
+	modelDeprecationRedirectsTotal.Inc(group.Name, model, successor)
+	requestLogger(c).WithFields(logrus.Fields{
+		"group_name": group.Name,
+		"model":      model,
+		"successor":  successor,
+	}).Debug("Deprecated model redirected")
+
+	return bodyBytes
+}
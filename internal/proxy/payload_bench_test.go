@@ -0,0 +1,192 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// payloadCases are upstream responses captured in testdata, served through the response
+// handlers with the group's outbound rules, the built-in usage captures and the rule match
+// counter, as the proxy does for a live request.
+// maxAllocs caps the allocations of one response. TestPayloadAllocations fails when the
+// response path allocates more; raise a cap only after confirming the increase is expected.
+var payloadCases = []struct {
+	name        string
+	file        string
+	contentType string
+	stream      bool
+	rules       []jsonengine.PathRule
+	maxAllocs   float64
+}{
+	{
+		name:        "openai stream",
+		file:        "openai_stream.sse",
+		contentType: "text/event-stream",
+		stream:      true,
+		rules: []jsonengine.PathRule{
+			{Path: "system_fingerprint", Action: jsonengine.ActionRemove},
+			{Path: "choices[*].delta.refusal", Action: jsonengine.ActionRemove},
+			{Path: "choices[*].logprobs", Action: jsonengine.ActionRemove},
+		},
+		maxAllocs: 8800,
+	},
+	{
+		name:        "gemini image",
+		file:        "gemini_image.json",
+		contentType: "application/json; charset=UTF-8",
+		rules: []jsonengine.PathRule{
+			{Path: "candidates[*].content.parts[*].thoughtSignature", Action: jsonengine.ActionRemove},
+			{Path: "candidates[*].safetyRatings", Action: jsonengine.ActionRemove},
+			{Path: "responseId", Action: jsonengine.ActionRename, Value: "id"},
+		},
+		maxAllocs: 200,
+	},
+	{
+		name:        "anthropic tool use stream",
+		file:        "anthropic_tool_use.sse",
+		contentType: "text/event-stream",
+		stream:      true,
+		rules: []jsonengine.PathRule{
+			{Path: "message.usage.cache_creation_input_tokens", Action: jsonengine.ActionRemove},
+			{Path: "content_block.input", Action: jsonengine.ActionAdd, Value: map[string]any{}},
+			{Path: "delta.stop_sequence", Action: jsonengine.ActionRemove},
+		},
+		maxAllocs: 1200,
+	},
+}
+
+// chunkedReader returns at most n bytes per read, as an upstream body arriving over the network.
+type chunkedReader struct {
+	data []byte
+	n    int
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p[:min(len(p), r.n)], r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// discardResponseWriter is a flushable client connection that counts and drops the body.
+type discardResponseWriter struct {
+	header http.Header
+	n      int
+}
+
+func (w *discardResponseWriter) Header() http.Header { return w.header }
+func (w *discardResponseWriter) WriteHeader(int)     {}
+func (w *discardResponseWriter) Flush()              {}
+
+func (w *discardResponseWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
+
+// payloadServer serves testdata responses through the response handlers of a proxy server
+// whose groups compile their engines with buildRuleEngine, as in NewProxyServer.
+type payloadServer struct {
+	ps      *ProxyServer
+	request *http.Request
+	writer  *discardResponseWriter
+}
+
+func newPayloadServer() *payloadServer {
+	gin.SetMode(gin.TestMode)
+	return &payloadServer{
+		ps:      &ProxyServer{ruleMatches: services.NewRuleMatchTracker()},
+		request: httptest.NewRequest(http.MethodPost, "/proxy/bench/v1/chat/completions", nil),
+		writer:  &discardResponseWriter{header: make(http.Header)},
+	}
+}
+
+func (s *payloadServer) newGroup(rules []jsonengine.PathRule) *models.Group {
+	group := &models.Group{Name: "bench", OutboundRuleList: rules}
+	group.SetRuleEngineBuilder(s.ps.buildRuleEngine)
+	return group
+}
+
+// serve handles one upstream response and returns the request context and the bytes sent.
+func (s *payloadServer) serve(group *models.Group, contentType string, stream bool, data []byte) (*gin.Context, int) {
+	clear(s.writer.header)
+	s.writer.n = 0
+	c, _ := gin.CreateTestContext(s.writer)
+	c.Request = s.request
+
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(&chunkedReader{data: data, n: 4096}),
+		ContentLength: -1,
+	}
+	c.Header("Content-Type", contentType)
+	c.Status(resp.StatusCode)
+	if stream {
+		s.ps.handleStreamingResponse(c, resp, group, "")
+	} else {
+		s.ps.handleNormalResponse(c, resp, group, "")
+	}
+	return c, s.writer.n
+}
+
+func readPayload(tb testing.TB, file string) []byte {
+	tb.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+func TestPayloadAllocations(t *testing.T) {
+	server := newPayloadServer()
+	for _, tc := range payloadCases {
+		data := readPayload(t, tc.file)
+		group := server.newGroup(tc.rules)
+
+		c, n := server.serve(group, tc.contentType, tc.stream, data)
+		if usage := getUpstreamUsage(c); usage == nil || usage.TotalTokens == 0 {
+			t.Fatalf("%s: no usage captured from the response", tc.name)
+		}
+		if n == 0 || n >= len(data) {
+			t.Fatalf("%s: sent %d bytes from %d, want the rewritten body", tc.name, n, len(data))
+		}
+
+		allocs := testing.AllocsPerRun(20, func() {
+			server.serve(group, tc.contentType, tc.stream, data)
+		})
+		t.Logf("%s: %.0f allocs", tc.name, allocs)
+		if allocs > tc.maxAllocs {
+			t.Errorf("%s: %.0f allocations per response, want at most %.0f", tc.name, allocs, tc.maxAllocs)
+		}
+	}
+}
+
+// BenchmarkPayloads measures captured upstream responses served through the response handlers,
+// run with go test -bench Payloads ./internal/proxy
+func BenchmarkPayloads(b *testing.B) {
+	server := newPayloadServer()
+	for _, tc := range payloadCases {
+		data := readPayload(b, tc.file)
+		group := server.newGroup(tc.rules)
+		b.Run(tc.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				server.serve(group, tc.contentType, tc.stream, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Names of the built-in proxy pipeline stages, in execution order.
+// Proxy key authentication runs as gin middleware before the pipeline starts.
+const (
+	StageAdmission       = "admission"        // per-tier concurrency admission
+	StageRoute           = "route"            // group and sub-group resolution, channel selection
+	StageTransform       = "transform"        // body read, prompt templates, validation and rewrites
+	StageBudget          = "budget"           // per-group and per-proxy-key usage budgets
+	StageStreamCap       = "stream_cap"       // per-group concurrent stream cap
+	StageCapture         = "capture"          // debug capture of inbound and transformed bodies
+	StageResponseCache   = "response_cache"   // cached responses to deterministic requests
+	StageEmbeddingsBatch = "embeddings_batch" // merging of concurrent embeddings requests
+	StageConcurrency     = "concurrency"      // per-group concurrency limit and queue
+)
+
+// ProxyRequest carries the state of one proxy request through the pipeline.
+// Fields are filled in by the stage that owns them: Group and Channel are set after
+// StageRoute, Body and FinalBody after StageTransform.
+type ProxyRequest struct {
+	Context       *gin.Context
+	StartTime     time.Time
+	GroupName     string
+	RequestPath   string
+	OriginalGroup *models.Group // the group named in the URL, possibly an aggregate
+	Group         *models.Group // the group that serves the request
+	Channel       channel.ChannelProxy
+	Body          []byte // client body after prompt templates
+	FinalBody     []byte // body sent upstream
+	IsStream      bool
+	Passthrough   bool // signed request, the body must not be rewritten
+}
+
+// Handler processes a proxy request.
+type Handler func(req *ProxyRequest)
+
+// Middleware is one stage of the proxy pipeline.
+// Handle calls next to continue the chain; a stage that rejects the request
+// writes the response itself and returns without calling next.
+type Middleware interface {
+	Name() string
+	Handle(req *ProxyRequest, next Handler)
+}
+
+// MiddlewareFunc adapts a function to the Middleware interface.
+func MiddlewareFunc(name string, fn func(req *ProxyRequest, next Handler)) Middleware {
+	return middlewareFunc{name: name, fn: fn}
+}
+
+type middlewareFunc struct {
+	name string
+	fn   func(req *ProxyRequest, next Handler)
+}
+
+func (m middlewareFunc) Name() string { return m.name }
+
+func (m middlewareFunc) Handle(req *ProxyRequest, next Handler) { m.fn(req, next) }
+
+type registeredMiddleware struct {
+	after      string
+	middleware Middleware
+}
+
+var (
+	middlewareMu     sync.Mutex
+	customMiddleware []registeredMiddleware
+)
+
+// RegisterMiddleware inserts a custom stage right after the named stage, or at the front
+// of the pipeline when after is empty. after may name a built-in stage or a previously
+// registered custom one. Call it from an init function: the pipeline is assembled once
+// when the proxy server is created.
+func RegisterMiddleware(after string, m Middleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	customMiddleware = append(customMiddleware, registeredMiddleware{after: after, middleware: m})
+}
+
+// builtinMiddleware returns the built-in stages in execution order.
+func (ps *ProxyServer) builtinMiddleware() []Middleware {
+	return []Middleware{
+		MiddlewareFunc(StageAdmission, ps.admissionStage),
+		MiddlewareFunc(StageRoute, ps.routeStage),
+		MiddlewareFunc(StageTransform, ps.transformStage),
+		MiddlewareFunc(StageBudget, ps.budgetStage),
+		MiddlewareFunc(StageStreamCap, ps.streamCapStage),
+		MiddlewareFunc(StageCapture, ps.captureStage),
+		MiddlewareFunc(StageResponseCache, ps.responseCacheStage),
+		MiddlewareFunc(StageEmbeddingsBatch, ps.embeddingsBatchStage),
+		MiddlewareFunc(StageConcurrency, ps.concurrencyStage),
+	}
+}
+
+// buildPipeline merges the registered stages into the built-in ones and chains them
+// in front of the upstream request with retries.
+func (ps *ProxyServer) buildPipeline() (Handler, error) {
+	stages := ps.builtinMiddleware()
+
+	middlewareMu.Lock()
+	custom := append([]registeredMiddleware(nil), customMiddleware...)
+	middlewareMu.Unlock()
+
+	for _, r := range custom {
+		pos := 0
+		if r.after != "" {
+			pos = -1
+			for i, m := range stages {
+				if m.Name() == r.after {
+					pos = i + 1
+					break
+				}
+			}
+			if pos < 0 {
+				return nil, fmt.Errorf("proxy middleware %q: unknown stage %q", r.middleware.Name(), r.after)
+			}
+		}
+		stages = append(stages[:pos], append([]Middleware{r.middleware}, stages[pos:]...)...)
+	}
+
+	handler := Handler(ps.forwardStage)
+	for i := len(stages) - 1; i >= 0; i-- {
+		m, next := stages[i], handler
+		handler = func(req *ProxyRequest) { m.Handle(req, next) }
+	}
+	return handler, nil
+}
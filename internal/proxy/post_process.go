@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxJSONExtractAttempts bounds how many candidate '{' / '[' positions extract_json tries.
+const maxJSONExtractAttempts = 32
+
+// responsePostProcessors are the built-in text processors selectable per group.
+var responsePostProcessors = map[string]func(string) string{
+	"strip_markdown_fences":    stripMarkdownFences,
+	"extract_json":             extractJSONText,
+	"trim_trailing_whitespace": trimTrailingWhitespace,
+}
+
+// generatedTextPaths locate the generated text in OpenAI, Anthropic and Gemini responses.
+var generatedTextPaths = []string{
+	"choices[*].message.content",
+	"choices[*].text",
+	"content[*].text",
+	"candidates[*].content.parts[*].text",
+}
+
+// buildPostProcessRules returns outbound transform rules applying the group's
+// configured post-processors, in order, to the generated text of the response.
+func buildPostProcessRules(group *models.Group) []jsonengine.PathRule {
+	names := utils.SplitAndTrim(group.EffectiveConfig.ResponsePostProcessors, ",")
+	if len(names) == 0 {
+		return nil
+	}
+
+	processors := make([]func(string) string, 0, len(names))
+	for _, name := range names {
+		processor, ok := responsePostProcessors[name]
+		if !ok {
+			logrus.WithField("group_name", group.Name).Warnf("Unknown response post-processor %q, skipping", name)
+			continue
+		}
+		processors = append(processors, processor)
+	}
+	if len(processors) == 0 {
+		return nil
+	}
+
+	transform := func(s string) string {
+		for _, processor := range processors {
+			s = processor(s)
+		}
+		return s
+	}
+
+	rules := make([]jsonengine.PathRule, 0, len(generatedTextPaths))
+	for _, path := range generatedTextPaths {
+		rules = append(rules, jsonengine.PathRule{Path: path, Action: jsonengine.ActionTransform, Value: transform})
+	}
+	return rules
+}
+
+// stripMarkdownFences unwraps a reply that consists of a single fenced code block.
+func stripMarkdownFences(s string) string {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return s
+	}
+
+	// Drop the opening fence line (which may carry a language tag) and the closing fence.
+	body := strings.TrimSuffix(trimmed, "```")
+	newline := strings.IndexByte(body, '\n')
+	if newline < 0 {
+		return s
+	}
+	body = body[newline+1:]
+	if strings.Contains(body, "```") {
+		// Multiple blocks or nested fences: leave the text as-is.
+		return s
+	}
+	return strings.TrimRight(body, "\n")
+}
+
+// extractJSONText returns the first complete JSON object or array found in the text.
+func extractJSONText(s string) string {
+	attempts := 0
+	for i := 0; i < len(s) && attempts < maxJSONExtractAttempts; i++ {
+		if s[i] != '{' && s[i] != '[' {
+			continue
+		}
+		attempts++
+
+		var raw json.RawMessage
+		decoder := json.NewDecoder(strings.NewReader(s[i:]))
+		if err := decoder.Decode(&raw); err == nil {
+			return string(raw)
+		}
+	}
+	return s
+}
+
+// trimTrailingWhitespace removes trailing whitespace from every line and the end of the text.
+func trimTrailingWhitespace(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	return strings.TrimRightFunc(strings.Join(lines, "\n"), unicode.IsSpace)
+}
@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gpt-load/internal/models"
+)
+
+const (
+	templateNameField      = "template"
+	templateVariablesField = "variables"
+)
+
+// applyPromptTemplate expands a request that references a named prompt template.
+// The "template" and "variables" fields are removed and the rendered template is
+// prepended to the conversation in the channel's native message format.
+// Templates of the group the client called take precedence over the selected sub-group.
+func (ps *ProxyServer) applyPromptTemplate(bodyBytes []byte, originalGroup, group *models.Group) ([]byte, error) {
+	if len(bodyBytes) == 0 || (len(originalGroup.PromptTemplateMap) == 0 && len(group.PromptTemplateMap) == 0) {
+		return bodyBytes, nil
+	}
+
+	var requestData map[string]any
+	if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
+		return bodyBytes, nil
+	}
+
+	rawName, ok := requestData[templateNameField]
+	if !ok {
+		return bodyBytes, nil
+	}
+	name, ok := rawName.(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("field '%s' must be a non-empty string", templateNameField)
+	}
+
+	tmpl, found := originalGroup.PromptTemplateMap[name]
+	if !found {
+		tmpl, found = group.PromptTemplateMap[name]
+	}
+	if !found {
+		return nil, fmt.Errorf("prompt template '%s' not found", name)
+	}
+
+	variables, _ := requestData[templateVariablesField].(map[string]any)
+	rendered, err := renderPromptTemplate(tmpl, variables)
+	if err != nil {
+		return nil, fmt.Errorf("prompt template '%s': %w", name, err)
+	}
+
+	delete(requestData, templateNameField)
+	delete(requestData, templateVariablesField)
+
+	switch group.ChannelType {
+	case "anthropic":
+		expandAnthropicTemplate(requestData, rendered)
+	case "gemini":
+		expandGeminiTemplate(requestData, rendered)
+	default:
+		expandOpenAITemplate(requestData, rendered)
+	}
+
+	return json.Marshal(requestData)
+}
+
+// renderPromptTemplate substitutes {{var}} placeholders in a single pass and fails on any
+// placeholder without a variable. Substituted values are not scanned again.
+func renderPromptTemplate(tmpl models.PromptTemplate, variables map[string]any) (models.PromptTemplate, error) {
+	values := make(map[string]string, len(variables))
+	for key, value := range variables {
+		str, ok := value.(string)
+		if !ok {
+			str = fmt.Sprint(value)
+		}
+		values[key] = str
+	}
+
+	render := func(text string) (string, error) {
+		var missing string
+		out := models.PromptPlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+			name := strings.TrimSpace(placeholder[2 : len(placeholder)-2])
+			value, ok := values[name]
+			if !ok && missing == "" {
+				missing = name
+			}
+			return value
+		})
+		if missing != "" {
+			return "", fmt.Errorf("missing variable '%s'", missing)
+		}
+		return out, nil
+	}
+
+	var rendered models.PromptTemplate
+	var err error
+	if rendered.System, err = render(tmpl.System); err != nil {
+		return rendered, err
+	}
+	rendered.Messages = make([]models.PromptMessage, 0, len(tmpl.Messages))
+	for _, msg := range tmpl.Messages {
+		content, err := render(msg.Content)
+		if err != nil {
+			return rendered, err
+		}
+		rendered.Messages = append(rendered.Messages, models.PromptMessage{Role: msg.Role, Content: content})
+	}
+	return rendered, nil
+}
+
+// expandOpenAITemplate prepends the template as chat messages, with the system prompt first.
+func expandOpenAITemplate(requestData map[string]any, tmpl models.PromptTemplate) {
+	messages := make([]any, 0, len(tmpl.Messages)+1)
+	if tmpl.System != "" {
+		messages = append(messages, map[string]any{"role": "system", "content": tmpl.System})
+	}
+	for _, msg := range tmpl.Messages {
+		messages = append(messages, map[string]any{"role": msg.Role, "content": msg.Content})
+	}
+	existing, _ := requestData["messages"].([]any)
+	requestData["messages"] = append(messages, existing...)
+}
+
+// expandAnthropicTemplate sets the top-level system prompt and prepends template messages.
+func expandAnthropicTemplate(requestData map[string]any, tmpl models.PromptTemplate) {
+	if tmpl.System != "" {
+		switch existing := requestData["system"].(type) {
+		case string:
+			if existing != "" {
+				requestData["system"] = tmpl.System + "\n\n" + existing
+			} else {
+				requestData["system"] = tmpl.System
+			}
+		case []any:
+			requestData["system"] = append([]any{map[string]any{"type": "text", "text": tmpl.System}}, existing...)
+		default:
+			requestData["system"] = tmpl.System
+		}
+	}
+
+	messages := make([]any, 0, len(tmpl.Messages))
+	for _, msg := range tmpl.Messages {
+		messages = append(messages, map[string]any{"role": msg.Role, "content": msg.Content})
+	}
+	existing, _ := requestData["messages"].([]any)
+	requestData["messages"] = append(messages, existing...)
+}
+
+// expandGeminiTemplate sets systemInstruction and prepends template turns to contents.
+func expandGeminiTemplate(requestData map[string]any, tmpl models.PromptTemplate) {
+	if tmpl.System != "" {
+		parts := []any{map[string]any{"text": tmpl.System}}
+		if existing, ok := requestData["systemInstruction"].(map[string]any); ok {
+			if existingParts, ok := existing["parts"].([]any); ok {
+				parts = append(parts, existingParts...)
+			}
+		}
+		requestData["systemInstruction"] = map[string]any{"parts": parts}
+	}
+
+	contents := make([]any, 0, len(tmpl.Messages))
+	for _, msg := range tmpl.Messages {
+		role := msg.Role
+		if role == "assistant" {
+			role = "model"
+		}
+		contents = append(contents, map[string]any{
+			"role":  role,
+			"parts": []any{map[string]any{"text": msg.Content}},
+		})
+	}
+	existing, _ := requestData["contents"].([]any)
+	requestData["contents"] = append(contents, existing...)
+}
@@ -0,0 +1,237 @@
+package proxy
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 限流响应头模式
+const (
+	// RateLimitHeadersPassthrough 原样转发上游限流头（默认）
+	RateLimitHeadersPassthrough = "passthrough"
+	// RateLimitHeadersNormalize 额外输出由厂商特定格式转换而来的标准限流头
+	RateLimitHeadersNormalize = "normalize"
+	// RateLimitHeadersPool 以分组内所有可用密钥的汇总限额替换上游限流头
+	RateLimitHeadersPool = "pool"
+)
+
+// 标准限流头，沿用 OpenAI 的命名，重置时间为相对时长（如 1s、6m0s）
+const (
+	headerLimitRequests     = "X-Ratelimit-Limit-Requests"
+	headerRemainingRequests = "X-Ratelimit-Remaining-Requests"
+	headerResetRequests     = "X-Ratelimit-Reset-Requests"
+	headerLimitTokens       = "X-Ratelimit-Limit-Tokens"
+	headerRemainingTokens   = "X-Ratelimit-Remaining-Tokens"
+	headerResetTokens       = "X-Ratelimit-Reset-Tokens"
+	headerRetryAfter        = "Retry-After"
+	// headerPoolActiveKeys pool 模式下输出分组当前可用的密钥数
+	headerPoolActiveKeys = "X-Gpt-Load-Active-Keys"
+)
+
+// rateLimitHeaderVariant 一种厂商的限流头命名
+type rateLimitHeaderVariant struct {
+	limit, remaining, reset string
+}
+
+// 按优先级排列，先匹配到的格式生效
+var (
+	requestLimitVariants = []rateLimitHeaderVariant{
+		{headerLimitRequests, headerRemainingRequests, headerResetRequests},
+		{"Anthropic-Ratelimit-Requests-Limit", "Anthropic-Ratelimit-Requests-Remaining", "Anthropic-Ratelimit-Requests-Reset"},
+		// OpenRouter 等服务使用的通用格式
+		{"X-Ratelimit-Limit", "X-Ratelimit-Remaining", "X-Ratelimit-Reset"},
+		{"Ratelimit-Limit", "Ratelimit-Remaining", "Ratelimit-Reset"},
+	}
+	tokenLimitVariants = []rateLimitHeaderVariant{
+		{headerLimitTokens, headerRemainingTokens, headerResetTokens},
+		{"Anthropic-Ratelimit-Tokens-Limit", "Anthropic-Ratelimit-Tokens-Remaining", "Anthropic-Ratelimit-Tokens-Reset"},
+	}
+)
+
+// parseRateLimitHeaders 解析上游响应中的限流头，未携带限流信息时返回 nil
+func parseRateLimitHeaders(header http.Header, now time.Time) *keypool.RateLimitState {
+	if header == nil {
+		return nil
+	}
+	state := &keypool.RateLimitState{
+		Requests:   parseRateLimitWindow(header, requestLimitVariants, now),
+		Tokens:     parseRateLimitWindow(header, tokenLimitVariants, now),
+		RetryAfter: parseRetryAfter(header, now),
+		RecordedAt: now,
+	}
+	if state.Empty() {
+		return nil
+	}
+	return state
+}
+
+func parseRateLimitWindow(header http.Header, variants []rateLimitHeaderVariant, now time.Time) *keypool.RateLimitWindow {
+	for _, v := range variants {
+		remaining, err := strconv.ParseInt(strings.TrimSpace(header.Get(v.remaining)), 10, 64)
+		if err != nil {
+			continue
+		}
+		limit, _ := strconv.ParseInt(strings.TrimSpace(header.Get(v.limit)), 10, 64)
+		return &keypool.RateLimitWindow{
+			Limit:     max(limit, 0),
+			Remaining: remaining,
+			Reset:     parseResetTime(header.Get(v.reset), now),
+		}
+	}
+	return nil
+}
+
+// parseResetTime 解析重置时间，支持相对时长（1s、6m0s、20ms）、RFC 3339 时间、
+// Unix 时间戳（秒或毫秒）和相对秒数
+func parseResetTime(value string, now time.Time) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d)
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return time.Time{}
+	}
+	switch {
+	case n >= 1e12:
+		return time.UnixMilli(int64(n))
+	case n >= 1e9:
+		return time.Unix(int64(n), 0)
+	default:
+		return now.Add(time.Duration(n * float64(time.Second)))
+	}
+}
+
+// parseRetryAfter 解析 retry-after-ms 和 Retry-After（秒数或 HTTP 日期）
+func parseRetryAfter(header http.Header, now time.Time) time.Time {
+	if ms, err := strconv.ParseFloat(strings.TrimSpace(header.Get("Retry-After-Ms")), 64); err == nil && ms >= 0 {
+		return now.Add(time.Duration(ms * float64(time.Millisecond)))
+	}
+	value := strings.TrimSpace(header.Get(headerRetryAfter))
+	if value == "" {
+		return time.Time{}
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// coolDownRateLimitedKey 在分组开启自适应冷却时，按 429 响应的 Retry-After 或限流重置头冷却密钥。
+// 返回 true 表示密钥已进入冷却，本次失败不再计入黑名单阈值
+func (ps *ProxyServer) coolDownRateLimitedKey(group *models.Group, apiKey *models.APIKey, resp *http.Response) bool {
+	if !group.EffectiveConfig.EnableAdaptiveCooldown || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	until := parseRateLimitHeaders(resp.Header, time.Now()).CooldownUntil()
+	if until.IsZero() {
+		return false
+	}
+	until, err := ps.keyProvider.CoolDownKey(group, apiKey.ID, until)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to cool down rate limited key")
+		return false
+	}
+	if until.IsZero() {
+		return false
+	}
+	logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "until": until}).Debug("Key is cooling down after upstream rate limit")
+	return true
+}
+
+// observeRateLimit 解析一次上游响应的限流头；pool 模式下保存到密钥的限流状态中以便汇总
+func (ps *ProxyServer) observeRateLimit(group *models.Group, apiKey *models.APIKey, resp *http.Response) *keypool.RateLimitState {
+	mode := group.EffectiveConfig.RateLimitHeaders
+	if resp == nil || mode == "" || mode == RateLimitHeadersPassthrough {
+		return nil
+	}
+	state := parseRateLimitHeaders(resp.Header, time.Now())
+	if mode == RateLimitHeadersPool && apiKey != nil {
+		ps.keyProvider.RecordRateLimit(group, apiKey.ID, state)
+	}
+	return state
+}
+
+// writeRateLimitHeaders 按分组配置写出限流响应头，必须在写出响应状态码之前调用
+// normalize 模式写出本次上游响应转换后的标准头；pool 模式写出分组所有可用密钥的汇总值，
+// 覆盖上游针对单个密钥的限流头
+func (ps *ProxyServer) writeRateLimitHeaders(c *gin.Context, group *models.Group, state *keypool.RateLimitState) {
+	header := c.Writer.Header()
+	now := time.Now()
+
+	switch group.EffectiveConfig.RateLimitHeaders {
+	case RateLimitHeadersNormalize:
+		if state == nil {
+			return
+		}
+		setRateLimitWindow(header, state.Requests, headerLimitRequests, headerRemainingRequests, headerResetRequests, now)
+		setRateLimitWindow(header, state.Tokens, headerLimitTokens, headerRemainingTokens, headerResetTokens, now)
+		if state.RetryAfter.After(now) {
+			header.Set(headerRetryAfter, retryAfterSeconds(state.RetryAfter, now))
+		}
+
+	case RateLimitHeadersPool:
+		pool, err := ps.keyProvider.PoolRateLimit(group)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"group": group.Name, "error": err}).Warn("Failed to aggregate pool rate limit")
+			return
+		}
+		for _, name := range []string{
+			headerLimitRequests, headerRemainingRequests, headerResetRequests,
+			headerLimitTokens, headerRemainingTokens, headerResetTokens,
+			headerRetryAfter, "Retry-After-Ms",
+		} {
+			header.Del(name)
+		}
+		for _, variants := range [][]rateLimitHeaderVariant{requestLimitVariants, tokenLimitVariants} {
+			for _, v := range variants {
+				header.Del(v.limit)
+				header.Del(v.remaining)
+				header.Del(v.reset)
+			}
+		}
+
+		header.Set(headerPoolActiveKeys, strconv.Itoa(pool.ActiveKeys))
+		setRateLimitWindow(header, pool.Requests, headerLimitRequests, headerRemainingRequests, headerResetRequests, now)
+		setRateLimitWindow(header, pool.Tokens, headerLimitTokens, headerRemainingTokens, headerResetTokens, now)
+		if pool.RetryAfter.After(now) {
+			header.Set(headerRetryAfter, retryAfterSeconds(pool.RetryAfter, now))
+		}
+	}
+}
+
+func setRateLimitWindow(header http.Header, w *keypool.RateLimitWindow, limitName, remainingName, resetName string, now time.Time) {
+	if w == nil {
+		return
+	}
+	if w.Limit > 0 {
+		header.Set(limitName, strconv.FormatInt(w.Limit, 10))
+	}
+	header.Set(remainingName, strconv.FormatInt(max(w.Remaining, 0), 10))
+	if w.Reset.After(now) {
+		header.Set(resetName, w.Reset.Sub(now).Round(time.Millisecond).String())
+	}
+}
+
+// retryAfterSeconds Retry-After 只支持整数秒，向上取整
+func retryAfterSeconds(at, now time.Time) string {
+	return strconv.FormatInt(int64(math.Ceil(at.Sub(now).Seconds())), 10)
+}
@@ -1,16 +1,19 @@
 package proxy
 
 import (
-	"bytes"
-	"compress/gzip"
+	"encoding/binary"
 	"encoding/json"
-	"io"
+	"errors"
+	"hash/fnv"
 	"net/http"
-	"time"
+	"path"
+	"strconv"
+	"strings"
 
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/jsonengine"
 	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
 
 	"github.com/sirupsen/logrus"
 )
@@ -33,50 +36,143 @@ func (ps *ProxyServer) applyParamOverrides(bodyBytes []byte, group *models.Group
 	return json.Marshal(requestData)
 }
 
-// applyInboundRules applies JSON transformation rules to request body
-func (ps *ProxyServer) applyInboundRules(bodyBytes []byte, group *models.Group) ([]byte, error) {
-	if len(group.InboundRuleList) == 0 || len(bodyBytes) == 0 {
+// bodySeed derives a seed from the canonical body hash, so requests that only differ in key
+// order or whitespace get the same seed. Bodies that are not valid JSON hash their raw bytes.
+func bodySeed(bodyBytes []byte) int64 {
+	if sum, err := jsonengine.CanonicalHash(bodyBytes); err == nil {
+		return int64(binary.BigEndian.Uint32(sum[:4]) & 0x7fffffff)
+	}
+	h := fnv.New32a()
+	h.Write(bodyBytes)
+	return int64(h.Sum32() & 0x7fffffff)
+}
+
+// applySeedInjection injects a seed into requests that support it, keeping any seed set by the client.
+// OpenAI-style requests take a top-level "seed"; Gemini requests take "generationConfig.seed".
+// The seed is added in one streaming pass, so the rest of the body keeps its key order and numbers.
+func (ps *ProxyServer) applySeedInjection(bodyBytes []byte, group *models.Group, requestPath string) ([]byte, error) {
+	if len(bodyBytes) == 0 {
+		return bodyBytes, nil
+	}
+	fixed, hash, err := utils.ParseRequestSeed(group.EffectiveConfig.RequestSeed)
+	if err != nil {
+		logrus.WithField("group_name", group.Name).Warnf("%v, skipping seed injection", err)
+		return bodyBytes, nil
+	}
+	if !hash && strings.TrimSpace(group.EffectiveConfig.RequestSeed) == "" {
 		return bodyBytes, nil
 	}
 
-	start := time.Now()
+	var gemini bool
+	switch {
+	case group.ChannelType == "openai" && (strings.HasSuffix(requestPath, "/chat/completions") || strings.HasSuffix(requestPath, "/completions")):
+	case group.ChannelType == "gemini" && (strings.HasSuffix(requestPath, ":generateContent") || strings.HasSuffix(requestPath, ":streamGenerateContent")):
+		gemini = true
+	default:
+		return bodyBytes, nil
+	}
+
+	seed := fixed
+	if hash {
+		seed = bodySeed(bodyBytes)
+	}
+	value := []byte(strconv.FormatInt(seed, 10))
+
+	rules := []jsonengine.PathRule{{Path: "seed", Action: jsonengine.ActionAdd, ValueBytes: value}}
+	if gemini {
+		// add does not create missing parents: the first rule covers a body without generationConfig
+		rules = []jsonengine.PathRule{
+			{Path: "generationConfig", Action: jsonengine.ActionAdd, ValueBytes: []byte(`{"seed":` + string(value) + `}`)},
+			{Path: "generationConfig.seed", Action: jsonengine.ActionAdd, ValueBytes: value},
+		}
+	}
+	engine, err := jsonengine.NewPathEngine(rules)
+	if err != nil {
+		return nil, err
+	}
+	return engine.ProcessBytes(bodyBytes)
+}
+
+// isPathAllowed checks the request path against the group's allowed endpoint paths.
+// An empty allowlist permits every path; a trailing "*" matches by prefix.
+func isPathAllowed(group *models.Group, requestPath string) bool {
+	allowedPaths := utils.SplitAndTrim(group.EffectiveConfig.AllowedPaths, ",")
+	if len(allowedPaths) == 0 {
+		return true
+	}
+
+	// Normalize to prevent "/v1/chat/../admin" style bypasses
+	cleanPath := path.Clean("/" + requestPath)
+	for _, pattern := range allowedPaths {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(cleanPath, prefix) {
+				return true
+			}
+		} else if cleanPath == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleConflictMode returns the engine option for the group's rule conflict resolution mode.
+func ruleConflictMode(group *models.Group) jsonengine.PathEngineOption {
+	return jsonengine.WithConflictMode(jsonengine.ConflictMode(group.EffectiveConfig.RuleConflictMode))
+}
 
-	// 记录引擎创建开始时间
-	engineCreateStart := time.Now()
-	engine, err := jsonengine.NewPathEngine(group.InboundRuleList)
-	engineCreateDuration := time.Since(engineCreateStart)
+// applyInboundRules applies the JSON transformation rules that apply to the request's route to its body
+func (ps *ProxyServer) applyInboundRules(bodyBytes []byte, group *models.Group, scope models.RuleScope) ([]byte, error) {
+	if !hasInboundProcessing(group) || len(bodyBytes) == 0 {
+		return bodyBytes, nil
+	}
 
+	engine, err := group.ScopedEngine(ruleDirectionInbound, scope)
 	if err != nil {
 		logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to create path engine for inbound rules")
 		return bodyBytes, nil // 失败时返回原始数据
 	}
+	if engine == nil {
+		return bodyBytes, nil
+	}
 
-	// 记录处理开始时间
-	processStart := time.Now()
-	var buf bytes.Buffer
-	if err := engine.Process(bytes.NewReader(bodyBytes), &buf); err != nil {
+	output, result, err := engine.ProcessBytesWithResult(bodyBytes)
+	if err != nil {
+		// 超出限制或不符合 schema 的请求体直接拒绝，不能绕过规则原样转发
+		var limitErr *jsonengine.LimitError
+		var schemaErr *jsonengine.SchemaError
+		if errors.As(err, &limitErr) || errors.As(err, &schemaErr) {
+			return nil, err
+		}
 		logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to apply inbound rules")
 		return bodyBytes, nil // 失败时返回原始数据
 	}
-	processDuration := time.Since(processStart)
-	totalDuration := time.Since(start)
 
-	// 详细性能日志
+	stats := result.Stats
+	recordRuleEngineStats(group, ruleDirectionInbound, stats)
 	logrus.WithFields(logrus.Fields{
-		"group":                  group.Name,
-		"rule_count":             len(group.InboundRuleList),
-		"input_bytes":            len(bodyBytes),
-		"output_bytes":           buf.Len(),
-		"engine_create_ms":       engineCreateDuration.Milliseconds(),
-		"process_ms":             processDuration.Milliseconds(),
-		"total_ms":               totalDuration.Milliseconds(),
-		"engine_create_seconds":  engineCreateDuration.Seconds(),
-		"process_seconds":        processDuration.Seconds(),
-		"total_seconds":          totalDuration.Seconds(),
-	}).Debugf("Inbound PathEngine processing: create=%v, process=%v, total=%v",
-		engineCreateDuration, processDuration, totalDuration)
-
-	return buf.Bytes(), nil
+		"group":        group.Name,
+		"rule_count":   len(group.InboundRuleList),
+		"input_bytes":  stats.BytesIn,
+		"output_bytes": stats.BytesOut,
+		"removed":      stats.Removed,
+		"set":          stats.Set,
+		"added":        stats.Added,
+		"renamed":      stats.Renamed,
+		"transformed":  stats.Transformed,
+	}).Debugf("Inbound PathEngine processing: process=%v", stats.Duration)
+
+	return output, nil
+}
+
+// rewritesResponseBody reports whether the group rewrites upstream response bodies, in which
+// case the upstream is asked for an uncompressed response. Signed passthrough forwards the
+// client headers unchanged, even when a watermark or post-processors come from system settings.
+func rewritesResponseBody(group *models.Group) bool {
+	cfg := group.EffectiveConfig
+	if cfg.SignedRequestPassthrough {
+		return false
+	}
+	return len(group.OutboundRuleList) > 0 || cfg.ResponseWatermarkField != "" || cfg.ResponsePostProcessors != ""
 }
 
 // logUpstreamError provides a centralized way to log errors from upstream interactions.
@@ -91,22 +187,13 @@ func logUpstreamError(context string, err error) {
 	}
 }
 
-// handleGzipCompression checks for gzip encoding and decompresses the body if necessary.
-func handleGzipCompression(resp *http.Response, bodyBytes []byte) []byte {
-	if resp.Header.Get("Content-Encoding") == "gzip" {
-		reader, gzipErr := gzip.NewReader(bytes.NewReader(bodyBytes))
-		if gzipErr != nil {
-			logrus.Warnf("Failed to create gzip reader for error body: %v", gzipErr)
-			return bodyBytes
-		}
-		defer reader.Close()
-
-		decompressedBody, readAllErr := io.ReadAll(reader)
-		if readAllErr != nil {
-			logrus.Warnf("Failed to decompress gzip error body: %v", readAllErr)
-			return bodyBytes
-		}
-		return decompressedBody
+// decompressErrorBody decodes an error body according to its Content-Encoding (gzip, deflate,
+// br or zstd), so it can be parsed, logged and rewritten. Bodies that cannot be decoded are
+// returned unchanged.
+func decompressErrorBody(resp *http.Response, bodyBytes []byte) []byte {
+	decompressed, err := utils.DecompressResponse(resp.Header.Get("Content-Encoding"), bodyBytes)
+	if err != nil {
+		return bodyBytes
 	}
-	return bodyBytes
+	return decompressed
 }
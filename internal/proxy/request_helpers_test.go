@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"testing"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+)
+
+func TestRewritesResponseBody(t *testing.T) {
+	rules := []jsonengine.PathRule{{Path: "id", Action: jsonengine.ActionRemove}}
+	tests := []struct {
+		name  string
+		group models.Group
+		want  bool
+	}{
+		{
+			name:  "no rewrites",
+			group: models.Group{},
+			want:  false,
+		},
+		{
+			name:  "outbound rules",
+			group: models.Group{OutboundRuleList: rules},
+			want:  true,
+		},
+		{
+			name:  "system watermark",
+			group: models.Group{EffectiveConfig: types.SystemSettings{ResponseWatermarkField: "x_served_by"}},
+			want:  true,
+		},
+		{
+			name: "passthrough under system watermark",
+			group: models.Group{EffectiveConfig: types.SystemSettings{
+				SignedRequestPassthrough: true,
+				ResponseWatermarkField:   "x_served_by",
+			}},
+			want: false,
+		},
+		{
+			name: "passthrough under system post-processors",
+			group: models.Group{EffectiveConfig: types.SystemSettings{
+				SignedRequestPassthrough: true,
+				ResponsePostProcessors:   "strip_reasoning",
+			}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewritesResponseBody(&tt.group); got != tt.want {
+				t.Errorf("rewritesResponseBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySeedInjection(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		seed    string
+		path    string
+		body    string
+		want    string
+	}{
+		{
+			name:    "openai fixed seed keeps key order",
+			channel: "openai",
+			seed:    "42",
+			path:    "/v1/chat/completions",
+			body:    `{"model":"gpt-4o","temperature":0.10,"messages":[]}`,
+			want:    `{"model":"gpt-4o","temperature":0.10,"messages":[],"seed":42}`,
+		},
+		{
+			name:    "openai client seed kept",
+			channel: "openai",
+			seed:    "42",
+			path:    "/v1/chat/completions",
+			body:    `{"seed":7,"model":"gpt-4o"}`,
+			want:    `{"seed":7,"model":"gpt-4o"}`,
+		},
+		{
+			name:    "gemini without generationConfig",
+			channel: "gemini",
+			seed:    "42",
+			path:    "/v1beta/models/gemini-2.0-flash:generateContent",
+			body:    `{"contents":[]}`,
+			want:    `{"contents":[],"generationConfig":{"seed":42}}`,
+		},
+		{
+			name:    "gemini with generationConfig",
+			channel: "gemini",
+			seed:    "42",
+			path:    "/v1beta/models/gemini-2.0-flash:generateContent",
+			body:    `{"generationConfig":{"temperature":1},"contents":[]}`,
+			want:    `{"generationConfig":{"temperature":1,"seed":42},"contents":[]}`,
+		},
+		{
+			name:    "gemini client seed kept",
+			channel: "gemini",
+			seed:    "42",
+			path:    "/v1beta/models/gemini-2.0-flash:generateContent",
+			body:    `{"generationConfig":{"seed":7}}`,
+			want:    `{"generationConfig":{"seed":7}}`,
+		},
+		{
+			name:    "unsupported endpoint",
+			channel: "openai",
+			seed:    "42",
+			path:    "/v1/embeddings",
+			body:    `{"input":"a"}`,
+			want:    `{"input":"a"}`,
+		},
+		{
+			name:    "invalid seed skipped",
+			channel: "openai",
+			seed:    "4294967296",
+			path:    "/v1/chat/completions",
+			body:    `{"model":"gpt-4o"}`,
+			want:    `{"model":"gpt-4o"}`,
+		},
+	}
+
+	ps := &ProxyServer{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &models.Group{ChannelType: tt.channel, EffectiveConfig: types.SystemSettings{RequestSeed: tt.seed}}
+			got, err := ps.applySeedInjection([]byte(tt.body), group, tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("applySeedInjection() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/jsonengine"
+
+	"github.com/gin-gonic/gin"
+)
+
+// endpointSchema binds a request schema to an endpoint class by path suffix.
+type endpointSchema struct {
+	class  string
+	suffix string
+	schema *jsonengine.Schema
+}
+
+// ValidationIssue describes a single schema violation in the request body.
+type ValidationIssue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is returned to the client when request validation fails.
+type ValidationErrorResponse struct {
+	Code     string            `json:"code"`
+	Message  string            `json:"message"`
+	Endpoint string            `json:"endpoint"`
+	Errors   []ValidationIssue `json:"errors"`
+}
+
+// endpointSchemas is ordered: more specific suffixes must come first. Optional parameters
+// also accept null, which SDKs send for parameters left unset. The Anthropic messages suffix
+// keeps its /v1 so OpenAI Assistants paths such as /v1/threads/{id}/messages do not match.
+var endpointSchemas = []endpointSchema{
+	{class: "chat_completions", suffix: "/chat/completions", schema: mustCompileSchema(`{
+		"type": "object",
+		"required": ["model", "messages"],
+		"properties": {
+			"model": {"type": "string", "minLength": 1},
+			"messages": {
+				"type": "array",
+				"minItems": 1,
+				"items": {"type": "object", "required": ["role"], "properties": {"role": {"type": "string", "minLength": 1}}}
+			},
+			"stream": {"type": ["boolean", "null"]},
+			"temperature": {"type": ["number", "null"], "minimum": 0, "maximum": 2},
+			"top_p": {"type": ["number", "null"], "minimum": 0, "maximum": 1},
+			"n": {"type": ["integer", "null"], "minimum": 1},
+			"max_tokens": {"type": ["integer", "null"], "minimum": 1},
+			"max_completion_tokens": {"type": ["integer", "null"], "minimum": 1}
+		}
+	}`)},
+	{class: "completions", suffix: "/completions", schema: mustCompileSchema(`{
+		"type": "object",
+		"required": ["model", "prompt"],
+		"properties": {
+			"model": {"type": "string", "minLength": 1},
+			"prompt": {"type": ["string", "array"]},
+			"stream": {"type": ["boolean", "null"]},
+			"max_tokens": {"type": ["integer", "null"], "minimum": 1}
+		}
+	}`)},
+	{class: "embeddings", suffix: "/embeddings", schema: mustCompileSchema(`{
+		"type": "object",
+		"required": ["model", "input"],
+		"properties": {
+			"model": {"type": "string", "minLength": 1},
+			"input": {"type": ["string", "array"]},
+			"encoding_format": {"enum": ["float", "base64", null]}
+		}
+	}`)},
+	{class: "messages", suffix: "/v1/messages", schema: mustCompileSchema(`{
+		"type": "object",
+		"required": ["model", "messages", "max_tokens"],
+		"properties": {
+			"model": {"type": "string", "minLength": 1},
+			"messages": {
+				"type": "array",
+				"minItems": 1,
+				"items": {
+					"type": "object",
+					"required": ["role", "content"],
+					"properties": {"role": {"enum": ["user", "assistant"]}, "content": {"type": ["string", "array"]}}
+				}
+			},
+			"max_tokens": {"type": "integer", "minimum": 1},
+			"stream": {"type": ["boolean", "null"]},
+			"temperature": {"type": ["number", "null"], "minimum": 0, "maximum": 1}
+		}
+	}`)},
+	{class: "generate_content", suffix: ":generateContent", schema: geminiGenerateContentSchema},
+	{class: "generate_content", suffix: ":streamGenerateContent", schema: geminiGenerateContentSchema},
+}
+
+var geminiGenerateContentSchema = mustCompileSchema(`{
+	"type": "object",
+	"required": ["contents"],
+	"properties": {
+		"contents": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"type": "object", "required": ["parts"], "properties": {"parts": {"type": "array", "minItems": 1}}}
+		}
+	}
+}`)
+
+// mustCompileSchema compiles a built-in schema and panics on malformed definitions.
+func mustCompileSchema(raw string) *jsonengine.Schema {
+	schema, err := jsonengine.CompileSchema([]byte(raw))
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in request schema: %v", err))
+	}
+	return schema
+}
+
+// findEndpointSchema returns the schema for the endpoint class of the given path.
+func findEndpointSchema(requestPath string) *endpointSchema {
+	for i := range endpointSchemas {
+		if strings.HasSuffix(requestPath, endpointSchemas[i].suffix) {
+			return &endpointSchemas[i]
+		}
+	}
+	return nil
+}
+
+// validateRequestBody validates the body against the endpoint schema.
+// It returns the endpoint class and the violations found, at most the first ten kept by
+// jsonengine.SchemaError; unknown endpoints and bodiless requests are not validated.
+func validateRequestBody(requestPath string, bodyBytes []byte) (string, []ValidationIssue) {
+	endpoint := findEndpointSchema(requestPath)
+	if endpoint == nil || len(bodyBytes) == 0 {
+		return "", nil
+	}
+
+	err := endpoint.schema.Validate(bodyBytes)
+	if err == nil {
+		return endpoint.class, nil
+	}
+	var schemaErr *jsonengine.SchemaError
+	if !errors.As(err, &schemaErr) {
+		return endpoint.class, []ValidationIssue{{Path: "", Message: err.Error()}}
+	}
+	issues := make([]ValidationIssue, len(schemaErr.Violations))
+	for i, v := range schemaErr.Violations {
+		issues[i] = ValidationIssue{Path: v.Path, Message: v.Msg}
+	}
+	return endpoint.class, issues
+}
+
+// writeValidationError responds with a structured 400 error listing the violations found.
+func writeValidationError(c *gin.Context, endpointClass string, issues []ValidationIssue) {
+	c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+		Code:     app_errors.ErrValidation.Code,
+		Message:  "Request body validation failed",
+		Endpoint: endpointClass,
+		Errors:   issues,
+	})
+}
@@ -0,0 +1,29 @@
+package proxy
+
+import "testing"
+
+func TestFindEndpointSchema(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/proxy/claude/v1/messages", "messages"},
+		{"/proxy/openai/v1/threads/thread_abc/messages", ""},
+		{"/proxy/openai/v1/chat/completions", "chat_completions"},
+		{"/proxy/openai/v1/completions", "completions"},
+		{"/proxy/openai/v1/embeddings", "embeddings"},
+		{"/proxy/gemini/v1beta/models/gemini-2.0-flash:streamGenerateContent", "generate_content"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := ""
+			if endpoint := findEndpointSchema(tt.path); endpoint != nil {
+				got = endpoint.class
+			}
+			if got != tt.want {
+				t.Errorf("findEndpointSchema(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
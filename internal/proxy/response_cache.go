@@ -0,0 +1,272 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Successful responses to deterministic requests are cached per group when the group sets
+// response_cache_ttl_seconds. The cache key hashes the request as sent upstream (after inbound
+// rules), so clients that differ only in key order or whitespace share entries. Responses are
+// stored decoded, as sent to the client, and the key includes the group's last update so
+// configuration changes never serve responses shaped by old rules.
+
+const (
+	headerResponseCache = "X-Gpt-Load-Cache"
+
+	cacheHit    = "HIT"
+	cacheMiss   = "MISS"
+	cacheBypass = "BYPASS"
+)
+
+var responseCacheRequestsTotal = metrics.NewCounter(
+	"gpt_load_response_cache_requests_total",
+	"Cacheable proxy requests, by group and cache result (hit, semantic_hit, miss, bypass).",
+	"group", "result",
+)
+
+// responseCacheStage serves deterministic requests from the response cache, or from the
+// semantic cache when the group enables it, and stores the responses of misses. Clients can
+// skip the lookup with Cache-Control: no-cache and prevent storing with Cache-Control: no-store.
+func (ps *ProxyServer) responseCacheStage(req *ProxyRequest, next Handler) {
+	c := req.Context
+	cfg := req.Group.EffectiveConfig
+	if cfg.ResponseCacheTTLSeconds <= 0 || req.Passthrough || requestBodySpool(c) != nil || !cacheableRequest(c.Request.URL.Path, req.FinalBody) {
+		next(req)
+		return
+	}
+
+	key := responseCacheKey(req)
+	noCache, noStore := requestCacheControl(c.Request.Header)
+	var semantic *semanticLookup
+	if noCache {
+		responseCacheRequestsTotal.Inc(req.Group.Name, "bypass")
+		c.Header(headerResponseCache, cacheBypass)
+	} else {
+		cached, err := ps.responseCache.Get(req.Group.ID, key)
+		if err != nil {
+			logrus.WithError(err).WithField("group", req.Group.Name).Debug("Response cache lookup failed")
+		}
+		if cached != nil && cached.Stream == req.IsStream {
+			responseCacheRequestsTotal.Inc(req.Group.Name, "hit")
+			c.Header(headerResponseCache, cacheHit)
+			c.Header("Age", strconv.Itoa(int(time.Since(cached.StoredAt).Seconds())))
+			replayCachedResponse(c, req.Group, cached)
+			return
+		}
+
+		if cfg.EnableSemanticCache {
+			var similarity float64
+			cached, similarity, semantic = ps.lookupSemanticCache(req)
+			if cached != nil {
+				responseCacheRequestsTotal.Inc(req.Group.Name, "semantic_hit")
+				c.Header(headerResponseCache, cacheHit)
+				c.Header(headerCacheSimilarity, strconv.FormatFloat(similarity, 'f', 4, 64))
+				c.Header("Age", strconv.Itoa(int(time.Since(cached.StoredAt).Seconds())))
+				replayCachedResponse(c, req.Group, cached)
+				return
+			}
+		}
+		responseCacheRequestsTotal.Inc(req.Group.Name, "miss")
+		c.Header(headerResponseCache, cacheMiss)
+	}
+	if noStore {
+		next(req)
+		return
+	}
+
+	maxSize := cfg.ResponseCacheMaxEntryKB * 1024
+	capture := &cacheCaptureWriter{ResponseWriter: c.Writer, limit: maxSize}
+	c.Writer = capture
+	next(req)
+	c.Writer = capture.ResponseWriter
+
+	// Interrupted or downgraded responses do not match the request and are not stored
+	if capture.Status() != http.StatusOK || capture.overflow || c.Request.Context().Err() != nil ||
+		c.Writer.Header().Get(headerStreamDowngraded) != "" {
+		return
+	}
+	body := capture.body.Bytes()
+	if encoding := c.Writer.Header().Get("Content-Encoding"); encoding != "" {
+		decoded, err := utils.DecompressResponse(encoding, body)
+		if err != nil || len(decoded) > maxSize {
+			return
+		}
+		body = decoded
+	}
+
+	cached := &services.CachedResponse{
+		Status:      http.StatusOK,
+		ContentType: c.Writer.Header().Get("Content-Type"),
+		Stream:      req.IsStream,
+		Body:        body,
+		StoredAt:    time.Now(),
+	}
+	ttl := time.Duration(cfg.ResponseCacheTTLSeconds) * time.Second
+	if err := ps.responseCache.Set(req.Group.ID, key, cached, ttl); err != nil {
+		logrus.WithError(err).WithField("group", req.Group.Name).Debug("Failed to store response in cache")
+		return
+	}
+	if semantic != nil {
+		ps.indexSemanticCache(req.Group, semantic, key, ttl)
+	}
+}
+
+// cacheableRequest reports whether a request is deterministic: an embeddings request, or a
+// generation request with temperature 0 (top-level, or generationConfig.temperature for Gemini).
+func cacheableRequest(requestPath string, body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	if endpoint := findEndpointSchema(requestPath); endpoint != nil && endpoint.class == "embeddings" {
+		return true
+	}
+
+	var data struct {
+		Temperature      *float64 `json:"temperature"`
+		GenerationConfig *struct {
+			Temperature *float64 `json:"temperature"`
+		} `json:"generationConfig"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return false
+	}
+	if data.GenerationConfig != nil && data.GenerationConfig.Temperature != nil {
+		return *data.GenerationConfig.Temperature == 0
+	}
+	return data.Temperature != nil && *data.Temperature == 0
+}
+
+// responseCacheKey hashes the group revision, method, endpoint and canonical JSON body.
+// Canonicalizing sorts object keys, drops insignificant whitespace and writes numbers in one
+// form (0.0 and 0 are equal); bodies that are not JSON are hashed as is.
+func responseCacheKey(req *ProxyRequest) string {
+	u := req.Context.Request.URL
+	h := sha256.New()
+	for _, part := range []string{
+		strconv.FormatInt(req.Group.UpdatedAt.UnixNano(), 10),
+		req.Context.Request.Method,
+		u.Path,
+		u.RawQuery,
+		strconv.FormatBool(req.IsStream),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	writeCanonicalBody(h, req.FinalBody)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeCanonicalBody adds a request body to a cache key: the jsonengine canonical hash of a
+// JSON body, or the raw bytes of any other body. A marker byte keeps the two forms apart.
+func writeCanonicalBody(h hash.Hash, body []byte) {
+	if sum, err := jsonengine.CanonicalHash(body); err == nil {
+		h.Write([]byte{'j'})
+		h.Write(sum[:])
+		return
+	}
+	h.Write([]byte{'r'})
+	h.Write(body)
+}
+
+// requestCacheControl reads the no-cache and no-store directives of the client request.
+func requestCacheControl(header http.Header) (noCache, noStore bool) {
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "no-cache":
+				noCache = true
+			case "no-store":
+				noStore = true
+			}
+		}
+	}
+	if strings.EqualFold(header.Get("Pragma"), "no-cache") {
+		noCache = true
+	}
+	return noCache, noStore
+}
+
+// replayCachedResponse writes a cached response. Event streams are replayed event by event
+// with a flush after each, so SSE clients see the same framing as a live stream.
+func replayCachedResponse(c *gin.Context, group *models.Group, cached *services.CachedResponse) {
+	if cached.ContentType != "" {
+		c.Header("Content-Type", cached.ContentType)
+	}
+	if !cached.Stream {
+		c.Status(cached.Status)
+		writeRewrittenBody(c, group, cached.Body)
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(cached.Status)
+
+	flusher, _ := c.Writer.(http.Flusher)
+	body := cached.Body
+	for len(body) > 0 {
+		event := body
+		if isEventStreamContentType(cached.ContentType) {
+			if i := bytes.Index(body, []byte("\n\n")); i >= 0 {
+				event = body[:i+2]
+			}
+		}
+		if _, err := c.Writer.Write(event); err != nil {
+			logUpstreamError("replaying cached stream", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		body = body[len(event):]
+	}
+}
+
+// cacheCaptureWriter passes the response through to the client and keeps a copy of the
+// body, up to limit bytes, for the response cache.
+type cacheCaptureWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *cacheCaptureWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *cacheCaptureWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.body = bytes.Buffer{}
+		return
+	}
+	w.body.Write(data)
+}
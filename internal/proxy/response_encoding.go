@@ -0,0 +1,261 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gpt-load/internal/models"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sirupsen/logrus"
+)
+
+// Response bodies rewritten by outbound rules or scripts are decoded before the rewrite and,
+// when the group enables compress_rewritten_responses, compressed again with an encoding the
+// client accepts. The upstream Content-Length and Content-Encoding already copied to the client
+// response are replaced so they describe the body actually sent.
+
+// responseDecoders open a decoding reader for each supported Content-Encoding.
+var responseDecoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gzip":    func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"x-gzip":  func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"deflate": openDeflate,
+	"br":      func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(brotli.NewReader(r)), nil },
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+// openDeflate decodes HTTP deflate, which is zlib-wrapped DEFLATE, falling back to raw DEFLATE
+// as sent by some servers when the stream does not start with a zlib header.
+func openDeflate(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// responseEncoders are the encodings rewritten bodies can be compressed with, in order of
+// preference when the client accepts several with the same weight. Levels favour speed,
+// since compression runs on the response path.
+var responseEncoders = []struct {
+	name string
+	open func(io.Writer) (io.WriteCloser, error)
+}{
+	{"zstd", func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+	}},
+	{"br", func(w io.Writer) (io.WriteCloser, error) { return brotli.NewWriterLevel(w, 4), nil }},
+	{"gzip", func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, gzip.BestSpeed) }},
+}
+
+// responseContentEncoding returns the normalized Content-Encoding of a response, empty for identity.
+func responseContentEncoding(header http.Header) string {
+	encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding")))
+	if encoding == "identity" {
+		return ""
+	}
+	return encoding
+}
+
+// decodeResponseBody replaces a compressed upstream body with its decoded content so it can be
+// rewritten, and removes the upstream Content-Encoding and Content-Length from both the upstream
+// and the client response headers. It returns false, leaving the body readable from the start,
+// for encodings it cannot decode (including stacked encodings such as "gzip, br"); such bodies
+// must be passed through unchanged.
+func decodeResponseBody(c *gin.Context, resp *http.Response) bool {
+	encoding := responseContentEncoding(resp.Header)
+	if encoding == "" {
+		return true
+	}
+	open, ok := responseDecoders[encoding]
+	if !ok {
+		return false
+	}
+
+	// Decoders read the stream header when opened; keep those bytes so a body that fails to
+	// open can still be passed through intact
+	rec := &recordingReader{r: resp.Body}
+	decoded, err := open(rec)
+	if err != nil {
+		logrus.WithError(err).WithField("content_encoding", encoding).Warn("Failed to decode upstream response, passing it through")
+		resp.Body = readCloser{Reader: io.MultiReader(&rec.buf, resp.Body), Closer: resp.Body}
+		return false
+	}
+	rec.stop()
+
+	resp.Body = readCloser{Reader: decoded, Closer: closers{decoded, resp.Body}}
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	for _, header := range []http.Header{resp.Header, c.Writer.Header()} {
+		header.Del("Content-Encoding")
+		header.Del("Content-Length")
+	}
+	return true
+}
+
+// rewrittenBodyWriter returns the writer for a rewritten response body streamed to the client,
+// compressing it when the group enables it and the client accepts a supported encoding. The
+// length of the rewritten body is unknown, so Content-Length is removed. finish must be called
+// after the body is written to flush the encoder.
+func rewrittenBodyWriter(c *gin.Context, group *models.Group) (w io.Writer, finish func()) {
+	c.Writer.Header().Del("Content-Length")
+	encoding, open := negotiateResponseEncoding(c, group)
+	if open == nil {
+		return c.Writer, func() {}
+	}
+	enc, err := open(c.Writer)
+	if err != nil {
+		logUpstreamError("creating response encoder", err)
+		return c.Writer, func() {}
+	}
+	setResponseEncoding(c, encoding)
+	return enc, func() {
+		if err := enc.Close(); err != nil {
+			logUpstreamError("finishing compressed response", err)
+		}
+	}
+}
+
+// writeRewrittenBody writes a buffered rewritten body, compressed as in rewrittenBodyWriter,
+// with a Content-Length matching the bytes sent.
+func writeRewrittenBody(c *gin.Context, group *models.Group, body []byte) {
+	if encoding, open := negotiateResponseEncoding(c, group); open != nil {
+		var compressed bytes.Buffer
+		enc, err := open(&compressed)
+		if err == nil {
+			_, err = enc.Write(body)
+		}
+		if err == nil {
+			err = enc.Close()
+		}
+		if err == nil {
+			setResponseEncoding(c, encoding)
+			body = compressed.Bytes()
+		} else {
+			logUpstreamError("compressing response body", err)
+		}
+	}
+
+	c.Writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if _, err := c.Writer.Write(body); err != nil {
+		logUpstreamError("writing response body", err)
+	}
+}
+
+// negotiateResponseEncoding picks the encoding for a rewritten body from the client's
+// Accept-Encoding, or returns a nil opener to send the body uncompressed.
+func negotiateResponseEncoding(c *gin.Context, group *models.Group) (string, func(io.Writer) (io.WriteCloser, error)) {
+	if !group.EffectiveConfig.CompressRewrittenResponses {
+		return "", nil
+	}
+	name := preferredEncoding(c.Request.Header.Get("Accept-Encoding"))
+	for _, enc := range responseEncoders {
+		if enc.name == name {
+			return enc.name, enc.open
+		}
+	}
+	return "", nil
+}
+
+// preferredEncoding returns the supported encoding with the highest q-value in an Accept-Encoding
+// header, breaking ties by responseEncoders order. "*" matches any supported encoding the header
+// does not list. It returns "" when the client accepts none of them.
+func preferredEncoding(acceptEncoding string) string {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range responseEncoders {
+		q, listed := weights[enc.name]
+		if !listed {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = enc.name, q
+		}
+	}
+	return best
+}
+
+// setResponseEncoding marks the client response as compressed with encoding.
+func setResponseEncoding(c *gin.Context, encoding string) {
+	header := c.Writer.Header()
+	header.Set("Content-Encoding", encoding)
+	for _, v := range header.Values("Vary") {
+		if strings.Contains(strings.ToLower(v), "accept-encoding") {
+			return
+		}
+	}
+	header.Add("Vary", "Accept-Encoding")
+}
+
+// recordingReader keeps the bytes read through it until stop is called.
+type recordingReader struct {
+	r       io.Reader
+	buf     bytes.Buffer
+	stopped bool
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if !r.stopped {
+		r.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+func (r *recordingReader) stop() {
+	r.stopped = true
+	r.buf = bytes.Buffer{}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// closers closes each closer in order, returning the first error.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var first error
+	for _, c := range cs {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"bufio"
+	"bytes"
 	"io"
 	"net/http"
 	"strings"
@@ -12,21 +14,68 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response, group *models.Group) {
+func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response, group *models.Group, upstreamModel string) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		logrus.Error("Streaming unsupported by the writer, falling back to normal response")
+		ps.handleNormalResponse(c, resp, group, upstreamModel)
+		return
+	}
+
+	// Gemini streamGenerateContent without alt=sse streams one top-level JSON array,
+	// which the engine can rewrite element by element as it arrives. Compressed streams
+	// are decoded for rewriting and sent to the client uncompressed.
+	if isJSONContentType(resp.Header.Get("Content-Type")) {
+		engine, err := ps.outboundEngine(c, group)
+		if err != nil {
+			logUpstreamError("creating path engine", err)
+		} else if engine != nil && decodeResponseBody(c, resp) {
+			c.Writer.Header().Del("Content-Length")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("X-Accel-Buffering", "no")
+			result, err := engine.ProcessWithResult(&flushingReader{r: resp.Body, flusher: flusher}, c.Writer)
+			if err != nil {
+				logUpstreamError("jsonengine stream processing", err)
+			}
+			recordRuleEngineStats(group, ruleDirectionOutbound, result.Stats)
+			recordUpstreamUsage(c, result.Captured)
+			flusher.Flush()
+			return
+		}
+	}
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
 
-	flusher, ok := c.Writer.(http.Flusher)
-	if !ok {
-		logrus.Error("Streaming unsupported by the writer, falling back to normal response")
-		ps.handleNormalResponse(c, resp, group)
-		return
+	// SSE 流按事件重组后改写每个事件的 JSON data
+	if isEventStreamContentType(resp.Header.Get("Content-Type")) {
+		engine, err := ps.outboundEngine(c, group)
+		if err != nil {
+			logUpstreamError("creating path engine", err)
+		} else if engine != nil && decodeResponseBody(c, resp) {
+			c.Writer.Header().Del("Content-Length")
+			var output io.Writer = c.Writer
+			var tracker *finishTracker
+			if group.EffectiveConfig.NormalizeFinishReason {
+				tracker = newFinishTracker(c.Writer)
+				output = tracker
+			}
+			result, err := engine.ProcessSSE(&flushingReader{r: resp.Body, flusher: flusher}, output)
+			if err != nil {
+				logUpstreamError("jsonengine event stream processing", err)
+			}
+			if tracker != nil {
+				tracker.finish(c, group, upstreamModel)
+			}
+			recordRuleEngineStats(group, ruleDirectionOutbound, result.Stats)
+			recordUpstreamUsage(c, result.Captured)
+			flusher.Flush()
+			return
+		}
 	}
 
-	// NOTE: 流式响应(SSE)格式为 "data: {...}\n\n"，不是纯 JSON
-	// 出站规则暂不支持流式响应，仅支持普通 JSON 响应
 	buf := make([]byte, 4*1024)
 	for {
 		n, err := resp.Body.Read(buf)
@@ -47,27 +96,189 @@ func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Respon
 	}
 }
 
-func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response, group *models.Group) {
-	// 检查是否有出站规则且响应是 JSON
-	if len(group.OutboundRuleList) > 0 {
-		contentType := resp.Header.Get("Content-Type")
-		if strings.Contains(contentType, "json") {
-			engine, err := jsonengine.NewPathEngine(group.OutboundRuleList)
-			if err != nil {
-				logUpstreamError("creating path engine", err)
+// flushingReader flushes the client writer before blocking on the next upstream read,
+// so output produced from one chunk is delivered before waiting for the next.
+type flushingReader struct {
+	r       io.Reader
+	flusher http.Flusher
+}
+
+func (fr *flushingReader) Read(p []byte) (int, error) {
+	fr.flusher.Flush()
+	return fr.r.Read(p)
+}
+
+func isJSONContentType(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "json")
+}
+
+func isEventStreamContentType(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "text/event-stream")
+}
+
+// buildOutboundRules 合并分组出站规则（按路由筛选后的）、内置后处理规则和结束原因归一化规则（水印按请求添加，见 watermarkEngine）
+func buildOutboundRules(group *models.Group, outboundRules []jsonengine.PathRule) []jsonengine.PathRule {
+	// 不修改分组缓存中的规则切片
+	if postRules := buildPostProcessRules(group); len(postRules) > 0 {
+		outboundRules = append(outboundRules[:len(outboundRules):len(outboundRules)], postRules...)
+	}
+	if reasonRules := buildFinishReasonRules(group); len(reasonRules) > 0 {
+		outboundRules = append(outboundRules[:len(outboundRules):len(outboundRules)], reasonRules...)
+	}
+	return outboundRules
+}
+
+func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response, group *models.Group, upstreamModel string) {
+	engine, err := ps.outboundEngine(c, group)
+	if err != nil {
+		logUpstreamError("creating path engine", err)
+	}
+	watermark := watermarkEngine(group, upstreamModel)
+
+	var body io.Reader = resp.Body
+
+	// 检查是否有出站规则、水印或响应体脚本且响应是 JSON
+	if engine != nil || watermark != nil || group.OutboundScriptProgram != nil {
+		var reason string
+		// 压缩的响应先解压再改写，无法解压的编码原样透传
+		if decodeResponseBody(c, resp) {
+			body, reason = sniffJSONBody(resp)
+		} else {
+			body, reason = resp.Body, "content encoding "+resp.Header.Get("Content-Encoding")
+		}
+		if reason == "" {
+			// 严格模式和响应体脚本都需要完整的响应体
+			if group.EffectiveConfig.StrictOutboundJSON || group.OutboundScriptProgram != nil {
+				ps.processBufferedResponse(c, resp, body, group, engine, watermark)
+				return
 			} else {
-				if err := engine.Process(resp.Body, c.Writer); err != nil {
+				// 响应体会被改写，上游的 Content-Length 不再准确，按分组设置重新压缩
+				output, finish := rewrittenBodyWriter(c, group)
+				defer finish()
+				if engine == nil {
+					// 只有水印：水印引擎直接改写响应
+					engine, watermark = watermark, nil
+				}
+				if watermark != nil {
+					var finishWatermark func()
+					output, finishWatermark = watermarkOutput(watermark, output)
+					defer finishWatermark()
+				}
+				check := newIntegrityCheck(group)
+				if check != nil {
+					body, output = check.wrap(body, output)
+				}
+				result, err := engine.ProcessWithResult(body, output)
+				if err != nil {
 					logUpstreamError("jsonengine processing", err)
+				} else if check != nil {
+					check.finish(group, result)
 				}
+				recordRuleEngineStats(group, ruleDirectionOutbound, result.Stats)
+				recordUpstreamUsage(c, result.Captured)
 				return
 			}
+		} else {
+			entry := logrus.WithFields(logrus.Fields{
+				"group":        group.Name,
+				"status":       resp.StatusCode,
+				"content_type": resp.Header.Get("Content-Type"),
+				"reason":       reason,
+			})
+			// 声明为 JSON 却返回其他内容，通常是网关错误页，需要关注
+			if isJSONContentType(resp.Header.Get("Content-Type")) && reason != "empty body" {
+				entry.Warn("Skipping outbound rules for malformed JSON response")
+			} else {
+				entry.Debug("Skipping outbound rules for non-JSON response")
+			}
 		}
 	}
 
 	// 无规则或非 JSON，使用大缓冲区直接透传
 	buf := make([]byte, 1024*1024) // 1MB buffer
-	_, err := io.CopyBuffer(c.Writer, resp.Body, buf)
-	if err != nil {
+	if _, err := io.CopyBuffer(c.Writer, body, buf); err != nil {
 		logUpstreamError("copying response body", err)
 	}
 }
+
+// processBufferedResponse 缓冲整个响应后改写：严格模式下以严格模式执行出站规则，
+// 上游返回非法 JSON 时原样透传，避免把截断或错乱的内容返回给客户端；之后执行分组的响应体脚本
+func (ps *ProxyServer) processBufferedResponse(c *gin.Context, resp *http.Response, body io.Reader, group *models.Group, engine, watermark *jsonengine.PathEngine) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		logUpstreamError("reading response body", err)
+		return
+	}
+
+	out := data
+	if engine != nil {
+		var transformed bytes.Buffer
+		transformed.Grow(len(data))
+		input, output := io.Reader(bytes.NewReader(data)), io.Writer(&transformed)
+		check := newIntegrityCheck(group)
+		if check != nil {
+			input, output = check.wrap(input, output)
+		}
+
+		result, err := engine.ProcessWithResult(input, output)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"group": group.Name,
+				"error": err,
+			}).Warn("Outbound rules skipped, passing through malformed JSON response")
+			if _, err := c.Writer.Write(data); err != nil {
+				logUpstreamError("copying response body", err)
+			}
+			return
+		}
+		if check != nil {
+			check.finish(group, result)
+		}
+		recordRuleEngineStats(group, ruleDirectionOutbound, result.Stats)
+		recordUpstreamUsage(c, result.Captured)
+		out = transformed.Bytes()
+	}
+	if watermark != nil {
+		if marked, err := watermark.ProcessBytes(out); err != nil {
+			logUpstreamError("adding response watermark", err)
+		} else {
+			out = marked
+		}
+	}
+	out = applyOutboundScript(c, out, group, resp.StatusCode)
+	writeRewrittenBody(c, group, out)
+}
+
+// sniffJSONBody 检查响应体是否为可改写的 JSON。
+// 返回用于后续读取的 body（已预读的字节不会丢失），以及跳过出站规则的原因（为空表示可处理）。
+func sniffJSONBody(resp *http.Response) (io.Reader, string) {
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified || resp.ContentLength == 0 {
+		return resp.Body, "empty body"
+	}
+
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if contentType != "" && !isJSONContentType(contentType) {
+		return resp.Body, "content type " + contentType
+	}
+
+	// 部分上游或网关会以 JSON Content-Type 返回 HTML 错误页或纯文本
+	reader := bufio.NewReader(resp.Body)
+	for n := 1; ; n++ {
+		peeked, err := reader.Peek(n)
+		if len(peeked) < n {
+			if err == io.EOF && len(bytes.TrimSpace(peeked)) == 0 {
+				return reader, "empty body"
+			}
+			// 读取错误或缓冲区内全是空白，交由透传流程处理
+			return reader, "unreadable body"
+		}
+		switch b := peeked[n-1]; b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return reader, ""
+		default:
+			return reader, "body is not a JSON object or array"
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+)
+
+// retryBudgetReserve is the number of retries a group may spend before its retry budget
+// has been earned by requests, and the most it can save up.
+const retryBudgetReserve = 10.0
+
+var (
+	retriesTotal = metrics.NewCounter(
+		"gpt_load_retries_total",
+		"Upstream requests retried with another key, by group.",
+		"group",
+	)
+	retryBudgetExhaustedTotal = metrics.NewCounter(
+		"gpt_load_retry_budget_exhausted_total",
+		"Failed requests returned without retrying because the group's retry budget was spent, by group.",
+		"group",
+	)
+)
+
+// isIdempotentRequest reports whether the request can be repeated without side effects.
+// GET and HEAD passthroughs (model lists, file metadata) carry no body to replay.
+func isIdempotentRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// retryLimit returns the maximum number of retries for the request.
+func retryLimit(r *http.Request, cfg types.SystemSettings) int {
+	if isIdempotentRequest(r) {
+		return cfg.IdempotentMaxRetries
+	}
+	return cfg.MaxRetries
+}
+
+// retryBudget limits the retries of each group to a share of its requests, so an upstream
+// outage does not multiply the traffic sent to it. Every request earns percent/100 of a
+// retry and every retry spends one, with a reserve of retryBudgetReserve.
+type retryBudget struct {
+	mu      sync.Mutex
+	balance map[string]float64
+}
+
+func newRetryBudget() *retryBudget {
+	return &retryBudget{balance: make(map[string]float64)}
+}
+
+func (b *retryBudget) balanceLocked(group string) float64 {
+	balance, ok := b.balance[group]
+	if !ok {
+		return retryBudgetReserve
+	}
+	return balance
+}
+
+// deposit credits a new request to the group's budget.
+func (b *retryBudget) deposit(group string, percent int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balance[group] = min(b.balanceLocked(group)+float64(percent)/100, retryBudgetReserve)
+}
+
+// withdraw spends one retry, reporting false when the budget is exhausted.
+func (b *retryBudget) withdraw(group string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	balance := b.balanceLocked(group)
+	if balance < 1 {
+		return false
+	}
+	b.balance[group] = balance - 1
+	return true
+}
+
+// planRetry decides whether a failed attempt is retried and how long to wait before it.
+// Connection errors (resp is nil) are always retryable; error statuses are retried when they
+// match retry_status_codes. The delay grows exponentially with jitter and, when the group
+// honors Retry-After, is at least the delay the upstream asked for; a request the upstream
+// asks to hold off longer than the maximum backoff is not retried. The retry budget is
+// charged last, only for retries that would otherwise happen.
+func (ps *ProxyServer) planRetry(r *http.Request, group *models.Group, resp *http.Response, retryCount int) (time.Duration, bool) {
+	cfg := group.EffectiveConfig
+	if resp != nil && !retryableStatus(cfg, resp.StatusCode) {
+		return 0, false
+	}
+
+	maxBackoff := time.Duration(cfg.RetryMaxBackoffMs) * time.Millisecond
+	delay := retryBackoff(r, cfg, retryCount, maxBackoff)
+	if cfg.HonorRetryAfter && resp != nil {
+		if at := parseRetryAfter(resp.Header, time.Now()); !at.IsZero() {
+			wait := time.Until(at)
+			if wait > maxBackoff {
+				return 0, false
+			}
+			delay = max(delay, wait)
+		}
+	}
+
+	if cfg.RetryBudgetPercent > 0 && !ps.retryBudget.withdraw(group.Name) {
+		retryBudgetExhaustedTotal.Inc(group.Name)
+		return 0, false
+	}
+	retriesTotal.Inc(group.Name)
+	return delay, true
+}
+
+// retryableStatus reports whether an upstream error status is retried. Without
+// retry_status_codes, or with an invalid list, every error status is retried.
+func retryableStatus(cfg types.SystemSettings, status int) bool {
+	patterns, err := utils.ParseStatusPatterns(cfg.RetryStatusCodes)
+	if err != nil || len(patterns) == 0 {
+		return true
+	}
+	return utils.StatusMatches(patterns, status)
+}
+
+// retryBackoff returns the delay before the next retry: the base delay for the request kind,
+// doubled on every attempt with random jitter and capped at maxBackoff. A zero base retries
+// immediately.
+func retryBackoff(r *http.Request, cfg types.SystemSettings, retryCount int, maxBackoff time.Duration) time.Duration {
+	base := cfg.RetryBackoffMs
+	if isIdempotentRequest(r) {
+		base = cfg.IdempotentRetryBackoffMs
+	}
+	if base <= 0 || maxBackoff <= 0 {
+		return 0
+	}
+
+	backoff := time.Duration(base) * time.Millisecond
+	for i := 0; i < retryCount && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxBackoff)
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// waitRetry sleeps for delay before a retry. It returns false if the client went away while waiting.
+func waitRetry(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// buildRuleEngine compiles a group's rule engine for one direction. Groups cache the result
+// per loaded revision (see models.Group.InboundEngine), so this runs once per group and rule set.
+// Outbound engines also carry the built-in post-processing and usage capture rules;
+// error outbound engines only run the group's own error rules.
+func (ps *ProxyServer) buildRuleEngine(group *models.Group, direction string) (*jsonengine.PathEngine, error) {
+	if direction == ruleDirectionInbound {
+		rules := group.RuleList(direction)
+		if !hasInboundProcessing(group) {
+			return nil, nil
+		}
+		opts := ps.ruleEngineOptions(group, direction, rules)
+		if spec := strings.TrimSpace(group.EffectiveConfig.InboundJSONSchema); spec != "" {
+			schema, err := jsonengine.CompileSchema([]byte(spec))
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, jsonengine.WithSchema(schema))
+		}
+		return jsonengine.NewPathEngine(rules, opts...)
+	}
+	if direction != ruleDirectionOutbound {
+		rules := group.RuleList(direction)
+		if len(rules) == 0 {
+			return nil, nil
+		}
+		return jsonengine.NewPathEngine(rules, ps.ruleEngineOptions(group, direction, rules)...)
+	}
+
+	rules := buildOutboundRules(group, group.RuleList(direction))
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	rules = withUsageCaptures(rules)
+	return jsonengine.NewPathEngine(rules, ps.ruleEngineOptions(group, direction, rules)...)
+}
+
+// ruleModelContextKey holds the model the client requested, set by the transform stage.
+const ruleModelContextKey = "ruleModel"
+
+// setRuleModel records the model rules with a models selector are matched against. It is the
+// model the client asked for, so rules select the same requests before and after model redirection.
+func setRuleModel(c *gin.Context, model string) {
+	c.Set(ruleModelContextKey, model)
+}
+
+// ruleScope returns the route used to select rules with path, method or model selectors: the request
+// method, the path forwarded upstream (relative to the group's proxy prefix) and the requested model.
+func ruleScope(c *gin.Context) models.RuleScope {
+	return models.RuleScope{Method: c.Request.Method, Path: c.Param("path"), Model: c.GetString(ruleModelContextKey)}
+}
+
+// hasInboundProcessing reports whether request bodies go through the inbound engine: the group
+// has inbound rules or an inbound JSON schema the transformed body must match.
+func hasInboundProcessing(group *models.Group) bool {
+	return len(group.InboundRuleList) > 0 || strings.TrimSpace(group.EffectiveConfig.InboundJSONSchema) != ""
+}
+
+// outboundEngine returns the group's cached engine for a response to the request in c, compiled
+// once per group revision and route. The response watermark differs per request and is added
+// by a separate engine (see watermarkEngine).
+func (ps *ProxyServer) outboundEngine(c *gin.Context, group *models.Group) (*jsonengine.PathEngine, error) {
+	return group.ScopedEngine(ruleDirectionOutbound, ruleScope(c))
+}
+
+func (ps *ProxyServer) ruleEngineOptions(group *models.Group, direction string, rules []jsonengine.PathRule) []jsonengine.PathEngineOption {
+	opts := []jsonengine.PathEngineOption{ruleConflictMode(group), ps.ruleMatchCounter(group, direction, rules)}
+	cfg := group.EffectiveConfig
+	if direction == ruleDirectionOutbound && cfg.StrictOutboundJSON {
+		opts = append(opts, jsonengine.WithStrictMode())
+	}
+	if direction == ruleDirectionOutbound && cfg.RemoveEmptyParents {
+		opts = append(opts, jsonengine.WithRemoveEmptyParents())
+	}
+	// Inbound bodies come from clients, so their nesting, key length and size are bounded
+	if direction == ruleDirectionInbound {
+		opts = append(opts,
+			jsonengine.WithMaxDepth(cfg.InboundJSONMaxDepth),
+			jsonengine.WithMaxKeyLength(cfg.InboundJSONMaxKeyLength),
+			jsonengine.WithMaxDocumentSize(int64(cfg.InboundJSONMaxSizeMB)<<20),
+		)
+	}
+	return opts
+}
+
+// applyErrorOutboundRules rewrites a non-2xx JSON response body with the group's error outbound rules
+// that apply to the request's route. Bodies that are not valid JSON are returned unchanged, as are
+// bodies the rules fail to process.
+func applyErrorOutboundRules(group *models.Group, scope models.RuleScope, body []byte) []byte {
+	engine, err := group.ScopedEngine(ruleDirectionErrorOutbound, scope)
+	if err != nil {
+		logUpstreamError("creating error path engine", err)
+		return body
+	}
+	if engine == nil || !json.Valid(body) {
+		return body
+	}
+
+	output, result, err := engine.ProcessBytesWithResult(body)
+	if err != nil {
+		logUpstreamError("jsonengine error response processing", err)
+		return body
+	}
+	recordRuleEngineStats(group, ruleDirectionErrorOutbound, result.Stats)
+	return output
+}
+
+// handleErrorResponse copies a non-2xx response that did not go through the retry path (such as a 404),
+// applying the error outbound rules instead of the success rules.
+func (ps *ProxyServer) handleErrorResponse(c *gin.Context, resp *http.Response, group *models.Group) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logUpstreamError("reading error response body", err)
+		return
+	}
+	// A compressed body is decoded for the rules and only sent decoded when they changed it
+	decoded := decompressErrorBody(resp, body)
+	if rewritten := applyErrorOutboundRules(group, ruleScope(c), decoded); !bytes.Equal(rewritten, decoded) {
+		c.Writer.Header().Del("Content-Encoding")
+		writeRewrittenBody(c, group, rewritten)
+		return
+	}
+	if _, err := c.Writer.Write(body); err != nil {
+		logUpstreamError("writing error response body", err)
+	}
+}
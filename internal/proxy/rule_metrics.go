@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+)
+
+const (
+	ruleDirectionInbound       = models.RuleDirectionInbound
+	ruleDirectionOutbound      = models.RuleDirectionOutbound
+	ruleDirectionErrorOutbound = models.RuleDirectionErrorOutbound
+	// notKeptRulePath labels fields dropped because no keep rule covers them
+	notKeptRulePath = "(not kept)"
+)
+
+var ruleMatchesTotal = metrics.NewCounter(
+	"gpt_load_rule_matches_total",
+	"JSON rule matches that changed a body, by group, direction (inbound, outbound, error_outbound), action and rule path.",
+	"group", "direction", "action", "path",
+)
+
+// ruleMatchCounter returns an engine option that counts rule matches per group and records
+// when each rule last matched, for the bulk rule lint.
+// Rule indexes refer to the engine's rules, which skip rules with an empty path.
+func (ps *ProxyServer) ruleMatchCounter(group *models.Group, direction string, rules []jsonengine.PathRule) jsonengine.PathEngineOption {
+	paths := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Path != "" {
+			paths = append(paths, rule.Path)
+		}
+	}
+	return jsonengine.WithOnMatch(func(e jsonengine.MatchEvent) {
+		path := notKeptRulePath
+		if e.Rule >= 0 && e.Rule < len(paths) {
+			path = paths[e.Rule]
+			ps.ruleMatches.Record(group.Name, direction, path)
+		}
+		ruleMatchesTotal.Inc(group.Name, direction, string(e.Action), path)
+	})
+}
+
+var (
+	ruleEngineDocumentsTotal = metrics.NewCounter(
+		"gpt_load_rule_engine_documents_total",
+		"Bodies processed by JSON rule engines, by group and direction.",
+		"group", "direction",
+	)
+	ruleEngineBytesTotal = metrics.NewCounter(
+		"gpt_load_rule_engine_bytes_total",
+		"Bytes read and written by JSON rule engines, by group, direction and flow (in, out).",
+		"group", "direction", "flow",
+	)
+	ruleEngineOperationsTotal = metrics.NewCounter(
+		"gpt_load_rule_engine_operations_total",
+		"Field operations applied by JSON rule engines, by group, direction and kind (removed, set, added, renamed, transformed).",
+		"group", "direction", "kind",
+	)
+	ruleEngineSecondsTotal = metrics.NewCounter(
+		"gpt_load_rule_engine_processing_seconds_total",
+		"Time JSON rule engines spent processing bodies, excluding time waiting for upstream data, by group and direction.",
+		"group", "direction",
+	)
+)
+
+// recordRuleEngineStats exports the statistics of one rule engine run.
+func recordRuleEngineStats(group *models.Group, direction string, stats jsonengine.EngineStats) {
+	if stats.Documents == 0 {
+		return
+	}
+	ruleEngineDocumentsTotal.Add(float64(stats.Documents), group.Name, direction)
+	ruleEngineBytesTotal.Add(float64(stats.BytesIn), group.Name, direction, "in")
+	ruleEngineBytesTotal.Add(float64(stats.BytesOut), group.Name, direction, "out")
+	for kind, n := range map[string]int64{
+		"removed":     stats.Removed,
+		"set":         stats.Set,
+		"added":       stats.Added,
+		"renamed":     stats.Renamed,
+		"transformed": stats.Transformed,
+	} {
+		if n > 0 {
+			ruleEngineOperationsTotal.Add(float64(n), group.Name, direction, kind)
+		}
+	}
+	ruleEngineSecondsTotal.Add(stats.Duration.Seconds(), group.Name, direction)
+}
@@ -0,0 +1,352 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The semantic cache extends the response cache: when a cacheable request misses the exact
+// cache, its prompt is embedded through the group's semantic_cache_group and compared with the
+// prompts of earlier requests that used the same parameters. A prompt at least
+// semantic_cache_similarity percent similar serves the response cached for that request.
+// Vectors are kept in memory on each instance and point at entries of the response cache, so
+// they share its TTL and storage.
+
+const headerCacheSimilarity = "X-Gpt-Load-Cache-Similarity"
+
+// promptFields are the request fields holding the prompt; the others must match exactly.
+var promptFields = []string{"system", "systemInstruction", "messages", "contents", "prompt"}
+
+var semanticCacheRequestsTotal = metrics.NewCounter(
+	"gpt_load_semantic_cache_requests_total",
+	"Semantic cache lookups after an exact cache miss, by group and result (hit, miss, error).",
+	"group", "result",
+)
+
+// semanticEntry is the prompt vector of a cached request.
+type semanticEntry struct {
+	bucket  string // hash of the request without its prompt
+	key     string // response cache key of the request
+	vector  []float32
+	expires time.Time
+}
+
+// semanticIndex holds the prompt vectors of each group, oldest first.
+type semanticIndex struct {
+	mu      sync.Mutex
+	entries map[uint][]semanticEntry
+}
+
+func newSemanticIndex() *semanticIndex {
+	return &semanticIndex{entries: make(map[uint][]semanticEntry)}
+}
+
+// lookup returns the most similar live entry in bucket, if its similarity reaches threshold.
+// Expired entries are dropped during the scan.
+func (s *semanticIndex) lookup(groupID uint, bucket string, vector []float32, threshold float64) (semanticEntry, float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	live := s.entries[groupID][:0]
+	var best semanticEntry
+	bestScore := -1.0
+	for _, e := range s.entries[groupID] {
+		if now.After(e.expires) {
+			continue
+		}
+		live = append(live, e)
+		if e.bucket != bucket || len(e.vector) != len(vector) {
+			continue
+		}
+		if score := dot(e.vector, vector); score > bestScore {
+			best, bestScore = e, score
+		}
+	}
+	s.entries[groupID] = live
+	return best, bestScore, bestScore >= threshold
+}
+
+// add stores an entry, evicting the oldest entries beyond maxEntries.
+func (s *semanticIndex) add(groupID uint, entry semanticEntry, maxEntries int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := append(s.entries[groupID], entry)
+	if len(entries) > maxEntries {
+		entries = append([]semanticEntry(nil), entries[len(entries)-maxEntries:]...)
+	}
+	s.entries[groupID] = entries
+}
+
+// remove drops the entries pointing at a response cache key that no longer exists.
+func (s *semanticIndex) remove(groupID uint, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.entries[groupID][:0]
+	for _, e := range s.entries[groupID] {
+		if e.key != key {
+			entries = append(entries, e)
+		}
+	}
+	s.entries[groupID] = entries
+}
+
+// semanticLookup is the state of a semantic cache miss, kept to index the response once stored.
+type semanticLookup struct {
+	bucket string
+	vector []float32
+}
+
+// lookupSemanticCache searches the semantic index for a request that missed the exact cache.
+// It returns the cached response and its similarity on a hit; on a miss it returns the state
+// needed to index the response, or nil when the request cannot be indexed.
+func (ps *ProxyServer) lookupSemanticCache(req *ProxyRequest) (*services.CachedResponse, float64, *semanticLookup) {
+	group := req.Group
+	cfg := group.EffectiveConfig
+	prompt, bucket, ok := semanticPrompt(req)
+	if !ok {
+		return nil, 0, nil
+	}
+
+	vector, err := ps.embedPrompt(req.Context.Request.Context(), cfg.SemanticCacheGroup, cfg.SemanticCacheModel, prompt)
+	if err != nil {
+		semanticCacheRequestsTotal.Inc(group.Name, "error")
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"group":            group.Name,
+			"embeddings_group": cfg.SemanticCacheGroup,
+		}).Warn("Failed to embed prompt for semantic cache")
+		return nil, 0, nil
+	}
+
+	threshold := float64(cfg.SemanticCacheSimilarity) / 100
+	if entry, score, hit := ps.semanticIndex.lookup(group.ID, bucket, vector, threshold); hit {
+		cached, err := ps.responseCache.Get(group.ID, entry.key)
+		if err == nil && cached != nil && cached.Stream == req.IsStream {
+			semanticCacheRequestsTotal.Inc(group.Name, "hit")
+			return cached, score, nil
+		}
+		if err == nil {
+			ps.semanticIndex.remove(group.ID, entry.key)
+		}
+	}
+	semanticCacheRequestsTotal.Inc(group.Name, "miss")
+	return nil, 0, &semanticLookup{bucket: bucket, vector: vector}
+}
+
+// indexSemanticCache records the prompt vector of a response stored under key.
+func (ps *ProxyServer) indexSemanticCache(group *models.Group, lookup *semanticLookup, key string, ttl time.Duration) {
+	ps.semanticIndex.add(group.ID, semanticEntry{
+		bucket:  lookup.bucket,
+		key:     key,
+		vector:  lookup.vector,
+		expires: time.Now().Add(ttl),
+	}, group.EffectiveConfig.SemanticCacheMaxEntries)
+}
+
+// semanticPrompt extracts the prompt text of an OpenAI, Anthropic or Gemini request and the
+// bucket hash of everything else in the request.
+func semanticPrompt(req *ProxyRequest) (prompt, bucket string, ok bool) {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(req.FinalBody, &data); err != nil {
+		return "", "", false
+	}
+
+	var sb strings.Builder
+	for _, field := range promptFields {
+		raw, exists := data[field]
+		if !exists {
+			continue
+		}
+		appendPromptText(&sb, field, raw)
+		delete(data, field)
+	}
+	if sb.Len() == 0 {
+		return "", "", false
+	}
+
+	rest, err := json.Marshal(data)
+	if err != nil {
+		return "", "", false
+	}
+	u := req.Context.Request.URL
+	h := sha256.New()
+	for _, part := range []string{
+		strconv.FormatInt(req.Group.UpdatedAt.UnixNano(), 10),
+		req.Context.Request.Method,
+		u.Path,
+		u.RawQuery,
+		strconv.FormatBool(req.IsStream),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	writeCanonicalBody(h, rest)
+	return sb.String(), hex.EncodeToString(h.Sum(nil)), true
+}
+
+// appendPromptText writes the text of a prompt field, one "role: text" line per message.
+// Strings, text parts ({"text": ...}) and Gemini parts are read; other content is ignored.
+func appendPromptText(sb *strings.Builder, field string, raw json.RawMessage) {
+	var messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+		Parts   json.RawMessage `json:"parts"`
+	}
+	if field == "messages" || field == "contents" {
+		if json.Unmarshal(raw, &messages) != nil {
+			return
+		}
+		for _, m := range messages {
+			content := m.Content
+			if content == nil {
+				content = m.Parts
+			}
+			if text := contentText(content); text != "" {
+				fmt.Fprintf(sb, "%s: %s\n", m.Role, text)
+			}
+		}
+		return
+	}
+
+	if field == "systemInstruction" {
+		var instruction struct {
+			Parts json.RawMessage `json:"parts"`
+		}
+		if json.Unmarshal(raw, &instruction) == nil {
+			raw = instruction.Parts
+		}
+	}
+	if text := contentText(raw); text != "" {
+		fmt.Fprintf(sb, "%s: %s\n", field, text)
+	}
+}
+
+// contentText returns the text of a string or of an array of strings and text parts.
+func contentText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []json.RawMessage
+	if json.Unmarshal(raw, &parts) != nil {
+		return ""
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		var text struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(part, &s) == nil {
+			texts = append(texts, s)
+		} else if json.Unmarshal(part, &text) == nil && text.Text != "" {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// embedPrompt embeds text with the OpenAI-compatible embeddings endpoint of the named group
+// and returns the normalized vector.
+func (ps *ProxyServer) embedPrompt(ctx context.Context, groupName, model, text string) ([]float32, error) {
+	if groupName == "" {
+		return nil, errors.New("no semantic cache group configured")
+	}
+	group, err := ps.groupManager.GetGroupByName(groupName)
+	if err != nil {
+		return nil, err
+	}
+	channelHandler, err := ps.channelFactory.GetChannel(group)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, err := ps.keyProvider.SelectKey(group)
+	if err != nil {
+		return nil, err
+	}
+	upstreamURL, err := channelHandler.BuildUpstreamURL(&url.URL{Path: "/v1/embeddings"}, group.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]any{"model": model, "input": text})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(group.EffectiveConfig.RequestTimeout)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upstreamURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	channelHandler.ModifyRequest(req, apiKey, group)
+
+	resp, err := channelHandler.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings request failed with status %d: %s", resp.StatusCode, decompressErrorBody(resp, respBody))
+	}
+
+	var result struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Data) == 0 || len(result.Data[0].Embedding) == 0 {
+		return nil, errors.New("embeddings response has no vector")
+	}
+	return normalize(result.Data[0].Embedding), nil
+}
+
+// normalize scales v to unit length so the dot product of two vectors is their cosine similarity.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
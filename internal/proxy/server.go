@@ -15,10 +15,13 @@ import (
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/jsonengine"
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
+	"gpt-load/internal/pricing"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
+	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -27,13 +30,27 @@ import (
 
 // ProxyServer represents the proxy server
 type ProxyServer struct {
-	keyProvider       *keypool.KeyProvider
-	groupManager      *services.GroupManager
-	subGroupManager   *services.SubGroupManager
-	settingsManager   *config.SystemSettingsManager
-	channelFactory    *channel.Factory
-	requestLogService *services.RequestLogService
-	encryptionSvc     encryption.Service
+	keyProvider           *keypool.KeyProvider
+	groupManager          *services.GroupManager
+	subGroupManager       *services.SubGroupManager
+	settingsManager       *config.SystemSettingsManager
+	channelFactory        *channel.Factory
+	requestLogService     *services.RequestLogService
+	providerStatusService *services.ProviderStatusService
+	groupDebugService     *services.GroupDebugService
+	ruleMatches           *services.RuleMatchTracker
+	responseCache         *services.ResponseCacheService
+	budgetService         *services.BudgetService
+	auditLogService       *services.AuditLogService
+	encryptionSvc         encryption.Service
+	configManager         types.ConfigManager
+	admission             *tierAdmission
+	streams               *streamCounter
+	embeddings            *embeddingsBatcher
+	semanticIndex         *semanticIndex
+	retryBudget           *retryBudget
+	concurrency           *concurrencyLimits
+	pipeline              Handler
 }
 
 // NewProxyServer creates a new proxy server
@@ -44,30 +61,86 @@ func NewProxyServer(
 	settingsManager *config.SystemSettingsManager,
 	channelFactory *channel.Factory,
 	requestLogService *services.RequestLogService,
+	providerStatusService *services.ProviderStatusService,
+	groupDebugService *services.GroupDebugService,
+	ruleMatches *services.RuleMatchTracker,
+	responseCache *services.ResponseCacheService,
+	budgetService *services.BudgetService,
+	auditLogService *services.AuditLogService,
 	encryptionSvc encryption.Service,
+	configManager types.ConfigManager,
 ) (*ProxyServer, error) {
-	return &ProxyServer{
-		keyProvider:       keyProvider,
-		groupManager:      groupManager,
-		subGroupManager:   subGroupManager,
-		settingsManager:   settingsManager,
-		channelFactory:    channelFactory,
-		requestLogService: requestLogService,
-		encryptionSvc:     encryptionSvc,
-	}, nil
+	ps := &ProxyServer{
+		keyProvider:           keyProvider,
+		groupManager:          groupManager,
+		subGroupManager:       subGroupManager,
+		settingsManager:       settingsManager,
+		channelFactory:        channelFactory,
+		requestLogService:     requestLogService,
+		providerStatusService: providerStatusService,
+		groupDebugService:     groupDebugService,
+		ruleMatches:           ruleMatches,
+		responseCache:         responseCache,
+		budgetService:         budgetService,
+		auditLogService:       auditLogService,
+		encryptionSvc:         encryptionSvc,
+		configManager:         configManager,
+		admission:             newTierAdmission(),
+		streams:               newStreamCounter(),
+		embeddings:            newEmbeddingsBatcher(),
+		semanticIndex:         newSemanticIndex(),
+		retryBudget:           newRetryBudget(),
+		concurrency:           newConcurrencyLimits(),
+	}
+	groupManager.SetRuleEngineBuilder(ps.buildRuleEngine)
+
+	pipeline, err := ps.buildPipeline()
+	if err != nil {
+		return nil, err
+	}
+	ps.pipeline = pipeline
+	return ps, nil
 }
 
-// HandleProxy is the main entry point for proxy requests, refactored based on the stable .bak logic.
+// HandleProxy is the main entry point for proxy requests. It runs the request through
+// the middleware pipeline assembled in NewProxyServer.
 func (ps *ProxyServer) HandleProxy(c *gin.Context) {
-	startTime := time.Now()
-	groupName := c.Param("group_name")
+	ps.initRequestTrace(c)
+
+	ps.pipeline(&ProxyRequest{
+		Context:     c,
+		StartTime:   time.Now(),
+		GroupName:   c.Param("group_name"),
+		RequestPath: c.Param("path"),
+	})
+}
 
-	originalGroup, err := ps.groupManager.GetGroupByName(groupName)
+// admissionStage admits the request by proxy key tier.
+func (ps *ProxyServer) admissionStage(req *ProxyRequest, next Handler) {
+	release, admitted := ps.admitRequest(req.Context)
+	if !admitted {
+		return
+	}
+	defer release()
+	next(req)
+}
+
+// routeStage resolves the target group, picks a sub-group for aggregate groups and
+// selects the channel.
+func (ps *ProxyServer) routeStage(req *ProxyRequest, next Handler) {
+	c := req.Context
+	originalGroup, err := ps.groupManager.GetGroupByName(req.GroupName)
 	if err != nil {
 		response.Error(c, app_errors.ParseDBError(err))
 		return
 	}
 
+	requestPath := req.RequestPath
+	if !isPathAllowed(originalGroup, requestPath) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrResourceNotFound, fmt.Sprintf("Path '%s' is not allowed for group '%s'", requestPath, originalGroup.Name)))
+		return
+	}
+
 	// Select sub-group if this is an aggregate group
 	subGroupName, err := ps.subGroupManager.SelectSubGroup(originalGroup)
 	if err != nil {
@@ -86,38 +159,138 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 			response.Error(c, app_errors.ParseDBError(err))
 			return
 		}
+		if !isPathAllowed(group, requestPath) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrResourceNotFound, fmt.Sprintf("Path '%s' is not allowed for group '%s'", requestPath, group.Name)))
+			return
+		}
 	}
 
+	ps.initDebugCapture(c, originalGroup, group)
+
 	channelHandler, err := ps.channelFactory.GetChannel(group)
 	if err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to get channel for group '%s': %v", groupName, err)))
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to get channel for group '%s': %v", req.GroupName, err)))
 		return
 	}
 
-	bodyBytes, err := io.ReadAll(c.Request.Body)
+	req.OriginalGroup = originalGroup
+	req.Group = group
+	req.Channel = channelHandler
+	next(req)
+}
+
+// transformStage reads the request body and applies prompt templates, validation and
+// every body rewrite configured for the group.
+func (ps *ProxyServer) transformStage(req *ProxyRequest, next Handler) {
+	c, group := req.Context, req.Group
+	bodyBytes, spool, err := ps.readRequestBody(c, req.OriginalGroup, group)
 	if err != nil {
 		logrus.Errorf("Failed to read request body: %v", err)
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body"))
 		return
 	}
-	c.Request.Body.Close()
-
-	finalBodyBytes, err := ps.applyParamOverrides(bodyBytes, group)
-	if err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply parameter overrides: %v", err)))
+	if spool != nil {
+		ps.transformSpooledBody(req, spool, next)
 		return
 	}
 
-	// Apply inbound rules (request body transformation)
-	finalBodyBytes, err = ps.applyInboundRules(finalBodyBytes, group)
-	if err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply inbound rules: %v", err)))
+	// Signed requests (e.g. SigV4) must reach the upstream byte-for-byte, so every body rewrite is skipped
+	passthrough := group.EffectiveConfig.SignedRequestPassthrough
+
+	// Expand named prompt templates before validation so the rendered messages are checked
+	if !passthrough {
+		bodyBytes, err = ps.applyPromptTemplate(bodyBytes, req.OriginalGroup, group)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			return
+		}
+	}
+
+	setRuleModel(c, req.Channel.ExtractModel(c, bodyBytes))
+
+	// Reject malformed requests before they consume a key and an upstream call
+	if group.EffectiveConfig.EnableRequestValidation {
+		if endpointClass, issues := validateRequestBody(req.RequestPath, bodyBytes); len(issues) > 0 {
+			writeValidationError(c, endpointClass, issues)
+			return
+		}
+	}
+
+	finalBodyBytes := bodyBytes
+	if !passthrough {
+		finalBodyBytes = ps.applyModelDeprecation(c, bodyBytes, group)
+
+		finalBodyBytes, err = ps.applyParamOverrides(finalBodyBytes, group)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply parameter overrides: %v", err)))
+			return
+		}
+
+		finalBodyBytes, err = ps.applySeedInjection(finalBodyBytes, group, req.RequestPath)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to inject seed: %v", err)))
+			return
+		}
+
+		// Apply inbound rules (request body transformation)
+		finalBodyBytes, err = ps.applyInboundRules(finalBodyBytes, group, ruleScope(c))
+		var limitErr *jsonengine.LimitError
+		var schemaErr *jsonengine.SchemaError
+		if errors.As(err, &limitErr) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("Request body exceeds limits: %v", err)))
+			return
+		}
+		if errors.As(err, &schemaErr) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("Request body does not match the inbound schema: %v", err)))
+			return
+		}
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply inbound rules: %v", err)))
+			return
+		}
+
+		finalBodyBytes = applyInboundScript(c, finalBodyBytes, group)
+	}
+
+	req.Body = bodyBytes
+	req.FinalBody = finalBodyBytes
+	req.Passthrough = passthrough
+	req.IsStream = req.Channel.IsStreamRequest(c, bodyBytes)
+	next(req)
+}
+
+// streamCapStage enforces the group's concurrent stream limit on streaming requests.
+func (ps *ProxyServer) streamCapStage(req *ProxyRequest, next Handler) {
+	if !req.IsStream {
+		next(req)
 		return
 	}
+	finalBodyBytes, isStream, release, admitted := ps.admitStream(req.Context, req.Group, req.FinalBody, req.Passthrough)
+	if !admitted {
+		return
+	}
+	defer release()
+	req.FinalBody, req.IsStream = finalBodyBytes, isStream
+	next(req)
+}
 
-	isStream := channelHandler.IsStreamRequest(c, bodyBytes)
+// captureStage records the inbound and transformed bodies for debug capture.
+func (ps *ProxyServer) captureStage(req *ProxyRequest, next Handler) {
+	if requestBodySpool(req.Context) != nil {
+		logCapturedBody(req.Context, "inbound", requestBodyForLog(req.Context, nil))
+		next(req)
+		return
+	}
+	logCapturedBody(req.Context, "inbound", req.Body)
+	if !bytes.Equal(req.FinalBody, req.Body) {
+		logCapturedBody(req.Context, "transformed", req.FinalBody)
+	}
+	next(req)
+}
 
-	ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, finalBodyBytes, isStream, startTime, 0)
+// forwardStage sends the request upstream, retrying with other keys on failure.
+func (ps *ProxyServer) forwardStage(req *ProxyRequest) {
+	ps.executeRequestWithRetry(req.Context, req.Channel, req.OriginalGroup, req.Group, req.FinalBody, req.IsStream, req.StartTime, 0)
 }
 
 // executeRequestWithRetry is the core recursive function for handling requests and retries.
@@ -132,15 +305,33 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	retryCount int,
 ) {
 	cfg := group.EffectiveConfig
+	maxRetries := retryLimit(c.Request, cfg)
+	attemptStart := time.Now()
+	if retryCount == 0 && cfg.RetryBudgetPercent > 0 {
+		ps.retryBudget.deposit(group.Name, cfg.RetryBudgetPercent)
+	}
 
-	apiKey, err := ps.keyProvider.SelectKey(group.ID)
+	cacheRef := channelHandler.ExtractCacheReference(c, bodyBytes)
+	apiKey, err := ps.selectKey(group, cacheRef, retryCount)
 	if err != nil {
 		logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, retryCount+1, err)
+		recordAttempt(c, group, nil, http.StatusServiceUnavailable, err.Error(), attemptStart)
+		writeTraceHeaders(c)
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
 		ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusServiceUnavailable, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
 		return
 	}
 
+	releaseKeySlot, err := ps.concurrency.acquire(c, group, concurrencyScopeKey, apiKey.ID, cfg.KeyMaxConcurrentRequests)
+	if err != nil {
+		recordAttempt(c, group, apiKey, http.StatusTooManyRequests, err.Error(), attemptStart)
+		writeTraceHeaders(c)
+		writeConcurrencyError(c, group, concurrencyScopeKey, err)
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusTooManyRequests, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+		return
+	}
+	defer releaseKeySlot()
+
 	upstreamURL, err := channelHandler.BuildUpstreamURL(c.Request.URL, originalGroup.Name)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to build upstream URL: %v", err)))
@@ -157,13 +348,14 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	}
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, c.Request.Method, upstreamURL, bytes.NewReader(bodyBytes))
+	body, bodySize := upstreamBody(c, bodyBytes)
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, upstreamURL, body)
 	if err != nil {
 		logrus.Errorf("Failed to create upstream request: %v", err)
 		response.Error(c, app_errors.ErrInternalServer)
 		return
 	}
-	req.ContentLength = int64(len(bodyBytes))
+	req.ContentLength = bodySize
 
 	req.Header = c.Request.Header.Clone()
 
@@ -171,18 +363,26 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	req.Header.Del("Authorization")
 	req.Header.Del("X-Api-Key")
 	req.Header.Del("X-Goog-Api-Key")
+	for _, name := range originalGroup.EffectiveConfig.ProxyKeyHeaderList {
+		req.Header.Del(name)
+	}
+
+	passthrough := group.EffectiveConfig.SignedRequestPassthrough
 
-	// Disable compression when outbound rules are configured (to avoid decompression overhead)
-	if len(group.OutboundRuleList) > 0 {
+	// Disable compression when the response body will be rewritten (to avoid decompression overhead)
+	if rewritesResponseBody(group) {
 		req.Header.Del("Accept-Encoding")
 	}
 
 	// Apply model redirection
-	finalBodyBytes, err := channelHandler.ApplyModelRedirect(req, bodyBytes, group)
-	if err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
-		ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
-		return
+	finalBodyBytes := bodyBytes
+	if !passthrough {
+		finalBodyBytes, err = channelHandler.ApplyModelRedirect(req, bodyBytes, group)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
 	}
 
 	// Update request body if it was modified by redirection
@@ -194,9 +394,9 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	channelHandler.ModifyRequest(req, apiKey, group)
 
 	// Apply custom header rules
-	if len(group.HeaderRuleList) > 0 {
+	if !passthrough && len(group.HeaderRuleList) > 0 {
 		headerCtx := utils.NewHeaderVariableContextFromGin(c, group, apiKey)
-		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+		utils.ApplyHeaderRules(req, group.HeaderRulesFor(ruleScope(c)), headerCtx)
 	}
 
 	var client *http.Client
@@ -207,15 +407,19 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		client = channelHandler.GetHTTPClient()
 	}
 
+	release := ps.keyProvider.BeginRequest(apiKey.ID)
+	defer release()
+	sentAt := time.Now()
 	resp, err := client.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
+		ps.keyProvider.RecordLatency(apiKey.ID, time.Since(sentAt))
 	}
 
 	// Unified error handling for retries. Exclude 404 from being a retryable error.
 	if err != nil || (resp != nil && resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
 		if err != nil && app_errors.IsIgnorableError(err) {
-			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
+			requestLogger(c).Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
 			ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
 			return
 		}
@@ -228,7 +432,7 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			statusCode = 500
 			errorMessage = err.Error()
 			parsedError = errorMessage
-			logrus.Debugf("Request failed (attempt %d/%d) for key %s: %v", retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), err)
+			requestLogger(c).Debugf("Request failed (attempt %d/%d) for key %s: %v", retryCount+1, maxRetries, utils.MaskAPIKey(apiKey.KeyValue), err)
 		} else {
 			// HTTP-level error (status >= 400)
 			statusCode = resp.StatusCode
@@ -238,26 +442,43 @@ func (ps *ProxyServer) executeRequestWithRetry(
 				errorBody = []byte("Failed to read error body")
 			}
 
-			errorBody = handleGzipCompression(resp, errorBody)
+			errorBody = decompressErrorBody(resp, errorBody)
 			errorMessage = string(errorBody)
 			parsedError = app_errors.ParseUpstreamError(errorBody)
-			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
+			requestLogger(c).Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, maxRetries, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
 		}
 
-		// 使用解析后的错误信息更新密钥状态
-		ps.keyProvider.UpdateStatus(apiKey, group, false, parsedError)
+		rateLimit := ps.observeRateLimit(group, apiKey, resp)
+		ps.recordCircuitResult(group, apiKey, upstreamURL, resp, err)
 
-		// 判断是否为最后一次尝试
-		isLastAttempt := retryCount >= cfg.MaxRetries
+		// 使用解析后的错误信息更新密钥状态，按上游限流头冷却的密钥不计入失败次数
+		if !ps.coolDownRateLimitedKey(group, apiKey, resp) {
+			ps.keyProvider.UpdateStatus(apiKey, ps.providerStatusService.FailoverGroup(group), false, parsedError)
+		}
+
+		// 判断是否为最后一次尝试：达到重试上限，或重试策略不允许重试
+		var retryDelay time.Duration
+		isLastAttempt := true
+		if retryCount < maxRetries {
+			var retry bool
+			retryDelay, retry = ps.planRetry(c.Request, group, resp, retryCount)
+			isLastAttempt = !retry
+		}
 		requestType := models.RequestTypeRetry
 		if isLastAttempt {
 			requestType = models.RequestTypeFinal
 		}
 
 		ps.logRequest(c, originalGroup, group, apiKey, startTime, statusCode, errors.New(parsedError), isStream, upstreamURL, channelHandler, bodyBytes, requestType)
+		recordAttempt(c, group, apiKey, statusCode, parsedError, attemptStart)
 
 		// 如果是最后一次尝试，直接返回错误，不再递归
 		if isLastAttempt {
+			writeTraceHeaders(c)
+			ps.writeRateLimitHeaders(c, group, rateLimit)
+			if resp != nil {
+				errorMessage = string(applyErrorOutboundRules(group, ruleScope(c), []byte(errorMessage)))
+			}
 			var errorJSON map[string]any
 			if err := json.Unmarshal([]byte(errorMessage), &errorJSON); err == nil {
 				c.JSON(statusCode, errorJSON)
@@ -267,12 +488,35 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			return
 		}
 
+		// 重试前退避，客户端已断开时不再重试
+		release()
+		releaseKeySlot()
+		if !waitRetry(c.Request.Context(), retryDelay) {
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, c.Request.Context().Err(), isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+
 		ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1)
 		return
 	}
 
 	// ps.keyProvider.UpdateStatus(apiKey, group, true) // 请求成功不再重置成功次数，减少IO消耗
-	logrus.Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
+	requestLogger(c).Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
+	recordAttempt(c, group, apiKey, resp.StatusCode, "", attemptStart)
+	ps.recordCircuitResult(group, apiKey, upstreamURL, resp, nil)
+	writeTraceHeaders(c)
+
+	// Capture token usage for the request log, budgets and the fair usage key scheduler
+	usageWriter := newUsageCaptureWriter(c.Writer)
+	c.Writer = usageWriter
+	defer func() {
+		usageWriter.finish()
+		c.Writer = usageWriter.ResponseWriter
+	}()
+	restoreAuditWriter := installAuditWriter(c, group)
+	defer restoreAuditWriter()
+
+	rateLimit := ps.observeRateLimit(group, apiKey, resp)
 
 	// Check if this is a model list request (needs special handling)
 	if shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
@@ -283,15 +527,35 @@ func (ps *ProxyServer) executeRequestWithRetry(
 				c.Header(key, value)
 			}
 		}
+		ps.writeRateLimitHeaders(c, group, rateLimit)
 		c.Status(resp.StatusCode)
 
-		if isStream {
-			ps.handleStreamingResponse(c, resp, group)
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			// 非 2xx 响应（如 404）只应用错误响应规则，避免成功响应的规则误改错误内容
+			ps.handleErrorResponse(c, resp, group)
+		} else if isStream {
+			ps.handleStreamingResponse(c, resp, group, upstreamModel(req, finalBodyBytes))
+		} else if channelHandler.IsCacheCreateRequest(c) {
+			ps.handleCacheCreateResponse(c, resp, group, apiKey, channelHandler)
 		} else {
-			ps.handleNormalResponse(c, resp, group)
+			ps.handleNormalResponse(c, resp, group, upstreamModel(req, finalBodyBytes))
 		}
 	}
 
+	ps.updateCacheBinding(c, group, apiKey, cacheRef, resp.StatusCode)
+
+	requestUsage := usageWriter.usage(c, bodyBytes)
+	if cfg.KeySelectionStrategy == keypool.KeySelectionFairUsage {
+		ps.keyProvider.RecordUsage(group, apiKey.ID, requestUsage.TotalTokens)
+	}
+	price, priced := pricing.Lookup(upstreamModel(req, finalBodyBytes), cfg.ModelPrices)
+	// A batched embeddings call is charged to each client for its own inputs
+	requestUsage = shareEmbeddingsBatch(c, requestUsage, price, priced, apiKey, upstreamURL)
+	setRequestUsage(c, requestUsage)
+	if priced {
+		setRequestCost(c, price.Cost(requestUsage))
+	}
+
 	ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
 }
 
@@ -316,8 +580,8 @@ func (ps *ProxyServer) logRequest(
 
 	var requestBodyToLog, userAgent string
 
-	if group.EffectiveConfig.EnableRequestBodyLogging {
-		requestBodyToLog = utils.TruncateString(string(bodyBytes), 65000)
+	if group.EffectiveConfig.EnableRequestBodyLogging || shouldCaptureBody(c) {
+		requestBodyToLog = utils.TruncateString(string(requestBodyForLog(c, bodyBytes)), 65000)
 		userAgent = c.Request.UserAgent()
 	}
 
@@ -336,6 +600,7 @@ func (ps *ProxyServer) logRequest(
 		IsStream:     isStream,
 		UpstreamAddr: utils.TruncateString(upstreamAddr, 500),
 		RequestBody:  requestBodyToLog,
+		Region:       ps.configManager.GetEffectiveServerConfig().Region,
 	}
 
 	// Set parent group
@@ -360,12 +625,23 @@ func (ps *ProxyServer) logRequest(
 		// 添加 KeyHash 用于反查
 		logEntry.KeyHash = ps.encryptionSvc.Hash(apiKey.KeyValue)
 	}
+	logEntry.ProxyKeyHash = ps.proxyKeyHash(c)
 
 	if finalError != nil {
 		logEntry.ErrorMessage = finalError.Error()
+	} else if requestUsage, ok := getRequestUsage(c); ok {
+		logEntry.PromptTokens = requestUsage.PromptTokens
+		logEntry.CompletionTokens = requestUsage.CompletionTokens
+		logEntry.TotalTokens = requestUsage.TotalTokens
+		logEntry.UsageEstimated = requestUsage.Estimated
+		logEntry.Cost, _ = getRequestCost(c)
 	}
 
 	if err := ps.requestLogService.Record(logEntry); err != nil {
 		logrus.Errorf("Failed to record request log: %v", err)
 	}
+
+	if requestType == models.RequestTypeFinal && group.EffectiveConfig.AuditLogEnabled {
+		ps.recordAudit(c, group, logEntry, bodyBytes)
+	}
 }
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	streamOverflowReject    = "reject"
+	streamOverflowDowngrade = "downgrade"
+
+	headerStreamDowngraded = "X-Gpt-Load-Stream-Downgraded"
+)
+
+var (
+	groupActiveStreams = metrics.NewGauge(
+		"gpt_load_group_active_streams",
+		"Streaming proxy requests currently in flight, by group.",
+		"group",
+	)
+	streamOverflowTotal = metrics.NewCounter(
+		"gpt_load_stream_overflow_total",
+		"Streaming requests beyond the group's concurrent stream limit, by group and action (reject, downgrade).",
+		"group", "action",
+	)
+)
+
+// streamCounter 按分组统计在途的流式请求
+type streamCounter struct {
+	mu     sync.Mutex
+	active map[string]int
+}
+
+func newStreamCounter() *streamCounter {
+	return &streamCounter{active: make(map[string]int)}
+}
+
+// tryAcquire 分组在途流数低于 limit 时占用一个槽位，limit 为 0 表示不限制
+func (s *streamCounter) tryAcquire(group string, limit int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit > 0 && s.active[group] >= limit {
+		return false
+	}
+	s.active[group]++
+	return true
+}
+
+func (s *streamCounter) release(group string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active[group] <= 1 {
+		delete(s.active, group)
+		return
+	}
+	s.active[group]--
+}
+
+// admitStream 按分组并发流上限准入流式请求
+// 超出上限时按 stream_overflow_action 处理：reject 写出 429 并返回 ok=false；
+// downgrade 将请求改写为非流式，返回改写后的请求体和 isStream=false。
+// 占用槽位时必须调用返回的 release。
+func (ps *ProxyServer) admitStream(c *gin.Context, group *models.Group, bodyBytes []byte, passthrough bool) (body []byte, isStream bool, release func(), ok bool) {
+	limit := group.EffectiveConfig.MaxConcurrentStreams
+	if ps.streams.tryAcquire(group.Name, limit) {
+		groupActiveStreams.Add(1, group.Name)
+		return bodyBytes, true, func() {
+			groupActiveStreams.Add(-1, group.Name)
+			ps.streams.release(group.Name)
+		}, true
+	}
+
+	// 签名透传的请求体不能改写，只能拒绝
+	if group.EffectiveConfig.StreamOverflowAction == streamOverflowDowngrade && !passthrough {
+		downgraded, err := downgradeStreamRequest(c, bodyBytes)
+		if err == nil {
+			streamOverflowTotal.Inc(group.Name, streamOverflowDowngrade)
+			c.Header(headerStreamDowngraded, "true")
+			return downgraded, false, func() {}, true
+		}
+		logrus.WithFields(logrus.Fields{
+			"group": group.Name,
+			"error": err,
+		}).Debug("Failed to downgrade streaming request, rejecting")
+	}
+
+	streamOverflowTotal.Inc(group.Name, streamOverflowReject)
+	c.Header("Retry-After", "1")
+	response.Error(c, app_errors.ErrTooManyStreams)
+	return nil, false, nil, false
+}
+
+// downgradeStreamRequest 将流式请求改写为非流式请求
+// OpenAI/Anthropic 风格的请求关闭 stream 并移除 stream_options；
+// Gemini 原生请求将 :streamGenerateContent 改为 :generateContent 并去掉 alt=sse
+func downgradeStreamRequest(c *gin.Context, bodyBytes []byte) ([]byte, error) {
+	body := bodyBytes
+	if len(bodyBytes) > 0 {
+		var requestData map[string]any
+		if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
+			return nil, err
+		}
+		if _, exists := requestData["stream"]; exists {
+			requestData["stream"] = false
+			delete(requestData, "stream_options")
+			var err error
+			if body, err = json.Marshal(requestData); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	u := c.Request.URL
+	if strings.HasSuffix(u.Path, ":streamGenerateContent") {
+		u.Path = strings.TrimSuffix(u.Path, ":streamGenerateContent") + ":generateContent"
+		u.RawPath = ""
+	}
+	if q := u.Query(); q.Has("alt") || q.Has("stream") {
+		if q.Get("alt") == "sse" {
+			q.Del("alt")
+		}
+		q.Del("stream")
+		u.RawQuery = q.Encode()
+	}
+	if strings.Contains(c.Request.Header.Get("Accept"), "text/event-stream") {
+		c.Request.Header.Set("Accept", "application/json")
+	}
+	return body, nil
+}
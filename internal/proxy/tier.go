@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/metrics"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	tierRequestsTotal = metrics.NewCounter(
+		"gpt_load_tier_requests_total",
+		"Proxy requests by proxy key tier and admission result (admitted, queued, rejected).",
+		"tier", "result",
+	)
+	tierInFlight = metrics.NewGauge(
+		"gpt_load_tier_in_flight_requests",
+		"Proxy requests currently in flight, by proxy key tier.",
+		"tier",
+	)
+	tierQueueWaitSeconds = metrics.NewCounter(
+		"gpt_load_tier_queue_wait_seconds_total",
+		"Time requests spent queued for admission, by proxy key tier.",
+		"tier",
+	)
+)
+
+// tierAdmission 按代理密钥等级控制准入
+// 在途请求达到并发上限中未预留的部分后视为饱和：interactive 请求继续进入预留容量，
+// batch 请求排队等待或直接拒绝
+type tierAdmission struct {
+	mu       sync.Mutex
+	inFlight int
+	waiting  int
+	released chan struct{} // 有请求结束且有排队请求时关闭，唤醒排队的请求
+}
+
+func newTierAdmission() *tierAdmission {
+	return &tierAdmission{released: make(chan struct{})}
+}
+
+// proxyKeyTier 返回 ProxyAuth 识别出的代理密钥等级
+func proxyKeyTier(c *gin.Context) string {
+	if tier := c.GetString("proxyKeyTier"); tier != "" {
+		return tier
+	}
+	return models.ProxyKeyTierInteractive
+}
+
+// batchLimit 返回 batch 请求可进入的在途请求上限，以及最多排队的 batch 请求数
+// 排队的请求同样占用全局并发槽位，因此队列长度限制为预留容量的一半，保证 interactive 请求始终有空位
+func (ps *ProxyServer) batchLimit() (limit, maxWaiting int, enabled bool) {
+	reserve := min(ps.settingsManager.GetSettings().InteractiveReservePercent, 100)
+	if reserve <= 0 {
+		return 0, 0, false
+	}
+	capacity := ps.configManager.GetPerformanceConfig().MaxConcurrentRequests
+	reserved := max(capacity*reserve/100, 1)
+	return capacity - reserved, reserved / 2, true
+}
+
+// admitRequest 按代理密钥等级准入当前请求，被拒绝时写出错误响应并返回 false
+// 准入成功后必须调用返回的 release
+func (ps *ProxyServer) admitRequest(c *gin.Context) (func(), bool) {
+	tier := proxyKeyTier(c)
+	a := ps.admission
+
+	admitted := func(result string) (func(), bool) {
+		tierRequestsTotal.Inc(tier, result)
+		tierInFlight.Add(1, tier)
+		return func() {
+			tierInFlight.Add(-1, tier)
+			a.release()
+		}, true
+	}
+
+	limit, maxWaiting, enabled := ps.batchLimit()
+	if tier != models.ProxyKeyTierBatch || !enabled {
+		a.acquire()
+		return admitted("admitted")
+	}
+	if a.tryAcquire(limit) {
+		return admitted("admitted")
+	}
+
+	timeout := time.Duration(ps.settingsManager.GetSettings().BatchQueueTimeoutMs) * time.Millisecond
+	if timeout > 0 && a.enqueue(maxWaiting) {
+		start := time.Now()
+		acquired := a.wait(c.Request.Context(), limit, timeout)
+		tierQueueWaitSeconds.Add(time.Since(start).Seconds(), tier)
+		if acquired {
+			return admitted("queued")
+		}
+	}
+
+	tierRequestsTotal.Inc(tier, "rejected")
+	c.Header("Retry-After", "1")
+	response.Error(c, app_errors.ErrServerBusy)
+	return nil, false
+}
+
+func (a *tierAdmission) acquire() {
+	a.mu.Lock()
+	a.inFlight++
+	a.mu.Unlock()
+}
+
+// tryAcquire 在途请求低于 limit 时准入
+func (a *tierAdmission) tryAcquire(limit int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.inFlight >= limit {
+		return false
+	}
+	a.inFlight++
+	return true
+}
+
+// enqueue 队列未满时占用一个排队位置
+func (a *tierAdmission) enqueue(maxWaiting int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.waiting >= maxWaiting {
+		return false
+	}
+	a.waiting++
+	return true
+}
+
+// wait 排队等待在途请求低于 limit，超时或客户端断开时返回 false；返回时释放排队位置
+func (a *tierAdmission) wait(ctx context.Context, limit int, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	defer func() {
+		a.mu.Lock()
+		a.waiting--
+		a.mu.Unlock()
+	}()
+
+	for {
+		a.mu.Lock()
+		if a.inFlight < limit {
+			a.inFlight++
+			a.mu.Unlock()
+			return true
+		}
+		released := a.released
+		a.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (a *tierAdmission) release() {
+	a.mu.Lock()
+	a.inFlight--
+	if a.waiting > 0 {
+		close(a.released)
+		a.released = make(chan struct{})
+	}
+	a.mu.Unlock()
+}
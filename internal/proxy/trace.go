@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// debugTraceRequestHeader 开启重试链路追踪的请求头，值必须为管理员 AUTH_KEY
+	debugTraceRequestHeader = "X-Debug-Trace"
+	// debugTraceResponseHeader 响应头，每次尝试输出一条记录
+	debugTraceResponseHeader = "X-Debug-Attempt"
+
+	traceContextKey      = "proxyTrace"
+	maxTraceReasonLength = 200
+)
+
+// attemptTrace 单次上游尝试的摘要
+type attemptTrace struct {
+	group    string
+	key      string
+	status   int
+	reason   string
+	duration time.Duration
+}
+
+// requestTrace 一个代理请求的完整尝试链路
+type requestTrace struct {
+	attempts []attemptTrace
+	written  bool
+}
+
+// initRequestTrace 检查调试请求头，仅当其值与管理员密钥一致时开启追踪
+// 调试头在任何情况下都不会转发到上游
+func (ps *ProxyServer) initRequestTrace(c *gin.Context) {
+	token := c.GetHeader(debugTraceRequestHeader)
+	if token == "" {
+		return
+	}
+	c.Request.Header.Del(debugTraceRequestHeader)
+
+	adminKey := ps.configManager.GetAuthConfig().Key
+	if adminKey == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminKey)) != 1 {
+		return
+	}
+	c.Set(traceContextKey, &requestTrace{})
+}
+
+// getRequestTrace 返回当前请求的追踪记录，未开启时返回 nil
+func getRequestTrace(c *gin.Context) *requestTrace {
+	value, exists := c.Get(traceContextKey)
+	if !exists {
+		return nil
+	}
+	trace, _ := value.(*requestTrace)
+	return trace
+}
+
+// recordAttempt 记录一次上游尝试
+func recordAttempt(c *gin.Context, group *models.Group, apiKey *models.APIKey, statusCode int, reason string, attemptStart time.Time) {
+	trace := getRequestTrace(c)
+	if trace == nil {
+		return
+	}
+
+	var maskedKey string
+	if apiKey != nil {
+		maskedKey = utils.MaskAPIKey(apiKey.KeyValue)
+	}
+
+	trace.attempts = append(trace.attempts, attemptTrace{
+		group:    group.Name,
+		key:      maskedKey,
+		status:   statusCode,
+		reason:   reason,
+		duration: time.Since(attemptStart),
+	})
+}
+
+// writeTraceHeaders 将尝试链路写入响应头，必须在写出响应状态码之前调用
+func writeTraceHeaders(c *gin.Context) {
+	trace := getRequestTrace(c)
+	if trace == nil || trace.written {
+		return
+	}
+	trace.written = true
+
+	header := c.Writer.Header()
+	for i, attempt := range trace.attempts {
+		header.Add(debugTraceResponseHeader, attempt.format(i+1))
+	}
+}
+
+// format 生成单条尝试记录，例如:
+// 1; group=openai; key=sk-a****wxyz; status=429; latency_ms=153; reason=rate limit exceeded
+func (a attemptTrace) format(index int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d; group=%s; key=%s; status=%d; latency_ms=%d", index, a.group, a.key, a.status, a.duration.Milliseconds())
+	if a.reason != "" {
+		reason := strings.Join(strings.Fields(a.reason), " ")
+		sb.WriteString("; reason=")
+		sb.WriteString(utils.TruncateString(reason, maxTraceReasonLength))
+	}
+	return sb.String()
+}
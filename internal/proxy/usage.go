@@ -0,0 +1,219 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/usage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	upstreamUsageContextKey = "upstreamUsage"
+	requestUsageContextKey  = "requestUsage"
+	requestCostContextKey   = "requestCost"
+)
+
+// usageCaptureRules collect the upstream model and token usage in the same pass
+// that applies the outbound rules, so large bodies are not parsed a second time.
+var usageCaptureRules = []jsonengine.PathRule{
+	{Path: "model", Action: jsonengine.ActionCapture},                                                // OpenAI, Anthropic
+	{Path: "modelVersion", Action: jsonengine.ActionCapture, Value: "model"},                         // Gemini
+	{Path: "usage.total_tokens", Action: jsonengine.ActionCapture, Value: "total_tokens"},            // OpenAI
+	{Path: "usageMetadata.totalTokenCount", Action: jsonengine.ActionCapture, Value: "total_tokens"}, // Gemini
+	{Path: "usage.input_tokens", Action: jsonengine.ActionCapture, Value: "input_tokens"},            // Anthropic
+	{Path: "usage.output_tokens", Action: jsonengine.ActionCapture, Value: "output_tokens"},          // Anthropic
+}
+
+// upstreamUsage is the model and token usage reported in the upstream response body.
+type upstreamUsage struct {
+	Model       string
+	TotalTokens int64
+}
+
+// usageCaptureWriter passes the response body through a usage accumulator so the token
+// usage of the request is known once the response was streamed to the client. Bodies passed
+// through compressed, as when the client's Accept-Encoding was forwarded upstream, are decoded
+// for the accumulator by a background reader while the compressed bytes go to the client.
+type usageCaptureWriter struct {
+	gin.ResponseWriter
+	acc     usage.Accumulator
+	started bool
+	decoder *io.PipeWriter // feeds the decoding reader, nil for uncompressed bodies
+	done    chan struct{}  // closed once the decoding reader has finished
+}
+
+func newUsageCaptureWriter(w gin.ResponseWriter) *usageCaptureWriter {
+	return &usageCaptureWriter{ResponseWriter: w}
+}
+
+func (w *usageCaptureWriter) Write(data []byte) (int, error) {
+	w.account(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *usageCaptureWriter) WriteString(s string) (int, error) {
+	w.account([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// account adds a part of the body to the accumulator, through the decoder when the body sent
+// to the client is compressed. The Content-Encoding is read at the first write, once the
+// response headers are final.
+func (w *usageCaptureWriter) account(data []byte) {
+	if !w.started {
+		w.started = true
+		if open, ok := responseDecoders[responseContentEncoding(w.Header())]; ok {
+			w.startDecoder(open)
+		}
+	}
+	if w.decoder != nil {
+		_, _ = w.decoder.Write(data)
+		return
+	}
+	_, _ = w.acc.Write(data)
+}
+
+// startDecoder decodes the body written to the pipe into the accumulator. A body that fails
+// to decode is drained so writes to the pipe never block.
+func (w *usageCaptureWriter) startDecoder(open func(io.Reader) (io.ReadCloser, error)) {
+	pr, pw := io.Pipe()
+	w.decoder, w.done = pw, make(chan struct{})
+	go func() {
+		defer close(w.done)
+		defer func() { _, _ = io.Copy(io.Discard, pr) }()
+		decoded, err := open(pr)
+		if err != nil {
+			return
+		}
+		defer decoded.Close()
+		_, _ = io.Copy(&w.acc, decoded)
+	}()
+}
+
+// finish waits for the decoder to account for the whole body. It is safe to call repeatedly.
+func (w *usageCaptureWriter) finish() {
+	if w.decoder == nil {
+		return
+	}
+	_ = w.decoder.Close()
+	<-w.done
+}
+
+// usage returns the token usage reported by the upstream or, when it reported none, the
+// estimate from the request body and the generated text. Outbound rules may remove the usage
+// block from the body sent to the client; the total captured from the upstream body is used
+// then, with the prompt share estimated.
+func (w *usageCaptureWriter) usage(c *gin.Context, requestBody []byte) usage.Usage {
+	w.finish()
+	if reported, ok := w.acc.Reported(); ok {
+		return reported
+	}
+	if captured := getUpstreamUsage(c); captured != nil && captured.TotalTokens > 0 {
+		prompt := min(usage.EstimatePromptTokens(requestBody), captured.TotalTokens)
+		return usage.Usage{
+			PromptTokens:     prompt,
+			CompletionTokens: captured.TotalTokens - prompt,
+			TotalTokens:      captured.TotalTokens,
+			Images:           w.acc.Images(),
+		}
+	}
+
+	estimate := w.acc.Result(requestBody)
+	if spool := requestBodySpool(c); spool != nil {
+		// Spooled bodies are not kept in memory; count four bytes per token
+		estimate.PromptTokens = spool.size / 4
+		estimate.TotalTokens = estimate.PromptTokens + estimate.CompletionTokens
+	}
+	return estimate
+}
+
+// setRequestUsage stores the token usage of the request on the context for the request log
+// and the stages that account for it.
+func setRequestUsage(c *gin.Context, u usage.Usage) {
+	c.Set(requestUsageContextKey, u)
+}
+
+// getRequestUsage returns the token usage of a request that was answered by the upstream.
+func getRequestUsage(c *gin.Context) (usage.Usage, bool) {
+	value, exists := c.Get(requestUsageContextKey)
+	if !exists {
+		return usage.Usage{}, false
+	}
+	u, ok := value.(usage.Usage)
+	return u, ok
+}
+
+// setRequestCost stores the cost of the request, priced from the model_prices table.
+func setRequestCost(c *gin.Context, cost float64) {
+	c.Set(requestCostContextKey, cost)
+}
+
+// getRequestCost returns the cost of the request, or false when its model has no price.
+func getRequestCost(c *gin.Context) (float64, bool) {
+	value, exists := c.Get(requestCostContextKey)
+	if !exists {
+		return 0, false
+	}
+	cost, ok := value.(float64)
+	return cost, ok
+}
+
+// withUsageCaptures appends the usage capture rules to the outbound rules.
+// The captures are skipped when their exact paths would take a more specific
+// branch than a wildcard group rule, which would keep that rule from applying.
+func withUsageCaptures(rules []jsonengine.PathRule) []jsonengine.PathRule {
+	combined := append(rules[:len(rules):len(rules)], usageCaptureRules...)
+	if countShadowed(jsonengine.ValidateRules(combined), len(rules)) > countShadowed(jsonengine.ValidateRules(rules), len(rules)) {
+		return rules
+	}
+	return combined
+}
+
+// countShadowed counts the shadowed rules among the first n rules.
+func countShadowed(issues []jsonengine.RuleIssue, n int) int {
+	count := 0
+	for _, issue := range issues {
+		if issue.Code == jsonengine.IssueShadowed && issue.Rule < n {
+			count++
+		}
+	}
+	return count
+}
+
+// recordUpstreamUsage stores the values collected by the usage capture rules on the request context.
+func recordUpstreamUsage(c *gin.Context, captured map[string]json.RawMessage) {
+	if len(captured) == 0 {
+		return
+	}
+
+	usage := &upstreamUsage{}
+	_ = json.Unmarshal(captured["model"], &usage.Model)
+	if raw, ok := captured["total_tokens"]; ok {
+		usage.TotalTokens, _ = strconv.ParseInt(string(raw), 10, 64)
+	} else {
+		input, _ := strconv.ParseInt(string(captured["input_tokens"]), 10, 64)
+		output, _ := strconv.ParseInt(string(captured["output_tokens"]), 10, 64)
+		usage.TotalTokens = input + output
+	}
+	c.Set(upstreamUsageContextKey, usage)
+
+	requestLogger(c).WithFields(logrus.Fields{
+		"upstream_model": usage.Model,
+		"total_tokens":   usage.TotalTokens,
+	}).Debug("Captured upstream usage")
+}
+
+// getUpstreamUsage returns the usage captured from the upstream response, or nil.
+func getUpstreamUsage(c *gin.Context) *upstreamUsage {
+	value, exists := c.Get(upstreamUsageContextKey)
+	if !exists {
+		return nil
+	}
+	usage, _ := value.(*upstreamUsage)
+	return usage
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func gzipBody(t *testing.T, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUsageCaptureGzipUpstream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		contentType string
+		stream      bool
+		body        string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"model":"gpt-4o","choices":[{"message":{"content":"hi"}}],"usage":{"prompt_tokens":12,"completion_tokens":34,"total_tokens":46}}`,
+		},
+		{
+			name:        "event stream",
+			contentType: "text/event-stream",
+			stream:      true,
+			body: "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n" +
+				"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":34,\"total_tokens\":46}}\n\n" +
+				"data: [DONE]\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed := gzipBody(t, tt.body)
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			c.Request = httptest.NewRequest(http.MethodPost, "/proxy/test/v1/chat/completions", nil)
+
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {tt.contentType}, "Content-Encoding": {"gzip"}},
+				Body:       io.NopCloser(bytes.NewReader(compressed)),
+			}
+			for key, values := range resp.Header {
+				for _, value := range values {
+					c.Header(key, value)
+				}
+			}
+
+			usageWriter := newUsageCaptureWriter(c.Writer)
+			c.Writer = usageWriter
+			ps := &ProxyServer{}
+			group := &models.Group{Name: "test"}
+			if tt.stream {
+				ps.handleStreamingResponse(c, resp, group, "gpt-4o")
+			} else {
+				ps.handleNormalResponse(c, resp, group, "gpt-4o")
+			}
+
+			if !bytes.Equal(rec.Body.Bytes(), compressed) {
+				t.Fatalf("client body was changed, want the compressed upstream body passed through")
+			}
+			got := usageWriter.usage(c, []byte(`{"messages":[{"role":"user","content":"hello"}]}`))
+			if got.PromptTokens != 12 || got.CompletionTokens != 34 || got.TotalTokens != 46 {
+				t.Errorf("usage = %+v, want the reported 12/34/46", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// watermark is the provenance metadata injected into JSON responses.
+type watermark struct {
+	Group     string `json:"group"`
+	Model     string `json:"model,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// buildWatermarkRule returns an outbound add rule that injects provenance metadata
+// into the configured top-level response field.
+func buildWatermarkRule(group *models.Group, upstreamModel string) (jsonengine.PathRule, bool) {
+	field := strings.TrimSpace(group.EffectiveConfig.ResponseWatermarkField)
+	if field == "" {
+		return jsonengine.PathRule{}, false
+	}
+
+	value, err := json.Marshal(watermark{
+		Group:     group.Name,
+		Model:     upstreamModel,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to build response watermark")
+		return jsonengine.PathRule{}, false
+	}
+
+	return jsonengine.PathRule{Path: field, Action: jsonengine.ActionAdd, ValueBytes: value}, true
+}
+
+// watermarkEngine returns the engine adding the response watermark of the group, or nil when
+// the group sets none. The watermark differs per request, so this single-rule engine is built
+// per response and runs after the group's cached outbound engine.
+func watermarkEngine(group *models.Group, upstreamModel string) *jsonengine.PathEngine {
+	rule, ok := buildWatermarkRule(group, upstreamModel)
+	if !ok {
+		return nil
+	}
+	engine, err := jsonengine.NewPathEngine([]jsonengine.PathRule{rule})
+	if err != nil {
+		logrus.WithError(err).WithField("group_name", group.Name).Warn("Failed to build response watermark")
+		return nil
+	}
+	return engine
+}
+
+// watermarkOutput returns a writer that adds the watermark to the JSON document written to it
+// and writes the result to output. finish must be called once the document is written; it
+// waits until the watermarked document was written to output.
+func watermarkOutput(engine *jsonengine.PathEngine, output io.Writer) (w io.Writer, finish func()) {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := engine.Process(pr, output); err != nil {
+			logUpstreamError("adding response watermark", err)
+		}
+		// Drain what the engine left unread so writes never block
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	return pw, func() {
+		_ = pw.Close()
+		<-done
+	}
+}
+
+// upstreamModel returns the model actually sent upstream, after any model redirect.
+// It reads the "model" body field first, then falls back to Gemini-style "/models/{model}:method" paths.
+func upstreamModel(req *http.Request, bodyBytes []byte) string {
+	if model, ok := jsonengine.Get(bodyBytes, "model"); ok && model.Type == jsonengine.ValueString {
+		if name := model.String(); name != "" {
+			return name
+		}
+	}
+
+	parts := strings.Split(req.URL.Path, "/")
+	for i, part := range parts {
+		if part == "models" && i+1 < len(parts) {
+			return strings.Split(parts[i+1], ":")[0]
+		}
+	}
+	return ""
+}
@@ -4,6 +4,7 @@ import (
 	"embed"
 	"gpt-load/internal/handler"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/metrics"
 	"gpt-load/internal/middleware"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
@@ -64,7 +65,7 @@ func NewRouter(
 	})
 
 	// 注册路由
-	registerSystemRoutes(router, serverHandler)
+	registerSystemRoutes(router, serverHandler, configManager)
 	registerAPIRoutes(router, serverHandler, configManager)
 	registerProxyRoutes(router, proxyServer, groupManager, serverHandler)
 	registerFrontendRoutes(router, buildFS, indexPage)
@@ -73,8 +74,9 @@ func NewRouter(
 }
 
 // registerSystemRoutes 注册系统级路由
-func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server) {
+func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server, configManager types.ConfigManager) {
 	router.GET("/health", serverHandler.Health)
+	router.GET("/metrics", middleware.Auth(configManager.GetAuthConfig()), metrics.Handler)
 }
 
 // registerAPIRoutes 注册API路由
@@ -101,11 +103,14 @@ func registerAPIRoutes(
 func registerPublicAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
 	api.POST("/auth/login", serverHandler.Login)
 	api.GET("/integration/info", serverHandler.GetIntegrationInfo)
+	api.POST("/webhooks/keys", serverHandler.KeyWebhook)
 }
 
 // registerProtectedAPIRoutes 认证API路由
 func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
 	api.GET("/channel-types", serverHandler.CommonHandler.GetChannelTypes)
+	api.GET("/jsonengine/capabilities", serverHandler.CommonHandler.GetEngineCapabilities)
+	api.POST("/jsonengine/benchmark", serverHandler.CommonHandler.BenchmarkEngineScanners)
 
 	groups := api.Group("/groups")
 	{
@@ -113,10 +118,20 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		groups.GET("", serverHandler.ListGroups)
 		groups.GET("/list", serverHandler.List)
 		groups.GET("/config-options", serverHandler.GetGroupConfigOptions)
+		groups.POST("/rules/explain", serverHandler.ExplainRules)
+		groups.GET("/rules/lint", serverHandler.LintAllRules)
 		groups.PUT("/:id", serverHandler.UpdateGroup)
 		groups.DELETE("/:id", serverHandler.DeleteGroup)
 		groups.GET("/:id/stats", serverHandler.GetGroupStats)
 		groups.POST("/:id/copy", serverHandler.CopyGroup)
+		groups.GET("/:id/debug", serverHandler.GetGroupDebug)
+		groups.POST("/:id/debug", serverHandler.StartGroupDebug)
+		groups.DELETE("/:id/debug", serverHandler.StopGroupDebug)
+		groups.GET("/:id/budgets", serverHandler.ListBudgets)
+		groups.POST("/:id/budgets", serverHandler.CreateBudget)
+		groups.PUT("/:id/budgets/:budgetId", serverHandler.UpdateBudget)
+		groups.DELETE("/:id/budgets/:budgetId", serverHandler.DeleteBudget)
+		groups.POST("/:id/budgets/:budgetId/reset", serverHandler.ResetBudget)
 
 		groups.GET("/:id/sub-groups", serverHandler.GetSubGroups)
 		groups.POST("/:id/sub-groups", serverHandler.AddSubGroups)
@@ -141,6 +156,7 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		keys.POST("/validate-group", serverHandler.ValidateGroupKeys)
 		keys.POST("/test-multiple", serverHandler.TestMultipleKeys)
 		keys.PUT("/:id/notes", serverHandler.UpdateKeyNotes)
+		keys.PUT("/:id/weight", serverHandler.UpdateKeyWeight)
 	}
 
 	// Tasks
@@ -161,6 +177,20 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		logs.GET("/export", serverHandler.ExportLogs)
 	}
 
+	// 用量统计
+	api.GET("/usage", serverHandler.GetUsage)
+	api.GET("/usage/export", serverHandler.ExportUsage)
+
+	// 审计日志
+	api.GET("/audit-logs", serverHandler.GetAuditLogs)
+
+	// 每日报告
+	reports := api.Group("/reports")
+	{
+		reports.GET("/daily", serverHandler.GetDailyReport)
+		reports.POST("/daily/send", serverHandler.SendDailyReport)
+	}
+
 	// 设置
 	settings := api.Group("/settings")
 	{
@@ -0,0 +1,157 @@
+// Package script runs a group's body scripts: expr-lang expressions that inspect a request
+// or response body and return the paths to rewrite. They cover logic too complex for path
+// rules, such as conditional model rewrites or prompt policies.
+//
+// A script sees three variables:
+//
+//	body      the JSON body; body.Get("messages[0].role") and body.Has("tools") look up
+//	          single values without parsing the whole document
+//	request   method, path, group and headers (lowercase names) of the client request
+//	response  status of the upstream response (zero for request scripts)
+//
+// It evaluates to a map from rule paths to new values, or nil to leave the body unchanged:
+//
+//	body.Get("model") == "gpt-4" && request.headers["x-tier"] == "free" ? {"model": "gpt-4o-mini"} : nil
+//
+// A value replaces the field, or adds it when the parent object exists but the field does not;
+// remove() deletes the field.
+package script
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gpt-load/internal/jsonengine"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// maxScriptNodes bounds the size of a compiled script.
+const maxScriptNodes = 2000
+
+// removeMarker is returned by remove() to delete a field.
+type removeMarker struct{}
+
+// Env is the data a script runs against.
+type Env struct {
+	Body     *Body        `expr:"body"`
+	Request  RequestInfo  `expr:"request"`
+	Response ResponseInfo `expr:"response"`
+}
+
+// RequestInfo describes the client request.
+type RequestInfo struct {
+	Method  string            `expr:"method"`
+	Path    string            `expr:"path"`
+	Group   string            `expr:"group"`
+	Headers map[string]string `expr:"headers"`
+}
+
+// ResponseInfo describes the upstream response.
+type ResponseInfo struct {
+	Status int `expr:"status"`
+}
+
+// Program is a compiled script, safe for concurrent use.
+type Program struct {
+	program *vm.Program
+}
+
+// Compile parses and type-checks a script.
+func Compile(source string) (*Program, error) {
+	program, err := expr.Compile(source,
+		expr.Env(Env{}),
+		expr.Function("remove", func(...any) (any, error) { return removeMarker{}, nil }, new(func() any)),
+		expr.MaxNodes(maxScriptNodes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{program: program}, nil
+}
+
+// Rewrite runs the script and applies the paths it returns to data. The body is returned
+// unchanged, without error, when the script returns nil or an empty map.
+func (p *Program) Rewrite(data []byte, env Env) ([]byte, error) {
+	env.Body = &Body{data: data}
+	out, err := expr.Run(p.program, env)
+	if err != nil {
+		return data, err
+	}
+	rules, err := mutationRules(out)
+	if err != nil || len(rules) == 0 {
+		return data, err
+	}
+	engine, err := jsonengine.NewPathEngine(rules)
+	if err != nil {
+		return data, fmt.Errorf("script result: %w", err)
+	}
+	return engine.ProcessBytes(data)
+}
+
+// mutationRules turns a script result into rules: set plus add for new values, remove for remove().
+func mutationRules(out any) ([]jsonengine.PathRule, error) {
+	if out == nil {
+		return nil, nil
+	}
+	paths, ok := out.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("script must return a map of paths to values or nil, got %T", out)
+	}
+	rules := make([]jsonengine.PathRule, 0, 2*len(paths))
+	for path, value := range paths {
+		if path == "" {
+			return nil, errors.New("script returned an empty path")
+		}
+		if _, ok := value.(removeMarker); ok {
+			rules = append(rules, jsonengine.PathRule{Path: path, Action: jsonengine.ActionRemove})
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("script value for %s: %w", path, err)
+		}
+		rules = append(rules,
+			jsonengine.PathRule{Path: path, Action: jsonengine.ActionSet, ValueBytes: raw},
+			jsonengine.PathRule{Path: path, Action: jsonengine.ActionAdd, ValueBytes: raw},
+		)
+	}
+	return rules, nil
+}
+
+// Body is a lazily parsed view of a JSON body: each lookup scans only as far as the value.
+type Body struct {
+	data []byte
+}
+
+// Get returns the value at path decoded to a string, float64, bool, map, slice or nil.
+// A missing path returns nil.
+func (b *Body) Get(path string) any {
+	v, ok := jsonengine.Get(b.data, path)
+	if !ok {
+		return nil
+	}
+	switch v.Type {
+	case jsonengine.ValueString:
+		return v.String()
+	case jsonengine.ValueNumber:
+		return v.Float()
+	case jsonengine.ValueBool:
+		return v.Bool()
+	case jsonengine.ValueNull:
+		return nil
+	}
+	var decoded any
+	if err := json.Unmarshal(v.Raw, &decoded); err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// Has reports whether path exists in the body, including fields set to null.
+func (b *Body) Has(path string) bool {
+	_, ok := jsonengine.Get(b.data, path)
+	return ok
+}
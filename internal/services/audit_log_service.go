@@ -0,0 +1,222 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// auditLogQueueSize 内存队列长度，写入跟不上时超出的记录被丢弃
+	auditLogQueueSize     = 10000
+	auditLogBatchSize     = 200
+	auditLogFlushInterval = 5 * time.Second
+	auditSinkTimeout      = 10 * time.Second
+)
+
+// AuditLogService 异步写入审计日志
+// 记录先进入内存队列，由后台协程按批写入数据库或 audit_log_sink 配置的外部接收端，不阻塞请求
+// 每个节点各自写入自己代理的请求
+type AuditLogService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	encryptionSvc   encryption.Service
+	client          *http.Client
+	queue           chan *models.AuditLog
+	dropped         atomic.Int64
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewAuditLogService creates a new AuditLogService.
+func NewAuditLogService(db *gorm.DB, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service) *AuditLogService {
+	return &AuditLogService{
+		db:              db,
+		settingsManager: settingsManager,
+		encryptionSvc:   encryptionSvc,
+		client:          &http.Client{Timeout: auditSinkTimeout},
+		queue:           make(chan *models.AuditLog, auditLogQueueSize),
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start 启动后台写入协程
+func (s *AuditLogService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Audit log service started")
+}
+
+// Stop 写出队列中剩余的记录后停止
+func (s *AuditLogService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("AuditLogService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("AuditLogService stop timed out.")
+	}
+}
+
+// Record 将一条审计记录加入写入队列，队列已满时丢弃
+func (s *AuditLogService) Record(entry *models.AuditLog) {
+	if entry.ID == "" {
+		entry.ID = uuid.NewString()
+	}
+	select {
+	case s.queue <- entry:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *AuditLogService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(auditLogFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*models.AuditLog, 0, auditLogBatchSize)
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) >= auditLogBatchSize {
+				s.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.write(batch)
+				batch = batch[:0]
+			}
+			if dropped := s.dropped.Swap(0); dropped > 0 {
+				logrus.WithField("dropped", dropped).Warn("Audit log queue is full, records were dropped")
+			}
+		case <-s.stopCh:
+			for {
+				select {
+				case entry := <-s.queue:
+					batch = append(batch, entry)
+				default:
+					if len(batch) > 0 {
+						s.write(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// write 将一批记录发送到外部接收端，未配置或发送失败时写入数据库
+func (s *AuditLogService) write(batch []*models.AuditLog) {
+	if sink := strings.TrimSpace(s.settingsManager.GetSettings().AuditLogSink); sink != "" {
+		err := s.sendToSink(sink, batch)
+		if err == nil {
+			return
+		}
+		logrus.WithError(err).WithField("count", len(batch)).Warn("Failed to send audit logs to the sink, storing them in the database")
+	}
+	if err := s.db.CreateInBatches(batch, auditLogBatchSize).Error; err != nil {
+		logrus.WithError(err).WithField("count", len(batch)).Error("Failed to write audit logs")
+	}
+}
+
+// sendToSink 以换行分隔的 JSON 将一批记录 POST 到 http(s) 接收端
+func (s *AuditLogService) sendToSink(sink string, batch []*models.AuditLog) error {
+	sinkURL, err := url.Parse(sink)
+	if err != nil || (sinkURL.Scheme != "http" && sinkURL.Scheme != "https") || sinkURL.Host == "" {
+		return fmt.Errorf("unsupported audit log sink %q, expected an http(s) URL", sink)
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, entry := range batch {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), auditSinkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sinkURL.String(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetAuditLogsQuery 返回按请求参数过滤的审计日志查询
+func (s *AuditLogService) GetAuditLogsQuery(c *gin.Context) *gorm.DB {
+	db := s.db.Model(&models.AuditLog{})
+	if groupName := c.Query("group_name"); groupName != "" {
+		db = db.Where("group_name LIKE ?", "%"+groupName+"%")
+	}
+	if keyValue := c.Query("key_value"); keyValue != "" {
+		db = db.Where("key_hash = ?", s.encryptionSvc.Hash(keyValue))
+	}
+	if proxyKey := c.Query("proxy_key"); proxyKey != "" {
+		db = db.Where("proxy_key_hash = ?", s.encryptionSvc.Hash(proxyKey))
+	}
+	if model := c.Query("model"); model != "" {
+		db = db.Where("model LIKE ?", "%"+model+"%")
+	}
+	if method := c.Query("method"); method != "" {
+		db = db.Where("method = ?", strings.ToUpper(method))
+	}
+	if statusCodeStr := c.Query("status_code"); statusCodeStr != "" {
+		if statusCode, err := strconv.Atoi(statusCodeStr); err == nil {
+			db = db.Where("status_code = ?", statusCode)
+		}
+	}
+	if isSuccessStr := c.Query("is_success"); isSuccessStr != "" {
+		if isSuccess, err := strconv.ParseBool(isSuccessStr); err == nil {
+			db = db.Where("is_success = ?", isSuccess)
+		}
+	}
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		if startTime, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
+			db = db.Where("timestamp >= ?", startTime)
+		}
+	}
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		if endTime, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
+			db = db.Where("timestamp <= ?", endTime)
+		}
+	}
+	return db
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// budgetCacheTTL 本地缓存分组预算列表的时间，避免每个代理请求都查询数据库
+const budgetCacheTTL = 5 * time.Second
+
+// 预算用量计数字段，费用以百万分之一美元为单位累计
+const (
+	budgetFieldRequests   = "requests"
+	budgetFieldTokens     = "tokens"
+	budgetFieldCostMicros = "cost_micros"
+)
+
+// BudgetRequest 创建或更新预算的参数，代理密钥只在创建时指定
+type BudgetRequest struct {
+	Name           string  `json:"name"`
+	ProxyKey       string  `json:"proxy_key"`
+	Schedule       string  `json:"schedule"`
+	MaxRequests    int64   `json:"max_requests"`
+	MaxTokens      int64   `json:"max_tokens"`
+	MaxCost        float64 `json:"max_cost"`
+	TokenPrice     float64 `json:"token_price"`
+	Action         string  `json:"action"`
+	DowngradeModel string  `json:"downgrade_model"`
+}
+
+// BudgetUsage 预算在当前周期内的用量
+type BudgetUsage struct {
+	Requests int64   `json:"requests"`
+	Tokens   int64   `json:"tokens"`
+	Cost     float64 `json:"cost"`
+}
+
+// BudgetStatus 预算及其当前周期的用量
+type BudgetStatus struct {
+	models.Budget
+	Usage       BudgetUsage `json:"usage"`
+	PeriodStart time.Time   `json:"period_start"`
+	ResetsAt    *time.Time  `json:"resets_at,omitempty"` // 为空表示不会再重置
+	Exhausted   bool        `json:"exhausted"`
+}
+
+// budgetCacheEntry 本地缓存的分组预算列表
+type budgetCacheEntry struct {
+	budgets   []models.Budget
+	fetchedAt time.Time
+}
+
+// budgetWindow 预算的当前周期 [start, end)，end 为零值表示之后不再重置
+type budgetWindow struct {
+	schedule string
+	start    time.Time
+	end      time.Time
+}
+
+// BudgetService 管理分组和代理密钥的用量预算
+// 预算配置保存在数据库中，用量按周期计入共享存储，多个实例共同累计
+type BudgetService struct {
+	db            *gorm.DB
+	store         store.Store
+	encryptionSvc encryption.Service
+	cache         sync.Map // map[uint]budgetCacheEntry，键为分组 ID
+	windows       sync.Map // map[uint]budgetWindow，键为预算 ID
+}
+
+// NewBudgetService creates a new BudgetService.
+func NewBudgetService(db *gorm.DB, store store.Store, encryptionSvc encryption.Service) *BudgetService {
+	return &BudgetService{
+		db:            db,
+		store:         store,
+		encryptionSvc: encryptionSvc,
+	}
+}
+
+func budgetUsageStoreKey(budgetID uint, periodStart time.Time) string {
+	return fmt.Sprintf("budget_usage:%d:%d", budgetID, periodStart.Unix())
+}
+
+// List 返回分组的所有预算及其当前用量
+func (s *BudgetService) List(groupID uint) ([]BudgetStatus, error) {
+	var budgets []models.Budget
+	if err := s.db.Where("group_id = ?", groupID).Order("id asc").Find(&budgets).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	now := time.Now()
+	statuses := make([]BudgetStatus, 0, len(budgets))
+	for i := range budgets {
+		status, err := s.Status(&budgets[i], now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// Create 为分组创建预算，指定代理密钥时只作用于使用该密钥的请求
+func (s *BudgetService) Create(groupID uint, req *BudgetRequest) (*BudgetStatus, error) {
+	var group models.Group
+	if err := s.db.Select("id").First(&group, groupID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	budget := models.Budget{GroupID: groupID}
+	if proxyKey := strings.TrimSpace(req.ProxyKey); proxyKey != "" {
+		budget.ProxyKeyHash = s.encryptionSvc.Hash(proxyKey)
+		budget.ProxyKeyMask = utils.MaskAPIKey(proxyKey)
+	}
+	if err := applyBudgetRequest(&budget, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&budget).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	s.cache.Delete(groupID)
+
+	logrus.WithFields(logrus.Fields{
+		"group_id":  groupID,
+		"budget_id": budget.ID,
+		"schedule":  budget.Schedule,
+	}).Info("Budget created")
+
+	return s.Status(&budget, time.Now())
+}
+
+// Update 更新预算的周期、上限和处理方式，当前周期的用量保留
+func (s *BudgetService) Update(groupID, budgetID uint, req *BudgetRequest) (*BudgetStatus, error) {
+	budget, err := s.find(groupID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyBudgetRequest(budget, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(budget).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	s.cache.Delete(groupID)
+	return s.Status(budget, time.Now())
+}
+
+// Delete 删除预算及其当前周期的用量
+func (s *BudgetService) Delete(groupID, budgetID uint) error {
+	budget, err := s.find(groupID, budgetID)
+	if err != nil {
+		return err
+	}
+	window, err := s.window(budget, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(budget).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	s.cache.Delete(groupID)
+	s.windows.Delete(budget.ID)
+	return s.store.Del(budgetUsageStoreKey(budget.ID, window.start))
+}
+
+// Reset 清空预算当前周期的用量
+func (s *BudgetService) Reset(groupID, budgetID uint) (*BudgetStatus, error) {
+	budget, err := s.find(groupID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	window, err := s.window(budget, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Del(budgetUsageStoreKey(budget.ID, window.start)); err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"group_id":  groupID,
+		"budget_id": budget.ID,
+	}).Info("Budget usage reset")
+	return s.Status(budget, now)
+}
+
+func (s *BudgetService) find(groupID, budgetID uint) (*models.Budget, error) {
+	var budget models.Budget
+	if err := s.db.Where("id = ? AND group_id = ?", budgetID, groupID).First(&budget).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &budget, nil
+}
+
+// applyBudgetRequest 校验参数并写入预算
+func applyBudgetRequest(budget *models.Budget, req *BudgetRequest) error {
+	schedule := strings.TrimSpace(req.Schedule)
+	if _, err := utils.ParseCron(schedule); err != nil {
+		return app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("invalid schedule: %v", err))
+	}
+	if req.MaxRequests < 0 || req.MaxTokens < 0 || req.MaxCost < 0 || req.TokenPrice < 0 {
+		return app_errors.NewAPIError(app_errors.ErrValidation, "limits and token_price must not be negative")
+	}
+	if req.MaxRequests == 0 && req.MaxTokens == 0 && req.MaxCost == 0 {
+		return app_errors.NewAPIError(app_errors.ErrValidation, "at least one of max_requests, max_tokens and max_cost must be set")
+	}
+	action := req.Action
+	if action == "" {
+		action = models.BudgetActionReject
+	}
+	downgradeModel := strings.TrimSpace(req.DowngradeModel)
+	switch action {
+	case models.BudgetActionReject:
+		downgradeModel = ""
+	case models.BudgetActionDowngrade:
+		if downgradeModel == "" {
+			return app_errors.NewAPIError(app_errors.ErrValidation, "downgrade_model is required for the downgrade action")
+		}
+	default:
+		return app_errors.NewAPIError(app_errors.ErrValidation, "action must be reject or downgrade")
+	}
+
+	budget.Name = strings.TrimSpace(req.Name)
+	budget.Schedule = schedule
+	budget.MaxRequests = req.MaxRequests
+	budget.MaxTokens = req.MaxTokens
+	budget.MaxCost = req.MaxCost
+	budget.TokenPrice = req.TokenPrice
+	budget.Action = action
+	budget.DowngradeModel = downgradeModel
+	return nil
+}
+
+// window 返回预算在 now 所处的周期，周期开始于 Schedule 最近一次触发的时间
+// 进入新周期时删除上一周期的用量
+func (s *BudgetService) window(budget *models.Budget, now time.Time) (budgetWindow, error) {
+	cached, ok := s.windows.Load(budget.ID)
+	if ok {
+		w := cached.(budgetWindow)
+		if w.schedule == budget.Schedule && !now.Before(w.start) && (w.end.IsZero() || now.Before(w.end)) {
+			return w, nil
+		}
+	}
+
+	schedule, err := utils.ParseCron(budget.Schedule)
+	if err != nil {
+		return budgetWindow{}, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("invalid schedule: %v", err))
+	}
+	w := budgetWindow{schedule: budget.Schedule, start: schedule.Prev(now), end: schedule.Next(now)}
+	if ok {
+		if previous := cached.(budgetWindow); !previous.start.Equal(w.start) {
+			if err := s.store.Del(budgetUsageStoreKey(budget.ID, previous.start)); err != nil {
+				logrus.WithError(err).WithField("budget_id", budget.ID).Warn("Failed to delete usage of the previous budget period")
+			}
+		}
+	}
+	s.windows.Store(budget.ID, w)
+	return w, nil
+}
+
+// Status 返回预算在 now 所处周期的用量
+func (s *BudgetService) Status(budget *models.Budget, now time.Time) (*BudgetStatus, error) {
+	window, err := s.window(budget, now)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := s.store.HGetAll(budgetUsageStoreKey(budget.ID, window.start))
+	if err != nil {
+		return nil, err
+	}
+
+	requests, _ := strconv.ParseInt(fields[budgetFieldRequests], 10, 64)
+	tokens, _ := strconv.ParseInt(fields[budgetFieldTokens], 10, 64)
+	costMicros, _ := strconv.ParseInt(fields[budgetFieldCostMicros], 10, 64)
+	status := &BudgetStatus{
+		Budget:      *budget,
+		Usage:       BudgetUsage{Requests: requests, Tokens: tokens, Cost: float64(costMicros) / 1e6},
+		PeriodStart: window.start,
+		Exhausted: (budget.MaxRequests > 0 && requests >= budget.MaxRequests) ||
+			(budget.MaxTokens > 0 && tokens >= budget.MaxTokens) ||
+			(budget.MaxCost > 0 && costMicros >= int64(math.Round(budget.MaxCost*1e6))),
+	}
+	if !window.end.IsZero() {
+		resetsAt := window.end
+		status.ResetsAt = &resetsAt
+	}
+	return status, nil
+}
+
+// Applicable 供代理热路径使用，返回作用于分组和代理密钥哈希的预算
+// 预算列表在本地缓存数秒，因此其他实例上的修改会有短暂延迟；数据库出错时视为没有预算
+func (s *BudgetService) Applicable(groupID uint, proxyKeyHash string) []models.Budget {
+	now := time.Now()
+	var budgets []models.Budget
+	if cached, ok := s.cache.Load(groupID); ok && now.Sub(cached.(budgetCacheEntry).fetchedAt) < budgetCacheTTL {
+		budgets = cached.(budgetCacheEntry).budgets
+	} else {
+		if err := s.db.Where("group_id = ?", groupID).Order("id asc").Find(&budgets).Error; err != nil {
+			logrus.WithError(err).WithField("group_id", groupID).Warn("Failed to load group budgets")
+			return nil
+		}
+		s.cache.Store(groupID, budgetCacheEntry{budgets: budgets, fetchedAt: now})
+	}
+
+	var applicable []models.Budget
+	for _, budget := range budgets {
+		if budget.ProxyKeyHash == "" || budget.ProxyKeyHash == proxyKeyHash {
+			applicable = append(applicable, budget)
+		}
+	}
+	return applicable
+}
+
+// Exhausted 返回已用尽的预算，存储出错的预算视为未用尽
+func (s *BudgetService) Exhausted(budgets []models.Budget) []BudgetStatus {
+	now := time.Now()
+	var exhausted []BudgetStatus
+	for i := range budgets {
+		status, err := s.Status(&budgets[i], now)
+		if err != nil {
+			logrus.WithError(err).WithField("budget_id", budgets[i].ID).Warn("Failed to load budget usage")
+			continue
+		}
+		if status.Exhausted {
+			exhausted = append(exhausted, *status)
+		}
+	}
+	return exhausted
+}
+
+// Record 将一个请求及其 token 用量和费用计入各预算的当前周期
+// priced 为 false 表示请求的模型不在价格表中，费用按预算的 token_price 估算
+func (s *BudgetService) Record(budgets []models.Budget, tokens int64, cost float64, priced bool) {
+	now := time.Now()
+	for i := range budgets {
+		budget := &budgets[i]
+		window, err := s.window(budget, now)
+		if err != nil {
+			continue
+		}
+		key := budgetUsageStoreKey(budget.ID, window.start)
+		if _, err := s.store.HIncrBy(key, budgetFieldRequests, 1); err != nil {
+			logrus.WithError(err).WithField("budget_id", budget.ID).Warn("Failed to record budget usage")
+			continue
+		}
+		if tokens > 0 {
+			if _, err := s.store.HIncrBy(key, budgetFieldTokens, tokens); err != nil {
+				logrus.WithError(err).WithField("budget_id", budget.ID).Warn("Failed to record budget usage")
+				continue
+			}
+		}
+		// 每百万 token 的美元价格乘以 token 数即为百万分之一美元
+		costMicros := int64(math.Round(cost * 1e6))
+		if !priced {
+			costMicros = int64(math.Round(float64(tokens) * budget.TokenPrice))
+		}
+		if costMicros > 0 {
+			if _, err := s.store.HIncrBy(key, budgetFieldCostMicros, costMicros); err != nil {
+				logrus.WithError(err).WithField("budget_id", budget.ID).Warn("Failed to record budget usage")
+			}
+		}
+	}
+}
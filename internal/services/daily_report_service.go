@@ -0,0 +1,488 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	dailyReportWindow         = 24 * time.Hour
+	dailyReportTopModels      = 5
+	dailyReportTopErrors      = 3
+	dailyReportErrorMaxLength = 200
+	dailyReportSendTimeout    = 30 * time.Second
+)
+
+// ErrDailyReportNoTarget is returned when neither a webhook URL nor email recipients are configured.
+var ErrDailyReportNoTarget = errors.New("daily report has no webhook url or email recipients configured")
+
+// DailyReport 过去一天的密钥池汇总报告
+type DailyReport struct {
+	Start     time.Time          `json:"start"`
+	End       time.Time          `json:"end"`
+	Usage     DailyReportUsage   `json:"usage"`
+	TopModels []DailyReportModel `json:"top_models"`
+	Groups    []DailyReportGroup `json:"groups"`
+}
+
+// DailyReportUsage 请求量统计，来自 group_hourly_stats 汇总
+type DailyReportUsage struct {
+	Requests  int64   `json:"requests"`
+	Failures  int64   `json:"failures"`
+	ErrorRate float64 `json:"error_rate"` // 百分比
+}
+
+// DailyReportModel 模型的请求量
+type DailyReportModel struct {
+	Model    string `json:"model"`
+	Requests int64  `json:"requests"`
+	Failures int64  `json:"failures"`
+}
+
+// DailyReportError 相同状态码和错误信息的失败请求
+type DailyReportError struct {
+	StatusCode int    `json:"status_code"`
+	Message    string `json:"message"`
+	Count      int64  `json:"count"`
+}
+
+// DailyReportGroup 单个分组的密钥健康度、用量、常用模型和主要错误
+type DailyReportGroup struct {
+	ID          uint               `json:"id"`
+	Name        string             `json:"name"`
+	DisplayName string             `json:"display_name"`
+	GroupType   string             `json:"group_type"`
+	ActiveKeys  int64              `json:"active_keys"`
+	InvalidKeys int64              `json:"invalid_keys"`
+	Usage       DailyReportUsage   `json:"usage"`
+	TopModels   []DailyReportModel `json:"top_models"`
+	Errors      []DailyReportError `json:"errors"`
+}
+
+// DailyReportService 每天定时生成密钥池报告，通过 webhook 或邮件发送
+type DailyReportService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	configManager   types.ConfigManager
+	client          *http.Client
+	stopCh          chan struct{}
+	wakeCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewDailyReportService 创建每日报告服务
+func NewDailyReportService(
+	db *gorm.DB,
+	settingsManager *config.SystemSettingsManager,
+	configManager types.ConfigManager,
+) *DailyReportService {
+	return &DailyReportService{
+		db:              db,
+		settingsManager: settingsManager,
+		configManager:   configManager,
+		client:          &http.Client{Timeout: dailyReportSendTimeout},
+		stopCh:          make(chan struct{}),
+		wakeCh:          make(chan struct{}, 1),
+	}
+}
+
+// Start 启动定时发送
+func (s *DailyReportService) Start() {
+	// 发送时间修改后重新计算下一次发送时间
+	err := config.OnSettingChange(s.settingsManager, "daily_report_hour", func(_, _ int) {
+		select {
+		case s.wakeCh <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to register daily report hour hook")
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Daily report service started")
+}
+
+// Stop 停止定时发送
+func (s *DailyReportService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("DailyReportService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("DailyReportService stop timed out.")
+	}
+}
+
+// run 主循环，在每天的发送时间生成并发送截至该时间的报告，未配置发送目标时跳过
+func (s *DailyReportService) run() {
+	defer s.wg.Done()
+
+	for {
+		next := nextDailyReportTime(time.Now(), s.settingsManager.GetSettings().DailyReportHour)
+		select {
+		case <-time.After(time.Until(next)):
+		case <-s.wakeCh:
+			continue
+		case <-s.stopCh:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*dailyReportSendTimeout)
+		err := s.SendReport(ctx, next)
+		cancel()
+		switch {
+		case errors.Is(err, ErrDailyReportNoTarget):
+		case err != nil:
+			logrus.WithError(err).Error("Failed to send daily report")
+		default:
+			logrus.Info("Daily report sent")
+		}
+	}
+}
+
+// nextDailyReportTime 返回 now 之后第一个整点为 hour 的时间（服务器本地时区）
+func nextDailyReportTime(now time.Time, hour int) time.Time {
+	hour = min(max(hour, 0), 23)
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// SendReport 生成截至 end 的过去一天的报告，发送到配置的 webhook 和邮箱
+// 两种方式互不影响，任一失败时返回合并的错误
+func (s *DailyReportService) SendReport(ctx context.Context, end time.Time) error {
+	settings := s.settingsManager.GetSettings()
+	webhookURL := strings.TrimSpace(settings.DailyReportWebhookURL)
+	recipients := parseEmailRecipients(settings.DailyReportEmailTo)
+	if webhookURL == "" && len(recipients) == 0 {
+		return ErrDailyReportNoTarget
+	}
+
+	report, err := s.BuildReport(ctx, end)
+	if err != nil {
+		return fmt.Errorf("failed to build daily report: %w", err)
+	}
+
+	var errs []error
+	if webhookURL != "" {
+		if err := s.sendWebhook(ctx, webhookURL, report); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if len(recipients) > 0 {
+		if err := s.sendEmail(recipients, report); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BuildReport 汇总 [end-24h, end) 内的数据：请求量来自小时统计，模型和错误来自请求日志
+func (s *DailyReportService) BuildReport(ctx context.Context, end time.Time) (*DailyReport, error) {
+	start := end.Add(-dailyReportWindow)
+	db := s.db.WithContext(ctx)
+
+	var groups []models.Group
+	if err := db.Select("id", "name", "display_name", "group_type").Order("sort ASC, id ASC").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	var keyRows []struct {
+		GroupID  uint
+		Status   string
+		KeyCount int64
+	}
+	if err := db.Model(&models.APIKey{}).
+		Select("group_id, status, COUNT(*) as key_count").
+		Group("group_id, status").
+		Scan(&keyRows).Error; err != nil {
+		return nil, err
+	}
+
+	// 小时统计按整点记录，窗口边界向下取整
+	var usageRows []struct {
+		GroupID      uint
+		SuccessCount int64
+		FailureCount int64
+	}
+	if err := db.Model(&models.GroupHourlyStat{}).
+		Select("group_id, SUM(success_count) as success_count, SUM(failure_count) as failure_count").
+		Where("time >= ? AND time < ?", start.Truncate(time.Hour), end.Truncate(time.Hour)).
+		Group("group_id").
+		Scan(&usageRows).Error; err != nil {
+		return nil, err
+	}
+
+	var modelRows []struct {
+		GroupID  uint
+		Model    string
+		Requests int64
+		Failures int64
+	}
+	if err := db.Model(&models.RequestLog{}).
+		Select("group_id, model, COUNT(*) as requests, SUM(CASE WHEN is_success = ? THEN 1 ELSE 0 END) as failures", false).
+		Where("timestamp >= ? AND timestamp < ? AND request_type = ?", start, end, models.RequestTypeFinal).
+		Group("group_id, model").
+		Scan(&modelRows).Error; err != nil {
+		return nil, err
+	}
+
+	var errorRows []struct {
+		GroupID      uint
+		StatusCode   int
+		ErrorMessage string
+		Occurrences  int64
+	}
+	if err := db.Model(&models.RequestLog{}).
+		Select("group_id, status_code, error_message, COUNT(*) as occurrences").
+		Where("timestamp >= ? AND timestamp < ? AND request_type = ? AND is_success = ?", start, end, models.RequestTypeFinal, false).
+		Group("group_id, status_code, error_message").
+		Order("occurrences DESC").
+		Scan(&errorRows).Error; err != nil {
+		return nil, err
+	}
+
+	report := &DailyReport{Start: start, End: end, TopModels: []DailyReportModel{}, Groups: make([]DailyReportGroup, 0, len(groups))}
+	index := make(map[uint]int, len(groups))
+	for _, g := range groups {
+		index[g.ID] = len(report.Groups)
+		report.Groups = append(report.Groups, DailyReportGroup{
+			ID:          g.ID,
+			Name:        g.Name,
+			DisplayName: g.DisplayName,
+			GroupType:   g.GroupType,
+			TopModels:   []DailyReportModel{},
+			Errors:      []DailyReportError{},
+		})
+	}
+
+	for _, row := range keyRows {
+		i, ok := index[row.GroupID]
+		if !ok {
+			continue
+		}
+		if row.Status == models.KeyStatusActive {
+			report.Groups[i].ActiveKeys += row.KeyCount
+		} else {
+			report.Groups[i].InvalidKeys += row.KeyCount
+		}
+	}
+
+	// 聚合分组的统计与子分组重复，总量只累计标准分组
+	for _, row := range usageRows {
+		i, ok := index[row.GroupID]
+		if !ok {
+			continue
+		}
+		g := &report.Groups[i]
+		g.Usage = newDailyReportUsage(row.SuccessCount+row.FailureCount, row.FailureCount)
+		if g.GroupType != "aggregate" {
+			report.Usage.Requests += g.Usage.Requests
+			report.Usage.Failures += g.Usage.Failures
+		}
+	}
+	report.Usage = newDailyReportUsage(report.Usage.Requests, report.Usage.Failures)
+
+	// 请求日志记录的是实际处理请求的标准分组
+	overall := make(map[string]*DailyReportModel)
+	for _, row := range modelRows {
+		if i, ok := index[row.GroupID]; ok {
+			report.Groups[i].TopModels = append(report.Groups[i].TopModels, DailyReportModel{Model: row.Model, Requests: row.Requests, Failures: row.Failures})
+		}
+		m := overall[row.Model]
+		if m == nil {
+			m = &DailyReportModel{Model: row.Model}
+			overall[row.Model] = m
+		}
+		m.Requests += row.Requests
+		m.Failures += row.Failures
+	}
+	for _, m := range overall {
+		report.TopModels = append(report.TopModels, *m)
+	}
+	report.TopModels = topDailyReportModels(report.TopModels)
+
+	for i := range report.Groups {
+		report.Groups[i].TopModels = topDailyReportModels(report.Groups[i].TopModels)
+	}
+
+	// errorRows 已按次数降序
+	for _, row := range errorRows {
+		i, ok := index[row.GroupID]
+		if !ok || len(report.Groups[i].Errors) >= dailyReportTopErrors {
+			continue
+		}
+		report.Groups[i].Errors = append(report.Groups[i].Errors, DailyReportError{
+			StatusCode: row.StatusCode,
+			Message:    truncateReportMessage(row.ErrorMessage),
+			Count:      row.Occurrences,
+		})
+	}
+
+	return report, nil
+}
+
+// newDailyReportUsage 计算错误率
+func newDailyReportUsage(requests, failures int64) DailyReportUsage {
+	usage := DailyReportUsage{Requests: requests, Failures: failures}
+	if requests > 0 {
+		usage.ErrorRate = float64(failures) / float64(requests) * 100
+	}
+	return usage
+}
+
+// topDailyReportModels 按请求量降序保留前几个模型
+func topDailyReportModels(list []DailyReportModel) []DailyReportModel {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Requests != list[j].Requests {
+			return list[i].Requests > list[j].Requests
+		}
+		return list[i].Model < list[j].Model
+	})
+	if len(list) > dailyReportTopModels {
+		list = list[:dailyReportTopModels]
+	}
+	return list
+}
+
+// truncateReportMessage 截断过长的错误信息
+func truncateReportMessage(message string) string {
+	message = strings.TrimSpace(message)
+	if runes := []rune(message); len(runes) > dailyReportErrorMaxLength {
+		return string(runes[:dailyReportErrorMaxLength]) + "..."
+	}
+	return message
+}
+
+// parseEmailRecipients 解析逗号分隔的收件人
+func parseEmailRecipients(value string) []string {
+	var recipients []string
+	for _, addr := range strings.Split(value, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			recipients = append(recipients, addr)
+		}
+	}
+	return recipients
+}
+
+// sendWebhook 以 JSON 格式 POST 报告
+func (s *DailyReportService) sendWebhook(ctx context.Context, url string, report *DailyReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendEmail 以纯文本邮件发送报告，服务器支持时使用 STARTTLS
+func (s *DailyReportService) sendEmail(recipients []string, report *DailyReport) error {
+	smtpConfig := s.configManager.GetSMTPConfig()
+	if smtpConfig.Host == "" {
+		return errors.New("SMTP_HOST is not configured")
+	}
+	from := smtpConfig.From
+	if from == "" {
+		from = smtpConfig.Username
+	}
+
+	var auth smtp.Auth
+	if smtpConfig.Username != "" {
+		auth = smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpConfig.Host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: GPT-Load daily report %s\r\n", report.End.Format("2006-01-02"))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(report.Text(), "\n", "\r\n"))
+
+	addr := net.JoinHostPort(smtpConfig.Host, strconv.Itoa(smtpConfig.Port))
+	return smtp.SendMail(addr, auth, from, recipients, msg.Bytes())
+}
+
+// Text renders the report as plain text for email.
+func (r *DailyReport) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "GPT-Load daily report\n%s - %s\n\n", r.Start.Format("2006-01-02 15:04"), r.End.Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&b, "Requests: %d, failures: %d (%.2f%%)\n", r.Usage.Requests, r.Usage.Failures, r.Usage.ErrorRate)
+	writeDailyReportModels(&b, "", r.TopModels)
+
+	for _, g := range r.Groups {
+		name := g.Name
+		if g.DisplayName != "" && g.DisplayName != g.Name {
+			name = fmt.Sprintf("%s (%s)", g.DisplayName, g.Name)
+		}
+		fmt.Fprintf(&b, "\n== %s ==\n", name)
+		if g.GroupType != "aggregate" {
+			fmt.Fprintf(&b, "Keys: %d active, %d invalid\n", g.ActiveKeys, g.InvalidKeys)
+		}
+		fmt.Fprintf(&b, "Requests: %d, failures: %d (%.2f%%)\n", g.Usage.Requests, g.Usage.Failures, g.Usage.ErrorRate)
+		writeDailyReportModels(&b, "  ", g.TopModels)
+		if len(g.Errors) > 0 {
+			b.WriteString("  Top errors:\n")
+			for _, e := range g.Errors {
+				fmt.Fprintf(&b, "    %dx [%d] %s\n", e.Count, e.StatusCode, e.Message)
+			}
+		}
+	}
+	return b.String()
+}
+
+func writeDailyReportModels(b *strings.Builder, indent string, list []DailyReportModel) {
+	if len(list) == 0 {
+		return
+	}
+	b.WriteString(indent + "Top models:\n")
+	for _, m := range list {
+		model := m.Model
+		if model == "" {
+			model = "(unknown)"
+		}
+		fmt.Fprintf(b, "%s  %s: %d requests, %d failures\n", indent, model, m.Requests, m.Failures)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxGroupDebugMinutes 单次调试窗口的最长时间
+	MaxGroupDebugMinutes = 240
+	// groupDebugCacheTTL 本地缓存调试窗口的时间，避免每个代理请求都访问存储
+	groupDebugCacheTTL = 5 * time.Second
+)
+
+// GroupDebugWindow 分组的临时调试窗口
+// 窗口内该分组的请求以 debug 级别输出日志，并按 SampleRate 抽样记录请求体
+type GroupDebugWindow struct {
+	GroupID    uint      `json:"group_id"`
+	GroupName  string    `json:"group_name"`
+	SampleRate float64   `json:"sample_rate"`
+	StartedAt  time.Time `json:"started_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// groupDebugCacheEntry 本地缓存的窗口查询结果，window 为 nil 表示未开启
+type groupDebugCacheEntry struct {
+	window    *GroupDebugWindow
+	fetchedAt time.Time
+}
+
+// GroupDebugService 管理分组调试窗口
+// 窗口保存在共享存储中并设置过期时间，到期后自动恢复，无需全局开启 debug 日志
+type GroupDebugService struct {
+	db    *gorm.DB
+	store store.Store
+	cache sync.Map // map[uint]groupDebugCacheEntry
+}
+
+// NewGroupDebugService creates a new GroupDebugService.
+func NewGroupDebugService(db *gorm.DB, store store.Store) *GroupDebugService {
+	return &GroupDebugService{
+		db:    db,
+		store: store,
+	}
+}
+
+func groupDebugStoreKey(groupID uint) string {
+	return fmt.Sprintf("group_debug:%d", groupID)
+}
+
+// Start 为分组开启持续 minutes 分钟的调试窗口，已存在的窗口会被覆盖
+func (s *GroupDebugService) Start(groupID uint, minutes int, sampleRate float64) (*GroupDebugWindow, error) {
+	if minutes < 1 || minutes > MaxGroupDebugMinutes {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("minutes must be between 1 and %d", MaxGroupDebugMinutes))
+	}
+	if sampleRate < 0 || sampleRate > 1 {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, "sample_rate must be between 0 and 1")
+	}
+
+	var group models.Group
+	if err := s.db.Select("id", "name").First(&group, groupID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	now := time.Now()
+	duration := time.Duration(minutes) * time.Minute
+	window := &GroupDebugWindow{
+		GroupID:    group.ID,
+		GroupName:  group.Name,
+		SampleRate: sampleRate,
+		StartedAt:  now,
+		ExpiresAt:  now.Add(duration),
+	}
+
+	payload, err := json.Marshal(window)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Set(groupDebugStoreKey(groupID), payload, duration); err != nil {
+		return nil, err
+	}
+	s.cache.Delete(groupID)
+
+	logrus.WithFields(logrus.Fields{
+		"group_name":  group.Name,
+		"minutes":     minutes,
+		"sample_rate": sampleRate,
+	}).Info("Group debug window started")
+
+	return window, nil
+}
+
+// Stop 提前结束分组的调试窗口
+func (s *GroupDebugService) Stop(groupID uint) error {
+	if err := s.store.Delete(groupDebugStoreKey(groupID)); err != nil {
+		return err
+	}
+	s.cache.Delete(groupID)
+	logrus.WithField("group_id", groupID).Info("Group debug window stopped")
+	return nil
+}
+
+// Get 返回分组当前的调试窗口，未开启或已过期时返回 nil
+func (s *GroupDebugService) Get(groupID uint) (*GroupDebugWindow, error) {
+	payload, err := s.store.Get(groupDebugStoreKey(groupID))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var window GroupDebugWindow
+	if err := json.Unmarshal(payload, &window); err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(window.ExpiresAt) {
+		return nil, nil
+	}
+	return &window, nil
+}
+
+// Active 供代理热路径使用，返回分组生效中的调试窗口
+// 查询结果在本地缓存数秒，因此其他实例上的开启和结束会有短暂延迟；存储出错时视为未开启
+func (s *GroupDebugService) Active(groupID uint) *GroupDebugWindow {
+	now := time.Now()
+	if cached, ok := s.cache.Load(groupID); ok {
+		entry := cached.(groupDebugCacheEntry)
+		if now.Sub(entry.fetchedAt) < groupDebugCacheTTL {
+			if entry.window != nil && !now.Before(entry.window.ExpiresAt) {
+				return nil
+			}
+			return entry.window
+		}
+	}
+
+	window, err := s.Get(groupID)
+	if err != nil {
+		logrus.WithError(err).WithField("group_id", groupID).Warn("Failed to load group debug window")
+		return nil
+	}
+	s.cache.Store(groupID, groupDebugCacheEntry{window: window, fetchedAt: now})
+	return window
+}
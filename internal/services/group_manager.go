@@ -3,20 +3,42 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gpt-load/internal/config"
 	"gpt-load/internal/jsonengine"
 	"gpt-load/internal/models"
+	"gpt-load/internal/script"
 	"gpt-load/internal/store"
 	"gpt-load/internal/syncer"
 	"gpt-load/internal/utils"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 )
 
 const GroupUpdateChannel = "groups:updated"
 
+const (
+	groupLoadRetryInitialDelay = time.Second
+	groupLoadRetryMaxDelay     = 30 * time.Second
+)
+
+const (
+	// groupMissTTL 缓存和数据库中都不存在的分组名在这段时间内直接返回未找到
+	groupMissTTL = 10 * time.Second
+	// groupMissMaxEntries 未找到记录的上限，超出时清空，防止随机分组名占用内存
+	groupMissMaxEntries = 1024
+)
+
+// ErrGroupsNotLoaded is returned while the initial group load has not succeeded yet.
+var ErrGroupsNotLoaded = errors.New("GroupManager is not initialized")
+
 // GroupManager manages the caching of group data.
 type GroupManager struct {
 	syncer          *syncer.CacheSyncer[map[string]*models.Group]
@@ -24,6 +46,38 @@ type GroupManager struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	subGroupManager *SubGroupManager
+	ready           atomic.Bool
+	stopChan        chan struct{}
+	wg              sync.WaitGroup
+
+	// 最近一次加载失败的分组及原因，失败的分组继续使用上一个可用版本
+	// ruleIssues 为最近一次加载时规则静态检查发现的问题
+	loadErrorsMu sync.RWMutex
+	loadErrors   map[string]string
+	ruleIssues   map[string][]GroupRuleIssue
+
+	// 分组规则引擎的编译方式，为 nil 时使用 models.DefaultRuleEngineBuilder
+	ruleEngineBuilder models.RuleEngineBuilder
+
+	// 缓存未命中时从数据库直接读取的分组（如刚创建、其他实例尚未收到缓存失效通知），
+	// 以及数据库中不存在或加载有问题的分组名，缓存重新加载后清空
+	// 同一分组名的并发读取通过 fetchFlight 合并为一次
+	fetchMu     sync.Mutex
+	fetched     map[string]*models.Group
+	misses      map[string]groupMiss
+	fetchFlight singleflight.Group
+}
+
+// groupMiss 直接读取失败的分组名：记录时间和返回的错误
+type groupMiss struct {
+	at  time.Time
+	err error
+}
+
+// GroupRuleIssue 分组规则静态检查发现的问题
+type GroupRuleIssue struct {
+	Direction string `json:"direction"` // inbound、outbound 或 error_outbound
+	jsonengine.RuleIssue
 }
 
 // NewGroupManager creates a new, uninitialized GroupManager.
@@ -38,9 +92,96 @@ func NewGroupManager(
 		store:           store,
 		settingsManager: settingsManager,
 		subGroupManager: subGroupManager,
+		stopChan:        make(chan struct{}),
+	}
+}
+
+// SetRuleEngineBuilder sets how groups compile their rule engines (see models.Group.InboundEngine).
+// It must be called before Start; engines are compiled once per loaded group revision.
+func (gm *GroupManager) SetRuleEngineBuilder(build models.RuleEngineBuilder) {
+	gm.ruleEngineBuilder = build
+}
+
+// Start performs the initial group load. If it fails, loading is retried in the
+// background with exponential backoff and IsReady reports false until it succeeds.
+// When warmup is enabled, every group's rule engines are compiled once after the load.
+func (gm *GroupManager) Start(warmup bool) {
+	if err := gm.Initialize(); err != nil {
+		logrus.WithError(err).Error("Initial group load failed, retrying in background")
+		gm.wg.Add(1)
+		go gm.retryInitialize(warmup)
+		return
+	}
+	if warmup {
+		gm.WarmUp()
+	}
+}
+
+// retryInitialize retries the initial load until it succeeds or the manager is stopped.
+func (gm *GroupManager) retryInitialize(warmup bool) {
+	defer gm.wg.Done()
+
+	delay := groupLoadRetryInitialDelay
+	for {
+		select {
+		case <-time.After(delay):
+		case <-gm.stopChan:
+			return
+		}
+
+		if err := gm.Initialize(); err != nil {
+			logrus.WithError(err).WithField("retry_in", delay).Warn("Group load failed")
+			delay = min(delay*2, groupLoadRetryMaxDelay)
+			continue
+		}
+
+		logrus.Info("Groups loaded, service is ready")
+		if warmup {
+			gm.WarmUp()
+		}
+		return
 	}
 }
 
+// IsReady reports whether the group cache has been loaded at least once.
+func (gm *GroupManager) IsReady() bool {
+	return gm.ready.Load()
+}
+
+// WarmUp compiles and caches the inbound and outbound rule engines of every cached group,
+// so invalid rules are reported at startup instead of on the first request.
+func (gm *GroupManager) WarmUp() {
+	if !gm.IsReady() {
+		return
+	}
+
+	start := time.Now()
+	groups := gm.syncer.Get()
+	failed := 0
+	for name, group := range groups {
+		engines := map[string]func() (*jsonengine.PathEngine, error){
+			models.RuleDirectionInbound:       group.InboundEngine,
+			models.RuleDirectionOutbound:      group.OutboundEngine,
+			models.RuleDirectionErrorOutbound: group.ErrorOutboundEngine,
+		}
+		for direction, engine := range engines {
+			if _, err := engine(); err != nil {
+				failed++
+				logrus.WithError(err).WithFields(logrus.Fields{
+					"group_name": name,
+					"direction":  direction,
+				}).Warn("Failed to compile rules during warmup")
+			}
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"groups":   len(groups),
+		"failed":   failed,
+		"duration": time.Since(start),
+	}).Info("Group rule engines warmed up")
+}
+
 // Initialize sets up the CacheSyncer. This is called separately to handle potential
 func (gm *GroupManager) Initialize() error {
 	loader := func() (map[string]*models.Group, error) {
@@ -67,212 +208,64 @@ func (gm *GroupManager) Initialize() error {
 			groupByID[group.ID] = group
 		}
 
+		// 上一次成功加载的缓存，用于回退加载失败的分组
+		var previous map[string]*models.Group
+		if gm.IsReady() {
+			previous = gm.syncer.Get()
+		}
+		loadErrors := make(map[string]string)
+		ruleIssues := make(map[string][]GroupRuleIssue)
+
 		groupMap := make(map[string]*models.Group, len(groups))
 		for _, group := range groups {
-			g := *group
-			g.EffectiveConfig = gm.settingsManager.GetEffectiveConfig(g.Config)
-			g.ProxyKeysMap = utils.StringToSet(g.ProxyKeys, ",")
-
-			// Parse header rules with error handling
-			if len(group.HeaderRules) > 0 {
-				if err := json.Unmarshal(group.HeaderRules, &g.HeaderRuleList); err != nil {
-					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse header rules for group")
-					g.HeaderRuleList = []models.HeaderRule{}
-				}
-			} else {
-				g.HeaderRuleList = []models.HeaderRule{}
-			}
-
-			// Parse inbound rules (request body transformation)
-			if len(group.InboundRules) > 0 {
-				if err := json.Unmarshal(group.InboundRules, &g.InboundRuleList); err != nil {
-					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse inbound rules for group")
-					g.InboundRuleList = []jsonengine.PathRule{}
-				}
-			} else {
-				g.InboundRuleList = []jsonengine.PathRule{}
-			}
-
-			// Parse outbound rules (response body transformation)
-			if len(group.OutboundRules) > 0 {
-				if err := json.Unmarshal(group.OutboundRules, &g.OutboundRuleList); err != nil {
-					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse outbound rules for group")
-					g.OutboundRuleList = []jsonengine.PathRule{}
-				}
-			} else {
-				g.OutboundRuleList = []jsonengine.PathRule{}
-			}
-
-			// Parse model redirect rules with weight support
-			g.ModelRedirectMap = make(map[string][]models.ModelRedirectTarget)
-
-			if len(group.ModelRedirectRules) > 0 {
-				hasInvalidRules := false
-				for key, value := range group.ModelRedirectRules {
-					var redirectTargets []models.ModelRedirectTarget
-
-					// 尝试多种可能的类型格式
-					// 某些情况下 GORM 可能直接返回 []map[string]interface{} 而不是 []interface{}
-					switch v := value.(type) {
-					case []interface{}:
-						// 标准 JSON 反序列化格式
-						for _, t := range v {
-							targetMap, ok := t.(map[string]interface{})
-							if !ok {
-								continue
-							}
-
-							// 提取 model
-							var model string
-							if m, ok := targetMap["model"]; ok {
-								if ms, ok := m.(string); ok {
-									model = ms
-								} else {
-									continue
-								}
-							} else {
-								continue
-							}
-
-							// 提取 weight，支持多种数字类型（包括 json.Number）
-							var weight int
-							if w, ok := targetMap["weight"]; ok {
-								switch v := w.(type) {
-								case json.Number:
-									// GORM 使用 json.Number 来避免精度损失
-									if i64, err := v.Int64(); err == nil {
-										weight = int(i64)
-									} else if f64, err := v.Float64(); err == nil {
-										weight = int(f64)
-									} else {
-										continue
-									}
-								case float64:
-									weight = int(v)
-								case float32:
-									weight = int(v)
-								case int:
-									weight = v
-								case int64:
-									weight = int(v)
-								case int32:
-									weight = int(v)
-								default:
-									continue
-								}
-							} else {
-								continue
-							}
-
-							if weight > 0 && model != "" {
-								redirectTargets = append(redirectTargets, models.ModelRedirectTarget{
-									Model:  model,
-									Weight: weight,
-								})
-							}
-						}
-						if len(redirectTargets) > 0 {
-							g.ModelRedirectMap[key] = redirectTargets
-						}
-					case []map[string]interface{}:
-						// GORM 直接返回 map 数组的格式
-						for _, targetMap := range v {
-							// 提取 model
-							var model string
-							if m, ok := targetMap["model"]; ok {
-								if ms, ok := m.(string); ok {
-									model = ms
-								} else {
-									continue
-								}
-							} else {
-								continue
-							}
-
-							// 提取 weight，支持多种数字类型（包括 json.Number）
-							var weight int
-							if w, ok := targetMap["weight"]; ok {
-								switch v := w.(type) {
-								case json.Number:
-									// GORM 使用 json.Number 来避免精度损失
-									if i64, err := v.Int64(); err == nil {
-										weight = int(i64)
-									} else if f64, err := v.Float64(); err == nil {
-										weight = int(f64)
-									} else {
-										continue
-									}
-								case float64:
-									weight = int(v)
-								case float32:
-									weight = int(v)
-								case int:
-									weight = v
-								case int64:
-									weight = int(v)
-								case int32:
-									weight = int(v)
-								default:
-									continue
-								}
-							} else {
-								continue
-							}
-
-							if weight > 0 && model != "" {
-								redirectTargets = append(redirectTargets, models.ModelRedirectTarget{
-									Model:  model,
-									Weight: weight,
-								})
-							}
-						}
-						if len(redirectTargets) > 0 {
-							g.ModelRedirectMap[key] = redirectTargets
-						}
-					default:
-						logrus.WithFields(logrus.Fields{
-							"group_name": g.Name,
-							"rule_key":   key,
-							"value_type": fmt.Sprintf("%T", value),
-						}).Error("Invalid model redirect rule format, expected array of targets")
-						hasInvalidRules = true
-					}
-				}
-				if hasInvalidRules {
-					logrus.WithField("group_name", g.Name).Warn("Group has invalid model redirect rules, some rules were skipped")
-				}
+			g, problems, issues := gm.prepareGroup(group, subGroupsByAggregateID[group.ID], groupByID)
+			if len(issues) > 0 {
+				ruleIssues[g.Name] = issues
 			}
-
-			// Load sub-groups for aggregate groups
-			if g.GroupType == "aggregate" {
-				if subGroups, ok := subGroupsByAggregateID[g.ID]; ok {
-					g.SubGroups = make([]models.GroupSubGroup, len(subGroups))
-					for i, sg := range subGroups {
-						g.SubGroups[i] = sg
-						if subGroup, exists := groupByID[sg.SubGroupID]; exists {
-							g.SubGroups[i].SubGroupName = subGroup.Name
-						}
-					}
+			if len(problems) > 0 {
+				loadErrors[g.Name] = strings.Join(problems, "; ")
+				if prev, ok := previous[g.Name]; ok {
+					logrus.WithFields(logrus.Fields{
+						"group_name": g.Name,
+						"problems":   loadErrors[g.Name],
+					}).Error("Group failed to load, keeping previous version")
+					groupMap[g.Name] = prev
+					continue
 				}
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"problems":   loadErrors[g.Name],
+				}).Warn("Group loaded with problems and has no previous version to keep")
 			}
 
-			groupMap[g.Name] = &g
+			groupMap[g.Name] = g
 			logrus.WithFields(logrus.Fields{
 				"group_name":                 g.Name,
 				"effective_config":           g.EffectiveConfig,
 				"header_rules_count":         len(g.HeaderRuleList),
 				"inbound_rules_count":        len(g.InboundRuleList),
 				"outbound_rules_count":       len(g.OutboundRuleList),
+				"error_outbound_rules_count": len(g.ErrorOutboundRuleList),
+				"prompt_templates_count":     len(g.PromptTemplateMap),
 				"model_redirect_rules_count": len(g.ModelRedirectMap),
 				"model_redirect_strict":      g.ModelRedirectStrict,
 				"sub_group_count":            len(g.SubGroups),
 			}).Debug("Loaded group with effective config")
 		}
 
+		gm.loadErrorsMu.Lock()
+		gm.loadErrors = loadErrors
+		gm.ruleIssues = ruleIssues
+		gm.loadErrorsMu.Unlock()
+
 		return groupMap, nil
 	}
 
 	afterReload := func(newCache map[string]*models.Group) {
+		gm.fetchMu.Lock()
+		gm.fetched = nil
+		gm.misses = nil
+		gm.fetchMu.Unlock()
 		gm.subGroupManager.RebuildSelectors(newCache)
 	}
 
@@ -287,34 +280,464 @@ func (gm *GroupManager) Initialize() error {
 		return fmt.Errorf("failed to create group syncer: %w", err)
 	}
 	gm.syncer = syncer
+	gm.ready.Store(true)
 	return nil
 }
 
+// prepareGroup 解析分组的配置、规则和聚合子分组，返回可缓存的分组以及加载问题和规则检查结果
+// subGroups 为聚合分组的有效子分组（weight > 0），groupByID 用于补全子分组名称
+func (gm *GroupManager) prepareGroup(
+	group *models.Group,
+	subGroups []models.GroupSubGroup,
+	groupByID map[uint]*models.Group,
+) (*models.Group, []string, []GroupRuleIssue) {
+	var problems []string
+	g := *group
+	g.EffectiveConfig = gm.settingsManager.GetEffectiveConfig(g.Config)
+	g.ProxyKeysMap = utils.StringToSet(g.ProxyKeys, ",")
+	g.EffectiveConfig.BatchProxyKeysMap = utils.StringToSet(g.EffectiveConfig.BatchProxyKeys, ",")
+	g.EffectiveConfig.ProxyKeyHeaderList = utils.SplitAndTrim(g.EffectiveConfig.ProxyKeyHeaders, ",")
+	g.SetRuleEngineBuilder(gm.ruleEngineBuilder)
+
+	// Parse header rules with error handling
+	if len(group.HeaderRules) > 0 {
+		if err := json.Unmarshal(group.HeaderRules, &g.HeaderRuleList); err != nil {
+			logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse header rules for group")
+			problems = append(problems, fmt.Sprintf("invalid header rules: %v", err))
+			g.HeaderRuleList = []models.HeaderRule{}
+		}
+	} else {
+		g.HeaderRuleList = []models.HeaderRule{}
+	}
+
+	// Parse inbound rules (request body transformation)
+	if len(group.InboundRules) > 0 {
+		if err := json.Unmarshal(group.InboundRules, &g.InboundRuleList); err != nil {
+			logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse inbound rules for group")
+			problems = append(problems, fmt.Sprintf("invalid inbound rules: %v", err))
+			g.InboundRuleList = []jsonengine.PathRule{}
+		}
+	} else {
+		g.InboundRuleList = []jsonengine.PathRule{}
+	}
+
+	// Parse outbound rules (response body transformation)
+	if len(group.OutboundRules) > 0 {
+		if err := json.Unmarshal(group.OutboundRules, &g.OutboundRuleList); err != nil {
+			logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse outbound rules for group")
+			problems = append(problems, fmt.Sprintf("invalid outbound rules: %v", err))
+			g.OutboundRuleList = []jsonengine.PathRule{}
+		}
+	} else {
+		g.OutboundRuleList = []jsonengine.PathRule{}
+	}
+
+	// Parse error outbound rules (non-2xx response body transformation)
+	if len(group.ErrorOutboundRules) > 0 {
+		if err := json.Unmarshal(group.ErrorOutboundRules, &g.ErrorOutboundRuleList); err != nil {
+			logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse error outbound rules for group")
+			problems = append(problems, fmt.Sprintf("invalid error outbound rules: %v", err))
+			g.ErrorOutboundRuleList = []jsonengine.PathRule{}
+		}
+	} else {
+		g.ErrorOutboundRuleList = []jsonengine.PathRule{}
+	}
+
+	// Compile body scripts
+	if src := strings.TrimSpace(g.EffectiveConfig.InboundScript); src != "" {
+		program, err := script.Compile(src)
+		if err != nil {
+			logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to compile inbound script for group")
+			problems = append(problems, fmt.Sprintf("invalid inbound script: %v", err))
+		}
+		g.InboundScriptProgram = program
+	}
+	if src := strings.TrimSpace(g.EffectiveConfig.OutboundScript); src != "" {
+		program, err := script.Compile(src)
+		if err != nil {
+			logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to compile outbound script for group")
+			problems = append(problems, fmt.Sprintf("invalid outbound script: %v", err))
+		}
+		g.OutboundScriptProgram = program
+	}
+
+	// Parse prompt templates
+	g.PromptTemplateMap = make(map[string]models.PromptTemplate)
+	if len(group.PromptTemplates) > 0 {
+		if err := json.Unmarshal(group.PromptTemplates, &g.PromptTemplateMap); err != nil {
+			logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse prompt templates for group")
+			problems = append(problems, fmt.Sprintf("invalid prompt templates: %v", err))
+			g.PromptTemplateMap = make(map[string]models.PromptTemplate)
+		}
+	}
+
+	// Parse model redirect rules with weight support
+	g.ModelRedirectMap = make(map[string][]models.ModelRedirectTarget)
+
+	if len(group.ModelRedirectRules) > 0 {
+		hasInvalidRules := false
+		for key, value := range group.ModelRedirectRules {
+			var redirectTargets []models.ModelRedirectTarget
+
+			// 尝试多种可能的类型格式
+			// 某些情况下 GORM 可能直接返回 []map[string]interface{} 而不是 []interface{}
+			switch v := value.(type) {
+			case []interface{}:
+				// 标准 JSON 反序列化格式
+				for _, t := range v {
+					targetMap, ok := t.(map[string]interface{})
+					if !ok {
+						continue
+					}
+
+					// 提取 model
+					var model string
+					if m, ok := targetMap["model"]; ok {
+						if ms, ok := m.(string); ok {
+							model = ms
+						} else {
+							continue
+						}
+					} else {
+						continue
+					}
+
+					// 提取 weight，支持多种数字类型（包括 json.Number）
+					var weight int
+					if w, ok := targetMap["weight"]; ok {
+						switch v := w.(type) {
+						case json.Number:
+							// GORM 使用 json.Number 来避免精度损失
+							if i64, err := v.Int64(); err == nil {
+								weight = int(i64)
+							} else if f64, err := v.Float64(); err == nil {
+								weight = int(f64)
+							} else {
+								continue
+							}
+						case float64:
+							weight = int(v)
+						case float32:
+							weight = int(v)
+						case int:
+							weight = v
+						case int64:
+							weight = int(v)
+						case int32:
+							weight = int(v)
+						default:
+							continue
+						}
+					} else {
+						continue
+					}
+
+					if weight > 0 && model != "" {
+						redirectTargets = append(redirectTargets, models.ModelRedirectTarget{
+							Model:  model,
+							Weight: weight,
+						})
+					}
+				}
+				if len(redirectTargets) > 0 {
+					g.ModelRedirectMap[key] = redirectTargets
+				}
+			case []map[string]interface{}:
+				// GORM 直接返回 map 数组的格式
+				for _, targetMap := range v {
+					// 提取 model
+					var model string
+					if m, ok := targetMap["model"]; ok {
+						if ms, ok := m.(string); ok {
+							model = ms
+						} else {
+							continue
+						}
+					} else {
+						continue
+					}
+
+					// 提取 weight，支持多种数字类型（包括 json.Number）
+					var weight int
+					if w, ok := targetMap["weight"]; ok {
+						switch v := w.(type) {
+						case json.Number:
+							// GORM 使用 json.Number 来避免精度损失
+							if i64, err := v.Int64(); err == nil {
+								weight = int(i64)
+							} else if f64, err := v.Float64(); err == nil {
+								weight = int(f64)
+							} else {
+								continue
+							}
+						case float64:
+							weight = int(v)
+						case float32:
+							weight = int(v)
+						case int:
+							weight = v
+						case int64:
+							weight = int(v)
+						case int32:
+							weight = int(v)
+						default:
+							continue
+						}
+					} else {
+						continue
+					}
+
+					if weight > 0 && model != "" {
+						redirectTargets = append(redirectTargets, models.ModelRedirectTarget{
+							Model:  model,
+							Weight: weight,
+						})
+					}
+				}
+				if len(redirectTargets) > 0 {
+					g.ModelRedirectMap[key] = redirectTargets
+				}
+			default:
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"rule_key":   key,
+					"value_type": fmt.Sprintf("%T", value),
+				}).Error("Invalid model redirect rule format, expected array of targets")
+				hasInvalidRules = true
+			}
+		}
+		if hasInvalidRules {
+			logrus.WithField("group_name", g.Name).Warn("Group has invalid model redirect rules, some rules were skipped")
+		}
+	}
+
+	// Load sub-groups for aggregate groups
+	if g.GroupType == "aggregate" {
+		if len(subGroups) > 0 {
+			g.SubGroups = make([]models.GroupSubGroup, len(subGroups))
+			for i, sg := range subGroups {
+				g.SubGroups[i] = sg
+				if subGroup, exists := groupByID[sg.SubGroupID]; exists {
+					g.SubGroups[i].SubGroupName = subGroup.Name
+				}
+			}
+		}
+	}
+
+	issues := lintGroupRules(&g)
+	problems = append(problems, validateLoadedGroup(&g, issues)...)
+	return &g, problems, issues
+}
+
+// lintGroupRules 静态检查分组的入站和出站规则，警告记录到日志，错误由 validateLoadedGroup 处理
+func lintGroupRules(g *models.Group) []GroupRuleIssue {
+	issues := validateGroupRules(g)
+	for _, issue := range issues {
+		if issue.Severity == jsonengine.SeverityWarning {
+			logrus.WithFields(logrus.Fields{
+				"group_name": g.Name,
+				"direction":  issue.Direction,
+				"rule":       issue.Rule,
+				"path":       issue.Path,
+				"code":       issue.Code,
+			}).Warn("Group rule will not apply as configured: " + issue.Message)
+		}
+	}
+	return issues
+}
+
+// validateGroupRules 静态检查分组各方向的规则，不记录日志
+func validateGroupRules(g *models.Group) []GroupRuleIssue {
+	var issues []GroupRuleIssue
+	for _, direction := range ruleDirections {
+		for _, issue := range jsonengine.ValidateRules(g.RuleList(direction)) {
+			issues = append(issues, GroupRuleIssue{Direction: direction, RuleIssue: issue})
+		}
+	}
+	return issues
+}
+
+// ruleDirections 分组规则的方向，按请求处理顺序排列
+var ruleDirections = []string{models.RuleDirectionInbound, models.RuleDirectionOutbound, models.RuleDirectionErrorOutbound}
+
+// validateLoadedGroup 检查分组的规则能否编译、聚合分组是否有可选的子分组
+// 规则静态检查中的错误视为加载失败，避免带着无效规则改写流量
+func validateLoadedGroup(g *models.Group, issues []GroupRuleIssue) []string {
+	var problems []string
+	for _, issue := range issues {
+		if issue.Severity == jsonengine.SeverityError {
+			problems = append(problems, fmt.Sprintf("invalid %s rule #%d (%s): %s", issue.Direction, issue.Rule, issue.Path, issue.Message))
+		}
+	}
+
+	// 静态检查已定位到具体规则时无需再编译
+	lintFailed := len(problems) > 0
+	if !lintFailed && len(g.InboundRuleList) > 0 {
+		if _, err := jsonengine.CompileRules(g.InboundRuleList, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("inbound rules failed to compile: %v", err))
+		}
+	}
+	if !lintFailed && len(g.OutboundRuleList) > 0 {
+		if _, err := jsonengine.CompileRules(g.OutboundRuleList, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("outbound rules failed to compile: %v", err))
+		}
+	}
+	if !lintFailed && len(g.ErrorOutboundRuleList) > 0 {
+		if _, err := jsonengine.CompileRules(g.ErrorOutboundRuleList, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("error outbound rules failed to compile: %v", err))
+		}
+	}
+	if g.GroupType == "aggregate" && len(g.SubGroups) == 0 {
+		problems = append(problems, "aggregate group has no sub-groups with weight > 0")
+	}
+	return problems
+}
+
+// GroupRuleIssues returns the rule lint issues found when the group was last loaded.
+func (gm *GroupManager) GroupRuleIssues(name string) []GroupRuleIssue {
+	gm.loadErrorsMu.RLock()
+	defer gm.loadErrorsMu.RUnlock()
+	return gm.ruleIssues[name]
+}
+
+// GroupLoadError returns why the group failed its last reload, or an empty string.
+// A failed group keeps serving the previous version loaded successfully.
+func (gm *GroupManager) GroupLoadError(name string) string {
+	gm.loadErrorsMu.RLock()
+	defer gm.loadErrorsMu.RUnlock()
+	return gm.loadErrors[name]
+}
+
 // GetGroupByName retrieves a single group by its name from the cache.
+// On a cache miss the group is read from the database, so a group created on another
+// instance is routable before the cache invalidation arrives. Names that do not exist, and
+// groups that fail to load, are remembered for a short time to keep them from hitting the
+// database.
 func (gm *GroupManager) GetGroupByName(name string) (*models.Group, error) {
-	if gm.syncer == nil {
-		return nil, fmt.Errorf("GroupManager is not initialized")
+	if !gm.IsReady() {
+		return nil, ErrGroupsNotLoaded
 	}
 
 	groups := gm.syncer.Get()
 	group, ok := groups[name]
 	if !ok {
-		return nil, gorm.ErrRecordNotFound
+		return gm.fetchGroup(name)
+	}
+	return group, nil
+}
+
+// fetchGroup 从数据库读取缓存中不存在的分组，结果保留到下一次缓存重新加载
+// 加载有问题的分组不会被使用（与缓存加载时保留上一个可用版本一致，这里没有上一个版本），
+// 聚合分组读取后注册子分组选择器
+func (gm *GroupManager) fetchGroup(name string) (*models.Group, error) {
+	gm.fetchMu.Lock()
+	if group, ok := gm.fetched[name]; ok {
+		gm.fetchMu.Unlock()
+		return group, nil
+	}
+	if miss, ok := gm.misses[name]; ok && time.Since(miss.at) < groupMissTTL {
+		gm.fetchMu.Unlock()
+		return nil, miss.err
+	}
+	gm.fetchMu.Unlock()
+
+	// 未认证的请求也会触发读取，同一分组名的并发未命中只读一次数据库
+	v, err, _ := gm.fetchFlight.Do(name, func() (any, error) {
+		return gm.loadFetchedGroup(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*models.Group), nil
+}
+
+// loadFetchedGroup 读取分组并记录结果：不存在或有问题的分组记为未命中
+func (gm *GroupManager) loadFetchedGroup(name string) (*models.Group, error) {
+	group, problems, err := gm.loadGroup(name)
+	if err == nil && len(problems) > 0 {
+		logrus.WithFields(logrus.Fields{
+			"group_name": name,
+			"problems":   strings.Join(problems, "; "),
+		}).Error("Group read from database has problems, not using it")
+		err = fmt.Errorf("group %s failed to load: %s", name, strings.Join(problems, "; "))
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || len(problems) > 0 {
+		gm.fetchMu.Lock()
+		if gm.misses == nil || len(gm.misses) >= groupMissMaxEntries {
+			gm.misses = make(map[string]groupMiss)
+		}
+		gm.misses[name] = groupMiss{at: time.Now(), err: err}
+		gm.fetchMu.Unlock()
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gm.fetchMu.Lock()
+	if existing, ok := gm.fetched[name]; ok {
+		gm.fetchMu.Unlock()
+		return existing, nil
+	}
+	if gm.fetched == nil {
+		gm.fetched = make(map[string]*models.Group)
+	}
+	gm.fetched[name] = group
+	delete(gm.misses, name)
+	gm.fetchMu.Unlock()
+
+	if group.GroupType == "aggregate" {
+		gm.subGroupManager.RegisterSelector(group)
 	}
 	return group, nil
 }
 
+// loadGroup 从数据库加载单个分组，处理方式与缓存加载相同，同时返回加载问题
+func (gm *GroupManager) loadGroup(name string) (*models.Group, []string, error) {
+	var group models.Group
+	if err := gm.db.Where("name = ?", name).First(&group).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var subGroups []models.GroupSubGroup
+	groupByID := make(map[uint]*models.Group)
+	if group.GroupType == "aggregate" {
+		if err := gm.db.Where("group_id = ? AND weight > 0", group.ID).Find(&subGroups).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to load valid sub groups: %w", err)
+		}
+		if len(subGroups) > 0 {
+			ids := make([]uint, len(subGroups))
+			for i, sg := range subGroups {
+				ids[i] = sg.SubGroupID
+			}
+			var members []*models.Group
+			if err := gm.db.Select("id", "name").Where("id IN ?", ids).Find(&members).Error; err != nil {
+				return nil, nil, fmt.Errorf("failed to load sub group names: %w", err)
+			}
+			for _, member := range members {
+				groupByID[member.ID] = member
+			}
+		}
+	}
+
+	g, problems, _ := gm.prepareGroup(&group, subGroups, groupByID)
+	logrus.WithField("group_name", g.Name).Debug("Group not in cache, read from database")
+	return g, problems, nil
+}
+
 // Invalidate triggers a cache reload across all instances.
 func (gm *GroupManager) Invalidate() error {
-	if gm.syncer == nil {
-		return fmt.Errorf("GroupManager is not initialized")
+	if !gm.IsReady() {
+		return ErrGroupsNotLoaded
 	}
 	return gm.syncer.Invalidate()
 }
 
 // Stop gracefully stops the GroupManager's background syncer.
 func (gm *GroupManager) Stop(ctx context.Context) {
-	if gm.syncer != nil {
+	close(gm.stopChan)
+	gm.wg.Wait()
+	if gm.IsReady() {
 		gm.syncer.Stop()
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -16,8 +17,11 @@ import (
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/httpclient"
 	"gpt-load/internal/jsonengine"
 	"gpt-load/internal/models"
+	"gpt-load/internal/pricing"
+	"gpt-load/internal/script"
 	"gpt-load/internal/utils"
 
 	"github.com/sirupsen/logrus"
@@ -101,6 +105,8 @@ type GroupCreateParams struct {
 	HeaderRules         []models.HeaderRule
 	InboundRules        []jsonengine.PathRule
 	OutboundRules       []jsonengine.PathRule
+	ErrorOutboundRules  []jsonengine.PathRule
+	PromptTemplates     map[string]models.PromptTemplate
 	ProxyKeys           string
 	SubGroups           []SubGroupInput
 }
@@ -125,6 +131,8 @@ type GroupUpdateParams struct {
 	HeaderRules         *[]models.HeaderRule
 	InboundRules        *[]jsonengine.PathRule
 	OutboundRules       *[]jsonengine.PathRule
+	ErrorOutboundRules  *[]jsonengine.PathRule
+	PromptTemplates     map[string]models.PromptTemplate
 	ProxyKeys           *string
 	SubGroups           *[]SubGroupInput
 }
@@ -157,6 +165,11 @@ type ConfigOption struct {
 	Name         string
 	Description  string
 	DefaultValue any
+	Type         string
+	MinValue     *int
+	MaxValue     *int
+	Options      []string
+	HotReload    bool
 }
 
 // CreateGroup validates and persists a new group.
@@ -235,6 +248,19 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		outboundRulesJSON = datatypes.JSON("[]")
 	}
 
+	errorOutboundRulesJSON, err := s.normalizeJSONRules(params.ErrorOutboundRules)
+	if err != nil {
+		return nil, err
+	}
+	if errorOutboundRulesJSON == nil {
+		errorOutboundRulesJSON = datatypes.JSON("[]")
+	}
+
+	promptTemplatesJSON, err := normalizePromptTemplates(params.PromptTemplates)
+	if err != nil {
+		return nil, err
+	}
+
 	// Validate model redirect rules for aggregate groups
 	if groupType == "aggregate" && len(params.ModelRedirectRules) > 0 {
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.aggregate_no_model_redirect", nil)
@@ -262,9 +288,47 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		HeaderRules:         headerRulesJSON,
 		InboundRules:        inboundRulesJSON,
 		OutboundRules:       outboundRulesJSON,
+		ErrorOutboundRules:  errorOutboundRulesJSON,
+		PromptTemplates:     promptTemplatesJSON,
 		ProxyKeys:           strings.TrimSpace(params.ProxyKeys),
 	}
 
+	if err := s.validateSignedPassthrough(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateUpstreamHostOverride(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateProxyKeyLocations(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateInboundJSONSchema(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateRetryStatusCodes(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateModelPrices(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateRequestSeed(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAuditRedactPaths(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateBodyScripts(&group); err != nil {
+		return nil, err
+	}
+
 	tx := s.db.WithContext(ctx).Begin()
 	if err := tx.Error; err != nil {
 		return nil, app_errors.ErrDatabase
@@ -493,6 +557,61 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		group.OutboundRules = outboundRulesJSON
 	}
 
+	if params.ErrorOutboundRules != nil {
+		errorOutboundRulesJSON, err := s.normalizeJSONRules(*params.ErrorOutboundRules)
+		if err != nil {
+			return nil, err
+		}
+		if errorOutboundRulesJSON == nil {
+			errorOutboundRulesJSON = datatypes.JSON("[]")
+		}
+		group.ErrorOutboundRules = errorOutboundRulesJSON
+	}
+
+	if params.PromptTemplates != nil {
+		promptTemplatesJSON, err := normalizePromptTemplates(params.PromptTemplates)
+		if err != nil {
+			return nil, err
+		}
+		group.PromptTemplates = promptTemplatesJSON
+	}
+
+	if err := s.validateSignedPassthrough(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateUpstreamHostOverride(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateProxyKeyLocations(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateInboundJSONSchema(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateRetryStatusCodes(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateModelPrices(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateRequestSeed(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateAuditRedactPaths(&group); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateBodyScripts(&group); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Save(&group).Error; err != nil {
 		return nil, app_errors.ParseDBError(err)
 	}
@@ -555,6 +674,10 @@ func (s *GroupService) DeleteGroup(ctx context.Context, id uint) error {
 		return app_errors.ErrDatabase
 	}
 
+	if err := tx.Where("group_id = ?", id).Delete(&models.Budget{}).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+
 	if err := tx.Delete(&models.Group{}, id).Error; err != nil {
 		return app_errors.ParseDBError(err)
 	}
@@ -818,51 +941,25 @@ func (s *GroupService) getAggregateGroupStats(ctx context.Context, groupID uint)
 }
 
 // GetGroupConfigOptions returns metadata describing available overrides.
+// Defaults reflect the current system settings.
 func (s *GroupService) GetGroupConfigOptions() ([]ConfigOption, error) {
-	defaultSettings := utils.DefaultSystemSettings()
-	settingDefinitions := utils.GenerateSettingsMetadata(&defaultSettings)
-	defMap := make(map[string]models.SystemSettingInfo)
-	for _, def := range settingDefinitions {
-		defMap[def.Key] = def
-	}
-
 	currentSettings := s.settingsManager.GetSettings()
-	currentSettingsValue := reflect.ValueOf(currentSettings)
-	currentSettingsType := currentSettingsValue.Type()
-	jsonToFieldMap := make(map[string]string)
-	for i := 0; i < currentSettingsType.NumField(); i++ {
-		field := currentSettingsType.Field(i)
-		jsonTag := strings.Split(field.Tag.Get("json"), ",")[0]
-		if jsonTag != "" {
-			jsonToFieldMap[jsonTag] = field.Name
-		}
-	}
 
-	groupConfigType := reflect.TypeOf(models.GroupConfig{})
 	var options []ConfigOption
-	for i := 0; i < groupConfigType.NumField(); i++ {
-		field := groupConfigType.Field(i)
-		jsonTag := field.Tag.Get("json")
-		key := strings.Split(jsonTag, ",")[0]
-		if key == "" || key == "-" {
+	for _, def := range config.SettingDefs() {
+		if !def.GroupOverride {
 			continue
 		}
-
-		definition, ok := defMap[key]
-		if !ok {
-			continue
-		}
-
-		var defaultValue any
-		if fieldName, ok := jsonToFieldMap[key]; ok {
-			defaultValue = currentSettingsValue.FieldByName(fieldName).Interface()
-		}
-
 		options = append(options, ConfigOption{
-			Key:          key,
-			Name:         definition.Name,
-			Description:  definition.Description,
-			DefaultValue: defaultValue,
+			Key:          def.Key,
+			Name:         def.Name,
+			Description:  def.Description,
+			DefaultValue: def.Value(currentSettings),
+			Type:         def.Type,
+			MinValue:     def.Min,
+			MaxValue:     def.Max,
+			Options:      def.Options,
+			HotReload:    def.HotReload,
 		})
 	}
 
@@ -935,11 +1032,17 @@ func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule) (datatype
 			continue
 		}
 		canonicalKey := http.CanonicalHeaderKey(key)
-		if seenKeys[canonicalKey] {
+		paths, methods := normalizeRoute(rule.Paths, rule.Methods)
+		if err := jsonengine.ValidateRoute(paths, methods); err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_header_rule", map[string]any{"key": canonicalKey, "error": err.Error()})
+		}
+		// The same header may be set differently for different routes
+		seenKey := canonicalKey + routeKey(paths, methods)
+		if seenKeys[seenKey] {
 			return nil, NewI18nError(app_errors.ErrValidation, "validation.duplicate_header", map[string]any{"key": canonicalKey})
 		}
-		seenKeys[canonicalKey] = true
-		normalized = append(normalized, models.HeaderRule{Key: canonicalKey, Value: rule.Value, Action: rule.Action})
+		seenKeys[seenKey] = true
+		normalized = append(normalized, models.HeaderRule{Key: canonicalKey, Value: rule.Value, Action: rule.Action, Paths: paths, Methods: methods})
 	}
 
 	if len(normalized) == 0 {
@@ -954,6 +1057,41 @@ func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule) (datatype
 	return datatypes.JSON(headerRulesBytes), nil
 }
 
+// normalizeRoute trims route selectors, drops empty entries and upper-cases methods.
+func normalizeRoute(paths, methods []string) ([]string, []string) {
+	var cleanPaths, cleanMethods []string
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			cleanPaths = append(cleanPaths, p)
+		}
+	}
+	for _, m := range methods {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+			cleanMethods = append(cleanMethods, m)
+		}
+	}
+	return cleanPaths, cleanMethods
+}
+
+// normalizeModelSelectors trims model selectors and drops empty entries.
+func normalizeModelSelectors(patterns []string) []string {
+	var clean []string
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p != "" {
+			clean = append(clean, p)
+		}
+	}
+	return clean
+}
+
+// routeKey identifies a route selector for duplicate detection.
+func routeKey(paths, methods []string) string {
+	if len(paths) == 0 && len(methods) == 0 {
+		return ""
+	}
+	return "|" + strings.Join(methods, ",") + "|" + strings.Join(paths, ",")
+}
+
 // normalizeJSONRules validates and normalizes JSON transformation rules.
 func (s *GroupService) normalizeJSONRules(rules []jsonengine.PathRule) (datatypes.JSON, error) {
 	if len(rules) == 0 {
@@ -969,17 +1107,28 @@ func (s *GroupService) normalizeJSONRules(rules []jsonengine.PathRule) (datatype
 			continue
 		}
 		path := strings.TrimSpace(rule.Path)
-		if seenPaths[path] {
+		paths, methods := normalizeRoute(rule.Paths, rule.Methods)
+		ruleModels := normalizeModelSelectors(rule.Models)
+		// 同一路径可以针对不同路由和模型分别配置
+		seenKey := path + routeKey(paths, methods) + routeKey(nil, ruleModels)
+		if seenPaths[seenKey] {
 			return nil, NewI18nError(app_errors.ErrValidation, "validation.duplicate_json_rule", map[string]any{"key": path})
 		}
-		seenPaths[path] = true
-		normalized = append(normalized, jsonengine.PathRule{Path: path, Action: rule.Action, Value: rule.Value, ValueBytes: rule.ValueBytes})
+		seenPaths[seenKey] = true
+		normalized = append(normalized, jsonengine.PathRule{Path: path, Action: rule.Action, Value: rule.Value, ValueBytes: rule.ValueBytes, Expr: rule.Expr, Priority: rule.Priority, Min: rule.Min, Max: rule.Max, Default: rule.Default, Paths: paths, Methods: methods, Models: ruleModels})
 	}
 
 	if len(normalized) == 0 {
 		return nil, nil
 	}
 
+	// 拒绝无法生效的规则，警告级别的问题在加载分组时记录
+	for _, issue := range jsonengine.ValidateRules(normalized) {
+		if issue.Severity == jsonengine.SeverityError {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_json_rule", map[string]any{"path": issue.Path, "error": issue.Message})
+		}
+	}
+
 	rulesBytes, err := json.Marshal(normalized)
 	if err != nil {
 		return nil, NewI18nError(app_errors.ErrInternalServer, "error.process_json_rules", map[string]any{"error": err.Error()})
@@ -1127,6 +1276,243 @@ func convertToJSONMap(input map[string][]models.ModelRedirectTarget) datatypes.J
 	return result
 }
 
+// normalizePromptTemplates validates named prompt templates and serializes them for storage.
+func normalizePromptTemplates(templates map[string]models.PromptTemplate) (datatypes.JSON, error) {
+	if len(templates) == 0 {
+		return datatypes.JSON("{}"), nil
+	}
+
+	normalized := make(map[string]models.PromptTemplate, len(templates))
+	for name, tmpl := range templates {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_prompt_template", map[string]any{"error": "template name cannot be empty"})
+		}
+		if strings.TrimSpace(tmpl.System) == "" && len(tmpl.Messages) == 0 {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_prompt_template", map[string]any{"error": fmt.Sprintf("template %s must define a system prompt or messages", name)})
+		}
+		for _, msg := range tmpl.Messages {
+			if msg.Role != "user" && msg.Role != "assistant" {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_prompt_template", map[string]any{"error": fmt.Sprintf("template %s has invalid message role: %s", name, msg.Role)})
+			}
+			if strings.TrimSpace(msg.Content) == "" {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_prompt_template", map[string]any{"error": fmt.Sprintf("template %s has an empty message", name)})
+			}
+		}
+		for _, text := range promptTemplateTexts(tmpl) {
+			if fragment := malformedPlaceholder(text); fragment != "" {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_prompt_template", map[string]any{"error": fmt.Sprintf("template %s has a malformed placeholder: %s", name, fragment)})
+			}
+		}
+		normalized[name] = tmpl
+	}
+
+	templatesBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, NewI18nError(app_errors.ErrInternalServer, "error.process_json_rules", map[string]any{"error": err.Error()})
+	}
+	return datatypes.JSON(templatesBytes), nil
+}
+
+// promptTemplateTexts returns the system prompt and message contents of a template.
+func promptTemplateTexts(tmpl models.PromptTemplate) []string {
+	texts := make([]string, 0, len(tmpl.Messages)+1)
+	texts = append(texts, tmpl.System)
+	for _, msg := range tmpl.Messages {
+		texts = append(texts, msg.Content)
+	}
+	return texts
+}
+
+// malformedPlaceholder returns the text from the first "{{" that does not start a valid
+// {{var}} placeholder, or "" when every placeholder is well formed.
+func malformedPlaceholder(text string) string {
+	rest := models.PromptPlaceholder.ReplaceAllString(text, "")
+	i := strings.Index(rest, "{{")
+	if i < 0 {
+		return ""
+	}
+	fragment := rest[i:]
+	if end := strings.Index(fragment, "}}"); end >= 0 {
+		fragment = fragment[:end+2]
+	} else if len(fragment) > 32 {
+		fragment = fragment[:32]
+	}
+	return fragment
+}
+
+// validateSignedPassthrough rejects rules that would alter a signed request.
+// With signed_request_passthrough enabled the proxy forwards the body and client
+// headers unchanged, so any configured rewrite would be silently ignored.
+// Response rewrites are rejected too, since they require dropping Accept-Encoding.
+func (s *GroupService) validateSignedPassthrough(group *models.Group) error {
+	cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+	if !cfg.SignedRequestPassthrough {
+		return nil
+	}
+
+	conflicts := []struct {
+		field string
+		set   bool
+	}{
+		{"param_overrides", len(group.ParamOverrides) > 0},
+		{"model_redirect_rules", len(group.ModelRedirectRules) > 0},
+		{"header_rules", hasJSONEntries(group.HeaderRules)},
+		{"inbound_rules", hasJSONEntries(group.InboundRules)},
+		{"outbound_rules", hasJSONEntries(group.OutboundRules)},
+		{"error_outbound_rules", hasJSONEntries(group.ErrorOutboundRules)},
+		{"prompt_templates", hasJSONEntries(group.PromptTemplates)},
+		{"request_seed", strings.TrimSpace(cfg.RequestSeed) != ""},
+		{"response_watermark_field", strings.TrimSpace(cfg.ResponseWatermarkField) != ""},
+		{"response_post_processors", strings.TrimSpace(cfg.ResponsePostProcessors) != ""},
+		{"inbound_json_schema", strings.TrimSpace(cfg.InboundJSONSchema) != ""},
+		{"inbound_script", strings.TrimSpace(cfg.InboundScript) != ""},
+		{"outbound_script", strings.TrimSpace(cfg.OutboundScript) != ""},
+	}
+	for _, c := range conflicts {
+		if c.set {
+			return NewI18nError(app_errors.ErrValidation, "validation.signed_passthrough_conflict", map[string]any{"field": c.field})
+		}
+	}
+	return nil
+}
+
+// validateUpstreamHostOverride rejects Host header and TLS server name overrides that
+// would fail certificate verification, and a server name set for plain HTTP upstreams.
+func (s *GroupService) validateUpstreamHostOverride(group *models.Group) error {
+	cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+	if _, err := httpclient.NewHostOverride(cfg.UpstreamHostHeader, cfg.UpstreamTLSServerName); err != nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_upstream_host_override", map[string]any{"error": err.Error()})
+	}
+	if strings.TrimSpace(cfg.UpstreamTLSServerName) == "" || len(group.Upstreams) == 0 {
+		return nil
+	}
+
+	var defs []struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(group.Upstreams, &defs); err != nil || len(defs) == 0 {
+		return nil
+	}
+	for _, def := range defs {
+		if strings.HasPrefix(def.URL, "https://") {
+			return nil
+		}
+	}
+	return NewI18nError(app_errors.ErrValidation, "validation.invalid_upstream_host_override", map[string]any{"error": "TLS server name requires at least one https upstream"})
+}
+
+// reservedProxyKeyHeaders are request headers the proxy forwards or relies on,
+// so they cannot carry the proxy key.
+var reservedProxyKeyHeaders = []string{"Host", "Content-Type", "Content-Length", "Content-Encoding", "Transfer-Encoding", "Accept", "Accept-Encoding", "Connection", "User-Agent"}
+
+// validateProxyKeyLocations rejects custom proxy key headers that are not valid
+// header names or would strip a header the upstream needs, and query parameter
+// names that cannot be matched in a URL.
+func (s *GroupService) validateProxyKeyLocations(group *models.Group) error {
+	cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+	for _, name := range utils.SplitAndTrim(cfg.ProxyKeyHeaders, ",") {
+		if !isHeaderToken(name) {
+			return NewI18nError(app_errors.ErrValidation, "validation.invalid_proxy_key_location", map[string]any{"error": fmt.Sprintf("invalid header name %q", name)})
+		}
+		if slices.Contains(reservedProxyKeyHeaders, http.CanonicalHeaderKey(name)) {
+			return NewI18nError(app_errors.ErrValidation, "validation.invalid_proxy_key_location", map[string]any{"error": fmt.Sprintf("header %s cannot carry the proxy key", http.CanonicalHeaderKey(name))})
+		}
+	}
+	if param := cfg.ProxyKeyQueryParam; param != strings.TrimSpace(param) || strings.ContainsAny(param, "&=#?+%") {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_proxy_key_location", map[string]any{"error": fmt.Sprintf("invalid query parameter name %q", param)})
+	}
+	return nil
+}
+
+// validateInboundJSONSchema rejects an inbound JSON schema that does not compile.
+func (s *GroupService) validateInboundJSONSchema(group *models.Group) error {
+	cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+	spec := strings.TrimSpace(cfg.InboundJSONSchema)
+	if spec == "" {
+		return nil
+	}
+	if _, err := jsonengine.CompileSchema([]byte(spec)); err != nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_inbound_json_schema", map[string]any{"error": err.Error()})
+	}
+	return nil
+}
+
+// validateRetryStatusCodes rejects a retry status list that does not parse.
+func (s *GroupService) validateRetryStatusCodes(group *models.Group) error {
+	cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+	if _, err := utils.ParseStatusPatterns(cfg.RetryStatusCodes); err != nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_retry_status_codes", map[string]any{"error": err.Error()})
+	}
+	return nil
+}
+
+// validateModelPrices rejects model price entries that do not parse.
+func (s *GroupService) validateModelPrices(group *models.Group) error {
+	cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+	if err := pricing.ValidateOverrides(cfg.ModelPrices); err != nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_model_prices", map[string]any{"error": err.Error()})
+	}
+	return nil
+}
+
+// validateRequestSeed rejects a request seed that is neither "hash" nor a 32-bit integer.
+func (s *GroupService) validateRequestSeed(group *models.Group) error {
+	cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+	if _, _, err := utils.ParseRequestSeed(cfg.RequestSeed); err != nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_request_seed", map[string]any{"error": err.Error()})
+	}
+	return nil
+}
+
+// validateAuditRedactPaths rejects audit redaction paths that do not parse.
+func (s *GroupService) validateAuditRedactPaths(group *models.Group) error {
+	cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+	if _, err := jsonengine.ParseMaskRules(cfg.AuditRedactPaths, jsonengine.MaskRedact); err != nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_audit_redact_paths", map[string]any{"error": err.Error()})
+	}
+	return nil
+}
+
+// validateBodyScripts rejects inbound and outbound scripts that do not compile.
+func (s *GroupService) validateBodyScripts(group *models.Group) error {
+	cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+	for _, src := range []string{cfg.InboundScript, cfg.OutboundScript} {
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+		if _, err := script.Compile(src); err != nil {
+			return NewI18nError(app_errors.ErrValidation, "validation.invalid_body_script", map[string]any{"error": err.Error()})
+		}
+	}
+	return nil
+}
+
+// isHeaderToken reports whether s is a valid HTTP header field name (RFC 9110 token).
+func isHeaderToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// hasJSONEntries reports whether a stored JSON array or object is non-empty.
+func hasJSONEntries(data datatypes.JSON) bool {
+	switch strings.TrimSpace(string(data)) {
+	case "", "null", "[]", "{}":
+		return false
+	}
+	return true
+}
+
 // validateModelRedirectRules validates the format and content of model redirect rules
 func validateModelRedirectRules(rules map[string][]models.ModelRedirectTarget) error {
 	if len(rules) == 0 {
@@ -92,10 +92,42 @@ func (s *KeyService) processAndCreateKeys(
 	keys []string,
 	progressCallback func(processed int),
 ) (addedCount int, ignoredCount int, err error) {
+	newKeysToCreate, err := s.prepareNewKeys(groupID, keys)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(newKeysToCreate) == 0 {
+		return 0, len(keys), nil
+	}
+
+	// Use KeyProvider to add keys in chunks
+	for i := 0; i < len(newKeysToCreate); i += chunkSize {
+		end := i + chunkSize
+		if end > len(newKeysToCreate) {
+			end = len(newKeysToCreate)
+		}
+		chunk := newKeysToCreate[i:end]
+		if err := s.KeyProvider.AddKeys(groupID, chunk); err != nil {
+			return addedCount, len(keys) - addedCount, err
+		}
+		addedCount += len(chunk)
+
+		if progressCallback != nil {
+			progressCallback(i + len(chunk))
+		}
+	}
+
+	return addedCount, len(keys) - addedCount, nil
+}
+
+// prepareNewKeys deduplicates keys against the group and encrypts the new ones.
+// The returned keys are not persisted yet.
+func (s *KeyService) prepareNewKeys(groupID uint, keys []string) ([]models.APIKey, error) {
 	// 1. Get existing key hashes in the group for deduplication
 	var existingHashes []string
 	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Pluck("key_hash", &existingHashes).Error; err != nil {
-		return 0, 0, err
+		return nil, err
 	}
 	existingHashMap := make(map[string]bool)
 	for _, h := range existingHashes {
@@ -133,28 +165,7 @@ func (s *KeyService) processAndCreateKeys(
 		})
 	}
 
-	if len(newKeysToCreate) == 0 {
-		return 0, len(keys), nil
-	}
-
-	// 3. Use KeyProvider to add keys in chunks
-	for i := 0; i < len(newKeysToCreate); i += chunkSize {
-		end := i + chunkSize
-		if end > len(newKeysToCreate) {
-			end = len(newKeysToCreate)
-		}
-		chunk := newKeysToCreate[i:end]
-		if err := s.KeyProvider.AddKeys(groupID, chunk); err != nil {
-			return addedCount, len(keys) - addedCount, err
-		}
-		addedCount += len(chunk)
-
-		if progressCallback != nil {
-			progressCallback(i + len(chunk))
-		}
-	}
-
-	return addedCount, len(keys) - addedCount, nil
+	return newKeysToCreate, nil
 }
 
 // ParseKeysFromText parses a string of keys from various formats into a string slice.
@@ -306,6 +317,23 @@ func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, sea
 	return query
 }
 
+// FillAvailableAt sets AvailableAt on the active keys that are cooling down after an upstream rate limit.
+func (s *KeyService) FillAvailableAt(keys []models.APIKey) {
+	for i := range keys {
+		if keys[i].Status != models.KeyStatusActive {
+			continue
+		}
+		availableAt, err := s.KeyProvider.KeyAvailableAt(keys[i].ID)
+		if err != nil {
+			logrus.WithError(err).WithField("key_id", keys[i].ID).Debug("Failed to read key cooldown")
+			continue
+		}
+		if !availableAt.IsZero() {
+			keys[i].AvailableAt = &availableAt
+		}
+	}
+}
+
 // TestMultipleKeys handles a one-off validation test for multiple keys.
 func (s *KeyService) TestMultipleKeys(group *models.Group, keysText string) ([]keypool.KeyTestResult, error) {
 	keysToTest := s.ParseKeysFromText(keysText)
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Key provisioning webhook actions
+const (
+	KeyWebhookActionAdd    = "add"
+	KeyWebhookActionRevoke = "revoke"
+)
+
+const (
+	// keyWebhookSignaturePrefix 签名头的格式前缀，值为 sha256=<hex>
+	keyWebhookSignaturePrefix = "sha256="
+	// keyWebhookTolerance 时间戳允许的最大偏差，超出视为重放
+	keyWebhookTolerance = 5 * time.Minute
+	// keyWebhookEventTTL 已处理事件 ID 的保留时间，用于幂等去重
+	keyWebhookEventTTL = 24 * time.Hour
+)
+
+// ErrKeyWebhookDisabled is returned when KEY_WEBHOOK_SECRET is not configured.
+var ErrKeyWebhookDisabled = errors.New("key webhook is disabled")
+
+// KeyProvisioningEvent 外部密钥管理系统推送的密钥变更事件
+type KeyProvisioningEvent struct {
+	ID     string   `json:"id"`
+	Action string   `json:"action"`
+	Group  string   `json:"group"`
+	Keys   []string `json:"keys"`
+}
+
+// KeyProvisioningResult 事件的处理结果
+type KeyProvisioningResult struct {
+	EventID      string `json:"event_id"`
+	Action       string `json:"action"`
+	Group        string `json:"group"`
+	AppliedCount int    `json:"applied_count"`
+	IgnoredCount int    `json:"ignored_count"`
+	Duplicate    bool   `json:"duplicate,omitempty"`
+}
+
+// KeyWebhookService 处理签名的密钥下发事件，用于自动化密钥轮换
+// 每个事件在单个事务中原子生效，并按事件 ID 幂等
+type KeyWebhookService struct {
+	DB            *gorm.DB
+	KeyService    *KeyService
+	GroupManager  *GroupManager
+	store         store.Store
+	configManager types.ConfigManager
+}
+
+// NewKeyWebhookService creates a new KeyWebhookService.
+func NewKeyWebhookService(
+	db *gorm.DB,
+	keyService *KeyService,
+	groupManager *GroupManager,
+	store store.Store,
+	configManager types.ConfigManager,
+) *KeyWebhookService {
+	return &KeyWebhookService{
+		DB:            db,
+		KeyService:    keyService,
+		GroupManager:  groupManager,
+		store:         store,
+		configManager: configManager,
+	}
+}
+
+// Enabled reports whether a webhook secret is configured.
+func (s *KeyWebhookService) Enabled() bool {
+	return s.configManager.GetAuthConfig().WebhookSecret != ""
+}
+
+// VerifySignature 校验请求签名
+// 签名为 HMAC-SHA256(secret, "<timestamp>.<body>") 的十六进制值，timestamp 为 Unix 秒
+func (s *KeyWebhookService) VerifySignature(body []byte, timestamp, signature string) error {
+	secret := s.configManager.GetAuthConfig().WebhookSecret
+	if secret == "" {
+		return ErrKeyWebhookDisabled
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return app_errors.NewAPIError(app_errors.ErrUnauthorized, "invalid or missing webhook timestamp")
+	}
+	if math.Abs(time.Since(time.Unix(ts, 0)).Seconds()) > keyWebhookTolerance.Seconds() {
+		return app_errors.NewAPIError(app_errors.ErrUnauthorized, "webhook timestamp is outside the allowed window")
+	}
+
+	provided, err := hex.DecodeString(strings.TrimPrefix(signature, keyWebhookSignaturePrefix))
+	if err != nil || !strings.HasPrefix(signature, keyWebhookSignaturePrefix) {
+		return app_errors.NewAPIError(app_errors.ErrUnauthorized, "invalid or missing webhook signature")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	if !hmac.Equal(provided, mac.Sum(nil)) {
+		return app_errors.NewAPIError(app_errors.ErrUnauthorized, "webhook signature mismatch")
+	}
+	return nil
+}
+
+// Apply 应用一个密钥下发事件
+// 相同 ID 的事件只生效一次，重复投递直接返回 Duplicate；处理失败时释放 ID 以便重试
+func (s *KeyWebhookService) Apply(event *KeyProvisioningEvent) (*KeyProvisioningResult, error) {
+	if err := s.validateEvent(event); err != nil {
+		return nil, err
+	}
+
+	result := &KeyProvisioningResult{EventID: event.ID, Action: event.Action, Group: event.Group}
+
+	eventKey := "webhook:key_event:" + event.ID
+	ok, err := s.store.SetNX(eventKey, []byte(event.Action), keyWebhookEventTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		result.Duplicate = true
+		return result, nil
+	}
+
+	if err := s.applyEvent(event, result); err != nil {
+		if delErr := s.store.Delete(eventKey); delErr != nil {
+			logrus.WithError(delErr).WithField("event_id", event.ID).Warn("Failed to release webhook event id")
+		}
+		return nil, err
+	}
+
+	// 刷新各实例的分组缓存
+	if err := s.GroupManager.Invalidate(); err != nil {
+		logrus.WithError(err).Error("Failed to invalidate group cache after key webhook")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"event_id": event.ID,
+		"action":   event.Action,
+		"group":    event.Group,
+		"applied":  result.AppliedCount,
+		"ignored":  result.IgnoredCount,
+	}).Info("Applied key provisioning event")
+
+	return result, nil
+}
+
+func (s *KeyWebhookService) validateEvent(event *KeyProvisioningEvent) error {
+	event.ID = strings.TrimSpace(event.ID)
+	event.Group = strings.TrimSpace(event.Group)
+
+	keys := event.Keys[:0]
+	for _, key := range event.Keys {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	event.Keys = keys
+
+	switch {
+	case event.ID == "":
+		return app_errors.NewAPIError(app_errors.ErrValidation, "event id is required")
+	case event.Action != KeyWebhookActionAdd && event.Action != KeyWebhookActionRevoke:
+		return app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("unsupported action %q", event.Action))
+	case event.Group == "":
+		return app_errors.NewAPIError(app_errors.ErrValidation, "group is required")
+	case len(event.Keys) == 0:
+		return app_errors.NewAPIError(app_errors.ErrValidation, "keys must not be empty")
+	case len(event.Keys) > maxRequestKeys:
+		return app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(event.Keys)))
+	}
+	return nil
+}
+
+// applyEvent 在单个事务中完成整个事件，失败时不会留下部分生效的密钥
+func (s *KeyWebhookService) applyEvent(event *KeyProvisioningEvent, result *KeyProvisioningResult) error {
+	var group models.Group
+	if err := s.DB.Where("name = ?", event.Group).First(&group).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	if group.GroupType == "aggregate" {
+		return app_errors.NewAPIError(app_errors.ErrValidation, "keys cannot be provisioned to an aggregate group")
+	}
+
+	switch event.Action {
+	case KeyWebhookActionAdd:
+		newKeys, err := s.KeyService.prepareNewKeys(group.ID, event.Keys)
+		if err != nil {
+			return err
+		}
+		if err := s.KeyService.KeyProvider.AddKeys(group.ID, newKeys); err != nil {
+			return err
+		}
+		result.AppliedCount = len(newKeys)
+
+	case KeyWebhookActionRevoke:
+		removed, err := s.KeyService.KeyProvider.RemoveKeys(group.ID, event.Keys)
+		if err != nil {
+			return err
+		}
+		result.AppliedCount = int(removed)
+	}
+
+	result.IgnoredCount = len(event.Keys) - result.AppliedCount
+	return nil
+}
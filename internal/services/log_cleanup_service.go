@@ -11,7 +11,7 @@ import (
 	"gorm.io/gorm"
 )
 
-// LogCleanupService 负责清理过期的请求日志
+// LogCleanupService 负责清理过期的请求日志和审计日志
 type LogCleanupService struct {
 	db              *gorm.DB
 	settingsManager *config.SystemSettingsManager
@@ -61,11 +61,13 @@ func (s *LogCleanupService) run() {
 
 	// 启动时先执行一次清理
 	s.cleanupExpiredLogs()
+	s.cleanupExpiredAuditLogs()
 
 	for {
 		select {
 		case <-ticker.C:
 			s.cleanupExpiredLogs()
+			s.cleanupExpiredAuditLogs()
 		case <-s.stopCh:
 			return
 		}
@@ -103,3 +105,25 @@ func (s *LogCleanupService) cleanupExpiredLogs() {
 		logrus.Debug("No expired request logs found to cleanup")
 	}
 }
+
+// cleanupExpiredAuditLogs 清理超过 audit_log_retention_days 的审计日志
+func (s *LogCleanupService) cleanupExpiredAuditLogs() {
+	retentionDays := s.settingsManager.GetSettings().AuditLogRetentionDays
+	if retentionDays <= 0 {
+		return
+	}
+
+	cutoffTime := time.Now().AddDate(0, 0, -retentionDays).UTC()
+	result := s.db.Where("timestamp < ?", cutoffTime).Delete(&models.AuditLog{})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to cleanup expired audit logs")
+		return
+	}
+	if result.RowsAffected > 0 {
+		logrus.WithFields(logrus.Fields{
+			"deleted_count":  result.RowsAffected,
+			"cutoff_time":    cutoffTime.Format(time.RFC3339),
+			"retention_days": retentionDays,
+		}).Info("Successfully cleaned up expired audit logs")
+	}
+}
@@ -48,6 +48,9 @@ func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB
 			keyHash := s.EncryptionSvc.Hash(keyValue)
 			db = db.Where("key_hash = ?", keyHash)
 		}
+		if proxyKey := c.Query("proxy_key"); proxyKey != "" {
+			db = db.Where("proxy_key_hash = ?", s.EncryptionSvc.Hash(proxyKey))
+		}
 		if model := c.Query("model"); model != "" {
 			db = db.Where("model LIKE ?", "%"+model+"%")
 		}
@@ -64,6 +67,9 @@ func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB
 				db = db.Where("status_code = ?", statusCode)
 			}
 		}
+		if region := c.Query("region"); region != "" {
+			db = db.Where("region = ?", region)
+		}
 		if sourceIP := c.Query("source_ip"); sourceIP != "" {
 			db = db.Where("source_ip = ?", sourceIP)
 		}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Provider status indicators, following the Statuspage convention
+const (
+	ProviderStatusNone     = "none"
+	ProviderStatusMinor    = "minor"
+	ProviderStatusMajor    = "major"
+	ProviderStatusCritical = "critical"
+)
+
+const (
+	providerStatusRequestTimeout = 10 * time.Second
+	providerStatusIdleInterval   = time.Minute
+)
+
+// ProviderStatus 上游服务商当前的公开状态
+type ProviderStatus struct {
+	Provider    string    `json:"provider"`
+	Indicator   string    `json:"indicator"`
+	Description string    `json:"description"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Outage reports whether the provider has declared a major or critical incident.
+func (s ProviderStatus) Outage() bool {
+	return s.Indicator == ProviderStatusMajor || s.Indicator == ProviderStatusCritical
+}
+
+// providerStatusFeed 服务商状态源，key 为渠道类型
+type providerStatusFeed struct {
+	url   string
+	parse func(body []byte) (indicator, description string, err error)
+}
+
+var providerStatusFeeds = map[string]providerStatusFeed{
+	"openai":    {url: "https://status.openai.com/api/v2/status.json", parse: parseStatuspageStatus},
+	"anthropic": {url: "https://status.anthropic.com/api/v2/status.json", parse: parseStatuspageStatus},
+	"gemini":    {url: "https://status.cloud.google.com/incidents.json", parse: parseGoogleCloudIncidents},
+}
+
+// ProviderStatusService 定期拉取服务商状态页，供分组展示和故障期间调整拉黑阈值
+type ProviderStatusService struct {
+	settingsManager *config.SystemSettingsManager
+	client          *http.Client
+	statuses        map[string]ProviderStatus
+	mu              sync.RWMutex
+	stopCh          chan struct{}
+	wakeCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewProviderStatusService 创建服务商状态服务
+func NewProviderStatusService(settingsManager *config.SystemSettingsManager) *ProviderStatusService {
+	return &ProviderStatusService{
+		settingsManager: settingsManager,
+		client:          &http.Client{Timeout: providerStatusRequestTimeout},
+		statuses:        make(map[string]ProviderStatus),
+		stopCh:          make(chan struct{}),
+		wakeCh:          make(chan struct{}, 1),
+	}
+}
+
+// Start 启动状态轮询
+func (s *ProviderStatusService) Start() {
+	// 轮询间隔修改后立即开始新一轮，开启轮询时无需等待空闲周期结束
+	err := config.OnSettingChange(s.settingsManager, "provider_status_poll_minutes", func(_, _ int) {
+		select {
+		case s.wakeCh <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to register provider status poll hook")
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Provider status service started")
+}
+
+// Stop 停止状态轮询
+func (s *ProviderStatusService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("ProviderStatusService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("ProviderStatusService stop timed out.")
+	}
+}
+
+// run 轮询主循环，间隔每轮重新读取，设置为 0 时清空状态并空闲等待，间隔变更时立即唤醒
+func (s *ProviderStatusService) run() {
+	defer s.wg.Done()
+
+	for {
+		interval := time.Duration(s.settingsManager.GetSettings().ProviderStatusPollMinutes) * time.Minute
+		if interval > 0 {
+			s.pollAll()
+		} else {
+			s.clear()
+			interval = providerStatusIdleInterval
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-s.wakeCh:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// pollAll 拉取所有状态源，失败的源保留上一次的状态
+func (s *ProviderStatusService) pollAll() {
+	for provider, feed := range providerStatusFeeds {
+		indicator, description, err := s.fetch(feed)
+		if err != nil {
+			logrus.WithError(err).WithField("provider", provider).Warn("Failed to poll provider status")
+			continue
+		}
+
+		status := ProviderStatus{
+			Provider:    provider,
+			Indicator:   indicator,
+			Description: description,
+			UpdatedAt:   time.Now(),
+		}
+
+		s.mu.Lock()
+		previous := s.statuses[provider]
+		s.statuses[provider] = status
+		s.mu.Unlock()
+
+		if previous.Outage() != status.Outage() {
+			logrus.WithFields(logrus.Fields{
+				"provider":    provider,
+				"indicator":   indicator,
+				"description": description,
+			}).Warn("Provider outage status changed")
+		}
+	}
+}
+
+func (s *ProviderStatusService) fetch(feed providerStatusFeed) (string, string, error) {
+	resp, err := s.client.Get(feed.url)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", "", fmt.Errorf("failed to decode status feed: %w", err)
+	}
+	return feed.parse(body)
+}
+
+func (s *ProviderStatusService) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.statuses) > 0 {
+		s.statuses = make(map[string]ProviderStatus)
+	}
+}
+
+// GetStatus 返回分组所属服务商的最新状态
+func (s *ProviderStatusService) GetStatus(channelType string) (ProviderStatus, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status, ok := s.statuses[channelType]
+	return status, ok
+}
+
+// FailoverGroup 服务商声明故障且分组配置了故障拉黑阈值时，返回使用该阈值的分组副本，
+// 使失败的密钥更快被拉黑并切换到其他密钥
+func (s *ProviderStatusService) FailoverGroup(group *models.Group) *models.Group {
+	threshold := group.EffectiveConfig.OutageBlacklistThreshold
+	if threshold <= 0 {
+		return group
+	}
+	status, ok := s.GetStatus(group.ChannelType)
+	if !ok || !status.Outage() {
+		return group
+	}
+	if base := group.EffectiveConfig.BlacklistThreshold; base > 0 && base <= threshold {
+		return group
+	}
+
+	g := *group
+	g.EffectiveConfig.BlacklistThreshold = threshold
+	return &g
+}
+
+// parseStatuspageStatus 解析 Statuspage 的 /api/v2/status.json
+func parseStatuspageStatus(body []byte) (string, string, error) {
+	var payload struct {
+		Status struct {
+			Indicator   string `json:"indicator"`
+			Description string `json:"description"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", "", err
+	}
+	if payload.Status.Indicator == "" {
+		return "", "", fmt.Errorf("status indicator is missing")
+	}
+	return payload.Status.Indicator, payload.Status.Description, nil
+}
+
+// parseGoogleCloudIncidents 解析 Google Cloud 的 incidents.json，
+// 取未结束且影响 Gemini 相关产品的事件中最严重的一条
+func parseGoogleCloudIncidents(body []byte) (string, string, error) {
+	var incidents []struct {
+		End              string `json:"end"`
+		Severity         string `json:"severity"`
+		ExternalDesc     string `json:"external_desc"`
+		AffectedProducts []struct {
+			Title string `json:"title"`
+		} `json:"affected_products"`
+	}
+	if err := json.Unmarshal(body, &incidents); err != nil {
+		return "", "", err
+	}
+
+	severityIndicators := map[string]string{
+		"low":    ProviderStatusMinor,
+		"medium": ProviderStatusMajor,
+		"high":   ProviderStatusCritical,
+	}
+	rank := map[string]int{ProviderStatusNone: 0, ProviderStatusMinor: 1, ProviderStatusMajor: 2, ProviderStatusCritical: 3}
+
+	indicator, description := ProviderStatusNone, ""
+	for _, incident := range incidents {
+		if incident.End != "" {
+			continue
+		}
+		affected := false
+		for _, product := range incident.AffectedProducts {
+			if strings.Contains(strings.ToLower(product.Title), "gemini") {
+				affected = true
+				break
+			}
+		}
+		if !affected {
+			continue
+		}
+		current, ok := severityIndicators[incident.Severity]
+		if !ok {
+			current = ProviderStatusMinor
+		}
+		if rank[current] > rank[indicator] {
+			indicator, description = current, incident.ExternalDesc
+		}
+	}
+	return indicator, description, nil
+}
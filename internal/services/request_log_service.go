@@ -29,6 +29,7 @@ type RequestLogService struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	stopChan        chan struct{}
+	intervalChanged chan struct{}
 	wg              sync.WaitGroup
 	ticker          *time.Ticker
 }
@@ -40,11 +41,23 @@ func NewRequestLogService(db *gorm.DB, store store.Store, sm *config.SystemSetti
 		store:           store,
 		settingsManager: sm,
 		stopChan:        make(chan struct{}),
+		intervalChanged: make(chan struct{}, 1),
 	}
 }
 
 // Start initializes the service and starts the periodic flush routine
 func (s *RequestLogService) Start() {
+	// 写入间隔修改后立即重置定时器，不必等待旧间隔结束
+	err := config.OnSettingChange(s.settingsManager, "request_log_write_interval_minutes", func(_, _ int) {
+		select {
+		case s.intervalChanged <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to register request log interval hook")
+	}
+
 	s.wg.Add(1)
 	go s.runLoop()
 }
@@ -55,32 +68,42 @@ func (s *RequestLogService) runLoop() {
 	// Initial flush on start
 	s.flush()
 
-	interval := time.Duration(s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes) * time.Minute
-	if interval <= 0 {
-		interval = time.Minute
-	}
+	interval := s.writeInterval()
 	s.ticker = time.NewTicker(interval)
 	defer s.ticker.Stop()
 
 	for {
 		select {
 		case <-s.ticker.C:
-			newInterval := time.Duration(s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes) * time.Minute
-			if newInterval <= 0 {
-				newInterval = time.Minute
-			}
-			if newInterval != interval {
-				s.ticker.Reset(newInterval)
-				interval = newInterval
-				logrus.Debugf("Request log write interval updated to: %v", interval)
-			}
+			s.updateInterval(&interval)
 			s.flush()
+		case <-s.intervalChanged:
+			s.updateInterval(&interval)
 		case <-s.stopChan:
 			return
 		}
 	}
 }
 
+// writeInterval returns the configured flush interval, at least one minute.
+func (s *RequestLogService) writeInterval() time.Duration {
+	interval := time.Duration(s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return interval
+}
+
+// updateInterval resets the ticker when the configured interval has changed.
+func (s *RequestLogService) updateInterval(interval *time.Duration) {
+	newInterval := s.writeInterval()
+	if newInterval != *interval {
+		s.ticker.Reset(newInterval)
+		*interval = newInterval
+		logrus.Debugf("Request log write interval updated to: %v", newInterval)
+	}
+}
+
 // Stop gracefully stops the RequestLogService
 func (s *RequestLogService) Stop(ctx context.Context) {
 	close(s.stopChan)
@@ -294,6 +317,68 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 			}
 		}
 
-		return nil
+		return upsertUsageHourlyStats(tx, logs)
 	})
 }
+
+// upsertUsageHourlyStats 按小时、分组、密钥、代理密钥和模型累计成功请求的 token 用量和费用
+func upsertUsageHourlyStats(tx *gorm.DB, logs []*models.RequestLog) error {
+	type usageDims struct {
+		Time         time.Time
+		GroupID      uint
+		KeyHash      string
+		Model        string
+		ProxyKeyHash string
+	}
+	usageStats := make(map[usageDims]*models.UsageHourlyStat)
+	for _, log := range logs {
+		if log.RequestType == models.RequestTypeRetry || !log.IsSuccess || (log.TotalTokens == 0 && log.Cost == 0) {
+			continue
+		}
+		key := usageDims{
+			Time:         log.Timestamp.Truncate(time.Hour),
+			GroupID:      log.GroupID,
+			KeyHash:      log.KeyHash,
+			Model:        log.Model,
+			ProxyKeyHash: log.ProxyKeyHash,
+		}
+		stat, ok := usageStats[key]
+		if !ok {
+			stat = &models.UsageHourlyStat{
+				Time:         key.Time,
+				GroupID:      key.GroupID,
+				KeyHash:      key.KeyHash,
+				Model:        key.Model,
+				ProxyKeyHash: key.ProxyKeyHash,
+			}
+			usageStats[key] = stat
+		}
+		stat.Requests++
+		stat.PromptTokens += log.PromptTokens
+		stat.CompletionTokens += log.CompletionTokens
+		stat.TotalTokens += log.TotalTokens
+		stat.Cost += log.Cost
+		if log.UsageEstimated {
+			stat.EstimatedRequests++
+		}
+	}
+
+	for _, stat := range usageStats {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "time"}, {Name: "group_id"}, {Name: "key_hash"}, {Name: "model"}, {Name: "proxy_key_hash"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"requests":           gorm.Expr("usage_hourly_stats.requests + ?", stat.Requests),
+				"prompt_tokens":      gorm.Expr("usage_hourly_stats.prompt_tokens + ?", stat.PromptTokens),
+				"completion_tokens":  gorm.Expr("usage_hourly_stats.completion_tokens + ?", stat.CompletionTokens),
+				"total_tokens":       gorm.Expr("usage_hourly_stats.total_tokens + ?", stat.TotalTokens),
+				"estimated_requests": gorm.Expr("usage_hourly_stats.estimated_requests + ?", stat.EstimatedRequests),
+				"cost":               gorm.Expr("usage_hourly_stats.cost + ?", stat.Cost),
+				"updated_at":         time.Now(),
+			}),
+		}).Create(stat).Error
+		if err != nil {
+			return fmt.Errorf("failed to upsert usage hourly stat: %w", err)
+		}
+	}
+	return nil
+}
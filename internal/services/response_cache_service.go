@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gpt-load/internal/store"
+)
+
+// CachedResponse 缓存的代理响应，Body 为未压缩的响应体
+type CachedResponse struct {
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type"`
+	Stream      bool      `json:"stream"`
+	Body        []byte    `json:"body"`
+	StoredAt    time.Time `json:"stored_at"`
+}
+
+// ResponseCacheService 在共享存储中保存确定性请求的响应
+// 缓存键由代理按规范化后的请求计算，条目依赖存储的 TTL 过期，多实例部署使用 Redis 时共享缓存
+type ResponseCacheService struct {
+	store store.Store
+}
+
+// NewResponseCacheService creates a new ResponseCacheService.
+func NewResponseCacheService(store store.Store) *ResponseCacheService {
+	return &ResponseCacheService{store: store}
+}
+
+func responseCacheStoreKey(groupID uint, key string) string {
+	return fmt.Sprintf("response_cache:%d:%s", groupID, key)
+}
+
+// Get 返回分组中缓存键对应的响应，未命中时返回 nil
+func (s *ResponseCacheService) Get(groupID uint, key string) (*CachedResponse, error) {
+	payload, err := s.store.Get(responseCacheStoreKey(groupID, key))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cached CachedResponse
+	if err := json.Unmarshal(payload, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+// Set 缓存响应 ttl 时长
+func (s *ResponseCacheService) Set(groupID uint, key string, cached *CachedResponse, ttl time.Duration) error {
+	payload, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(responseCacheStoreKey(groupID, key), payload, ttl)
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultRuleLintUnusedDays 默认的未命中统计窗口（天）
+	DefaultRuleLintUnusedDays = 30
+	// MaxRuleLintUnusedDays 未命中统计窗口的上限（天）
+	MaxRuleLintUnusedDays = 365
+)
+
+// 批量体检在静态检查（jsonengine.ValidateRules）之外报告的问题
+const (
+	// SeverityInfo 规则可以生效，但建议清理或迁移
+	SeverityInfo jsonengine.IssueSeverity = "info"
+
+	IssueInvalidJSON      = "invalid_json"      // 规则列表不是合法 JSON，分组无法加载
+	IssueDeprecatedSyntax = "deprecated_syntax" // 使用了旧格式
+	IssueNeverMatched     = "never_matched"     // 统计窗口内从未命中
+)
+
+// RuleMatchTracker 记录每条规则最近一次改变请求或响应体的时间，供批量体检找出长期未命中的规则
+// 只保存在当前进程中：重启后从零开始，多实例部署时只反映本实例的流量
+type RuleMatchTracker struct {
+	since time.Time
+	last  sync.Map // ruleMatchKey -> *atomic.Int64（Unix 纳秒）
+}
+
+type ruleMatchKey struct {
+	group     string
+	direction string
+	path      string
+}
+
+// NewRuleMatchTracker creates a new RuleMatchTracker.
+func NewRuleMatchTracker() *RuleMatchTracker {
+	return &RuleMatchTracker{since: time.Now()}
+}
+
+// Record 记录规则命中，在代理热路径上调用
+func (t *RuleMatchTracker) Record(group, direction, path string) {
+	key := ruleMatchKey{group: group, direction: direction, path: path}
+	now := time.Now().UnixNano()
+	if v, ok := t.last.Load(key); ok {
+		v.(*atomic.Int64).Store(now)
+		return
+	}
+	v := new(atomic.Int64)
+	v.Store(now)
+	if actual, loaded := t.last.LoadOrStore(key, v); loaded {
+		actual.(*atomic.Int64).Store(now)
+	}
+}
+
+// LastMatched 返回规则最近一次命中的时间，本进程内未命中过时返回 false
+func (t *RuleMatchTracker) LastMatched(group, direction, path string) (time.Time, bool) {
+	v, ok := t.last.Load(ruleMatchKey{group: group, direction: direction, path: path})
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, v.(*atomic.Int64).Load()), true
+}
+
+// Since 返回开始记录的时间
+func (t *RuleMatchTracker) Since() time.Time {
+	return t.since
+}
+
+// RuleLintFinding 批量体检发现的单个问题
+type RuleLintFinding struct {
+	GroupID   uint   `json:"group_id"`
+	GroupName string `json:"group_name"`
+	Direction string `json:"direction"` // inbound、outbound 或 error_outbound
+	jsonengine.RuleIssue
+	LastMatchedAt *time.Time `json:"last_matched_at,omitempty"`
+}
+
+// RuleLintReport 所有分组规则的体检报告，Findings 按处理优先级排序：
+// 先 error（分组无法加载或规则无法生效），再 warning，最后 info（可清理的规则）
+type RuleLintReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	UnusedDays  int       `json:"unused_days"`
+	// ObservedSince 命中统计的起点（本进程启动时间）；晚于统计窗口起点时 Partial 为 true，
+	// 此时不报告 never_matched，避免重启后所有规则都被判定为未命中
+	ObservedSince time.Time `json:"observed_since"`
+	Partial       bool      `json:"partial"`
+	// MatchWindowNote 说明命中统计的范围：只覆盖当前进程，多实例部署时只反映本实例的流量
+	MatchWindowNote string                           `json:"match_window_note"`
+	Groups          int                              `json:"groups"`
+	Rules           int                              `json:"rules"`
+	Summary         map[jsonengine.IssueSeverity]int `json:"summary"`
+	Findings        []RuleLintFinding                `json:"findings"`
+}
+
+// RuleLintService 一次性检查所有分组的入站、出站和错误出站规则
+type RuleLintService struct {
+	db      *gorm.DB
+	matches *RuleMatchTracker
+}
+
+// NewRuleLintService creates a new RuleLintService.
+func NewRuleLintService(db *gorm.DB, matches *RuleMatchTracker) *RuleLintService {
+	return &RuleLintService{db: db, matches: matches}
+}
+
+// LintAll 检查数据库中所有分组保存的规则，unusedDays 为未命中统计窗口（天）
+// 检查的是保存的版本而非缓存：加载失败而继续使用旧版本的分组同样会报告其问题
+func (s *RuleLintService) LintAll(unusedDays int) (*RuleLintReport, error) {
+	if unusedDays < 1 || unusedDays > MaxRuleLintUnusedDays {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("unused_days must be between 1 and %d", MaxRuleLintUnusedDays))
+	}
+
+	var groups []*models.Group
+	if err := s.db.Select("id", "name", "inbound_rules", "outbound_rules", "error_outbound_rules").Order("name ASC").Find(&groups).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -unusedDays)
+	report := &RuleLintReport{
+		GeneratedAt:   now,
+		UnusedDays:    unusedDays,
+		ObservedSince: s.matches.Since(),
+		Partial:       s.matches.Since().After(cutoff),
+		Groups:        len(groups),
+		MatchWindowNote: fmt.Sprintf("rule matches are tracked in memory by this instance since %s; never_matched is reported once the instance has run for the whole %d-day window",
+			s.matches.Since().Format(time.RFC3339), unusedDays),
+		Summary:  make(map[jsonengine.IssueSeverity]int),
+		Findings: []RuleLintFinding{},
+	}
+
+	for _, group := range groups {
+		for _, direction := range ruleDirections {
+			raw := rawGroupRules(group, direction)
+			if len(raw) == 0 {
+				continue
+			}
+			finding := func(issue jsonengine.RuleIssue) RuleLintFinding {
+				return RuleLintFinding{GroupID: group.ID, GroupName: group.Name, Direction: direction, RuleIssue: issue}
+			}
+
+			var rules []jsonengine.PathRule
+			if err := json.Unmarshal(raw, &rules); err != nil {
+				report.Findings = append(report.Findings, finding(jsonengine.RuleIssue{
+					Rule: -1, Severity: jsonengine.SeverityError, Code: IssueInvalidJSON,
+					Message: fmt.Sprintf("rules are not a valid rule list, the group cannot load: %v", err),
+				}))
+				continue
+			}
+			report.Rules += len(rules)
+
+			issues := jsonengine.ValidateRules(rules)
+			for _, issue := range issues {
+				report.Findings = append(report.Findings, finding(issue))
+			}
+			for _, issue := range deprecatedRuleSyntax(raw) {
+				report.Findings = append(report.Findings, finding(issue))
+			}
+			if !report.Partial {
+				report.Findings = append(report.Findings, s.unusedRules(group.Name, direction, rules, issues, cutoff, finding)...)
+			}
+		}
+	}
+
+	sortRuleLintFindings(report.Findings)
+	for _, f := range report.Findings {
+		report.Summary[f.Severity]++
+	}
+	return report, nil
+}
+
+// unusedRules 报告统计窗口内没有命中的规则，只在本进程已运行满整个统计窗口时调用
+// 已被静态检查判定为无法生效的规则、keep 和 capture（不产生命中事件）不重复报告
+func (s *RuleLintService) unusedRules(group, direction string, rules []jsonengine.PathRule, issues []jsonengine.RuleIssue, cutoff time.Time, finding func(jsonengine.RuleIssue) RuleLintFinding) []RuleLintFinding {
+	reported := make(map[int]bool, len(issues))
+	for _, issue := range issues {
+		if issue.Severity == jsonengine.SeverityError || issue.Code == jsonengine.IssueUnreachable || issue.Code == jsonengine.IssueInvalidPath {
+			reported[issue.Rule] = true
+		}
+	}
+
+	var findings []RuleLintFinding
+	for i, rule := range rules {
+		if reported[i] || rule.Action == jsonengine.ActionKeep || rule.Action == jsonengine.ActionCapture {
+			continue
+		}
+		path := strings.TrimSpace(rule.Path)
+		last, matched := s.matches.LastMatched(group, direction, path)
+		if matched && last.After(cutoff) {
+			continue
+		}
+		f := finding(jsonengine.RuleIssue{Rule: i, Path: path, Severity: SeverityInfo, Code: IssueNeverMatched})
+		since := cutoff
+		if matched {
+			f.LastMatchedAt = &last
+			since = last
+		}
+		f.Message = fmt.Sprintf("no match on this instance since %s, the rule may be obsolete", since.Format(time.RFC3339))
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// deprecatedRuleSyntax 检查保存的规则中的旧格式写法
+// 旧版顶层规则以 key 指定字段，现在 key 不再读取：只有 key 的规则被当作空路径忽略
+func deprecatedRuleSyntax(raw []byte) []jsonengine.RuleIssue {
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil
+	}
+	var issues []jsonengine.RuleIssue
+	for i, entry := range entries {
+		key, hasKey := entry["key"]
+		if !hasKey {
+			continue
+		}
+		var name, path string
+		_ = json.Unmarshal(key, &name)
+		_ = json.Unmarshal(entry["path"], &path)
+		msg := fmt.Sprintf("uses the legacy key field (%q), which is ignored; set path instead", name)
+		if path != "" {
+			msg = "has both path and the legacy key field, key is ignored and can be removed"
+		}
+		issues = append(issues, jsonengine.RuleIssue{
+			Rule: i, Path: path, Severity: jsonengine.SeverityWarning, Code: IssueDeprecatedSyntax, Message: msg,
+		})
+	}
+	return issues
+}
+
+// rawGroupRules 返回分组某个方向保存的规则 JSON
+func rawGroupRules(g *models.Group, direction string) []byte {
+	switch direction {
+	case models.RuleDirectionInbound:
+		return g.InboundRules
+	case models.RuleDirectionErrorOutbound:
+		return g.ErrorOutboundRules
+	}
+	return g.OutboundRules
+}
+
+// ruleLintSeverityRank 严重程度的处理顺序
+var ruleLintSeverityRank = map[jsonengine.IssueSeverity]int{
+	jsonengine.SeverityError:   0,
+	jsonengine.SeverityWarning: 1,
+	SeverityInfo:               2,
+}
+
+// sortRuleLintFindings 按严重程度排序，同级按分组、方向和规则下标排序
+func sortRuleLintFindings(findings []RuleLintFinding) {
+	directionRank := make(map[string]int, len(ruleDirections))
+	for i, direction := range ruleDirections {
+		directionRank[direction] = i
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if ra, rb := ruleLintSeverityRank[a.Severity], ruleLintSeverityRank[b.Severity]; ra != rb {
+			return ra < rb
+		}
+		if a.GroupName != b.GroupName {
+			return a.GroupName < b.GroupName
+		}
+		if a.Direction != b.Direction {
+			return directionRank[a.Direction] < directionRank[b.Direction]
+		}
+		return a.Rule < b.Rule
+	})
+}
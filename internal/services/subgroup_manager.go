@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
 	"sync"
 
 	"github.com/sirupsen/logrus"
@@ -12,6 +14,8 @@ import (
 // SubGroupManager manages weighted round-robin selection for all aggregate groups
 type SubGroupManager struct {
 	store     store.Store
+	region    string
+	groups    map[string]*models.Group
 	selectors map[uint]*selector
 	mu        sync.RWMutex
 }
@@ -22,12 +26,14 @@ type subGroupItem struct {
 	subGroupID    uint
 	weight        int
 	currentWeight int
+	local         bool // 子分组的优先区域包含当前实例区域
 }
 
 // NewSubGroupManager creates a new sub-group manager service
-func NewSubGroupManager(store store.Store) *SubGroupManager {
+func NewSubGroupManager(store store.Store, configManager types.ConfigManager) *SubGroupManager {
 	return &SubGroupManager{
 		store:     store,
+		region:    configManager.GetEffectiveServerConfig().Region,
 		selectors: make(map[uint]*selector),
 	}
 }
@@ -60,6 +66,10 @@ func (m *SubGroupManager) SelectSubGroup(group *models.Group) (string, error) {
 func (m *SubGroupManager) RebuildSelectors(groups map[string]*models.Group) {
 	newSelectors := make(map[uint]*selector)
 
+	m.mu.Lock()
+	m.groups = groups
+	m.mu.Unlock()
+
 	for _, group := range groups {
 		if group.GroupType == "aggregate" && len(group.SubGroups) > 0 {
 			if sel := m.createSelector(group); sel != nil {
@@ -75,6 +85,25 @@ func (m *SubGroupManager) RebuildSelectors(groups map[string]*models.Group) {
 	logrus.WithField("new_count", len(newSelectors)).Debug("Rebuilt selectors for aggregate groups")
 }
 
+// RegisterSelector creates the selector of an aggregate group loaded outside a cache reload,
+// replacing any selector built from an older version of the group.
+func (m *SubGroupManager) RegisterSelector(group *models.Group) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sel := m.createSelector(group)
+	if sel == nil {
+		delete(m.selectors, group.ID)
+		return
+	}
+	m.selectors[group.ID] = sel
+	logrus.WithFields(logrus.Fields{
+		"group_id":        group.ID,
+		"group_name":      group.Name,
+		"sub_group_count": len(sel.subGroups),
+	}).Debug("Registered sub-group selector")
+}
+
 // getSelector retrieves or creates a selector for the aggregate group
 func (m *SubGroupManager) getSelector(group *models.Group) *selector {
 	m.mu.RLock()
@@ -111,12 +140,16 @@ func (m *SubGroupManager) createSelector(group *models.Group) *selector {
 	}
 
 	var items []subGroupItem
+	hasLocal := false
 	for _, sg := range group.SubGroups {
+		local := m.isLocal(sg.SubGroupName)
+		hasLocal = hasLocal || local
 		items = append(items, subGroupItem{
 			name:          sg.SubGroupName,
 			subGroupID:    sg.SubGroupID,
 			weight:        sg.Weight,
 			currentWeight: 0,
+			local:         local,
 		})
 	}
 
@@ -128,20 +161,37 @@ func (m *SubGroupManager) createSelector(group *models.Group) *selector {
 		groupID:   group.ID,
 		groupName: group.Name,
 		subGroups: items,
+		hasLocal:  hasLocal,
 		store:     m.store,
 	}
 }
 
+// isLocal reports whether the sub-group lists the instance region in its preferred regions.
+// Callers must hold m.mu or be the only writer of m.groups.
+func (m *SubGroupManager) isLocal(subGroupName string) bool {
+	if m.region == "" {
+		return false
+	}
+	subGroup, ok := m.groups[subGroupName]
+	if !ok {
+		return false
+	}
+	_, ok = utils.StringToSet(subGroup.EffectiveConfig.PreferredRegions, ",")[m.region]
+	return ok
+}
+
 // selector encapsulates the weighted round-robin algorithm for a single aggregate group
 type selector struct {
 	groupID   uint
 	groupName string
 	subGroups []subGroupItem
+	hasLocal  bool
 	store     store.Store
 	mu        sync.Mutex
 }
 
-// selectNext uses weighted round-robin algorithm to select a sub-group with active keys
+// selectNext selects a sub-group with active keys, trying sub-groups that prefer the
+// instance region first and falling back to the others.
 func (s *selector) selectNext() string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -161,9 +211,36 @@ func (s *selector) selectNext() string {
 		return ""
 	}
 
+	if s.hasLocal {
+		if name := s.selectAmong(true); name != "" {
+			return name
+		}
+	}
+	if name := s.selectAmong(false); name != "" {
+		return name
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"aggregate_group":  s.groupName,
+		"total_sub_groups": len(s.subGroups),
+	}).Warn("No sub-groups with active keys available")
+
+	return ""
+}
+
+// selectAmong uses weighted round-robin algorithm to select a sub-group with active keys.
+// When there is no local sub-group, local is false for every item and all of them are candidates.
+func (s *selector) selectAmong(local bool) string {
+	candidates := 0
+	for i := range s.subGroups {
+		if s.subGroups[i].local == local {
+			candidates++
+		}
+	}
+
 	attempted := make(map[uint]bool)
-	for len(attempted) < len(s.subGroups) {
-		item := s.selectByWeight()
+	for len(attempted) < candidates {
+		item := s.selectByWeight(local)
 		if item == nil {
 			break
 		}
@@ -178,6 +255,7 @@ func (s *selector) selectNext() string {
 				"aggregate_group": s.groupName,
 				"selected_group":  item.name,
 				"attempts":        len(attempted),
+				"local":           item.local,
 			}).Debug("Selected sub-group with active keys")
 			return item.name
 		}
@@ -189,21 +267,20 @@ func (s *selector) selectNext() string {
 		}).Debug("Sub-group has no active keys, trying next")
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"aggregate_group":  s.groupName,
-		"total_sub_groups": len(s.subGroups),
-	}).Warn("No sub-groups with active keys available")
-
 	return ""
 }
 
-// selectByWeight implements smooth weighted round-robin algorithm
-func (s *selector) selectByWeight() *subGroupItem {
+// selectByWeight implements smooth weighted round-robin algorithm over the sub-groups
+// whose locality matches
+func (s *selector) selectByWeight(local bool) *subGroupItem {
 	totalWeight := 0
 	var best *subGroupItem
 
 	for i := range s.subGroups {
 		item := &s.subGroups[i]
+		if item.local != local {
+			continue
+		}
 		totalWeight += item.weight
 		item.currentWeight += item.weight
 
@@ -213,7 +290,7 @@ func (s *selector) selectByWeight() *subGroupItem {
 	}
 
 	if best == nil {
-		return &s.subGroups[0]
+		return nil
 	}
 
 	best.currentWeight -= totalWeight
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 用量汇总的维度
+const (
+	UsageDimensionHour     = "hour"
+	UsageDimensionGroup    = "group"
+	UsageDimensionKey      = "key"
+	UsageDimensionProxyKey = "proxy_key"
+	UsageDimensionModel    = "model"
+)
+
+// usageDimensionColumns 各维度对应 usage_hourly_stats 表的列
+var usageDimensionColumns = map[string]string{
+	UsageDimensionHour:     "time",
+	UsageDimensionGroup:    "group_id",
+	UsageDimensionKey:      "key_hash",
+	UsageDimensionProxyKey: "proxy_key_hash",
+	UsageDimensionModel:    "model",
+}
+
+// maxUsageQueryRange 单次查询的最长时间范围
+const maxUsageQueryRange = 366 * 24 * time.Hour
+
+// UsageQuery 用量查询条件，时间范围按整点对齐，GroupBy 为空时只返回总计
+type UsageQuery struct {
+	Start    time.Time
+	End      time.Time
+	GroupID  uint
+	KeyID    uint
+	ProxyKey string
+	Model    string
+	GroupBy  []string
+}
+
+// UsageAggregate 一组维度取值下的用量合计，未参与分组的维度为空
+// 未使用代理密钥的请求以及已移除的代理密钥，ProxyKey 为空
+type UsageAggregate struct {
+	Time              *time.Time `json:"time,omitempty"`
+	GroupID           uint       `json:"group_id,omitempty"`
+	GroupName         string     `json:"group_name,omitempty"`
+	KeyID             uint       `json:"key_id,omitempty"`
+	MaskedKey         string     `json:"masked_key,omitempty"`
+	ProxyKey          string     `json:"proxy_key,omitempty"`
+	Model             string     `json:"model,omitempty"`
+	Requests          int64      `json:"requests"`
+	PromptTokens      int64      `json:"prompt_tokens"`
+	CompletionTokens  int64      `json:"completion_tokens"`
+	TotalTokens       int64      `json:"total_tokens"`
+	EstimatedRequests int64      `json:"estimated_requests"`
+	Cost              float64    `json:"cost"`
+}
+
+// UsageReport 用量查询结果
+type UsageReport struct {
+	Start   time.Time        `json:"start"`
+	End     time.Time        `json:"end"`
+	GroupBy []string         `json:"group_by"`
+	Total   UsageAggregate   `json:"total"`
+	Items   []UsageAggregate `json:"items"`
+}
+
+// usageRow 汇总查询的结果行
+type usageRow struct {
+	Time              time.Time
+	GroupID           uint
+	KeyHash           string
+	ProxyKeyHash      string
+	Model             string
+	Requests          int64
+	PromptTokens      int64
+	CompletionTokens  int64
+	TotalTokens       int64
+	EstimatedRequests int64
+	Cost              float64
+}
+
+// UsageService 查询按小时持久化的 token 用量和费用
+// 用量随请求日志写入 usage_hourly_stats 表，见 RequestLogService
+type UsageService struct {
+	db              *gorm.DB
+	encryptionSvc   encryption.Service
+	settingsManager *config.SystemSettingsManager
+}
+
+// NewUsageService creates a new UsageService.
+func NewUsageService(db *gorm.DB, encryptionSvc encryption.Service, settingsManager *config.SystemSettingsManager) *UsageService {
+	return &UsageService{
+		db:              db,
+		encryptionSvc:   encryptionSvc,
+		settingsManager: settingsManager,
+	}
+}
+
+// Query 按条件汇总用量
+func (s *UsageService) Query(query UsageQuery) (*UsageReport, error) {
+	start, end := query.Start.Truncate(time.Hour), query.End
+	if !end.After(start) {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, "end must be after start")
+	}
+	if end.Sub(start) > maxUsageQueryRange {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, "the time range must not exceed 366 days")
+	}
+
+	columns := make([]string, 0, len(query.GroupBy))
+	for _, dimension := range query.GroupBy {
+		column, ok := usageDimensionColumns[dimension]
+		if !ok {
+			return nil, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("unknown group_by dimension %q, expected hour, group, key, proxy_key or model", dimension))
+		}
+		columns = append(columns, column)
+	}
+
+	db := s.db.Model(&models.UsageHourlyStat{}).Where("time >= ? AND time < ?", start, end)
+	if query.GroupID != 0 {
+		db = db.Where("group_id = ?", query.GroupID)
+	}
+	if query.KeyID != 0 {
+		var key models.APIKey
+		if err := s.db.Select("key_hash").First(&key, query.KeyID).Error; err != nil {
+			return nil, app_errors.ParseDBError(err)
+		}
+		db = db.Where("key_hash = ?", key.KeyHash)
+	}
+	if query.ProxyKey != "" {
+		db = db.Where("proxy_key_hash = ?", s.encryptionSvc.Hash(query.ProxyKey))
+	}
+	if query.Model != "" {
+		db = db.Where("model = ?", query.Model)
+	}
+	// 同一组条件分别用于明细和总计查询
+	db = db.Session(&gorm.Session{})
+
+	sums := "SUM(requests) AS requests, SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, " +
+		"SUM(total_tokens) AS total_tokens, SUM(estimated_requests) AS estimated_requests, SUM(cost) AS cost"
+	var rows []usageRow
+	if len(columns) > 0 {
+		groupBy := strings.Join(columns, ", ")
+		order := "total_tokens DESC"
+		if slices.Contains(query.GroupBy, UsageDimensionHour) {
+			order = "time ASC, " + order
+		}
+		err := db.Select(groupBy + ", " + sums).Group(groupBy).Order(order).Scan(&rows).Error
+		if err != nil {
+			return nil, app_errors.ParseDBError(err)
+		}
+	}
+	var total usageRow
+	if err := db.Select(sums).Scan(&total).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	report := &UsageReport{
+		Start:   start,
+		End:     end,
+		GroupBy: query.GroupBy,
+		Total:   aggregateFromRow(total),
+		Items:   make([]UsageAggregate, 0, len(rows)),
+	}
+	groupNames, keys, proxyKeys := s.dimensionLabels(rows, query.GroupBy)
+	for _, row := range rows {
+		item := aggregateFromRow(row)
+		for _, dimension := range query.GroupBy {
+			switch dimension {
+			case UsageDimensionHour:
+				hour := row.Time
+				item.Time = &hour
+			case UsageDimensionGroup:
+				item.GroupID = row.GroupID
+				item.GroupName = groupNames[row.GroupID]
+			case UsageDimensionKey:
+				key := keys[row.KeyHash]
+				item.KeyID, item.MaskedKey = key.ID, key.KeyValue
+			case UsageDimensionProxyKey:
+				item.ProxyKey = proxyKeys[row.ProxyKeyHash]
+			case UsageDimensionModel:
+				item.Model = row.Model
+			}
+		}
+		report.Items = append(report.Items, item)
+	}
+	return report, nil
+}
+
+// WriteCSV 将用量明细以 CSV 格式写出，每个分组维度对应一列或两列，费用单位为美元
+func (s *UsageService) WriteCSV(report *UsageReport, writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+
+	var header []string
+	for _, dimension := range report.GroupBy {
+		switch dimension {
+		case UsageDimensionHour:
+			header = append(header, "hour")
+		case UsageDimensionGroup:
+			header = append(header, "group_id", "group_name")
+		case UsageDimensionKey:
+			header = append(header, "key_id", "masked_key")
+		case UsageDimensionProxyKey:
+			header = append(header, "proxy_key")
+		case UsageDimensionModel:
+			header = append(header, "model")
+		}
+	}
+	header = append(header, "requests", "prompt_tokens", "completion_tokens", "total_tokens", "estimated_requests", "cost")
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	items := report.Items
+	if len(report.GroupBy) == 0 {
+		items = []UsageAggregate{report.Total}
+	}
+	for _, item := range items {
+		record := make([]string, 0, len(header))
+		for _, dimension := range report.GroupBy {
+			switch dimension {
+			case UsageDimensionHour:
+				hour := ""
+				if item.Time != nil {
+					hour = item.Time.Format(time.RFC3339)
+				}
+				record = append(record, hour)
+			case UsageDimensionGroup:
+				record = append(record, strconv.FormatUint(uint64(item.GroupID), 10), item.GroupName)
+			case UsageDimensionKey:
+				record = append(record, strconv.FormatUint(uint64(item.KeyID), 10), item.MaskedKey)
+			case UsageDimensionProxyKey:
+				record = append(record, item.ProxyKey)
+			case UsageDimensionModel:
+				record = append(record, item.Model)
+			}
+		}
+		record = append(record,
+			strconv.FormatInt(item.Requests, 10),
+			strconv.FormatInt(item.PromptTokens, 10),
+			strconv.FormatInt(item.CompletionTokens, 10),
+			strconv.FormatInt(item.TotalTokens, 10),
+			strconv.FormatInt(item.EstimatedRequests, 10),
+			strconv.FormatFloat(item.Cost, 'f', 6, 64),
+		)
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func aggregateFromRow(row usageRow) UsageAggregate {
+	return UsageAggregate{
+		Requests:          row.Requests,
+		PromptTokens:      row.PromptTokens,
+		CompletionTokens:  row.CompletionTokens,
+		TotalTokens:       row.TotalTokens,
+		EstimatedRequests: row.EstimatedRequests,
+		Cost:              row.Cost,
+	}
+}
+
+// dimensionLabels 查询结果中分组的名称、密钥的 ID 和脱敏后的值，以及代理密钥脱敏后的值
+// 已删除的分组和密钥没有对应记录，结果中只保留 ID 或留空
+func (s *UsageService) dimensionLabels(rows []usageRow, groupBy []string) (map[uint]string, map[string]models.APIKey, map[string]string) {
+	groupNames := make(map[uint]string)
+	keys := make(map[string]models.APIKey)
+	var proxyKeys map[string]string
+	var groupIDs []uint
+	var keyHashes []string
+	for _, dimension := range groupBy {
+		for _, row := range rows {
+			switch dimension {
+			case UsageDimensionGroup:
+				groupIDs = append(groupIDs, row.GroupID)
+			case UsageDimensionKey:
+				keyHashes = append(keyHashes, row.KeyHash)
+			}
+		}
+		if dimension == UsageDimensionProxyKey {
+			proxyKeys = s.proxyKeyLabels()
+		}
+	}
+
+	if len(groupIDs) > 0 {
+		var groups []models.Group
+		if err := s.db.Select("id", "name").Where("id IN ?", groupIDs).Find(&groups).Error; err != nil {
+			logrus.WithError(err).Warn("Failed to load group names for the usage report")
+		}
+		for _, group := range groups {
+			groupNames[group.ID] = group.Name
+		}
+	}
+	if len(keyHashes) > 0 {
+		var apiKeys []models.APIKey
+		if err := s.db.Select("id", "key_value", "key_hash").Where("key_hash IN ?", keyHashes).Find(&apiKeys).Error; err != nil {
+			logrus.WithError(err).Warn("Failed to load keys for the usage report")
+		}
+		for _, key := range apiKeys {
+			masked := ""
+			if keyValue, err := s.encryptionSvc.Decrypt(key.KeyValue); err == nil {
+				masked = utils.MaskAPIKey(keyValue)
+			}
+			keys[key.KeyHash] = models.APIKey{ID: key.ID, KeyValue: masked}
+		}
+	}
+	return groupNames, keys, proxyKeys
+}
+
+// proxyKeyLabels 当前配置的全局和分组代理密钥，按哈希索引脱敏后的值
+func (s *UsageService) proxyKeyLabels() map[string]string {
+	labels := make(map[string]string)
+	add := func(proxyKeys string) {
+		for _, proxyKey := range strings.Split(proxyKeys, ",") {
+			if proxyKey = strings.TrimSpace(proxyKey); proxyKey != "" {
+				labels[s.encryptionSvc.Hash(proxyKey)] = utils.MaskAPIKey(proxyKey)
+			}
+		}
+	}
+
+	settings := s.settingsManager.GetSettings()
+	add(settings.ProxyKeys)
+	add(settings.BatchProxyKeys)
+	var groups []models.Group
+	if err := s.db.Select("id", "proxy_keys", "config").Find(&groups).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to load group proxy keys for the usage report")
+	}
+	for _, group := range groups {
+		add(group.ProxyKeys)
+		add(s.settingsManager.GetEffectiveConfig(group.Config).BatchProxyKeys)
+	}
+	return labels
+}
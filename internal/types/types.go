@@ -8,6 +8,8 @@ type ConfigManager interface {
 	GetPerformanceConfig() PerformanceConfig
 	GetLogConfig() LogConfig
 	GetDatabaseConfig() DatabaseConfig
+	GetSMTPConfig() SMTPConfig
+	GetPluginConfig() PluginConfig
 	GetEncryptionKey() string
 	GetEffectiveServerConfig() ServerConfig
 	GetRedisDSN() string
@@ -21,28 +23,109 @@ type SystemSettings struct {
 	// 基础参数
 	AppUrl                         string `json:"app_url" default:"http://localhost:3001" name:"config.app_url" category:"config.category.basic" desc:"config.app_url_desc" validate:"required"`
 	ProxyKeys                      string `json:"proxy_keys" name:"config.proxy_keys" category:"config.category.basic" desc:"config.proxy_keys_desc" validate:"required"`
+	BatchProxyKeys                 string `json:"batch_proxy_keys" name:"config.batch_proxy_keys" category:"config.category.basic" desc:"config.batch_proxy_keys_desc"`
+	ProxyKeyHeaders                string `json:"proxy_key_headers" name:"config.proxy_key_headers" category:"config.category.basic" desc:"config.proxy_key_headers_desc"`
+	ProxyKeyQueryParam             string `json:"proxy_key_query_param" default:"key" name:"config.proxy_key_query_param" category:"config.category.basic" desc:"config.proxy_key_query_param_desc"`
+	ProxyKeyBasicAuth              bool   `json:"proxy_key_basic_auth" default:"false" name:"config.proxy_key_basic_auth" category:"config.category.basic" desc:"config.proxy_key_basic_auth_desc"`
 	RequestLogRetentionDays        int    `json:"request_log_retention_days" default:"7" name:"config.log_retention_days" category:"config.category.basic" desc:"config.log_retention_days_desc" validate:"required,min=0"`
 	RequestLogWriteIntervalMinutes int    `json:"request_log_write_interval_minutes" default:"1" name:"config.log_write_interval" category:"config.category.basic" desc:"config.log_write_interval_desc" validate:"required,min=0"`
 	EnableRequestBodyLogging       bool   `json:"enable_request_body_logging" default:"false" name:"config.enable_request_body_logging" category:"config.category.basic" desc:"config.enable_request_body_logging_desc"`
+	ProviderStatusPollMinutes      int    `json:"provider_status_poll_minutes" default:"0" name:"config.provider_status_poll_minutes" category:"config.category.basic" desc:"config.provider_status_poll_minutes_desc" validate:"required,min=0"`
+	MetricsModelLabels             string `json:"metrics_model_labels" name:"config.metrics_model_labels" category:"config.category.basic" desc:"config.metrics_model_labels_desc"`
+	MetricsPathLabels              string `json:"metrics_path_labels" name:"config.metrics_path_labels" category:"config.category.basic" desc:"config.metrics_path_labels_desc"`
+	DailyReportHour                int    `json:"daily_report_hour" default:"8" name:"config.daily_report_hour" category:"config.category.basic" desc:"config.daily_report_hour_desc" validate:"required,min=0,max=23"`
+	DailyReportWebhookURL          string `json:"daily_report_webhook_url" name:"config.daily_report_webhook_url" category:"config.category.basic" desc:"config.daily_report_webhook_url_desc"`
+	DailyReportEmailTo             string `json:"daily_report_email_to" name:"config.daily_report_email_to" category:"config.category.basic" desc:"config.daily_report_email_to_desc"`
+	AuditLogSink                   string `json:"audit_log_sink" name:"config.audit_log_sink" category:"config.category.basic" desc:"config.audit_log_sink_desc"`
+	AuditLogRetentionDays          int    `json:"audit_log_retention_days" default:"30" name:"config.audit_log_retention_days" category:"config.category.basic" desc:"config.audit_log_retention_days_desc" validate:"required,min=0"`
 
 	// 请求设置
-	RequestTimeout        int    `json:"request_timeout" default:"600" name:"config.request_timeout" category:"config.category.request" desc:"config.request_timeout_desc" validate:"required,min=1"`
-	ConnectTimeout        int    `json:"connect_timeout" default:"15" name:"config.connect_timeout" category:"config.category.request" desc:"config.connect_timeout_desc" validate:"required,min=1"`
-	IdleConnTimeout       int    `json:"idle_conn_timeout" default:"120" name:"config.idle_conn_timeout" category:"config.category.request" desc:"config.idle_conn_timeout_desc" validate:"required,min=1"`
-	ResponseHeaderTimeout int    `json:"response_header_timeout" default:"600" name:"config.response_header_timeout" category:"config.category.request" desc:"config.response_header_timeout_desc" validate:"required,min=1"`
-	MaxIdleConns          int    `json:"max_idle_conns" default:"100" name:"config.max_idle_conns" category:"config.category.request" desc:"config.max_idle_conns_desc" validate:"required,min=1"`
-	MaxIdleConnsPerHost   int    `json:"max_idle_conns_per_host" default:"50" name:"config.max_idle_conns_per_host" category:"config.category.request" desc:"config.max_idle_conns_per_host_desc" validate:"required,min=1"`
-	ProxyURL              string `json:"proxy_url" name:"config.proxy_url" category:"config.category.request" desc:"config.proxy_url_desc"`
+	RequestTimeout              int    `json:"request_timeout" default:"600" name:"config.request_timeout" category:"config.category.request" desc:"config.request_timeout_desc" validate:"required,min=1"`
+	ConnectTimeout              int    `json:"connect_timeout" default:"15" name:"config.connect_timeout" category:"config.category.request" desc:"config.connect_timeout_desc" validate:"required,min=1"`
+	IdleConnTimeout             int    `json:"idle_conn_timeout" default:"120" name:"config.idle_conn_timeout" category:"config.category.request" desc:"config.idle_conn_timeout_desc" validate:"required,min=1"`
+	ResponseHeaderTimeout       int    `json:"response_header_timeout" default:"600" name:"config.response_header_timeout" category:"config.category.request" desc:"config.response_header_timeout_desc" validate:"required,min=1"`
+	MaxIdleConns                int    `json:"max_idle_conns" default:"100" name:"config.max_idle_conns" category:"config.category.request" desc:"config.max_idle_conns_desc" validate:"required,min=1"`
+	MaxIdleConnsPerHost         int    `json:"max_idle_conns_per_host" default:"50" name:"config.max_idle_conns_per_host" category:"config.category.request" desc:"config.max_idle_conns_per_host_desc" validate:"required,min=1"`
+	ProxyURL                    string `json:"proxy_url" name:"config.proxy_url" category:"config.category.request" desc:"config.proxy_url_desc"`
+	UpstreamHostHeader          string `json:"upstream_host_header" name:"config.upstream_host_header" category:"config.category.request" desc:"config.upstream_host_header_desc"`
+	UpstreamTLSServerName       string `json:"upstream_tls_server_name" name:"config.upstream_tls_server_name" category:"config.category.request" desc:"config.upstream_tls_server_name_desc"`
+	AllowedPaths                string `json:"allowed_paths" name:"config.allowed_paths" category:"config.category.request" desc:"config.allowed_paths_desc"`
+	EnableRequestValidation     bool   `json:"enable_request_validation" default:"false" name:"config.enable_request_validation" category:"config.category.request" desc:"config.enable_request_validation_desc"`
+	ResponseWatermarkField      string `json:"response_watermark_field" name:"config.response_watermark_field" category:"config.category.request" desc:"config.response_watermark_field_desc"`
+	ResponsePostProcessors      string `json:"response_post_processors" name:"config.response_post_processors" category:"config.category.request" desc:"config.response_post_processors_desc"`
+	RequestSeed                 string `json:"request_seed" name:"config.request_seed" category:"config.category.request" desc:"config.request_seed_desc"`
+	PreferredRegions            string `json:"preferred_regions" name:"config.preferred_regions" category:"config.category.request" desc:"config.preferred_regions_desc"`
+	StrictOutboundJSON          bool   `json:"strict_outbound_json" default:"false" name:"config.strict_outbound_json" category:"config.category.request" desc:"config.strict_outbound_json_desc"`
+	RemoveEmptyParents          bool   `json:"remove_empty_parents" default:"false" name:"config.remove_empty_parents" category:"config.category.request" desc:"config.remove_empty_parents_desc"`
+	CompressRewrittenResponses  bool   `json:"compress_rewritten_responses" default:"false" name:"config.compress_rewritten_responses" category:"config.category.request" desc:"config.compress_rewritten_responses_desc"`
+	IntegritySamplePercent      int    `json:"integrity_sample_percent" default:"0" name:"config.integrity_sample_percent" category:"config.category.request" desc:"config.integrity_sample_percent_desc" validate:"required,min=0,max=100"`
+	RuleConflictMode            string `json:"rule_conflict_mode" default:"highest_priority" name:"config.rule_conflict_mode" category:"config.category.request" desc:"config.rule_conflict_mode_desc" validate:"required,oneof=highest_priority first_match all_apply"`
+	SignedRequestPassthrough    bool   `json:"signed_request_passthrough" default:"false" name:"config.signed_request_passthrough" category:"config.category.request" desc:"config.signed_request_passthrough_desc"`
+	RateLimitHeaders            string `json:"rate_limit_headers" default:"passthrough" name:"config.rate_limit_headers" category:"config.category.request" desc:"config.rate_limit_headers_desc" validate:"required,oneof=passthrough normalize pool"`
+	ModelDeprecationRemap       bool   `json:"model_deprecation_remap" default:"false" name:"config.model_deprecation_remap" category:"config.category.request" desc:"config.model_deprecation_remap_desc"`
+	ModelDeprecations           string `json:"model_deprecations" name:"config.model_deprecations" category:"config.category.request" desc:"config.model_deprecations_desc"`
+	ModelPrices                 string `json:"model_prices" name:"config.model_prices" category:"config.category.request" desc:"config.model_prices_desc"`
+	AuditLogEnabled             bool   `json:"audit_log_enabled" default:"false" name:"config.audit_log_enabled" category:"config.category.request" desc:"config.audit_log_enabled_desc"`
+	AuditBodySamplePercent      int    `json:"audit_body_sample_percent" default:"0" name:"config.audit_body_sample_percent" category:"config.category.request" desc:"config.audit_body_sample_percent_desc" validate:"required,min=0,max=100"`
+	AuditRedactPaths            string `json:"audit_redact_paths" name:"config.audit_redact_paths" category:"config.category.request" desc:"config.audit_redact_paths_desc"`
+	InteractiveReservePercent   int    `json:"interactive_reserve_percent" default:"0" name:"config.interactive_reserve_percent" category:"config.category.request" desc:"config.interactive_reserve_percent_desc" validate:"required,min=0,max=100"`
+	BatchQueueTimeoutMs         int    `json:"batch_queue_timeout_ms" default:"0" name:"config.batch_queue_timeout_ms" category:"config.category.request" desc:"config.batch_queue_timeout_ms_desc" validate:"required,min=0"`
+	MaxConcurrentStreams        int    `json:"max_concurrent_streams" default:"0" name:"config.max_concurrent_streams" category:"config.category.request" desc:"config.max_concurrent_streams_desc" validate:"required,min=0"`
+	StreamOverflowAction        string `json:"stream_overflow_action" default:"reject" name:"config.stream_overflow_action" category:"config.category.request" desc:"config.stream_overflow_action_desc" validate:"required,oneof=reject downgrade"`
+	GroupMaxConcurrentRequests  int    `json:"group_max_concurrent_requests" default:"0" name:"config.group_max_concurrent_requests" category:"config.category.request" desc:"config.group_max_concurrent_requests_desc" validate:"required,min=0"`
+	KeyMaxConcurrentRequests    int    `json:"key_max_concurrent_requests" default:"0" name:"config.key_max_concurrent_requests" category:"config.category.request" desc:"config.key_max_concurrent_requests_desc" validate:"required,min=0"`
+	ConcurrencyQueueSize        int    `json:"concurrency_queue_size" default:"100" name:"config.concurrency_queue_size" category:"config.category.request" desc:"config.concurrency_queue_size_desc" validate:"required,min=0"`
+	ConcurrencyQueueTimeoutMs   int    `json:"concurrency_queue_timeout_ms" default:"10000" name:"config.concurrency_queue_timeout_ms" category:"config.category.request" desc:"config.concurrency_queue_timeout_ms_desc" validate:"required,min=0"`
+	EmbeddingsBatchWindowMs     int    `json:"embeddings_batch_window_ms" default:"0" name:"config.embeddings_batch_window_ms" category:"config.category.request" desc:"config.embeddings_batch_window_ms_desc" validate:"required,min=0"`
+	EmbeddingsBatchMaxInputs    int    `json:"embeddings_batch_max_inputs" default:"256" name:"config.embeddings_batch_max_inputs" category:"config.category.request" desc:"config.embeddings_batch_max_inputs_desc" validate:"required,min=1"`
+	ResponseCacheTTLSeconds     int    `json:"response_cache_ttl_seconds" default:"0" name:"config.response_cache_ttl_seconds" category:"config.category.request" desc:"config.response_cache_ttl_seconds_desc" validate:"required,min=0"`
+	ResponseCacheMaxEntryKB     int    `json:"response_cache_max_entry_kb" default:"1024" name:"config.response_cache_max_entry_kb" category:"config.category.request" desc:"config.response_cache_max_entry_kb_desc" validate:"required,min=1"`
+	EnableSemanticCache         bool   `json:"enable_semantic_cache" default:"false" name:"config.enable_semantic_cache" category:"config.category.request" desc:"config.enable_semantic_cache_desc"`
+	SemanticCacheGroup          string `json:"semantic_cache_group" name:"config.semantic_cache_group" category:"config.category.request" desc:"config.semantic_cache_group_desc"`
+	SemanticCacheModel          string `json:"semantic_cache_model" default:"text-embedding-3-small" name:"config.semantic_cache_model" category:"config.category.request" desc:"config.semantic_cache_model_desc"`
+	SemanticCacheSimilarity     int    `json:"semantic_cache_similarity" default:"95" name:"config.semantic_cache_similarity" category:"config.category.request" desc:"config.semantic_cache_similarity_desc" validate:"required,min=50,max=100"`
+	SemanticCacheMaxEntries     int    `json:"semantic_cache_max_entries" default:"1000" name:"config.semantic_cache_max_entries" category:"config.category.request" desc:"config.semantic_cache_max_entries_desc" validate:"required,min=1"`
+	InboundJSONMaxDepth         int    `json:"inbound_json_max_depth" default:"256" name:"config.inbound_json_max_depth" category:"config.category.request" desc:"config.inbound_json_max_depth_desc" validate:"required,min=0"`
+	InboundJSONMaxKeyLength     int    `json:"inbound_json_max_key_length" default:"4096" name:"config.inbound_json_max_key_length" category:"config.category.request" desc:"config.inbound_json_max_key_length_desc" validate:"required,min=0"`
+	InboundJSONMaxSizeMB        int    `json:"inbound_json_max_size_mb" default:"0" name:"config.inbound_json_max_size_mb" category:"config.category.request" desc:"config.inbound_json_max_size_mb_desc" validate:"required,min=0"`
+	InboundJSONSchema           string `json:"inbound_json_schema" name:"config.inbound_json_schema" category:"config.category.request" desc:"config.inbound_json_schema_desc"`
+	InboundScript               string `json:"inbound_script" name:"config.inbound_script" category:"config.category.request" desc:"config.inbound_script_desc"`
+	OutboundScript              string `json:"outbound_script" name:"config.outbound_script" category:"config.category.request" desc:"config.outbound_script_desc"`
+	NormalizeFinishReason       bool   `json:"normalize_finish_reason" default:"false" name:"config.normalize_finish_reason" category:"config.category.request" desc:"config.normalize_finish_reason_desc"`
+	FinishReasonMap             string `json:"finish_reason_map" name:"config.finish_reason_map" category:"config.category.request" desc:"config.finish_reason_map_desc"`
+	RuleEnginePositionsCap      int    `json:"rule_engine_positions_cap" default:"131072" name:"config.rule_engine_positions_cap" category:"config.category.request" desc:"config.rule_engine_positions_cap_desc" validate:"required,min=1024"`
+	RuleEnginePoolMaxIdle       int    `json:"rule_engine_pool_max_idle" default:"0" name:"config.rule_engine_pool_max_idle" category:"config.category.request" desc:"config.rule_engine_pool_max_idle_desc" validate:"required,min=0"`
+	RequestBodySpoolThresholdMB int    `json:"request_body_spool_threshold_mb" default:"0" name:"config.request_body_spool_threshold_mb" category:"config.category.request" desc:"config.request_body_spool_threshold_mb_desc" validate:"required,min=0"`
+	RequestBodySpoolDir         string `json:"request_body_spool_dir" name:"config.request_body_spool_dir" category:"config.category.request" desc:"config.request_body_spool_dir_desc"`
 
 	// 密钥配置
-	MaxRetries                   int `json:"max_retries" default:"3" name:"config.max_retries" category:"config.category.key" desc:"config.max_retries_desc" validate:"required,min=0"`
-	BlacklistThreshold           int `json:"blacklist_threshold" default:"3" name:"config.blacklist_threshold" category:"config.category.key" desc:"config.blacklist_threshold_desc" validate:"required,min=0"`
-	KeyValidationIntervalMinutes int `json:"key_validation_interval_minutes" default:"60" name:"config.key_validation_interval" category:"config.category.key" desc:"config.key_validation_interval_desc" validate:"required,min=1"`
-	KeyValidationConcurrency     int `json:"key_validation_concurrency" default:"10" name:"config.key_validation_concurrency" category:"config.category.key" desc:"config.key_validation_concurrency_desc" validate:"required,min=1"`
-	KeyValidationTimeoutSeconds  int `json:"key_validation_timeout_seconds" default:"20" name:"config.key_validation_timeout" category:"config.category.key" desc:"config.key_validation_timeout_desc" validate:"required,min=1"`
+	MaxRetries                   int    `json:"max_retries" default:"3" name:"config.max_retries" category:"config.category.key" desc:"config.max_retries_desc" validate:"required,min=0"`
+	IdempotentMaxRetries         int    `json:"idempotent_max_retries" default:"2" name:"config.idempotent_max_retries" category:"config.category.key" desc:"config.idempotent_max_retries_desc" validate:"required,min=0"`
+	IdempotentRetryBackoffMs     int    `json:"idempotent_retry_backoff_ms" default:"200" name:"config.idempotent_retry_backoff_ms" category:"config.category.key" desc:"config.idempotent_retry_backoff_ms_desc" validate:"required,min=0"`
+	RetryBackoffMs               int    `json:"retry_backoff_ms" default:"0" name:"config.retry_backoff_ms" category:"config.category.key" desc:"config.retry_backoff_ms_desc" validate:"required,min=0"`
+	RetryMaxBackoffMs            int    `json:"retry_max_backoff_ms" default:"5000" name:"config.retry_max_backoff_ms" category:"config.category.key" desc:"config.retry_max_backoff_ms_desc" validate:"required,min=0"`
+	RetryStatusCodes             string `json:"retry_status_codes" name:"config.retry_status_codes" category:"config.category.key" desc:"config.retry_status_codes_desc"`
+	HonorRetryAfter              bool   `json:"honor_retry_after" default:"false" name:"config.honor_retry_after" category:"config.category.key" desc:"config.honor_retry_after_desc"`
+	RetryBudgetPercent           int    `json:"retry_budget_percent" default:"0" name:"config.retry_budget_percent" category:"config.category.key" desc:"config.retry_budget_percent_desc" validate:"required,min=0,max=100"`
+	CircuitBreakerFailurePercent int    `json:"circuit_breaker_failure_percent" default:"0" name:"config.circuit_breaker_failure_percent" category:"config.category.key" desc:"config.circuit_breaker_failure_percent_desc" validate:"required,min=0,max=100"`
+	CircuitBreakerMinRequests    int    `json:"circuit_breaker_min_requests" default:"20" name:"config.circuit_breaker_min_requests" category:"config.category.key" desc:"config.circuit_breaker_min_requests_desc" validate:"required,min=1"`
+	CircuitBreakerWindowSeconds  int    `json:"circuit_breaker_window_seconds" default:"60" name:"config.circuit_breaker_window_seconds" category:"config.category.key" desc:"config.circuit_breaker_window_seconds_desc" validate:"required,min=1"`
+	CircuitBreakerOpenSeconds    int    `json:"circuit_breaker_open_seconds" default:"30" name:"config.circuit_breaker_open_seconds" category:"config.category.key" desc:"config.circuit_breaker_open_seconds_desc" validate:"required,min=1"`
+	EnableAdaptiveCooldown       bool   `json:"enable_adaptive_cooldown" default:"false" name:"config.enable_adaptive_cooldown" category:"config.category.key" desc:"config.enable_adaptive_cooldown_desc"`
+	MaxKeyCooldownSeconds        int    `json:"max_key_cooldown_seconds" default:"3600" name:"config.max_key_cooldown_seconds" category:"config.category.key" desc:"config.max_key_cooldown_seconds_desc" validate:"required,min=1"`
+	BlacklistThreshold           int    `json:"blacklist_threshold" default:"3" name:"config.blacklist_threshold" category:"config.category.key" desc:"config.blacklist_threshold_desc" validate:"required,min=0"`
+	OutageBlacklistThreshold     int    `json:"outage_blacklist_threshold" default:"0" name:"config.outage_blacklist_threshold" category:"config.category.key" desc:"config.outage_blacklist_threshold_desc" validate:"required,min=0"`
+	KeyValidationIntervalMinutes int    `json:"key_validation_interval_minutes" default:"60" name:"config.key_validation_interval" category:"config.category.key" desc:"config.key_validation_interval_desc" validate:"required,min=1"`
+	KeyValidationConcurrency     int    `json:"key_validation_concurrency" default:"10" name:"config.key_validation_concurrency" category:"config.category.key" desc:"config.key_validation_concurrency_desc" validate:"required,min=1"`
+	KeyValidationTimeoutSeconds  int    `json:"key_validation_timeout_seconds" default:"20" name:"config.key_validation_timeout" category:"config.category.key" desc:"config.key_validation_timeout_desc" validate:"required,min=1"`
+	KeySelectionStrategy         string `json:"key_selection_strategy" default:"round_robin" name:"config.key_selection_strategy" category:"config.category.key" desc:"config.key_selection_strategy_desc" validate:"required,oneof=round_robin fair_usage least_loaded latency weighted_random"`
+	FairUsageWindowHours         int    `json:"fair_usage_window_hours" default:"720" name:"config.fair_usage_window_hours" category:"config.category.key" desc:"config.fair_usage_window_hours_desc" validate:"required,min=1"`
+	KeyWarmupMinutes             int    `json:"key_warmup_minutes" default:"0" name:"config.key_warmup_minutes" category:"config.category.key" desc:"config.key_warmup_minutes_desc" validate:"required,min=0"`
 
 	// For cache
-	ProxyKeysMap map[string]struct{} `json:"-"`
+	ProxyKeysMap       map[string]struct{} `json:"-"`
+	BatchProxyKeysMap  map[string]struct{} `json:"-"`
+	ProxyKeyHeaderList []string            `json:"-"`
 }
 
 // ServerConfig represents server configuration
@@ -50,15 +133,18 @@ type ServerConfig struct {
 	Port                    int    `json:"port"`
 	Host                    string `json:"host"`
 	IsMaster                bool   `json:"is_master"`
+	Region                  string `json:"region"`
 	ReadTimeout             int    `json:"read_timeout"`
 	WriteTimeout            int    `json:"write_timeout"`
 	IdleTimeout             int    `json:"idle_timeout"`
 	GracefulShutdownTimeout int    `json:"graceful_shutdown_timeout"`
+	StartupWarmup           bool   `json:"startup_warmup"`
 }
 
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
-	Key string `json:"key"`
+	Key           string `json:"key"`
+	WebhookSecret string `json:"webhook_secret"`
 }
 
 // CORSConfig represents CORS configuration
@@ -88,6 +174,22 @@ type DatabaseConfig struct {
 	DSN string `json:"dsn"`
 }
 
+// SMTPConfig represents the mail server used to send reports
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"-"`
+	From     string `json:"from"`
+}
+
+// PluginConfig represents the WASM value transform plugins
+type PluginConfig struct {
+	Dir           string `json:"dir"`
+	TimeoutMs     int    `json:"timeout_ms"`
+	MemoryLimitMB int    `json:"memory_limit_mb"`
+}
+
 type RetryError struct {
 	StatusCode         int    `json:"status_code"`
 	ErrorMessage       string `json:"error_message"`
@@ -0,0 +1,243 @@
+package usage
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// maxBufferedBody is the largest non-streaming body kept whole to be decoded at the end.
+	// Larger bodies, such as big embeddings responses, keep only their tail.
+	maxBufferedBody = 1 << 20
+	// tailSize is the part of an oversized body searched for a usage block.
+	tailSize = 16 * 1024
+	// maxEventSize is the largest stream event decoded; larger events are skipped.
+	maxEventSize = 1 << 20
+)
+
+var (
+	promptTokensPattern     = regexp.MustCompile(`"(?:prompt_tokens|input_tokens|promptTokenCount)"\s*:\s*(\d+)`)
+	completionTokensPattern = regexp.MustCompile(`"(?:completion_tokens|output_tokens|candidatesTokenCount)"\s*:\s*(\d+)`)
+	totalTokensPattern      = regexp.MustCompile(`"(?:total_tokens|totalTokenCount)"\s*:\s*(\d+)`)
+
+	// imageMarkers appear once per generated image: the base64 field of OpenAI images and
+	// the MIME type of Gemini inline image data.
+	imageMarkers = [][]byte{[]byte(`"b64_json"`), []byte(`"image/`)}
+)
+
+// Accumulator collects the usage of a response body written to it as it is sent to the
+// client. Server-sent event streams are decoded event by event, so usage reported in several
+// chunks is combined; other bodies are decoded once complete. Without reported usage, the
+// tokens of the generated text are estimated along the way.
+type Accumulator struct {
+	started  bool
+	sse      bool
+	line     []byte // incomplete line of an event stream
+	skipLine bool   // the current line is over maxEventSize
+
+	body     []byte // non-streaming body, or its tail once it is over maxBufferedBody
+	overflow bool
+	size     int64
+
+	usage      Usage
+	found      bool
+	completion int64 // estimated tokens of the generated text
+	images     int64 // images in the decoded objects
+	markers    imageCounter
+}
+
+// Write adds the next part of the response body. It never fails.
+func (a *Accumulator) Write(p []byte) (int, error) {
+	a.size += int64(len(p))
+	a.markers.write(p)
+	if !a.started {
+		trimmed := bytes.TrimLeft(p, " \t\r\n")
+		if len(trimmed) == 0 {
+			return len(p), nil
+		}
+		a.started = true
+		a.sse = trimmed[0] != '{' && trimmed[0] != '['
+	}
+	if a.sse {
+		a.writeEvents(p)
+		return len(p), nil
+	}
+
+	a.body = append(a.body, p...)
+	if len(a.body) > maxBufferedBody {
+		a.overflow = true
+		a.body = append(a.body[:0], a.body[len(a.body)-tailSize:]...)
+	}
+	return len(p), nil
+}
+
+// writeEvents decodes every complete data line of an event stream.
+func (a *Accumulator) writeEvents(p []byte) {
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			if !a.skipLine {
+				a.line = append(a.line, p...)
+				if len(a.line) > maxEventSize {
+					a.line, a.skipLine = a.line[:0], true
+				}
+			}
+			return
+		}
+
+		if !a.skipLine {
+			line := p[:i]
+			if len(a.line) > 0 {
+				a.line = append(a.line, line...)
+				line = a.line
+			}
+			a.addEvent(line)
+		}
+		a.line, a.skipLine = a.line[:0], false
+		p = p[i+1:]
+	}
+}
+
+func (a *Accumulator) addEvent(line []byte) {
+	data, ok := bytes.CutPrefix(bytes.TrimRight(line, "\r"), []byte("data:"))
+	if !ok {
+		return
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return // [DONE] and other sentinels
+	}
+	if env, ok := decodeEnvelope(data); ok {
+		a.add(env)
+	}
+}
+
+func (a *Accumulator) add(env *envelope) {
+	if u, ok := env.reported(); ok {
+		a.usage.merge(u)
+		a.found = true
+	}
+	a.completion += env.completionTokens()
+	a.images += env.images()
+}
+
+// addJSON decodes a complete JSON object, or each element of a JSON array.
+func (a *Accumulator) addJSON(body []byte) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var elements []json.RawMessage
+		if json.Unmarshal(body, &elements) != nil {
+			return
+		}
+		for _, element := range elements {
+			if env, ok := decodeEnvelope(element); ok {
+				a.add(env)
+			}
+		}
+		return
+	}
+	if env, ok := decodeEnvelope(body); ok {
+		a.add(env)
+	}
+}
+
+// finish decodes a buffered body once the response is complete.
+func (a *Accumulator) finish() {
+	if a.sse || len(a.body) == 0 {
+		return
+	}
+	body := a.body
+	a.body = nil
+	if !a.overflow {
+		a.addJSON(body)
+		return
+	}
+
+	// Only the tail of an oversized body is left; take the last usage numbers in it
+	u := Usage{
+		PromptTokens:     lastIntMatch(promptTokensPattern, body),
+		CompletionTokens: lastIntMatch(completionTokensPattern, body),
+		TotalTokens:      lastIntMatch(totalTokensPattern, body),
+	}
+	if u.PromptTokens > 0 || u.CompletionTokens > 0 || u.TotalTokens > 0 {
+		a.usage.merge(u)
+		a.found = true
+	}
+}
+
+// Reported returns the usage the provider reported in the response, once it is complete.
+func (a *Accumulator) Reported() (Usage, bool) {
+	a.finish()
+	u := a.usage.finish()
+	u.Images = a.Images()
+	return u, a.found
+}
+
+// Images returns the number of generated images in the response. Images in bodies or events
+// too large to decode are counted by their markers.
+func (a *Accumulator) Images() int64 {
+	return max(a.images, a.markers.count)
+}
+
+// Result returns the reported usage or, when the provider reported none, an estimate: prompt
+// tokens are estimated from the request body, completion tokens from the generated text.
+func (a *Accumulator) Result(requestBody []byte) Usage {
+	if u, ok := a.Reported(); ok {
+		return u
+	}
+	completion := a.completion
+	if a.overflow {
+		completion = a.size / 4
+	}
+	return Usage{
+		PromptTokens:     EstimatePromptTokens(requestBody),
+		CompletionTokens: completion,
+		Images:           a.Images(),
+		Estimated:        true,
+	}.finish()
+}
+
+// Size returns the number of body bytes written.
+func (a *Accumulator) Size() int64 {
+	return a.size
+}
+
+func lastIntMatch(pattern *regexp.Regexp, data []byte) int64 {
+	matches := pattern.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		return 0
+	}
+	value, _ := strconv.ParseInt(string(matches[len(matches)-1][1]), 10, 64)
+	return value
+}
+
+// imageCounter counts the image markers in data written in pieces, including markers split
+// between two writes.
+type imageCounter struct {
+	tail  [16]byte // the last bytes written, longer than any marker
+	n     int
+	count int64
+}
+
+func (ic *imageCounter) write(p []byte) {
+	for _, marker := range imageMarkers {
+		ic.count += int64(bytes.Count(p, marker))
+		// A marker split between the writes starts in the tail and ends in the first
+		// len(marker)-1 bytes of p
+		var window [32]byte
+		k := min(ic.n, len(marker)-1)
+		joined := append(window[:0], ic.tail[ic.n-k:ic.n]...)
+		joined = append(joined, p[:min(len(p), len(marker)-1)]...)
+		ic.count += int64(bytes.Count(joined, marker))
+	}
+
+	if len(p) >= len(ic.tail) {
+		ic.n = copy(ic.tail[:], p[len(p)-len(ic.tail):])
+		return
+	}
+	keep := min(ic.n, len(ic.tail)-len(p))
+	copy(ic.tail[:], ic.tail[ic.n-keep:ic.n])
+	ic.n = keep + copy(ic.tail[keep:], p)
+}
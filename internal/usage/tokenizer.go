@@ -0,0 +1,129 @@
+package usage
+
+import (
+	"encoding/json"
+	"unicode"
+)
+
+const (
+	// tokensPerMessage is the formatting overhead of each chat message.
+	tokensPerMessage = 3
+	// tokensPerReply primes the reply of a chat request.
+	tokensPerReply = 3
+)
+
+// promptTextFields are the request fields whose strings are sent to the model as text.
+var promptTextFields = map[string]bool{
+	"content":      true, // OpenAI and Anthropic messages
+	"text":         true, // content parts, Gemini parts
+	"prompt":       true, // completions
+	"input":        true, // embeddings, Responses API
+	"system":       true, // Anthropic
+	"instructions": true, // Responses API
+}
+
+// promptMessageFields are the request fields that list chat messages.
+var promptMessageFields = map[string]bool{
+	"messages": true, // OpenAI, Anthropic
+	"contents": true, // Gemini
+}
+
+// EstimateTokens approximates the token count of text the way byte-pair encoders such as
+// cl100k split it: a common word of ASCII letters is one token and longer words one per six
+// characters, other letters one per two, digits are grouped in threes, and each CJK character
+// and each punctuation mark is a token. Single spaces join the following word; longer runs
+// of whitespace are a token.
+func EstimateTokens(text string) int64 {
+	var tokens int64
+	var letters, wideLetters, digits, spaces int64
+	flush := func() {
+		tokens += (letters+5)/6 + (wideLetters+1)/2 + (digits+2)/3
+		if spaces > 1 {
+			tokens++
+		}
+		letters, wideLetters, digits, spaces = 0, 0, 0, 0
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens++
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			if digits > 0 || wideLetters > 0 || spaces > 1 {
+				flush()
+			}
+			letters++
+			spaces = 0
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			if digits > 0 || letters > 0 || spaces > 1 {
+				flush()
+			}
+			wideLetters++
+			spaces = 0
+		case unicode.IsDigit(r):
+			if letters > 0 || wideLetters > 0 || spaces > 1 {
+				flush()
+			}
+			digits++
+			spaces = 0
+		case unicode.IsSpace(r):
+			if letters > 0 || wideLetters > 0 || digits > 0 {
+				flush()
+			}
+			spaces++
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character, which tokenizers
+// encode as one or more tokens each.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// EstimatePromptTokens estimates the prompt tokens of an OpenAI, Anthropic or Gemini request
+// body from the text of its messages, prompts and inputs, plus the formatting overhead of
+// chat messages. Images, tools and other structured content are not counted. A body that is
+// not JSON is counted as plain text.
+func EstimatePromptTokens(body []byte) int64 {
+	if len(body) == 0 {
+		return 0
+	}
+	var request any
+	if err := json.Unmarshal(body, &request); err != nil {
+		return EstimateTokens(string(body))
+	}
+	return promptTokens(request, false)
+}
+
+// promptTokens counts the text of a decoded request value; inText is true below a text field.
+func promptTokens(value any, inText bool) int64 {
+	switch v := value.(type) {
+	case string:
+		if inText {
+			return EstimateTokens(v)
+		}
+	case []any:
+		var tokens int64
+		for _, item := range v {
+			tokens += promptTokens(item, inText)
+		}
+		return tokens
+	case map[string]any:
+		var tokens int64
+		for field, item := range v {
+			tokens += promptTokens(item, inText || promptTextFields[field])
+			if messages, ok := item.([]any); ok && promptMessageFields[field] {
+				tokens += int64(len(messages))*tokensPerMessage + tokensPerReply
+			}
+		}
+		return tokens
+	}
+	return 0
+}
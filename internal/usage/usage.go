@@ -0,0 +1,200 @@
+// Package usage reads the token usage reported in upstream responses, including usage
+// spread over streamed chunks, and estimates it when the provider reports none.
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Usage is the token usage of one request.
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	Images           int64 `json:"images,omitempty"`    // generated images, for models priced per image
+	Estimated        bool  `json:"estimated,omitempty"` // counted by the tokenizer estimate, not the provider
+}
+
+// merge combines the usage of two chunks of one response. Providers report running totals
+// when they stream usage (Anthropic message_start then message_delta, Gemini on every
+// chunk), so each count keeps its highest value.
+func (u *Usage) merge(other Usage) {
+	u.PromptTokens = max(u.PromptTokens, other.PromptTokens)
+	u.CompletionTokens = max(u.CompletionTokens, other.CompletionTokens)
+	u.TotalTokens = max(u.TotalTokens, other.TotalTokens)
+}
+
+// finish makes the total at least the sum of its parts, for providers that report no total.
+func (u Usage) finish() Usage {
+	u.TotalTokens = max(u.TotalTokens, u.PromptTokens+u.CompletionTokens)
+	return u
+}
+
+// usageBlock is the usage object of OpenAI Chat Completions and Embeddings (prompt and
+// completion tokens) and of Anthropic and the OpenAI Responses API (input and output tokens).
+type usageBlock struct {
+	PromptTokens             int64 `json:"prompt_tokens"`
+	CompletionTokens         int64 `json:"completion_tokens"`
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	TotalTokens              int64 `json:"total_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"` // Anthropic, not part of input_tokens
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`     // Anthropic, not part of input_tokens
+}
+
+func (b *usageBlock) usage() Usage {
+	return Usage{
+		PromptTokens:     b.PromptTokens + b.InputTokens + b.CacheCreationInputTokens + b.CacheReadInputTokens,
+		CompletionTokens: b.CompletionTokens + b.OutputTokens,
+		TotalTokens:      b.TotalTokens,
+	}
+}
+
+// geminiUsage is the usageMetadata object of Gemini.
+type geminiUsage struct {
+	PromptTokenCount     int64 `json:"promptTokenCount"`
+	CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int64 `json:"thoughtsTokenCount"`
+	TotalTokenCount      int64 `json:"totalTokenCount"`
+}
+
+// textPart is a piece of generated text in any of the supported formats.
+type textPart struct {
+	Text string `json:"text"`
+}
+
+// envelope holds the fields of a response body or stream event that carry usage or generated text.
+type envelope struct {
+	Usage         *usageBlock  `json:"usage"`
+	UsageMetadata *geminiUsage `json:"usageMetadata"`
+	Message       *struct {
+		Usage *usageBlock `json:"usage"`
+	} `json:"message"` // Anthropic message_start
+	Response *struct {
+		Usage *usageBlock `json:"usage"`
+	} `json:"response"` // OpenAI Responses API response.completed
+
+	Choices []struct {
+		Message textContent `json:"message"`
+		Delta   textContent `json:"delta"`
+		Text    string      `json:"text"`
+	} `json:"choices"`
+	Content    []textPart `json:"content"` // Anthropic message
+	Delta      textPart   `json:"delta"`   // Anthropic content_block_delta
+	Candidates []struct {
+		Content struct {
+			Parts []geminiPart `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+	Data []struct {
+		URL     string `json:"url"`
+		B64JSON string `json:"b64_json"`
+	} `json:"data"` // OpenAI images
+}
+
+// geminiPart is a part of a Gemini candidate: text, or inline data such as a generated image.
+type geminiPart struct {
+	Text       string `json:"text"`
+	InlineData *struct {
+		MimeType string `json:"mimeType"`
+	} `json:"inlineData"`
+}
+
+// textContent is an OpenAI message or delta; content holds text unless it is a list of parts.
+type textContent struct {
+	Content string `json:"content"`
+}
+
+// decodeEnvelope decodes a JSON object. Fields of unexpected types are left empty instead of
+// failing the whole object.
+func decodeEnvelope(data []byte) (*envelope, bool) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return nil, false
+		}
+	}
+	return &env, true
+}
+
+// reported returns the usage the object reports, if any.
+func (e *envelope) reported() (Usage, bool) {
+	var u Usage
+	found := false
+	for _, block := range []*usageBlock{e.Usage, e.messageUsage(), e.responseUsage()} {
+		if block != nil {
+			u.merge(block.usage())
+			found = true
+		}
+	}
+	if m := e.UsageMetadata; m != nil {
+		u.merge(Usage{
+			PromptTokens:     m.PromptTokenCount,
+			CompletionTokens: m.CandidatesTokenCount + m.ThoughtsTokenCount,
+			TotalTokens:      m.TotalTokenCount,
+		})
+		found = true
+	}
+	return u, found
+}
+
+func (e *envelope) messageUsage() *usageBlock {
+	if e.Message == nil {
+		return nil
+	}
+	return e.Message.Usage
+}
+
+func (e *envelope) responseUsage() *usageBlock {
+	if e.Response == nil {
+		return nil
+	}
+	return e.Response.Usage
+}
+
+// completionTokens estimates the tokens of the text generated in the object.
+func (e *envelope) completionTokens() int64 {
+	var tokens int64
+	for _, choice := range e.Choices {
+		tokens += EstimateTokens(choice.Message.Content) + EstimateTokens(choice.Delta.Content) + EstimateTokens(choice.Text)
+	}
+	for _, part := range e.Content {
+		tokens += EstimateTokens(part.Text)
+	}
+	tokens += EstimateTokens(e.Delta.Text)
+	for _, candidate := range e.Candidates {
+		for _, part := range candidate.Content.Parts {
+			tokens += EstimateTokens(part.Text)
+		}
+	}
+	return tokens
+}
+
+// images counts the generated images in the object.
+func (e *envelope) images() int64 {
+	var images int64
+	for _, item := range e.Data {
+		if item.URL != "" || item.B64JSON != "" {
+			images++
+		}
+	}
+	for _, candidate := range e.Candidates {
+		for _, part := range candidate.Content.Parts {
+			if part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "image/") {
+				images++
+			}
+		}
+	}
+	return images
+}
+
+// FromBody returns the usage reported in a complete JSON response body, or in every element
+// of a streamed JSON array (Gemini streamGenerateContent without alt=sse).
+func FromBody(body []byte) (Usage, bool) {
+	var a Accumulator
+	a.addJSON(body)
+	return a.Reported()
+}
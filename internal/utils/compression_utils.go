@@ -2,9 +2,12 @@ package utils
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/andybalholm/brotli"
 	"github.com/klauspost/compress/zstd"
@@ -22,6 +25,7 @@ var decompressorRegistry = make(map[string]Decompressor)
 // init registers default decompressors
 func init() {
 	RegisterDecompressor("gzip", &GzipDecompressor{})
+	RegisterDecompressor("x-gzip", &GzipDecompressor{})
 	RegisterDecompressor("br", &BrotliDecompressor{})
 	RegisterDecompressor("deflate", &DeflateDecompressor{})
 	RegisterDecompressor("zstd", &ZstdDecompressor{})
@@ -35,8 +39,11 @@ func RegisterDecompressor(encoding string, decompressor Decompressor) {
 
 // DecompressResponse automatically decompresses response data based on Content-Encoding header
 func DecompressResponse(contentEncoding string, data []byte) ([]byte, error) {
+	// Encoding names are case-insensitive; identity means no encoding
+	contentEncoding = strings.ToLower(strings.TrimSpace(contentEncoding))
+
 	// If no encoding specified or empty data, return as-is
-	if contentEncoding == "" || len(data) == 0 {
+	if contentEncoding == "" || contentEncoding == "identity" || len(data) == 0 {
 		return data, nil
 	}
 
@@ -93,18 +100,20 @@ func (b *BrotliDecompressor) Decompress(data []byte) ([]byte, error) {
 	return decompressed, nil
 }
 
-// DeflateDecompressor handles deflate compression (same as gzip without header)
+// DeflateDecompressor handles deflate compression
 type DeflateDecompressor struct{}
 
-// Decompress implements Decompressor interface for deflate
+// Decompress implements Decompressor interface for deflate.
+// HTTP deflate is zlib-wrapped DEFLATE, but some servers send raw DEFLATE, so both are accepted.
 func (d *DeflateDecompressor) Decompress(data []byte) ([]byte, error) {
-	// For deflate, we can use the same logic as gzip
-	// In practice, deflate is raw DEFLATE format without gzip header
-	// For now, use gzip reader which handles both
-	reader, err := gzip.NewReader(bytes.NewReader(data))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create deflate reader: %w", err)
+	if reader, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		defer reader.Close()
+		if decompressed, err := io.ReadAll(reader); err == nil {
+			return decompressed, nil
+		}
 	}
+
+	reader := flate.NewReader(bytes.NewReader(data))
 	defer reader.Close()
 
 	decompressed, err := io.ReadAll(reader)
@@ -115,18 +124,16 @@ func (d *DeflateDecompressor) Decompress(data []byte) ([]byte, error) {
 	return decompressed, nil
 }
 
+// zstdDecoder is shared by all zstd decompressions; DecodeAll is safe for concurrent use
+// and reuses the decoder's buffers instead of starting new goroutines per body.
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+
 // ZstdDecompressor handles Zstandard compression
 type ZstdDecompressor struct{}
 
 // Decompress implements Decompressor interface for zstd
 func (z *ZstdDecompressor) Decompress(data []byte) ([]byte, error) {
-	reader, err := zstd.NewReader(bytes.NewReader(data))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create zstd reader: %w", err)
-	}
-	defer reader.Close()
-
-	decompressed, err := io.ReadAll(reader)
+	decompressed, err := zstdDecoder.DecodeAll(data, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read zstd data: %w", err)
 	}
@@ -12,59 +12,6 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// GenerateSettingsMetadata 使用反射从 SystemSettings 结构体动态生成元数据
-func GenerateSettingsMetadata(s *types.SystemSettings) []models.SystemSettingInfo {
-	var settingsInfo []models.SystemSettingInfo
-	v := reflect.ValueOf(s).Elem()
-	t := v.Type()
-
-	for i := range t.NumField() {
-		field := t.Field(i)
-		fieldValue := v.Field(i)
-
-		jsonTag := field.Tag.Get("json")
-		if jsonTag == "" || jsonTag == "-" {
-			continue
-		}
-
-		nameTag := field.Tag.Get("name")
-		descTag := field.Tag.Get("desc")
-		defaultTag := field.Tag.Get("default")
-		validateTag := field.Tag.Get("validate")
-		categoryTag := field.Tag.Get("category")
-
-		var minValue *int
-		var required bool
-
-		rules := strings.Split(validateTag, ",")
-		for _, rule := range rules {
-			rule = strings.TrimSpace(rule)
-			if rule == "required" {
-				required = true
-			} else if strings.HasPrefix(rule, "min=") {
-				valStr := strings.TrimPrefix(rule, "min=")
-				if val, err := strconv.Atoi(valStr); err == nil {
-					minValue = &val
-				}
-			}
-		}
-
-		info := models.SystemSettingInfo{
-			Key:          jsonTag,
-			Name:         nameTag,
-			Value:        fieldValue.Interface(),
-			Type:         field.Type.String(),
-			DefaultValue: defaultTag,
-			Description:  descTag,
-			Category:     categoryTag,
-			MinValue:     minValue,
-			Required:     required,
-		}
-		settingsInfo = append(settingsInfo, info)
-	}
-	return settingsInfo
-}
-
 // DefaultSystemSettings 返回默认的系统配置
 func DefaultSystemSettings() types.SystemSettings {
 	s := types.SystemSettings{}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RequestSeedHash is the request_seed value that derives the seed from the request body.
+const RequestSeedHash = "hash"
+
+// ParseRequestSeed parses a request_seed setting: "hash", or a fixed seed that fits in 32 bits.
+// An empty value disables seed injection and is accepted.
+func ParseRequestSeed(spec string) (seed int64, hash bool, err error) {
+	switch spec = strings.TrimSpace(spec); spec {
+	case "":
+		return 0, false, nil
+	case RequestSeedHash:
+		return 0, true, nil
+	}
+	seed, err = strconv.ParseInt(spec, 10, 32)
+	if err != nil {
+		return 0, false, fmt.Errorf("request_seed must be %q or a 32-bit integer, got %q", RequestSeedHash, spec)
+	}
+	return seed, false, nil
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseStatusPatterns parses a comma-separated list of HTTP status codes and classes
+// such as "429,500,5xx".
+func ParseStatusPatterns(spec string) ([]string, error) {
+	patterns := SplitAndTrim(strings.ToLower(spec), ",")
+	for _, p := range patterns {
+		if len(p) == 3 && p[0] >= '1' && p[0] <= '5' && p[1:] == "xx" {
+			continue
+		}
+		if code, err := strconv.Atoi(p); err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status code %q", p)
+		}
+	}
+	return patterns, nil
+}
+
+// StatusMatches reports whether code matches one of the patterns returned by ParseStatusPatterns.
+func StatusMatches(patterns []string, code int) bool {
+	s := strconv.Itoa(code)
+	for _, p := range patterns {
+		if p == s || (strings.HasSuffix(p, "xx") && p[0] == s[0]) {
+			return true
+		}
+	}
+	return false
+}
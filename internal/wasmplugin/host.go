@@ -0,0 +1,239 @@
+// Package wasmplugin loads WASM modules as value transform plugins for "plugin" rules.
+//
+// A plugin module exports its linear memory as "memory" and two functions:
+//
+//	alloc(size i32) i32                   // returns a buffer of size bytes for the input
+//	transform(ptr i32, len i32) i64       // returns the output as ptr<<32 | len
+//
+// The input is the matched JSON value and the output must be a JSON value. A zero result
+// keeps the original value, as does output that is not valid JSON. An optional
+// dealloc(ptr i32, len i32) export is called for the input and output buffers after each call.
+// WASI is available so modules built for wasip1 run, but they get no filesystem,
+// environment, network or clock beyond what wazero provides by default.
+package wasmplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// wasmPageSize is the size of a WASM linear memory page.
+const wasmPageSize = 64 << 10
+
+// Host owns the WASM runtime and the plugins loaded from the plugin directory.
+type Host struct {
+	runtime wazero.Runtime
+	names   []string
+}
+
+// NewHost compiles every .wasm module in the configured plugin directory and registers it
+// with the rule engine under its file name without the extension. Plugins are disabled when
+// no directory is configured. A module that fails to compile or lacks the required exports
+// fails startup, so rules referencing it are never silently left without effect.
+func NewHost(configManager types.ConfigManager) (*Host, error) {
+	cfg := configManager.GetPluginConfig()
+	if cfg.Dir == "" {
+		return &Host{}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.Dir, "*.wasm"))
+	if err != nil {
+		return nil, fmt.Errorf("listing WASM plugins: %w", err)
+	}
+	sort.Strings(files)
+
+	ctx := context.Background()
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(int64(cfg.MemoryLimitMB)<<20/wasmPageSize)).
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		rt.Close(ctx)
+		return nil, fmt.Errorf("instantiating WASI: %w", err)
+	}
+
+	h := &Host{runtime: rt}
+	timeout := time.Duration(cfg.TimeoutMs) * time.Millisecond
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		p, err := loadPlugin(ctx, rt, name, file, timeout)
+		if err != nil {
+			rt.Close(ctx)
+			return nil, err
+		}
+		jsonengine.RegisterValuePlugin(name, p)
+		h.names = append(h.names, name)
+	}
+	logrus.Infof("Loaded %d WASM plugin(s) from %s", len(h.names), cfg.Dir)
+	return h, nil
+}
+
+// Names returns the names of the loaded plugins.
+func (h *Host) Names() []string {
+	return h.names
+}
+
+// Close unregisters the plugins and releases the runtime, closing all plugin instances.
+func (h *Host) Close(ctx context.Context) {
+	if h.runtime == nil {
+		return
+	}
+	for _, name := range h.names {
+		jsonengine.RegisterValuePlugin(name, nil)
+	}
+	if err := h.runtime.Close(ctx); err != nil {
+		logrus.Warnf("Closing WASM plugin runtime: %v", err)
+	}
+}
+
+// plugin is one compiled module. Instances keep the guest's memory between calls,
+// so each one serves a single call at a time and idle instances are pooled.
+type plugin struct {
+	name     string
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	timeout  time.Duration
+	idle     chan api.Module
+}
+
+func loadPlugin(ctx context.Context, rt wazero.Runtime, name, file string, timeout time.Duration) (*plugin, error) {
+	code, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading WASM plugin %s: %w", name, err)
+	}
+	compiled, err := rt.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("compiling WASM plugin %s: %w", name, err)
+	}
+	exports := compiled.ExportedFunctions()
+	for _, fn := range []string{"alloc", "transform"} {
+		if _, ok := exports[fn]; !ok {
+			return nil, fmt.Errorf("WASM plugin %s does not export %q", name, fn)
+		}
+	}
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		return nil, fmt.Errorf("WASM plugin %s does not export its memory", name)
+	}
+
+	p := &plugin{
+		name:     name,
+		runtime:  rt,
+		compiled: compiled,
+		timeout:  timeout,
+		idle:     make(chan api.Module, runtime.GOMAXPROCS(0)),
+	}
+	// Instantiate once up front so initialization failures surface at startup
+	mod, err := p.instantiate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.release(mod)
+	return p, nil
+}
+
+// instantiate creates an anonymous instance, running a reactor module's _initialize.
+func (p *plugin) instantiate(ctx context.Context) (api.Module, error) {
+	cfg := wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize")
+	mod, err := p.runtime.InstantiateModule(ctx, p.compiled, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating WASM plugin %s: %w", p.name, err)
+	}
+	return mod, nil
+}
+
+// acquire returns an idle instance or creates a new one.
+func (p *plugin) acquire(ctx context.Context) (api.Module, error) {
+	select {
+	case mod := <-p.idle:
+		return mod, nil
+	default:
+		return p.instantiate(ctx)
+	}
+}
+
+// release returns an instance to the pool, closing it when the pool is full.
+func (p *plugin) release(mod api.Module) {
+	select {
+	case p.idle <- mod:
+	default:
+		mod.Close(context.Background())
+	}
+}
+
+// TransformValue runs the module on one value within the configured time limit.
+// An instance whose call failed is closed instead of reused, since a trap or timeout
+// can leave the guest's memory in an inconsistent state.
+func (p *plugin) TransformValue(value []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	mod, err := p.acquire(ctx)
+	if err != nil {
+		logrus.Warnf("WASM plugin %s: %v", p.name, err)
+		return nil, err
+	}
+	out, err := p.call(ctx, mod, value)
+	if err != nil {
+		mod.Close(context.Background())
+		logrus.Warnf("WASM plugin %s: %v", p.name, err)
+		return nil, err
+	}
+	p.release(mod)
+	return out, nil
+}
+
+func (p *plugin) call(ctx context.Context, mod api.Module, value []byte) ([]byte, error) {
+	mem := mod.Memory()
+	res, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(value)))
+	if err != nil {
+		return nil, fmt.Errorf("alloc: %w", err)
+	}
+	in := uint32(res[0])
+	if !mem.Write(in, value) {
+		return nil, errors.New("alloc returned a buffer outside the module memory")
+	}
+
+	res, err = mod.ExportedFunction("transform").Call(ctx, uint64(in), uint64(len(value)))
+	if err != nil {
+		return nil, fmt.Errorf("transform: %w", err)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	var out []byte
+	if outLen > 0 {
+		view, ok := mem.Read(outPtr, outLen)
+		if !ok {
+			return nil, errors.New("transform returned a buffer outside the module memory")
+		}
+		// The view aliases guest memory, which the next call may overwrite
+		out = append([]byte(nil), view...)
+	}
+
+	if dealloc := mod.ExportedFunction("dealloc"); dealloc != nil {
+		if _, err := dealloc.Call(ctx, uint64(in), uint64(len(value))); err != nil {
+			return nil, fmt.Errorf("dealloc: %w", err)
+		}
+		if outLen > 0 && outPtr != in {
+			if _, err := dealloc.Call(ctx, uint64(outPtr), uint64(outLen)); err != nil {
+				return nil, fmt.Errorf("dealloc: %w", err)
+			}
+		}
+	}
+	if outLen == 0 {
+		return value, nil
+	}
+	return out, nil
+}
@@ -0,0 +1,153 @@
+// Package client provides a typed Go client for the gpt-load admin API.
+//
+// The client wraps the /api endpoints used by the web console (groups, keys,
+// rules, stats and settings) so that operators can automate gpt-load from Go
+// tools. Models are shared with the server through type aliases, so values
+// returned here are the same types the server serializes.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Client talks to a gpt-load instance with the admin auth key.
+type Client struct {
+	baseURL    string
+	authKey    string
+	httpClient *http.Client
+	userAgent  string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client, e.g. to customize TLS or proxies.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New creates a client for the instance at baseURL (e.g. "http://localhost:3001")
+// authenticated with the AUTH_KEY of that instance.
+func New(baseURL, authKey string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		authKey:    authKey,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		userAgent:  "gpt-load-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned when the server answers with a non-2xx status.
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("gpt-load: HTTP %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("gpt-load: HTTP %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// envelope is the standard success response wrapper.
+type envelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// do sends a request to path (relative to /api) and decodes the data field of
+// the response envelope into out when out is non-nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	respBody, err := c.send(ctx, method, path, query, body)
+	if err != nil || out == nil {
+		return err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(respBody, &env); err != nil {
+		return fmt.Errorf("gpt-load: failed to decode response: %w", err)
+	}
+	if len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("gpt-load: failed to decode response data: %w", err)
+	}
+	return nil
+}
+
+// send performs the HTTP round trip and returns the raw response body.
+// Non-2xx responses are converted to *APIError.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body any) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("gpt-load: failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	endpoint := c.baseURL + "/api" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.authKey)
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("gpt-load: failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if json.Unmarshal(respBody, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(respBody))
+		}
+		return nil, apiErr
+	}
+	return respBody, nil
+}
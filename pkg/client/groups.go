@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Group copy modes accepted by CopyGroup.
+const (
+	CopyKeysNone      = "none"
+	CopyKeysValidOnly = "valid_only"
+	CopyKeysAll       = "all"
+)
+
+// ListGroups returns all groups with their full configuration.
+func (c *Client) ListGroups(ctx context.Context) ([]Group, error) {
+	var groups []Group
+	if err := c.do(ctx, http.MethodGet, "/groups", nil, nil, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetGroupByName returns the group with the given name.
+// The admin API has no single-group endpoint, so this filters ListGroups.
+func (c *Client) GetGroupByName(ctx context.Context, name string) (*Group, error) {
+	groups, err := c.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].Name == name {
+			return &groups[i], nil
+		}
+	}
+	return nil, &APIError{StatusCode: http.StatusNotFound, Code: "NOT_FOUND", Message: fmt.Sprintf("group %q not found", name)}
+}
+
+// CreateGroup creates a group and returns it as stored by the server.
+func (c *Client) CreateGroup(ctx context.Context, req *CreateGroupRequest) (*Group, error) {
+	var group Group
+	if err := c.do(ctx, http.MethodPost, "/groups", nil, req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// UpdateGroup updates the group with the given ID.
+func (c *Client) UpdateGroup(ctx context.Context, id uint, req *UpdateGroupRequest) (*Group, error) {
+	var group Group
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/groups/%d", id), nil, req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// DeleteGroup deletes the group with the given ID together with its keys.
+func (c *Client) DeleteGroup(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/groups/%d", id), nil, nil, nil)
+}
+
+// CopyGroup duplicates a group. copyKeys is one of the CopyKeys* constants.
+func (c *Client) CopyGroup(ctx context.Context, id uint, copyKeys string) (*Group, error) {
+	var resp struct {
+		Group *Group `json:"group"`
+	}
+	body := map[string]string{"copy_keys": copyKeys}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/groups/%d/copy", id), nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Group, nil
+}
+
+// GetGroupStats returns key counts and request statistics of a group.
+func (c *Client) GetGroupStats(ctx context.Context, id uint) (*GroupStats, error) {
+	var stats GroupStats
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/groups/%d/stats", id), nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// SetGroupRules replaces the inbound and outbound JSON path rules of a group.
+// A nil slice leaves the corresponding rule set unchanged; pass an empty
+// slice to clear it.
+func (c *Client) SetGroupRules(ctx context.Context, id uint, inbound, outbound []PathRule) (*Group, error) {
+	return c.UpdateGroup(ctx, id, &UpdateGroupRequest{InboundRules: inbound, OutboundRules: outbound})
+}
+
+// ExplainRules dry-runs JSON path rules against a sample body and reports what
+// each rule would change. conflictMode may be empty for the default mode.
+func (c *Client) ExplainRules(ctx context.Context, rules []PathRule, conflictMode string, input json.RawMessage) (*ExplainResult, error) {
+	body := map[string]any{"rules": rules, "conflict_mode": conflictMode, "input": input}
+	var result ExplainResult
+	if err := c.do(ctx, http.MethodPost, "/groups/rules/explain", nil, body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StartGroupDebug raises the log verbosity of a group for the given number of
+// minutes. sampleRate (0 to 1) is the share of requests whose body is captured.
+func (c *Client) StartGroupDebug(ctx context.Context, id uint, minutes int, sampleRate float64) (*GroupDebugWindow, error) {
+	body := map[string]any{"minutes": minutes, "sample_rate": sampleRate}
+	var window GroupDebugWindow
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/groups/%d/debug", id), nil, body, &window); err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// GetGroupDebug returns the active debug window of a group, or nil when there is none.
+func (c *Client) GetGroupDebug(ctx context.Context, id uint) (*GroupDebugWindow, error) {
+	var window *GroupDebugWindow
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/groups/%d/debug", id), nil, nil, &window); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// StopGroupDebug ends the debug window of a group before it expires.
+func (c *Client) StopGroupDebug(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/groups/%d/debug", id), nil, nil, nil)
+}
+
+// SetHeaderRules replaces the header rules of a group.
+func (c *Client) SetHeaderRules(ctx context.Context, id uint, rules []HeaderRule) (*Group, error) {
+	if rules == nil {
+		rules = []HeaderRule{}
+	}
+	return c.UpdateGroup(ctx, id, &UpdateGroupRequest{HeaderRules: rules})
+}
+
+// ListSubGroups returns the sub groups of an aggregate group.
+func (c *Client) ListSubGroups(ctx context.Context, id uint) ([]SubGroupInfo, error) {
+	var subGroups []SubGroupInfo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/groups/%d/sub-groups", id), nil, nil, &subGroups); err != nil {
+		return nil, err
+	}
+	return subGroups, nil
+}
+
+// AddSubGroups adds sub groups to an aggregate group.
+func (c *Client) AddSubGroups(ctx context.Context, id uint, subGroups []SubGroupInput) error {
+	body := map[string]any{"sub_groups": subGroups}
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/groups/%d/sub-groups", id), nil, body, nil)
+}
+
+// UpdateSubGroupWeight changes the weight of a sub group within an aggregate group.
+func (c *Client) UpdateSubGroupWeight(ctx context.Context, id, subGroupID uint, weight int) error {
+	body := map[string]int{"weight": weight}
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/groups/%d/sub-groups/%d/weight", id, subGroupID), nil, body, nil)
+}
+
+// RemoveSubGroup removes a sub group from an aggregate group.
+func (c *Client) RemoveSubGroup(ctx context.Context, id, subGroupID uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/groups/%d/sub-groups/%d", id, subGroupID), nil, nil, nil)
+}
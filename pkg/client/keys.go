@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListKeysOptions filters and paginates ListKeys.
+type ListKeysOptions struct {
+	Status   string // KeyStatusActive, KeyStatusInvalid or empty for all
+	KeyValue string // exact key value to search for
+	Page     int
+	PageSize int
+}
+
+// ListKeys returns one page of keys of a group.
+func (c *Client) ListKeys(ctx context.Context, groupID uint, opts *ListKeysOptions) (*KeyPage, error) {
+	query := url.Values{"group_id": {strconv.FormatUint(uint64(groupID), 10)}}
+	if opts != nil {
+		if opts.Status != "" {
+			query.Set("status", opts.Status)
+		}
+		if opts.KeyValue != "" {
+			query.Set("key_value", opts.KeyValue)
+		}
+		if opts.Page > 0 {
+			query.Set("page", strconv.Itoa(opts.Page))
+		}
+		if opts.PageSize > 0 {
+			query.Set("page_size", strconv.Itoa(opts.PageSize))
+		}
+	}
+
+	var page KeyPage
+	if err := c.do(ctx, http.MethodGet, "/keys", query, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ExportKeys returns the key values of a group, one per line.
+// status is KeyStatusActive, KeyStatusInvalid or empty for all keys.
+func (c *Client) ExportKeys(ctx context.Context, groupID uint, status string) ([]byte, error) {
+	query := url.Values{"group_id": {strconv.FormatUint(uint64(groupID), 10)}}
+	if status != "" {
+		query.Set("status", status)
+	}
+	return c.send(ctx, http.MethodGet, "/keys/export", query, nil)
+}
+
+// keyTextRequest is the payload of the key operations taking a text block.
+type keyTextRequest struct {
+	GroupID  uint   `json:"group_id"`
+	KeysText string `json:"keys_text"`
+}
+
+// AddKeys adds keys to a group. keysText may separate keys by newlines,
+// commas or spaces, or be a JSON array.
+func (c *Client) AddKeys(ctx context.Context, groupID uint, keysText string) (*AddKeysResult, error) {
+	var result AddKeysResult
+	if err := c.do(ctx, http.MethodPost, "/keys/add-multiple", nil, keyTextRequest{groupID, keysText}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AddKeysAsync starts a background import, for large key lists.
+// Poll TaskStatus for progress.
+func (c *Client) AddKeysAsync(ctx context.Context, groupID uint, keysText string) (*TaskStatus, error) {
+	var status TaskStatus
+	if err := c.do(ctx, http.MethodPost, "/keys/add-async", nil, keyTextRequest{groupID, keysText}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// DeleteKeys deletes the given keys from a group.
+func (c *Client) DeleteKeys(ctx context.Context, groupID uint, keysText string) (*DeleteKeysResult, error) {
+	var result DeleteKeysResult
+	if err := c.do(ctx, http.MethodPost, "/keys/delete-multiple", nil, keyTextRequest{groupID, keysText}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteKeysAsync starts a background deletion, for large key lists.
+func (c *Client) DeleteKeysAsync(ctx context.Context, groupID uint, keysText string) (*TaskStatus, error) {
+	var status TaskStatus
+	if err := c.do(ctx, http.MethodPost, "/keys/delete-async", nil, keyTextRequest{groupID, keysText}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// RestoreKeys marks the given invalid keys of a group active again.
+func (c *Client) RestoreKeys(ctx context.Context, groupID uint, keysText string) (*RestoreKeysResult, error) {
+	var result RestoreKeysResult
+	if err := c.do(ctx, http.MethodPost, "/keys/restore-multiple", nil, keyTextRequest{groupID, keysText}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TestKeys validates the given keys against the group's upstream synchronously.
+func (c *Client) TestKeys(ctx context.Context, groupID uint, keysText string) (*TestKeysResult, error) {
+	var result TestKeysResult
+	if err := c.do(ctx, http.MethodPost, "/keys/test-multiple", nil, keyTextRequest{groupID, keysText}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ValidateGroupKeys starts a background validation of the keys of a group.
+// status limits validation to KeyStatusActive or KeyStatusInvalid keys; empty validates all.
+func (c *Client) ValidateGroupKeys(ctx context.Context, groupID uint, status string) (*TaskStatus, error) {
+	body := struct {
+		GroupID uint   `json:"group_id"`
+		Status  string `json:"status,omitempty"`
+	}{groupID, status}
+
+	var taskStatus TaskStatus
+	if err := c.do(ctx, http.MethodPost, "/keys/validate-group", nil, body, &taskStatus); err != nil {
+		return nil, err
+	}
+	return &taskStatus, nil
+}
+
+// RestoreAllInvalidKeys marks every invalid key of a group active again.
+func (c *Client) RestoreAllInvalidKeys(ctx context.Context, groupID uint) error {
+	return c.groupKeyAction(ctx, "/keys/restore-all-invalid", groupID)
+}
+
+// ClearInvalidKeys deletes every invalid key of a group.
+func (c *Client) ClearInvalidKeys(ctx context.Context, groupID uint) error {
+	return c.groupKeyAction(ctx, "/keys/clear-all-invalid", groupID)
+}
+
+// ClearAllKeys deletes every key of a group.
+func (c *Client) ClearAllKeys(ctx context.Context, groupID uint) error {
+	return c.groupKeyAction(ctx, "/keys/clear-all", groupID)
+}
+
+func (c *Client) groupKeyAction(ctx context.Context, path string, groupID uint) error {
+	body := map[string]uint{"group_id": groupID}
+	return c.do(ctx, http.MethodPost, path, nil, body, nil)
+}
+
+// UpdateKeyNotes sets the notes of a key.
+func (c *Client) UpdateKeyNotes(ctx context.Context, keyID uint, notes string) error {
+	body := map[string]string{"notes": notes}
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/keys/%d/notes", keyID), nil, body, nil)
+}
+
+// TaskStatus returns the state of the current or last background task.
+func (c *Client) TaskStatus(ctx context.Context) (*TaskStatus, error) {
+	var status TaskStatus
+	if err := c.do(ctx, http.MethodGet, "/tasks/status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
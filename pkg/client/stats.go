@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DashboardStats returns the dashboard summary cards.
+func (c *Client) DashboardStats(ctx context.Context) (*DashboardStatsResponse, error) {
+	var stats DashboardStatsResponse
+	if err := c.do(ctx, http.MethodGet, "/dashboard/stats", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// DashboardChart returns the hourly request chart, optionally limited to one group.
+// A groupID of 0 covers all groups.
+func (c *Client) DashboardChart(ctx context.Context, groupID uint) (*ChartData, error) {
+	var query url.Values
+	if groupID > 0 {
+		query = url.Values{"groupId": {strconv.FormatUint(uint64(groupID), 10)}}
+	}
+
+	var chart ChartData
+	if err := c.do(ctx, http.MethodGet, "/dashboard/chart", query, nil, &chart); err != nil {
+		return nil, err
+	}
+	return &chart, nil
+}
+
+// GetSettings returns the system settings grouped by category.
+func (c *Client) GetSettings(ctx context.Context) ([]CategorizedSettings, error) {
+	var settings []CategorizedSettings
+	if err := c.do(ctx, http.MethodGet, "/settings", nil, nil, &settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// UpdateSettings updates system settings by key, e.g. {"request_timeout": 600}.
+// Keys that are not present are left unchanged.
+func (c *Client) UpdateSettings(ctx context.Context, settings map[string]any) error {
+	return c.do(ctx, http.MethodPut, "/settings", nil, settings, nil)
+}
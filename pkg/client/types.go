@@ -0,0 +1,238 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"gpt-load/internal/jsonengine"
+	"gpt-load/internal/models"
+)
+
+// Models shared with the server.
+type (
+	APIKey                 = models.APIKey
+	HeaderRule             = models.HeaderRule
+	ModelRedirectTarget    = models.ModelRedirectTarget
+	PromptTemplate         = models.PromptTemplate
+	SubGroupInfo           = models.SubGroupInfo
+	DashboardStatsResponse = models.DashboardStatsResponse
+	ChartData              = models.ChartData
+	CategorizedSettings    = models.CategorizedSettings
+	SystemSettingInfo      = models.SystemSettingInfo
+	PathRule               = jsonengine.PathRule
+	MatchReport            = jsonengine.MatchReport
+	RuleIssue              = jsonengine.RuleIssue
+)
+
+// Key status values accepted by the key filters.
+const (
+	KeyStatusActive  = models.KeyStatusActive
+	KeyStatusInvalid = models.KeyStatusInvalid
+)
+
+// Group is a group as returned by the admin API.
+type Group struct {
+	ID                  uint                      `json:"id"`
+	Name                string                    `json:"name"`
+	Endpoint            string                    `json:"endpoint"`
+	DisplayName         string                    `json:"display_name"`
+	Description         string                    `json:"description"`
+	GroupType           string                    `json:"group_type"`
+	Upstreams           []Upstream                `json:"upstreams"`
+	ChannelType         string                    `json:"channel_type"`
+	Sort                int                       `json:"sort"`
+	TestModel           string                    `json:"test_model"`
+	ValidationEndpoint  string                    `json:"validation_endpoint"`
+	ParamOverrides      map[string]any            `json:"param_overrides"`
+	ModelRedirectRules  map[string]any            `json:"model_redirect_rules"`
+	ModelRedirectStrict bool                      `json:"model_redirect_strict"`
+	Config              map[string]any            `json:"config"`
+	HeaderRules         []HeaderRule              `json:"header_rules"`
+	InboundRules        []PathRule                `json:"inbound_rules"`
+	OutboundRules       []PathRule                `json:"outbound_rules"`
+	ErrorOutboundRules  []PathRule                `json:"error_outbound_rules"`
+	PromptTemplates     map[string]PromptTemplate `json:"prompt_templates"`
+	ProxyKeys           string                    `json:"proxy_keys"`
+	SubGroupIDs         []uint                    `json:"sub_group_ids,omitempty"`
+	ProviderStatus      *ProviderStatus           `json:"provider_status,omitempty"`
+	LoadError           string                    `json:"load_error,omitempty"`
+	RuleIssues          []GroupRuleIssue          `json:"rule_issues,omitempty"`
+	LastValidatedAt     *time.Time                `json:"last_validated_at"`
+	CreatedAt           time.Time                 `json:"created_at"`
+	UpdatedAt           time.Time                 `json:"updated_at"`
+}
+
+// GroupRuleIssue is a lint issue found in the inbound or outbound rules of a group.
+type GroupRuleIssue struct {
+	Direction string `json:"direction"`
+	RuleIssue
+}
+
+// ProviderStatus is the public status of the group's upstream provider.
+type ProviderStatus struct {
+	Provider    string    `json:"provider"`
+	Indicator   string    `json:"indicator"`
+	Description string    `json:"description"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Upstream is a single upstream entry of a standard group.
+type Upstream struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// CreateGroupRequest is the payload for creating a group.
+type CreateGroupRequest struct {
+	Name                string                           `json:"name"`
+	DisplayName         string                           `json:"display_name,omitempty"`
+	Description         string                           `json:"description,omitempty"`
+	GroupType           string                           `json:"group_type,omitempty"`
+	Upstreams           []Upstream                       `json:"upstreams,omitempty"`
+	ChannelType         string                           `json:"channel_type"`
+	Sort                int                              `json:"sort,omitempty"`
+	TestModel           string                           `json:"test_model"`
+	ValidationEndpoint  string                           `json:"validation_endpoint,omitempty"`
+	ParamOverrides      map[string]any                   `json:"param_overrides,omitempty"`
+	ModelRedirectRules  map[string][]ModelRedirectTarget `json:"model_redirect_rules,omitempty"`
+	ModelRedirectStrict bool                             `json:"model_redirect_strict,omitempty"`
+	Config              map[string]any                   `json:"config,omitempty"`
+	HeaderRules         []HeaderRule                     `json:"header_rules,omitempty"`
+	InboundRules        []PathRule                       `json:"inbound_rules,omitempty"`
+	OutboundRules       []PathRule                       `json:"outbound_rules,omitempty"`
+	ErrorOutboundRules  []PathRule                       `json:"error_outbound_rules,omitempty"`
+	PromptTemplates     map[string]PromptTemplate        `json:"prompt_templates,omitempty"`
+	ProxyKeys           string                           `json:"proxy_keys,omitempty"`
+}
+
+// UpdateGroupRequest is the payload for updating a group.
+// Nil fields are left unchanged by the server; empty (non-nil) slices and maps
+// clear the corresponding setting.
+type UpdateGroupRequest struct {
+	Name                *string                          `json:"name,omitempty"`
+	DisplayName         *string                          `json:"display_name,omitempty"`
+	Description         *string                          `json:"description,omitempty"`
+	GroupType           *string                          `json:"group_type,omitempty"`
+	Upstreams           []Upstream                       `json:"upstreams,omitempty"`
+	ChannelType         *string                          `json:"channel_type,omitempty"`
+	Sort                *int                             `json:"sort,omitempty"`
+	TestModel           string                           `json:"test_model,omitempty"`
+	ValidationEndpoint  *string                          `json:"validation_endpoint,omitempty"`
+	ParamOverrides      map[string]any                   `json:"param_overrides"`
+	ModelRedirectRules  map[string][]ModelRedirectTarget `json:"model_redirect_rules"`
+	ModelRedirectStrict *bool                            `json:"model_redirect_strict,omitempty"`
+	Config              map[string]any                   `json:"config"`
+	HeaderRules         []HeaderRule                     `json:"header_rules"`
+	InboundRules        []PathRule                       `json:"inbound_rules"`
+	OutboundRules       []PathRule                       `json:"outbound_rules"`
+	ErrorOutboundRules  []PathRule                       `json:"error_outbound_rules"`
+	PromptTemplates     map[string]PromptTemplate        `json:"prompt_templates"`
+	ProxyKeys           *string                          `json:"proxy_keys,omitempty"`
+}
+
+// ExplainResult is the outcome of a rule dry run.
+type ExplainResult struct {
+	Matches []MatchReport   `json:"matches"`
+	Output  json.RawMessage `json:"output"`
+}
+
+// GroupDebugWindow is a temporary debug window of a group. While it is active
+// the group logs at debug level and captures sampled request bodies.
+type GroupDebugWindow struct {
+	GroupID    uint      `json:"group_id"`
+	GroupName  string    `json:"group_name"`
+	SampleRate float64   `json:"sample_rate"`
+	StartedAt  time.Time `json:"started_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// SubGroupInput is a sub group reference used when building aggregate groups.
+type SubGroupInput struct {
+	GroupID uint `json:"group_id"`
+	Weight  int  `json:"weight"`
+}
+
+// KeyStats summarizes the keys of a group.
+type KeyStats struct {
+	TotalKeys   int64 `json:"total_keys"`
+	ActiveKeys  int64 `json:"active_keys"`
+	InvalidKeys int64 `json:"invalid_keys"`
+}
+
+// RequestStats captures request success and failure ratios over a time window.
+type RequestStats struct {
+	TotalRequests  int64   `json:"total_requests"`
+	FailedRequests int64   `json:"failed_requests"`
+	FailureRate    float64 `json:"failure_rate"`
+}
+
+// GroupStats aggregates the per-group metrics shown on the group page.
+type GroupStats struct {
+	KeyStats    KeyStats     `json:"key_stats"`
+	Stats24Hour RequestStats `json:"stats_24_hour"`
+	Stats7Day   RequestStats `json:"stats_7_day"`
+	Stats30Day  RequestStats `json:"stats_30_day"`
+}
+
+// Pagination describes a page of a paginated listing.
+type Pagination struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	TotalItems int64 `json:"total_items"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// KeyPage is one page of keys.
+type KeyPage struct {
+	Items      []APIKey   `json:"items"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// AddKeysResult is the result of adding keys synchronously.
+type AddKeysResult struct {
+	AddedCount   int   `json:"added_count"`
+	IgnoredCount int   `json:"ignored_count"`
+	TotalInGroup int64 `json:"total_in_group"`
+}
+
+// DeleteKeysResult is the result of deleting keys synchronously.
+type DeleteKeysResult struct {
+	DeletedCount int   `json:"deleted_count"`
+	IgnoredCount int   `json:"ignored_count"`
+	TotalInGroup int64 `json:"total_in_group"`
+}
+
+// RestoreKeysResult is the result of restoring keys.
+type RestoreKeysResult struct {
+	RestoredCount int   `json:"restored_count"`
+	IgnoredCount  int   `json:"ignored_count"`
+	TotalInGroup  int64 `json:"total_in_group"`
+}
+
+// KeyTestResult is the validation outcome of a single key.
+type KeyTestResult struct {
+	KeyValue string `json:"key_value"`
+	IsValid  bool   `json:"is_valid"`
+	Error    string `json:"error,omitempty"`
+}
+
+// TestKeysResult is the result of testing keys against the upstream.
+type TestKeysResult struct {
+	Results       []KeyTestResult `json:"results"`
+	TotalDuration int64           `json:"total_duration"`
+}
+
+// TaskStatus is the state of the current background task (async import,
+// deletion or validation). Only one task runs at a time.
+type TaskStatus struct {
+	TaskType        string          `json:"task_type"`
+	IsRunning       bool            `json:"is_running"`
+	GroupName       string          `json:"group_name,omitempty"`
+	Processed       int             `json:"processed"`
+	Total           int             `json:"total"`
+	Result          json.RawMessage `json:"result,omitempty"`
+	Error           string          `json:"error,omitempty"`
+	StartedAt       time.Time       `json:"started_at"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+	DurationSeconds float64         `json:"duration_seconds,omitempty"`
+}
@@ -358,17 +358,29 @@ export default {
     inboundRulesTooltip: "Transform request body JSON before forwarding to upstream. Supports add, modify, and remove field operations",
     outboundRules: "Outbound Rules (Response Body)",
     outboundRulesTooltip: "Transform response body JSON before returning to client. Supports add, modify, and remove field operations",
+    errorOutboundRules: "Error Outbound Rules (Non-2xx Response Body)",
+    errorOutboundRulesTooltip:
+      "Transform non-2xx JSON error responses before returning to client, e.g. to normalize or redact upstream error details. Success responses are never affected",
     rule: "Rule",
     jsonPathPlaceholder: "Path, e.g.: user.name or items[0].price or users[*].email",
     jsonValuePlaceholder: "Value (JSON format)",
+    rulePriority: "Priority",
+    maskModePlaceholder: "Mask mode: partial / redact / hash (default partial)",
+    transformPlaceholder: "Transform: lower / upper / trim, or a JSON object with op",
     actionSet: "Modify",
     actionAdd: "Add",
     actionRemove: "Remove",
+    actionMask: "Mask",
+    actionTransform: "Transform",
     willRemoveField: "This field will be removed",
     addInboundRule: "Add Inbound Rule",
     addOutboundRule: "Add Outbound Rule",
+    addErrorOutboundRule: "Add Error Outbound Rule",
     paramOverridesTooltip:
       "Define the API request parameters to be overridden using JSON format. These parameters will be merged with the original parameters when sending the request.",
+    promptTemplates: "Prompt Templates",
+    promptTemplatesTooltip:
+      "Define named prompt templates using JSON format. When a request carries template and variables fields, the proxy substitutes the variables into the template placeholders and expands the template into the channel's message format server-side.",
     modelRedirectPolicy: "Unconfigured Model Policy",
     modelRedirectPolicyTooltip:
       "Choose how to handle requests for models not configured in redirect rules",
@@ -357,17 +357,29 @@ export default {
     inboundRulesTooltip: "アップストリームに転送する前にリクエストボディJSONを変換。フィールドの追加、変更、削除操作をサポート",
     outboundRules: "アウトバウンドルール（レスポンスボディ）",
     outboundRulesTooltip: "クライアントに返す前にレスポンスボディJSONを変換。フィールドの追加、変更、削除操作をサポート",
+    errorOutboundRules: "エラーアウトバウンドルール（2xx 以外のレスポンスボディ）",
+    errorOutboundRulesTooltip:
+      "2xx 以外のJSONエラーレスポンスをクライアントに返す前に変換します（上流エラー詳細の正規化や秘匿など）。成功レスポンスには影響しません",
     rule: "ルール",
     jsonPathPlaceholder: "パス、例：user.name または items[0].price または users[*].email",
     jsonValuePlaceholder: "値（JSON形式）",
+    rulePriority: "優先度",
+    maskModePlaceholder: "マスク方式：partial / redact / hash（デフォルト partial）",
+    transformPlaceholder: "変換：lower / upper / trim、または op を含む JSON オブジェクト",
     actionSet: "変更",
     actionAdd: "追加",
     actionRemove: "削除",
+    actionMask: "マスク",
+    actionTransform: "変換",
     willRemoveField: "このフィールドは削除されます",
     addInboundRule: "インバウンドルール追加",
     addOutboundRule: "アウトバウンドルール追加",
+    addErrorOutboundRule: "エラーアウトバウンドルール追加",
     paramOverridesTooltip:
       "JSON形式を使用して、上書きするAPIリクエストパラメータを定義します。これらのパラメータは、リクエスト送信時に元のパラメータにマージされます。",
+    promptTemplates: "プロンプトテンプレート",
+    promptTemplatesTooltip:
+      "JSON形式で名前付きプロンプトテンプレートを定義します。リクエストに template と variables フィールドが含まれる場合、プロキシはサーバー側で変数をテンプレートのプレースホルダーに代入し、チャネルのメッセージ形式に展開します。",
     modelRedirectPolicy: "未設定モデルポリシー",
     modelRedirectPolicyTooltip:
       "リダイレクトルールで設定されていないモデルのリクエストをどう処理するか選択",
@@ -345,17 +345,29 @@ export default {
     inboundRulesTooltip: "在请求转发至上游前，对请求体JSON进行修改。支持添加、修改、删除字段操作",
     outboundRules: "出站规则（响应体转换）",
     outboundRulesTooltip: "在响应返回客户端前，对响应体JSON进行修改。支持添加、修改、删除字段操作",
+    errorOutboundRules: "错误响应出站规则（非 2xx 响应体）",
+    errorOutboundRulesTooltip:
+      "在非 2xx 的 JSON 错误响应返回客户端前进行修改，可用于统一或隐藏上游错误详情，不会影响成功响应",
     rule: "规则",
     jsonPathPlaceholder: "路径，如：user.name 或 items[0].price 或 users[*].email",
     jsonValuePlaceholder: "值（JSON格式）",
+    rulePriority: "优先级",
+    maskModePlaceholder: "脱敏方式：partial / redact / hash（默认 partial）",
+    transformPlaceholder: "转换：lower / upper / trim，或包含 op 的 JSON 对象",
     actionSet: "修改",
     actionAdd: "添加",
     actionRemove: "删除",
+    actionMask: "脱敏",
+    actionTransform: "转换",
     willRemoveField: "将删除此字段",
     addInboundRule: "添加入站规则",
     addOutboundRule: "添加出站规则",
+    addErrorOutboundRule: "添加错误响应出站规则",
     paramOverridesTooltip:
       "使用JSON格式定义要覆盖的API请求参数。这些参数会在发送请求时合并到原始参数中",
+    promptTemplates: "提示词模板",
+    promptTemplatesTooltip:
+      "使用JSON格式定义命名提示词模板。请求中携带 template 与 variables 字段时，代理会在服务端用变量替换模板占位符后展开为对应渠道的消息格式",
     modelRedirectPolicy: "未配置模型策略",
     modelRedirectPolicyTooltip: "选择如何处理未在重定向规则中配置的模型请求",
     modelRedirectStrictMode: "严格模式：拒绝未配置的模型请求（返回404）",
@@ -50,6 +50,13 @@ export interface JSONRule {
   path: string;  // 路径支持嵌套（如 "user.email" 或 "candidates.[*].content"）
   action: "set" | "add" | "remove";
   value?: unknown;
+  priority?: number;
+}
+
+// 提示词模板
+export interface PromptTemplate {
+  system?: string;
+  messages?: { role: "user" | "assistant"; content: string }[];
 }
 
 // 子分组配置（创建/更新时使用）
@@ -94,6 +101,8 @@ export interface Group {
   header_rules?: HeaderRule[];
   inbound_rules?: JSONRule[];
   outbound_rules?: JSONRule[];
+  error_outbound_rules?: JSONRule[];
+  prompt_templates?: Record<string, PromptTemplate>;
   proxy_keys: string;
   group_type?: GroupType;
   sub_groups?: SubGroupInfo[]; // 子分组列表（仅聚合分组）